@@ -24,326 +24,361 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/auth/login": {
+        "/api/admin/cache/purge": {
             "post": {
-                "description": "Verifies provided credentials, sign jwt and refresh token",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Removes all customers from redis and in-memory caches, so they are read from datastore on the next request.\nPurge is broadcast over customers stream, so in-memory caches of other instances are purged shortly after.\nReturns number of removed redis entries and in-memory entries of instance serving request.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Login user",
-                "parameters": [
-                    {
-                        "description": "User credentials",
-                        "name": "login",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.login"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Purge customers cache",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.session"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.purgedCache"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/auth/logout": {
-            "post": {
-                "description": "Remove any user-related session data",
-                "consumes": [
-                    "application/json"
-                ],
-                "tags": [
-                    "auth"
-                ],
-                "summary": "Logout user",
-                "parameters": [
-                    {
-                        "description": "Refresh token id",
-                        "name": "logout",
-                        "in": "body",
-                        "required": true,
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/handlers.logout"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Successful status code"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/auth/refresh": {
-            "post": {
-                "description": "Sign new jwt and refresh token",
-                "consumes": [
-                    "application/json"
+        "/api/admin/customers/{id}/recent-activity": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Returns the newest events of caller tenant customer occurred within configured window, e.g. the last hour.\nEvents are read from capped collection, so the oldest events may be already overwritten.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Refresh jwt",
+                "summary": "Recent customer activity",
                 "parameters": [
                     {
-                        "description": "Fingerprint and refresh token id",
-                        "name": "refresh",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.refresh"
-                        }
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Max number of events",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.session"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.CustomerEvent"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/auth/signup": {
+        "/api/admin/reload": {
             "post": {
-                "description": "Register new account based on provided credentials",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Re-reads runtime config (cache TTL and its overrides per importance) and feature flags and applies them without restart",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
-                ],
-                "summary": "Signup new account",
-                "parameters": [
-                    {
-                        "description": "New user data",
-                        "name": "signup",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.signup"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Reload runtime config",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.newUser"
+                            "$ref": "#/definitions/handlers.runtimeConfig"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/v1/customers": {
-            "get": {
+        "/api/admin/search/reindex": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns all customers",
+                "description": "Indexes all customers of tenant from datastore and removes documents of customers missing in it, admin only.\nIndex is kept in sync with customer changes, so reindex is needed only to backfill it or to recover it after outage.\nReturns number of indexed customers.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
+                ],
+                "summary": "Reindex customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Get all customers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Customer"
-                            }
+                            "$ref": "#/definitions/handlers.reindexResult"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/admin/sessions": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates new customer",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns page of sessions (refresh tokens) ordered from the newest, sessions can be filtered by user and expiration.\nRefresh token itself is never returned.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "New Customer",
+                "summary": "List sessions",
                 "parameters": [
                     {
-                        "description": "Data for new customer",
-                        "name": "newCustomer",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.newCustomer"
-                        }
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User id",
+                        "name": "userId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return only not expired sessions",
+                        "name": "activeOnly",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of sessions to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "$ref": "#/definitions/handlers.sessionsPage"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/v1/customers/{id}": {
+        "/api/admin/webhooks": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns single customer with provided id",
+                "description": "Returns webhooks of caller tenant, secrets are never returned",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "Get single customer by id",
+                "summary": "List webhooks",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Customer guid",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.webhookInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Updates customer or creates new if not exist",
+                "description": "Subscribes webhook to customer events of caller tenant. Each event is POSTed to webhook url as json\nsigned with HMAC-SHA256 of body keyed with secret in X-Webhook-Signature header, e.g. sha256=5d41...\nSecret is generated if omitted and is returned only in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -351,70 +386,94 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "Update/Create Customer",
+                "summary": "New webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Customer guid",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
                     },
                     {
-                        "description": "Customer data",
-                        "name": "updateCustomer",
+                        "description": "Webhook url and event types",
+                        "name": "newWebhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.updateCustomer"
+                            "$ref": "#/definitions/handlers.newWebhook"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "$ref": "#/definitions/handlers.webhookInfo"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/admin/webhooks/{id}": {
             "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Deletes customer with provided id",
+                "description": "Unsubscribes webhook, its deliveries are deleted as well",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
                 ],
-                "summary": "Delete customer by id",
+                "summary": "Delete webhook by id",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "Customer guid",
+                        "description": "Webhook guid",
                         "name": "id",
-                        "in": "query",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -425,64 +484,112 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/v2/customers": {
+        "/api/admin/webhooks/{id}/deliveries": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Returns all customers",
+                "description": "Returns deliveries of events to webhook from the newest with number of attempts and last failure",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "admin"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Webhook guid",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all customers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.Customer"
+                                "$ref": "#/definitions/handlers.webhookDelivery"
                             }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/auth/introspect": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates new customer",
+                "description": "Verifies jwt and returns its subject, issuer and expiration if it is active, nothing is returned about inactive tokens",
                 "consumes": [
                     "application/json"
                 ],
@@ -490,17 +597,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "auth"
                 ],
-                "summary": "New Customer",
+                "summary": "Introspect jwt",
                 "parameters": [
                     {
-                        "description": "Data for new customer",
-                        "name": "newCustomer",
+                        "description": "Token to introspect",
+                        "name": "introspect",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.newCustomer"
+                            "$ref": "#/definitions/handlers.introspect"
                         }
                     }
                 ],
@@ -508,77 +615,137 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "$ref": "#/definitions/handlers.introspection"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/api/v2/customers/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
+        "/api/auth/login": {
+            "post": {
+                "description": "Verifies provided credentials, sign jwt and refresh token",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Returns single customer with provided id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "auth"
                 ],
-                "summary": "Get single customer by id",
+                "summary": "Login user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Customer guid",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "User credentials",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.login"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "$ref": "#/definitions/handlers.session"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            },
-            "put": {
-                "security": [
+            }
+        },
+        "/api/auth/logout": {
+            "post": {
+                "description": "Remove any user-related session data",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout user",
+                "parameters": [
                     {
-                        "ApiKeyAuth": []
+                        "description": "Refresh token id",
+                        "name": "logout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.logout"
+                        }
                     }
                 ],
-                "description": "Updates customer or creates new if not exist",
+                "responses": {
+                    "200": {
+                        "description": "Successful status code"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Sign new jwt and refresh token, refresh token issued less than min rotation age ago is rejected",
                 "consumes": [
                     "application/json"
                 ],
@@ -586,25 +753,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "auth"
                 ],
-                "summary": "Update/Create Customer",
+                "summary": "Refresh jwt",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Customer guid",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "description": "Customer data",
-                        "name": "updateCustomer",
+                        "description": "Fingerprint and refresh token id",
+                        "name": "refresh",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.updateCustomer"
+                            "$ref": "#/definitions/handlers.refresh"
                         }
                     }
                 ],
@@ -612,186 +771,2050 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Customer"
+                            "$ref": "#/definitions/handlers.session"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
+            }
+        },
+        "/api/auth/rotate": {
+            "post": {
+                "description": "Proactively replace refresh token of session without re-login. Provided refresh token is revoked, new jwt and refresh token are issued\nand expiry of both is returned. Refresh token issued less than min rotation age ago is rejected and revoked to prevent rotation storms, guard is disabled by default",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Deletes customer with provided id",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "customers"
+                    "auth"
                 ],
-                "summary": "Delete customer by id",
+                "summary": "Rotate refresh token",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Customer guid",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "Fingerprint and refresh token id",
+                        "name": "rotate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.refresh"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Successful status code"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.session"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/images/upload": {
+        "/api/auth/signup": {
             "post": {
-                "description": "Uploads image to the server",
+                "description": "Register new account based on provided credentials",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
                 ],
                 "tags": [
-                    "images"
+                    "auth"
                 ],
-                "summary": "Upload image",
+                "summary": "Signup new account",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Image",
-                        "name": "image",
-                        "in": "formData",
-                        "required": true
+                        "description": "New user data",
+                        "name": "signup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.signup"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful status code"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.newUser"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
             }
         },
-        "/images/{name}/download": {
+        "/api/v1/customers": {
             "get": {
-                "description": "Downloads image from the server",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns all customers",
                 "produces": [
-                    "image/gif",
-                    "image/jpeg",
-                    "image/pjpeg",
-                    "image/png",
-                    "image/svg+xml",
-                    "image/tiff",
-                    "image/vnd.microsoft.icon",
-                    "image/vnd.wap.wbmp",
-                    "image/webp"
+                    "application/json",
+                    "application/msgpack"
                 ],
                 "tags": [
-                    "images"
+                    "customers"
                 ],
-                "summary": "Download image",
+                "summary": "Get all customers",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Image name",
-                        "name": "name",
-                        "in": "query",
-                        "required": true
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "application/vnd.customers.envelope+json wraps list into envelope with meta",
+                        "name": "Accept",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return only active customers",
+                        "name": "activeOnly",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.customerV1"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
+                            "$ref": "#/definitions/handlers.errorEnvelope"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "echo.HTTPError": {
-            "type": "object",
-            "properties": {
-                "message": {}
-            }
-        },
-        "handlers.login": {
-            "type": "object",
-            "required": [
-                "email",
-                "fingerprint",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "fingerprint": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.logout": {
-            "type": "object",
-            "required": [
-                "refreshToken"
-            ],
-            "properties": {
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates new customer.\nIf createIfNotExists is requested, customer with the same email is returned with 200 instead of error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "New Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Data for new customer",
+                        "name": "newCustomer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.newCustomer"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return existing customer with the same email instead of error",
+                        "name": "createIfNotExists",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV1"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV1"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/bulk-update": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies partial update to all customers matching filter, admin only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Bulk update customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Filter and fields to change",
+                        "name": "bulkUpdate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.bulkUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.bulkUpdateResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams change events of caller tenant customers as Server-Sent Events until client disconnects.\nEach event has stream id, event name is event type (e.g. customer.created) and data is event json.\nStream is resumed after event passed in Last-Event-ID header, otherwise only new events are sent.\nStream is ended if client falls behind, so client resumes it with Last-Event-ID on reconnect.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Stream customer events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Id of the last received event to resume stream after",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/import.csv": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Upserts customers from CSV file with header row. Columns are named as customer fields, firstName, lastName, email\nand importance are required, id is optional. Customer is matched by id if it is set, otherwise by email.\nRejected rows are reported with line they start at, they don't stop import unless it is strict.\nStrict import imports nothing if any row is invalid or violates business rules.\nImport is stopped once file exceeds max size or number of rows, rows read before are reported along with error.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Import customers from CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Import nothing if any row is invalid",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.importReport"
+                        }
+                    },
+                    "400": {
+                        "description": "malformed csv, errorEnvelope is returned for malformed header",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.importReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "413": {
+                        "description": "file exceeds max size or number of rows",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.importReport"
+                        }
+                    },
+                    "422": {
+                        "description": "strict import rejected, nothing is imported",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.importReport"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.importReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns page of customers whose name or email matches query, the most relevant customers go first.\nCustomers are ranked and typos are tolerated only if search index is configured.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Search customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 256,
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            1,
+                            2,
+                            3,
+                            4
+                        ],
+                        "type": "integer",
+                        "description": "Only customers with importance",
+                        "name": "importance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only active or inactive customers",
+                        "name": "inactive",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of customers to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customersPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/ws": {
+            "get": {
+                "description": "Upgrades connection to websocket. JWT is passed in access_token query parameter or in the first frame {\"type\":\"auth\",\"token\":\"...\"}.\nServer confirms authentication with {\"type\":\"authenticated\"} and then pushes change events of caller tenant customers\nas {\"type\":\"event\",\"eventId\":\"...\",\"event\":{...}}. Customer is requested with {\"type\":\"get\",\"id\":\"1\",\"customerId\":\"...\"}\nand returned as {\"type\":\"customer\",\"id\":\"1\",\"customer\":{...}}, failed requests get {\"type\":\"error\",\"id\":\"1\",\"code\":404,\"message\":\"...\"}.\nConnection is closed with policy violation once token expires or is revoked and with try again later once client falls behind events.",
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Customers websocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT, otherwise it is expected in the first frame",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns single customer with provided id",
+                "produces": [
+                    "application/json",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get single customer by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV1"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates customer or creates new if not exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Update/Create Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Respond with customerUpdate containing customer and its changed fields",
+                        "name": "includeDiff",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pass * to only create customer, existing customer isn't updated",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Customer data",
+                        "name": "updateCustomer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateCustomer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV1"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes customer with provided id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Delete customer by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful status code"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies RFC 6902 JSON patch to existing customer, patched customer is validated as a whole and patch is applied entirely or not at all.\nOperations add, remove and replace are supported for paths /firstName, /lastName, /middleName, /email, /importance and /inactive.",
+                "consumes": [
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Patch Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON patch document",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.patchOperation"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV1"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/customers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns all customers",
+                "produces": [
+                    "application/json",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get all customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "application/vnd.customers.envelope+json wraps list into envelope with meta",
+                        "name": "Accept",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return only active customers",
+                        "name": "activeOnly",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.customerV2"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates new customer.\nIf createIfNotExists is requested, customer with the same email is returned with 200 instead of error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "New Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Data for new customer",
+                        "name": "newCustomer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.newCustomer"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return existing customer with the same email instead of error",
+                        "name": "createIfNotExists",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV2"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/customers/bulk-update": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies partial update to all customers matching filter, admin only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Bulk update customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Filter and fields to change",
+                        "name": "bulkUpdate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.bulkUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.bulkUpdateResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v2/customers/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns single customer with provided id",
+                "produces": [
+                    "application/json",
+                    "application/msgpack"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Get single customer by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates customer or creates new if not exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Update/Create Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Respond with customerUpdate containing customer and its changed fields",
+                        "name": "includeDiff",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pass * to only create customer, existing customer isn't updated",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Customer data",
+                        "name": "updateCustomer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateCustomer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes customer with provided id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Delete customer by id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful status code"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies RFC 6902 JSON patch to existing customer, patched customer is validated as a whole and patch is applied entirely or not at all.\nOperations add, remove and replace are supported for paths /firstName, /lastName, /middleName, /email, /importance and /inactive.",
+                "consumes": [
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customers"
+                ],
+                "summary": "Patch Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller tenant, must match tenant of access token if provided",
+                        "name": "X-Tenant-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Customer guid",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON patch document",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.patchOperation"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.customerV2"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/live": {
+            "get": {
+                "description": "Reports that process is up, dependencies are not checked",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.healthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/ready": {
+            "get": {
+                "description": "Pings dependencies, responds 503 if any of them is unavailable",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.healthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.healthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/images": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns page of uploaded images metadata ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "List images",
+                "parameters": [
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned with previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.imagesPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Uploads image to the server, existing image is replaced only if overwrite is requested.\nImage with identical content is stored once and shared, image is available under its own name anyway.\nEXIF and other metadata is removed from jpeg images if stripping is enabled.\nFile extension must be allowed and match detected MIME type, e.g. png content must be named .png.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "Upload image",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Replace existing image with the same name",
+                        "name": "overwrite",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.uploadedImage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/{name}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes image, content shared by images with identical content is removed from the server with the last of them",
+                "tags": [
+                    "images"
+                ],
+                "summary": "Delete image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image name",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Successful status code"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/{name}/download": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Downloads image from the server, supports range and conditional requests. Authorization is not required if public downloads are enabled.\nETag is content hash of the image, response is cached as immutable if requested version matches it.",
+                "produces": [
+                    "image/gif",
+                    "image/jpeg",
+                    "image/pjpeg",
+                    "image/png",
+                    "image/svg+xml",
+                    "image/tiff",
+                    "image/vnd.microsoft.icon",
+                    "image/vnd.wap.wbmp",
+                    "image/webp"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "Download image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Display image inline instead of downloading it as attachment",
+                        "name": "inline",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Content hash of expected image version",
+                        "name": "v",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested range not satisfiable"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/{name}/signed-download": {
+            "get": {
+                "description": "Verifies signature and expiration of URL and downloads image without authorization",
+                "produces": [
+                    "image/gif",
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "Download image by signed URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Unix time URL expires at",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "URL signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "URL is bound to client IP",
+                        "name": "bindIp",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Display image inline instead of downloading it as attachment",
+                        "name": "inline",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/images/{name}/signed-url": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns time-limited URL image can be downloaded with without authorization, URL can be bound to client IP",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "images"
+                ],
+                "summary": "Sign image download URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Allow download only from IP URL is requested from",
+                        "name": "bindIp",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.signedURL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.errorEnvelope"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.bulkUpdate": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "$ref": "#/definitions/handlers.customersFilter"
+                },
+                "update": {
+                    "$ref": "#/definitions/handlers.customersPatch"
+                }
+            }
+        },
+        "handlers.bulkUpdateResult": {
+            "type": "object",
+            "properties": {
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.customerV1": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "importance": {
+                    "type": "integer"
+                },
+                "inactive": {
+                    "type": "boolean"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "middleName": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.customerV2": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "importance": {
+                    "type": "integer"
+                },
+                "inactive": {
+                    "type": "boolean"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "middleName": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.customersFilter": {
+            "type": "object",
+            "properties": {
+                "importance": {
+                    "type": "integer"
+                },
+                "inactive": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.customersPage": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "array",
+                    "items": {}
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.customersPatch": {
+            "type": "object",
+            "properties": {
+                "importance": {
+                    "type": "integer"
+                },
+                "inactive": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.errorEnvelope": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "status text in snake case, e.g. not_found",
+                    "type": "string"
+                },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.Violation"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.healthStatus": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "status of each dependency, readiness only",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.imagesPage": {
+            "type": "object",
+            "properties": {
+                "images": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.ImageInfo"
+                    }
+                },
+                "nextCursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.importReport": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.rejectedRow"
+                    }
+                }
+            }
+        },
+        "handlers.introspect": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.introspection": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "iss": {
+                    "type": "string"
+                },
+                "sub": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.login": {
+            "type": "object",
+            "required": [
+                "email",
+                "fingerprint",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.logout": {
+            "type": "object",
+            "required": [
+                "refreshToken"
+            ],
+            "properties": {
                 "refreshToken": {
                     "type": "string"
                 }
@@ -807,28 +2830,26 @@ const docTemplate = `{
             ],
             "properties": {
                 "email": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 250
                 },
                 "firstName": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 200
                 },
                 "importance": {
-                    "type": "integer",
-                    "enum": [
-                        1,
-                        2,
-                        3,
-                        4
-                    ]
+                    "type": "integer"
                 },
                 "inactive": {
                     "type": "boolean"
                 },
                 "lastName": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 200
                 },
                 "middleName": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 200
                 }
             }
         },
@@ -843,6 +2864,60 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.newWebhook": {
+            "type": "object",
+            "required": [
+                "eventTypes",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "description": "webhook is active if omitted",
+                    "type": "boolean"
+                },
+                "eventTypes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "generated if omitted",
+                    "type": "string",
+                    "maxLength": 256,
+                    "minLength": 16
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "handlers.patchOperation": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "value": {
+                    "description": "any JSON value, missing for remove",
+                    "type": "object"
+                }
+            }
+        },
+        "handlers.purgedCache": {
+            "type": "object",
+            "properties": {
+                "removed": {
+                    "type": "integer"
+                }
+            }
+        },
         "handlers.refresh": {
             "type": "object",
             "required": [
@@ -851,13 +2926,53 @@ const docTemplate = `{
             ],
             "properties": {
                 "fingerprint": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 },
                 "refreshToken": {
                     "type": "string"
                 }
             }
         },
+        "handlers.reindexResult": {
+            "type": "object",
+            "properties": {
+                "indexed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.rejectedRow": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.Violation"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.runtimeConfig": {
+            "type": "object",
+            "properties": {
+                "customerCacheTimeToLive": {
+                    "type": "string"
+                },
+                "customerCacheTimeToLiveByImportance": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "handlers.session": {
             "type": "object",
             "properties": {
@@ -869,6 +2984,54 @@ const docTemplate = `{
                 },
                 "refreshToken": {
                     "type": "string"
+                },
+                "refreshTokenExpiresAt": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.sessionInfo": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.sessionsPage": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.sessionInfo"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.signedURL": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
@@ -894,36 +3057,100 @@ const docTemplate = `{
             "required": [
                 "email",
                 "firstName",
-                "id",
                 "importance",
                 "lastName"
             ],
             "properties": {
                 "email": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 250
                 },
                 "firstName": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "importance": {
+                    "type": "integer"
+                },
+                "inactive": {
+                    "type": "boolean"
+                },
+                "lastName": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "middleName": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "handlers.uploadedImage": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.webhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "importance": {
-                    "type": "integer",
-                    "enum": [
-                        1,
-                        2,
-                        3,
-                        4
-                    ]
+                "lastError": {
+                    "type": "string"
                 },
-                "inactive": {
+                "lastStatusCode": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.webhookInfo": {
+            "type": "object",
+            "properties": {
+                "active": {
                     "type": "boolean"
                 },
-                "lastName": {
+                "createdAt": {
                     "type": "string"
                 },
-                "middleName": {
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "returned on creation only",
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
@@ -953,6 +3180,97 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.CustomerChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "model.CustomerEvent": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "updated event only",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.CustomerChange"
+                    }
+                },
+                "customer": {
+                    "$ref": "#/definitions/model.Customer"
+                },
+                "customerId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "occurredAt": {
+                    "type": "string"
+                },
+                "tenantId": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.ImageInfo": {
+            "type": "object",
+            "properties": {
+                "blob": {
+                    "type": "string"
+                },
+                "contentType": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "metadataStripped": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "refCount": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "uploadedAt": {
+                    "type": "string"
+                },
+                "uploader": {
+                    "type": "string"
+                }
+            }
+        },
+        "validation.Violation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "failed validation tag, stable for clients unlike message",
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "param": {
+                    "description": "tag parameter, e.g. min length",
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
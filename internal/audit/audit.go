@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+)
+
+// EventType is type of audited authentication event
+type EventType string
+
+const (
+	// EventSignup is raised on new account registration
+	EventSignup EventType = "signup"
+	// EventLogin is raised on login attempt
+	EventLogin EventType = "login"
+	// EventRefresh is raised on session refresh attempt
+	EventRefresh EventType = "refresh"
+	// EventLogout is raised on logout
+	EventLogout EventType = "logout"
+)
+
+const (
+	// OutcomeSuccess means event has completed successfully
+	OutcomeSuccess = "success"
+	// OutcomeFailure means event has been rejected or failed
+	OutcomeFailure = "failure"
+)
+
+const internalErrorReason = "internal error"
+
+// Event represents authentication event, it must never carry secrets like passwords or tokens
+type Event struct {
+	Type        EventType
+	UserID      string
+	Email       string
+	Fingerprint string
+	Reason      string
+}
+
+// Logger writes authentication events as structured log entries
+type Logger struct {
+	log logrus.FieldLogger
+}
+
+// NewLogger builds new Logger writing to provided logger
+func NewLogger(log logrus.FieldLogger) *Logger {
+	return &Logger{log: log}
+}
+
+// Log writes event, outcome is failure if err is not nil
+func (l *Logger) Log(ctx context.Context, e Event, err error) {
+	fields := logrus.Fields{
+		"event":   e.Type,
+		"outcome": OutcomeSuccess,
+	}
+
+	if e.UserID != "" {
+		fields["userId"] = e.UserID
+	}
+
+	if e.Email != "" {
+		fields["email"] = e.Email
+	}
+
+	if e.Fingerprint != "" {
+		fields["fingerprint"] = e.Fingerprint
+	}
+
+	if ip := ClientIPFromContext(ctx); ip != "" {
+		fields["ip"] = ip
+	}
+
+	if err == nil {
+		l.log.WithFields(fields).Infof("auth event %s succeeded", e.Type)
+		return
+	}
+
+	if e.Reason == "" {
+		e.Reason = reason(err)
+	}
+	fields["outcome"] = OutcomeFailure
+	fields["reason"] = e.Reason
+
+	l.log.WithFields(fields).Warnf("auth event %s failed", e.Type)
+}
+
+// reason exposes only client facing errors, so internal details don't leak to audit trail
+func reason(err error) string {
+	var businessErr *appErrors.BusinessErr
+	var notFoundErr *appErrors.EntryNotFoundErr
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.Is(err, appErrors.ErrUnauthorized):
+		return appErrors.ErrUnauthorized.Error()
+	case errors.As(err, &notFoundErr):
+		return notFoundErr.Error()
+	case errors.As(err, &businessErr):
+		return businessErr.Error()
+	case errors.As(err, &httpErr):
+		return fmt.Sprint(httpErr.Message)
+	default:
+		return internalErrorReason
+	}
+}
+
+type clientIPCtxKey struct{}
+
+// ContextWithClientIP returns copy of parent context carrying client ip
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPCtxKey{}, ip)
+}
+
+// ClientIPFromContext extracts client ip from context, empty string is returned if it is missing
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPCtxKey{}).(string)
+	return ip
+}
@@ -0,0 +1,2 @@
+// Package audit contains security audit trail of authentication events
+package audit
@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type claimsCtxKey struct{}
+
+// ContextWithClaims returns copy of parent context carrying verified jwt claims
+func ContextWithClaims(ctx context.Context, claims JwtClaims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// ClaimsFromContext extracts verified jwt claims from context, if any
+func ClaimsFromContext(ctx context.Context) (JwtClaims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(JwtClaims)
+	return claims, ok
+}
@@ -9,9 +9,25 @@ import (
 	"github.com/google/uuid"
 )
 
-// JwtClaims represents JWT claims
+var (
+	// ErrInvalidIssuer is raised when jwt is issued by unexpected issuer
+	ErrInvalidIssuer = errors.New("token issuer is invalid")
+	// ErrInvalidAudience is raised when jwt is not intended for expected audience
+	ErrInvalidAudience = errors.New("token audience is invalid")
+)
+
+// JwtClaims represents JWT claims, subject is user email
 type JwtClaims struct {
 	jwt.RegisteredClaims
+	UserID   string `json:"uid,omitempty"`
+	TenantID string `json:"tid,omitempty"`
+}
+
+// Principal is user jwt is issued for
+type Principal struct {
+	Email    string
+	UserID   string
+	TenantID string
 }
 
 // Jwt represents signed jwt and unix expires at
@@ -20,36 +36,52 @@ type Jwt struct {
 	ExpiresAt int64
 }
 
+// Introspection represents state of jwt, claims are filled for active tokens only
+type Introspection struct {
+	Active    bool
+	Subject   string
+	Issuer    string
+	ExpiresAt int64
+}
+
 // JwtIssuer issues jwt according to config
 type JwtIssuer struct {
 	issuer     string
+	audience   string
 	method     jwt.SigningMethod
 	timeToLive time.Duration
 	privateKey crypto.PrivateKey
 }
 
-// NewJwtIssuer builds JwtIssuer
-func NewJwtIssuer(issuer string, method jwt.SigningMethod, ttl time.Duration, key crypto.PrivateKey) *JwtIssuer {
+// NewJwtIssuer builds JwtIssuer, audience claim is omitted if audience is empty
+func NewJwtIssuer(issuer string, audience string, method jwt.SigningMethod, ttl time.Duration, key crypto.PrivateKey) *JwtIssuer {
 	return &JwtIssuer{
 		issuer:     issuer,
+		audience:   audience,
 		method:     method,
 		timeToLive: ttl,
 		privateKey: key,
 	}
 }
 
-// Sign issues new jwt
-func (j *JwtIssuer) Sign(subj string, issuedAt time.Time) (*Jwt, error) {
+// Sign issues new jwt for principal, email becomes subject, user id and tenant are kept in uid and tid claims
+func (j *JwtIssuer) Sign(p Principal, issuedAt time.Time) (*Jwt, error) {
 	expiresAt := issuedAt.Add(j.timeToLive)
 
 	claims := JwtClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.NewString(),
 			Issuer:    j.issuer,
-			Subject:   subj,
+			Subject:   p.Email,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(issuedAt),
 		},
+		UserID:   p.UserID,
+		TenantID: p.TenantID,
+	}
+
+	if j.audience != "" {
+		claims.Audience = jwt.ClaimStrings{j.audience}
 	}
 
 	token := jwt.NewWithClaims(j.method, claims)
@@ -66,19 +98,37 @@ func (j *JwtIssuer) Sign(subj string, issuedAt time.Time) (*Jwt, error) {
 type JwtValidator struct {
 	method    jwt.SigningMethod
 	publicKey crypto.PublicKey
+	issuer    string
+	audience  string
+	parser    *jwt.Parser
 }
 
-// NewJwtValidator builds new JwtValidator
-func NewJwtValidator(method jwt.SigningMethod, key crypto.PublicKey) *JwtValidator {
-	return &JwtValidator{publicKey: key, method: method}
+// NewJwtValidator builds new JwtValidator accepting only tokens of expected issuer, audience is verified only if it is not empty
+func NewJwtValidator(method jwt.SigningMethod, key crypto.PublicKey, issuer string, audience string) *JwtValidator {
+	return &JwtValidator{
+		publicKey: key,
+		method:    method,
+		issuer:    issuer,
+		audience:  audience,
+		parser:    jwt.NewParser(jwt.WithValidMethods([]string{method.Alg()})),
+	}
 }
 
 // Verify checks if jwt valid
 func (j *JwtValidator) Verify(rawToken string) (JwtClaims, error) {
 	var claims JwtClaims
-	if _, err := jwt.ParseWithClaims(rawToken, &claims, j.keyFunc); err != nil {
+	if _, err := j.parser.ParseWithClaims(rawToken, &claims, j.keyFunc); err != nil {
 		return JwtClaims{}, err
 	}
+
+	if !claims.VerifyIssuer(j.issuer, true) {
+		return JwtClaims{}, ErrInvalidIssuer
+	}
+
+	if j.audience != "" && !claims.VerifyAudience(j.audience, true) {
+		return JwtClaims{}, ErrInvalidAudience
+	}
+
 	return claims, nil
 }
 
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	jwtAlgoEd25519 = "EdDSA"
+	jwtIssuerClaim = "customers-api"
+	jwtAudience    = "customers-web"
+	jwtTimeToLive  = 3 * time.Minute
+	jwtSubject     = "0c8e2f4a-6b1d-4e3f-9a5c-7d2b8e1f0a4c"
+)
+
+type jwtTestSuite struct {
+	suite.Suite
+	method     jwt.SigningMethod
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+func (s *jwtTestSuite) SetupSuite() {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	s.Require().NoError(err, "failed to generate jwt keys")
+
+	s.method = jwt.GetSigningMethod(jwtAlgoEd25519)
+	s.publicKey = publicKey
+	s.privateKey = privateKey
+}
+
+func (s *jwtTestSuite) sign(issuer string, audience string) string {
+	token, err := NewJwtIssuer(issuer, audience, s.method, jwtTimeToLive, s.privateKey).Sign(Principal{Email: jwtSubject}, time.Now())
+	s.Require().NoError(err, "failed to sign jwt")
+	return token.Signed
+}
+
+func (s *jwtTestSuite) TestVerifyIssuer() {
+	t := s.T()
+	require := s.Require()
+
+	validator := NewJwtValidator(s.method, s.publicKey, jwtIssuerClaim, "")
+
+	t.Log("token of expected issuer is accepted")
+	{
+		claims, err := validator.Verify(s.sign(jwtIssuerClaim, ""))
+		require.NoError(err, "token must be valid")
+		require.Equal(jwtSubject, claims.Subject, "subject must be returned")
+		require.Equal(jwtIssuerClaim, claims.Issuer, "issuer must be returned")
+	}
+
+	t.Log("token of another issuer signed with the same key is rejected")
+	{
+		_, err := validator.Verify(s.sign("billing-api", ""))
+		require.ErrorIs(err, ErrInvalidIssuer, "token issuer must be rejected")
+	}
+
+	t.Log("token without issuer is rejected")
+	{
+		_, err := validator.Verify(s.sign("", ""))
+		require.ErrorIs(err, ErrInvalidIssuer, "token without issuer must be rejected")
+	}
+}
+
+func (s *jwtTestSuite) TestVerifyAudience() {
+	t := s.T()
+	require := s.Require()
+
+	validator := NewJwtValidator(s.method, s.publicKey, jwtIssuerClaim, jwtAudience)
+
+	t.Log("token for expected audience is accepted")
+	{
+		claims, err := validator.Verify(s.sign(jwtIssuerClaim, jwtAudience))
+		require.NoError(err, "token must be valid")
+		require.Equal(jwt.ClaimStrings{jwtAudience}, claims.Audience, "audience must be returned")
+	}
+
+	t.Log("token for another audience is rejected")
+	{
+		_, err := validator.Verify(s.sign(jwtIssuerClaim, "billing-web"))
+		require.ErrorIs(err, ErrInvalidAudience, "token audience must be rejected")
+	}
+
+	t.Log("token without audience is rejected")
+	{
+		_, err := validator.Verify(s.sign(jwtIssuerClaim, ""))
+		require.ErrorIs(err, ErrInvalidAudience, "token without audience must be rejected")
+	}
+
+	t.Log("audience is not verified if it is not configured")
+	{
+		_, err := NewJwtValidator(s.method, s.publicKey, jwtIssuerClaim, "").Verify(s.sign(jwtIssuerClaim, jwtAudience))
+		require.NoError(err, "token must be valid")
+	}
+}
+
+func (s *jwtTestSuite) TestVerifySigningMethod() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("token signed with another method is rejected")
+	{
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, JwtClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: jwtIssuerClaim, Subject: jwtSubject},
+		})
+		signed, err := token.SignedString([]byte("secret"))
+		require.NoError(err, "failed to sign jwt")
+
+		_, err = NewJwtValidator(s.method, s.publicKey, jwtIssuerClaim, "").Verify(signed)
+		require.Error(err, "token signed with another method must be rejected")
+	}
+}
+
+// start jwt test suite
+func TestJwtTestSuite(t *testing.T) {
+	suite.Run(t, new(jwtTestSuite))
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenRevoker keeps revoked access tokens until they would have expired anyway
+type TokenRevoker interface {
+	Revoke(context.Context, JwtClaims) error
+	RevokeSubject(context.Context, string, time.Time) error
+	IsRevoked(context.Context, JwtClaims) (bool, error)
+}
+
+type inMemoryTokenRevoker struct {
+	timeToLive time.Duration
+	tokens     map[string]time.Time
+	subjects   map[string]time.Time
+	mu         sync.Mutex
+}
+
+// NewInMemoryTokenRevoker builds in-memory TokenRevoker, ttl is access token time to live
+func NewInMemoryTokenRevoker(ttl time.Duration) TokenRevoker {
+	return &inMemoryTokenRevoker{
+		timeToLive: ttl,
+		tokens:     make(map[string]time.Time),
+		subjects:   make(map[string]time.Time),
+	}
+}
+
+func (r *inMemoryTokenRevoker) Revoke(_ context.Context, claims JwtClaims) error {
+	if claims.ExpiresAt == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[claims.ID] = claims.ExpiresAt.Time
+	return nil
+}
+
+func (r *inMemoryTokenRevoker) RevokeSubject(_ context.Context, subject string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subjects[subject] = at
+	return nil
+}
+
+func (r *inMemoryTokenRevoker) IsRevoked(_ context.Context, claims JwtClaims) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.evictExpired(now)
+
+	if _, ok := r.tokens[claims.ID]; ok {
+		return true, nil
+	}
+
+	if at, ok := r.subjects[claims.Subject]; ok && claims.IssuedAt != nil {
+		return !claims.IssuedAt.After(at), nil
+	}
+
+	return false, nil
+}
+
+func (r *inMemoryTokenRevoker) evictExpired(now time.Time) {
+	for id, expiresAt := range r.tokens {
+		if !now.Before(expiresAt) {
+			delete(r.tokens, id)
+		}
+	}
+
+	for subject, at := range r.subjects {
+		if !now.Before(at.Add(r.timeToLive)) {
+			delete(r.subjects, subject)
+		}
+	}
+}
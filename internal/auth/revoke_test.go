@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type inMemoryTokenRevokerTestSuite struct {
+	suite.Suite
+	revoker TokenRevoker
+}
+
+func (s *inMemoryTokenRevokerTestSuite) SetupTest() {
+	s.revoker = NewInMemoryTokenRevoker(time.Minute)
+}
+
+func (s *inMemoryTokenRevokerTestSuite) TestExpiredEntries() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	issuedAt := time.Now().Add(-2 * time.Minute)
+
+	expired := JwtClaims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "3c2b1a09-8f7e-4d6c-9b5a-4f3e2d1c0b99",
+		Subject:   "john@somemail.com",
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+		ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Minute)),
+	}}
+
+	t.Log("revocation entries are dropped once token would have expired")
+	{
+		require.NoError(s.revoker.Revoke(ctx, expired), "failed to revoke token")
+		require.NoError(s.revoker.RevokeSubject(ctx, expired.Subject, issuedAt), "failed to revoke subject tokens")
+
+		revoked, err := s.revoker.IsRevoked(ctx, expired)
+		require.NoError(err, "failed to check token")
+		require.False(revoked, "expired revocation entries must be evicted")
+
+		r := s.revoker.(*inMemoryTokenRevoker)
+		require.Empty(r.tokens, "expired token entry must be removed")
+		require.Empty(r.subjects, "expired subject entry must be removed")
+	}
+}
+
+// start in-memory token revoker test suite
+func TestInMemoryTokenRevokerTestSuite(t *testing.T) {
+	suite.Run(t, new(inMemoryTokenRevokerTestSuite))
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is raised when signature doesn't match signed URL parameters
+	ErrInvalidSignature = errors.New("signature is invalid")
+	// ErrSignatureExpired is raised when signed URL is used after expiration
+	ErrSignatureExpired = errors.New("signature is expired")
+)
+
+// URLSigner signs object names, so they can be accessed without authorization until signature expires
+type URLSigner struct {
+	key       []byte
+	clockSkew time.Duration
+}
+
+// NewURLSigner builds new URLSigner, signature is still accepted within clock skew after expiration
+func NewURLSigner(key []byte, clockSkew time.Duration) *URLSigner {
+	return &URLSigner{key: key, clockSkew: clockSkew}
+}
+
+// Sign returns signature over object name, expiration and client IP, empty client IP allows access from any IP
+func (s *URLSigner) Sign(name string, expiresAt time.Time, clientIP string) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s\n%d\n%s", name, expiresAt.Unix(), clientIP)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature and expiration of signed URL
+func (s *URLSigner) Verify(name string, expiresAt time.Time, clientIP string, signature string, now time.Time) error {
+	expected := s.Sign(name, expiresAt, clientIP)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	if now.After(expiresAt.Add(s.clockSkew)) {
+		return ErrSignatureExpired
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	signedURLClockSkew = 5 * time.Second
+	signedObjectName   = "avatar.png"
+	signedClientIP     = "10.0.0.7"
+)
+
+type urlSignerTestSuite struct {
+	suite.Suite
+	signer    *URLSigner
+	expiresAt time.Time
+}
+
+func (s *urlSignerTestSuite) SetupTest() {
+	s.signer = NewURLSigner([]byte("0123456789abcdef0123456789abcdef"), signedURLClockSkew)
+	s.expiresAt = time.Unix(time.Now().Add(time.Minute).Unix(), 0)
+}
+
+func (s *urlSignerTestSuite) TestVerify() {
+	t := s.T()
+	require := s.Require()
+
+	signature := s.signer.Sign(signedObjectName, s.expiresAt, "")
+	now := s.expiresAt.Add(-time.Minute)
+
+	t.Log("valid signature is accepted")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt, "", signature, now)
+		require.NoError(err, "signature must be valid")
+	}
+
+	t.Log("signature for another object is rejected")
+	{
+		err := s.signer.Verify("other.png", s.expiresAt, "", signature, now)
+		require.ErrorIs(err, ErrInvalidSignature, "signature must be bound to object name")
+	}
+
+	t.Log("extended expiration is rejected")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt.Add(time.Hour), "", signature, now)
+		require.ErrorIs(err, ErrInvalidSignature, "signature must be bound to expiration")
+	}
+
+	t.Log("tampered signature is rejected")
+	{
+		tampered := []byte(signature)
+		tampered[0] ^= 1
+		err := s.signer.Verify(signedObjectName, s.expiresAt, "", string(tampered), now)
+		require.ErrorIs(err, ErrInvalidSignature, "tampered signature must be rejected")
+	}
+
+	t.Log("signature of another key is rejected")
+	{
+		other := NewURLSigner([]byte("fedcba9876543210fedcba9876543210"), signedURLClockSkew)
+		err := other.Verify(signedObjectName, s.expiresAt, "", signature, now)
+		require.ErrorIs(err, ErrInvalidSignature, "signature must be bound to key")
+	}
+}
+
+func (s *urlSignerTestSuite) TestVerifyExpiration() {
+	t := s.T()
+	require := s.Require()
+
+	signature := s.signer.Sign(signedObjectName, s.expiresAt, "")
+
+	t.Log("signature is accepted within clock skew")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt, "", signature, s.expiresAt.Add(signedURLClockSkew))
+		require.NoError(err, "signature must be valid within clock skew")
+	}
+
+	t.Log("signature is rejected after clock skew")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt, "", signature, s.expiresAt.Add(signedURLClockSkew+time.Second))
+		require.ErrorIs(err, ErrSignatureExpired, "expired signature must be rejected")
+	}
+}
+
+func (s *urlSignerTestSuite) TestVerifyClientIP() {
+	t := s.T()
+	require := s.Require()
+
+	signature := s.signer.Sign(signedObjectName, s.expiresAt, signedClientIP)
+	now := s.expiresAt.Add(-time.Minute)
+
+	t.Log("signature bound to client IP is accepted from the same IP")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt, signedClientIP, signature, now)
+		require.NoError(err, "signature must be valid")
+	}
+
+	t.Log("signature bound to client IP is rejected from another IP")
+	{
+		err := s.signer.Verify(signedObjectName, s.expiresAt, "10.0.0.8", signature, now)
+		require.ErrorIs(err, ErrInvalidSignature, "signature must be bound to client IP")
+
+		err = s.signer.Verify(signedObjectName, s.expiresAt, "", signature, now)
+		require.ErrorIs(err, ErrInvalidSignature, "client IP binding must not be dropped")
+	}
+}
+
+// start url signer test suite
+func TestURLSignerTestSuite(t *testing.T) {
+	suite.Run(t, new(urlSignerTestSuite))
+}
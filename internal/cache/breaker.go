@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/breaker"
+)
+
+type circuitBreakerCustomerCache struct {
+	next    CustomerCacheRepository
+	breaker *breaker.Breaker
+}
+
+// NewCircuitBreakerCustomerCache wraps cache with circuit breaker, so calls are short-circuited to cache miss
+// for cooldown window after consecutive failures instead of waiting for unavailable cache
+func NewCircuitBreakerCustomerCache(
+	name string,
+	next CustomerCacheRepository,
+	reg prometheus.Registerer,
+	cfg *config.CacheBreakerCfg,
+) CustomerCacheRepository {
+	stateGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "customers_cache_breaker_state",
+		Help:        "State of customers cache circuit breaker, 0 - closed, 1 - half-open, 2 - open",
+		ConstLabels: prometheus.Labels{"cache": name},
+	})
+	reg.MustRegister(stateGauge)
+
+	b := breaker.New(breaker.Settings{
+		Name:             name,
+		Kind:             "cache",
+		FailureThreshold: cfg.FailureThreshold,
+		Cooldown:         cfg.Cooldown,
+		IsSuccessful: func(err error) bool {
+			// cancelled request says nothing about cache health
+			return err == nil || errors.Is(err, context.Canceled)
+		},
+		StateGauge: stateGauge,
+	})
+
+	return &circuitBreakerCustomerCache{next: next, breaker: b}
+}
+
+func (c *circuitBreakerCustomerCache) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	customer, err := breaker.Execute(c.breaker, func() (*model.Customer, error) {
+		return c.next.FindByID(ctx, tenantID, id)
+	})
+	if breaker.IsRejection(err) {
+		return nil, nil // read goes straight to database
+	}
+	return customer, err
+}
+
+func (c *circuitBreakerCustomerCache) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	_, err := breaker.Execute(c.breaker, func() (struct{}, error) {
+		return struct{}{}, c.next.DeleteByID(ctx, tenantID, id)
+	})
+	if breaker.IsRejection(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *circuitBreakerCustomerCache) Create(ctx context.Context, customer *model.Customer) error {
+	_, err := breaker.Execute(c.breaker, func() (struct{}, error) {
+		return struct{}{}, c.next.Create(ctx, customer)
+	})
+	if breaker.IsRejection(err) {
+		return nil
+	}
+	return err
+}
+
+// unavailable reports whether calls are short-circuited by open breaker
+func (c *circuitBreakerCustomerCache) unavailable() bool {
+	return c.breaker.Open()
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	breakerFailureThreshold = 3
+	breakerCooldown         = 50 * time.Millisecond
+)
+
+type circuitBreakerTestSuite struct {
+	suite.Suite
+	cacheMock *mocks.CustomerCacheRepository
+	registry  *prometheus.Registry
+	cache     CustomerCacheRepository
+	customer  *model.Customer
+}
+
+func (s *circuitBreakerTestSuite) SetupTest() {
+	s.cacheMock = mocks.NewCustomerCacheRepository(s.T())
+	s.registry = prometheus.NewRegistry()
+	s.cache = NewCircuitBreakerCustomerCache("redis", s.cacheMock, s.registry, &config.CacheBreakerCfg{
+		FailureThreshold: breakerFailureThreshold,
+		Cooldown:         breakerCooldown,
+	})
+	s.customer = &model.Customer{ID: "0b5f8c2e-7d3a-4e1b-9f6c-2a8d4e0b1c3f", TenantID: "acme", FirstName: "John", LastName: "Smith"}
+}
+
+func (s *circuitBreakerTestSuite) breakerState() float64 {
+	families, err := s.registry.Gather()
+	s.Require().NoError(err, "failed to gather metrics")
+	s.Require().Len(families, 1, "breaker state metric must be registered")
+	return families[0].GetMetric()[0].GetGauge().GetValue()
+}
+
+func (s *circuitBreakerTestSuite) TestOpenBreakerShortCircuitsToCacheMiss() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+	redisErr := errors.New("i/o timeout")
+
+	t.Log("failures are returned until threshold is reached")
+	{
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(nil, redisErr).
+			Times(breakerFailureThreshold)
+
+		for i := 0; i < breakerFailureThreshold; i++ {
+			_, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+			require.ErrorIs(err, redisErr, "cache error must be returned while breaker is closed")
+		}
+		require.Equal(float64(2), s.breakerState(), "breaker must be open")
+	}
+
+	t.Log("open breaker short-circuits calls without touching cache")
+	{
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "open breaker must report cache miss")
+		require.Nil(c, "open breaker must report cache miss")
+
+		err = s.cache.Create(ctx, s.customer)
+		require.NoError(err, "open breaker must skip cache writes")
+
+		err = s.cache.DeleteByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "open breaker must skip cache deletes")
+	}
+
+	t.Log("cached customer is returned once breaker is closed")
+	{
+		time.Sleep(breakerCooldown + 10*time.Millisecond)
+
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(s.customer, nil).
+			Once()
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "cached customer must be returned")
+	}
+}
+
+func (s *circuitBreakerTestSuite) TestCancelledCallsDoNotTripBreaker() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("cancelled requests are not counted as failures")
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(nil, context.Canceled).
+			Times(breakerFailureThreshold + 1)
+
+		for i := 0; i <= breakerFailureThreshold; i++ {
+			_, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+			require.ErrorIs(err, context.Canceled, "cancellation must be returned")
+		}
+		require.Zero(s.breakerState(), "breaker must stay closed")
+	}
+}
+
+// start circuit breaker test suite
+func TestCircuitBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(circuitBreakerTestSuite))
+}
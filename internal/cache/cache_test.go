@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	connectionTimeout = 3 * time.Second
+	testCtxTimeout    = 10 * time.Second
+)
+
+const (
+	redisContainerName = "redis-cache-test-customers"
+	redisTestPassword  = "cache-test"
+	redisPort          = "6380"
+	redisTestDB        = 0
+)
+
+type cacheTestSuite struct {
+	suite.Suite
+	dockerPool  *dockertest.Pool
+	redis       *dockertest.Resource
+	redisClient *redis.Client
+}
+
+func (s *cacheTestSuite) SetupSuite() {
+	t := s.T()
+	assert := s.Require()
+
+	// build docker pool
+	t.Log("build docker pool")
+	dockerPool, err := dockertest.NewPool("")
+	assert.NoError(err, "failed to create pool")
+
+	t.Log("sending ping to docker...")
+	err = dockerPool.Client.Ping()
+	assert.NoError(err, "failed to connect to docker")
+
+	s.dockerPool = dockerPool // assign pool
+
+	t.Log("starting redis...")
+	redisCache, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       redisContainerName,
+		Repository: "redis",
+		Tag:        "latest",
+		Cmd:        []string{fmt.Sprintf("--requirepass %s", redisTestPassword)},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"6379/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", redisPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start redis")
+
+	s.redis = redisCache // assign redis
+
+	// connect to redis
+	t.Log("connecting to redis...")
+	err = dockerPool.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+		defer cancel()
+
+		s.redisClient = redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("localhost:%s", redisPort),
+			Password: redisTestPassword,
+			DB:       redisTestDB,
+		})
+
+		return s.redisClient.Ping(ctx).Err()
+	})
+	assert.NoError(err, "failed to establish connection to redis")
+}
+
+func (s *cacheTestSuite) TearDownSuite() {
+	t := s.T()
+
+	if s.redisClient != nil {
+		t.Log("closing connection to redis")
+		if err := s.redisClient.Close(); err != nil {
+			t.Logf("failed to gracefully close connection to redis - %v", err)
+		}
+	}
+
+	if s.redis != nil {
+		if err := s.dockerPool.Purge(s.redis); err != nil {
+			t.Logf("failed to purge redis container - %v", err)
+		}
+	}
+}
+
+func (s *cacheTestSuite) SetupTest() {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+	s.Require().NoError(s.redisClient.FlushDB(ctx).Err(), "failed to flush redis database")
+}
+
+func (s *cacheTestSuite) TestCustomerStreamLag() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	inMemoryCache := NewInMemoryCache()
+	streamCache := NewRedisStreamCustomerCache(s.redisClient, inMemoryCache)
+	reader := NewRedisCustomerStreamReader(
+		s.redisClient,
+		inMemoryCache,
+		prometheus.NewRegistry(),
+		&config.CustomersStreamCfg{LagWarnThreshold: 1, LagCheckInterval: time.Second},
+	)
+
+	customers := []*model.Customer{
+		{ID: "7c0a3c7b-4a52-4d0e-9a4e-3f1f0b7f2d11", FirstName: "John", LastName: "Norman", Email: "john@somemail.com"},
+		{ID: "0f5d3c1e-9b8e-4e55-8d2a-6f8e1b2c3d44", FirstName: "Albert", LastName: "Peers", Email: "albert@somemail.com"},
+		{ID: "b3e2a1d0-5c6f-4e7a-8b9c-0d1e2f3a4b55", FirstName: "Andrew", LastName: "Wallet", Email: "andrew@somemail.com"},
+	}
+
+	t.Log("reader starts from the end of empty stream")
+	{
+		err := reader.seekToEnd(ctx)
+		require.NoError(err, "failed to seek to the end of stream")
+
+		lag, err := reader.Lag(ctx)
+		require.NoError(err, "failed to calculate lag")
+		require.Zero(lag, "nothing was produced, lag must be zero")
+	}
+
+	t.Logf("produce %d messages", len(customers))
+	{
+		for _, c := range customers {
+			err := streamCache.Create(ctx, c)
+			require.NoError(err, "failed to produce message")
+		}
+
+		lag, err := reader.Lag(ctx)
+		require.NoError(err, "failed to calculate lag")
+		require.Equal(int64(len(customers)), lag, "messages are not consumed, so lag must be equal to number of produced messages")
+		require.Equal(float64(len(customers)), testutil.ToFloat64(reader.lagGauge), "gauge must reflect lag")
+	}
+
+	t.Log("consume messages")
+	{
+		err := reader.read(ctx)
+		require.NoError(err, "failed to read messages")
+
+		lag, err := reader.Lag(ctx)
+		require.NoError(err, "failed to calculate lag")
+		require.Zero(lag, "all messages are consumed, lag must be zero")
+		require.Zero(testutil.ToFloat64(reader.lagGauge), "gauge must be reset after consumption")
+
+		c, err := inMemoryCache.FindByID(ctx, customers[0].TenantID, customers[0].ID)
+		require.NoError(err, "failed to read customer from in-memory cache")
+		require.NotNil(c, "consumed customer must be in in-memory cache")
+	}
+}
+
+func (s *cacheTestSuite) TestCustomerStreamPurge() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	inMemoryCache := NewInMemoryCache()
+	fallbackCache := NewInMemoryCache()
+	reader := NewRedisCustomerStreamReader(
+		s.redisClient,
+		inMemoryCache,
+		prometheus.NewRegistry(),
+		&config.CustomersStreamCfg{LagWarnThreshold: 1, LagCheckInterval: time.Second},
+		inMemoryCache, fallbackCache,
+	)
+	require.NoError(reader.seekToEnd(ctx), "failed to seek to the end of stream")
+
+	customer := &model.Customer{ID: "7c0a3c7b-4a52-4d0e-9a4e-3f1f0b7f2d11", TenantID: "acme", FirstName: "John", LastName: "Norman", Email: "john@somemail.com"}
+	require.NoError(inMemoryCache.Create(ctx, customer), "failed to cache customer")
+	require.NoError(fallbackCache.Create(ctx, customer), "failed to cache customer")
+
+	t.Log("broadcast purge removes nothing by itself")
+	{
+		removed, err := NewRedisStreamCustomerCachePurger(s.redisClient).Purge(ctx)
+		require.NoError(err, "failed to broadcast purge")
+		require.Zero(removed, "broadcast must not remove entries")
+	}
+
+	t.Log("reader purges local caches once purge is consumed")
+	{
+		require.NoError(reader.read(ctx), "failed to read messages")
+
+		for _, c := range []CustomerCacheRepository{inMemoryCache, fallbackCache} {
+			found, err := c.FindByID(ctx, customer.TenantID, customer.ID)
+			require.NoError(err, "failed to read customer from local cache")
+			require.Nil(found, "customer must be purged from local cache")
+		}
+	}
+}
+
+func (s *cacheTestSuite) TestRedisTokenRevoker() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	revoker := NewRedisTokenRevoker(s.redisClient, time.Minute)
+	issuedAt := time.Now().Add(-time.Second)
+
+	claims := func(id string, subject string, iat time.Time) auth.JwtClaims {
+		return auth.JwtClaims{RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(iat),
+			ExpiresAt: jwt.NewNumericDate(iat.Add(time.Minute)),
+		}}
+	}
+
+	revokedToken := claims("1f1bd4c6-5a5e-4a55-9a3f-8f0f0d3c2b11", "john@somemail.com", issuedAt)
+	validToken := claims("6b0d8c2e-1c7a-4e2f-b8f1-2c3d4e5f6a77", "john@somemail.com", issuedAt)
+
+	t.Log("token is revoked by id")
+	{
+		err := revoker.Revoke(ctx, revokedToken)
+		require.NoError(err, "failed to revoke token")
+
+		revoked, err := revoker.IsRevoked(ctx, revokedToken)
+		require.NoError(err, "failed to check revoked token")
+		require.True(revoked, "token must be revoked")
+
+		revoked, err = revoker.IsRevoked(ctx, validToken)
+		require.NoError(err, "failed to check valid token")
+		require.False(revoked, "other token of the same subject must stay valid")
+
+		ttl, err := s.redisClient.TTL(ctx, "revoked:jti:"+revokedToken.ID).Result()
+		require.NoError(err, "failed to read revocation entry ttl")
+		require.Positive(ttl, "revocation entry must expire")
+		require.LessOrEqual(ttl, time.Minute, "revocation entry must not outlive token")
+	}
+
+	t.Log("all subject tokens issued before revocation are revoked")
+	{
+		err := revoker.RevokeSubject(ctx, validToken.Subject, time.Now())
+		require.NoError(err, "failed to revoke subject tokens")
+
+		revoked, err := revoker.IsRevoked(ctx, validToken)
+		require.NoError(err, "failed to check token issued before revocation")
+		require.True(revoked, "token issued before revocation must be revoked")
+
+		newToken := claims("9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b66", validToken.Subject, time.Now().Add(time.Second))
+		revoked, err = revoker.IsRevoked(ctx, newToken)
+		require.NoError(err, "failed to check token issued after revocation")
+		require.False(revoked, "token issued after revocation must be valid")
+	}
+}
+
+func (s *cacheTestSuite) TestRedisCustomerCacheTimeToLive() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	runtimeCfg, err := config.NewRuntimeHolder("", config.RuntimeCfg{
+		CustomerCacheTimeToLive: time.Minute,
+		CustomerCacheTimeToLiveByImportance: config.ImportanceTimeToLive{
+			model.ImportanceCritical: time.Hour,
+			model.ImportanceHigh:     10 * time.Minute,
+		},
+	})
+	require.NoError(err, "failed to build runtime config")
+
+	customerCache := NewRedisCustomerCache(s.redisClient, runtimeCfg)
+
+	ttlOf := func(importance model.Importance, id string) time.Duration {
+		err := customerCache.Create(ctx, &model.Customer{ID: id, TenantID: "acme", FirstName: "John", LastName: "Smith", Importance: importance})
+		require.NoError(err, "failed to cache customer")
+
+		ttl, err := s.redisClient.TTL(ctx, "customer:acme:"+id).Result()
+		require.NoError(err, "failed to read cached customer ttl")
+		return ttl
+	}
+
+	t.Log("time to live configured for importance is applied")
+	{
+		ttl := ttlOf(model.ImportanceCritical, "5a4b3c2d-1e0f-4a9b-8c7d-6e5f4a3b2c1d")
+		require.Greater(ttl, 10*time.Minute, "critical customer must use its time to live")
+		require.LessOrEqual(ttl, time.Hour, "critical customer must use its time to live")
+
+		ttl = ttlOf(model.ImportanceHigh, "b1c2d3e4-f5a6-4b7c-8d9e-0f1a2b3c4d5e")
+		require.Greater(ttl, time.Minute, "high customer must use its time to live")
+		require.LessOrEqual(ttl, 10*time.Minute, "high customer must use its time to live")
+	}
+
+	t.Log("default time to live is applied to unmapped importance")
+	{
+		ttl := ttlOf(model.ImportanceLow, "e9f8a7b6-c5d4-4e3f-a2b1-c0d9e8f7a6b5")
+		require.Positive(ttl, "cached customer must expire")
+		require.LessOrEqual(ttl, time.Minute, "low customer must use default time to live")
+	}
+}
+
+func (s *cacheTestSuite) TestRedisCustomerCachePurger() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	runtimeCfg, err := config.NewRuntimeHolder("", config.RuntimeCfg{CustomerCacheTimeToLive: time.Minute})
+	require.NoError(err, "failed to build runtime config")
+
+	msgpackCache := NewRedisCustomerCache(s.redisClient, runtimeCfg)
+	protoCache := NewRedisProtoCustomerCache(s.redisClient, runtimeCfg)
+	purger := NewRedisCustomerCachePurger(s.redisClient)
+
+	// more customers than fit into single batch
+	cachedCount := purgeBatchSize + 10
+	for i := 0; i < cachedCount; i++ {
+		customerCache := msgpackCache
+		if i%2 == 0 {
+			customerCache = protoCache
+		}
+		err := customerCache.Create(ctx, &model.Customer{ID: fmt.Sprintf("customer-%d", i), TenantID: "acme", FirstName: "John", LastName: "Smith", Importance: model.ImportanceLow})
+		require.NoError(err, "failed to cache customer")
+	}
+
+	foreignKeys := []string{"revoked:jti:8d7c6b5a-4f3e-4d2c-9b1a-0f9e8d7c6b5a", customersStream, "customers:counter"}
+	for _, key := range foreignKeys {
+		require.NoError(s.redisClient.Set(ctx, key, "1", time.Minute).Err(), "failed to set key outside of customers namespace")
+	}
+
+	t.Log("all customer keys are removed")
+	{
+		removed, err := purger.Purge(ctx)
+		require.NoError(err, "failed to purge customers cache")
+		require.Equal(cachedCount, removed, "all cached customers must be removed")
+
+		keys, err := s.redisClient.Keys(ctx, customerKeysPattern).Result()
+		require.NoError(err, "failed to list customer keys")
+		require.Empty(keys, "customer keys must not remain")
+	}
+
+	t.Log("keys outside of customers namespace are kept")
+	{
+		exists, err := s.redisClient.Exists(ctx, foreignKeys...).Result()
+		require.NoError(err, "failed to check keys outside of customers namespace")
+		require.Equal(int64(len(foreignKeys)), exists, "keys outside of customers namespace must be kept")
+	}
+
+	t.Log("purge of empty cache removes nothing")
+	{
+		removed, err := purger.Purge(ctx)
+		require.NoError(err, "failed to purge customers cache")
+		require.Zero(removed, "nothing must be removed")
+	}
+}
+
+// start cache test suite
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(cacheTestSuite))
+}
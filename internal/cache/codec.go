@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// customerCodec encodes customer to bytes stored in cache and decodes it back
+type customerCodec interface {
+	Marshal(*model.Customer) ([]byte, error)
+	Unmarshal([]byte) (*model.Customer, error)
+}
+
+type msgpackCustomerCodec struct{}
+
+func (msgpackCustomerCodec) Marshal(c *model.Customer) ([]byte, error) {
+	return msgpack.Marshal(c)
+}
+
+func (msgpackCustomerCodec) Unmarshal(b []byte) (*model.Customer, error) {
+	var c model.Customer
+	if err := msgpack.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+type protoCustomerCodec struct{}
+
+func (protoCustomerCodec) Marshal(c *model.Customer) ([]byte, error) {
+	return protobuf.Marshal(&proto.CustomerResponse{
+		Id:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: proto.CustomerImportance(c.Importance),
+		Inactive:   c.Inactive,
+	})
+}
+
+func (protoCustomerCodec) Unmarshal(b []byte) (*model.Customer, error) {
+	var res proto.CustomerResponse
+	if err := protobuf.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+
+	return &model.Customer{
+		ID:         res.Id,
+		FirstName:  res.FirstName,
+		LastName:   res.LastName,
+		MiddleName: res.MiddleName,
+		Email:      res.Email,
+		Importance: model.Importance(res.Importance),
+		Inactive:   res.Inactive,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+func benchmarkCustomer() *model.Customer {
+	middleName := "Jr"
+	return &model.Customer{
+		ID:         "ecc770d9-4576-4f72-affa-8b1454246692",
+		FirstName:  "John",
+		LastName:   "Walls",
+		MiddleName: &middleName,
+		Email:      "john.walls@somemal.com",
+		Importance: model.ImportanceCritical,
+		Inactive:   true,
+	}
+}
+
+type codecTestSuite struct {
+	suite.Suite
+}
+
+func (s *codecTestSuite) TestRoundTrip() {
+	t := s.T()
+	require := s.Require()
+
+	codecs := map[string]customerCodec{
+		"msgpack": msgpackCustomerCodec{},
+		"proto":   protoCustomerCodec{},
+	}
+
+	customer := benchmarkCustomer()
+	noMiddleName := benchmarkCustomer()
+	noMiddleName.MiddleName = nil
+
+	for name, codec := range codecs {
+		t.Logf("%s codec must decode exactly the same customer it encoded", name)
+		{
+			for _, c := range []*model.Customer{customer, noMiddleName} {
+				encoded, err := codec.Marshal(c)
+				require.NoError(err, "no error must be raised on marshal")
+
+				decoded, err := codec.Unmarshal(encoded)
+				require.NoError(err, "no error must be raised on unmarshal")
+				require.Equal(c, decoded, "decoded customer differs from original")
+			}
+		}
+
+		t.Logf("%s codec must fail on corrupted data", name)
+		{
+			_, err := codec.Unmarshal([]byte{0xff, 0xff, 0xff})
+			require.Error(err, "corrupted data must raise error")
+		}
+	}
+}
+
+// start codec test suite
+func TestCodecTestSuite(t *testing.T) {
+	suite.Run(t, new(codecTestSuite))
+}
+
+func BenchmarkMsgpackCustomerCodec(b *testing.B) {
+	benchmarkCustomerCodec(b, msgpackCustomerCodec{})
+}
+
+func BenchmarkProtoCustomerCodec(b *testing.B) {
+	benchmarkCustomerCodec(b, protoCustomerCodec{})
+}
+
+func benchmarkCustomerCodec(b *testing.B, codec customerCodec) {
+	c := benchmarkCustomer()
+
+	encoded, err := codec.Marshal(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encoded, err := codec.Marshal(c)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := codec.Unmarshal(encoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(encoded)), "bytes/customer")
+}
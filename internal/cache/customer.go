@@ -8,33 +8,44 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v9"
+	"github.com/umalmyha/customers/internal/config"
 	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 const (
-	cachedCustomerTimeToLive = 3 * time.Minute
-	customerStreamMaxLen     = 1000
+	customerStreamMaxLen = 1000
+	customersStream      = "customers-stream"
 )
 
 // CustomerCacheRepository interface representing customer cache behavior
 type CustomerCacheRepository interface {
-	FindByID(context.Context, string) (*model.Customer, error)
-	DeleteByID(context.Context, string) error
+	FindByID(context.Context, string, string) (*model.Customer, error)
+	DeleteByID(context.Context, string, string) error
 	Create(context.Context, *model.Customer) error
 }
 
 type redisCustomerCache struct {
-	client *redis.Client
+	client     *redis.Client
+	codec      customerCodec
+	runtimeCfg *config.RuntimeHolder
 }
 
-// NewRedisCustomerCache builds new redis customer cache
-func NewRedisCustomerCache(client *redis.Client) CustomerCacheRepository {
-	return &redisCustomerCache{client: client}
+// NewRedisCustomerCache builds new redis customer cache storing customers encoded with msgpack
+func NewRedisCustomerCache(client *redis.Client, runtimeCfg *config.RuntimeHolder) CustomerCacheRepository {
+	return &redisCustomerCache{client: client, codec: msgpackCustomerCodec{}, runtimeCfg: runtimeCfg}
 }
 
-func (r *redisCustomerCache) FindByID(ctx context.Context, id string) (*model.Customer, error) {
-	res, err := r.client.Get(ctx, r.key(id)).Result()
+// NewRedisProtoCustomerCache builds new redis customer cache storing customers encoded as proto CustomerResponse
+func NewRedisProtoCustomerCache(client *redis.Client, runtimeCfg *config.RuntimeHolder) CustomerCacheRepository {
+	return &redisCustomerCache{client: client, codec: protoCustomerCodec{}, runtimeCfg: runtimeCfg}
+}
+
+func (r *redisCustomerCache) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	key := r.key(tenantID, id)
+	res, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, nil
@@ -42,110 +53,216 @@ func (r *redisCustomerCache) FindByID(ctx context.Context, id string) (*model.Cu
 		return nil, err
 	}
 
-	var c model.Customer
-	if err := msgpack.Unmarshal([]byte(res), &c); err != nil {
-		return nil, err
+	c, err := r.codec.Unmarshal([]byte(res))
+	if err != nil { // corrupt entry is treated as cache miss, so customer is read from database and cached again
+		logging.FromContext(ctx).Warnf("failed to decode cached customer %s, removing it from cache - %v", key, err)
+		if err := r.client.Del(ctx, key).Err(); err != nil {
+			logging.FromContext(ctx).Errorf("failed to remove corrupt cached customer %s - %v", key, err)
+		}
+		return nil, nil
 	}
+	c.TenantID = tenantID // tenant is part of the key, so not every codec keeps it in value
 
-	return &c, nil
+	return c, nil
 }
 
-func (r *redisCustomerCache) DeleteByID(ctx context.Context, id string) error {
-	if _, err := r.client.Del(ctx, r.key(id)).Result(); err != nil {
+func (r *redisCustomerCache) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	if _, err := r.client.Del(ctx, r.key(tenantID, id)).Result(); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (r *redisCustomerCache) Create(ctx context.Context, c *model.Customer) error {
-	encoded, err := msgpack.Marshal(c)
+	encoded, err := r.codec.Marshal(c)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.client.SetNX(ctx, r.key(c.ID), encoded, cachedCustomerTimeToLive).Result()
+	_, err = r.client.SetNX(ctx, r.key(c.TenantID, c.ID), encoded, r.runtimeCfg.CustomerCacheTimeToLiveFor(c.Importance)).Result()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *redisCustomerCache) key(id string) string {
-	return fmt.Sprintf("customer:%s", id)
+func (r *redisCustomerCache) key(tenantID string, id string) string {
+	return fmt.Sprintf("customer:%s:%s", tenantID, id)
+}
+
+type inMemoryCacheKey struct {
+	tenantID string
+	id       string
+}
+
+// inMemoryCacheSweepInterval is min interval between removals of expired customers which are never read again
+const inMemoryCacheSweepInterval = time.Minute
+
+type inMemoryCacheEntry struct {
+	customer  *model.Customer
+	expiresAt time.Time // zero if customer never expires
+}
+
+func (e inMemoryCacheEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
 }
 
 type inMemoryCache struct {
-	customers map[string]*model.Customer
+	customers map[inMemoryCacheKey]inMemoryCacheEntry
+	ttl       func(model.Importance) time.Duration // nil if customers never expire
+	now       func() time.Time
+	swept     time.Time
 	mu        sync.RWMutex
 }
 
-// NewInMemoryCache builds new in-memory cache
-func NewInMemoryCache() CustomerCacheRepository {
+// NewInMemoryCache builds new in-memory cache, customers are kept until deleted
+func NewInMemoryCache() PurgeableCustomerCache {
+	return newInMemoryCache(nil, time.Now)
+}
+
+// NewExpiringInMemoryCache builds new in-memory cache expiring customers after the same time to live as redis cache,
+// so customers cached while redis is unreachable are not served forever
+func NewExpiringInMemoryCache(runtimeCfg *config.RuntimeHolder) PurgeableCustomerCache {
+	return newInMemoryCache(runtimeCfg.CustomerCacheTimeToLiveFor, time.Now)
+}
+
+func newInMemoryCache(ttl func(model.Importance) time.Duration, now func() time.Time) *inMemoryCache {
 	return &inMemoryCache{
-		customers: make(map[string]*model.Customer),
+		customers: make(map[inMemoryCacheKey]inMemoryCacheEntry),
+		ttl:       ttl,
+		now:       now,
+		swept:     now(),
 	}
 }
 
-func (c *inMemoryCache) FindByID(_ context.Context, id string) (*model.Customer, error) {
+func (c *inMemoryCache) FindByID(_ context.Context, tenantID string, id string) (*model.Customer, error) {
+	key := inMemoryCacheKey{tenantID: tenantID, id: id}
+
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	entry, ok := c.customers[key]
+	c.mu.RUnlock()
 
-	customer, ok := c.customers[id]
 	if !ok {
 		return nil, nil
 	}
 
-	return customer, nil
+	if now := c.now(); entry.expired(now) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if entry, ok := c.customers[key]; ok && entry.expired(now) { // might have been cached again meanwhile
+			delete(c.customers, key)
+		}
+		return nil, nil
+	}
+
+	return entry.customer, nil
 }
 
 func (c *inMemoryCache) Create(_ context.Context, customer *model.Customer) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.customers[customer.ID] = customer
+	now := c.now()
+	entry := inMemoryCacheEntry{customer: customer}
+	if c.ttl != nil {
+		entry.expiresAt = now.Add(c.ttl(customer.Importance))
+		c.sweep(now)
+	}
+
+	c.customers[inMemoryCacheKey{tenantID: customer.TenantID, id: customer.ID}] = entry
 	return nil
 }
 
-func (c *inMemoryCache) DeleteByID(_ context.Context, id string) error {
+// sweep removes expired customers, it runs at most once per sweep interval, so writes stay cheap
+func (c *inMemoryCache) sweep(now time.Time) {
+	if now.Sub(c.swept) < inMemoryCacheSweepInterval {
+		return
+	}
+
+	c.swept = now
+	for key, entry := range c.customers {
+		if entry.expired(now) {
+			delete(c.customers, key)
+		}
+	}
+}
+
+func (c *inMemoryCache) DeleteByID(_ context.Context, tenantID string, id string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.customers, id)
+	delete(c.customers, inMemoryCacheKey{tenantID: tenantID, id: id})
 	return nil
 }
 
-type redisStreamCustomerCache struct {
-	client *redis.Client
+// Purge removes all cached customers and returns their number
+func (c *inMemoryCache) Purge(context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := len(c.customers)
+	c.customers = make(map[inMemoryCacheKey]inMemoryCacheEntry)
+	return removed, nil
+}
+
+// cacheOpPublisher publishes cache operation to replicas of in-memory cache
+type cacheOpPublisher func(ctx context.Context, op string, tenantID string, value []byte) error
+
+// replicatedCustomerCache publishes cache changes to replicas of in-memory cache instead of applying them directly,
+// reads are served from primary cache, which is replica of instance itself
+type replicatedCustomerCache struct {
+	publish cacheOpPublisher
 	CustomerCacheRepository
 }
 
 // NewRedisStreamCustomerCache builds redis stream customer cache
 func NewRedisStreamCustomerCache(client *redis.Client, primary CustomerCacheRepository) CustomerCacheRepository {
-	return &redisStreamCustomerCache{client: client, CustomerCacheRepository: primary}
+	return &replicatedCustomerCache{
+		publish: func(ctx context.Context, op string, tenantID string, value []byte) error {
+			return xAddCacheOp(ctx, client, op, tenantID, value)
+		},
+		CustomerCacheRepository: primary,
+	}
 }
 
-func (r *redisStreamCustomerCache) Create(ctx context.Context, c *model.Customer) error {
+func (r *replicatedCustomerCache) Create(ctx context.Context, c *model.Customer) error {
 	value, err := msgpack.Marshal(c)
 	if err != nil {
 		return err
 	}
 
-	return r.sendMessage(ctx, "create", value)
+	return r.sendMessage(ctx, createCacheOp, c.TenantID, value)
+}
+
+func (r *replicatedCustomerCache) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	return r.sendMessage(ctx, deleteCacheOp, tenantID, []byte(id))
 }
 
-func (r *redisStreamCustomerCache) DeleteByID(ctx context.Context, id string) error {
-	return r.sendMessage(ctx, "delete", id)
+// sendMessage publishes cache operation to replicas, message is published after commit if called within transaction,
+// so replicas never see operation caused by rolled back change. Failed postponed publish is only logged
+func (r *replicatedCustomerCache) sendMessage(ctx context.Context, op string, tenantID string, value []byte) error {
+	registered := transactor.RegisterAfterCommit(ctx, func(ctx context.Context) {
+		if err := r.publish(ctx, op, tenantID, value); err != nil {
+			logging.FromContext(ctx).Errorf("failed to publish %s cache message after commit - %v", op, err)
+		}
+	})
+	if registered {
+		return nil
+	}
+	return r.publish(ctx, op, tenantID, value)
 }
 
-func (r *redisStreamCustomerCache) sendMessage(ctx context.Context, op string, value any) error {
-	return r.client.XAdd(ctx, &redis.XAddArgs{
-		Stream: "customers-stream",
+// xAddCacheOp appends cache operation to customers stream, stream is capped, so only recent operations are kept
+func xAddCacheOp(ctx context.Context, client *redis.Client, op string, tenantID string, value []byte) error {
+	return client.XAdd(ctx, &redis.XAddArgs{
+		Stream: customersStream,
 		MaxLen: customerStreamMaxLen,
 		Approx: true,
 		ID:     "*",
 		Values: map[string]any{
-			"op":    op,
-			"value": value,
+			"op":     op,
+			"tenant": tenantID,
+			"value":  value,
 		},
 	}).Err()
 }
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+type fallbackCustomerCache struct {
+	primary  CustomerCacheRepository
+	fallback CustomerCacheRepository
+}
+
+// NewFallbackCustomerCache wraps primary cache, so calls are served by fallback cache while primary is unreachable
+// or short-circuited by open circuit breaker. Deletes are always applied to fallback too, so it doesn't serve
+// stale customers on the next outage
+func NewFallbackCustomerCache(primary CustomerCacheRepository, fallback CustomerCacheRepository) CustomerCacheRepository {
+	return &fallbackCustomerCache{primary: primary, fallback: fallback}
+}
+
+func (c *fallbackCustomerCache) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	if c.primaryUnavailable() {
+		return c.fallback.FindByID(ctx, tenantID, id)
+	}
+
+	customer, err := c.primary.FindByID(ctx, tenantID, id)
+	if err != nil && isConnectionError(err) {
+		logging.FromContext(ctx).Warnf("primary cache is unreachable, customer %s is read from fallback cache - %v", id, err)
+		return c.fallback.FindByID(ctx, tenantID, id)
+	}
+	return customer, err
+}
+
+func (c *fallbackCustomerCache) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	if err := c.fallback.DeleteByID(ctx, tenantID, id); err != nil {
+		return err
+	}
+
+	if c.primaryUnavailable() {
+		return nil
+	}
+
+	err := c.primary.DeleteByID(ctx, tenantID, id)
+	if err != nil && isConnectionError(err) {
+		logging.FromContext(ctx).Warnf("primary cache is unreachable, customer %s is deleted from fallback cache only - %v", id, err)
+		return nil
+	}
+	return err
+}
+
+func (c *fallbackCustomerCache) Create(ctx context.Context, customer *model.Customer) error {
+	if c.primaryUnavailable() {
+		return c.fallback.Create(ctx, customer)
+	}
+
+	err := c.primary.Create(ctx, customer)
+	if err != nil && isConnectionError(err) {
+		logging.FromContext(ctx).Warnf("primary cache is unreachable, customer %s is cached in fallback cache - %v", customer.ID, err)
+		return c.fallback.Create(ctx, customer)
+	}
+	return err
+}
+
+// primaryUnavailable reports whether primary cache is known to be down without calling it, e.g. its breaker is open
+func (c *fallbackCustomerCache) primaryUnavailable() bool {
+	u, ok := c.primary.(interface{ unavailable() bool })
+	return ok && u.unavailable()
+}
+
+// isConnectionError reports whether cache can't be reached, other errors (e.g. encoding) are not worth falling back
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false // caller gave up, context deadline error satisfies net.Error as well
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrClosed)
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type fallbackTestSuite struct {
+	suite.Suite
+	primaryMock *mocks.CustomerCacheRepository
+	fallback    CustomerCacheRepository
+	cache       CustomerCacheRepository
+	customer    *model.Customer
+	connErr     error
+}
+
+func (s *fallbackTestSuite) SetupTest() {
+	s.primaryMock = mocks.NewCustomerCacheRepository(s.T())
+	s.fallback = NewInMemoryCache()
+	s.cache = NewFallbackCustomerCache(s.primaryMock, s.fallback)
+	s.customer = &model.Customer{ID: "3c9e1f2a-4b5d-4e6f-8a7b-9c0d1e2f3a4b", TenantID: "acme", FirstName: "John", LastName: "Smith"}
+	s.connErr = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func (s *fallbackTestSuite) TestFallback() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("customer is cached in fallback if primary is unreachable")
+	{
+		s.primaryMock.EXPECT().Create(mock.Anything, s.customer).Return(s.connErr).Once()
+
+		err := s.cache.Create(ctx, s.customer)
+		require.NoError(err, "connection error must not be surfaced")
+
+		c, err := s.fallback.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "customer must be cached in fallback")
+	}
+
+	t.Log("customer is read from fallback if primary is unreachable")
+	{
+		s.primaryMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(nil, s.connErr).Once()
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "connection error must not be surfaced")
+		require.Equal(s.customer, c, "customer must be read from fallback")
+	}
+
+	t.Log("customer is deleted from fallback if primary is unreachable")
+	{
+		s.primaryMock.EXPECT().DeleteByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(s.connErr).Once()
+
+		err := s.cache.DeleteByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "connection error must not be surfaced")
+
+		c, err := s.fallback.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Nil(c, "customer must be deleted from fallback")
+	}
+}
+
+func (s *fallbackTestSuite) TestPrimaryAvailable() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("primary serves calls while it is reachable")
+	{
+		s.primaryMock.EXPECT().Create(mock.Anything, s.customer).Return(nil).Once()
+		s.primaryMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(s.customer, nil).Once()
+
+		require.NoError(s.cache.Create(ctx, s.customer), "no error must be raised")
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "customer must be read from primary")
+
+		c, err = s.fallback.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Nil(c, "customer must not be cached in fallback")
+	}
+
+	t.Log("deletes are applied to fallback as well")
+	{
+		require.NoError(s.fallback.Create(ctx, s.customer), "no error must be raised")
+		s.primaryMock.EXPECT().DeleteByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(nil).Once()
+
+		require.NoError(s.cache.DeleteByID(ctx, s.customer.TenantID, s.customer.ID), "no error must be raised")
+
+		c, err := s.fallback.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Nil(c, "stale customer must be deleted from fallback")
+	}
+
+	t.Log("errors other than connection ones are returned")
+	{
+		encodeErr := errors.New("msgpack: unsupported type")
+		s.primaryMock.EXPECT().Create(mock.Anything, s.customer).Return(encodeErr).Once()
+
+		err := s.cache.Create(ctx, s.customer)
+		require.ErrorIs(err, encodeErr, "error must be returned")
+	}
+}
+
+func (s *fallbackTestSuite) TestBreakerOpen() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	breaker := NewCircuitBreakerCustomerCache("redis", s.primaryMock, prometheus.NewRegistry(), &config.CacheBreakerCfg{
+		FailureThreshold: 1,
+		Cooldown:         breakerCooldown * 100,
+	})
+	s.cache = NewFallbackCustomerCache(breaker, s.fallback)
+
+	t.Log("fallback is used while primary breaker is open")
+	{
+		s.primaryMock.EXPECT().Create(mock.Anything, s.customer).Return(s.connErr).Once()
+
+		require.NoError(s.cache.Create(ctx, s.customer), "connection error must not be surfaced")
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "customer must be read from fallback without calling primary")
+	}
+}
+
+func (s *fallbackTestSuite) TestFallbackExpires() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	now := time.Now()
+	ttl := func(model.Importance) time.Duration {
+		return time.Minute
+	}
+	fallback := newInMemoryCache(ttl, func() time.Time {
+		return now
+	})
+	s.cache = NewFallbackCustomerCache(s.primaryMock, fallback)
+
+	s.primaryMock.EXPECT().Create(mock.Anything, s.customer).Return(s.connErr).Once()
+	s.primaryMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(nil, s.connErr).Twice()
+	require.NoError(s.cache.Create(ctx, s.customer), "connection error must not be surfaced")
+
+	t.Log("customer is served from fallback within time to live")
+	{
+		now = now.Add(time.Minute - time.Second)
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "customer must be read from fallback")
+	}
+
+	t.Log("customer expires from fallback after time to live")
+	{
+		now = now.Add(time.Second)
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Nil(c, "expired customer must not be served")
+		require.Empty(fallback.customers, "expired customer must be removed")
+	}
+}
+
+// start fallback test suite
+func TestFallbackTestSuite(t *testing.T) {
+	suite.Run(t, new(fallbackTestSuite))
+}
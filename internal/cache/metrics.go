@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type metricsCustomerCache struct {
+	next             CustomerCacheRepository
+	hits             prometheus.Counter
+	misses           prometheus.Counter
+	populationErrors prometheus.Counter
+}
+
+// NewMetricsCustomerCache wraps cache of customers backend with counters of cache hits, misses and failures to populate cache
+func NewMetricsCustomerCache(backend string, next CustomerCacheRepository, reg prometheus.Registerer) CustomerCacheRepository {
+	labels := prometheus.Labels{"backend": backend}
+
+	c := &metricsCustomerCache{
+		next: next,
+		hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "customers_cache_hits_total",
+			Help:        "Number of customers found in cache",
+			ConstLabels: labels,
+		}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "customers_cache_misses_total",
+			Help:        "Number of customers not found in cache",
+			ConstLabels: labels,
+		}),
+		populationErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "customers_cache_population_errors_total",
+			Help:        "Number of customers which failed to be put to cache",
+			ConstLabels: labels,
+		}),
+	}
+	reg.MustRegister(c.hits, c.misses, c.populationErrors)
+
+	return c
+}
+
+func (c *metricsCustomerCache) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	customer, err := c.next.FindByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer == nil {
+		c.misses.Inc()
+	} else {
+		c.hits.Inc()
+	}
+	return customer, nil
+}
+
+func (c *metricsCustomerCache) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	return c.next.DeleteByID(ctx, tenantID, id)
+}
+
+func (c *metricsCustomerCache) Create(ctx context.Context, customer *model.Customer) error {
+	if err := c.next.Create(ctx, customer); err != nil {
+		c.populationErrors.Inc()
+		return err
+	}
+	return nil
+}
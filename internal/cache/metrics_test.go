@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type metricsTestSuite struct {
+	suite.Suite
+	cacheMock *mocks.CustomerCacheRepository
+	registry  *prometheus.Registry
+	cache     CustomerCacheRepository
+	customer  *model.Customer
+}
+
+func (s *metricsTestSuite) SetupTest() {
+	s.cacheMock = mocks.NewCustomerCacheRepository(s.T())
+	s.registry = prometheus.NewRegistry()
+	s.cache = NewMetricsCustomerCache("postgres", s.cacheMock, s.registry)
+	s.customer = &model.Customer{ID: "4e2a9c7b-1f3d-4b5e-8a6c-0d9e7f1a2b3c", TenantID: "acme", FirstName: "John", LastName: "Smith"}
+}
+
+func (s *metricsTestSuite) counter(name string) float64 {
+	families, err := s.registry.Gather()
+	s.Require().NoError(err, "failed to gather metrics")
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+
+		m := f.GetMetric()[0]
+		s.Require().Equal("backend", m.GetLabel()[0].GetName(), "counter must be labeled by backend")
+		s.Require().Equal("postgres", m.GetLabel()[0].GetValue(), "counter must be labeled by backend")
+		return m.GetCounter().GetValue()
+	}
+
+	s.Require().Failf("metric is not registered", "metric %s must be registered", name)
+	return 0
+}
+
+func (s *metricsTestSuite) TestHitsAndMisses() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("hit increments hit counter")
+	{
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(s.customer, nil).Once()
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "cached customer must be returned")
+		require.Equal(float64(1), s.counter("customers_cache_hits_total"), "hit must be counted")
+		require.Equal(float64(0), s.counter("customers_cache_misses_total"), "miss must not be counted")
+	}
+
+	t.Log("miss increments miss counter")
+	{
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(nil, nil).Once()
+
+		c, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Nil(c, "miss must be reported")
+		require.Equal(float64(1), s.counter("customers_cache_hits_total"), "hit must not be counted")
+		require.Equal(float64(1), s.counter("customers_cache_misses_total"), "miss must be counted")
+	}
+
+	t.Log("failed read is neither hit nor miss")
+	{
+		s.cacheMock.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).Return(nil, errors.New("i/o timeout")).Once()
+
+		_, err := s.cache.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.Error(err, "error must be returned")
+		require.Equal(float64(1), s.counter("customers_cache_hits_total"), "hit must not be counted")
+		require.Equal(float64(1), s.counter("customers_cache_misses_total"), "miss must not be counted")
+	}
+}
+
+func (s *metricsTestSuite) TestPopulationErrors() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("failure to populate cache is counted")
+	{
+		s.cacheMock.EXPECT().Create(mock.Anything, s.customer).Return(nil).Once()
+		s.cacheMock.EXPECT().Create(mock.Anything, s.customer).Return(errors.New("i/o timeout")).Once()
+
+		require.NoError(s.cache.Create(ctx, s.customer), "no error must be raised")
+		require.Error(s.cache.Create(ctx, s.customer), "error must be returned")
+		require.Equal(float64(1), s.counter("customers_cache_population_errors_total"), "failure must be counted")
+	}
+}
+
+// start metrics test suite
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(metricsTestSuite))
+}
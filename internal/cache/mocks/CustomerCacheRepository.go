@@ -60,13 +60,13 @@ func (_c *CustomerCacheRepository_Create_Call) Return(_a0 error) *CustomerCacheR
 	return _c
 }
 
-// DeleteByID provides a mock function with given fields: _a0, _a1
-func (_m *CustomerCacheRepository) DeleteByID(_a0 context.Context, _a1 string) error {
-	ret := _m.Called(_a0, _a1)
+// DeleteByID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerCacheRepository) DeleteByID(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -82,13 +82,14 @@ type CustomerCacheRepository_DeleteByID_Call struct {
 // DeleteByID is a helper method to define mock.On call
 //  - _a0 context.Context
 //  - _a1 string
-func (_e *CustomerCacheRepository_Expecter) DeleteByID(_a0 interface{}, _a1 interface{}) *CustomerCacheRepository_DeleteByID_Call {
-	return &CustomerCacheRepository_DeleteByID_Call{Call: _e.mock.On("DeleteByID", _a0, _a1)}
+//  - _a2 string
+func (_e *CustomerCacheRepository_Expecter) DeleteByID(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerCacheRepository_DeleteByID_Call {
+	return &CustomerCacheRepository_DeleteByID_Call{Call: _e.mock.On("DeleteByID", _a0, _a1, _a2)}
 }
 
-func (_c *CustomerCacheRepository_DeleteByID_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerCacheRepository_DeleteByID_Call {
+func (_c *CustomerCacheRepository_DeleteByID_Call) Run(run func(_a0 context.Context, _a1 string, _a2 string)) *CustomerCacheRepository_DeleteByID_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
@@ -98,13 +99,13 @@ func (_c *CustomerCacheRepository_DeleteByID_Call) Return(_a0 error) *CustomerCa
 	return _c
 }
 
-// FindByID provides a mock function with given fields: _a0, _a1
-func (_m *CustomerCacheRepository) FindByID(_a0 context.Context, _a1 string) (*model.Customer, error) {
-	ret := _m.Called(_a0, _a1)
+// FindByID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerCacheRepository) FindByID(_a0 context.Context, _a1 string, _a2 string) (*model.Customer, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *model.Customer
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Customer); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Customer); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.Customer)
@@ -112,8 +113,8 @@ func (_m *CustomerCacheRepository) FindByID(_a0 context.Context, _a1 string) (*m
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -129,13 +130,14 @@ type CustomerCacheRepository_FindByID_Call struct {
 // FindByID is a helper method to define mock.On call
 //  - _a0 context.Context
 //  - _a1 string
-func (_e *CustomerCacheRepository_Expecter) FindByID(_a0 interface{}, _a1 interface{}) *CustomerCacheRepository_FindByID_Call {
-	return &CustomerCacheRepository_FindByID_Call{Call: _e.mock.On("FindByID", _a0, _a1)}
+//  - _a2 string
+func (_e *CustomerCacheRepository_Expecter) FindByID(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerCacheRepository_FindByID_Call {
+	return &CustomerCacheRepository_FindByID_Call{Call: _e.mock.On("FindByID", _a0, _a1, _a2)}
 }
 
-func (_c *CustomerCacheRepository_FindByID_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerCacheRepository_FindByID_Call {
+func (_c *CustomerCacheRepository_FindByID_Call) Run(run func(_a0 context.Context, _a1 string, _a2 string)) *CustomerCacheRepository_FindByID_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/redact"
+)
+
+const (
+	cacheOpHeader          = "Cache-Op"
+	cacheTenantHeader      = "Cache-Tenant"
+	subscribeRetryInterval = time.Second
+)
+
+// NewJetStreamCustomerCache builds customer cache replicated over JetStream subject, so replication doesn't need redis
+func NewJetStreamCustomerCache(js nats.JetStreamContext, subject string, primary CustomerCacheRepository) CustomerCacheRepository {
+	return &replicatedCustomerCache{
+		publish: func(ctx context.Context, op string, tenantID string, value []byte) error {
+			return publishCacheOp(ctx, js, subject, op, tenantID, value)
+		},
+		CustomerCacheRepository: primary,
+	}
+}
+
+type jetStreamCustomerCachePurger struct {
+	js      nats.JetStreamContext
+	subject string
+}
+
+// NewJetStreamCustomerCachePurger builds purger broadcasting purge over JetStream subject, so readers of every instance
+// purge their local in-memory caches. Broadcast removes nothing by itself, so zero is returned
+func NewJetStreamCustomerCachePurger(js nats.JetStreamContext, subject string) CustomerCachePurger {
+	return &jetStreamCustomerCachePurger{js: js, subject: subject}
+}
+
+// Purge publishes purge operation to JetStream subject
+func (p *jetStreamCustomerCachePurger) Purge(ctx context.Context) (int, error) {
+	if err := publishCacheOp(ctx, p.js, p.subject, purgeCacheOp, "", nil); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// publishCacheOp publishes cache operation to JetStream subject, operation and tenant are sent in headers
+func publishCacheOp(ctx context.Context, js nats.JetStreamContext, subject string, op string, tenantID string, value []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = value
+	msg.Header.Set(cacheOpHeader, op)
+	msg.Header.Set(cacheTenantHeader, tenantID)
+
+	ctx, cancel := context.WithTimeout(ctx, cacheWriteTimeout)
+	defer cancel()
+
+	if _, err := js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("jetstream: failed to publish %s cache operation - %w", op, err)
+	}
+	return nil
+}
+
+// JetStreamCustomerStreamReader reads cache operations published to JetStream subject and applies them to the underlying
+// cache. Each instance reads with own ephemeral consumer, which delivers operations published since reader is subscribed
+type JetStreamCustomerStreamReader struct {
+	js       nats.JetStreamContext
+	subject  string
+	cache    CustomerCacheRepository
+	purgers  []CustomerCachePurger
+	cfg      *config.CustomersStreamCfg
+	lagGauge prometheus.Gauge
+	mu       sync.RWMutex
+	sub      *nats.Subscription
+}
+
+// NewJetStreamCustomerStreamReader builds new JetStreamCustomerStreamReader and registers its metrics,
+// purgers are local caches of instance which are purged once purge is broadcast over subject
+func NewJetStreamCustomerStreamReader(
+	js nats.JetStreamContext,
+	subject string,
+	customerCache CustomerCacheRepository,
+	reg prometheus.Registerer,
+	cfg *config.CustomersStreamCfg,
+	purgers ...CustomerCachePurger,
+) *JetStreamCustomerStreamReader {
+	lagGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "customers_stream_lag",
+		Help: "Number of customers stream messages which are not processed by reader yet",
+	})
+	reg.MustRegister(lagGauge)
+
+	return &JetStreamCustomerStreamReader{
+		js:       js,
+		subject:  subject,
+		cache:    customerCache,
+		purgers:  purgers,
+		cfg:      cfg,
+		lagGauge: lagGauge,
+	}
+}
+
+// Listen reads new messages from subject until context is cancelled, subscription is retried until it succeeds
+func (r *JetStreamCustomerStreamReader) Listen(ctx context.Context) {
+	logrus.Infof("starting to read customers jetstream subject %s", r.subject)
+
+	sub, err := r.subscribe(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			logrus.Errorf("failed to unsubscribe from customers jetstream subject - %v", err)
+		}
+	}()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, nats.ErrBadSubscription) || errors.Is(err, nats.ErrConnectionClosed) {
+				return
+			}
+			logrus.Errorf("error occurred on reading message from jetstream - %v", err)
+			continue
+		}
+
+		if err := r.process(ctx, msg); err != nil {
+			logrus.Errorf("error occurred on jetstream message processing - %s", redact.Text(err.Error()))
+		}
+	}
+}
+
+// MonitorLag periodically calculates reader lag until context is cancelled
+func (r *JetStreamCustomerStreamReader) MonitorLag(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.LagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Lag(ctx); err != nil {
+				logrus.Errorf("failed to calculate customers stream lag - %v", err)
+			}
+		}
+	}
+}
+
+// Lag calculates number of messages which are published to subject, but not processed by reader yet and updates lag gauge.
+// Messages not delivered to consumer yet are counted along with delivered ones buffered by subscription
+func (r *JetStreamCustomerStreamReader) Lag(context.Context) (int64, error) {
+	sub := r.subscription()
+	if sub == nil {
+		return 0, errors.New("reader is not subscribed yet")
+	}
+
+	info, err := sub.ConsumerInfo()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read customers jetstream consumer info - %w", err)
+	}
+
+	buffered, _, err := sub.Pending()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read buffered customers jetstream messages - %w", err)
+	}
+
+	lag := int64(info.NumPending) + int64(buffered)
+	r.lagGauge.Set(float64(lag))
+	if lag > r.cfg.LagWarnThreshold {
+		logrus.Warnf("customers stream reader is falling behind - %d messages are not processed, threshold is %d", lag, r.cfg.LagWarnThreshold)
+	}
+	return lag, nil
+}
+
+func (r *JetStreamCustomerStreamReader) subscribe(ctx context.Context) (*nats.Subscription, error) {
+	for {
+		sub, err := r.js.SubscribeSync(r.subject, nats.DeliverNew(), nats.AckNone())
+		if err == nil {
+			r.mu.Lock()
+			r.sub = sub
+			r.mu.Unlock()
+			return sub, nil
+		}
+		logrus.Errorf("failed to subscribe to customers jetstream subject %s, retrying - %v", r.subject, err)
+
+		timer := time.NewTimer(subscribeRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *JetStreamCustomerStreamReader) subscription() *nats.Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sub
+}
+
+func (r *JetStreamCustomerStreamReader) process(ctx context.Context, msg *nats.Msg) error {
+	op := msg.Header.Get(cacheOpHeader)
+	if op == "" {
+		return errors.New("message has incorrect format - op header is missing, skipped")
+	}
+
+	tenantID := msg.Header.Get(cacheTenantHeader)
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+
+	return applyCacheOp(ctx, r.cache, r.purgers, op, tenantID, msg.Data)
+}
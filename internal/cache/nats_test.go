@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	natsContainerName = "nats-cache-test-customers"
+	natsPort          = "14223"
+	natsTestStream    = "CUSTOMERS_CACHE_TEST"
+	natsTestSubject   = "customers.cache.test"
+)
+
+type natsCacheTestSuite struct {
+	suite.Suite
+	dockerPool *dockertest.Pool
+	nats       *dockertest.Resource
+	conn       *nats.Conn
+	js         nats.JetStreamContext
+}
+
+func (s *natsCacheTestSuite) SetupSuite() {
+	t := s.T()
+	assert := s.Require()
+
+	dockerPool, err := dockertest.NewPool("")
+	assert.NoError(err, "failed to create pool")
+
+	t.Log("sending ping to docker...")
+	err = dockerPool.Client.Ping()
+	assert.NoError(err, "failed to connect to docker")
+
+	s.dockerPool = dockerPool
+
+	t.Log("starting nats with jetstream...")
+	natsResource, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       natsContainerName,
+		Repository: "nats",
+		Tag:        "2.9",
+		Cmd:        []string{"-js"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"4222/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", natsPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start nats")
+	s.nats = natsResource
+
+	t.Log("connecting to nats...")
+	err = dockerPool.Retry(func() error {
+		conn, err := nats.Connect(fmt.Sprintf("nats://localhost:%s", natsPort))
+		if err != nil {
+			return err
+		}
+
+		js, err := conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+
+		s.conn, s.js = conn, js
+		return nil
+	})
+	assert.NoError(err, "failed to establish connection to nats")
+
+	_, err = s.js.AddStream(&nats.StreamConfig{Name: natsTestStream, Subjects: []string{natsTestSubject}, Storage: nats.MemoryStorage})
+	assert.NoError(err, "failed to create stream")
+}
+
+func (s *natsCacheTestSuite) TearDownSuite() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+
+	if s.nats != nil {
+		if err := s.dockerPool.Purge(s.nats); err != nil {
+			s.T().Logf("failed to purge nats container - %v", err)
+		}
+	}
+}
+
+func (s *natsCacheTestSuite) TestJetStreamReplication() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	inMemoryCache := NewInMemoryCache()
+	fallbackCache := NewInMemoryCache()
+	replicatedCache := NewJetStreamCustomerCache(s.js, natsTestSubject, inMemoryCache)
+	reader := NewJetStreamCustomerStreamReader(
+		s.js,
+		natsTestSubject,
+		inMemoryCache,
+		prometheus.NewRegistry(),
+		&config.CustomersStreamCfg{LagWarnThreshold: 1, LagCheckInterval: time.Second},
+		inMemoryCache, fallbackCache,
+	)
+	go reader.Listen(ctx)
+	require.Eventually(func() bool { return reader.subscription() != nil }, testCtxTimeout, 10*time.Millisecond, "reader must subscribe")
+
+	customer := &model.Customer{ID: "7c0a3c7b-4a52-4d0e-9a4e-3f1f0b7f2d11", TenantID: "acme", FirstName: "John", LastName: "Norman", Email: "john@somemail.com"}
+	cached := func(c CustomerCacheRepository) bool {
+		found, _ := c.FindByID(ctx, customer.TenantID, customer.ID)
+		return found != nil
+	}
+
+	t.Log("created customer is replicated to in-memory cache")
+	{
+		require.NoError(replicatedCache.Create(ctx, customer), "failed to publish customer")
+		require.Eventually(func() bool { return cached(inMemoryCache) }, testCtxTimeout, 10*time.Millisecond, "customer must be replicated")
+
+		lag, err := reader.Lag(ctx)
+		require.NoError(err, "failed to calculate lag")
+		require.Zero(lag, "all messages are consumed, lag must be zero")
+	}
+
+	t.Log("deleted customer is removed from in-memory cache")
+	{
+		require.NoError(replicatedCache.DeleteByID(ctx, customer.TenantID, customer.ID), "failed to publish deletion")
+		require.Eventually(func() bool { return !cached(inMemoryCache) }, testCtxTimeout, 10*time.Millisecond, "customer must be removed")
+	}
+
+	t.Log("broadcast purge removes nothing by itself, but purges local caches once consumed")
+	{
+		require.NoError(inMemoryCache.Create(ctx, customer), "failed to cache customer")
+		require.NoError(fallbackCache.Create(ctx, customer), "failed to cache customer")
+
+		removed, err := NewJetStreamCustomerCachePurger(s.js, natsTestSubject).Purge(ctx)
+		require.NoError(err, "failed to broadcast purge")
+		require.Zero(removed, "broadcast must not remove entries")
+
+		require.Eventually(func() bool {
+			return !cached(inMemoryCache) && !cached(fallbackCache)
+		}, testCtxTimeout, 10*time.Millisecond, "customer must be purged from local caches")
+	}
+}
+
+// start nats cache test suite
+func TestNatsCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(natsCacheTestSuite))
+}
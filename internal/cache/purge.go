@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v9"
+)
+
+const (
+	customerKeysPattern = "customer:*"
+	purgeBatchSize      = 500
+	createCacheOp       = "create"
+	deleteCacheOp       = "delete"
+	purgeCacheOp        = "purge"
+)
+
+// CustomerCachePurger represents behavior of cache all customers can be removed from at once
+type CustomerCachePurger interface {
+	Purge(context.Context) (int, error)
+}
+
+// PurgeableCustomerCache is customer cache which can be purged
+type PurgeableCustomerCache interface {
+	CustomerCacheRepository
+	CustomerCachePurger
+}
+
+type redisCustomerCachePurger struct {
+	client *redis.Client
+}
+
+// NewRedisCustomerCachePurger builds purger removing cached customers from redis regardless of serialization,
+// keys are found with SCAN, so redis isn't blocked and keys outside of customers namespace are left untouched
+func NewRedisCustomerCachePurger(client *redis.Client) CustomerCachePurger {
+	return &redisCustomerCachePurger{client: client}
+}
+
+type redisStreamCustomerCachePurger struct {
+	client *redis.Client
+}
+
+// NewRedisStreamCustomerCachePurger builds purger broadcasting purge over customers stream, so stream readers
+// of every instance purge their local in-memory caches. Broadcast removes nothing by itself, so zero is returned
+func NewRedisStreamCustomerCachePurger(client *redis.Client) CustomerCachePurger {
+	return &redisStreamCustomerCachePurger{client: client}
+}
+
+// Purge publishes purge operation to customers stream
+func (p *redisStreamCustomerCachePurger) Purge(ctx context.Context) (int, error) {
+	if err := xAddCacheOp(ctx, p.client, purgeCacheOp, "", nil); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// Purge removes customer keys in batches and returns number of removed keys, keys removed before failure are counted too
+func (p *redisCustomerCachePurger) Purge(ctx context.Context) (int, error) {
+	removed := 0
+	keys := make([]string, 0, purgeBatchSize)
+	del := func() error {
+		n, err := p.client.Del(ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		removed += int(n)
+		keys = keys[:0]
+		return nil
+	}
+
+	iter := p.client.Scan(ctx, 0, customerKeysPattern, purgeBatchSize).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == purgeBatchSize {
+			if err := del(); err != nil {
+				return removed, err
+			}
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+
+	if len(keys) > 0 {
+		if err := del(); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/umalmyha/customers/internal/auth"
+)
+
+type redisTokenRevoker struct {
+	client     *redis.Client
+	timeToLive time.Duration
+}
+
+// NewRedisTokenRevoker builds redis TokenRevoker, ttl is access token time to live
+func NewRedisTokenRevoker(client *redis.Client, ttl time.Duration) auth.TokenRevoker {
+	return &redisTokenRevoker{client: client, timeToLive: ttl}
+}
+
+func (r *redisTokenRevoker) Revoke(ctx context.Context, claims auth.JwtClaims) error {
+	if claims.ExpiresAt == nil {
+		return nil
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil // already expired
+	}
+
+	return r.client.Set(ctx, r.tokenKey(claims.ID), 1, ttl).Err()
+}
+
+func (r *redisTokenRevoker) RevokeSubject(ctx context.Context, subject string, at time.Time) error {
+	ttl := time.Until(at.Add(r.timeToLive))
+	if ttl <= 0 {
+		return nil // tokens issued before are already expired
+	}
+
+	return r.client.Set(ctx, r.subjectKey(subject), at.Unix(), ttl).Err()
+}
+
+func (r *redisTokenRevoker) IsRevoked(ctx context.Context, claims auth.JwtClaims) (bool, error) {
+	res, err := r.client.MGet(ctx, r.tokenKey(claims.ID), r.subjectKey(claims.Subject)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if res[0] != nil {
+		return true, nil
+	}
+
+	if res[1] == nil || claims.IssuedAt == nil {
+		return false, nil
+	}
+
+	revokedAt, err := strconv.ParseInt(fmt.Sprint(res[1]), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("malformed subject revocation entry - %w", err)
+	}
+
+	return claims.IssuedAt.Unix() <= revokedAt, nil
+}
+
+func (r *redisTokenRevoker) tokenKey(id string) string {
+	return fmt.Sprintf("revoked:jti:%s", id)
+}
+
+func (r *redisTokenRevoker) subjectKey(subject string) string {
+	return fmt.Sprintf("revoked:sub:%s", subject)
+}
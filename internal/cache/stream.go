@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/redact"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	readStreamMessagesMaxCount = 10
+	readStreamBlockTime        = 0
+	cacheWriteTimeout          = 5 * time.Second
+	streamStartID              = "0-0"
+)
+
+// CustomerStreamListener represents behavior of reader applying replicated cache operations to local cache
+type CustomerStreamListener interface {
+	Listen(context.Context)
+	MonitorLag(context.Context)
+}
+
+// RedisCustomerStreamReader reads customers stream and applies changes to the underlying cache
+type RedisCustomerStreamReader struct {
+	client   *redis.Client
+	cache    CustomerCacheRepository
+	purgers  []CustomerCachePurger
+	cfg      *config.CustomersStreamCfg
+	lagGauge prometheus.Gauge
+	mu       sync.RWMutex
+	lastID   string
+}
+
+// NewRedisCustomerStreamReader builds new RedisCustomerStreamReader and registers its metrics,
+// purgers are local caches of instance which are purged once purge is broadcast over stream
+func NewRedisCustomerStreamReader(
+	client *redis.Client,
+	customerCache CustomerCacheRepository,
+	reg prometheus.Registerer,
+	cfg *config.CustomersStreamCfg,
+	purgers ...CustomerCachePurger,
+) *RedisCustomerStreamReader {
+	lagGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "customers_stream_lag",
+		Help: "Number of customers stream messages which are not processed by reader yet",
+	})
+	reg.MustRegister(lagGauge)
+
+	return &RedisCustomerStreamReader{
+		client:   client,
+		cache:    customerCache,
+		purgers:  purgers,
+		cfg:      cfg,
+		lagGauge: lagGauge,
+		lastID:   streamStartID,
+	}
+}
+
+// Listen reads new messages from customers stream until context is cancelled
+func (r *RedisCustomerStreamReader) Listen(ctx context.Context) {
+	logrus.Info("starting to read customers redis stream")
+
+	if err := r.seekToEnd(ctx); err != nil {
+		logrus.Errorf("failed to find last message of customers stream, reading from the beginning - %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if err := r.read(ctx); err != nil {
+				logrus.Errorf("error occurred on reading message from stream - %v", err)
+			}
+		}
+	}
+}
+
+// MonitorLag periodically calculates reader lag until context is cancelled
+func (r *RedisCustomerStreamReader) MonitorLag(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.LagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Lag(ctx); err != nil {
+				logrus.Errorf("failed to calculate customers stream lag - %v", err)
+			}
+		}
+	}
+}
+
+// Lag calculates number of messages which are added to stream, but not processed by reader yet and updates lag gauge
+func (r *RedisCustomerStreamReader) Lag(ctx context.Context) (int64, error) {
+	processedID := r.processedID()
+
+	length, err := r.client.XLen(ctx, customersStream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read customers stream length - %w", err)
+	}
+
+	var lag int64
+	if length > 0 {
+		info, err := r.client.XInfoStream(ctx, customersStream).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read customers stream info - %w", err)
+		}
+
+		if info.LastGeneratedID != processedID {
+			pending, err := r.client.XRangeN(ctx, customersStream, "("+processedID, "+", length).Result()
+			if err != nil {
+				return 0, fmt.Errorf("failed to read pending customers stream messages - %w", err)
+			}
+			lag = int64(len(pending))
+		}
+	}
+
+	r.lagGauge.Set(float64(lag))
+	if lag > r.cfg.LagWarnThreshold {
+		logrus.Warnf("customers stream reader is falling behind - %d messages are not processed, threshold is %d", lag, r.cfg.LagWarnThreshold)
+	}
+	return lag, nil
+}
+
+func (r *RedisCustomerStreamReader) seekToEnd(ctx context.Context) error {
+	msgs, err := r.client.XRevRangeN(ctx, customersStream, "+", "-", 1).Result()
+	if err != nil {
+		return err
+	}
+
+	if len(msgs) > 0 {
+		r.setProcessedID(msgs[0].ID)
+	}
+	return nil
+}
+
+func (r *RedisCustomerStreamReader) read(ctx context.Context) error {
+	lastID := r.processedID()
+	logrus.Infof("waiting for new messages starting from %s", lastID)
+
+	streams, err := r.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{customersStream, lastID},
+		Count:   readStreamMessagesMaxCount,
+		Block:   readStreamBlockTime,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	logrus.Info("messages were received")
+
+	for _, stream := range streams {
+		logrus.Info("number of message received = ", len(stream.Messages))
+
+		for _, m := range stream.Messages {
+			r.setProcessedID(m.ID)
+			if err := r.process(ctx, m); err != nil {
+				logrus.Errorf("error occurred on message %s processing - %s", m.ID, redact.Text(err.Error()))
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RedisCustomerStreamReader) process(ctx context.Context, m redis.XMessage) error {
+	op, ok := m.Values["op"].(string)
+	if !ok || op == "" {
+		return errors.New("message has incorrect format - op field is missing, skipped")
+	}
+
+	value, ok := m.Values["value"].(string)
+	if !ok {
+		return errors.New("message has incorrect format - value field is missing, skipped")
+	}
+
+	tenantID, ok := m.Values["tenant"].(string)
+	if !ok || tenantID == "" { // messages produced before tenants were introduced
+		tenantID = tenant.DefaultID
+	}
+
+	return applyCacheOp(ctx, r.cache, r.purgers, op, tenantID, []byte(value))
+}
+
+// applyCacheOp applies replicated cache operation to local cache, purge is applied to all local caches of instance
+func applyCacheOp(ctx context.Context, customerCache CustomerCacheRepository, purgers []CustomerCachePurger, op string, tenantID string, value []byte) error {
+	logrus.Infof("%s operation is requested", op)
+
+	writeCtx, cancel := context.WithTimeout(ctx, cacheWriteTimeout)
+	defer cancel()
+
+	switch op {
+	case createCacheOp:
+		var c model.Customer
+		if err := msgpack.Unmarshal(value, &c); err != nil {
+			return fmt.Errorf("failed to deserialize customer - %w", err)
+		}
+
+		if err := customerCache.Create(writeCtx, &c); err != nil {
+			return fmt.Errorf("failed to create customer entry in cache - %w", err)
+		}
+	case deleteCacheOp:
+		if err := customerCache.DeleteByID(writeCtx, tenantID, string(value)); err != nil {
+			return fmt.Errorf("failed to delete customer entry from cache - %w", err)
+		}
+	case purgeCacheOp:
+		removed := 0
+		for _, p := range purgers {
+			n, err := p.Purge(writeCtx)
+			removed += n
+			if err != nil {
+				return fmt.Errorf("failed to purge local cache, %d entries are removed - %w", removed, err)
+			}
+		}
+		logrus.Infof("local customer caches are purged, %d entries are removed", removed)
+	}
+
+	return nil
+}
+
+func (r *RedisCustomerStreamReader) processedID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastID
+}
+
+func (r *RedisCustomerStreamReader) setProcessedID(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID = id
+}
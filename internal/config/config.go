@@ -2,10 +2,14 @@ package config
 
 import (
 	"crypto/ed25519"
+	"errors"
 	"fmt"
+	"net"
+	"net/mail"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -14,37 +18,373 @@ import (
 
 const jwtSigningAlgorithmEd25519 = "EdDSA"
 
+const signedURLMinKeyLength = 32
+
+// RefreshTokenExceedStrategy defines what happens with user refresh tokens when max count is reached
+type RefreshTokenExceedStrategy string
+
+const (
+	// RefreshTokenExceedDeleteAll removes all user refresh tokens, so user is logged out everywhere
+	RefreshTokenExceedDeleteAll RefreshTokenExceedStrategy = "delete-all"
+	// RefreshTokenExceedEvictOldest removes only the oldest user refresh tokens to stay under the limit
+	RefreshTokenExceedEvictOldest RefreshTokenExceedStrategy = "evict-oldest"
+)
+
+// CustomersBackend defines datastore customers api version is served from
+type CustomersBackend string
+
+const (
+	// CustomersBackendPostgres serves customers from postgres
+	CustomersBackendPostgres CustomersBackend = "postgres"
+	// CustomersBackendMongo serves customers from mongo
+	CustomersBackendMongo CustomersBackend = "mongo"
+)
+
+// EventBackend defines broker customer events are relayed to for consumers outside of service,
+// events are appended to redis stream consumed within service regardless of backend
+type EventBackend string
+
+const (
+	// EventBackendRedis keeps customer events in redis stream only
+	EventBackendRedis EventBackend = "redis"
+	// EventBackendKafka relays customer events to kafka topic
+	EventBackendKafka EventBackend = "kafka"
+	// EventBackendNats relays customer events to NATS JetStream, in-memory customer cache is replicated over JetStream too
+	EventBackendNats EventBackend = "nats"
+)
+
+// EmailUniqueness defines scope customer email must be unique within
+type EmailUniqueness string
+
+const (
+	// EmailUniquenessTenant allows the same email for customers of different tenants
+	EmailUniquenessTenant EmailUniqueness = "tenant"
+	// EmailUniquenessGlobal requires email to be unique across all tenants
+	EmailUniquenessGlobal EmailUniqueness = "global"
+)
+
+// FingerprintFormat defines format of fingerprint clients bind refresh tokens to
+type FingerprintFormat string
+
+const (
+	// FingerprintFormatAny accepts any fingerprint within max length
+	FingerprintFormatAny FingerprintFormat = "any"
+	// FingerprintFormatUUID accepts only uuid fingerprints
+	FingerprintFormatUUID FingerprintFormat = "uuid"
+)
+
+// FingerprintBinding defines how strictly refresh token is bound to fingerprint it was issued for
+type FingerprintBinding string
+
+const (
+	// FingerprintBindingStrict rejects refresh with fingerprint other than token was issued for
+	FingerprintBindingStrict FingerprintBinding = "strict"
+	// FingerprintBindingLenient logs fingerprint mismatch, but refreshes token bound to the new fingerprint
+	FingerprintBindingLenient FingerprintBinding = "lenient"
+)
+
+// CacheSerialization defines format customers are encoded with in redis cache
+type CacheSerialization string
+
+const (
+	// CacheSerializationMsgpack encodes cached customers with msgpack
+	CacheSerializationMsgpack CacheSerialization = "msgpack"
+	// CacheSerializationProto encodes cached customers as proto CustomerResponse
+	CacheSerializationProto CacheSerialization = "proto"
+)
+
+// CachePopulationFailure defines what happens with read request when customer found in datastore can't be cached
+type CachePopulationFailure string
+
+const (
+	// CachePopulationFailureFail fails read request
+	CachePopulationFailureFail CachePopulationFailure = "fail"
+	// CachePopulationFailureIgnore logs failure and returns customer read from datastore
+	CachePopulationFailureIgnore CachePopulationFailure = "ignore"
+)
+
 // JwtCfg contains config for jwt
 type JwtCfg struct {
 	SigningMethod jwt.SigningMethod
 	Issuer        string             `env:"AUTH_JWT_ISSUER" envDefault:"customers-api"`
+	Audience      string             `env:"AUTH_JWT_AUDIENCE" envDefault:""`
 	TimeToLive    time.Duration      `env:"AUTH_JWT_TIME_TO_LIVE" envDefault:"10m"`
+	MinTimeToLive time.Duration      `env:"AUTH_JWT_MIN_TIME_TO_LIVE" envDefault:"1m"`
+	MaxTimeToLive time.Duration      `env:"AUTH_JWT_MAX_TIME_TO_LIVE" envDefault:"24h"`
 	PrivateKey    ed25519.PrivateKey `env:"AUTH_JWT_PRIVATE_KEY_FILE"`
 	PublicKey     ed25519.PublicKey  `env:"AUTH_JWT_PUBLIC_KEY_FILE"`
 }
 
 // RefreshTokenCfg contains config for refresh token
 type RefreshTokenCfg struct {
-	MaxCount   int           `env:"AUTH_REFRESH_TOKEN_MAX_COUNT" envDefault:"5"`
-	TimeToLive time.Duration `env:"AUTH_REFRESH_TOKEN_TIME_TO_LIVE" envDefault:"720h"`
+	MaxCount           int                        `env:"AUTH_REFRESH_TOKEN_MAX_COUNT" envDefault:"5"`
+	TimeToLive         time.Duration              `env:"AUTH_REFRESH_TOKEN_TIME_TO_LIVE" envDefault:"720h"`
+	MinTimeToLive      time.Duration              `env:"AUTH_REFRESH_TOKEN_MIN_TIME_TO_LIVE" envDefault:"1h"`
+	MaxTimeToLive      time.Duration              `env:"AUTH_REFRESH_TOKEN_MAX_TIME_TO_LIVE" envDefault:"2160h"`
+	ExceedStrategy     RefreshTokenExceedStrategy `env:"AUTH_REFRESH_TOKEN_EXCEED_STRATEGY" envDefault:"delete-all"`
+	FingerprintFormat  FingerprintFormat          `env:"AUTH_REFRESH_TOKEN_FINGERPRINT_FORMAT" envDefault:"any"`
+	FingerprintBinding FingerprintBinding         `env:"AUTH_REFRESH_TOKEN_FINGERPRINT_BINDING" envDefault:"strict"`
+	MinRotationAge     time.Duration              `env:"AUTH_REFRESH_TOKEN_MIN_ROTATION_AGE" envDefault:"0s"` // younger tokens are revoked instead of rotation, zero disables guard
+}
+
+// SignupCfg contains config for public signup, accounts can't be registered by users themselves if it is disabled
+type SignupCfg struct {
+	Enabled bool `env:"SIGNUP_ENABLED" envDefault:"true"`
 }
 
 // RedisCfg contains config for redis
 type RedisCfg struct {
-	Addr       string `env:"REDIS_ADDR"`
-	Password   string `env:"REDIS_PASSWORD"`
-	DB         int    `env:"REDIS_DB" envDefault:"0"`
-	MaxRetries int    `env:"REDIS_MAX_RETRIES" envDefault:"3"`
-	PoolSize   int    `env:"REDIS_POOL_SIZE" envDefault:"50"`
+	Addr          string             `env:"REDIS_ADDR"`
+	Username      string             `env:"REDIS_USERNAME" envDefault:""` // ACL user of redis 6+, default user is used if empty
+	Password      string             `env:"REDIS_PASSWORD"`
+	DB            int                `env:"REDIS_DB" envDefault:"0"`
+	MaxRetries    int                `env:"REDIS_MAX_RETRIES" envDefault:"3"`
+	PoolSize      int                `env:"REDIS_POOL_SIZE" envDefault:"50"`
+	Serialization CacheSerialization `env:"REDIS_CACHE_SERIALIZATION" envDefault:"msgpack"`
+	Fallback      bool               `env:"REDIS_CACHE_FALLBACK" envDefault:"false"` // in-memory cache is used while redis is unreachable
+	TLS           TLSCfg             `envPrefix:"REDIS_"`
+	// PopulationFailure defines whether failure to cache customer read from datastore fails the read
+	PopulationFailure CachePopulationFailure `env:"REDIS_CACHE_POPULATION_FAILURE" envDefault:"fail"`
+}
+
+// MongoCfg contains config for mongo, credentials and auth options are passed via connection string
+type MongoCfg struct {
+	ConnString string `env:"MONGO_URL"`
+	TLS        TLSCfg `envPrefix:"MONGO_"`
+}
+
+// TLSCfg contains config for TLS connection to datastore, system root CAs are used if CA file is empty
+type TLSCfg struct {
+	Enabled bool   `env:"TLS_ENABLED" envDefault:"false"`
+	CAFile  string `env:"TLS_CA_FILE" envDefault:""`
+}
+
+// CacheBreakerCfg contains config for circuit breaker around customers cache, zero failure threshold disables it
+type CacheBreakerCfg struct {
+	FailureThreshold uint32        `env:"REDIS_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	Cooldown         time.Duration `env:"REDIS_BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+// ElasticsearchCfg contains config for customers full-text search, search is served from datastores if url is empty
+type ElasticsearchCfg struct {
+	URL      string        `env:"ELASTICSEARCH_URL" envDefault:""`
+	Index    string        `env:"ELASTICSEARCH_INDEX" envDefault:"customers"`
+	Username string        `env:"ELASTICSEARCH_USERNAME" envDefault:""`
+	Password string        `env:"ELASTICSEARCH_PASSWORD" envDefault:""`
+	Timeout  time.Duration `env:"ELASTICSEARCH_TIMEOUT" envDefault:"5s"`
+}
+
+// CacheWarmUpCfg contains config for loading the most important customers into cache on startup
+type CacheWarmUpCfg struct {
+	Enabled bool     `env:"CACHE_WARMUP_ENABLED" envDefault:"false"`
+	Tenants []string `env:"CACHE_WARMUP_TENANTS" envSeparator:"," envDefault:"default"`
+	Limit   int      `env:"CACHE_WARMUP_LIMIT" envDefault:"1000"` // max number of cached customers per tenant
+}
+
+// CustomersStreamCfg contains config for customers redis stream reader
+type CustomersStreamCfg struct {
+	LagWarnThreshold int64         `env:"CUSTOMERS_STREAM_LAG_WARN_THRESHOLD" envDefault:"100"`
+	LagCheckInterval time.Duration `env:"CUSTOMERS_STREAM_LAG_CHECK_INTERVAL" envDefault:"15s"`
+}
+
+// WebhooksCfg contains config for delivery of customer events to webhooks, interval between attempts
+// is doubled after each failed attempt up to max interval, delivery is dead once attempts are exhausted
+type WebhooksCfg struct {
+	MaxAttempts      int           `env:"WEBHOOKS_MAX_ATTEMPTS" envDefault:"5"`
+	RetryInterval    time.Duration `env:"WEBHOOKS_RETRY_INTERVAL" envDefault:"1s"`
+	RetryMaxInterval time.Duration `env:"WEBHOOKS_RETRY_MAX_INTERVAL" envDefault:"1m"`
+	Timeout          time.Duration `env:"WEBHOOKS_TIMEOUT" envDefault:"5s"`       // timeout of single attempt
+	PollInterval     time.Duration `env:"WEBHOOKS_POLL_INTERVAL" envDefault:"1s"` // how often due deliveries are looked up
+}
+
+// ActivityCfg contains config for recent activity of customers kept in capped mongo collection,
+// the oldest events are overwritten once collection size in bytes is reached
+type ActivityCfg struct {
+	CollectionSize int64         `env:"ACTIVITY_COLLECTION_SIZE" envDefault:"16777216"`
+	Window         time.Duration `env:"ACTIVITY_WINDOW" envDefault:"1h"` // only events which occurred within window are read
+}
+
+// PoolMetricsCfg contains config for collection of postgres and mongo connection pool metrics
+type PoolMetricsCfg struct {
+	Interval time.Duration `env:"POOL_METRICS_INTERVAL" envDefault:"15s"`
+}
+
+// KafkaSASLMechanism defines SASL mechanism used to authenticate to kafka brokers
+type KafkaSASLMechanism string
+
+const (
+	// KafkaSASLNone disables SASL authentication
+	KafkaSASLNone KafkaSASLMechanism = ""
+	// KafkaSASLPlain authenticates with SASL/PLAIN
+	KafkaSASLPlain KafkaSASLMechanism = "plain"
+	// KafkaSASLScramSHA256 authenticates with SASL/SCRAM-SHA-256
+	KafkaSASLScramSHA256 KafkaSASLMechanism = "scram-sha-256"
+	// KafkaSASLScramSHA512 authenticates with SASL/SCRAM-SHA-512
+	KafkaSASLScramSHA512 KafkaSASLMechanism = "scram-sha-512"
+)
+
+// KafkaCfg contains config for publishing customer events to kafka in addition to redis stream, events are relayed
+// from outbox once saved and retried every relay interval
+type KafkaCfg struct {
+	Enabled             bool               `env:"KAFKA_ENABLED" envDefault:"false"`
+	Brokers             []string           `env:"KAFKA_BROKERS" envSeparator:"," envDefault:""`
+	Topic               string             `env:"KAFKA_TOPIC" envDefault:"customer-events"`
+	WriteTimeout        time.Duration      `env:"KAFKA_WRITE_TIMEOUT" envDefault:"10s"`
+	SASLMechanism       KafkaSASLMechanism `env:"KAFKA_SASL_MECHANISM" envDefault:""`
+	SASLUsername        string             `env:"KAFKA_SASL_USERNAME" envDefault:""`
+	SASLPassword        string             `env:"KAFKA_SASL_PASSWORD" envDefault:""`
+	OutboxRelayInterval time.Duration      `env:"KAFKA_OUTBOX_RELAY_INTERVAL" envDefault:"5s"`
+	TLS                 TLSCfg             `envPrefix:"KAFKA_"`
+}
+
+// NatsCfg contains config for relaying customer events to NATS JetStream and replicating in-memory customer cache over it,
+// streams are created on start unless they exist. Relayed events are deduplicated by id within duplicate window
+type NatsCfg struct {
+	URL                 string        `env:"NATS_URL" envDefault:"nats://localhost:4222"`
+	Stream              string        `env:"NATS_STREAM" envDefault:"CUSTOMER_EVENTS"`
+	Subject             string        `env:"NATS_SUBJECT" envDefault:"customers.events"`
+	CacheStream         string        `env:"NATS_CACHE_STREAM" envDefault:"CUSTOMERS_CACHE"`
+	CacheSubject        string        `env:"NATS_CACHE_SUBJECT" envDefault:"customers.cache"`
+	DuplicateWindow     time.Duration `env:"NATS_DUPLICATE_WINDOW" envDefault:"2m"`
+	PublishTimeout      time.Duration `env:"NATS_PUBLISH_TIMEOUT" envDefault:"10s"`
+	OutboxRelayInterval time.Duration `env:"NATS_OUTBOX_RELAY_INTERVAL" envDefault:"5s"`
+}
+
+// CustomersCfg contains config for customers api versions, each version can be served from any backend
+type CustomersCfg struct {
+	V1Backend       CustomersBackend `env:"CUSTOMERS_V1_BACKEND" envDefault:"postgres"`
+	V2Backend       CustomersBackend `env:"CUSTOMERS_V2_BACKEND" envDefault:"mongo"`
+	EmailUniqueness EmailUniqueness  `env:"CUSTOMERS_EMAIL_UNIQUENESS" envDefault:"tenant"`
+}
+
+// ImportCfg contains limits of customers import, import is stopped once file exceeds any of them
+type ImportCfg struct {
+	MaxRows     int   `env:"IMPORT_MAX_ROWS" envDefault:"10000"`
+	MaxBodySize int64 `env:"IMPORT_MAX_BODY_SIZE" envDefault:"10485760"` // max size in bytes of import file
+}
+
+// RepositoryCfg contains config for repositories
+type RepositoryCfg struct {
+	SlowQueryThreshold time.Duration `env:"REPOSITORY_SLOW_QUERY_THRESHOLD" envDefault:"200ms"`
+	BestEffortReads    bool          `env:"REPOSITORY_BEST_EFFORT_READS" envDefault:"false"` // skip malformed rows instead of failing whole read
+}
+
+// RepositoryBreakerCfg contains config for circuit breaker around customers repositories, zero failure threshold disables it
+type RepositoryBreakerCfg struct {
+	FailureThreshold uint32        `env:"REPOSITORY_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	Cooldown         time.Duration `env:"REPOSITORY_BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+// ImagesCfg contains config for images endpoints
+type ImagesCfg struct {
+	PublicDownloads     bool          `env:"IMAGES_PUBLIC_DOWNLOADS" envDefault:"false"`
+	StripMetadata       bool          `env:"IMAGES_STRIP_METADATA" envDefault:"true"`
+	CacheMaxAge         time.Duration `env:"IMAGES_CACHE_MAX_AGE" envDefault:"1h"`
+	SignedURLKey        string        `env:"IMAGES_SIGNED_URL_KEY" envDefault:""`
+	SignedURLTimeToLive time.Duration `env:"IMAGES_SIGNED_URL_TIME_TO_LIVE" envDefault:"15m"`
+	SignedURLClockSkew  time.Duration `env:"IMAGES_SIGNED_URL_CLOCK_SKEW" envDefault:"30s"`
+	StreamMaxSize       int64         `env:"IMAGES_STREAM_MAX_SIZE" envDefault:"10485760"` // max size in bytes of image uploaded via gRPC stream
+	// extensions allowed for uploaded images, extension must also match detected MIME type
+	AllowedExtensions []string `env:"IMAGES_ALLOWED_EXTENSIONS" envSeparator:"," envDefault:".gif,.jpg,.jpeg,.png,.svg,.tif,.tiff,.ico,.webp"`
+}
+
+// SMTPCfg contains config for sending emails via SMTP, emails are logged instead of sent if host is empty.
+// Emails are sent in background by workers, interval between attempts is doubled after each failed attempt up to max interval
+type SMTPCfg struct {
+	Host             string        `env:"SMTP_HOST" envDefault:""`
+	Port             int           `env:"SMTP_PORT" envDefault:"587"`
+	From             string        `env:"SMTP_FROM" envDefault:""`
+	Username         string        `env:"SMTP_USERNAME" envDefault:""`
+	Password         string        `env:"SMTP_PASSWORD" envDefault:""`
+	TLS              TLSCfg        `envPrefix:"SMTP_"`                      // implicit TLS, otherwise STARTTLS is used if server supports it
+	Timeout          time.Duration `env:"SMTP_TIMEOUT" envDefault:"10s"`    // timeout of single attempt
+	QueueSize        int           `env:"SMTP_QUEUE_SIZE" envDefault:"100"` // emails are dropped while queue is full
+	Workers          int           `env:"SMTP_WORKERS" envDefault:"2"`
+	MaxAttempts      int           `env:"SMTP_MAX_ATTEMPTS" envDefault:"5"`
+	RetryInterval    time.Duration `env:"SMTP_RETRY_INTERVAL" envDefault:"1s"`
+	RetryMaxInterval time.Duration `env:"SMTP_RETRY_MAX_INTERVAL" envDefault:"1m"`
+}
+
+// HTTPCfg contains config for http api, zero max concurrent requests disables load shedding.
+// Client IP is taken from X-Forwarded-For only if request comes from one of trusted proxies CIDRs, connection address is used otherwise
+type HTTPCfg struct {
+	ListEnvelope          bool          `env:"HTTP_LIST_ENVELOPE" envDefault:"false"`
+	MaxConcurrentRequests int           `env:"HTTP_MAX_CONCURRENT_REQUESTS" envDefault:"1000"`
+	ShedRetryAfter        time.Duration `env:"HTTP_SHED_RETRY_AFTER" envDefault:"1s"`
+	EventsKeepAlive       time.Duration `env:"HTTP_EVENTS_KEEP_ALIVE" envDefault:"15s"` // interval of keep alive comments sent to idle event streams
+	ProductionMode        bool          `env:"HTTP_PRODUCTION_MODE" envDefault:"true"`  // hides messages of 5xx errors from clients
+	RequireHTTPS          bool          `env:"AUTH_HTTPS" envDefault:"false"`           // TLS is terminated upstream, scheme is checked via X-Forwarded-Proto
+	TrustedProxies        []string      `env:"HTTP_TRUSTED_PROXIES" envSeparator:"," envDefault:""`
+}
+
+// WebSocketCfg contains config for customers websocket, each connection may issue up to RequestsPerSecond
+// requests with bursts up to RequestsBurst, client must authenticate within AuthTimeout if token is not passed in url
+type WebSocketCfg struct {
+	MaxConnections    int           `env:"WS_MAX_CONNECTIONS" envDefault:"1000"`
+	RequestsPerSecond float64       `env:"WS_REQUESTS_PER_SECOND" envDefault:"10"`
+	RequestsBurst     int           `env:"WS_REQUESTS_BURST" envDefault:"20"`
+	AuthTimeout       time.Duration `env:"WS_AUTH_TIMEOUT" envDefault:"10s"`
+	PingInterval      time.Duration `env:"WS_PING_INTERVAL" envDefault:"30s"`
+	RevocationCheck   time.Duration `env:"WS_REVOCATION_CHECK_INTERVAL" envDefault:"1m"` // revoked token closes connection within interval
+	WriteTimeout      time.Duration `env:"WS_WRITE_TIMEOUT" envDefault:"10s"`
+	RetryAfter        time.Duration `env:"WS_RETRY_AFTER" envDefault:"1s"` // advertised to clients rejected with 503
+}
+
+// LogCfg contains config for logging
+type LogCfg struct {
+	RedactKeys  []string `env:"LOG_REDACT_KEYS" envSeparator:"," envDefault:""` // masked in addition to built-in sensitive keys
+	ServiceName string   `env:"SERVICE_NAME" envDefault:"customers"`
+	Environment string   `env:"ENVIRONMENT" envDefault:""`
+	InstanceID  string   `env:"INSTANCE_ID" envDefault:""` // hostname is used if empty
+}
+
+// StartupCfg contains config for connecting to dependencies on startup, interval is doubled after each failed attempt
+type StartupCfg struct {
+	ConnectAttempts    int           `env:"STARTUP_CONNECT_ATTEMPTS" envDefault:"5"`
+	ConnectInterval    time.Duration `env:"STARTUP_CONNECT_INTERVAL" envDefault:"1s"`
+	ConnectMaxInterval time.Duration `env:"STARTUP_CONNECT_MAX_INTERVAL" envDefault:"10s"`
+}
+
+// AdminCfg contains config for admin endpoints
+type AdminCfg struct {
+	UserIDs []string `env:"ADMIN_USER_IDS" envSeparator:"," envDefault:""`
 }
 
 // Config contains necessary application configuration
 type Config struct {
-	PostgresConnString string `env:"POSTGRES_URL"`
-	MongoConnString    string `env:"MONGO_URL"`
-	RedisCfg           RedisCfg
-	JwtCfg             JwtCfg
-	RefreshTokenCfg    RefreshTokenCfg
+	PostgresConnString   string `env:"POSTGRES_URL"`
+	MongoCfg             MongoCfg
+	RedisCfg             RedisCfg
+	ElasticsearchCfg     ElasticsearchCfg
+	JwtCfg               JwtCfg
+	RefreshTokenCfg      RefreshTokenCfg
+	SignupCfg            SignupCfg
+	CustomersCfg         CustomersCfg
+	CustomersStreamCfg   CustomersStreamCfg
+	WebhooksCfg          WebhooksCfg
+	ActivityCfg          ActivityCfg
+	PoolMetricsCfg       PoolMetricsCfg
+	WebSocketCfg         WebSocketCfg
+	KafkaCfg             KafkaCfg
+	NatsCfg              NatsCfg
+	ImagesCfg            ImagesCfg
+	ImportCfg            ImportCfg
+	RepositoryCfg        RepositoryCfg
+	RepositoryBreakerCfg RepositoryBreakerCfg
+	CacheBreakerCfg      CacheBreakerCfg
+	CacheWarmUpCfg       CacheWarmUpCfg
+	RuntimeCfg           RuntimeCfg
+	AdminCfg             AdminCfg
+	HTTPCfg              HTTPCfg
+	SMTPCfg              SMTPCfg
+	LogCfg               LogCfg
+	StartupCfg           StartupCfg
+	RuntimeCfgFile       string `env:"RUNTIME_CONFIG_FILE" envDefault:""`
+	AuditLogFile         string `env:"AUDIT_LOG_FILE" envDefault:""`
+	FeatureFlagsFile     string `env:"FEATURE_FLAGS_FILE" envDefault:""`
+	RequestIDHeader      string `env:"REQUEST_ID_HEADER" envDefault:"X-Request-Id"` // used for grpc metadata as well
+
+	EventBackend EventBackend `env:"EVENT_BACKEND" envDefault:"redis"`
 }
 
 // Build constructs new Config based on environment variables
@@ -54,17 +394,238 @@ func Build() (Config, error) {
 
 	opts := env.Options{RequiredIfNoDef: true}
 	parsers := map[reflect.Type]env.ParserFunc{
-		reflect.TypeOf(cfg.JwtCfg.PrivateKey): privateKeyFromFileParser,
-		reflect.TypeOf(cfg.JwtCfg.PublicKey):  publicKeyFromFileParser,
+		reflect.TypeOf(cfg.JwtCfg.PrivateKey):                              privateKeyFromFileParser,
+		reflect.TypeOf(cfg.JwtCfg.PublicKey):                               publicKeyFromFileParser,
+		reflect.TypeOf(cfg.RuntimeCfg.CustomerCacheTimeToLiveByImportance): importanceTimeToLiveParser,
 	}
 
 	if err := env.ParseWithFuncs(&cfg, parsers, opts); err != nil {
 		return cfg, fmt.Errorf("failed to parse environment variables - %w", err)
 	}
 
+	if err := validateTimeToLive(cfg.JwtCfg.TimeToLive, cfg.JwtCfg.MinTimeToLive, cfg.JwtCfg.MaxTimeToLive); err != nil {
+		return cfg, fmt.Errorf("invalid AUTH_JWT_TIME_TO_LIVE - %w", err)
+	}
+
+	if err := validateTimeToLive(cfg.RefreshTokenCfg.TimeToLive, cfg.RefreshTokenCfg.MinTimeToLive, cfg.RefreshTokenCfg.MaxTimeToLive); err != nil {
+		return cfg, fmt.Errorf("invalid AUTH_REFRESH_TOKEN_TIME_TO_LIVE - %w", err)
+	}
+
+	if cfg.RefreshTokenCfg.MaxCount < 1 {
+		return cfg, errors.New("max refresh tokens count must be positive")
+	}
+
+	switch cfg.RefreshTokenCfg.ExceedStrategy {
+	case RefreshTokenExceedDeleteAll, RefreshTokenExceedEvictOldest:
+	default:
+		return cfg, fmt.Errorf("unknown refresh token exceed strategy %s", cfg.RefreshTokenCfg.ExceedStrategy)
+	}
+
+	switch cfg.RefreshTokenCfg.FingerprintFormat {
+	case FingerprintFormatAny, FingerprintFormatUUID:
+	default:
+		return cfg, fmt.Errorf("unknown refresh token fingerprint format %s", cfg.RefreshTokenCfg.FingerprintFormat)
+	}
+
+	switch cfg.RefreshTokenCfg.FingerprintBinding {
+	case FingerprintBindingStrict, FingerprintBindingLenient:
+	default:
+		return cfg, fmt.Errorf("unknown refresh token fingerprint binding %s", cfg.RefreshTokenCfg.FingerprintBinding)
+	}
+
+	if cfg.RefreshTokenCfg.MinRotationAge < 0 {
+		return cfg, errors.New("refresh token min rotation age must not be negative")
+	}
+
+	for _, proxy := range cfg.HTTPCfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			return cfg, fmt.Errorf("invalid trusted proxy %s, CIDR is expected - %w", proxy, err)
+		}
+	}
+
+	switch cfg.CustomersCfg.EmailUniqueness {
+	case EmailUniquenessTenant, EmailUniquenessGlobal:
+	default:
+		return cfg, fmt.Errorf("unknown customers email uniqueness %s", cfg.CustomersCfg.EmailUniqueness)
+	}
+
+	if cfg.SMTPCfg.Host != "" {
+		if _, err := mail.ParseAddress(cfg.SMTPCfg.From); err != nil {
+			return cfg, fmt.Errorf("invalid smtp sender address %s - %w", cfg.SMTPCfg.From, err)
+		}
+	}
+
+	if cfg.ActivityCfg.CollectionSize < 1 {
+		return cfg, errors.New("customer activity collection size must be positive")
+	}
+
+	if cfg.CustomersStreamCfg.LagCheckInterval <= 0 {
+		return cfg, errors.New("customers stream lag check interval must be positive")
+	}
+
+	if cfg.HTTPCfg.EventsKeepAlive <= 0 {
+		return cfg, errors.New("customer events keep alive interval must be positive")
+	}
+
+	if cfg.WebSocketCfg.PingInterval <= 0 || cfg.WebSocketCfg.RevocationCheck <= 0 {
+		return cfg, errors.New("websocket ping and revocation check intervals must be positive")
+	}
+
+	if cfg.WebhooksCfg.Timeout <= 0 || cfg.WebhooksCfg.PollInterval <= 0 {
+		return cfg, errors.New("webhooks timeout and poll interval must be positive")
+	}
+
+	if cfg.PoolMetricsCfg.Interval <= 0 {
+		return cfg, errors.New("pool metrics collection interval must be positive")
+	}
+
+	if cfg.ImportCfg.MaxRows < 1 || cfg.ImportCfg.MaxBodySize < 1 {
+		return cfg, errors.New("import max rows and max body size must be positive")
+	}
+
+	if cfg.SMTPCfg.QueueSize < 1 || cfg.SMTPCfg.Workers < 1 {
+		return cfg, errors.New("smtp queue size and number of workers must be positive")
+	}
+
+	if err := validateTLSCfg(cfg.SMTPCfg.TLS); err != nil {
+		return cfg, fmt.Errorf("invalid smtp tls config - %w", err)
+	}
+
+	if key := cfg.ImagesCfg.SignedURLKey; key != "" && len(key) < signedURLMinKeyLength {
+		return cfg, fmt.Errorf("images signed URL key must be at least %d bytes long", signedURLMinKeyLength)
+	}
+
+	if err := validateTLSCfg(cfg.RedisCfg.TLS); err != nil {
+		return cfg, fmt.Errorf("invalid redis tls config - %w", err)
+	}
+
+	if err := validateTLSCfg(cfg.MongoCfg.TLS); err != nil {
+		return cfg, fmt.Errorf("invalid mongo tls config - %w", err)
+	}
+
+	// KAFKA_ENABLED predates EVENT_BACKEND, so it still selects kafka
+	if cfg.KafkaCfg.Enabled && cfg.EventBackend == EventBackendRedis {
+		cfg.EventBackend = EventBackendKafka
+	}
+
+	switch cfg.EventBackend {
+	case EventBackendRedis:
+	case EventBackendKafka:
+		cfg.KafkaCfg.Enabled = true
+	case EventBackendNats:
+		if cfg.KafkaCfg.Enabled {
+			return cfg, errors.New("kafka can't be enabled along with nats event backend")
+		}
+
+		if err := validateNatsCfg(cfg.NatsCfg); err != nil {
+			return cfg, fmt.Errorf("invalid nats config - %w", err)
+		}
+	default:
+		return cfg, fmt.Errorf("unknown event backend %s", cfg.EventBackend)
+	}
+
+	if err := validateKafkaCfg(cfg.KafkaCfg); err != nil {
+		return cfg, fmt.Errorf("invalid kafka config - %w", err)
+	}
+
+	for version, backend := range map[string]CustomersBackend{"v1": cfg.CustomersCfg.V1Backend, "v2": cfg.CustomersCfg.V2Backend} {
+		switch backend {
+		case CustomersBackendPostgres, CustomersBackendMongo:
+		default:
+			return cfg, fmt.Errorf("unknown customers %s backend %s", version, backend)
+		}
+	}
+
+	switch cfg.RedisCfg.Serialization {
+	case CacheSerializationMsgpack, CacheSerializationProto:
+	default:
+		return cfg, fmt.Errorf("unknown redis cache serialization %s", cfg.RedisCfg.Serialization)
+	}
+
+	if cfg.CacheWarmUpCfg.Enabled && cfg.CacheWarmUpCfg.Limit <= 0 {
+		return cfg, errors.New("cache warm-up limit must be positive")
+	}
+
+	switch cfg.RedisCfg.PopulationFailure {
+	case CachePopulationFailureFail, CachePopulationFailureIgnore:
+	default:
+		return cfg, fmt.Errorf("unknown redis cache population failure behavior %s", cfg.RedisCfg.PopulationFailure)
+	}
+
 	return cfg, nil
 }
 
+// validateTimeToLive checks token time to live is within bounds, so misconfiguration like year-long tokens fails startup
+func validateTimeToLive(ttl time.Duration, min time.Duration, max time.Duration) error {
+	if min <= 0 || min > max {
+		return fmt.Errorf("time to live bounds [%s, %s] are invalid", min, max)
+	}
+
+	if ttl < min || ttl > max {
+		return fmt.Errorf("time to live %s is out of range [%s, %s]", ttl, min, max)
+	}
+	return nil
+}
+
+func validateTLSCfg(cfg TLSCfg) error {
+	if !cfg.Enabled || cfg.CAFile == "" {
+		return nil
+	}
+
+	info, err := os.Stat(cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("failed to access CA file - %w", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("CA file %s is a directory", cfg.CAFile)
+	}
+	return nil
+}
+
+func validateKafkaCfg(cfg KafkaCfg) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if len(cfg.Brokers) == 0 {
+		return errors.New("at least one broker must be provided")
+	}
+
+	switch cfg.SASLMechanism {
+	case KafkaSASLNone:
+	case KafkaSASLPlain, KafkaSASLScramSHA256, KafkaSASLScramSHA512:
+		if cfg.SASLUsername == "" {
+			return fmt.Errorf("username must be provided for sasl mechanism %s", cfg.SASLMechanism)
+		}
+	default:
+		return fmt.Errorf("unknown sasl mechanism %s", cfg.SASLMechanism)
+	}
+
+	return validateTLSCfg(cfg.TLS)
+}
+
+func validateNatsCfg(cfg NatsCfg) error {
+	if cfg.URL == "" || cfg.Subject == "" || cfg.CacheSubject == "" {
+		return errors.New("url and subjects must be provided")
+	}
+
+	for _, stream := range []string{cfg.Stream, cfg.CacheStream} {
+		if stream == "" || strings.ContainsAny(stream, ". *>") {
+			return fmt.Errorf("invalid stream name %q", stream)
+		}
+	}
+
+	if cfg.Stream == cfg.CacheStream || cfg.Subject == cfg.CacheSubject {
+		return errors.New("customer events and cache must be kept in different streams and subjects")
+	}
+
+	if cfg.DuplicateWindow <= 0 || cfg.PublishTimeout <= 0 || cfg.OutboxRelayInterval <= 0 {
+		return errors.New("duplicate window, publish timeout and outbox relay interval must be positive")
+	}
+	return nil
+}
+
 func privateKeyFromFileParser(v string) (any, error) {
 	path := filepath.Clean(v)
 
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type configTestSuite struct {
+	suite.Suite
+}
+
+func (s *configTestSuite) SetupTest() {
+	t := s.T()
+	require := s.Require()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(err, "failed to generate keys")
+
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(err, "failed to marshal private key")
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(err, "failed to marshal public key")
+
+	dir := t.TempDir()
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	publicKeyFile := filepath.Join(dir, "public.pem")
+
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}), 0o600)
+	require.NoError(err, "failed to write private key")
+
+	err = os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}), 0o600)
+	require.NoError(err, "failed to write public key")
+
+	t.Setenv("POSTGRES_URL", "postgres://localhost:5432/customers")
+	t.Setenv("MONGO_URL", "mongodb://localhost:27017")
+	t.Setenv("REDIS_ADDR", "localhost:6379")
+	t.Setenv("REDIS_PASSWORD", "")
+	t.Setenv("AUTH_JWT_PRIVATE_KEY_FILE", privateKeyFile)
+	t.Setenv("AUTH_JWT_PUBLIC_KEY_FILE", publicKeyFile)
+}
+
+func (s *configTestSuite) TestBuildDefaults() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("default token time to live is within bounds")
+	{
+		cfg, err := Build()
+		require.NoError(err, "default config must be valid")
+		require.Equal(10*time.Minute, cfg.JwtCfg.TimeToLive, "incorrect jwt time to live")
+		require.Equal(720*time.Hour, cfg.RefreshTokenCfg.TimeToLive, "incorrect refresh token time to live")
+	}
+}
+
+func (s *configTestSuite) TestBuildJwtTimeToLiveOutOfRange() {
+	t := s.T()
+	require := s.Require()
+
+	for _, ttl := range []string{"30s", "8760h"} {
+		t.Logf("jwt time to live %s is rejected", ttl)
+		{
+			t.Setenv("AUTH_JWT_TIME_TO_LIVE", ttl)
+			_, err := Build()
+			require.ErrorContains(err, "AUTH_JWT_TIME_TO_LIVE", "out of range jwt time to live must be rejected")
+		}
+	}
+
+	t.Log("jwt time to live within configured bounds is accepted")
+	{
+		t.Setenv("AUTH_JWT_TIME_TO_LIVE", "48h")
+		t.Setenv("AUTH_JWT_MAX_TIME_TO_LIVE", "72h")
+		cfg, err := Build()
+		require.NoError(err, "jwt time to live within bounds must be accepted")
+		require.Equal(48*time.Hour, cfg.JwtCfg.TimeToLive, "incorrect jwt time to live")
+	}
+}
+
+func (s *configTestSuite) TestBuildRefreshTokenTimeToLiveOutOfRange() {
+	t := s.T()
+	require := s.Require()
+
+	for _, ttl := range []string{"10m", "8760h"} {
+		t.Logf("refresh token time to live %s is rejected", ttl)
+		{
+			t.Setenv("AUTH_REFRESH_TOKEN_TIME_TO_LIVE", ttl)
+			_, err := Build()
+			require.ErrorContains(err, "AUTH_REFRESH_TOKEN_TIME_TO_LIVE", "out of range refresh token time to live must be rejected")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildRefreshTokenMaxCount() {
+	t := s.T()
+	require := s.Require()
+
+	for _, count := range []string{"0", "-1"} {
+		t.Logf("max refresh tokens count %s is rejected", count)
+		{
+			t.Setenv("AUTH_REFRESH_TOKEN_MAX_COUNT", count)
+			_, err := Build()
+			require.ErrorContains(err, "max refresh tokens count", "non-positive max count must be rejected")
+		}
+	}
+
+	t.Log("single refresh token per user is accepted")
+	{
+		t.Setenv("AUTH_REFRESH_TOKEN_MAX_COUNT", "1")
+		cfg, err := Build()
+		require.NoError(err, "max count of 1 must be accepted")
+		require.Equal(1, cfg.RefreshTokenCfg.MaxCount, "incorrect max count")
+	}
+}
+
+func (s *configTestSuite) TestBuildMinRotationAge() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("min rotation age guard is disabled by default")
+	{
+		cfg, err := Build()
+		require.NoError(err, "default config must be valid")
+		require.Zero(cfg.RefreshTokenCfg.MinRotationAge, "incorrect default min rotation age")
+	}
+
+	t.Log("negative min rotation age is rejected")
+	{
+		t.Setenv("AUTH_REFRESH_TOKEN_MIN_ROTATION_AGE", "-1s")
+		_, err := Build()
+		require.Error(err, "negative min rotation age must be rejected")
+	}
+
+	t.Log("zero min rotation age disables guard")
+	{
+		t.Setenv("AUTH_REFRESH_TOKEN_MIN_ROTATION_AGE", "0s")
+		cfg, err := Build()
+		require.NoError(err, "zero min rotation age must be accepted")
+		require.Zero(cfg.RefreshTokenCfg.MinRotationAge, "incorrect min rotation age")
+	}
+}
+
+func (s *configTestSuite) TestBuildStreamLagCheckInterval() {
+	t := s.T()
+	require := s.Require()
+
+	for _, interval := range []string{"0s", "-1s"} {
+		t.Logf("lag check interval %s is rejected", interval)
+		{
+			t.Setenv("CUSTOMERS_STREAM_LAG_CHECK_INTERVAL", interval)
+			_, err := Build()
+			require.ErrorContains(err, "lag check interval", "non-positive lag check interval must be rejected")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildEventsKeepAlive() {
+	t := s.T()
+	require := s.Require()
+
+	for _, interval := range []string{"0s", "-1s"} {
+		t.Logf("events keep alive %s is rejected", interval)
+		{
+			t.Setenv("HTTP_EVENTS_KEEP_ALIVE", interval)
+			_, err := Build()
+			require.ErrorContains(err, "keep alive", "non-positive keep alive must be rejected")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildWebSocketIntervals() {
+	t := s.T()
+	require := s.Require()
+
+	for _, env := range []string{"WS_PING_INTERVAL", "WS_REVOCATION_CHECK_INTERVAL"} {
+		t.Logf("zero %s is rejected", env)
+		{
+			t.Setenv(env, "0s")
+			_, err := Build()
+			require.ErrorContains(err, "websocket", "non-positive websocket interval must be rejected")
+			t.Setenv(env, "1s")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildWebhooksIntervals() {
+	t := s.T()
+	require := s.Require()
+
+	for _, env := range []string{"WEBHOOKS_TIMEOUT", "WEBHOOKS_POLL_INTERVAL"} {
+		t.Logf("zero %s is rejected", env)
+		{
+			t.Setenv(env, "0s")
+			_, err := Build()
+			require.ErrorContains(err, "webhooks", "non-positive webhooks interval must be rejected")
+			t.Setenv(env, "1s")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildImportLimits() {
+	t := s.T()
+	require := s.Require()
+
+	for _, env := range []string{"IMPORT_MAX_ROWS", "IMPORT_MAX_BODY_SIZE"} {
+		t.Logf("zero %s is rejected", env)
+		{
+			t.Setenv(env, "0")
+			_, err := Build()
+			require.ErrorContains(err, "import", "non-positive import limit must be rejected")
+			t.Setenv(env, "1")
+		}
+	}
+}
+
+func (s *configTestSuite) TestBuildEventBackend() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("customer events are kept in redis stream only by default")
+	{
+		cfg, err := Build()
+		require.NoError(err, "default config must be valid")
+		require.Equal(EventBackendRedis, cfg.EventBackend, "incorrect default event backend")
+	}
+
+	t.Log("kafka is selected once it is enabled")
+	{
+		t.Setenv("KAFKA_ENABLED", "true")
+		t.Setenv("KAFKA_BROKERS", "localhost:9092")
+		cfg, err := Build()
+		require.NoError(err, "kafka config must be valid")
+		require.Equal(EventBackendKafka, cfg.EventBackend, "kafka must be selected")
+	}
+
+	t.Log("kafka can't be enabled along with nats")
+	{
+		t.Setenv("EVENT_BACKEND", "nats")
+		_, err := Build()
+		require.ErrorContains(err, "kafka", "kafka along with nats must be rejected")
+		t.Setenv("KAFKA_ENABLED", "false")
+	}
+
+	t.Log("nats is selected with default streams")
+	{
+		cfg, err := Build()
+		require.NoError(err, "nats config must be valid")
+		require.Equal(EventBackendNats, cfg.EventBackend, "nats must be selected")
+		require.False(cfg.KafkaCfg.Enabled, "kafka must stay disabled")
+	}
+
+	t.Log("nats stream name with dots is rejected")
+	{
+		t.Setenv("NATS_STREAM", "customer.events")
+		_, err := Build()
+		require.ErrorContains(err, "stream name", "invalid stream name must be rejected")
+		t.Setenv("NATS_STREAM", "CUSTOMER_EVENTS")
+	}
+
+	t.Log("unknown event backend is rejected")
+	{
+		t.Setenv("EVENT_BACKEND", "rabbitmq")
+		_, err := Build()
+		require.ErrorContains(err, "unknown event backend", "unknown backend must be rejected")
+	}
+}
+
+func (s *configTestSuite) TestBuildTrustedProxies() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("trusted proxies are parsed as CIDRs")
+	{
+		t.Setenv("HTTP_TRUSTED_PROXIES", "10.0.0.0/8,fd00::/8")
+		cfg, err := Build()
+		require.NoError(err, "valid CIDRs must be accepted")
+		require.Equal([]string{"10.0.0.0/8", "fd00::/8"}, cfg.HTTPCfg.TrustedProxies, "incorrect trusted proxies")
+	}
+
+	t.Log("trusted proxy which is not CIDR is rejected")
+	{
+		t.Setenv("HTTP_TRUSTED_PROXIES", "10.0.0.1")
+		_, err := Build()
+		require.ErrorContains(err, "10.0.0.1", "invalid trusted proxy must be rejected")
+	}
+}
+
+func (s *configTestSuite) TestValidateTimeToLive() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("bounds are inclusive")
+	{
+		require.NoError(validateTimeToLive(time.Minute, time.Minute, time.Hour), "min time to live must be accepted")
+		require.NoError(validateTimeToLive(time.Hour, time.Minute, time.Hour), "max time to live must be accepted")
+	}
+
+	t.Log("invalid bounds are rejected")
+	{
+		require.Error(validateTimeToLive(time.Minute, time.Hour, time.Minute), "min exceeding max must be rejected")
+		require.Error(validateTimeToLive(time.Minute, 0, time.Hour), "non-positive min must be rejected")
+	}
+}
+
+// start config test suite
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(configTestSuite))
+}
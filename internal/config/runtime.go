@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// importanceNames maps names used in config to customer importance
+var importanceNames = map[string]model.Importance{
+	"low":      model.ImportanceLow,
+	"medium":   model.ImportanceMedium,
+	"high":     model.ImportanceHigh,
+	"critical": model.ImportanceCritical,
+}
+
+// ImportanceTimeToLive maps customer importance to time to live of cached customer
+type ImportanceTimeToLive map[model.Importance]time.Duration
+
+// ByName returns time to live formatted as string per importance name
+func (t ImportanceTimeToLive) ByName() map[string]string {
+	byName := make(map[string]string, len(t))
+	for name, importance := range importanceNames {
+		if ttl, ok := t[importance]; ok {
+			byName[name] = ttl.String()
+		}
+	}
+	return byName
+}
+
+// RuntimeCfg contains config which is safe to change while application is running
+type RuntimeCfg struct {
+	CustomerCacheTimeToLive time.Duration `env:"REDIS_CACHE_TIME_TO_LIVE" envDefault:"3m"`
+	// overrides time to live per importance, e.g. critical:30m,high:10m, unmapped importance gets default one
+	CustomerCacheTimeToLiveByImportance ImportanceTimeToLive `env:"REDIS_CACHE_TIME_TO_LIVE_BY_IMPORTANCE" envDefault:""`
+}
+
+// runtimeCfgFile is json representation of runtime config overrides, empty values keep defaults
+type runtimeCfgFile struct {
+	CustomerCacheTimeToLive             string            `json:"customerCacheTimeToLive"`
+	CustomerCacheTimeToLiveByImportance map[string]string `json:"customerCacheTimeToLiveByImportance"`
+}
+
+// RuntimeHolder holds runtime config and allows to swap it while application is running
+type RuntimeHolder struct {
+	path     string
+	defaults RuntimeCfg
+	mu       sync.RWMutex
+	cfg      RuntimeCfg
+}
+
+// NewRuntimeHolder builds new RuntimeHolder, defaults are overridden with values from file if path is not empty
+func NewRuntimeHolder(path string, defaults RuntimeCfg) (*RuntimeHolder, error) {
+	h := &RuntimeHolder{path: path, defaults: defaults, cfg: defaults}
+	if _, err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Get returns copy of current runtime config
+func (h *RuntimeHolder) Get() RuntimeCfg {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cfg
+}
+
+// CustomerCacheTimeToLive returns current time to live of cached customers
+func (h *RuntimeHolder) CustomerCacheTimeToLive() time.Duration {
+	return h.Get().CustomerCacheTimeToLive
+}
+
+// CustomerCacheTimeToLiveFor returns current time to live of cached customer with provided importance
+func (h *RuntimeHolder) CustomerCacheTimeToLiveFor(importance model.Importance) time.Duration {
+	cfg := h.Get()
+	if ttl, ok := cfg.CustomerCacheTimeToLiveByImportance[importance]; ok {
+		return ttl
+	}
+	return cfg.CustomerCacheTimeToLive
+}
+
+// Reload re-reads runtime config file and swaps current config, config is left untouched on failure
+func (h *RuntimeHolder) Reload() (RuntimeCfg, error) {
+	if h.path == "" {
+		return h.Get(), nil
+	}
+
+	content, err := os.ReadFile(filepath.Clean(h.path))
+	if err != nil {
+		return h.Get(), fmt.Errorf("failed to read runtime config file - %w", err)
+	}
+
+	var f runtimeCfgFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return h.Get(), fmt.Errorf("failed to decode runtime config - %w", err)
+	}
+
+	cfg := h.defaults
+	if f.CustomerCacheTimeToLive != "" {
+		ttl, err := time.ParseDuration(f.CustomerCacheTimeToLive)
+		if err != nil || ttl <= 0 {
+			return h.Get(), fmt.Errorf("invalid customer cache time to live %s", f.CustomerCacheTimeToLive)
+		}
+		cfg.CustomerCacheTimeToLive = ttl
+	}
+
+	if len(f.CustomerCacheTimeToLiveByImportance) > 0 {
+		byImportance := make(ImportanceTimeToLive, len(f.CustomerCacheTimeToLiveByImportance))
+		for name, v := range f.CustomerCacheTimeToLiveByImportance {
+			importance, ttl, err := parseImportanceTimeToLive(name, v)
+			if err != nil {
+				return h.Get(), err
+			}
+			byImportance[importance] = ttl
+		}
+		cfg.CustomerCacheTimeToLiveByImportance = byImportance
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cfg = cfg
+
+	return cfg, nil
+}
+
+// importanceTimeToLiveParser parses comma separated importance:ttl pairs, e.g. critical:30m,high:10m
+func importanceTimeToLiveParser(v string) (any, error) {
+	byImportance := make(ImportanceTimeToLive)
+	for _, pair := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid importance time to live %s, expected importance:ttl", pair)
+		}
+
+		importance, ttl, err := parseImportanceTimeToLive(name, value)
+		if err != nil {
+			return nil, err
+		}
+		byImportance[importance] = ttl
+	}
+	return byImportance, nil
+}
+
+func parseImportanceTimeToLive(name string, value string) (model.Importance, time.Duration, error) {
+	importance, ok := importanceNames[strings.ToLower(name)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown customer importance %s", name)
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, 0, fmt.Errorf("invalid %s customer cache time to live %s", name, value)
+	}
+	return importance, ttl, nil
+}
@@ -0,0 +1,2 @@
+// Package email contains senders used to deliver emails to users
+package email
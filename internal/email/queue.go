@@ -0,0 +1,119 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/pkg/logging"
+	"github.com/umalmyha/customers/pkg/retry"
+)
+
+// ErrQueueFull is raised when message can't be queued since queue is full
+var ErrQueueFull = errors.New("email queue is full")
+
+// Queue is sender which queues messages and sends them in background by fixed number of workers,
+// so callers never wait for SMTP server. Failed messages are retried with backoff and dropped once attempts are exhausted
+type Queue struct {
+	next     Sender
+	cfg      *config.SMTPCfg
+	messages chan *Message
+	sent     prometheus.Counter
+	failed   prometheus.Counter
+	errors   prometheus.Counter
+	dropped  prometheus.Counter
+}
+
+// NewQueue builds new Queue sending messages via next sender, messages are sent only while Run is active
+func NewQueue(next Sender, cfg *config.SMTPCfg, reg prometheus.Registerer) *Queue {
+	q := &Queue{
+		next:     next,
+		cfg:      cfg,
+		messages: make(chan *Message, cfg.QueueSize),
+		sent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "emails_sent_total",
+			Help: "Number of sent emails",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "emails_failed_total",
+			Help: "Number of emails which failed to be sent after all attempts",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "emails_send_errors_total",
+			Help: "Number of failed attempts to send email",
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "emails_dropped_total",
+			Help: "Number of emails dropped since queue is full",
+		}),
+	}
+	reg.MustRegister(q.sent, q.failed, q.errors, q.dropped)
+
+	return q
+}
+
+// Send queues message without blocking, ErrQueueFull is raised if there is no room for message
+func (q *Queue) Send(_ context.Context, m *Message) error {
+	select {
+	case q.messages <- m:
+		return nil
+	default:
+		q.dropped.Inc()
+		return ErrQueueFull
+	}
+}
+
+// Run sends queued messages until context is done, messages left in queue are not sent
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-q.messages:
+			q.send(ctx, m)
+		}
+	}
+}
+
+func (q *Queue) send(ctx context.Context, m *Message) {
+	// invalid message is never sent, so it is not retried
+	var invalidErr error
+
+	backoff := retry.Backoff{Attempts: q.cfg.MaxAttempts, Interval: q.cfg.RetryInterval, MaxInterval: q.cfg.RetryMaxInterval}
+	err := retry.Do(ctx, backoff, func(ctx context.Context) error {
+		err := q.next.Send(ctx, m)
+		if errors.Is(err, ErrInvalidMessage) {
+			invalidErr = err
+			return nil
+		}
+		return err
+	}, func(attempt int, err error) {
+		q.errors.Inc()
+		logging.FromContext(ctx).Warnf("attempt %d to send email %q failed - %v", attempt, m.Subject, err)
+	})
+
+	if err == nil {
+		err = invalidErr
+	}
+
+	if err != nil {
+		q.failed.Inc()
+		logging.FromContext(ctx).Errorf("email %q is dropped - %v", m.Subject, err)
+		return
+	}
+	q.sent.Inc()
+}
@@ -0,0 +1,132 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+)
+
+var errServerUnavailable = errors.New("connection refused")
+
+// flakySender fails first attempts to send each message
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	attempts map[string]int
+	sent     chan *Message
+}
+
+func (f *flakySender) Send(_ context.Context, m *Message) error {
+	f.mu.Lock()
+	f.attempts[m.Subject]++
+	attempt := f.attempts[m.Subject]
+	f.mu.Unlock()
+
+	if attempt <= f.failures {
+		return errServerUnavailable
+	}
+	f.sent <- m
+	return nil
+}
+
+type queueTestSuite struct {
+	suite.Suite
+	cfg    *config.SMTPCfg
+	sender *flakySender
+}
+
+func (s *queueTestSuite) SetupTest() {
+	s.cfg = &config.SMTPCfg{
+		QueueSize:        2,
+		Workers:          2,
+		MaxAttempts:      3,
+		RetryInterval:    time.Millisecond,
+		RetryMaxInterval: time.Millisecond,
+	}
+	s.sender = &flakySender{attempts: make(map[string]int), sent: make(chan *Message, 10)}
+}
+
+func (s *queueTestSuite) TestSendRetried() {
+	t := s.T()
+	require := s.Require()
+
+	s.sender.failures = 2
+	q := NewQueue(s.sender, s.cfg, prometheus.NewRegistry())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	t.Log("message is sent in background once server is available")
+	{
+		require.NoError(q.Send(context.Background(), &Message{To: []string{"john@somemail.com"}, Subject: "Welcome"}), "message must be queued")
+
+		select {
+		case m := <-s.sender.sent:
+			require.Equal("Welcome", m.Subject, "incorrect message")
+		case <-time.After(5 * time.Second):
+			require.Fail("message must be sent")
+		}
+
+		require.Eventually(func() bool { return testutil.ToFloat64(q.sent) == 1 }, time.Second, 10*time.Millisecond, "sent message must be counted")
+		require.Equal(2.0, testutil.ToFloat64(q.errors), "failed attempts must be counted")
+	}
+}
+
+func (s *queueTestSuite) TestSendFailed() {
+	t := s.T()
+	require := s.Require()
+
+	s.sender.failures = s.cfg.MaxAttempts
+	q := NewQueue(s.sender, s.cfg, prometheus.NewRegistry())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	t.Log("message is dropped once attempts are exhausted")
+	{
+		require.NoError(q.Send(context.Background(), &Message{To: []string{"john@somemail.com"}, Subject: "Welcome"}), "message must be queued")
+		require.Eventually(func() bool { return testutil.ToFloat64(q.failed) == 1 }, 5*time.Second, 10*time.Millisecond, "failed message must be counted")
+		require.Equal(3.0, testutil.ToFloat64(q.errors), "failed attempts must be counted")
+		require.Zero(testutil.ToFloat64(q.sent), "message must not be sent")
+	}
+
+	t.Log("invalid message is not retried")
+	{
+		q.next = NewSMTPSender(&config.SMTPCfg{Host: "localhost", Port: 25, From: "no-reply@somemail.com"}, nil)
+		require.NoError(q.Send(context.Background(), &Message{Subject: "No recipients"}), "message must be queued")
+		require.Eventually(func() bool { return testutil.ToFloat64(q.failed) == 2 }, 5*time.Second, 10*time.Millisecond, "failed message must be counted")
+		require.Equal(3.0, testutil.ToFloat64(q.errors), "invalid message must not be retried")
+	}
+}
+
+func (s *queueTestSuite) TestSendQueueFull() {
+	t := s.T()
+	require := s.Require()
+
+	q := NewQueue(s.sender, s.cfg, prometheus.NewRegistry())
+
+	t.Log("message is dropped without blocking if queue is full")
+	{
+		for i := 0; i < s.cfg.QueueSize; i++ {
+			require.NoError(q.Send(context.Background(), &Message{Subject: "Welcome"}), "message must be queued")
+		}
+
+		err := q.Send(context.Background(), &Message{Subject: "Welcome"})
+		require.ErrorIs(err, ErrQueueFull, "message must not be queued")
+		require.Equal(1.0, testutil.ToFloat64(q.dropped), "dropped message must be counted")
+	}
+}
+
+// start queue test suite
+func TestQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(queueTestSuite))
+}
@@ -0,0 +1,58 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// Message represents plain text or html email message
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    bool
+}
+
+// Sender represents behavior of email sender
+type Sender interface {
+	Send(context.Context, *Message) error
+}
+
+// NewSender builds SMTP sender if SMTP host is configured, otherwise emails are logged, so they can be read in development
+func NewSender(cfg *config.SMTPCfg, tlsCfg *tls.Config) Sender {
+	if cfg.Host == "" {
+		return NewLogSender()
+	}
+	return NewSMTPSender(cfg, tlsCfg)
+}
+
+type noopSender struct{}
+
+// NewNoopSender builds sender which drops all messages
+func NewNoopSender() Sender {
+	return noopSender{}
+}
+
+func (noopSender) Send(context.Context, *Message) error {
+	return nil
+}
+
+type logSender struct{}
+
+// NewLogSender builds sender which logs messages with debug level instead of sending them
+func NewLogSender() Sender {
+	return logSender{}
+}
+
+func (logSender) Send(ctx context.Context, m *Message) error {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"to":      strings.Join(m.To, ", "),
+		"subject": m.Subject,
+	}).Debugf("email is not sent since smtp server is not configured\n%s", m.Body)
+	return nil
+}
@@ -0,0 +1,194 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/umalmyha/customers/internal/config"
+)
+
+// ErrInvalidMessage is raised when message can't be sent as is
+var ErrInvalidMessage = errors.New("invalid email message")
+
+// sendMailFunc delivers raw message to SMTP server, it matches smtp.SendMail signature
+type sendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+type smtpSender struct {
+	addr     string
+	from     *mail.Address
+	auth     smtp.Auth
+	sendMail sendMailFunc
+	now      func() time.Time
+}
+
+// NewSMTPSender builds sender delivering emails via SMTP server, connection is established over TLS
+// if tls config is provided, otherwise it is upgraded via STARTTLS if server supports it
+func NewSMTPSender(cfg *config.SMTPCfg, tlsCfg *tls.Config) Sender {
+	return newSMTPSender(cfg, dialSendMail(cfg.Host, cfg.Timeout, tlsCfg), time.Now)
+}
+
+func newSMTPSender(cfg *config.SMTPCfg, sendMail sendMailFunc, now func() time.Time) *smtpSender {
+	s := &smtpSender{
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from:     &mail.Address{Address: cfg.From},
+		sendMail: sendMail,
+		now:      now,
+	}
+
+	if from, err := mail.ParseAddress(cfg.From); err == nil {
+		s.from = from
+	}
+
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return s
+}
+
+func (s *smtpSender) Send(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rcpts, msg, err := s.build(m)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sendMail(s.addr, s.auth, s.from.Address, rcpts, msg); err != nil {
+		return fmt.Errorf("smtp: failed to send email - %w", err)
+	}
+	return nil
+}
+
+// build returns bare recipient addresses for SMTP envelope and message with headers
+func (s *smtpSender) build(m *Message) ([]string, []byte, error) {
+	if len(m.To) == 0 {
+		return nil, nil, fmt.Errorf("%w - no recipients", ErrInvalidMessage)
+	}
+
+	rcpts := make([]string, 0, len(m.To))
+	to := make([]string, 0, len(m.To))
+	for _, rcpt := range m.To {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w - recipient %q - %v", ErrInvalidMessage, rcpt, err)
+		}
+		rcpts = append(rcpts, addr.Address)
+		to = append(to, addr.String())
+	}
+
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, nil, fmt.Errorf("%w - subject must be single line", ErrInvalidMessage)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", s.from.String())
+	writeHeader(&buf, "To", strings.Join(to, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", s.now().Format(time.RFC1123Z))
+	writeHeader(&buf, "MIME-Version", "1.0")
+	if m.HTML {
+		writeHeader(&buf, "Content-Type", "text/html; charset=utf-8")
+	} else {
+		writeHeader(&buf, "Content-Type", "text/plain; charset=utf-8")
+	}
+	writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(m.Body)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := qp.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return rcpts, buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// dialSendMail returns sendMailFunc which behaves like smtp.SendMail, but the whole conversation
+// is limited by timeout and connection is established over TLS if tls config is provided
+func dialSendMail(host string, timeout time.Duration, tlsCfg *tls.Config) sendMailFunc {
+	return func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return err
+		}
+
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return err
+		}
+
+		if tlsCfg != nil {
+			conn = tls.Client(conn, tlsCfg)
+		}
+
+		c, err := smtp.NewClient(conn, host)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		defer c.Close()
+
+		if tlsCfg == nil {
+			if ok, _ := c.Extension("STARTTLS"); ok {
+				if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+					return err
+				}
+			}
+		}
+
+		if a != nil {
+			if ok, _ := c.Extension("AUTH"); ok {
+				if err := c.Auth(a); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := c.Mail(from); err != nil {
+			return err
+		}
+
+		for _, rcpt := range to {
+			if err := c.Rcpt(rcpt); err != nil {
+				return err
+			}
+		}
+
+		w, err := c.Data()
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(msg); err != nil {
+			return err
+		}
+
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return c.Quit()
+	}
+}
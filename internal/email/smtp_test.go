@@ -0,0 +1,217 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+)
+
+type sentMail struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+	msg  string
+}
+
+type smtpSenderTestSuite struct {
+	suite.Suite
+	cfg  *config.SMTPCfg
+	now  time.Time
+	sent []sentMail
+}
+
+func (s *smtpSenderTestSuite) SetupTest() {
+	s.cfg = &config.SMTPCfg{
+		Host:     "smtp.somemail.com",
+		Port:     587,
+		From:     "Customers <no-reply@somemail.com>",
+		Username: "mailer",
+		Password: "secret",
+	}
+	s.now = time.Date(2022, time.October, 3, 12, 30, 0, 0, time.UTC)
+	s.sent = nil
+}
+
+func (s *smtpSenderTestSuite) sender(cfg *config.SMTPCfg) *smtpSender {
+	stubSendMail := func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		s.sent = append(s.sent, sentMail{addr: addr, auth: a, from: from, to: to, msg: string(msg)})
+		return nil
+	}
+	return newSMTPSender(cfg, stubSendMail, func() time.Time { return s.now })
+}
+
+func (s *smtpSenderTestSuite) TestSend() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("message is built and sent to configured server")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{
+			To:      []string{"john@somemail.com", "Albert Peers <albert@somemail.com>"},
+			Subject: "Welcome, John",
+			Body:    "Hello!\nYour account is ready.",
+		})
+		require.NoError(err, "message must be sent")
+		require.Len(s.sent, 1, "single message must be sent")
+
+		sent := s.sent[0]
+		require.Equal("smtp.somemail.com:587", sent.addr, "incorrect server address")
+		require.NotNil(sent.auth, "credentials must be used")
+		require.Equal("no-reply@somemail.com", sent.from, "envelope sender must be bare address")
+		require.Equal([]string{"john@somemail.com", "albert@somemail.com"}, sent.to, "envelope recipients must be bare addresses")
+
+		expected := "From: \"Customers\" <no-reply@somemail.com>\r\n" +
+			"To: <john@somemail.com>, \"Albert Peers\" <albert@somemail.com>\r\n" +
+			"Subject: Welcome, John\r\n" +
+			"Date: Mon, 03 Oct 2022 12:30:00 +0000\r\n" +
+			"MIME-Version: 1.0\r\n" +
+			"Content-Type: text/plain; charset=utf-8\r\n" +
+			"Content-Transfer-Encoding: quoted-printable\r\n" +
+			"\r\n" +
+			"Hello!\r\nYour account is ready."
+		require.Equal(expected, sent.msg, "incorrect message")
+	}
+
+	t.Log("non-ascii subject and body are encoded")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{
+			To:      []string{"john@somemail.com"},
+			Subject: "Привет",
+			Body:    "Добро пожаловать",
+		})
+		require.NoError(err, "message must be sent")
+		require.Contains(s.sent[1].msg, "Subject: =?utf-8?q?", "subject must be q-encoded")
+		require.Contains(s.sent[1].msg, "=D0=94=D0=BE=D0=B1=D1=80=D0=BE", "body must be quoted-printable")
+	}
+
+	t.Log("credentials are not used if username is empty")
+	{
+		cfg := *s.cfg
+		cfg.Username = ""
+		err := s.sender(&cfg).Send(context.Background(), &Message{To: []string{"john@somemail.com"}, Subject: "Hi"})
+		require.NoError(err, "message must be sent")
+		require.Nil(s.sent[2].auth, "credentials must not be used")
+	}
+
+	t.Log("html message is sent with html content type")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{To: []string{"john@somemail.com"}, Subject: "Hi", Body: "<p>Hi</p>", HTML: true})
+		require.NoError(err, "message must be sent")
+		require.Contains(s.sent[3].msg, "Content-Type: text/html; charset=utf-8\r\n", "incorrect content type")
+	}
+}
+
+func (s *smtpSenderTestSuite) TestSendToServer() {
+	t := s.T()
+	require := s.Require()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err, "failed to start fake smtp server")
+	defer lis.Close()
+
+	received := make(chan []string, 1)
+	go serveFakeSMTP(lis, received)
+
+	cfg := *s.cfg
+	cfg.Host = "127.0.0.1"
+	cfg.Port = lis.Addr().(*net.TCPAddr).Port
+	cfg.Username = ""
+	cfg.Timeout = 5 * time.Second
+
+	t.Log("message is delivered to smtp server")
+	{
+		err := NewSMTPSender(&cfg, nil).Send(context.Background(), &Message{To: []string{"john@somemail.com"}, Subject: "Hi", Body: "Hello!"})
+		require.NoError(err, "message must be sent")
+
+		lines := <-received
+		require.Contains(lines, "MAIL FROM:<no-reply@somemail.com>", "envelope sender must be sent")
+		require.Contains(lines, "RCPT TO:<john@somemail.com>", "envelope recipient must be sent")
+		require.Contains(lines, "Subject: Hi", "message must be sent")
+		require.Contains(lines, "Hello!", "message body must be sent")
+		require.Equal("QUIT", lines[len(lines)-1], "conversation must be finished")
+	}
+}
+
+// serveFakeSMTP accepts single connection, replies success to every command and sends received lines once client quits
+func serveFakeSMTP(lis net.Listener, received chan<- []string) {
+	conn, err := lis.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var lines []string
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	data := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+
+		switch {
+		case data:
+			if line == "." {
+				data = false
+				reply("250 OK")
+			}
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case line == "DATA":
+			data = true
+			reply("354 go ahead")
+		case line == "QUIT":
+			reply("221 bye")
+			received <- lines
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpSenderTestSuite) TestSendInvalidMessage() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("message without recipients is rejected")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{Subject: "Hi"})
+		require.ErrorIs(err, ErrInvalidMessage, "message without recipients must be rejected")
+	}
+
+	t.Log("invalid recipient is rejected")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{To: []string{"not an email"}, Subject: "Hi"})
+		require.ErrorIs(err, ErrInvalidMessage, "invalid recipient must be rejected")
+	}
+
+	t.Log("header injection via subject is rejected")
+	{
+		err := s.sender(s.cfg).Send(context.Background(), &Message{
+			To:      []string{"john@somemail.com"},
+			Subject: "Hi\r\nBcc: victim@somemail.com",
+		})
+		require.ErrorIs(err, ErrInvalidMessage, "multiline subject must be rejected")
+	}
+
+	require.Empty(s.sent, "invalid messages must not be sent")
+}
+
+// start smtp sender test suite
+func TestSMTPSenderTestSuite(t *testing.T) {
+	suite.Run(t, new(smtpSenderTestSuite))
+}
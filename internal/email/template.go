@@ -0,0 +1,46 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+)
+
+// Template is name of embedded html template of email
+type Template string
+
+// Templates of emails
+const (
+	TemplateWelcome Template = "welcome.html" // data is WelcomeData
+)
+
+// WelcomeData is data of welcome email sent to signed up user
+type WelcomeData struct {
+	Email string
+}
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// subjects are kept aside of templates, since html escaping is not applicable to headers
+var subjects = map[Template]string{
+	TemplateWelcome: "Welcome to Customers",
+}
+
+// Render builds html message from template, data is escaped according to context it is rendered in
+func Render(t Template, to []string, data any) (*Message, error) {
+	subject, ok := subjects[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %s", t)
+	}
+
+	var body bytes.Buffer
+	if err := templates.ExecuteTemplate(&body, string(t), data); err != nil {
+		return nil, fmt.Errorf("failed to render email template %s - %w", t, err)
+	}
+
+	return &Message{To: to, Subject: subject, Body: body.String(), HTML: true}, nil
+}
@@ -0,0 +1,37 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type templateTestSuite struct {
+	suite.Suite
+}
+
+func (s *templateTestSuite) TestRender() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("welcome email is rendered with escaped data")
+	{
+		m, err := Render(TemplateWelcome, []string{"john@somemail.com"}, WelcomeData{Email: "<b>john</b>@somemail.com"})
+		require.NoError(err, "failed to render template")
+		require.Equal([]string{"john@somemail.com"}, m.To, "incorrect recipients")
+		require.Equal("Welcome to Customers", m.Subject, "incorrect subject")
+		require.True(m.HTML, "message must be html")
+		require.Contains(m.Body, "&lt;b&gt;john&lt;/b&gt;@somemail.com", "data must be escaped")
+	}
+
+	t.Log("unknown template is rejected")
+	{
+		_, err := Render("unknown.html", []string{"john@somemail.com"}, nil)
+		require.Error(err, "unknown template must be rejected")
+	}
+}
+
+// start template test suite
+func TestTemplateTestSuite(t *testing.T) {
+	suite.Run(t, new(templateTestSuite))
+}
@@ -0,0 +1,2 @@
+// Package errors contains typed errors returned by services, they are mapped to transport status codes by handlers
+package errors
@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrDuplicateEmail is reason of business error raised when email is already taken
+	ErrDuplicateEmail = errors.New("duplicate email")
+	// ErrConcurrentModification is reason of business error raised when entry is changed by concurrent request
+	ErrConcurrentModification = errors.New("concurrent modification")
+	// ErrInvalidArgument is reason of business error raised when provided value can't be accepted regardless of state
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrForbidden is reason of business error raised when action is disabled for caller regardless of provided data
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized is raised when provided credentials are wrong, the exact reason is never exposed to client
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// BusinessErr is violation of business rule, reason is optional sentinel error classifying violation
+type BusinessErr struct {
+	reason error
+	msg    string
+}
+
+// NewBusinessErr builds new BusinessErr with message exposed to client
+func NewBusinessErr(reason error, msg string) *BusinessErr {
+	return &BusinessErr{reason: reason, msg: msg}
+}
+
+func (e *BusinessErr) Error() string {
+	return e.msg
+}
+
+// Unwrap returns reason of violation, so it can be checked with errors.Is
+func (e *BusinessErr) Unwrap() error {
+	return e.reason
+}
+
+// EntryNotFoundErr is raised when requested entry doesn't exist
+type EntryNotFoundErr struct {
+	Entry string
+	ID    string
+}
+
+// NewEntryNotFoundErr builds new EntryNotFoundErr for entry of provided kind, e.g. customer
+func NewEntryNotFoundErr(entry, id string) *EntryNotFoundErr {
+	return &EntryNotFoundErr{Entry: entry, ID: id}
+}
+
+func (e *EntryNotFoundErr) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Entry, e.ID)
+}
+
+// UnavailableErr is raised when dependency is temporarily unavailable, call may succeed after RetryAfter
+type UnavailableErr struct {
+	RetryAfter time.Duration
+	msg        string
+	cause      error
+}
+
+// NewUnavailableErr builds new UnavailableErr with message exposed to client, cause is kept for logging only
+func NewUnavailableErr(msg string, retryAfter time.Duration, cause error) *UnavailableErr {
+	return &UnavailableErr{RetryAfter: retryAfter, msg: msg, cause: cause}
+}
+
+func (e *UnavailableErr) Error() string {
+	return e.msg
+}
+
+// Unwrap returns cause of unavailability
+func (e *UnavailableErr) Unwrap() error {
+	return e.cause
+}
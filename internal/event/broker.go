@@ -0,0 +1,109 @@
+package event
+
+import (
+	"context"
+	"sync"
+
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// Delivery is event delivered by Broker along with its stream id, id is empty if event isn't read from stream
+type Delivery struct {
+	StreamID string
+	Event    *model.CustomerEvent
+}
+
+// Subscription receives events of single tenant published to Broker
+type Subscription struct {
+	tenantID string
+	events   chan Delivery
+	dropped  chan struct{}
+	once     sync.Once
+}
+
+// Events returns channel of received events
+func (s *Subscription) Events() <-chan Delivery {
+	return s.events
+}
+
+// Dropped returns channel which is closed once subscriber falls behind and further events are not delivered
+func (s *Subscription) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+func (s *Subscription) drop() {
+	s.once.Do(func() {
+		close(s.dropped)
+	})
+}
+
+// Broker fans out events to subscriptions of their tenant. Publishing never blocks, subscription
+// with full buffer is dropped instead, so slow subscriber doesn't delay the others
+type Broker struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	bufferSize    int
+}
+
+// NewBroker builds new Broker, each subscription buffers up to bufferSize events
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		subscriptions: make(map[*Subscription]struct{}),
+		bufferSize:    bufferSize,
+	}
+}
+
+// Subscribe subscribes to events of tenant, subscription must be cancelled with Unsubscribe
+func (b *Broker) Subscribe(tenantID string) *Subscription {
+	s := &Subscription{
+		tenantID: tenantID,
+		events:   make(chan Delivery, b.bufferSize),
+		dropped:  make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[s] = struct{}{}
+
+	return s
+}
+
+// Unsubscribe cancels subscription
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscriptions, s)
+}
+
+// Subscribers returns number of active subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscriptions)
+}
+
+// Publish delivers event to subscriptions of its tenant, it has signature of Handler,
+// so broker can be fed by stream consumer
+func (b *Broker) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	return b.PublishStream(ctx, "", e)
+}
+
+// PublishStream delivers event read from stream to subscriptions of its tenant along with its stream id,
+// it has signature of StreamHandler, so broker can be fed by stream reader
+func (b *Broker) PublishStream(_ context.Context, streamID string, e *model.CustomerEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subscriptions {
+		if s.tenantID != e.TenantID {
+			continue
+		}
+
+		select {
+		case s.events <- Delivery{StreamID: streamID, Event: e}:
+		default:
+			s.drop()
+		}
+	}
+	return nil
+}
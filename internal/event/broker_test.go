@@ -0,0 +1,87 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type brokerTestSuite struct {
+	suite.Suite
+	broker *Broker
+}
+
+func (s *brokerTestSuite) SetupTest() {
+	s.broker = NewBroker(2)
+}
+
+func (s *brokerTestSuite) TestPublish() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	acme := s.broker.Subscribe("acme")
+	globex := s.broker.Subscribe("globex")
+
+	t.Log("event is delivered to subscriptions of its tenant only")
+	{
+		require.NoError(s.broker.Publish(ctx, s.event("acme")), "no error must be raised")
+		require.Len(acme.Events(), 1, "event must be delivered")
+		require.Empty(globex.Events(), "event of other tenant must not be delivered")
+	}
+
+	t.Log("subscription is dropped once buffer is full, others are not affected")
+	{
+		require.NoError(s.broker.Publish(ctx, s.event("acme")), "no error must be raised")
+		require.NoError(s.broker.Publish(ctx, s.event("acme")), "publishing must not block on slow subscriber")
+		require.NoError(s.broker.Publish(ctx, s.event("globex")), "no error must be raised")
+
+		require.Len(acme.Events(), 2, "buffered events must be kept")
+		select {
+		case <-acme.Dropped():
+		default:
+			require.Fail("slow subscription must be dropped")
+		}
+
+		require.Len(globex.Events(), 1, "event must be delivered")
+		select {
+		case <-globex.Dropped():
+			require.Fail("subscription must not be dropped")
+		default:
+		}
+	}
+
+	t.Log("event read from stream is delivered with its stream id")
+	{
+		stream := s.broker.Subscribe("initech")
+		defer s.broker.Unsubscribe(stream)
+
+		require.NoError(s.broker.PublishStream(ctx, "1700000000000-0", s.event("initech")), "no error must be raised")
+		d := <-stream.Events()
+		require.Equal("1700000000000-0", d.StreamID, "stream id must be delivered")
+		require.Equal("initech", d.Event.TenantID, "incorrect event")
+	}
+
+	t.Log("events are not delivered after unsubscribe")
+	{
+		s.broker.Unsubscribe(globex)
+		require.NoError(s.broker.Publish(ctx, s.event("globex")), "no error must be raised")
+		require.Len(globex.Events(), 1, "event must not be delivered")
+	}
+}
+
+func (s *brokerTestSuite) event(tenantID string) *model.CustomerEvent {
+	return &model.CustomerEvent{
+		ID:         "6f1d2a4b-8c3e-4f5a-9b7d-1e2f3a4b5c6d",
+		Type:       model.CustomerCreated,
+		TenantID:   tenantID,
+		CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692",
+	}
+}
+
+// start broker test suite
+func TestBrokerTestSuite(t *testing.T) {
+	suite.Run(t, new(brokerTestSuite))
+}
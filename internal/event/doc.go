@@ -0,0 +1,2 @@
+// Package event contains publishers and consumers of customer change events
+package event
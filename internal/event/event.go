@@ -0,0 +1,109 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// SchemaVersion is version of schema of customer event messages published outside of service, e.g. to kafka or JetStream,
+// it is bumped on incompatible changes. Version 2 numbers importance from 1 as http api does, version 1 numbered it from 0
+const SchemaVersion = 2
+
+// versionedEvent is customer event message published outside of service, fields of event are inlined next to schema version
+type versionedEvent struct {
+	SchemaVersion int `json:"schemaVersion"`
+	*model.CustomerEvent
+}
+
+// newVersionedEvent builds message of event converted to the one exposed outside of service
+func newVersionedEvent(e *model.CustomerEvent) *versionedEvent {
+	return &versionedEvent{SchemaVersion: SchemaVersion, CustomerEvent: e.External()}
+}
+
+// Publisher represents behavior of customer events publisher
+type Publisher interface {
+	Publish(context.Context, *model.CustomerEvent) error
+}
+
+// Handler processes single consumed event, event is acknowledged once handler returns
+type Handler func(context.Context, *model.CustomerEvent) error
+
+// StreamHandler processes single event read from stream along with its stream id
+type StreamHandler func(context.Context, string, *model.CustomerEvent) error
+
+// Replayer represents behavior of stream reader passing events which are already published after given stream id to handler,
+// id of the last passed event is returned
+type Replayer interface {
+	Replay(context.Context, string, StreamHandler) (string, error)
+}
+
+// StreamIDAfter reports whether stream id is greater than other one, stream ids are <milliseconds>-<sequence>
+// and can't be compared as strings. Malformed id is never after the other one
+func StreamIDAfter(id, other string) bool {
+	ms, seq, ok := parseStreamID(id)
+	if !ok {
+		return false
+	}
+
+	otherMs, otherSeq, ok := parseStreamID(other)
+	if !ok {
+		return true
+	}
+	return ms > otherMs || (ms == otherMs && seq > otherSeq)
+}
+
+func parseStreamID(id string) (uint64, uint64, bool) {
+	rawMs, rawSeq, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	ms, err := strconv.ParseUint(rawMs, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+type noopPublisher struct{}
+
+// NewNoopPublisher builds publisher which drops all events
+func NewNoopPublisher() Publisher {
+	return noopPublisher{}
+}
+
+func (noopPublisher) Publish(context.Context, *model.CustomerEvent) error {
+	return nil
+}
+
+type multiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher builds publisher passing every event to all publishers, failure of one doesn't prevent publishing to others
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &multiPublisher{publishers: publishers}
+}
+
+func (p *multiPublisher) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	msgs := make([]string, 0)
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, e); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("failed to publish customer event %s - %s", e.ID, strings.Join(msgs, "; "))
+	}
+	return nil
+}
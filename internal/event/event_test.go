@@ -0,0 +1,37 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type eventTestSuite struct {
+	suite.Suite
+}
+
+func (s *eventTestSuite) TestStreamIDAfter() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("ids are compared by milliseconds and then by sequence")
+	{
+		require.True(StreamIDAfter("1700000000001-0", "1700000000000-5"), "later milliseconds must be after")
+		require.True(StreamIDAfter("1700000000000-10", "1700000000000-9"), "sequence must be compared as number")
+		require.True(StreamIDAfter("10-0", "9-0"), "milliseconds must be compared as number")
+		require.False(StreamIDAfter("1700000000000-1", "1700000000000-1"), "the same id must not be after")
+		require.False(StreamIDAfter("1700000000000-0", "1700000000001-0"), "earlier id must not be after")
+	}
+
+	t.Log("malformed id is never after, any id is after malformed one")
+	{
+		require.False(StreamIDAfter("", "1700000000000-0"), "empty id must not be after")
+		require.False(StreamIDAfter("$", "0-0"), "special id must not be after")
+		require.True(StreamIDAfter("0-1", "latest"), "well-formed id must be after malformed one")
+	}
+}
+
+// start event test suite
+func TestEventTestSuite(t *testing.T) {
+	suite.Run(t, new(eventTestSuite))
+}
@@ -0,0 +1,39 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher builds publisher writing events to topic of writer as json, messages are keyed by customer id,
+// so events of customer land on the same partition and keep their order
+func NewKafkaPublisher(writer *kafka.Writer) Publisher {
+	return &kafkaPublisher{writer: writer}
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	value, err := json.Marshal(newVersionedEvent(e))
+	if err != nil {
+		return fmt.Errorf("failed to serialize customer event %s - %w", e.ID, err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(e.CustomerID),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "eventType", Value: []byte(e.Type)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("kafka: failed to publish customer event %s - %w", e.ID, err)
+	}
+	return nil
+}
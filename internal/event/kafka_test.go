@@ -0,0 +1,194 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+const (
+	redpandaContainerName = "redpanda-event-test-customers"
+	redpandaPort          = "19092"
+	kafkaTestTopic        = "customer-events-test"
+	kafkaTestPartitions   = 3
+	testCtxTimeout        = 30 * time.Second
+)
+
+type kafkaTestSuite struct {
+	suite.Suite
+	dockerPool *dockertest.Pool
+	redpanda   *dockertest.Resource
+	broker     string
+}
+
+func (s *kafkaTestSuite) SetupSuite() {
+	t := s.T()
+	assert := s.Require()
+
+	// create docker pool
+	dockerPool, err := dockertest.NewPool("")
+	assert.NoError(err, "failed to create pool")
+
+	t.Log("sending ping to docker...")
+	err = dockerPool.Client.Ping()
+	assert.NoError(err, "failed to connect to docker")
+
+	s.dockerPool = dockerPool
+	s.broker = net.JoinHostPort("localhost", redpandaPort)
+
+	// start redpanda, it speaks kafka protocol and starts much faster than kafka
+	t.Log("starting redpanda...")
+	redpanda, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       redpandaContainerName,
+		Repository: "docker.redpanda.com/vectorized/redpanda",
+		Tag:        "v22.2.7",
+		Cmd: []string{
+			"redpanda", "start",
+			"--overprovisioned", "--smp", "1", "--memory", "512M", "--reserve-memory", "0M",
+			"--node-id", "0", "--check=false",
+			"--kafka-addr", "PLAINTEXT://0.0.0.0:9092",
+			"--advertise-kafka-addr", fmt.Sprintf("PLAINTEXT://%s", s.broker),
+		},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9092/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", redpandaPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start redpanda")
+	s.redpanda = redpanda
+
+	// wait for redpanda and create topic
+	t.Log("creating topic...")
+	err = dockerPool.Retry(func() error {
+		return s.createTopic()
+	})
+	assert.NoError(err, "failed to create topic")
+}
+
+func (s *kafkaTestSuite) TearDownSuite() {
+	if s.redpanda != nil {
+		if err := s.dockerPool.Purge(s.redpanda); err != nil {
+			s.T().Logf("failed to purge redpanda container - %v", err)
+		}
+	}
+}
+
+func (s *kafkaTestSuite) TestKafkaPublisher() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(s.broker),
+		Topic:        kafkaTestTopic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	outbox := repository.NewInMemoryEventOutboxRepository()
+	publisher := NewOutboxPublisher(NewKafkaPublisher(writer), outbox)
+
+	customerID := "ecc770d9-4576-4f72-affa-8b1454246692"
+	events := []*model.CustomerEvent{
+		{ID: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", Type: model.CustomerCreated, TenantID: "acme", CustomerID: customerID},
+		{ID: "1b2c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e", Type: model.CustomerUpdated, TenantID: "acme", CustomerID: customerID},
+		{ID: "2c3d4e5f-6a7b-4c8d-8e9f-1a2b3c4d5e6f", Type: model.CustomerDeleted, TenantID: "acme", CustomerID: customerID},
+	}
+
+	t.Log("events are kept in outbox until it is relayed")
+	{
+		for _, e := range events {
+			require.NoError(publisher.Publish(ctx, e), "no error must be raised")
+		}
+
+		pending, err := outbox.FindPending(ctx, outboxRelayBatchSize)
+		require.NoError(err, "failed to read outbox")
+		require.Len(pending, len(events), "events must be saved to outbox")
+	}
+
+	t.Log("outbox is relayed")
+	{
+		require.NoError(publisher.relay(ctx), "no error must be raised")
+
+		pending, err := outbox.FindPending(ctx, outboxRelayBatchSize)
+		require.NoError(err, "failed to read outbox")
+		require.Empty(pending, "outbox must be drained")
+	}
+
+	t.Log("events of customer are consumed from single partition in order")
+	{
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     []string{s.broker},
+			Topic:       kafkaTestTopic,
+			GroupID:     "customer-events-test-consumer",
+			StartOffset: kafka.FirstOffset,
+		})
+		defer reader.Close()
+
+		partition := -1
+		for _, expected := range events {
+			msg, err := reader.ReadMessage(ctx)
+			require.NoError(err, "failed to read message")
+
+			if partition == -1 {
+				partition = msg.Partition
+			}
+			require.Equal(partition, msg.Partition, "events of customer must land on the same partition")
+			require.Equal(customerID, string(msg.Key), "message must be keyed by customer id")
+			require.Equal([]kafka.Header{{Key: "eventType", Value: []byte(expected.Type)}}, msg.Headers, "event type must be passed in header")
+
+			var consumed struct {
+				SchemaVersion int    `json:"schemaVersion"`
+				ID            string `json:"id"`
+				CustomerID    string `json:"customerId"`
+			}
+			require.NoError(json.Unmarshal(msg.Value, &consumed), "message must be json")
+			require.Equal(SchemaVersion, consumed.SchemaVersion, "schema version must be set")
+			require.Equal(expected.ID, consumed.ID, "events must be consumed in order")
+		}
+	}
+}
+
+// createTopic creates test topic on controller broker, it fails until redpanda is ready
+func (s *kafkaTestSuite) createTopic() error {
+	conn, err := kafka.Dial("tcp", s.broker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	return controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             kafkaTestTopic,
+		NumPartitions:     kafkaTestPartitions,
+		ReplicationFactor: 1,
+	})
+}
+
+// start kafka test suite
+func TestKafkaTestSuite(t *testing.T) {
+	suite.Run(t, new(kafkaTestSuite))
+}
@@ -0,0 +1,46 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// eventTypeHeader carries type of customer event, so consumers can filter events without decoding them
+const eventTypeHeader = "Event-Type"
+
+type jetStreamPublisher struct {
+	js      nats.JetStreamContext
+	subject string
+	timeout time.Duration
+}
+
+// NewJetStreamPublisher builds publisher of events to JetStream subject as json. Message id is set to event id, which
+// identifies event outbox row as well, so event relayed again after its acknowledgement was lost or it wasn't
+// deleted from outbox is dropped by JetStream as duplicate within stream duplicate window
+func NewJetStreamPublisher(js nats.JetStreamContext, subject string, timeout time.Duration) Publisher {
+	return &jetStreamPublisher{js: js, subject: subject, timeout: timeout}
+}
+
+func (p *jetStreamPublisher) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	value, err := json.Marshal(newVersionedEvent(e))
+	if err != nil {
+		return fmt.Errorf("failed to serialize customer event %s - %w", e.ID, err)
+	}
+
+	msg := nats.NewMsg(p.subject)
+	msg.Data = value
+	msg.Header.Set(eventTypeHeader, string(e.Type))
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if _, err := p.js.PublishMsg(msg, nats.MsgId(e.ID), nats.Context(ctx)); err != nil {
+		return fmt.Errorf("jetstream: failed to publish customer event %s - %w", e.ID, err)
+	}
+	return nil
+}
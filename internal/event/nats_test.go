@@ -0,0 +1,162 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+const (
+	natsContainerName = "nats-event-test-customers"
+	natsPort          = "14222"
+	natsTestStream    = "CUSTOMER_EVENTS_TEST"
+	natsTestSubject   = "customers.events.test"
+)
+
+type natsTestSuite struct {
+	suite.Suite
+	dockerPool *dockertest.Pool
+	nats       *dockertest.Resource
+	conn       *nats.Conn
+	js         nats.JetStreamContext
+}
+
+func (s *natsTestSuite) SetupSuite() {
+	t := s.T()
+	assert := s.Require()
+
+	dockerPool, err := dockertest.NewPool("")
+	assert.NoError(err, "failed to create pool")
+
+	t.Log("sending ping to docker...")
+	err = dockerPool.Client.Ping()
+	assert.NoError(err, "failed to connect to docker")
+
+	s.dockerPool = dockerPool
+
+	t.Log("starting nats with jetstream...")
+	natsResource, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       natsContainerName,
+		Repository: "nats",
+		Tag:        "2.9",
+		Cmd:        []string{"-js"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"4222/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", natsPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start nats")
+	s.nats = natsResource
+
+	t.Log("connecting to nats...")
+	err = dockerPool.Retry(func() error {
+		conn, err := nats.Connect(fmt.Sprintf("nats://localhost:%s", natsPort))
+		if err != nil {
+			return err
+		}
+
+		js, err := conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+
+		s.conn, s.js = conn, js
+		return nil
+	})
+	assert.NoError(err, "failed to establish connection to nats")
+}
+
+func (s *natsTestSuite) TearDownSuite() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+
+	if s.nats != nil {
+		if err := s.dockerPool.Purge(s.nats); err != nil {
+			s.T().Logf("failed to purge nats container - %v", err)
+		}
+	}
+}
+
+func (s *natsTestSuite) SetupTest() {
+	_ = s.js.DeleteStream(natsTestStream)
+	_, err := s.js.AddStream(&nats.StreamConfig{
+		Name:       natsTestStream,
+		Subjects:   []string{natsTestSubject},
+		Storage:    nats.MemoryStorage,
+		Duplicates: time.Minute,
+	})
+	s.Require().NoError(err, "failed to create stream")
+}
+
+func (s *natsTestSuite) TestJetStreamPublisher() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	publisher := NewJetStreamPublisher(s.js, natsTestSubject, time.Second)
+	e := &model.CustomerEvent{
+		ID:         "5e4d3c2b-1a09-4f8e-9d7c-6b5a4f3e2d1c",
+		Type:       model.CustomerCreated,
+		TenantID:   "acme",
+		CustomerID: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d",
+		Customer:   &model.Customer{ID: "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", Importance: model.ImportanceHigh},
+		OccurredAt: time.Now().UTC(),
+	}
+
+	t.Log("event is published with its id as message id")
+	{
+		require.NoError(publisher.Publish(ctx, e), "failed to publish event")
+
+		sub, err := s.js.SubscribeSync(natsTestSubject, nats.DeliverAll())
+		require.NoError(err, "failed to subscribe")
+
+		msg, err := sub.NextMsgWithContext(ctx)
+		require.NoError(err, "failed to consume event")
+		require.NoError(sub.Unsubscribe(), "failed to unsubscribe")
+		require.Equal(e.ID, msg.Header.Get(nats.MsgIdHdr), "event id must be message id")
+		require.Equal(string(e.Type), msg.Header.Get(eventTypeHeader), "event type must be sent in header")
+
+		var consumed struct {
+			SchemaVersion int `json:"schemaVersion"`
+			*model.CustomerEvent
+		}
+		require.NoError(json.Unmarshal(msg.Data, &consumed), "event must be json encoded")
+		require.Equal(SchemaVersion, consumed.SchemaVersion, "schema version must be set")
+		require.Equal(e.ID, consumed.ID, "incorrect event")
+		require.Equal(model.ImportanceHigh.External(), consumed.Customer.Importance, "importance must be numbered as in http api")
+	}
+
+	t.Log("event relayed from outbox after it was published is dropped as duplicate")
+	{
+		outbox := repository.NewInMemoryEventOutboxRepository()
+		require.NoError(outbox.Save(ctx, e), "failed to save event to outbox")
+
+		outboxPublisher := NewOutboxPublisher(publisher, outbox)
+		require.NoError(outboxPublisher.relay(ctx), "failed to relay outbox")
+
+		info, err := s.js.StreamInfo(natsTestStream)
+		require.NoError(err, "failed to read stream info")
+		require.Equal(uint64(1), info.State.Msgs, "duplicate must be dropped")
+
+		pending, err := outbox.FindPending(ctx, 1)
+		require.NoError(err, "failed to read outbox")
+		require.Empty(pending, "duplicate must be removed from outbox")
+	}
+}
+
+// start nats test suite
+func TestNatsTestSuite(t *testing.T) {
+	suite.Run(t, new(natsTestSuite))
+}
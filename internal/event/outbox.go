@@ -0,0 +1,99 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+const outboxRelayBatchSize = 100
+
+// OutboxPublisher saves events to outbox and relays them to next publisher. Outbox is shared by all instances and relayed
+// by the one holding its lock only, so events are published in order they were saved whichever instance saved them.
+// Events are delivered at least once, so consumers must deduplicate them by id
+type OutboxPublisher struct {
+	next   Publisher
+	outbox repository.EventOutboxRepository
+	saved  chan struct{} // signals relay that event is saved, so it isn't delayed until next interval
+}
+
+// NewOutboxPublisher builds new OutboxPublisher
+func NewOutboxPublisher(next Publisher, outbox repository.EventOutboxRepository) *OutboxPublisher {
+	return &OutboxPublisher{next: next, outbox: outbox, saved: make(chan struct{}, 1)}
+}
+
+// Publish saves event to outbox and signals relay to publish it, error is returned if event isn't saved
+func (p *OutboxPublisher) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	if err := p.outbox.Save(ctx, e); err != nil {
+		return fmt.Errorf("failed to save customer event %s to outbox - %w", e.ID, err)
+	}
+
+	select {
+	case p.saved <- struct{}{}:
+	default: // relay is already signaled
+	}
+	return nil
+}
+
+// Relay publishes pending outbox events once event is saved and every interval until context is cancelled.
+// Events saved while outbox is relayed by another instance are published by it or on the next interval
+func (p *OutboxPublisher) Relay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.relay(ctx); err != nil {
+			logrus.Errorf("failed to relay outbox customer events - %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.saved:
+		}
+	}
+}
+
+// relay publishes pending events unless outbox is relayed by another instance sharing it
+func (p *OutboxPublisher) relay(ctx context.Context) error {
+	unlock, locked, err := p.outbox.TryLock(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !locked {
+		return nil
+	}
+	defer unlock()
+
+	return p.publishPending(ctx)
+}
+
+// publishPending publishes pending events in order, it stops on first failure, so the rest are not published ahead of it
+func (p *OutboxPublisher) publishPending(ctx context.Context) error {
+	for {
+		events, err := p.outbox.FindPending(ctx, outboxRelayBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		for _, e := range events {
+			if err := p.next.Publish(ctx, e); err != nil {
+				return fmt.Errorf("failed to publish customer event %s - %w", e.ID, err)
+			}
+
+			if err := p.outbox.DeleteByID(ctx, e.ID); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+type recordingPublisher struct {
+	mu        sync.Mutex
+	failing   bool
+	published []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e *model.CustomerEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failing {
+		return errors.New("broker is unavailable")
+	}
+	p.published = append(p.published, e.ID)
+	return nil
+}
+
+type outboxTestSuite struct {
+	suite.Suite
+	next      *recordingPublisher
+	outbox    repository.EventOutboxRepository
+	publisher *OutboxPublisher
+}
+
+func (s *outboxTestSuite) SetupTest() {
+	s.next = &recordingPublisher{}
+	s.outbox = repository.NewInMemoryEventOutboxRepository()
+	s.publisher = NewOutboxPublisher(s.next, s.outbox)
+}
+
+func (s *outboxTestSuite) TestPublish() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+
+	t.Log("event is saved to outbox and left to relay")
+	{
+		require.NoError(s.publisher.Publish(ctx, s.event("7b0c3a5e-1d2f-4e6a-8b9c-0d1e2f3a4b5c")), "no error must be raised")
+		require.Empty(s.next.published, "event must not be published ahead of relay")
+		require.Len(s.pending(), 1, "event must be saved to outbox")
+	}
+
+	t.Log("relay publishes outbox events in order")
+	{
+		require.NoError(s.publisher.Publish(ctx, s.event("c4d5e6f7-0a1b-4c2d-9e3f-5a6b7c8d9e0f")), "no error must be raised")
+		require.NoError(s.publisher.relay(ctx), "no error must be raised")
+		require.Empty(s.pending(), "outbox must be drained")
+		require.Equal([]string{
+			"7b0c3a5e-1d2f-4e6a-8b9c-0d1e2f3a4b5c",
+			"c4d5e6f7-0a1b-4c2d-9e3f-5a6b7c8d9e0f",
+		}, s.next.published, "events must be published in order")
+	}
+}
+
+func (s *outboxTestSuite) TestRelayFailed() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	require.NoError(s.publisher.Publish(ctx, s.event("1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d")), "no error must be raised")
+	require.NoError(s.publisher.Publish(ctx, s.event("9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a")), "no error must be raised")
+
+	t.Log("relay stops on failure and keeps events in outbox")
+	{
+		s.next.failing = true
+		require.Error(s.publisher.relay(ctx), "relay failure must be reported")
+		require.Len(s.pending(), 2, "events must be kept in outbox")
+	}
+
+	t.Log("relay publishes pending events in order once publisher is back")
+	{
+		s.next.failing = false
+		require.NoError(s.publisher.relay(ctx), "no error must be raised")
+		require.Empty(s.pending(), "outbox must be drained")
+		require.Equal([]string{
+			"1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d",
+			"9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a",
+		}, s.next.published, "events must be published in order")
+	}
+}
+
+func (s *outboxTestSuite) TestRelayLocked() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	other := NewOutboxPublisher(s.next, s.outbox) // another instance sharing outbox
+	require.NoError(s.publisher.Publish(ctx, s.event("5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f")), "no error must be raised")
+
+	unlock, locked, err := s.outbox.TryLock(ctx)
+	require.NoError(err, "failed to lock outbox")
+	require.True(locked, "outbox must be locked")
+
+	t.Log("outbox relayed by another instance is left to it")
+	{
+		require.NoError(other.Publish(ctx, s.event("6d7e8f9a-0b1c-4d2e-9f3a-4b5c6d7e8f9a")), "no error must be raised")
+		require.NoError(s.publisher.relay(ctx), "no error must be raised")
+		require.NoError(other.relay(ctx), "no error must be raised")
+		require.Empty(s.next.published, "events must not be published")
+		require.Len(s.pending(), 2, "events must be kept in outbox")
+	}
+
+	t.Log("outbox is relayed in order events were saved by any instance once it is unlocked")
+	{
+		unlock()
+		require.NoError(other.relay(ctx), "no error must be raised")
+		require.Empty(s.pending(), "outbox must be drained")
+		require.Equal([]string{
+			"5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f",
+			"6d7e8f9a-0b1c-4d2e-9f3a-4b5c6d7e8f9a",
+		}, s.next.published, "events must be published in order")
+	}
+}
+
+func (s *outboxTestSuite) TestRelay() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.publisher.Relay(ctx, time.Hour)
+
+	t.Log("saved event is relayed without waiting for relay interval")
+	{
+		require.NoError(s.publisher.Publish(ctx, s.event("7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9a0b")), "no error must be raised")
+		require.Eventually(func() bool {
+			s.next.mu.Lock()
+			defer s.next.mu.Unlock()
+			return len(s.next.published) == 1
+		}, 5*time.Second, 10*time.Millisecond, "event must be relayed")
+	}
+}
+
+func (s *outboxTestSuite) pending() []*model.CustomerEvent {
+	events, err := s.outbox.FindPending(context.Background(), outboxRelayBatchSize)
+	s.Require().NoError(err, "failed to read outbox")
+	return events
+}
+
+func (s *outboxTestSuite) event(id string) *model.CustomerEvent {
+	return &model.CustomerEvent{
+		ID:         id,
+		Type:       model.CustomerUpdated,
+		TenantID:   "acme",
+		CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692",
+	}
+}
+
+// start outbox test suite
+func TestOutboxTestSuite(t *testing.T) {
+	suite.Run(t, new(outboxTestSuite))
+}
@@ -0,0 +1,334 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/redact"
+)
+
+const (
+	customerEventsStream       = "customer-events"
+	customerEventsStreamMaxLen = 10000
+	readEventsMaxCount         = 10
+	readEventsBlockTime        = 0
+	consumeEventsBlockTime     = 5 * time.Second // consumer wakes up regularly to reclaim pending events
+	reclaimEventsMinIdle       = time.Minute
+	reclaimEventsBatchSize     = 100
+	reclaimEventsMaxDeliveries = 10
+	replayEventsBatchSize      = 100
+	consumerGroupExistsErr     = "BUSYGROUP"
+	streamEndID                = "$"
+	streamStartID              = "0-0"
+)
+
+// errMalformedMessage is raised if stream message can't be deserialized into event, such message is never retried
+var errMalformedMessage = errors.New("message is malformed")
+
+type redisStreamPublisher struct {
+	client *redis.Client
+}
+
+// NewRedisStreamPublisher builds publisher appending events to customer events redis stream
+func NewRedisStreamPublisher(client *redis.Client) Publisher {
+	return &redisStreamPublisher{client: client}
+}
+
+func (p *redisStreamPublisher) Publish(ctx context.Context, e *model.CustomerEvent) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to serialize customer event %s - %w", e.ID, err)
+	}
+
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: customerEventsStream,
+		MaxLen: customerEventsStreamMaxLen,
+		Approx: true,
+		ID:     "*",
+		Values: map[string]any{"value": value},
+	}).Err()
+}
+
+// RedisStreamConsumer reads customer events redis stream as member of consumer group,
+// so each event is handled by single instance of the group
+type RedisStreamConsumer struct {
+	client   *redis.Client
+	group    string
+	consumer string
+}
+
+// NewRedisStreamConsumer builds new RedisStreamConsumer, consumer must be unique within group, e.g. instance id
+func NewRedisStreamConsumer(client *redis.Client, group string, consumer string) *RedisStreamConsumer {
+	return &RedisStreamConsumer{client: client, group: group, consumer: consumer}
+}
+
+// Consume passes new events to handler until context is cancelled, events published before
+// consumer group was created are skipped. Event is acknowledged once it is handled, so event which handler
+// failed on is left pending and handled again once it is reclaimed. Events pending in any consumer of the group
+// for too long, e.g. consumer is gone, are reclaimed regularly
+func (c *RedisStreamConsumer) Consume(ctx context.Context, h Handler) error {
+	err := c.client.XGroupCreateMkStream(ctx, customerEventsStream, c.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), consumerGroupExistsErr) {
+		return fmt.Errorf("failed to create consumer group %s - %w", c.group, err)
+	}
+
+	logrus.Infof("starting to consume customer events as %s of group %s", c.consumer, c.group)
+
+	// events read, but not acknowledged before restart are handled first
+	if err := c.readPending(ctx, h); err != nil && !errors.Is(err, context.Canceled) {
+		logrus.Errorf("error occurred on reading pending customer events - %v", err)
+	}
+
+	var reclaimedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			if time.Since(reclaimedAt) >= reclaimEventsMinIdle {
+				if err := c.reclaim(ctx, h); err != nil && !errors.Is(err, context.Canceled) {
+					logrus.Errorf("error occurred on reclaiming pending customer events - %v", err)
+				}
+				reclaimedAt = time.Now()
+			}
+
+			if err := c.read(ctx, h); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, redis.Nil) {
+				logrus.Errorf("error occurred on reading customer events - %v", err)
+			}
+		}
+	}
+}
+
+func (c *RedisStreamConsumer) read(ctx context.Context, h Handler) error {
+	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.consumer,
+		Streams:  []string{customerEventsStream, ">"},
+		Count:    readEventsMaxCount,
+		Block:    consumeEventsBlockTime,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		for _, m := range stream.Messages {
+			c.handle(ctx, m, h)
+		}
+	}
+	return nil
+}
+
+// readPending handles events delivered to consumer, but not acknowledged yet
+func (c *RedisStreamConsumer) readPending(ctx context.Context, h Handler) error {
+	lastID := streamStartID
+	for {
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{customerEventsStream, lastID},
+			Count:    readEventsMaxCount,
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return nil
+		}
+
+		for _, m := range streams[0].Messages {
+			c.handle(ctx, m, h)
+			lastID = m.ID
+		}
+	}
+}
+
+// reclaim claims events pending in any consumer of the group for too long and handles them,
+// events delivered too many times are acknowledged without handling, so they don't stay pending forever
+func (c *RedisStreamConsumer) reclaim(ctx context.Context, h Handler) error {
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: customerEventsStream,
+		Group:  c.group,
+		Idle:   reclaimEventsMinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  reclaimEventsBatchSize,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(pending))
+	for _, p := range pending {
+		if p.RetryCount >= reclaimEventsMaxDeliveries {
+			logrus.Errorf("customer event message %s is given up after %d deliveries", p.ID, p.RetryCount)
+			c.ack(ctx, p.ID)
+			continue
+		}
+		ids = append(ids, p.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	messages, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   customerEventsStream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		MinIdle:  reclaimEventsMinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		c.handle(ctx, m, h)
+	}
+	return nil
+}
+
+// handle passes message to handler and acknowledges it once it is handled, malformed message is acknowledged as well
+func (c *RedisStreamConsumer) handle(ctx context.Context, m redis.XMessage, h Handler) {
+	if err := process(ctx, m, h); err != nil {
+		logrus.Errorf("error occurred on customer event message %s processing - %s", m.ID, redact.Text(err.Error()))
+		if !errors.Is(err, errMalformedMessage) {
+			return
+		}
+	}
+	c.ack(ctx, m.ID)
+}
+
+func (c *RedisStreamConsumer) ack(ctx context.Context, id string) {
+	if err := c.client.XAck(ctx, customerEventsStream, c.group, id).Err(); err != nil {
+		logrus.Errorf("failed to acknowledge customer event message %s - %v", id, err)
+	}
+}
+
+// RedisStreamReader reads customer events redis stream without consumer group,
+// so each instance receives every event
+type RedisStreamReader struct {
+	client *redis.Client
+}
+
+// NewRedisStreamReader builds new RedisStreamReader
+func NewRedisStreamReader(client *redis.Client) *RedisStreamReader {
+	return &RedisStreamReader{client: client}
+}
+
+// Read passes events published after start to handler along with their stream ids until context is cancelled.
+// End of stream is resolved to id once, so events published between reads or while redis is unavailable are not skipped
+func (r *RedisStreamReader) Read(ctx context.Context, h StreamHandler) error {
+	lastID := streamEndID
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if lastID == streamEndID {
+			id, err := r.endID(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					logrus.Errorf("failed to find the last customer event - %v", err)
+				}
+				continue
+			}
+			lastID = id
+		}
+
+		id, err := r.readBlocking(ctx, lastID, readEventsBlockTime, h)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logrus.Errorf("error occurred on reading customer events - %v", err)
+		}
+		lastID = id
+	}
+}
+
+// Replay passes events published after afterID which are still kept in stream to handler and returns id of the last one,
+// afterID is returned if there are none. It doesn't wait for new events, so it is used to catch up before live events
+func (r *RedisStreamReader) Replay(ctx context.Context, afterID string, h StreamHandler) (string, error) {
+	for {
+		msgs, err := r.client.XRangeN(ctx, customerEventsStream, "("+afterID, "+", replayEventsBatchSize).Result()
+		if err != nil {
+			return afterID, err
+		}
+
+		for _, m := range msgs {
+			afterID = m.ID
+			err := process(ctx, m, func(ctx context.Context, e *model.CustomerEvent) error {
+				return h(ctx, m.ID, e)
+			})
+			if err != nil {
+				logrus.Errorf("error occurred on customer event message %s processing - %s", m.ID, redact.Text(err.Error()))
+			}
+		}
+
+		if len(msgs) < replayEventsBatchSize {
+			return afterID, nil
+		}
+	}
+}
+
+// endID returns id of the newest event in stream, zero id is returned for empty stream
+func (r *RedisStreamReader) endID(ctx context.Context) (string, error) {
+	msgs, err := r.client.XRevRangeN(ctx, customerEventsStream, "+", "-", 1).Result()
+	if err != nil {
+		return streamEndID, err
+	}
+
+	if len(msgs) == 0 {
+		return streamStartID, nil
+	}
+	return msgs[0].ID, nil
+}
+
+// readBlocking handles batch of events published after lastID waiting up to block for them, zero block waits
+// indefinitely, redis.Nil is returned if no events are published meanwhile
+func (r *RedisStreamReader) readBlocking(ctx context.Context, lastID string, block time.Duration, h StreamHandler) (string, error) {
+	streams, err := r.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{customerEventsStream, lastID},
+		Count:   readEventsMaxCount,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		return lastID, err
+	}
+
+	for _, stream := range streams {
+		for _, m := range stream.Messages {
+			lastID = m.ID
+			err := process(ctx, m, func(ctx context.Context, e *model.CustomerEvent) error {
+				return h(ctx, m.ID, e)
+			})
+			if err != nil {
+				logrus.Errorf("error occurred on customer event message %s processing - %s", m.ID, redact.Text(err.Error()))
+			}
+		}
+	}
+	return lastID, nil
+}
+
+func process(ctx context.Context, m redis.XMessage, h Handler) error {
+	value, ok := m.Values["value"].(string)
+	if !ok {
+		return fmt.Errorf("%w - value field is missing, skipped", errMalformedMessage)
+	}
+
+	var e model.CustomerEvent
+	if err := json.Unmarshal([]byte(value), &e); err != nil {
+		return fmt.Errorf("%w - failed to deserialize customer event - %v", errMalformedMessage, err)
+	}
+
+	return h(ctx, &e)
+}
@@ -0,0 +1,2 @@
+// Package feature contains feature flags used to toggle application functionality per environment
+package feature
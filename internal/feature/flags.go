@@ -0,0 +1,65 @@
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// ImagesList gates images listing endpoint
+	ImagesList = "images-list"
+	// ImagesDelete gates image deletion endpoint
+	ImagesDelete = "images-delete"
+)
+
+// Flags holds feature flags loaded from json file with flag name to enabled pairs.
+// Flags missing in the file are treated as enabled.
+type Flags struct {
+	path  string
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// NewFlags builds new Flags and loads them from provided file, all flags are enabled if path is empty
+func NewFlags(path string) (*Flags, error) {
+	f := &Flags{path: path, flags: make(map[string]bool)}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Enabled checks if feature with provided name is enabled
+func (f *Flags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	enabled, ok := f.flags[name]
+	return !ok || enabled
+}
+
+// Reload re-reads flags from file, flags are left untouched if file can't be read
+func (f *Flags) Reload() error {
+	if f.path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(filepath.Clean(f.path))
+	if err != nil {
+		return fmt.Errorf("failed to read feature flags file - %w", err)
+	}
+
+	flags := make(map[string]bool)
+	if err := json.Unmarshal(content, &flags); err != nil {
+		return fmt.Errorf("failed to decode feature flags - %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags = flags
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// acceptedMediaType is media type listed in Accept header along with its quality
+type acceptedMediaType struct {
+	mediaType string
+	quality   float64
+}
+
+// acceptedMediaTypes parses Accept header keeping order of media types, malformed entries are skipped
+func acceptedMediaTypes(r *http.Request) []acceptedMediaType {
+	accepted := make([]acceptedMediaType, 0)
+	for _, accept := range strings.Split(r.Header.Get(echo.HeaderAccept), ",") {
+		mediaType, params, err := mime.ParseMediaType(accept)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		accepted = append(accepted, acceptedMediaType{mediaType: mediaType, quality: quality})
+	}
+	return accepted
+}
+
+// preferredMediaType negotiates response media type. Offers map accepted media type to the one it is served as,
+// e.g. json flavors are served as json. Offer with the highest quality wins, the one listed first wins on equal quality.
+// Empty string is returned if client accepts none of offers
+func preferredMediaType(r *http.Request, offers map[string]string) string {
+	preferred, preferredQuality := "", 0.0
+	for _, accepted := range acceptedMediaTypes(r) {
+		served, ok := offers[accepted.mediaType]
+		if !ok {
+			continue
+		}
+
+		if accepted.quality > preferredQuality {
+			preferred, preferredQuality = served, accepted.quality
+		}
+	}
+	return preferred
+}
+
+// acceptsMediaType reports if media type is listed in Accept header
+func acceptsMediaType(r *http.Request, mediaType string) bool {
+	for _, accepted := range acceptedMediaTypes(r) {
+		if accepted.mediaType == mediaType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type acceptTestSuite struct {
+	suite.Suite
+}
+
+func (s *acceptTestSuite) TestPreferredMediaType() {
+	t := s.T()
+	require := s.Require()
+
+	request := func(accept string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		r.Header.Set(echo.HeaderAccept, accept)
+		return r
+	}
+
+	t.Log("offer with higher quality wins")
+	{
+		r := request("application/json;q=0.5, application/msgpack")
+		require.True(prefersMsgpack(r), "msgpack must be preferred")
+		require.False(prefersProblemJSON(r), "problem json is not accepted")
+	}
+
+	t.Log("offer listed first wins on equal quality")
+	{
+		require.False(prefersMsgpack(request("application/json, application/msgpack")), "json must be preferred")
+		require.True(prefersProblemJSON(request("application/problem+json, application/json")), "problem json must be preferred")
+	}
+
+	t.Log("aliased offer is served as its target media type")
+	{
+		r := request(MIMEApplicationListEnvelopeJSON + ", application/msgpack;q=0.9")
+		require.False(prefersMsgpack(r), "list envelope is json flavor")
+		require.True(acceptsListEnvelope(r), "list envelope must be accepted")
+	}
+
+	t.Log("malformed, zero quality and unknown entries are skipped")
+	{
+		r := request("application/msgpack;q=abc, application/problem+json;q=0, text/html")
+		require.Empty(preferredMediaType(r, map[string]string{
+			MIMEApplicationMsgpack:     MIMEApplicationMsgpack,
+			MIMEApplicationProblemJSON: MIMEApplicationProblemJSON,
+		}), "no offer must be negotiated")
+	}
+}
+
+// start accept test suite
+func TestAcceptTestSuite(t *testing.T) {
+	suite.Run(t, new(acceptTestSuite))
+}
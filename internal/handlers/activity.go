@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/service"
+)
+
+const defaultActivityLimit = 50
+
+type activityQuery struct {
+	Limit int `query:"limit" validate:"min=1,max=100"`
+}
+
+// CustomerActivityHTTPHandler is http handler for recent activity of customers admin endpoint
+type CustomerActivityHTTPHandler struct {
+	activitySvc service.CustomerActivityService
+}
+
+// NewCustomerActivityHTTPHandler builds new CustomerActivityHTTPHandler
+func NewCustomerActivityHTTPHandler(activitySvc service.CustomerActivityService) *CustomerActivityHTTPHandler {
+	return &CustomerActivityHTTPHandler{activitySvc: activitySvc}
+}
+
+// RecentActivity returns recent events of customer
+// @Summary     Recent customer activity
+// @Description Returns the newest events of caller tenant customer occurred within configured window, e.g. the last hour.
+// @Description Events are read from capped collection, so the oldest events may be already overwritten.
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Param       id    path     string true  "Customer guid" Format(uuid)
+// @Param       limit query    int    false "Max number of events" minimum(1) maximum(100) default(50)
+// @Success     200   {array}  model.CustomerEvent
+// @Failure     400   {object} errorEnvelope
+// @Failure     401   {object} errorEnvelope
+// @Failure     403   {object} errorEnvelope
+// @Failure     500   {object} errorEnvelope
+// @Router      /api/admin/customers/{id}/recent-activity [get]
+func (h *CustomerActivityHTTPHandler) RecentActivity(c echo.Context) error {
+	id, err := pathUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	q := activityQuery{Limit: defaultActivityLimit}
+	if err := c.Bind(&q); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(&q); err != nil {
+		return inURL(err)
+	}
+
+	events, err := h.activitySvc.FindRecent(c.Request().Context(), id, q.Limit)
+	if err != nil {
+		return err
+	}
+
+	external := make([]*model.CustomerEvent, len(events))
+	for i, e := range events {
+		external[i] = e.External()
+	}
+	return c.JSON(http.StatusOK, external)
+}
@@ -0,0 +1,690 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/interceptors"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/internal/validation"
+	"github.com/umalmyha/customers/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// conformanceOutcome is transport agnostic result of customer API call
+type conformanceOutcome string
+
+const (
+	outcomeOK         conformanceOutcome = "ok"
+	outcomeNotFound   conformanceOutcome = "not found"
+	outcomeBadRequest conformanceOutcome = "bad request"
+	outcomeFailed     conformanceOutcome = "failed"
+)
+
+// importance values of public contracts, they are spelled out instead of converted to catch drift between them
+var (
+	httpImportances = map[model.Importance]int{
+		model.ImportanceLow:      1,
+		model.ImportanceMedium:   2,
+		model.ImportanceHigh:     3,
+		model.ImportanceCritical: 4,
+	}
+	grpcImportances = map[model.Importance]proto.CustomerImportance{
+		model.ImportanceLow:      proto.CustomerImportance_LOW,
+		model.ImportanceMedium:   proto.CustomerImportance_MEDIUM,
+		model.ImportanceHigh:     proto.CustomerImportance_HIGH,
+		model.ImportanceCritical: proto.CustomerImportance_CRITICAL,
+	}
+)
+
+// customerAPI is customer endpoint as seen by client, tenant is taken from context
+type customerAPI interface {
+	Create(context.Context, *model.Customer) (*model.Customer, conformanceOutcome)
+	Get(context.Context, string) (*model.Customer, conformanceOutcome)
+	List(context.Context) ([]*model.Customer, conformanceOutcome)
+	Update(context.Context, *model.Customer) (*model.Customer, conformanceOutcome)
+	Delete(context.Context, string) conformanceOutcome
+}
+
+type customerConformanceCase struct {
+	name string
+	// run performs scenario against tenant with single seeded customer, outcome of the first failed step is returned,
+	// other is api of another transport to verify data is seen the same way by all clients
+	run         func(ctx context.Context, api, other customerAPI, seeded *model.Customer) (any, conformanceOutcome)
+	want        func(seeded *model.Customer) any
+	wantOutcome conformanceOutcome
+}
+
+const unknownCustomerID = "9b7c6d5e-4f3a-4b2c-8d1e-0f9a8b7c6d5e"
+
+func customerConformanceCases() []customerConformanceCase {
+	cases := make([]customerConformanceCase, 0)
+
+	for _, importance := range []model.Importance{model.ImportanceLow, model.ImportanceMedium, model.ImportanceHigh, model.ImportanceCritical} {
+		importance := importance
+		cases = append(cases, customerConformanceCase{
+			name: fmt.Sprintf("importance %d is kept across transports", importance),
+			run: func(ctx context.Context, api, other customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				created, outcome := api.Create(ctx, &model.Customer{
+					FirstName:  "Important",
+					LastName:   "Customer",
+					Email:      "important@conformance.com",
+					Importance: importance,
+				})
+				if outcome != outcomeOK {
+					return nil, outcome
+				}
+
+				c, outcome := other.Get(ctx, created.ID)
+				if outcome != outcomeOK {
+					return nil, outcome
+				}
+				return c.Importance, outcome
+			},
+			want: func(*model.Customer) any {
+				return importance
+			},
+			wantOutcome: outcomeOK,
+		})
+	}
+
+	return append(cases, []customerConformanceCase{
+		{
+			name: "create stores empty middle name as null",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				empty := ""
+				created, outcome := api.Create(ctx, &model.Customer{
+					FirstName:  "Empty",
+					LastName:   "Middle",
+					MiddleName: &empty,
+					Email:      "empty.middle@conformance.com",
+					Importance: model.ImportanceLow,
+				})
+				if outcome != outcomeOK {
+					return nil, outcome
+				}
+
+				c, outcome := api.Get(ctx, created.ID)
+				if outcome != outcomeOK {
+					return nil, outcome
+				}
+				return c.MiddleName, outcome
+			},
+			want: func(*model.Customer) any {
+				return (*string)(nil)
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "create with taken email is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				duplicate := *seeded
+				duplicate.ID = ""
+				duplicate.FirstName = "Duplicate"
+				return api.Create(ctx, &duplicate)
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "create with malformed email is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return api.Create(ctx, &model.Customer{
+					FirstName:  "Malformed",
+					LastName:   "Email",
+					Email:      "malformed.email",
+					Importance: model.ImportanceLow,
+				})
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "create of inactive critical customer is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return api.Create(ctx, &model.Customer{
+					FirstName:  "Inactive",
+					LastName:   "Critical",
+					Email:      "inactive.critical@conformance.com",
+					Importance: model.ImportanceCritical,
+					Inactive:   true,
+				})
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "get returns customer",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				return api.Get(ctx, seeded.ID)
+			},
+			want: func(seeded *model.Customer) any {
+				return seeded
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "get of unknown customer is not found",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return api.Get(ctx, unknownCustomerID)
+			},
+			wantOutcome: outcomeNotFound,
+		},
+		{
+			name: "get with malformed id is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return api.Get(ctx, "malformed-id")
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "list returns customers of tenant only",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				other := tenant.ContextWithID(ctx, tenant.IDFromContext(ctx)+"-other")
+				if _, outcome := api.Create(other, &model.Customer{
+					FirstName:  "Other",
+					LastName:   "Tenant",
+					Email:      seeded.Email,
+					Importance: model.ImportanceLow,
+				}); outcome != outcomeOK {
+					return nil, outcome
+				}
+				return api.List(ctx)
+			},
+			want: func(seeded *model.Customer) any {
+				return []*model.Customer{seeded}
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "update changes customer",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				if _, outcome := api.Update(ctx, updatedConformanceCustomer(seeded)); outcome != outcomeOK {
+					return nil, outcome
+				}
+				return api.Get(ctx, seeded.ID)
+			},
+			want: func(seeded *model.Customer) any {
+				return updatedConformanceCustomer(seeded)
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "update of unknown customer creates it",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				upserted := updatedConformanceCustomer(seeded)
+				upserted.ID = unknownCustomerID
+				if _, outcome := api.Update(ctx, upserted); outcome != outcomeOK {
+					return nil, outcome
+				}
+				return api.Get(ctx, unknownCustomerID)
+			},
+			want: func(seeded *model.Customer) any {
+				upserted := updatedConformanceCustomer(seeded)
+				upserted.ID = unknownCustomerID
+				return upserted
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "update with email of another customer is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				another, outcome := api.Create(ctx, &model.Customer{
+					FirstName:  "Another",
+					LastName:   "Customer",
+					Email:      "another@conformance.com",
+					Importance: model.ImportanceLow,
+				})
+				if outcome != outcomeOK {
+					return nil, outcome
+				}
+
+				another.Email = seeded.Email
+				return api.Update(ctx, another)
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "update to inactive critical customer is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				updated := updatedConformanceCustomer(seeded)
+				updated.Importance = model.ImportanceCritical
+				return api.Update(ctx, updated)
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+		{
+			name: "delete removes customer",
+			run: func(ctx context.Context, api, _ customerAPI, seeded *model.Customer) (any, conformanceOutcome) {
+				if outcome := api.Delete(ctx, seeded.ID); outcome != outcomeOK {
+					return nil, outcome
+				}
+				return api.Get(ctx, seeded.ID)
+			},
+			wantOutcome: outcomeNotFound,
+		},
+		{
+			name: "delete of unknown customer succeeds",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return nil, api.Delete(ctx, unknownCustomerID)
+			},
+			wantOutcome: outcomeOK,
+		},
+		{
+			name: "delete with malformed id is rejected",
+			run: func(ctx context.Context, api, _ customerAPI, _ *model.Customer) (any, conformanceOutcome) {
+				return nil, api.Delete(ctx, "malformed-id")
+			},
+			wantOutcome: outcomeBadRequest,
+		},
+	}...)
+}
+
+func updatedConformanceCustomer(seeded *model.Customer) *model.Customer {
+	middleName := "Updated"
+	return &model.Customer{
+		ID:         seeded.ID,
+		FirstName:  seeded.FirstName + "Updated",
+		LastName:   seeded.LastName + "Updated",
+		MiddleName: &middleName,
+		Email:      "updated@conformance.com",
+		Importance: model.ImportanceHigh,
+		Inactive:   true,
+	}
+}
+
+// runCustomerConformance runs the same scenarios via http and gRPC against customer service backed by provided repository,
+// every scenario gets own tenant, so backend may keep data of previous runs
+func runCustomerConformance(t *testing.T, backend string, customerRps repository.CustomerRepository) {
+	customerSvc := service.NewCustomerService(customerRps, cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+
+	grpcAPI, stop := newGrpcCustomerAPI(t, customerSvc)
+	defer stop()
+
+	apis := []struct {
+		transport string
+		api       customerAPI
+	}{
+		{transport: "http", api: newHTTPCustomerAPI(t, customerSvc)},
+		{transport: "grpc", api: grpcAPI},
+	}
+
+	for j, a := range apis {
+		other := apis[(j+1)%len(apis)].api
+		for i, tc := range customerConformanceCases() {
+			a, tc := a, tc
+			tenantID := fmt.Sprintf("conformance-%s-%s-%d", backend, a.transport, i)
+
+			t.Run(fmt.Sprintf("%s/%s/%s", backend, a.transport, tc.name), func(t *testing.T) {
+				require := require.New(t)
+				ctx := tenant.ContextWithID(context.Background(), tenantID)
+
+				seeded, outcome := a.api.Create(ctx, &model.Customer{
+					FirstName:  "Seeded",
+					LastName:   "Customer",
+					Email:      "seeded@conformance.com",
+					Importance: model.ImportanceMedium,
+				})
+				require.Equal(outcomeOK, outcome, "failed to seed customer")
+
+				res, outcome := tc.run(ctx, a.api, other, seeded)
+				require.Equal(tc.wantOutcome, outcome, "unexpected outcome")
+				if tc.want != nil {
+					require.Equal(tc.want(seeded), res, "unexpected result")
+				}
+			})
+		}
+	}
+}
+
+func TestCustomerConformanceInMemory(t *testing.T) {
+	runCustomerConformance(t, "memory", repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant))
+}
+
+// httpCustomer is customer as encoded in http api
+type httpCustomer struct {
+	ID         string  `json:"id,omitempty"`
+	FirstName  string  `json:"firstName"`
+	LastName   string  `json:"lastName"`
+	MiddleName *string `json:"middleName"`
+	Email      string  `json:"email"`
+	Importance int     `json:"importance"`
+	Inactive   bool    `json:"inactive"`
+}
+
+type httpCustomerAPI struct {
+	app     *echo.Echo
+	handler *CustomerHTTPHandler
+}
+
+func newHTTPCustomerAPI(t *testing.T, customerSvc service.CustomerService) customerAPI {
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	RegisterCustomerValidation(v)
+
+	uni, err := validation.Translations(v)
+	require.NoError(t, err, "failed to register validation translations")
+
+	app := echo.New()
+	app.Validator = validation.Echo(v, uni)
+
+	return &httpCustomerAPI{app: app, handler: NewCustomerHTTPHandler(customerSvc, false)}
+}
+
+func (a *httpCustomerAPI) Create(ctx context.Context, c *model.Customer) (*model.Customer, conformanceOutcome) {
+	ec, rec := a.context(ctx, http.MethodPost, "", a.payload(c))
+	if err := a.handler.Post(ec); err != nil {
+		return nil, a.outcome(err)
+	}
+	return a.customer(rec)
+}
+
+func (a *httpCustomerAPI) Get(ctx context.Context, id string) (*model.Customer, conformanceOutcome) {
+	ec, rec := a.context(ctx, http.MethodGet, id, "")
+	if err := a.handler.Get(ec); err != nil {
+		return nil, a.outcome(err)
+	}
+
+	if strings.TrimSpace(rec.Body.String()) == "null" { // missing customer is responded with null
+		return nil, outcomeNotFound
+	}
+	return a.customer(rec)
+}
+
+func (a *httpCustomerAPI) List(ctx context.Context) ([]*model.Customer, conformanceOutcome) {
+	ec, rec := a.context(ctx, http.MethodGet, "", "")
+	if err := a.handler.GetAll(ec); err != nil {
+		return nil, a.outcome(err)
+	}
+
+	var encoded []httpCustomer
+	if err := json.Unmarshal(rec.Body.Bytes(), &encoded); err != nil {
+		return nil, outcomeFailed
+	}
+
+	customers := make([]*model.Customer, len(encoded))
+	for i := range encoded {
+		customers[i] = a.model(&encoded[i])
+	}
+	return sortedByEmail(customers), outcomeOK
+}
+
+func (a *httpCustomerAPI) Update(ctx context.Context, c *model.Customer) (*model.Customer, conformanceOutcome) {
+	ec, rec := a.context(ctx, http.MethodPut, c.ID, a.payload(c))
+	if err := a.handler.Put(ec); err != nil {
+		return nil, a.outcome(err)
+	}
+	return a.customer(rec)
+}
+
+func (a *httpCustomerAPI) Delete(ctx context.Context, id string) conformanceOutcome {
+	ec, _ := a.context(ctx, http.MethodDelete, id, "")
+	return a.outcome(a.handler.DeleteByID(ec))
+}
+
+func (a *httpCustomerAPI) context(ctx context.Context, method, id, payload string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/api/v1/customers", strings.NewReader(payload)).WithContext(ctx)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := a.app.NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	return c, rec
+}
+
+func (a *httpCustomerAPI) payload(c *model.Customer) string {
+	payload, _ := json.Marshal(&httpCustomer{
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: httpImportances[c.Importance],
+		Inactive:   c.Inactive,
+	})
+	return string(payload)
+}
+
+func (a *httpCustomerAPI) customer(rec *httptest.ResponseRecorder) (*model.Customer, conformanceOutcome) {
+	var encoded httpCustomer
+	if err := json.Unmarshal(rec.Body.Bytes(), &encoded); err != nil {
+		return nil, outcomeFailed
+	}
+	return a.model(&encoded), outcomeOK
+}
+
+func (a *httpCustomerAPI) model(c *httpCustomer) *model.Customer {
+	m := &model.Customer{
+		ID:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Inactive:   c.Inactive,
+	}
+
+	for importance, encoded := range httpImportances {
+		if encoded == c.Importance {
+			m.Importance = importance
+		}
+	}
+	return m
+}
+
+func (a *httpCustomerAPI) outcome(err error) conformanceOutcome {
+	if err == nil {
+		return outcomeOK
+	}
+
+	switch httpStatus(err) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return outcomeBadRequest
+	case http.StatusNotFound:
+		return outcomeNotFound
+	default:
+		return outcomeFailed
+	}
+}
+
+// tenantClaims stands in for Authorize middleware, request is authenticated as user of tenant passed in X-Tenant-ID header
+func tenantClaims() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			claims := auth.JwtClaims{TenantID: req.Header.Get(middleware.TenantHeader)}
+			c.SetRequest(req.WithContext(auth.ContextWithClaims(req.Context(), claims)))
+			return next(c)
+		}
+	}
+}
+
+// tenantClaimsUnaryInterceptor stands in for auth interceptor, call is authenticated as user of tenant passed in tenantId metadata
+func tenantClaimsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		return h(contextWithTenantClaims(ctx), req)
+	}
+}
+
+// tenantClaimsStreamInterceptor stands in for auth interceptor of streaming calls
+func tenantClaimsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		return h(srv, &claimsServerStream{ServerStream: ss, ctx: contextWithTenantClaims(ss.Context())})
+	}
+}
+
+type claimsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *claimsServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func contextWithTenantClaims(ctx context.Context) context.Context {
+	var claims auth.JwtClaims
+	if headers, ok := metadata.FromIncomingContext(ctx); ok {
+		if tenantHdr := headers.Get("tenantId"); len(tenantHdr) > 0 {
+			claims.TenantID = tenantHdr[0]
+		}
+	}
+	return auth.ContextWithClaims(ctx, claims)
+}
+
+type grpcCustomerAPI struct {
+	client proto.CustomerServiceClient
+}
+
+// newGrpcCustomerAPI serves customer service over in-memory connection with the same interceptors as in production,
+// except of auth one which is replaced with tenant claims stand-in
+func newGrpcCustomerAPI(t *testing.T, customerSvc service.CustomerService) (customerAPI, func()) {
+	listener := bufconn.Listen(grpcConnBufSize)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		tenantClaimsUnaryInterceptor(),
+		interceptors.TenantUnaryInterceptor(),
+		interceptors.ValidatorUnaryInterceptor(true),
+		interceptors.ErrorUnaryInterceptor(),
+	))
+	proto.RegisterCustomerServiceServer(server, NewCustomerGrpcHandler(customerSvc, event.NewBroker(1)))
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err, "failed to create gRPC connection")
+
+	return &grpcCustomerAPI{client: proto.NewCustomerServiceClient(conn)}, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func (a *grpcCustomerAPI) Create(ctx context.Context, c *model.Customer) (*model.Customer, conformanceOutcome) {
+	res, err := a.client.Create(a.context(ctx), &proto.NewCustomerRequest{
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: grpcImportances[c.Importance],
+		Inactive:   c.Inactive,
+	})
+	if err != nil {
+		return nil, a.outcome(err)
+	}
+	return a.model(res), outcomeOK
+}
+
+func (a *grpcCustomerAPI) Get(ctx context.Context, id string) (*model.Customer, conformanceOutcome) {
+	res, err := a.client.GetByID(a.context(ctx), &proto.GetCustomerByIdRequest{Id: id})
+	if err != nil {
+		return nil, a.outcome(err)
+	}
+	return a.model(res), outcomeOK
+}
+
+func (a *grpcCustomerAPI) List(ctx context.Context) ([]*model.Customer, conformanceOutcome) {
+	res, err := a.client.GetAll(a.context(ctx), new(emptypb.Empty))
+	if err != nil {
+		return nil, a.outcome(err)
+	}
+
+	customers := make([]*model.Customer, len(res.Customers))
+	for i, c := range res.Customers {
+		customers[i] = a.model(c)
+	}
+	return sortedByEmail(customers), outcomeOK
+}
+
+func (a *grpcCustomerAPI) Update(ctx context.Context, c *model.Customer) (*model.Customer, conformanceOutcome) {
+	res, err := a.client.Upsert(a.context(ctx), &proto.UpdateCustomerRequest{
+		Id:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: grpcImportances[c.Importance],
+		Inactive:   c.Inactive,
+	})
+	if err != nil {
+		return nil, a.outcome(err)
+	}
+	return a.model(res), outcomeOK
+}
+
+func (a *grpcCustomerAPI) Delete(ctx context.Context, id string) conformanceOutcome {
+	_, err := a.client.DeleteByID(a.context(ctx), &proto.DeleteCustomerByIdRequest{Id: id})
+	return a.outcome(err)
+}
+
+func (a *grpcCustomerAPI) context(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "tenantId", tenant.IDFromContext(ctx))
+}
+
+func (a *grpcCustomerAPI) model(c *proto.CustomerResponse) *model.Customer {
+	m := &model.Customer{
+		ID:         c.Id,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Inactive:   c.Inactive,
+	}
+
+	for importance, encoded := range grpcImportances {
+		if encoded == c.Importance {
+			m.Importance = importance
+		}
+	}
+	return m
+}
+
+func (a *grpcCustomerAPI) outcome(err error) conformanceOutcome {
+	switch status.Code(err) {
+	case codes.OK:
+		return outcomeOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.AlreadyExists: // taken email is 400 over http
+		return outcomeBadRequest
+	case codes.NotFound:
+		return outcomeNotFound
+	default:
+		return outcomeFailed
+	}
+}
+
+func sortedByEmail(customers []*model.Customer) []*model.Customer {
+	sort.Slice(customers, func(i, j int) bool {
+		return customers[i].Email < customers[j].Email
+	})
+	return customers
+}
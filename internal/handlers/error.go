@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/internal/validation"
+	"github.com/umalmyha/customers/pkg/logging"
+	"github.com/umalmyha/customers/pkg/redact"
+)
+
+// MIMEApplicationProblemJSON is media type clients accept to get errors as RFC 7807 problem details
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+const (
+	payloadErrorMessage = "payload validation failed"
+	problemTypePrefix   = "urn:customers:error:" // followed by error code, e.g. urn:customers:error:not_found
+)
+
+// errorEnvelope is body of every http error response, details are filled for payload validation errors only
+type errorEnvelope struct {
+	Code      string                 `json:"code"` // status text in snake case, e.g. not_found
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"requestId"`
+	Details   []validation.Violation `json:"details"`
+}
+
+// problemDetails is RFC 7807 representation of errorEnvelope, violations are extension member
+type problemDetails struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Status     int                    `json:"status"`
+	Detail     string                 `json:"detail"`
+	Instance   string                 `json:"instance,omitempty"` // request id
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+// NewHTTPErrorHandler builds echo error handler responding with errorEnvelope or problemDetails if client prefers it, payload violations are translated
+// to locale accepted by client, messages of 5xx errors are replaced with status text in production mode
+func NewHTTPErrorHandler(v *validation.EchoValidator, production bool) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		requestID := requestid.IDFromContext(c.Request().Context())
+		logging.FromContext(c.Request().Context()).WithField(logging.FieldRequestID, requestID).Errorf("error occurred during request processing - %s", redact.Text(err.Error()))
+
+		if c.Response().Committed {
+			return
+		}
+
+		status, envelope := errorResponse(err, v.Translator(c.Request().Header.Get("Accept-Language")))
+		if production && status >= http.StatusInternalServerError {
+			envelope.Message = http.StatusText(status)
+		}
+		envelope.RequestID = requestID
+
+		var unavailableErr *appErrors.UnavailableErr
+		if errors.As(err, &unavailableErr) {
+			c.Response().Header().Set("Retry-After", retryAfterSeconds(unavailableErr.RetryAfter))
+		}
+
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+		switch {
+		case c.Request().Method == http.MethodHead:
+			err = c.NoContent(status)
+		case prefersProblemJSON(c.Request()):
+			c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+			err = c.JSON(status, newProblemDetails(status, envelope))
+		default:
+			err = c.JSON(status, envelope)
+		}
+
+		if err != nil {
+			logrus.Errorf("failed to send error response - %v", err)
+		}
+	}
+}
+
+// MethodNotAllowedHandler replaces echo.MethodNotAllowedHandler, so methods allowed for matched route
+// are listed in Allow header and in message of error envelope
+func MethodNotAllowedHandler(c echo.Context) error {
+	allow, _ := c.Get(echo.ContextKeyHeaderAllow).(string)
+	if allow == "" {
+		return echo.ErrMethodNotAllowed
+	}
+
+	c.Response().Header().Set(echo.HeaderAllow, allow)
+	return echo.NewHTTPError(http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed, allowed methods are %s", c.Request().Method, allow))
+}
+
+func errorResponse(err error, trans ut.Translator) (int, *errorEnvelope) {
+	status := httpStatus(err)
+	envelope := &errorEnvelope{Message: err.Error(), Details: make([]validation.Violation, 0)}
+
+	var pldErr *validation.PayloadError
+	var businessErr *appErrors.BusinessErr
+	var notFoundErr *appErrors.EntryNotFoundErr
+	var unavailableErr *appErrors.UnavailableErr
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &pldErr):
+		envelope.Message = payloadErrorMessage
+		envelope.Details = pldErr.Translate(trans).Violations()
+	case errors.Is(err, appErrors.ErrUnauthorized):
+		envelope.Message = http.StatusText(status) // reason of rejection is never exposed
+	case errors.As(err, &notFoundErr):
+		envelope.Message = notFoundErr.Error()
+	case errors.As(err, &businessErr):
+		envelope.Message = businessErr.Error()
+	case errors.As(err, &unavailableErr):
+		envelope.Message = unavailableErr.Error()
+	case errors.As(err, &httpErr):
+		envelope.Message = fmt.Sprint(unwrapHTTPError(httpErr).Message)
+	}
+
+	envelope.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	return status, envelope
+}
+
+// httpStatus maps error to response status code, typed service errors are checked before echo errors.
+// Payload which is well-formed but fails validation is unprocessable, malformed payload is rejected as bad request by handlers.
+// Invalid path and query parameters are syntax errors of request, so they are bad request too
+func httpStatus(err error) int {
+	var pldErr *validation.PayloadError
+	var businessErr *appErrors.BusinessErr
+	var notFoundErr *appErrors.EntryNotFoundErr
+	var unavailableErr *appErrors.UnavailableErr
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &pldErr) && pldErr.IsInURL():
+		return http.StatusBadRequest
+	case errors.As(err, &pldErr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, appErrors.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound
+	case errors.Is(err, appErrors.ErrConcurrentModification):
+		return http.StatusConflict
+	case errors.Is(err, appErrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.As(err, &businessErr):
+		return http.StatusBadRequest
+	case errors.As(err, &unavailableErr):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &httpErr):
+		return unwrapHTTPError(httpErr).Code
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func newProblemDetails(status int, envelope *errorEnvelope) *problemDetails {
+	return &problemDetails{
+		Type:       problemTypePrefix + envelope.Code,
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     envelope.Message,
+		Instance:   envelope.RequestID,
+		Violations: envelope.Details,
+	}
+}
+
+// prefersProblemJSON reports if problem+json is accepted with higher quality than json,
+// the one listed first wins on equal quality
+func prefersProblemJSON(r *http.Request) bool {
+	offers := map[string]string{
+		echo.MIMEApplicationJSON:   echo.MIMEApplicationJSON,
+		MIMEApplicationProblemJSON: MIMEApplicationProblemJSON,
+	}
+	return preferredMediaType(r, offers) == MIMEApplicationProblemJSON
+}
+
+// retryAfterSeconds formats duration as Retry-After header value, it is rounded up to whole seconds and is at least one second
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
+
+func unwrapHTTPError(httpErr *echo.HTTPError) *echo.HTTPError {
+	if internal, ok := httpErr.Internal.(*echo.HTTPError); ok {
+		return internal
+	}
+	return httpErr
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// streamEndID is position of the newest event in stream, so only events published after subscription are sent
+const streamEndID = "$"
+
+var streamIDRegexp = regexp.MustCompile(`^\d+-\d+$`)
+
+type streamEvent struct {
+	id string
+	e  *model.CustomerEvent
+}
+
+// CustomerEventsHTTPHandler is http handler streaming customer events as Server-Sent Events. Live events are fanned out
+// by broker fed by single stream reader of instance, stream is read per request only to replay events missed by client
+type CustomerEventsHTTPHandler struct {
+	broker    *event.Broker
+	replayer  event.Replayer
+	keepAlive time.Duration
+}
+
+// NewCustomerEventsHTTPHandler builds new CustomerEventsHTTPHandler, keep alive comment is sent to idle streams every keepAlive
+func NewCustomerEventsHTTPHandler(broker *event.Broker, replayer event.Replayer, keepAlive time.Duration) *CustomerEventsHTTPHandler {
+	return &CustomerEventsHTTPHandler{broker: broker, replayer: replayer, keepAlive: keepAlive}
+}
+
+// Stream streams customer events of caller tenant
+// @Summary     Stream customer events
+// @Description Streams change events of caller tenant customers as Server-Sent Events until client disconnects.
+// @Description Each event has stream id, event name is event type (e.g. customer.created) and data is event json.
+// @Description Stream is resumed after event passed in Last-Event-ID header, otherwise only new events are sent.
+// @Description Stream is ended if client falls behind, so client resumes it with Last-Event-ID on reconnect.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID   header string false "Caller tenant, must match tenant of access token if provided"
+// @Param       Last-Event-ID header string false "Id of the last received event to resume stream after"
+// @Produce     text/event-stream
+// @Success     200
+// @Failure     400 {object} errorEnvelope
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Router      /api/v1/customers/events [get]
+func (h *CustomerEventsHTTPHandler) Stream(c echo.Context) error {
+	lastID := streamEndID
+	if id := c.Request().Header.Get("Last-Event-ID"); id != "" {
+		if !streamIDRegexp.MatchString(id) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid Last-Event-ID %s", id))
+		}
+		lastID = id
+	}
+
+	ctx := c.Request().Context()
+	tenantID := tenant.IDFromContext(ctx)
+
+	// subscribed before replay, so events published meanwhile are buffered and nothing is missed
+	sub := h.broker.Subscribe(tenantID)
+	defer h.broker.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // disables buffering of proxies like nginx
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	if lastID != streamEndID {
+		replayedID, err := h.replayer.Replay(ctx, lastID, func(ctx context.Context, id string, e *model.CustomerEvent) error {
+			if e.TenantID != tenantID {
+				return nil
+			}
+			return h.writeEvent(res, streamEvent{id: id, e: e})
+		})
+		if err != nil { // stream is ended, client resumes it with Last-Event-ID on reconnect
+			logging.FromContext(ctx).Errorf("failed to replay customer events after %s - %v", lastID, err)
+			return nil
+		}
+		lastID = replayedID
+	}
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Dropped():
+			logging.FromContext(ctx).Warn("customer events stream is ended, since client doesn't receive events fast enough")
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(res, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case d := <-sub.Events():
+			if lastID != streamEndID && !event.StreamIDAfter(d.StreamID, lastID) { // already replayed
+				continue
+			}
+
+			if err := h.writeEvent(res, streamEvent{id: d.StreamID, e: d.Event}); err != nil {
+				logging.FromContext(ctx).Errorf("failed to write customer event %s - %v", d.Event.ID, err)
+				return nil
+			}
+			ticker.Reset(h.keepAlive)
+		}
+	}
+}
+
+func (h *CustomerEventsHTTPHandler) writeEvent(res *echo.Response, se streamEvent) error {
+	data, err := json.Marshal(se.e.External())
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", se.id, se.e.Type, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
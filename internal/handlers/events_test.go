@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	eventsTestTimeout    = 5 * time.Second
+	eventsTestKeepAlive  = 50 * time.Millisecond
+	eventsTestBufferSize = 4
+)
+
+type followedEvent struct {
+	id string
+	e  *model.CustomerEvent
+}
+
+// sliceReplayer replays events kept in slice and records ids replay is requested after
+type sliceReplayer struct {
+	events   []followedEvent
+	afterIDs chan string
+}
+
+func (r *sliceReplayer) Replay(ctx context.Context, afterID string, h event.StreamHandler) (string, error) {
+	r.afterIDs <- afterID
+
+	lastID := afterID
+	for _, fe := range r.events {
+		if !event.StreamIDAfter(fe.id, afterID) {
+			continue
+		}
+
+		if err := h(ctx, fe.id, fe.e); err != nil {
+			return lastID, err
+		}
+		lastID = fe.id
+	}
+	return lastID, nil
+}
+
+type sseEvent struct {
+	id    string
+	name  string
+	data  string
+	lines []string
+}
+
+type eventsTestSuite struct {
+	suite.Suite
+	broker   *event.Broker
+	replayer *sliceReplayer
+	server   *httptest.Server
+}
+
+func (s *eventsTestSuite) SetupTest() {
+	s.broker = event.NewBroker(eventsTestBufferSize)
+	s.replayer = &sliceReplayer{afterIDs: make(chan string, 1)}
+
+	h := NewCustomerEventsHTTPHandler(s.broker, s.replayer, eventsTestKeepAlive)
+
+	app := echo.New()
+	app.GET("/api/v1/customers/events", h.Stream, tenantClaims(), middleware.Tenant())
+	s.server = httptest.NewServer(app)
+}
+
+func (s *eventsTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *eventsTestSuite) TestStream() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventsTestTimeout)
+	defer cancel()
+
+	streamCtx, disconnect := context.WithCancel(ctx)
+	defer disconnect()
+
+	res := s.stream(streamCtx, "acme", "")
+	defer res.Body.Close()
+	reader := bufio.NewReader(res.Body)
+
+	t.Log("stream is opened from the current position")
+	{
+		require.Equal(http.StatusOK, res.StatusCode, "response status must be OK")
+		require.Equal("text/event-stream", res.Header.Get(echo.HeaderContentType), "incorrect content type")
+		require.Empty(s.replayer.afterIDs, "nothing must be replayed without Last-Event-ID")
+	}
+
+	t.Log("events of caller tenant are streamed")
+	{
+		created := &model.CustomerEvent{
+			ID:         "2f6e8a1c-3b5d-4c7e-9f0a-1b2c3d4e5f6a",
+			Type:       model.CustomerCreated,
+			TenantID:   "acme",
+			CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692",
+			Customer:   &model.Customer{ID: "ecc770d9-4576-4f72-affa-8b1454246692", FirstName: "John", LastName: "Walls"},
+		}
+		deleted := &model.CustomerEvent{
+			ID:         "8d7c6b5a-4f3e-4d2c-9b1a-0f9e8d7c6b5a",
+			Type:       model.CustomerDeleted,
+			TenantID:   "acme",
+			CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692",
+		}
+
+		require.NoError(s.broker.PublishStream(ctx, "1700000000000-0", created), "failed to publish event")
+		require.NoError(s.broker.PublishStream(ctx, "1700000000001-0", &model.CustomerEvent{ID: "c1d2e3f4-a5b6-4c7d-8e9f-0a1b2c3d4e5f", Type: model.CustomerCreated, TenantID: "globex"}), "failed to publish event")
+		require.NoError(s.broker.PublishStream(ctx, "1700000000002-0", deleted), "failed to publish event")
+
+		first := s.next(reader)
+		require.Equal("1700000000000-0", first.id, "stream id must be event id")
+		require.Equal(string(model.CustomerCreated), first.name, "event type must be event name")
+
+		var data model.CustomerEvent
+		require.NoError(json.Unmarshal([]byte(first.data), &data), "event data must be json")
+		require.Equal(created.ID, data.ID, "incorrect event")
+		require.Equal("John", data.Customer.FirstName, "customer must be part of event")
+
+		second := s.next(reader)
+		require.Equal("1700000000002-0", second.id, "events of other tenants must be skipped")
+		require.Equal(string(model.CustomerDeleted), second.name, "event type must be event name")
+	}
+
+	t.Log("keep alive comment is sent to idle stream")
+	{
+		ev := s.read(reader)
+		require.Equal([]string{": keepalive"}, ev.lines, "keep alive comment must be sent")
+	}
+
+	t.Log("subscription is cancelled once client disconnects")
+	{
+		disconnect()
+
+		require.Eventually(func() bool {
+			return s.broker.Subscribers() == 0
+		}, eventsTestTimeout, 10*time.Millisecond, "subscription must be cancelled after client disconnects")
+	}
+}
+
+func (s *eventsTestSuite) TestStreamResumed() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventsTestTimeout)
+	defer cancel()
+
+	s.replayer.events = []followedEvent{
+		{id: "1700000000001-0", e: &model.CustomerEvent{ID: "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", Type: model.CustomerCreated, TenantID: "acme"}},
+		{id: "1700000000002-0", e: &model.CustomerEvent{ID: "2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", Type: model.CustomerCreated, TenantID: "acme"}},
+		{id: "1700000000003-0", e: &model.CustomerEvent{ID: "3c4d5e6f-7a8b-4c9d-0e1f-2a3b4c5d6e7f", Type: model.CustomerCreated, TenantID: "globex"}},
+		{id: "1700000000010-0", e: &model.CustomerEvent{ID: "4d5e6f7a-8b9c-4d0e-1f2a-3b4c5d6e7f8a", Type: model.CustomerUpdated, TenantID: "acme"}},
+	}
+
+	t.Log("stream is resumed after Last-Event-ID")
+	{
+		res := s.stream(ctx, "acme", "1700000000001-0")
+		defer res.Body.Close()
+		reader := bufio.NewReader(res.Body)
+
+		require.Equal(http.StatusOK, res.StatusCode, "response status must be OK")
+		require.Equal("1700000000001-0", <-s.replayer.afterIDs, "stream must be replayed after last event")
+
+		require.Equal("1700000000002-0", s.next(reader).id, "missed event must be replayed")
+		require.Equal("1700000000010-0", s.next(reader).id, "events of other tenants must be skipped")
+	}
+
+	t.Log("live events which are already replayed are skipped")
+	{
+		res := s.stream(ctx, "acme", "1700000000009-0")
+		defer res.Body.Close()
+		reader := bufio.NewReader(res.Body)
+
+		require.Equal("1700000000009-0", <-s.replayer.afterIDs, "stream must be replayed after last event")
+		require.Equal("1700000000010-0", s.next(reader).id, "missed event must be replayed")
+
+		require.NoError(s.broker.PublishStream(ctx, "1700000000010-0", s.replayer.events[3].e), "failed to publish event")
+		require.NoError(s.broker.PublishStream(ctx, "1700000000011-0", s.replayer.events[0].e), "failed to publish event")
+		require.Equal("1700000000011-0", s.next(reader).id, "replayed event must not be sent twice")
+	}
+
+	t.Log("invalid Last-Event-ID is rejected")
+	{
+		res := s.stream(ctx, "acme", "latest")
+		defer res.Body.Close()
+
+		require.Equal(http.StatusBadRequest, res.StatusCode, "response status must be Bad Request")
+	}
+}
+
+func (s *eventsTestSuite) stream(ctx context.Context, tenantID string, lastEventID string) *http.Response {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.server.URL+"/api/v1/customers/events", http.NoBody)
+	s.Require().NoError(err, "failed to build request")
+
+	req.Header.Set(middleware.TenantHeader, tenantID)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	res, err := s.server.Client().Do(req)
+	s.Require().NoError(err, "failed to open stream")
+	return res
+}
+
+// next reads stream until event is received, keep alive comments are skipped
+func (s *eventsTestSuite) next(reader *bufio.Reader) sseEvent {
+	for {
+		if ev := s.read(reader); ev.id != "" {
+			return ev
+		}
+	}
+}
+
+// read reads single block of stream terminated by empty line
+func (s *eventsTestSuite) read(reader *bufio.Reader) sseEvent {
+	var ev sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		s.Require().NoError(err, "failed to read stream")
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return ev
+		}
+		ev.lines = append(ev.lines, line)
+
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.name = value
+		case "data":
+			ev.data = value
+		}
+	}
+}
+
+// start events test suite
+func TestEventsTestSuite(t *testing.T) {
+	suite.Run(t, new(eventsTestSuite))
+}
@@ -1,13 +1,29 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/event"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/storage"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/internal/validation"
 	"github.com/umalmyha/customers/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // AuthGrpcHandler is gRPC handler for auth endpoint
@@ -77,13 +93,15 @@ func (h *AuthGrpcHandler) Refresh(ctx context.Context, req *proto.RefreshRequest
 type CustomerGrpcHandler struct {
 	proto.UnimplementedCustomerServiceServer
 	customerSvc service.CustomerService
+	broker      *event.Broker
 }
 
-// NewCustomerGrpcHandler builds customerGrpcHandler
-func NewCustomerGrpcHandler(customerSvc service.CustomerService) *CustomerGrpcHandler {
+// NewCustomerGrpcHandler builds customerGrpcHandler, customer change events are watched via broker
+func NewCustomerGrpcHandler(customerSvc service.CustomerService, broker *event.Broker) *CustomerGrpcHandler {
 	return &CustomerGrpcHandler{
 		UnimplementedCustomerServiceServer: proto.UnimplementedCustomerServiceServer{},
 		customerSvc:                        customerSvc,
+		broker:                             broker,
 	}
 }
 
@@ -94,6 +112,10 @@ func (h *CustomerGrpcHandler) GetByID(ctx context.Context, req *proto.GetCustome
 		return nil, err
 	}
 
+	if c == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("customer %s not found", req.Id))
+	}
+
 	return h.customerResponse(c), nil
 }
 
@@ -114,6 +136,10 @@ func (h *CustomerGrpcHandler) GetAll(ctx context.Context, _ *emptypb.Empty) (*pr
 
 // Create creates new customer
 func (h *CustomerGrpcHandler) Create(ctx context.Context, req *proto.NewCustomerRequest) (*proto.CustomerResponse, error) {
+	if err := exclusionsError(req, grpcCustomerExclusions); err != nil {
+		return nil, err
+	}
+
 	c, err := h.customerSvc.Create(ctx, &model.Customer{
 		FirstName:  req.FirstName,
 		LastName:   req.LastName,
@@ -131,7 +157,11 @@ func (h *CustomerGrpcHandler) Create(ctx context.Context, req *proto.NewCustomer
 
 // Upsert create/update customer
 func (h *CustomerGrpcHandler) Upsert(ctx context.Context, req *proto.UpdateCustomerRequest) (*proto.CustomerResponse, error) {
-	c, err := h.customerSvc.Upsert(ctx, &model.Customer{
+	if err := exclusionsError(req, grpcCustomerExclusions); err != nil {
+		return nil, err
+	}
+
+	c, _, err := h.customerSvc.Upsert(ctx, &model.Customer{
 		ID:         req.Id,
 		FirstName:  req.FirstName,
 		LastName:   req.LastName,
@@ -155,6 +185,46 @@ func (h *CustomerGrpcHandler) DeleteByID(ctx context.Context, req *proto.DeleteC
 	return new(emptypb.Empty), nil
 }
 
+// Watch streams change events of caller tenant customers until client disconnects. Headers are sent
+// once subscription is established, client which falls behind is disconnected with ResourceExhausted
+func (h *CustomerGrpcHandler) Watch(_ *emptypb.Empty, stream proto.CustomerService_WatchServer) error {
+	ctx := stream.Context()
+
+	sub := h.broker.Subscribe(tenant.IDFromContext(ctx))
+	defer h.broker.Unsubscribe(sub)
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Dropped():
+			return status.Error(codes.ResourceExhausted, "customer events are not received fast enough, subscription is dropped")
+		case d := <-sub.Events():
+			if err := stream.Send(h.customerEvent(d.Event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (h *CustomerGrpcHandler) customerEvent(e *model.CustomerEvent) *proto.CustomerEvent {
+	res := &proto.CustomerEvent{
+		Id:         e.ID,
+		Type:       eventTypeToProto[e.Type],
+		CustomerId: e.CustomerID,
+		OccurredAt: timestamppb.New(e.OccurredAt),
+	}
+
+	if e.Customer != nil {
+		res.Customer = h.customerResponse(e.Customer)
+	}
+	return res
+}
+
 func (h *CustomerGrpcHandler) customerResponse(c *model.Customer) *proto.CustomerResponse {
 	return &proto.CustomerResponse{
 		Id:         c.ID,
@@ -166,3 +236,97 @@ func (h *CustomerGrpcHandler) customerResponse(c *model.Customer) *proto.Custome
 		Inactive:   c.Inactive,
 	}
 }
+
+// grpcCustomerExclusions are customerExclusions expressed in proto values, they can't be declared with protoc-gen-validate
+var grpcCustomerExclusions = []validation.Exclusion{
+	{Field: "Importance", Value: proto.CustomerImportance_CRITICAL, Other: "Inactive", OtherValue: true},
+}
+
+// exclusionsError builds InvalidArgument status with field violation for each exclusion held by request
+func exclusionsError(req any, exclusions []validation.Exclusion) error {
+	br := &errdetails.BadRequest{}
+	for _, e := range exclusions {
+		if v, ok := e.Check(req); ok {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: v.Field, Description: v.Message})
+		}
+	}
+
+	if len(br.FieldViolations) == 0 {
+		return nil
+	}
+
+	st := status.New(codes.InvalidArgument, br.FieldViolations[0].Description)
+	if withDetails, err := st.WithDetails(br); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+var eventTypeToProto = map[model.CustomerEventType]proto.CustomerEventType{
+	model.CustomerCreated: proto.CustomerEventType_CREATED,
+	model.CustomerUpdated: proto.CustomerEventType_UPDATED,
+	model.CustomerDeleted: proto.CustomerEventType_DELETED,
+}
+
+// ImageGrpcHandler is gRPC handler for images endpoint
+type ImageGrpcHandler struct {
+	proto.UnimplementedImageServiceServer
+	*imageUploader
+}
+
+// NewImageGrpcHandler builds new ImageGrpcHandler
+func NewImageGrpcHandler(imageStorage storage.ImageStorage, imageMetaStore storage.ImageMetadataStore, cfg *config.ImagesCfg) *ImageGrpcHandler {
+	return &ImageGrpcHandler{
+		UnimplementedImageServiceServer: proto.UnimplementedImageServiceServer{},
+		imageUploader:                   newImageUploader(imageStorage, imageMetaStore, cfg),
+	}
+}
+
+// UploadImage assembles image from streamed chunks, name, overwrite flag and MIME type are taken from the first chunk
+func (h *ImageGrpcHandler) UploadImage(stream proto.ImageService_UploadImageServer) error {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return echo.NewHTTPError(http.StatusBadRequest, "no image chunks received")
+	}
+	if err != nil {
+		return err
+	}
+
+	if first.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "image name must be provided in the first chunk")
+	}
+
+	mimeType := http.DetectContentType(first.Data)
+	if !h.isMimeTypeAllowed(mimeType) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("MIME type %s is not allowed", mimeType))
+	}
+
+	if err := h.checkExtension(first.Name, mimeType); err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	chunk := first
+	for {
+		if int64(content.Len()+len(chunk.Data)) > h.cfg.StreamMaxSize {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("image must not exceed %d bytes", h.cfg.StreamMaxSize))
+		}
+		content.Write(chunk.Data)
+
+		chunk, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	info, err := h.store(stream.Context(), first.Name, bytes.NewReader(content.Bytes()), int64(content.Len()), mimeType, first.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	uploaded := newUploadedImage(info.Name)
+	return stream.SendAndClose(&proto.ImageRef{Name: uploaded.Name, Url: uploaded.URL})
+}
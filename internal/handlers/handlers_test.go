@@ -1,39 +1,64 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/go-playground/locales/en"
-	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v9"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/labstack/echo/v4"
+	echoMw "github.com/labstack/echo/v4/middleware"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/audit"
 	"github.com/umalmyha/customers/internal/auth"
 	"github.com/umalmyha/customers/internal/cache"
 	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/email"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/feature"
+	"github.com/umalmyha/customers/internal/interceptors"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/repository"
 	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/storage"
+	"github.com/umalmyha/customers/internal/tenant"
 	"github.com/umalmyha/customers/internal/validation"
 	"github.com/umalmyha/customers/pkg/db/transactor"
 	"github.com/umalmyha/customers/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -60,6 +85,13 @@ const (
 	redisTestDB        = 0
 )
 
+const (
+	mongoContainerName = "mongo-handlers-test-customers"
+	mongoPort          = "27017"
+	mongoTestUser      = "handlers-test"
+	mongoTestPassword  = "handlers-test"
+)
+
 const (
 	jwtAlgoEd25519 = "EdDSA"
 	jwtIssuerClaim = "test-issuer"
@@ -67,6 +99,10 @@ const (
 	jwtPrivateKey  = "MC4CAQAwBQYDK2VwBCIEIBvYJuek9MjwZuvYT+6W7S9RRgr0SmxRqejl2v6y9jjo"
 )
 
+const customerCacheTimeToLive = 3 * time.Minute
+
+const imageCacheMaxAge = time.Hour
+
 const (
 	refreshTokenMaxCount   = 2
 	refreshTokenTimeToLive = 720 * time.Hour
@@ -81,6 +117,7 @@ const (
 type handlersDockerResources struct {
 	postgres *dockertest.Resource
 	redis    *dockertest.Resource
+	mongodb  *dockertest.Resource
 	network  *docker.Network
 }
 
@@ -89,10 +126,13 @@ type handlersTestSuite struct {
 	app         *echo.Echo
 	authSvc     service.AuthService
 	customerSvc service.CustomerService
+	sessionSvc  service.SessionService
+	runtimeCfg  *config.RuntimeHolder
 	dockerPool  *dockertest.Pool
 	resources   handlersDockerResources
 	pgPool      *pgxpool.Pool
 	redisClient *redis.Client
+	mongoClient *mongo.Client
 	bufListener *bufconn.Listener
 	bufDialer   func(context.Context, string) (net.Conn, error)
 }
@@ -145,6 +185,7 @@ func (s *handlersTestSuite) SetupSuite() {
 		fmt.Sprintf("-user=%s", pgTestUser),
 		fmt.Sprintf("-password=%s", pgTestPassword),
 		"-connectRetries=10",
+		"-placeholders.customersEmailUniqueness=tenant",
 		"migrate",
 	}
 
@@ -222,38 +263,84 @@ func (s *handlersTestSuite) SetupSuite() {
 	})
 	assert.NoError(err, "failed to establish connection to redis")
 
+	// start mongo
+	t.Log("starting mongodb...")
+	mongodb, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       mongoContainerName,
+		Repository: "mongo",
+		Tag:        "latest",
+		NetworkID:  network.ID,
+		Env: []string{
+			fmt.Sprintf("MONGO_INITDB_ROOT_USERNAME=%s", mongoTestUser),
+			fmt.Sprintf("MONGO_INITDB_ROOT_PASSWORD=%s", mongoTestPassword),
+		},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"27017/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", mongoPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start mongodb")
+
+	s.resources.mongodb = mongodb // assign mongodb
+
+	// connect to mongo
+	t.Log("connecting to mongodb...")
+	mongoURI := fmt.Sprintf("mongodb://%s:%s@localhost:%s", mongoTestUser, mongoTestPassword, mongoPort)
+	err = dockerPool.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+		defer cancel()
+
+		var e error
+		s.mongoClient, e = mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+		if e != nil {
+			return e
+		}
+		return s.mongoClient.Ping(ctx, readpref.Primary())
+	})
+	assert.NoError(err, "failed to establish connection to mongodb")
+
 	// create validator for echo
-	enLocale := en.New()
-	unvTranslator := ut.New(enLocale, enLocale)
-	trans, ok := unvTranslator.GetTranslator("en")
-	if !ok {
-		assert.Fail("failed to build echo validator because of missing en translations")
-	}
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	RegisterCustomerValidation(v)
+	uni, err := validation.Translations(v)
+	assert.NoError(err, "failed to register validation translations")
 
 	// create echo app instance
+	echoValidator := validation.Echo(v, uni)
 	s.app = echo.New()
-	s.app.Validator = validation.Echo(validator.New(), trans)
+	s.app.Validator = echoValidator
+	s.app.HTTPErrorHandler = NewHTTPErrorHandler(echoValidator, true)
 
 	// create service dependencies
-	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, jwt.GetSigningMethod(jwtAlgoEd25519), jwtTimeToLive, ed25519.PrivateKey(jwtPrivateKey))
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", jwt.GetSigningMethod(jwtAlgoEd25519), jwtTimeToLive, ed25519.PrivateKey(jwtPrivateKey))
 	rfrTokenCfg := &config.RefreshTokenCfg{MaxCount: refreshTokenMaxCount, TimeToLive: refreshTokenTimeToLive}
 
 	txExecutor := transactor.NewPgxWithinTransactionExecutor(s.pgPool)
 	userRps := repository.NewPostgresUserRepository(txExecutor)
 	rfrTokenRps := repository.NewPostgresRefreshTokenRepository(txExecutor)
-	customerRps := repository.NewPostgresCustomerRepository(s.pgPool)
-	customerCache := cache.NewRedisCustomerCache(s.redisClient)
+	customerRps := repository.NewPostgresCustomerRepository(txExecutor, config.EmailUniquenessTenant, &config.RepositoryCfg{})
+	s.runtimeCfg, err = config.NewRuntimeHolder("", config.RuntimeCfg{CustomerCacheTimeToLive: customerCacheTimeToLive})
+	assert.NoError(err, "failed to build runtime config")
+	customerCache := cache.NewRedisCustomerCache(s.redisClient, s.runtimeCfg)
 
-	s.authSvc = service.NewAuthService(jwtIssuer, rfrTokenCfg, transactor.NewPgxTransactor(s.pgPool), userRps, rfrTokenRps)
-	s.customerSvc = service.NewCustomerService(customerRps, customerCache)
+	jwtValidator := auth.NewJwtValidator(jwt.GetSigningMethod(jwtAlgoEd25519), ed25519.PrivateKey(jwtPrivateKey).Public(), jwtIssuerClaim, "")
+	tokenRevoker := auth.NewInMemoryTokenRevoker(jwtTimeToLive)
+	s.authSvc = service.NewAuthService(jwtIssuer, jwtValidator, tokenRevoker, rfrTokenCfg, &config.SignupCfg{Enabled: true}, transactor.NewPgxTransactor(s.pgPool), userRps, rfrTokenRps, audit.NewLogger(logrus.New()), email.NewNoopSender())
+	s.customerSvc = service.NewCustomerService(customerRps, customerCache, config.CachePopulationFailureFail)
+	s.sessionSvc = service.NewSessionService(rfrTokenRps)
 
 	// start gRPC server
 	s.bufListener = bufconn.Listen(grpcConnBufSize)
 
 	authGrpcHandler := NewAuthGrpcHandler(s.authSvc)
-	customerGrpcHandler := NewCustomerGrpcHandler(s.customerSvc)
-
-	server := grpc.NewServer()
+	customerGrpcHandler := NewCustomerGrpcHandler(s.customerSvc, event.NewBroker(1))
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		tenantClaimsUnaryInterceptor(),
+		interceptors.TenantUnaryInterceptor(),
+		interceptors.ValidatorUnaryInterceptor(true),
+		interceptors.ErrorUnaryInterceptor(),
+	))
 	proto.RegisterAuthServiceServer(server, authGrpcHandler)
 	proto.RegisterCustomerServiceServer(server, customerGrpcHandler)
 
@@ -283,6 +370,15 @@ func (s *handlersTestSuite) TearDownSuite() {
 		}
 	}
 
+	if s.mongoClient != nil {
+		t.Log("closing connection to mongodb")
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+		if err := s.mongoClient.Disconnect(ctx); err != nil {
+			t.Logf("failed to gracefully close connection to mongodb - %v", err)
+		}
+		cancel()
+	}
+
 	resources := s.resources
 
 	if resources.postgres != nil {
@@ -297,6 +393,12 @@ func (s *handlersTestSuite) TearDownSuite() {
 		}
 	}
 
+	if resources.mongodb != nil {
+		if err := s.dockerPool.Purge(resources.mongodb); err != nil {
+			t.Logf("failed to purge mongodb container - %v", err)
+		}
+	}
+
 	if resources.network != nil {
 		if err := s.dockerPool.Client.RemoveNetwork(resources.network.ID); err != nil {
 			t.Logf("failed to delete network - %v", err)
@@ -310,6 +412,7 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 	require := s.Require()
 
 	var sess session
+	var rotateJSON string
 	authHTTPHandler := NewAuthHTTPHandler(s.authSvc)
 
 	t.Log("signup with wrong payload")
@@ -339,6 +442,15 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 		require.Equal(http.StatusOK, rec.Code, "response status code must be OK")
 	}
 
+	t.Log("signup with already registered email")
+	{
+		signupJSON := fmt.Sprintf(`{"email":%q,"password":%q}"`, testEmail, testPassword)
+		c, _ := s.echoPostContext("/api/auth/signup", signupJSON)
+		err := authHTTPHandler.Signup(c)
+		require.ErrorIs(err, appErrors.ErrDuplicateEmail, "error must be duplicate email")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+
 	t.Log("login with wrong payload")
 	{
 		wrongPayloadJSON := `{"email":"testemail.email.c`
@@ -357,13 +469,23 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
 	}
 
+	t.Log("login with overlong fingerprint")
+	{
+		overlongJSON := fmt.Sprintf(`{"email":%q,"password":%q,"fingerprint":%q}`, testEmail, testPassword, strings.Repeat("f", 256))
+		c, _ := s.echoPostContext("/api/auth/login", overlongJSON)
+		err := authHTTPHandler.Login(c)
+		require.Error(err, "overlong fingerprint has been provided but no error raised")
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+	}
+
 	t.Log("login with wrong password")
 	{
 		wrongCredsJSON := fmt.Sprintf(`{"email":%q,"password":"wrong","fingerprint":%q}`, testEmail, testFingerprint)
 		c, _ := s.echoPostContext("/api/auth/login", wrongCredsJSON)
 		err := authHTTPHandler.Login(c)
 		require.Error(err, "wrong credentials have been provided but no error raised")
-		require.ErrorIs(err, echo.ErrUnauthorized, "code must be unauthorized")
+		require.ErrorIs(err, appErrors.ErrUnauthorized, "error must be unauthorized")
+		require.Equal(http.StatusUnauthorized, s.httpErrorCode(err), "response status must be Unauthorized")
 	}
 
 	t.Log("successful login")
@@ -397,6 +519,15 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
 	}
 
+	t.Log("refresh with overlong fingerprint")
+	{
+		overlongJSON := fmt.Sprintf(`{"fingerprint":%q,"refreshToken":%q}`, strings.Repeat("f", 256), sess.RefreshToken)
+		c, _ := s.echoPostContext("/api/auth/refresh", overlongJSON)
+		err := authHTTPHandler.Refresh(c)
+		require.Error(err, "overlong fingerprint has been provided but no error raised")
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+	}
+
 	t.Log("successful refresh")
 	{
 		refreshJSON := fmt.Sprintf(`{"fingerprint":%q,"refreshToken":%q}`, testFingerprint, sess.RefreshToken)
@@ -404,6 +535,19 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 		err := authHTTPHandler.Refresh(c)
 		require.NoError(err, "refresh request is correct but error raised")
 		require.Equal(http.StatusOK, rec.Code, "response status code must be OK")
+
+		var refreshed session
+		require.NoError(json.NewDecoder(rec.Body).Decode(&refreshed), "failed to parse session from response")
+		require.Greater(refreshed.RefreshTokenExpiresAt, refreshed.ExpiresAt, "refresh token must outlive jwt")
+		rotateJSON = fmt.Sprintf(`{"fingerprint":%q,"refreshToken":%q}`, testFingerprint, refreshed.RefreshToken)
+	}
+
+	t.Log("successful rotation")
+	{
+		c, rec := s.echoPostContext("/api/auth/rotate", rotateJSON)
+		err := authHTTPHandler.Rotate(c)
+		require.NoError(err, "rotate request is correct but error raised")
+		require.Equal(http.StatusOK, rec.Code, "response status code must be OK")
 	}
 
 	t.Log("logout with wrong payload")
@@ -432,6 +576,23 @@ func (s *handlersTestSuite) TestAuthHTTPHandler() {
 		require.NoError(err, "refresh request is correct but error raised")
 		require.Equal(http.StatusOK, rec.Code, "response status code must be OK")
 	}
+
+	t.Log("introspect without token")
+	{
+		c, _ := s.echoPostContext("/api/auth/introspect", `{}`)
+		err := authHTTPHandler.Introspect(c)
+		require.Error(err, "token is missing but no error raised")
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+	}
+
+	t.Log("introspect malformed token")
+	{
+		c, rec := s.echoPostContext("/api/auth/introspect", `{"token":"not.a.jwt"}`)
+		err := authHTTPHandler.Introspect(c)
+		require.NoError(err, "introspection request is correct but error raised")
+		require.Equal(http.StatusOK, rec.Code, "response status code must be OK")
+		require.JSONEq(`{"active":false}`, rec.Body.String(), "nothing but activity must be returned for inactive token")
+	}
 }
 
 //nolint:funlen // function contains a lot of inlined tests
@@ -439,11 +600,11 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 	t := s.T()
 	require := s.Require()
 
-	customerRps := repository.NewPostgresCustomerRepository(s.pgPool)
-	redisCacheRps := cache.NewRedisCustomerCache(s.redisClient)
+	customerRps := repository.NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessTenant, &config.RepositoryCfg{})
+	redisCacheRps := cache.NewRedisCustomerCache(s.redisClient, s.runtimeCfg)
 
-	customerSvc := service.NewCustomerService(customerRps, redisCacheRps)
-	customerHTTPHandler := NewCustomerHTTPHandler(customerSvc)
+	customerSvc := service.NewCustomerService(customerRps, redisCacheRps, config.CachePopulationFailureFail)
+	customerHTTPHandler := NewCustomerHTTPHandler(customerSvc, false)
 
 	testID := "7b45dbaa-ddf8-4ded-b858-78be123b3e6f"
 
@@ -479,6 +640,17 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 		err := customerHTTPHandler.Post(c)
 		require.Error(err, "wrong data in payload has been provided but no error raised")
 		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+
+		var pldErr struct {
+			Errors []struct {
+				Code string `json:"code"`
+			} `json:"errors"`
+		}
+		encoded, err := json.Marshal(err)
+		require.NoError(err, "failed to encode payload error")
+		require.NoError(json.Unmarshal(encoded, &pldErr), "failed to decode payload error")
+		require.Len(pldErr.Errors, 1, "only email must be invalid")
+		require.Equal("email", pldErr.Errors[0].Code, "violation code must be failed validation tag")
 	}
 
 	t.Log("post customer successfully")
@@ -498,6 +670,89 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 		require.Equal(http.StatusCreated, rec.Code, "response code must be Created")
 	}
 
+	t.Log("post customer with email already taken within tenant")
+	{
+		postCustomer := `{
+   			"firstName":"Johnny",
+   			"lastName":"Smith",
+   			"middleName":null,
+   			"email":"john.smith@testapi.com",
+   			"importance": 1,
+   			"inactive":false
+		}`
+
+		c, _ := s.echoPostContext("/api/v1/customers", postCustomer)
+		err := customerHTTPHandler.Post(c)
+		require.Error(err, "email is already taken but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+
+	t.Log("post existing customer with createIfNotExists")
+	{
+		postCustomer := `{
+   			"firstName":"Johnny",
+   			"lastName":"Smith",
+   			"middleName":null,
+   			"email":"john.smith@testapi.com",
+   			"importance": 1,
+   			"inactive":false
+		}`
+
+		c, rec := s.echoPostContext("/api/v1/customers?createIfNotExists=true", postCustomer)
+		err := customerHTTPHandler.Post(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response code must be OK for existing customer")
+
+		var existing model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &existing), "failed to decode existing customer")
+		require.Equal("John", existing.FirstName, "existing customer must be returned unchanged")
+	}
+
+	t.Log("post new customer with createIfNotExists")
+	{
+		postCustomer := `{
+   			"firstName":"Jane",
+   			"lastName":"Smith",
+   			"middleName":null,
+   			"email":"jane.smith@testapi.com",
+   			"importance": 1,
+   			"inactive":false
+		}`
+
+		c, rec := s.echoPostContext("/api/v1/customers?createIfNotExists=true", postCustomer)
+		err := customerHTTPHandler.Post(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusCreated, rec.Code, "response code must be Created for new customer")
+	}
+
+	t.Log("post customer with the same email for another tenant")
+	{
+		postCustomer := `{
+   			"firstName":"John",
+   			"lastName":"Smith",
+   			"middleName":null,
+   			"email":"john.smith@testapi.com",
+   			"importance": 2,
+   			"inactive":false
+		}`
+
+		c, rec := s.echoPostContext("/api/v1/customers", postCustomer)
+		c.SetRequest(c.Request().WithContext(tenant.ContextWithID(c.Request().Context(), "globex")))
+		err := customerHTTPHandler.Post(c)
+		require.NoError(err, "email must be unique only within tenant")
+		require.Equal(http.StatusCreated, rec.Code, "response code must be Created")
+
+		var created model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &created), "failed to decode created customer")
+
+		c, rec = s.echoGetContext(fmt.Sprintf("/api/v1/customers/%s", created.ID))
+		c.SetParamNames("id")
+		c.SetParamValues(created.ID)
+		err = customerHTTPHandler.Get(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal("null", strings.TrimSpace(rec.Body.String()), "customer of another tenant must not be found")
+	}
+
 	t.Log("put customer with wrong payload")
 	{
 		wrongPayloadJSON := `{
@@ -538,7 +793,7 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 			"firstName":"John",
 			"lastName":"Smith",
 			"middleName":null,
-			"email":"john.smith@testapi.com",
+			"email":"john.smith.put@testapi.com",
 			"importance": 2,
 			"inactive":false
 		}`
@@ -549,6 +804,81 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 		require.Equal(http.StatusOK, rec.Code, "response code must be OK")
 	}
 
+	t.Log("put customer with diff included")
+	{
+		putCustomer := `{
+			"firstName":"John",
+			"lastName":"Smith",
+			"middleName":null,
+			"email":"john.smith.put@testapi.com",
+			"importance": 3,
+			"inactive":false
+		}`
+
+		c, rec := s.echoPutContext(fmt.Sprintf("/api/v1/customers/%s?includeDiff=true", testID), testID, putCustomer)
+		err := customerHTTPHandler.Put(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response code must be OK")
+
+		var upd struct {
+			Customer customerV1             `json:"customer"`
+			Diff     []model.CustomerChange `json:"diff"`
+		}
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &upd), "response must be customer with diff")
+		require.Equal(testID, upd.Customer.ID, "updated customer must be returned")
+		require.Equal([]model.CustomerChange{{Field: "importance", Old: float64(2), New: float64(3)}}, upd.Diff, "only importance must be changed")
+	}
+
+	t.Log("conditional create of existing customer")
+	{
+		putCustomer := `{
+			"firstName":"John",
+			"lastName":"Smith",
+			"middleName":null,
+			"email":"john.smith.put@testapi.com",
+			"importance": 4,
+			"inactive":false
+		}`
+
+		c, _ := s.echoPutContext(fmt.Sprintf("/api/v1/customers/%s", testID), testID, putCustomer)
+		c.Request().Header.Set("If-None-Match", "*")
+		err := customerHTTPHandler.Put(c)
+		require.Error(err, "customer already exists but no error raised")
+		require.Equal(http.StatusPreconditionFailed, s.httpErrorCode(err), "response status must be Precondition Failed")
+
+		c, rec := s.echoGetContext(fmt.Sprintf("/api/v1/customers/%s", testID))
+		c.SetParamNames("id")
+		c.SetParamValues(testID)
+		require.NoError(customerHTTPHandler.Get(c), "no error must be raised")
+
+		var customer model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &customer), "failed to decode customer")
+		require.Equal(importanceToHTTP(model.ImportanceHigh), customer.Importance, "existing customer must be left untouched")
+	}
+
+	t.Log("conditional create of missing customer")
+	{
+		newID := "9a4c1e2b-3d5f-4a6b-8c7d-0e1f2a3b4c5d"
+		putCustomer := `{
+			"firstName":"Jane",
+			"lastName":"Smith",
+			"middleName":null,
+			"email":"jane.smith.put@testapi.com",
+			"importance": 1,
+			"inactive":false
+		}`
+
+		c, rec := s.echoPutContext(fmt.Sprintf("/api/v1/customers/%s", newID), newID, putCustomer)
+		c.Request().Header.Set("If-None-Match", "*")
+		err := customerHTTPHandler.Put(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response code must be OK")
+
+		var customer model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &customer), "failed to decode customer")
+		require.Equal(newID, customer.ID, "customer must be created with provided id")
+	}
+
 	t.Log("get customer by id with wrong uuid format")
 	{
 		c, _ := s.echoGetContext(fmt.Sprintf("/api/v1/customers/%s", "1111"))
@@ -586,100 +916,2289 @@ func (s *handlersTestSuite) TestCustomerHTTPHandler() {
 	}
 }
 
-func (s *handlersTestSuite) TestAuthGrpcHandler() {
+func (s *handlersTestSuite) TestCustomerHTTPHandlerListEnvelope() {
 	t := s.T()
 	require := s.Require()
-	grpcTestEmail := "sometest@someemail.com"
 
-	ctx := context.Background()
-	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(s.bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
-	require.NoError(err, "failed to create gRPC connection")
-	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
 
-	client := proto.NewAuthServiceClient(conn)
+	bareHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+	envelopeHandler := NewCustomerHTTPHandler(s.customerSvc, true)
 
-	var rfrToken string
+	listTenant := "list-envelope"
+	tenantCtx := tenant.ContextWithID(ctx, listTenant)
 
-	t.Log("signup new user")
-	_, err = client.Signup(ctx, &proto.SignupRequest{
-		Email:    grpcTestEmail,
-		Password: testPassword,
-	})
-	require.NoError(err, "no error must be raised")
+	getAll := func(h *CustomerHTTPHandler, tenantID, accept string) *httptest.ResponseRecorder {
+		c, rec := s.echoGetContext("/api/v1/customers")
+		req := c.Request()
+		if accept != "" {
+			req.Header.Set(echo.HeaderAccept, accept)
+		}
+		c.SetRequest(req.WithContext(tenant.ContextWithID(req.Context(), tenantID)))
 
-	t.Log("login with recently created user")
-	sess, err := client.Login(ctx, &proto.LoginRequest{
-		Email:       grpcTestEmail,
-		Password:    testPassword,
-		Fingerprint: testFingerprint,
-	})
-	require.NoError(err, "no error must be raised")
-	rfrToken = sess.RefreshToken
+		err := h.GetAll(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		return rec
+	}
 
-	t.Log("refresh session")
-	_, err = client.Refresh(ctx, &proto.RefreshRequest{
-		Fingerprint:  testFingerprint,
-		RefreshToken: rfrToken,
-	})
-	require.NoError(err, "no error must be raised")
+	for _, email := range []string{"list.envelope.first@somemail.com", "list.envelope.second@somemail.com"} {
+		_, err := s.customerSvc.Create(tenantCtx, &model.Customer{
+			FirstName:  "List",
+			LastName:   "Envelope",
+			Email:      email,
+			Importance: model.ImportanceLow,
+		})
+		require.NoError(err, "failed to create customer")
+	}
 
-	t.Log("logout")
-	_, err = client.Logout(ctx, &proto.LogoutRequest{RefreshToken: rfrToken})
-	require.NoError(err, "no error must be raised")
+	t.Log("bare list is returned by default")
+	{
+		rec := getAll(bareHandler, listTenant, echo.MIMEApplicationJSON)
+
+		var customers []*model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &customers), "bare list must be json array")
+		require.Len(customers, 2, "all tenant customers must be returned")
+	}
+
+	t.Log("envelope is returned if client accepts it")
+	{
+		rec := getAll(bareHandler, listTenant, fmt.Sprintf("%s, %s", MIMEApplicationListEnvelopeJSON, echo.MIMEApplicationJSON))
+
+		var envelope listEnvelope[*model.Customer]
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "response must be envelope")
+		require.Len(envelope.Data, 2, "all tenant customers must be in envelope data")
+		require.Equal(listMeta{Total: 2, Page: 1}, envelope.Meta, "envelope meta is incorrect")
+	}
+
+	t.Log("envelope is returned if it is enabled in config")
+	{
+		rec := getAll(envelopeHandler, listTenant, "")
+
+		var envelope listEnvelope[*model.Customer]
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "response must be envelope")
+		require.Len(envelope.Data, 2, "all tenant customers must be in envelope data")
+		require.Equal(listMeta{Total: 2, Page: 1}, envelope.Meta, "envelope meta is incorrect")
+	}
+
+	t.Log("empty list is enveloped with empty data")
+	{
+		rec := getAll(envelopeHandler, "list-envelope-empty", "")
+		require.JSONEq(`{"data": [], "meta": {"total": 0, "page": 1}}`, rec.Body.String(), "empty envelope is incorrect")
+	}
 }
 
-func (s *handlersTestSuite) TestCustomerGrpcHandler() {
+func (s *handlersTestSuite) TestCustomerHTTPHandlerMsgpack() {
 	t := s.T()
 	require := s.Require()
 
-	ctx := context.Background()
-	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(s.bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
-	require.NoError(err, "failed to create gRPC connection")
-	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
 
-	testID := "e7be204e-b693-4b99-b067-2eae1610b3ee"
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
 
-	client := proto.NewCustomerServiceClient(conn)
+	msgpackTenant := "msgpack"
+	tenantCtx := tenant.ContextWithID(ctx, msgpackTenant)
 
-	t.Log("create customer")
-	_, err = client.Create(ctx, &proto.NewCustomerRequest{
-		FirstName:  "John",
-		LastName:   "Smith",
-		MiddleName: nil,
-		Email:      "john.smith@testapi.com",
-		Importance: proto.CustomerImportance_HIGH,
-		Inactive:   false,
+	middleName := "Pack"
+	customer, err := s.customerSvc.Create(tenantCtx, &model.Customer{
+		FirstName:  "Msg",
+		LastName:   "Encoded",
+		MiddleName: &middleName,
+		Email:      "msgpack.encoded@somemail.com",
+		Importance: model.ImportanceHigh,
 	})
-	require.NoError(err, "no error must be raised")
+	require.NoError(err, "failed to create customer")
 
-	t.Log("put new customer")
-	_, err = client.Upsert(ctx, &proto.UpdateCustomerRequest{
-		Id:         testID,
-		FirstName:  "John",
-		LastName:   "Smith",
-		MiddleName: nil,
-		Email:      "john.smith@testapi.com",
-		Importance: proto.CustomerImportance_HIGH,
-		Inactive:   false,
-	})
-	require.NoError(err, "no error must be raised")
+	expected := *customer
+	expected.TenantID = ""                                      // tenant isn't exposed in responses
+	expected.Importance = importanceToHTTP(customer.Importance) // responses number importance as http api
 
-	t.Log("get recently created customer")
-	c, err := client.GetByID(ctx, &proto.GetCustomerByIdRequest{Id: testID})
-	require.NoError(err, "no error must be raised")
-	require.Equal(testID, c.Id, "incorrect customer was returned")
+	withAccept := func(c echo.Context, accept string) {
+		req := c.Request()
+		req.Header.Set(echo.HeaderAccept, accept)
+		c.SetRequest(req.WithContext(tenant.ContextWithID(req.Context(), msgpackTenant)))
+	}
 
-	t.Log("delete customer by id")
-	_, err = client.DeleteByID(ctx, &proto.DeleteCustomerByIdRequest{Id: testID})
-	require.NoError(err, "no error must be raised")
+	decode := func(rec *httptest.ResponseRecorder, v any) {
+		dec := msgpack.NewDecoder(rec.Body)
+		dec.SetCustomStructTag("json")
+		require.NoError(dec.Decode(v), "response must be msgpack encoded")
+	}
 
-	t.Log("get all customers")
-	list, err := client.GetAll(ctx, new(emptypb.Empty))
-	require.NoError(err, "no error must be raised")
-	require.NotEqual(0, len(list.Customers), "incorrect number of customers returned")
-}
+	t.Log("single customer is encoded with msgpack if client accepts it")
+	{
+		c, rec := s.echoGetContext("/api/v1/customers/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(customer.ID)
+		withAccept(c, MIMEApplicationMsgpack)
 
-func (s *handlersTestSuite) echoPostContext(target, payload string) (echo.Context, *httptest.ResponseRecorder) {
+		err := customerHTTPHandler.Get(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(MIMEApplicationMsgpack, rec.Header().Get(echo.HeaderContentType), "content type must be msgpack")
+
+		var decoded model.Customer
+		decode(rec, &decoded)
+		require.Equal(expected, decoded, "decoded customer must be equal to created")
+	}
+
+	t.Log("customers list is encoded with msgpack if client prefers it to json")
+	{
+		c, rec := s.echoGetContext("/api/v1/customers")
+		withAccept(c, fmt.Sprintf("%s;q=0.5, %s", echo.MIMEApplicationJSON, MIMEApplicationMsgpack))
+
+		err := customerHTTPHandler.GetAll(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(MIMEApplicationMsgpack, rec.Header().Get(echo.HeaderContentType), "content type must be msgpack")
+
+		var decoded []*model.Customer
+		decode(rec, &decoded)
+		require.Equal([]*model.Customer{&expected}, decoded, "all tenant customers must be returned")
+	}
+
+	t.Log("json is returned if client prefers it to msgpack")
+	{
+		c, rec := s.echoGetContext("/api/v1/customers")
+		withAccept(c, fmt.Sprintf("%s, %s;q=0.5", echo.MIMEApplicationJSON, MIMEApplicationMsgpack))
+
+		err := customerHTTPHandler.GetAll(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType), "content type must be json")
+
+		var customers []*model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &customers), "response must be json array")
+		require.Len(customers, 1, "all tenant customers must be returned")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerHTTPHandlerCorruptCache() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+
+	corruptTenant := "corrupt-cache"
+	customer, err := s.customerSvc.Create(tenant.ContextWithID(ctx, corruptTenant), &model.Customer{
+		FirstName:  "Broken",
+		LastName:   "Cache",
+		Email:      "broken.cache@somemail.com",
+		Importance: model.ImportanceMedium,
+	})
+	require.NoError(err, "failed to create customer")
+
+	key := fmt.Sprintf("customer:%s:%s", corruptTenant, customer.ID)
+	garbage := "\xc1garbage" // 0xc1 is never used by msgpack
+	require.NoError(s.redisClient.Set(ctx, key, garbage, customerCacheTimeToLive).Err(), "failed to corrupt cache entry")
+
+	t.Log("customer is read from database if cache entry is corrupt")
+	{
+		c, rec := s.echoGetContext("/api/v1/customers/:id")
+		c.SetParamNames("id")
+		c.SetParamValues(customer.ID)
+		c.SetRequest(c.Request().WithContext(tenant.ContextWithID(c.Request().Context(), corruptTenant)))
+
+		err := customerHTTPHandler.Get(c)
+		require.NoError(err, "corrupt cache entry must not fail request")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		var found model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &found), "failed to decode customer")
+		require.Equal(customer.ID, found.ID, "customer must be found in database")
+		require.Equal(customer.Email, found.Email, "customer must be found in database")
+	}
+
+	t.Log("corrupt cache entry is replaced")
+	{
+		cached, err := s.redisClient.Get(ctx, key).Result()
+		require.NoError(err, "customer must be cached again")
+		require.NotEqual(garbage, cached, "corrupt entry must be removed")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerHTTPHandlerBulkUpdate() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+
+	bulkTenant := "bulk-update"
+	tenantCtx := tenant.ContextWithID(ctx, bulkTenant)
+
+	postBulkUpdate := func(payload string) (*httptest.ResponseRecorder, error) {
+		c, rec := s.echoPostContext("/api/v1/customers/bulk-update", payload)
+		req := c.Request()
+		c.SetRequest(req.WithContext(tenantCtx))
+		return rec, customerHTTPHandler.BulkUpdate(c)
+	}
+
+	customers := make([]*model.Customer, 0)
+	for _, importance := range []model.Importance{model.ImportanceLow, model.ImportanceHigh, model.ImportanceHigh} {
+		c, err := s.customerSvc.Create(tenantCtx, &model.Customer{
+			FirstName:  "Bulk",
+			LastName:   "Update",
+			Email:      fmt.Sprintf("bulk.update.%d@somemail.com", len(customers)),
+			Importance: importance,
+		})
+		require.NoError(err, "failed to create customer")
+
+		_, err = s.customerSvc.FindByID(tenantCtx, c.ID) // warm up cache
+		require.NoError(err, "failed to read customer")
+
+		customers = append(customers, c)
+	}
+
+	t.Log("bulk update without update fields is rejected")
+	{
+		_, err := postBulkUpdate(`{"filter": {"importance": 3}, "update": {}}`)
+		require.Error(err, "empty update has been provided but no error raised")
+		require.Equal(http.StatusUnprocessableEntity, s.httpErrorCode(err), "response status must be Unprocessable Entity")
+	}
+
+	t.Log("bulk update with invalid importance is rejected")
+	{
+		_, err := postBulkUpdate(`{"filter": {"importance": 9}, "update": {"inactive": true}}`)
+		require.Error(err, "invalid importance has been provided but no error raised")
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+	}
+
+	t.Log("customers matching filter are updated")
+	{
+		rec, err := postBulkUpdate(fmt.Sprintf(`{"filter": {"importance": %d}, "update": {"inactive": true}}`, importanceToHTTP(model.ImportanceHigh)))
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.JSONEq(`{"updated": 2}`, rec.Body.String(), "count of updated customers is incorrect")
+
+		for _, c := range customers {
+			dbCustomer, err := s.customerSvc.FindByID(tenantCtx, c.ID)
+			require.NoError(err, "failed to read customer")
+			require.Equal(c.Importance == model.ImportanceHigh, dbCustomer.Inactive, "only customers matching filter must be updated")
+		}
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerImportHTTPHandlerStrict() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	importSvc := service.NewCustomerImportService(s.customerSvc, transactor.NewPgxTransactor(s.pgPool))
+	importHTTPHandler := NewCustomerImportHTTPHandler(importSvc, &config.ImportCfg{MaxRows: 10, MaxBodySize: 1 << 20})
+
+	tenantCtx := tenant.ContextWithID(ctx, "strict-import")
+	_, err := s.customerSvc.Create(tenantCtx, &model.Customer{
+		FirstName:  "Strict",
+		LastName:   "Import",
+		Email:      "strict.import@somemail.com",
+		Importance: model.ImportanceLow,
+	})
+	require.NoError(err, "failed to create customer")
+
+	t.Log("strict import is rolled back once row violates business rules")
+	{
+		csv := "id,firstName,lastName,email,importance\n" +
+			",Jane,Doe,jane.doe.import@somemail.com,2\n" +
+			"4a1f0c3e-8b2d-4e5f-9a6b-7c8d9e0f1a2b,Jack,Black,strict.import@somemail.com,2\n"
+
+		c, rec := s.echoPostContext("/api/v1/customers/import.csv?strict=true", csv)
+		c.SetRequest(c.Request().WithContext(tenantCtx))
+		require.NoError(importHTTPHandler.ImportCSV(c), "no error must be raised")
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+
+		var report importReport
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &report), "failed to decode report")
+		require.Zero(report.Imported, "nothing must be imported")
+		require.Len(report.Rejected, 1, "row violating business rules must be rejected")
+		require.Equal(3, report.Rejected[0].Line, "incorrect line of rejected row")
+
+		customers, err := s.customerSvc.FindAll(tenantCtx)
+		require.NoError(err, "failed to read customers")
+		require.Len(customers, 1, "customer imported before rejected row must be rolled back")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerFieldLengths() {
+	t := s.T()
+	require := s.Require()
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+
+	// column sizes of customers table
+	const nameMaxLength = 200
+	const emailMaxLength = 250
+
+	// email local part and domain labels have own limits, so length is gained by splitting domain into short labels
+	emailOfLength := func(n int) string {
+		domain := []byte(strings.Repeat("d", n-len("e@.com")))
+		for i := 49; i < len(domain)-1; i += 50 {
+			domain[i] = '.'
+		}
+		return "e@" + string(domain) + ".com"
+	}
+
+	postCustomer := func(firstName, middleName, email string) error {
+		payload, err := json.Marshal(map[string]any{
+			"firstName":  firstName,
+			"lastName":   "Length",
+			"middleName": middleName,
+			"email":      email,
+			"importance": 2,
+		})
+		require.NoError(err, "failed to encode payload")
+
+		c, _ := s.echoPostContext("/api/v1/customers", string(payload))
+		return customerHTTPHandler.Post(c)
+	}
+
+	requireMaxViolation := func(err error, limit int) {
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+
+		var pldErr struct {
+			Errors []struct {
+				Code  string `json:"code"`
+				Param string `json:"param"`
+			} `json:"errors"`
+		}
+		encoded, err := json.Marshal(err)
+		require.NoError(err, "failed to encode payload error")
+		require.NoError(json.Unmarshal(encoded, &pldErr), "failed to decode payload error")
+		require.Len(pldErr.Errors, 1, "only one field must be invalid")
+		require.Equal("max", pldErr.Errors[0].Code, "violation code must be max")
+		require.Equal(strconv.Itoa(limit), pldErr.Errors[0].Param, "violation must name the limit")
+	}
+
+	t.Log("fields of exactly max length are accepted")
+	{
+		name := strings.Repeat("n", nameMaxLength)
+		err := postCustomer(name, name, emailOfLength(emailMaxLength))
+		require.NoError(err, "no error must be raised")
+	}
+
+	t.Log("first name longer than max length is rejected")
+	{
+		err := postCustomer(strings.Repeat("n", nameMaxLength+1), "", "length.first@somemail.com")
+		requireMaxViolation(err, nameMaxLength)
+	}
+
+	t.Log("middle name longer than max length is rejected")
+	{
+		err := postCustomer("John", strings.Repeat("n", nameMaxLength+1), "length.middle@somemail.com")
+		requireMaxViolation(err, nameMaxLength)
+	}
+
+	t.Log("email longer than max length is rejected")
+	{
+		err := postCustomer("John", "", emailOfLength(emailMaxLength+1))
+		requireMaxViolation(err, emailMaxLength)
+	}
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(s.bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err, "failed to create gRPC connection")
+	defer conn.Close()
+
+	client := proto.NewCustomerServiceClient(conn)
+
+	t.Log("gRPC accepts fields of exactly max length")
+	{
+		_, err := client.Create(ctx, &proto.NewCustomerRequest{
+			FirstName:  strings.Repeat("g", nameMaxLength),
+			LastName:   strings.Repeat("g", nameMaxLength),
+			Email:      emailOfLength(emailMaxLength - 1), // email of max length is taken by HTTP customer
+			Importance: proto.CustomerImportance_HIGH,
+		})
+		require.NoError(err, "no error must be raised")
+	}
+
+	t.Log("gRPC rejects fields longer than max length")
+	{
+		_, err := client.Create(ctx, &proto.NewCustomerRequest{
+			FirstName:  strings.Repeat("g", nameMaxLength+1),
+			LastName:   "Length",
+			Email:      "length.grpc@somemail.com",
+			Importance: proto.CustomerImportance_HIGH,
+		})
+		require.Equal(codes.InvalidArgument, status.Code(err), "status code must be invalid argument")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerBlankNames() {
+	t := s.T()
+	require := s.Require()
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+
+	customerPayload := func(firstName, lastName, middleName, email string) string {
+		payload, err := json.Marshal(map[string]any{
+			"firstName":  firstName,
+			"lastName":   lastName,
+			"middleName": middleName,
+			"email":      email,
+			"importance": 2,
+		})
+		require.NoError(err, "failed to encode payload")
+		return string(payload)
+	}
+
+	requireBlankViolation := func(err error, field string) {
+		require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+
+		var pldErr struct {
+			Errors []struct {
+				Field string `json:"field"`
+				Code  string `json:"code"`
+			} `json:"errors"`
+		}
+		encoded, err := json.Marshal(err)
+		require.NoError(err, "failed to encode payload error")
+		require.NoError(json.Unmarshal(encoded, &pldErr), "failed to decode payload error")
+		require.Len(pldErr.Errors, 1, "only one field must be invalid")
+		require.Equal(field, pldErr.Errors[0].Field, "violation must be reported under json field name")
+		require.Equal(validation.NotBlankTag, pldErr.Errors[0].Code, "violation code must be notblank")
+	}
+
+	t.Log("whitespace-only first name is rejected")
+	{
+		c, _ := s.echoPostContext("/api/v1/customers", customerPayload("   ", "Blank", "", "blank.first@somemail.com"))
+		requireBlankViolation(customerHTTPHandler.Post(c), "firstName")
+	}
+
+	t.Log("whitespace-only last name is rejected")
+	{
+		c, _ := s.echoPostContext("/api/v1/customers", customerPayload("John", "\t ", "", "blank.last@somemail.com"))
+		requireBlankViolation(customerHTTPHandler.Post(c), "lastName")
+	}
+
+	t.Log("whitespace-only name is rejected on update")
+	{
+		id := "b0f9a3c2-7d4e-4b8a-9c1f-2e3d4c5b6a79"
+		c, _ := s.echoPutContext("/api/v1/customers/:id", id, customerPayload("John", " ", "", "blank.update@somemail.com"))
+		requireBlankViolation(customerHTTPHandler.Put(c), "lastName")
+	}
+
+	t.Log("empty middle name is stored as null")
+	{
+		c, rec := s.echoPostContext("/api/v1/customers", customerPayload("John", "Blank", "", "blank.middle@somemail.com"))
+		err := customerHTTPHandler.Post(c)
+		require.NoError(err, "no error must be raised")
+
+		var created model.Customer
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &created), "failed to decode customer")
+		require.Nil(created.MiddleName, "middle name must be null in response")
+
+		stored, err := s.customerSvc.FindByID(c.Request().Context(), created.ID)
+		require.NoError(err, "failed to find created customer")
+		require.Nil(stored.MiddleName, "middle name must be stored as null")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerConformancePostgres() {
+	runCustomerConformance(s.T(), "postgres", repository.NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessTenant, &config.RepositoryCfg{}))
+}
+
+func (s *handlersTestSuite) TestCustomerConformanceMongo() {
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	err := repository.MigrateMongoCustomers(ctx, s.mongoClient, config.EmailUniquenessTenant)
+	s.Require().NoError(err, "failed to migrate mongo customers")
+
+	runCustomerConformance(s.T(), "mongo", repository.NewMongoCustomerRepository(transactor.NewMongoWithinTransactionExecutor(s.mongoClient), config.EmailUniquenessTenant))
+}
+
+func (s *handlersTestSuite) TestHTTPErrorEnvelope() {
+	t := s.T()
+	require := s.Require()
+
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(jwt.GetSigningMethod(jwtAlgoEd25519), publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+	failing := func(echo.Context) error {
+		return errors.New("postgres: connection to 10.0.0.5 refused")
+	}
+	serviceErrors := map[string]error{
+		"duplicate":    appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, "customer with email john@email.com already exist"),
+		"concurrent":   appErrors.NewBusinessErr(appErrors.ErrConcurrentModification, "customer with email john@email.com was modified concurrently"),
+		"expired":      appErrors.NewBusinessErr(nil, "refresh token already expired"),
+		"forbidden":    appErrors.NewBusinessErr(appErrors.ErrForbidden, "signup is disabled, contact administrator to get an account"),
+		"missing":      appErrors.NewEntryNotFoundErr("user", "42"),
+		"unauthorized": fmt.Errorf("login failed - %w", appErrors.ErrUnauthorized),
+		"unavailable":  appErrors.NewUnavailableErr("postgres is temporarily unavailable", 2500*time.Millisecond, errors.New("circuit breaker is open")),
+	}
+
+	newApp := func(errHandler echo.HTTPErrorHandler) *echo.Echo {
+		app := echo.New()
+		app.Validator = s.app.Validator
+		app.HTTPErrorHandler = errHandler
+		app.Use(middleware.RequestID(echo.HeaderXRequestID))
+		app.Use(echoMw.Recover())
+		app.POST("/api/v1/customers", customerHTTPHandler.Post)
+		app.GET("/api/v1/customers/:id", customerHTTPHandler.Get, authorizeMw)
+		app.GET("/failing", failing)
+		app.GET("/service/:kind", func(c echo.Context) error {
+			return serviceErrors[c.Param("kind")]
+		})
+		app.GET("/panicking", func(echo.Context) error {
+			panic("nil map of tenant 42")
+		})
+		return app
+	}
+	app := newApp(s.app.HTTPErrorHandler)
+
+	serve := func(app *echo.Echo, method, target, payload string) (int, errorEnvelope) {
+		req := httptest.NewRequest(method, target, strings.NewReader(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		var envelope errorEnvelope
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "error response must be envelope")
+		require.Equal(rec.Header().Get(echo.HeaderXRequestID), envelope.RequestID, "request id must be responded")
+		require.NotEmpty(envelope.RequestID, "request id must be present")
+		require.NotNil(envelope.Details, "details must be always present")
+		return rec.Code, envelope
+	}
+
+	t.Log("validation errors are listed in details")
+	{
+		status, envelope := serve(app, http.MethodPost, "/api/v1/customers", `{"firstName": "John", "lastName": " ", "email": "invalid", "importance": 2}`)
+		require.Equal(http.StatusUnprocessableEntity, status, "response status must be Unprocessable Entity")
+		require.Equal("unprocessable_entity", envelope.Code, "incorrect error code")
+		require.Equal(payloadErrorMessage, envelope.Message, "incorrect error message")
+
+		fields := make([]string, 0)
+		for _, d := range envelope.Details {
+			fields = append(fields, d.Field)
+		}
+		require.ElementsMatch([]string{"email", "lastName"}, fields, "violations must be listed in details")
+	}
+
+	t.Log("malformed payload error has no details")
+	{
+		status, envelope := serve(app, http.MethodPost, "/api/v1/customers", `{"firstName":`)
+		require.Equal(http.StatusBadRequest, status, "response status must be Bad Request")
+		require.Equal("bad_request", envelope.Code, "incorrect error code")
+		require.Empty(envelope.Details, "details must be empty")
+	}
+
+	t.Log("auth error is wrapped into envelope")
+	{
+		status, envelope := serve(app, http.MethodGet, "/api/v1/customers/b0f9a3c2-7d4e-4b8a-9c1f-2e3d4c5b6a79", "")
+		require.Equal(http.StatusUnauthorized, status, "response status must be Unauthorized")
+		require.Equal("unauthorized", envelope.Code, "incorrect error code")
+		require.Equal("invalid Authorization header format", envelope.Message, "incorrect error message")
+	}
+
+	t.Log("unknown route error is wrapped into envelope")
+	{
+		status, envelope := serve(app, http.MethodGet, "/api/v1/unknown", "")
+		require.Equal(http.StatusNotFound, status, "response status must be Not Found")
+		require.Equal("not_found", envelope.Code, "incorrect error code")
+		require.Equal(http.StatusText(http.StatusNotFound), envelope.Message, "incorrect error message")
+	}
+
+	t.Log("service errors are mapped to status codes")
+	{
+		expectations := []struct {
+			kind    string
+			status  int
+			code    string
+			message string
+		}{
+			{"duplicate", http.StatusBadRequest, "bad_request", "customer with email john@email.com already exist"},
+			{"concurrent", http.StatusConflict, "conflict", "customer with email john@email.com was modified concurrently"},
+			{"expired", http.StatusBadRequest, "bad_request", "refresh token already expired"},
+			{"forbidden", http.StatusForbidden, "forbidden", "signup is disabled, contact administrator to get an account"},
+			{"missing", http.StatusNotFound, "not_found", "user 42 not found"},
+			{"unauthorized", http.StatusUnauthorized, "unauthorized", http.StatusText(http.StatusUnauthorized)},
+		}
+
+		for _, e := range expectations {
+			status, envelope := serve(app, http.MethodGet, "/service/"+e.kind, "")
+			require.Equal(e.status, status, "incorrect response status for %s error", e.kind)
+			require.Equal(e.code, envelope.Code, "incorrect error code for %s error", e.kind)
+			require.Equal(e.message, envelope.Message, "incorrect error message for %s error", e.kind)
+		}
+	}
+
+	t.Log("unavailable error advertises when to retry")
+	{
+		req := httptest.NewRequest(http.MethodGet, "/service/unavailable", http.NoBody)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(http.StatusServiceUnavailable, rec.Code, "response status must be Service Unavailable")
+		require.Equal("3", rec.Header().Get("Retry-After"), "retry after must be rounded up to seconds")
+	}
+
+	t.Log("internal errors aren't leaked in production mode")
+	{
+		for _, target := range []string{"/failing", "/panicking"} {
+			status, envelope := serve(app, http.MethodGet, target, "")
+			require.Equal(http.StatusInternalServerError, status, "response status must be Internal Server Error")
+			require.Equal("internal_server_error", envelope.Code, "incorrect error code")
+			require.Equal(http.StatusText(http.StatusInternalServerError), envelope.Message, "internal error must be hidden")
+		}
+	}
+
+	t.Log("internal errors are responded outside of production mode")
+	{
+		devApp := newApp(NewHTTPErrorHandler(s.app.Validator.(*validation.EchoValidator), false))
+		status, envelope := serve(devApp, http.MethodGet, "/failing", "")
+		require.Equal(http.StatusInternalServerError, status, "response status must be Internal Server Error")
+		require.Contains(envelope.Message, "connection to 10.0.0.5 refused", "internal error must be responded")
+	}
+
+	t.Log("request id is responded and logged with internal error")
+	{
+		logHook := logrusTest.NewGlobal()
+		defer logHook.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/failing", http.NoBody)
+		req.Header.Set(echo.HeaderXRequestID, "0b7d6c1e-quoted-by-user")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		var envelope errorEnvelope
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "error response must be envelope")
+		require.Equal("0b7d6c1e-quoted-by-user", envelope.RequestID, "request id passed by client must be responded")
+
+		entry := logHook.LastEntry()
+		require.NotNil(entry, "error must be logged")
+		require.Equal("0b7d6c1e-quoted-by-user", entry.Data["requestId"], "request id must be logged")
+	}
+
+	serveAccepting := func(method, target, payload, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Log("problem details are responded if client prefers them")
+	{
+		rec := serveAccepting(http.MethodGet, "/service/missing", "", MIMEApplicationProblemJSON)
+		require.Equal(http.StatusNotFound, rec.Code, "response status must be Not Found")
+		require.Equal(MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType), "content type must be problem+json")
+
+		var problem problemDetails
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &problem), "error response must be problem details")
+		require.Equal(problemDetails{
+			Type:     "urn:customers:error:not_found",
+			Title:    http.StatusText(http.StatusNotFound),
+			Status:   http.StatusNotFound,
+			Detail:   "user 42 not found",
+			Instance: rec.Header().Get(echo.HeaderXRequestID),
+		}, problem, "incorrect problem details")
+		require.NotEmpty(problem.Instance, "request id must be problem instance")
+	}
+
+	t.Log("validation errors are listed in problem details extension")
+	{
+		rec := serveAccepting(http.MethodPost, "/api/v1/customers", `{"firstName": "John", "lastName": " ", "email": "invalid", "importance": 2}`, MIMEApplicationProblemJSON)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+
+		var problem problemDetails
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &problem), "error response must be problem details")
+		require.Equal("urn:customers:error:unprocessable_entity", problem.Type, "incorrect problem type")
+		require.Equal(payloadErrorMessage, problem.Detail, "incorrect problem detail")
+
+		fields := make([]string, 0)
+		for _, v := range problem.Violations {
+			fields = append(fields, v.Field)
+		}
+		require.ElementsMatch([]string{"email", "lastName"}, fields, "violations must be listed in extension member")
+	}
+
+	t.Log("envelope is responded if client prefers json to problem details")
+	{
+		rec := serveAccepting(http.MethodGet, "/service/missing", "", fmt.Sprintf("%s;q=0.5, %s", MIMEApplicationProblemJSON, echo.MIMEApplicationJSON))
+		require.Equal(http.StatusNotFound, rec.Code, "response status must be Not Found")
+		require.Equal(echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType), "content type must be json")
+
+		var envelope errorEnvelope
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "error response must be envelope")
+		require.Equal("not_found", envelope.Code, "incorrect error code")
+		require.Equal(echo.HeaderAccept, rec.Header().Get(echo.HeaderVary), "error representation must vary by Accept")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerHTTPPathID() {
+	t := s.T()
+	require := s.Require()
+
+	customerHTTPHandler := NewCustomerHTTPHandler(s.customerSvc, false)
+	putCustomer := `{"firstName":"John","lastName":"Smith","email":"john.smith.path@testapi.com","importance":2}`
+
+	requirePathViolation := func(err error) {
+		var pldErr *validation.PayloadError
+		require.ErrorAs(err, &pldErr, "error must be payload error")
+		require.Equal([]validation.Violation{{
+			Field:   "id",
+			Message: "id must be a valid UUID",
+			Code:    "uuid",
+		}}, pldErr.Violations(), "violation must be reported under path parameter name")
+		require.Equal(http.StatusBadRequest, httpStatus(err), "invalid path parameter must be bad request")
+	}
+
+	t.Log("get customer with invalid id")
+	{
+		c, _ := s.echoGetContext("/api/v1/customers/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1111")
+		requirePathViolation(customerHTTPHandler.Get(c))
+	}
+
+	t.Log("put customer with invalid id")
+	{
+		c, _ := s.echoPutContext("/api/v1/customers/:id", "1111", putCustomer)
+		requirePathViolation(customerHTTPHandler.Put(c))
+	}
+
+	t.Log("delete customer with invalid id")
+	{
+		c, _ := s.echoDeleteContext("/api/v1/customers/:id", "1111")
+		requirePathViolation(customerHTTPHandler.DeleteByID(c))
+	}
+
+	t.Log("missing id is required")
+	{
+		c, _ := s.echoDeleteContext("/api/v1/customers/:id", "")
+		err := customerHTTPHandler.DeleteByID(c)
+
+		var pldErr *validation.PayloadError
+		require.ErrorAs(err, &pldErr, "error must be payload error")
+		require.Len(pldErr.Violations(), 1, "only id must be invalid")
+		require.Equal("required", pldErr.Violations()[0].Code, "id must be required")
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerHTTPVersions() {
+	t := s.T()
+	require := s.Require()
+
+	customerSvc := service.NewCustomerService(repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant), cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+
+	jsonFields := func(dto any) []string {
+		typ := reflect.TypeOf(dto)
+		fields := make([]string, 0, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		return fields
+	}
+
+	keys := func(encoded json.RawMessage) []string {
+		var customer map[string]any
+		require.NoError(json.Unmarshal(encoded, &customer), "customer must be json object")
+		fields := make([]string, 0, len(customer))
+		for field := range customer {
+			fields = append(fields, field)
+		}
+		return fields
+	}
+
+	versions := []struct {
+		name    string
+		handler interface {
+			Get(echo.Context) error
+			GetAll(echo.Context) error
+			Post(echo.Context) error
+			Put(echo.Context) error
+		}
+		fields []string
+	}{
+		{"v1", NewCustomerHTTPHandler(customerSvc, false), jsonFields(customerV1{})},
+		{"v2", NewCustomerHTTPHandlerV2(customerSvc, false), jsonFields(customerV2{})},
+	}
+
+	for _, v := range versions {
+		var created json.RawMessage
+		t.Logf("%s created customer has fields of %s representation", v.name, v.name)
+		{
+			payload := fmt.Sprintf(`{"firstName":"John","lastName":"Smith","email":"john.smith.%s@testapi.com","importance":2}`, v.name)
+			c, rec := s.echoPostContext("/api/customers", payload)
+			require.NoError(v.handler.Post(c), "no error must be raised")
+			created = rec.Body.Bytes()
+			require.ElementsMatch(v.fields, keys(created), "created customer fields must match %s representation", v.name)
+		}
+
+		var customer customerV1
+		require.NoError(json.Unmarshal(created, &customer), "failed to decode created customer")
+
+		t.Logf("%s found customer has fields of %s representation", v.name, v.name)
+		{
+			c, rec := s.echoGetContext("/api/customers/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(customer.ID)
+			require.NoError(v.handler.Get(c), "no error must be raised")
+			require.ElementsMatch(v.fields, keys(rec.Body.Bytes()), "found customer fields must match %s representation", v.name)
+		}
+
+		t.Logf("%s listed customers have fields of %s representation", v.name, v.name)
+		{
+			c, rec := s.echoGetContext("/api/customers")
+			require.NoError(v.handler.GetAll(c), "no error must be raised")
+
+			var customers []json.RawMessage
+			require.NoError(json.Unmarshal(rec.Body.Bytes(), &customers), "response must be json array")
+			require.NotEmpty(customers, "customers must be listed")
+			for _, listed := range customers {
+				require.ElementsMatch(v.fields, keys(listed), "listed customer fields must match %s representation", v.name)
+			}
+		}
+
+		t.Logf("%s updated customer has fields of %s representation", v.name, v.name)
+		{
+			payload := fmt.Sprintf(`{"firstName":"Johnny","lastName":"Smith","email":"john.smith.%s@testapi.com","importance":3}`, v.name)
+			c, rec := s.echoPutContext("/api/customers/:id?includeDiff=true", customer.ID, payload)
+			require.NoError(v.handler.Put(c), "no error must be raised")
+
+			var update struct {
+				Customer json.RawMessage `json:"customer"`
+			}
+			require.NoError(json.Unmarshal(rec.Body.Bytes(), &update), "response must be customer update")
+			require.ElementsMatch(v.fields, keys(update.Customer), "updated customer fields must match %s representation", v.name)
+		}
+	}
+}
+
+func (s *handlersTestSuite) TestCustomerHTTPMethods() {
+	t := s.T()
+	require := s.Require()
+
+	// routes are registered the same way as in application
+	defaultMethodNotAllowedHandler := echo.MethodNotAllowedHandler
+	echo.MethodNotAllowedHandler = MethodNotAllowedHandler
+	defer func() {
+		echo.MethodNotAllowedHandler = defaultMethodNotAllowedHandler
+	}()
+
+	customerSvc := service.NewCustomerService(repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant), cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+	customerHTTPHandler := NewCustomerHTTPHandler(customerSvc, false)
+
+	app := echo.New()
+	app.Validator = s.app.Validator
+	app.HTTPErrorHandler = s.app.HTTPErrorHandler
+	customers := app.Group("/api/v1/customers")
+	customers.GET("", customerHTTPHandler.GetAll)
+	customers.HEAD("", HeadHandler(customerHTTPHandler.GetAll))
+	customers.GET("/:id", customerHTTPHandler.Get)
+	customers.HEAD("/:id", HeadHandler(customerHTTPHandler.Get))
+	customers.POST("", customerHTTPHandler.Post)
+	customers.PUT("/:id", customerHTTPHandler.Put)
+	customers.DELETE("/:id", customerHTTPHandler.DeleteByID)
+
+	serve := func(method, target, payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(payload))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var created model.Customer
+	t.Log("create customer")
+	{
+		rec := serve(http.MethodPost, "/api/v1/customers", `{"firstName":"John","lastName":"Smith","email":"john.smith.head@testapi.com","importance":2}`)
+		require.Equal(http.StatusCreated, rec.Code, "response status must be Created")
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &created), "failed to decode created customer")
+	}
+
+	t.Log("head customer")
+	{
+		get := serve(http.MethodGet, fmt.Sprintf("/api/v1/customers/%s", created.ID), "")
+		head := serve(http.MethodHead, fmt.Sprintf("/api/v1/customers/%s", created.ID), "")
+		require.Equal(http.StatusOK, head.Code, "response status must be OK")
+		require.Equal(get.Header().Get(echo.HeaderContentType), head.Header().Get(echo.HeaderContentType), "headers must be the same as for GET")
+		require.Empty(head.Body.Bytes(), "body must be empty")
+	}
+
+	t.Log("head customers list")
+	{
+		rec := serve(http.MethodHead, "/api/v1/customers", "")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Empty(rec.Body.Bytes(), "body must be empty")
+	}
+
+	t.Log("head customer with invalid id")
+	{
+		rec := serve(http.MethodHead, "/api/v1/customers/1111", "")
+		require.Equal(http.StatusBadRequest, rec.Code, "response status must be Bad Request")
+		require.Empty(rec.Body.Bytes(), "body must be empty")
+	}
+
+	t.Log("disallowed method on customer")
+	{
+		rec := serve(http.MethodPatch, fmt.Sprintf("/api/v1/customers/%s", created.ID), `{"importance":3}`)
+		require.Equal(http.StatusMethodNotAllowed, rec.Code, "response status must be Method Not Allowed")
+		require.Equal("OPTIONS, DELETE, GET, HEAD, PUT", rec.Header().Get(echo.HeaderAllow), "allowed methods of route must be listed")
+
+		var envelope errorEnvelope
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "error response must be envelope")
+		require.Equal("method_not_allowed", envelope.Code, "incorrect error code")
+		require.Equal("method PATCH is not allowed, allowed methods are OPTIONS, DELETE, GET, HEAD, PUT", envelope.Message, "incorrect error message")
+	}
+
+	t.Log("disallowed method on customers list")
+	{
+		rec := serve(http.MethodDelete, "/api/v1/customers", "")
+		require.Equal(http.StatusMethodNotAllowed, rec.Code, "response status must be Method Not Allowed")
+		require.Equal("OPTIONS, GET, HEAD, POST", rec.Header().Get(echo.HeaderAllow), "allowed methods of route must be listed")
+	}
+}
+
+func (s *handlersTestSuite) TestAuthGrpcHandler() {
+	t := s.T()
+	require := s.Require()
+	grpcTestEmail := "sometest@someemail.com"
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(s.bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err, "failed to create gRPC connection")
+	defer conn.Close()
+
+	client := proto.NewAuthServiceClient(conn)
+
+	var rfrToken string
+
+	t.Log("signup new user")
+	_, err = client.Signup(ctx, &proto.SignupRequest{
+		Email:    grpcTestEmail,
+		Password: testPassword,
+	})
+	require.NoError(err, "no error must be raised")
+
+	t.Log("login with recently created user")
+	sess, err := client.Login(ctx, &proto.LoginRequest{
+		Email:       grpcTestEmail,
+		Password:    testPassword,
+		Fingerprint: testFingerprint,
+	})
+	require.NoError(err, "no error must be raised")
+	rfrToken = sess.RefreshToken
+
+	t.Log("refresh session")
+	_, err = client.Refresh(ctx, &proto.RefreshRequest{
+		Fingerprint:  testFingerprint,
+		RefreshToken: rfrToken,
+	})
+	require.NoError(err, "no error must be raised")
+
+	t.Log("logout")
+	_, err = client.Logout(ctx, &proto.LogoutRequest{RefreshToken: rfrToken})
+	require.NoError(err, "no error must be raised")
+}
+
+func (s *handlersTestSuite) TestCustomerGrpcHandler() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(s.bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err, "failed to create gRPC connection")
+	defer conn.Close()
+
+	testID := "e7be204e-b693-4b99-b067-2eae1610b3ee"
+
+	client := proto.NewCustomerServiceClient(conn)
+
+	t.Log("create customer")
+	_, err = client.Create(ctx, &proto.NewCustomerRequest{
+		FirstName:  "John",
+		LastName:   "Smith",
+		MiddleName: nil,
+		Email:      "john.smith.grpc@testapi.com",
+		Importance: proto.CustomerImportance_HIGH,
+		Inactive:   false,
+	})
+	require.NoError(err, "no error must be raised")
+
+	t.Log("put new customer")
+	_, err = client.Upsert(ctx, &proto.UpdateCustomerRequest{
+		Id:         testID,
+		FirstName:  "John",
+		LastName:   "Smith",
+		MiddleName: nil,
+		Email:      "john.smith.grpc.upsert@testapi.com",
+		Importance: proto.CustomerImportance_HIGH,
+		Inactive:   false,
+	})
+	require.NoError(err, "no error must be raised")
+
+	t.Log("get recently created customer")
+	c, err := client.GetByID(ctx, &proto.GetCustomerByIdRequest{Id: testID})
+	require.NoError(err, "no error must be raised")
+	require.Equal(testID, c.Id, "incorrect customer was returned")
+
+	t.Log("delete customer by id")
+	_, err = client.DeleteByID(ctx, &proto.DeleteCustomerByIdRequest{Id: testID})
+	require.NoError(err, "no error must be raised")
+
+	t.Log("get all customers")
+	list, err := client.GetAll(ctx, new(emptypb.Empty))
+	require.NoError(err, "no error must be raised")
+	require.NotEqual(0, len(list.Customers), "incorrect number of customers returned")
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandler() {
+	t := s.T()
+	require := s.Require()
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{StripMetadata: true, CacheMaxAge: imageCacheMaxAge})
+
+	imageName := "logo.png"
+	pngContent := []byte("\x89PNG\r\n\x1a\noriginal")
+	pngContentUpd := []byte("\x89PNG\r\n\x1a\nupdated")
+
+	t.Log("upload image")
+	{
+		c, rec := s.echoUploadContext("/images/upload", imageName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+	}
+
+	t.Log("upload image with the same name without overwrite")
+	{
+		c, _ := s.echoUploadContext("/images/upload", imageName, pngContentUpd)
+		err := imageHTTPHandler.Upload(c)
+		require.Error(err, "image already exists but no error raised")
+		require.Equal(http.StatusConflict, s.httpErrorCode(err), "response status must be Conflict")
+	}
+
+	t.Log("overwrite image")
+	{
+		c, rec := s.echoUploadContext("/images/upload?overwrite=true", imageName, pngContentUpd)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+	}
+
+	var etag string
+
+	t.Log("download overwritten image")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(pngContentUpd, rec.Body.Bytes(), "image content must be overwritten")
+		require.Equal("image/png", rec.Header().Get(echo.HeaderContentType), "content type detected on upload must be used")
+		require.Equal(`attachment; filename="logo.png"`, rec.Header().Get(echo.HeaderContentDisposition), "image must be served as attachment")
+		require.Equal("bytes", rec.Header().Get("Accept-Ranges"), "range requests must be supported")
+
+		etag = rec.Header().Get("ETag")
+		require.NotEmpty(etag, "etag must be set")
+	}
+
+	t.Log("download image inline")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download?inline=true", imageName), imageName)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(`inline; filename="logo.png"`, rec.Header().Get(echo.HeaderContentDisposition), "image must be served inline")
+	}
+
+	t.Log("download image range")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("Range", "bytes=0-3")
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusPartialContent, rec.Code, "response status must be Partial Content")
+		require.Equal(pngContentUpd[:4], rec.Body.Bytes(), "only requested range must be returned")
+		require.Equal(fmt.Sprintf("bytes 0-3/%d", len(pngContentUpd)), rec.Header().Get("Content-Range"), "incorrect content range")
+	}
+
+	t.Log("download not modified image")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("If-None-Match", etag)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNotModified, rec.Code, "response status must be Not Modified")
+		require.Empty(rec.Body.Bytes(), "body must be empty for not modified image")
+	}
+
+	t.Log("download missing image")
+	{
+		c, _ := s.echoDownloadImageContext("/images/missing.png/download", "missing.png")
+		err := imageHTTPHandler.Download(c)
+		require.Error(err, "image is missing but no error raised")
+		require.Equal(http.StatusNotFound, s.httpErrorCode(err), "response status must be Not Found")
+	}
+
+	t.Log("list images page by page")
+	{
+		c, _ := s.echoUploadContext("/images/upload", "banner.png", pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+
+		c, rec := s.echoGetContext("/images?limit=1")
+		err = imageHTTPHandler.List(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		var page imagesPage
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &page), "failed to decode images page")
+		require.Len(page.Images, 1, "page must contain single image")
+		require.Equal("banner.png", page.Images[0].Name, "images must be ordered by name")
+		require.Equal("image/png", page.Images[0].ContentType, "incorrect content type stored")
+		require.Equal(int64(len(pngContent)), page.Images[0].Size, "incorrect size stored")
+		require.NotEmpty(page.NextCursor, "next page cursor must be returned")
+
+		c, rec = s.echoGetContext(fmt.Sprintf("/images?limit=1&cursor=%s", page.NextCursor))
+		err = imageHTTPHandler.List(c)
+		require.NoError(err, "no error must be raised")
+
+		page = imagesPage{}
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &page), "failed to decode images page")
+		require.Len(page.Images, 1, "page must contain single image")
+		require.Equal(imageName, page.Images[0].Name, "incorrect image on the second page")
+		require.Equal(int64(len(pngContentUpd)), page.Images[0].Size, "size must reflect overwritten image")
+		require.Empty(page.NextCursor, "last page must not return cursor")
+	}
+
+	t.Log("list images with invalid limit")
+	{
+		c, _ := s.echoGetContext(fmt.Sprintf("/images?limit=%d", maxImagesPageLimit+1))
+		err := imageHTTPHandler.List(c)
+		require.Error(err, "limit is out of range but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+
+	t.Log("delete existing image")
+	{
+		c, rec := s.echoDeleteImageContext(imageName)
+		err := imageHTTPHandler.Delete(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNoContent, rec.Code, "response status must be No Content")
+
+		infos, err := imageMetaStore.List(context.Background(), "", maxImagesPageLimit)
+		require.NoError(err, "no error must be raised")
+		require.Len(infos, 1, "deleted image must be removed from index")
+	}
+
+	t.Log("delete missing image")
+	{
+		c, _ := s.echoDeleteImageContext(imageName)
+		err := imageHTTPHandler.Delete(c)
+		require.Error(err, "image is missing but no error raised")
+		require.Equal(http.StatusNotFound, s.httpErrorCode(err), "response status must be Not Found")
+	}
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandlerExtensions() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{
+		CacheMaxAge:       imageCacheMaxAge,
+		AllowedExtensions: []string{".png", ".JPG"},
+	})
+
+	pngContent := []byte("\x89PNG\r\n\x1a\nextension")
+
+	t.Log("upload png content named as jpeg")
+	{
+		c, _ := s.echoUploadContext("/images/upload", "logo.jpg", pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.Error(err, "extension doesn't match content but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+
+		_, err = imageMetaStore.Get(ctx, "logo.jpg")
+		require.ErrorIs(err, storage.ErrImageNotFound, "mismatched image must not be stored")
+	}
+
+	t.Log("upload png content without extension")
+	{
+		c, _ := s.echoUploadContext("/images/upload", "logo", pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.Error(err, "extension is missing but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+
+	t.Log("upload image with extension matching content but not in allowlist")
+	{
+		c, _ := s.echoUploadContext("/images/upload", "logo.gif", []byte("GIF89a-not-allowed"))
+		err := imageHTTPHandler.Upload(c)
+		require.Error(err, "extension is not allowed but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+
+	t.Log("upload image with matching extension in different case")
+	{
+		c, rec := s.echoUploadContext("/images/upload", "logo.PNG", pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+	}
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandlerDeduplication() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{StripMetadata: true, CacheMaxAge: imageCacheMaxAge})
+
+	imageName, copyName := "logo.png", "logo-copy.png"
+	pngContent := []byte("\x89PNG\r\n\x1a\nshared-logo")
+
+	storedImages := func() []string {
+		entries, err := os.ReadDir(imagesRoot)
+		require.NoError(err, "failed to read images directory")
+
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), ".") {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+
+	t.Log("upload image")
+	{
+		c, rec := s.echoUploadContext("/images/upload", imageName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.JSONEq(`{"name": "logo.png", "url": "/images/logo.png/download"}`, rec.Body.String(), "uploaded image must be returned")
+	}
+
+	t.Log("upload identical image under another name")
+	{
+		c, rec := s.echoUploadContext("/images/upload", copyName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.JSONEq(`{"name": "logo-copy.png", "url": "/images/logo-copy.png/download"}`, rec.Body.String(), "image must be available under its own name")
+
+		require.Equal([]string{imageName}, storedImages(), "identical content must be stored once")
+
+		infos, err := imageMetaStore.List(ctx, "", maxImagesPageLimit)
+		require.NoError(err, "no error must be raised")
+		require.Len(infos, 2, "both images must be in index")
+		for _, info := range infos {
+			require.Equal(2, info.RefCount, "content must be shared by both images")
+			require.Equal(imageName, info.ContentName(), "content must be stored under name of the first image")
+			require.NotEmpty(info.Hash, "content hash must be stored")
+		}
+
+		reloadedStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+		require.NoError(err, "failed to reload image metadata store")
+		info, err := reloadedStore.Get(ctx, copyName)
+		require.NoError(err, "no error must be raised")
+		require.Equal(2, info.RefCount, "references must be persisted")
+	}
+
+	t.Log("upload identical image under the same name again")
+	{
+		c, rec := s.echoUploadContext("/images/upload", copyName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		info, err := imageMetaStore.Get(ctx, copyName)
+		require.NoError(err, "no error must be raised")
+		require.Equal(2, info.RefCount, "image must not be referenced twice")
+	}
+
+	t.Log("download image by another name")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", copyName), copyName)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(pngContent, rec.Body.Bytes(), "shared content must be downloaded")
+		require.Contains(rec.Header().Get(echo.HeaderContentDisposition), copyName, "image must be downloaded under requested name")
+	}
+
+	t.Log("overwrite of shared content is rejected")
+	{
+		c, _ := s.echoUploadContext("/images/upload?overwrite=true", imageName, []byte("\x89PNG\r\n\x1a\nother-logo"))
+		err := imageHTTPHandler.Upload(c)
+		require.Error(err, "content is shared but no error raised")
+		require.Equal(http.StatusConflict, s.httpErrorCode(err), "response status must be Conflict")
+	}
+
+	t.Log("delete image uploaded first")
+	{
+		c, rec := s.echoDeleteImageContext(imageName)
+		err := imageHTTPHandler.Delete(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNoContent, rec.Code, "response status must be No Content")
+
+		require.Equal([]string{imageName}, storedImages(), "content must be kept while it is shared")
+
+		_, err = imageMetaStore.Get(ctx, imageName)
+		require.ErrorIs(err, storage.ErrImageNameReserved, "deleted image name must be reserved while content is shared")
+
+		c, _ = s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		err = imageHTTPHandler.Download(c)
+		require.Error(err, "image is deleted but no error raised")
+		require.Equal(http.StatusNotFound, s.httpErrorCode(err), "response status must be Not Found")
+
+		c, rec = s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", copyName), copyName)
+		require.NoError(imageHTTPHandler.Download(c), "no error must be raised")
+		require.Equal(pngContent, rec.Body.Bytes(), "image sharing content must be still downloaded")
+
+		info, err := imageMetaStore.Get(ctx, copyName)
+		require.NoError(err, "no error must be raised")
+		require.Equal(1, info.RefCount, "references must be decremented")
+	}
+
+	t.Log("delete image by another name")
+	{
+		c, rec := s.echoDeleteImageContext(copyName)
+		err := imageHTTPHandler.Delete(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNoContent, rec.Code, "response status must be No Content")
+
+		require.Empty(storedImages(), "content must be removed with the last image sharing it")
+
+		_, err = imageMetaStore.Get(ctx, copyName)
+		require.ErrorIs(err, storage.ErrImageNotFound, "image metadata must be removed")
+		_, err = imageMetaStore.Get(ctx, imageName)
+		require.ErrorIs(err, storage.ErrImageNotFound, "name must be released with the content")
+	}
+
+	t.Log("upload image again after it was removed")
+	{
+		c, rec := s.echoUploadContext("/images/upload", copyName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.JSONEq(`{"name": "logo-copy.png", "url": "/images/logo-copy.png/download"}`, rec.Body.String(), "new image must be stored")
+		require.Equal([]string{copyName}, storedImages(), "image must be stored again")
+	}
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandlerCaching() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{CacheMaxAge: imageCacheMaxAge})
+
+	imageName := "avatar.png"
+	pngContent := []byte("\x89PNG\r\n\x1a\navatar")
+
+	t.Log("upload image")
+	{
+		c, rec := s.echoUploadContext("/images/upload", imageName, pngContent)
+		err := imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+	}
+
+	info, err := imageMetaStore.Get(ctx, imageName)
+	require.NoError(err, "failed to read image metadata")
+
+	var lastModified string
+
+	t.Log("download image with cache headers")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(pngContent, rec.Body.Bytes(), "full image must be returned")
+		require.Equal(fmt.Sprintf("%q", info.Hash), rec.Header().Get("ETag"), "etag must be content hash")
+		require.Equal("private, max-age=3600", rec.Header().Get("Cache-Control"), "max age from config must be used")
+
+		lastModified = rec.Header().Get("Last-Modified")
+		require.NotEmpty(lastModified, "last modified must be set")
+	}
+
+	t.Log("download image with matching etag")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("If-None-Match", fmt.Sprintf("%q", info.Hash))
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNotModified, rec.Code, "response status must be Not Modified")
+		require.Empty(rec.Body.Bytes(), "body must be empty for not modified image")
+	}
+
+	t.Log("download image with stale etag")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("If-None-Match", `"stale"`)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(pngContent, rec.Body.Bytes(), "full image must be returned")
+	}
+
+	t.Log("download image not modified since last download")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("If-Modified-Since", lastModified)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusNotModified, rec.Code, "response status must be Not Modified")
+	}
+
+	t.Log("download image modified since requested date")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		c.Request().Header.Set("If-Modified-Since", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(pngContent, rec.Body.Bytes(), "full image must be returned")
+	}
+
+	t.Log("download image version addressed by content hash")
+	{
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download?v=%s", imageName, info.Hash), imageName)
+		err := imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal("private, max-age=31536000, immutable", rec.Header().Get("Cache-Control"), "hash addressed image must be immutable")
+
+		c, rec = s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download?v=outdated", imageName), imageName)
+		err = imageHTTPHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal("private, max-age=3600", rec.Header().Get("Cache-Control"), "outdated version must not be immutable")
+	}
+
+	t.Log("public image without max age must be revalidated")
+	{
+		publicHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{PublicDownloads: true})
+
+		c, rec := s.echoDownloadImageContext(fmt.Sprintf("/images/%s/download", imageName), imageName)
+		err := publicHandler.Download(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal("public, no-cache", rec.Header().Get("Cache-Control"), "image must be revalidated")
+	}
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandlerMetadataStripping() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	jpegContent, err := os.ReadFile(filepath.Join("testdata", "exif.jpg"))
+	require.NoError(err, "failed to read jpeg fixture")
+	require.True(bytes.Contains(jpegContent, []byte("Exif\x00\x00")), "fixture must contain exif")
+
+	t.Log("exif is stripped from uploaded jpeg")
+	{
+		imagesRoot := t.TempDir()
+		imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+		require.NoError(err, "failed to build image metadata store")
+
+		imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{StripMetadata: true, CacheMaxAge: imageCacheMaxAge})
+
+		c, rec := s.echoUploadContext("/images/upload", "photo.jpg", jpegContent)
+		err = imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		stored, err := os.ReadFile(filepath.Join(imagesRoot, "photo.jpg"))
+		require.NoError(err, "failed to read stored image")
+		require.False(bytes.Contains(stored, []byte("Exif\x00\x00")), "stored image must not contain exif")
+		require.False(bytes.Contains(stored, []byte("SecretCam")), "stored image must not contain camera make")
+
+		info, err := imageMetaStore.Get(ctx, "photo.jpg")
+		require.NoError(err, "no error must be raised")
+		require.True(info.MetadataStripped, "stripping must be recorded in metadata")
+		require.Equal(int64(len(stored)), info.Size, "size of stripped image must be stored")
+	}
+
+	t.Log("jpeg is stored as is if stripping is disabled")
+	{
+		imagesRoot := t.TempDir()
+		imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+		require.NoError(err, "failed to build image metadata store")
+
+		imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{CacheMaxAge: imageCacheMaxAge})
+
+		c, _ := s.echoUploadContext("/images/upload", "photo.jpg", jpegContent)
+		err = imageHTTPHandler.Upload(c)
+		require.NoError(err, "no error must be raised")
+
+		stored, err := os.ReadFile(filepath.Join(imagesRoot, "photo.jpg"))
+		require.NoError(err, "failed to read stored image")
+		require.Equal(jpegContent, stored, "image must be stored unchanged")
+
+		info, err := imageMetaStore.Get(ctx, "photo.jpg")
+		require.NoError(err, "no error must be raised")
+		require.False(info.MetadataStripped, "stripping must not be recorded")
+	}
+
+	t.Log("corrupted jpeg is rejected")
+	{
+		imagesRoot := t.TempDir()
+		imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+		require.NoError(err, "failed to build image metadata store")
+
+		imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{StripMetadata: true, CacheMaxAge: imageCacheMaxAge})
+
+		c, _ := s.echoUploadContext("/images/upload", "broken.jpg", jpegContent[:20])
+		err = imageHTTPHandler.Upload(c)
+		require.Error(err, "jpeg is corrupted but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+}
+
+func (s *handlersTestSuite) TestImageGrpcHandler() {
+	t := s.T()
+	require := s.Require()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	jwtValidator := auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, "")
+
+	token, err := jwtIssuer.Sign(auth.Principal{Email: testEmail}, time.Now())
+	require.NoError(err, "failed to sign jwt")
+
+	imagesRoot := t.TempDir()
+	imageStorage := storage.NewFilesystemImageStorage(imagesRoot)
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageMaxSize := int64(64)
+	imageGrpcHandler := NewImageGrpcHandler(imageStorage, imageMetaStore, &config.ImagesCfg{StreamMaxSize: imageMaxSize})
+
+	// interceptors are chained the same way as in application
+	listener := bufconn.Listen(grpcConnBufSize)
+	server := grpc.NewServer(grpc.ChainStreamInterceptor(
+		interceptors.AuthStreamInterceptor(jwtValidator, auth.NewInMemoryTokenRevoker(jwtTimeToLive)),
+		interceptors.ErrorStreamInterceptor(),
+	))
+	proto.RegisterImageServiceServer(server, imageGrpcHandler)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(err, "failed to create gRPC connection")
+	defer conn.Close()
+
+	client := proto.NewImageServiceClient(conn)
+	authCtx := metadata.AppendToOutgoingContext(ctx, "accessToken", token.Signed)
+
+	// upload streams content in chunks of provided size, only first chunk carries name
+	upload := func(ctx context.Context, name string, content []byte, chunkSize int) (*proto.ImageRef, error) {
+		stream, err := client.UploadImage(ctx)
+		require.NoError(err, "failed to open upload stream")
+
+		for offset := 0; offset < len(content); offset += chunkSize {
+			end := offset + chunkSize
+			if end > len(content) {
+				end = len(content)
+			}
+
+			chunk := &proto.ImageChunk{Data: content[offset:end]}
+			if offset == 0 {
+				chunk.Name = name
+			}
+
+			if err := stream.Send(chunk); err != nil {
+				break // server has already finished stream, error is returned on close
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	pngContent := []byte("\x89PNG\r\n\x1a\nstreamed-in-several-chunks")
+
+	t.Log("upload without token is rejected")
+	{
+		_, err := upload(ctx, "logo.png", pngContent, 8)
+		require.Equal(codes.Unauthenticated, status.Code(err), "status code must be unauthenticated")
+	}
+
+	t.Log("image streamed in chunks is stored")
+	{
+		ref, err := upload(authCtx, "logo.png", pngContent, 8)
+		require.NoError(err, "no error must be raised")
+		require.Equal("logo.png", ref.Name, "incorrect image name returned")
+		require.Equal("/images/logo.png/download", ref.Url, "incorrect image url returned")
+
+		info, err := imageMetaStore.Get(ctx, "logo.png")
+		require.NoError(err, "image metadata must be stored")
+		require.Equal(int64(len(pngContent)), info.Size, "incorrect size stored")
+		require.Equal("image/png", info.ContentType, "content type must be detected from the first chunk")
+		require.Equal(testEmail, info.Uploader, "uploader must be taken from token")
+
+		file, err := imageStorage.Open(ctx, "logo.png")
+		require.NoError(err, "failed to open stored image")
+		defer file.Close()
+
+		stored, err := io.ReadAll(file)
+		require.NoError(err, "failed to read stored image")
+		require.Equal(pngContent, stored, "chunks must be assembled in order")
+	}
+
+	t.Log("image with the same name without overwrite is rejected")
+	{
+		_, err := upload(authCtx, "logo.png", append(pngContent, "-updated"...), 8)
+		require.Equal(codes.AlreadyExists, status.Code(err), "status code must be already exists")
+	}
+
+	t.Log("image without name is rejected")
+	{
+		_, err := upload(authCtx, "", pngContent, 8)
+		require.Equal(codes.FailedPrecondition, status.Code(err), "status code must be failed precondition")
+	}
+
+	t.Log("not allowed MIME type is rejected on the first chunk")
+	{
+		_, err := upload(authCtx, "notes.txt", []byte("plain text is not an image"), 8)
+		require.Equal(codes.FailedPrecondition, status.Code(err), "status code must be failed precondition")
+	}
+
+	t.Log("image with extension not matching content is rejected on the first chunk")
+	{
+		_, err := upload(authCtx, "logo.gif", pngContent, 8)
+		require.Equal(codes.FailedPrecondition, status.Code(err), "status code must be failed precondition")
+	}
+
+	t.Log("image exceeding max size is rejected")
+	{
+		oversized := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("x"), int(imageMaxSize))...)
+		_, err := upload(authCtx, "big.png", oversized, 16)
+		require.Equal(codes.ResourceExhausted, status.Code(err), "status code must be resource exhausted")
+
+		_, err = imageMetaStore.Get(ctx, "big.png")
+		require.ErrorIs(err, storage.ErrImageNotFound, "oversized image must not be stored")
+	}
+}
+
+func (s *handlersTestSuite) TestImageHTTPHandlerAuthorization() {
+	t := s.T()
+	require := s.Require()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	token, err := jwtIssuer.Sign(auth.Principal{Email: testEmail}, time.Now())
+	require.NoError(err, "failed to sign jwt")
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &config.ImagesCfg{StripMetadata: true, CacheMaxAge: imageCacheMaxAge})
+
+	// routes are mounted the same way as in application
+	app := echo.New()
+	images := app.Group("/images")
+	images.GET("", imageHTTPHandler.List, authorizeMw)
+	images.POST("/upload", imageHTTPHandler.Upload, authorizeMw)
+	images.GET("/:name/download", imageHTTPHandler.Download, authorizeMw)
+	images.DELETE("/:name", imageHTTPHandler.Delete, authorizeMw)
+
+	publicImages := app.Group("/public/images")
+	publicImages.POST("/upload", imageHTTPHandler.Upload, authorizeMw)
+	publicImages.GET("/:name/download", imageHTTPHandler.Download)
+
+	imageName := "avatar.png"
+	pngContent := []byte("\x89PNG\r\n\x1a\navatar")
+
+	t.Log("endpoints reject requests without token")
+	{
+		rec := s.serveRequest(app, s.uploadRequest("/images/upload", imageName, pngContent), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "upload must require token")
+
+		rec = s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/images", http.NoBody), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "list must require token")
+
+		rec = s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/images/avatar.png/download", http.NoBody), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "download must require token")
+
+		rec = s.serveRequest(app, httptest.NewRequest(http.MethodDelete, "/images/avatar.png", http.NoBody), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "delete must require token")
+	}
+
+	t.Log("endpoints reject requests with invalid token")
+	{
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/images", http.NoBody), "invalid")
+		require.Equal(http.StatusUnauthorized, rec.Code, "list must reject invalid token")
+	}
+
+	t.Log("endpoints serve requests with token")
+	{
+		rec := s.serveRequest(app, s.uploadRequest("/images/upload", imageName, pngContent), token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "upload must be allowed with token")
+
+		rec = s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/images", http.NoBody), token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "list must be allowed with token")
+
+		var page imagesPage
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &page), "failed to decode images page")
+		require.Len(page.Images, 1, "uploaded image must be listed")
+		require.Equal(testEmail, page.Images[0].Uploader, "token subject must be stored as uploader")
+
+		rec = s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/images/avatar.png/download", http.NoBody), token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "download must be allowed with token")
+		require.Equal(pngContent, rec.Body.Bytes(), "incorrect image content downloaded")
+	}
+
+	t.Log("public downloads don't require token while uploads still do")
+	{
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/public/images/avatar.png/download", http.NoBody), "")
+		require.Equal(http.StatusOK, rec.Code, "public download must be allowed without token")
+
+		rec = s.serveRequest(app, s.uploadRequest("/public/images/upload", imageName, pngContent), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "upload must require token even if downloads are public")
+	}
+
+	t.Log("delete image with token")
+	{
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodDelete, "/images/avatar.png", http.NoBody), token.Signed)
+		require.Equal(http.StatusNoContent, rec.Code, "delete must be allowed with token")
+	}
+}
+
+//nolint:funlen // function contains a lot of inlined tests
+func (s *handlersTestSuite) TestImageHTTPHandlerSignedURL() {
+	t := s.T()
+	require := s.Require()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	token, err := jwtIssuer.Sign(auth.Principal{Email: testEmail}, time.Now())
+	require.NoError(err, "failed to sign jwt")
+
+	imagesRoot := t.TempDir()
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	require.NoError(err, "failed to build image metadata store")
+
+	imagesCfg := &config.ImagesCfg{
+		CacheMaxAge:         imageCacheMaxAge,
+		SignedURLKey:        "0123456789abcdef0123456789abcdef",
+		SignedURLTimeToLive: time.Minute,
+		SignedURLClockSkew:  time.Second,
+	}
+	imageHTTPHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, imagesCfg)
+
+	// routes are mounted the same way as in application
+	app := echo.New()
+	app.IPExtractor, err = middleware.IPExtractor(nil)
+	require.NoError(err, "failed to build ip extractor")
+	images := app.Group("/images")
+	images.POST("/upload", imageHTTPHandler.Upload, authorizeMw)
+	images.GET("/:name/download", imageHTTPHandler.Download, authorizeMw)
+	images.POST("/:name/signed-url", imageHTTPHandler.SignedURL, authorizeMw)
+	images.GET("/:name/signed-download", imageHTTPHandler.SignedDownload)
+
+	imageName := "private photo.png"
+	pngContent := []byte("\x89PNG\r\n\x1a\nprivate")
+
+	signURL := func(query string) signedURL {
+		target := fmt.Sprintf("/images/%s/signed-url%s", url.PathEscape(imageName), query)
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodPost, target, http.NoBody), token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "URL must be signed")
+
+		var signed signedURL
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &signed), "failed to decode signed URL")
+		return signed
+	}
+
+	download := func(target string, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		if remoteAddr != "" {
+			req.RemoteAddr = remoteAddr
+		}
+		return s.serveRequest(app, req, "")
+	}
+
+	t.Log("upload image")
+	{
+		rec := s.serveRequest(app, s.uploadRequest("/images/upload", imageName, pngContent), token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "upload must succeed")
+	}
+
+	t.Log("signing URL requires token")
+	{
+		target := fmt.Sprintf("/images/%s/signed-url", url.PathEscape(imageName))
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodPost, target, http.NoBody), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "signing URL must require token")
+	}
+
+	t.Log("signing URL for missing image is rejected")
+	{
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodPost, "/images/missing.png/signed-url", http.NoBody), token.Signed)
+		require.Equal(http.StatusNotFound, rec.Code, "response status must be Not Found")
+	}
+
+	signed := signURL("")
+
+	t.Log("image is downloaded by signed URL without token")
+	{
+		require.WithinDuration(time.Now().Add(time.Minute), time.Unix(signed.ExpiresAt, 0), 2*time.Second, "incorrect expiration")
+
+		rec := download(signed.URL, "")
+		require.Equal(http.StatusOK, rec.Code, "signed download must succeed")
+		require.Equal(pngContent, rec.Body.Bytes(), "incorrect image content downloaded")
+	}
+
+	t.Log("tampered signed URL is rejected")
+	{
+		parsed, err := url.Parse(signed.URL)
+		require.NoError(err, "failed to parse signed URL")
+
+		q := parsed.Query()
+		q.Set("expires", fmt.Sprint(signed.ExpiresAt+3600))
+		rec := download(fmt.Sprintf("%s?%s", parsed.EscapedPath(), q.Encode()), "")
+		require.Equal(http.StatusForbidden, rec.Code, "extended expiration must be rejected")
+
+		otherName := fmt.Sprintf("/images/other.png/signed-download?%s", parsed.RawQuery)
+		rec = download(otherName, "")
+		require.Equal(http.StatusForbidden, rec.Code, "signature for another image must be rejected")
+
+		q = parsed.Query()
+		q.Set("signature", "forged")
+		rec = download(fmt.Sprintf("%s?%s", parsed.EscapedPath(), q.Encode()), "")
+		require.Equal(http.StatusForbidden, rec.Code, "forged signature must be rejected")
+
+		rec = download(parsed.EscapedPath(), "")
+		require.Equal(http.StatusForbidden, rec.Code, "unsigned URL must be rejected")
+	}
+
+	t.Log("signed URL bound to client IP")
+	{
+		bound := signURL("?bindIp=true")
+
+		rec := download(bound.URL, "")
+		require.Equal(http.StatusOK, rec.Code, "download from the same IP must succeed")
+
+		rec = download(bound.URL, "203.0.113.9:4321")
+		require.Equal(http.StatusForbidden, rec.Code, "download from another IP must be rejected")
+
+		unbound := strings.Replace(bound.URL, "bindIp=true", "bindIp=false", 1)
+		rec = download(unbound, "203.0.113.9:4321")
+		require.Equal(http.StatusForbidden, rec.Code, "IP binding must not be dropped")
+	}
+
+	t.Log("client IP of signed URL can't be spoofed with X-Forwarded-For")
+	{
+		bound := signURL("?bindIp=true")
+		boundIP, _, err := net.SplitHostPort(httptest.NewRequest(http.MethodGet, "/", http.NoBody).RemoteAddr)
+		require.NoError(err, "failed to parse remote address")
+
+		req := httptest.NewRequest(http.MethodGet, bound.URL, http.NoBody)
+		req.RemoteAddr = "203.0.113.9:4321"
+		req.Header.Set(echo.HeaderXForwardedFor, boundIP)
+		rec := s.serveRequest(app, req, "")
+		require.Equal(http.StatusForbidden, rec.Code, "download from another IP claiming bound IP must be rejected")
+
+		target := fmt.Sprintf("/images/%s/signed-url?bindIp=true", url.PathEscape(imageName))
+		req = httptest.NewRequest(http.MethodPost, target, http.NoBody)
+		req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.9")
+		rec = s.serveRequest(app, req, token.Signed)
+		require.Equal(http.StatusOK, rec.Code, "URL must be signed")
+
+		var spoofed signedURL
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &spoofed), "failed to decode signed URL")
+
+		rec = download(spoofed.URL, "203.0.113.9:4321")
+		require.Equal(http.StatusForbidden, rec.Code, "URL must be bound to connection address, not to X-Forwarded-For")
+
+		rec = download(spoofed.URL, "")
+		require.Equal(http.StatusOK, rec.Code, "download from connection address must succeed")
+	}
+
+	t.Log("expired signed URL is rejected")
+	{
+		expiredCfg := *imagesCfg
+		expiredCfg.SignedURLTimeToLive = -time.Minute
+		expiredHandler := NewImageHTTPHandler(storage.NewFilesystemImageStorage(imagesRoot), imageMetaStore, &expiredCfg)
+
+		expiredApp := echo.New()
+		expiredApp.POST("/images/:name/signed-url", expiredHandler.SignedURL)
+		expiredApp.GET("/images/:name/signed-download", expiredHandler.SignedDownload)
+
+		target := fmt.Sprintf("/images/%s/signed-url", url.PathEscape(imageName))
+		rec := s.serveRequest(expiredApp, httptest.NewRequest(http.MethodPost, target, http.NoBody), "")
+		require.Equal(http.StatusOK, rec.Code, "URL must be signed")
+
+		var expired signedURL
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &expired), "failed to decode signed URL")
+
+		rec = s.serveRequest(expiredApp, httptest.NewRequest(http.MethodGet, expired.URL, http.NoBody), "")
+		require.Equal(http.StatusForbidden, rec.Code, "expired URL must be rejected")
+	}
+}
+
+func (s *handlersTestSuite) TestAdminHTTPHandler() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	adminID := "3f6c1d2e-8a4b-4c5d-9e0f-1a2b3c4d5e6f"
+	adminToken, err := jwtIssuer.Sign(auth.Principal{Email: "admin@somemail.com", UserID: adminID}, time.Now())
+	require.NoError(err, "failed to sign admin jwt")
+
+	userToken, err := jwtIssuer.Sign(auth.Principal{Email: "user@somemail.com", UserID: "9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a"}, time.Now())
+	require.NoError(err, "failed to sign user jwt")
+
+	runtimeCfgFile := filepath.Join(t.TempDir(), "runtime.json")
+	require.NoError(os.WriteFile(runtimeCfgFile, []byte(`{}`), 0o600), "failed to write runtime config")
+
+	runtimeCfg, err := config.NewRuntimeHolder(runtimeCfgFile, config.RuntimeCfg{CustomerCacheTimeToLive: customerCacheTimeToLive})
+	require.NoError(err, "failed to build runtime config")
+
+	flags, err := feature.NewFlags("")
+	require.NoError(err, "failed to build feature flags")
+
+	customerCache := cache.NewRedisCustomerCache(s.redisClient, runtimeCfg)
+	adminHTTPHandler := NewAdminHTTPHandler(runtimeCfg, flags, s.sessionSvc, service.NewCacheService())
+
+	// routes are mounted the same way as in application
+	app := echo.New()
+	app.POST("/api/admin/reload", adminHTTPHandler.Reload, authorizeMw, middleware.Admin([]string{adminID}))
+
+	reloadRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/api/admin/reload", http.NoBody)
+	}
+
+	t.Log("reload is available only for admins")
+	{
+		rec := s.serveRequest(app, reloadRequest(), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "reload must require token")
+
+		rec = s.serveRequest(app, reloadRequest(), userToken.Signed)
+		require.Equal(http.StatusForbidden, rec.Code, "reload must be forbidden for regular user")
+	}
+
+	t.Log("invalid runtime config is rejected and current config is kept")
+	{
+		err := os.WriteFile(runtimeCfgFile, []byte(`{"customerCacheTimeToLive": "soon"}`), 0o600)
+		require.NoError(err, "failed to write runtime config")
+
+		rec := s.serveRequest(app, reloadRequest(), adminToken.Signed)
+		require.Equal(http.StatusInternalServerError, rec.Code, "invalid config must be rejected")
+		require.Equal(customerCacheTimeToLive, runtimeCfg.CustomerCacheTimeToLive(), "current config must be kept")
+	}
+
+	t.Log("changed cache TTL takes effect after reload")
+	{
+		newTimeToLive := 10 * time.Minute
+		err := os.WriteFile(runtimeCfgFile, []byte(`{"customerCacheTimeToLive": "10m"}`), 0o600)
+		require.NoError(err, "failed to write runtime config")
+
+		rec := s.serveRequest(app, reloadRequest(), adminToken.Signed)
+		require.Equal(http.StatusOK, rec.Code, "reload must succeed")
+		require.JSONEq(`{"customerCacheTimeToLive": "10m0s"}`, rec.Body.String(), "reloaded config must be returned")
+		require.Equal(newTimeToLive, runtimeCfg.CustomerCacheTimeToLive(), "new TTL must be applied")
+
+		c := &model.Customer{
+			ID:         "5e4d3c2b-1a0f-4e9d-8c7b-6a5f4e3d2c1b",
+			TenantID:   tenant.DefaultID,
+			FirstName:  "John",
+			LastName:   "Smith",
+			Email:      "john.smith.ttl@somemail.com",
+			Importance: model.ImportanceMedium,
+		}
+		err = customerCache.Create(ctx, c)
+		require.NoError(err, "failed to cache customer")
+
+		ttl, err := s.redisClient.TTL(ctx, fmt.Sprintf("customer:%s:%s", c.TenantID, c.ID)).Result()
+		require.NoError(err, "failed to read cached customer TTL")
+		require.Greater(ttl, customerCacheTimeToLive, "cached customer must use new TTL")
+		require.LessOrEqual(ttl, newTimeToLive, "cached customer TTL can't exceed configured one")
+	}
+}
+
+func (s *handlersTestSuite) TestAdminHTTPHandlerPurgeCache() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	adminID := "3f6c1d2e-8a4b-4c5d-9e0f-1a2b3c4d5e6f"
+	adminToken, err := jwtIssuer.Sign(auth.Principal{Email: "admin@somemail.com", UserID: adminID}, time.Now())
+	require.NoError(err, "failed to sign admin jwt")
+
+	userToken, err := jwtIssuer.Sign(auth.Principal{Email: "user@somemail.com", UserID: "9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a"}, time.Now())
+	require.NoError(err, "failed to sign user jwt")
+
+	runtimeCfg, err := config.NewRuntimeHolder("", config.RuntimeCfg{CustomerCacheTimeToLive: customerCacheTimeToLive})
+	require.NoError(err, "failed to build runtime config")
+
+	flags, err := feature.NewFlags("")
+	require.NoError(err, "failed to build feature flags")
+
+	redisCustomerCache := cache.NewRedisCustomerCache(s.redisClient, runtimeCfg)
+	inMemoryCustomerCache := cache.NewInMemoryCache()
+	cacheSvc := service.NewCacheService(cache.NewRedisCustomerCachePurger(s.redisClient), inMemoryCustomerCache)
+	adminHTTPHandler := NewAdminHTTPHandler(runtimeCfg, flags, s.sessionSvc, cacheSvc)
+
+	// routes are mounted the same way as in application
+	app := echo.New()
+	app.POST("/api/admin/cache/purge", adminHTTPHandler.PurgeCache, authorizeMw, middleware.Admin([]string{adminID}))
+
+	purgeRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/api/admin/cache/purge", http.NoBody)
+	}
+
+	customers := []*model.Customer{
+		{ID: "7b6a5f4e-3d2c-4b1a-9f8e-7d6c5b4a3f2e", TenantID: tenant.DefaultID, FirstName: "John", LastName: "Smith", Email: "john.smith.purge@somemail.com", Importance: model.ImportanceLow},
+		{ID: "8c7b6a5f-4e3d-4c2b-8a9f-8e7d6c5b4a3f", TenantID: "acme", FirstName: "Jane", LastName: "Doe", Email: "jane.doe.purge@somemail.com", Importance: model.ImportanceHigh},
+	}
+	for _, c := range customers {
+		require.NoError(redisCustomerCache.Create(ctx, c), "failed to cache customer in redis")
+		require.NoError(inMemoryCustomerCache.Create(ctx, c), "failed to cache customer in memory")
+	}
+
+	foreignKey := "revoked:sub:9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a"
+	require.NoError(s.redisClient.Set(ctx, foreignKey, "1", time.Minute).Err(), "failed to set key outside of customers namespace")
+
+	t.Log("purge is available only for admins")
+	{
+		rec := s.serveRequest(app, purgeRequest(), "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "purge must require token")
+
+		rec = s.serveRequest(app, purgeRequest(), userToken.Signed)
+		require.Equal(http.StatusForbidden, rec.Code, "purge must be forbidden for regular user")
+	}
+
+	t.Log("customer keys are removed from redis and memory, other keys are kept")
+	{
+		rec := s.serveRequest(app, purgeRequest(), adminToken.Signed)
+		require.Equal(http.StatusOK, rec.Code, "purge must succeed")
+		require.JSONEq(`{"removed": 4}`, rec.Body.String(), "number of removed entries must be returned")
+
+		for _, c := range customers {
+			exists, err := s.redisClient.Exists(ctx, fmt.Sprintf("customer:%s:%s", c.TenantID, c.ID)).Result()
+			require.NoError(err, "failed to check cached customer")
+			require.Zero(exists, "customer must be removed from redis")
+
+			cached, err := inMemoryCustomerCache.FindByID(ctx, c.TenantID, c.ID)
+			require.NoError(err, "failed to read cached customer")
+			require.Nil(cached, "customer must be removed from memory")
+		}
+
+		exists, err := s.redisClient.Exists(ctx, foreignKey).Result()
+		require.NoError(err, "failed to check key outside of customers namespace")
+		require.Equal(int64(1), exists, "key outside of customers namespace must be kept")
+	}
+
+	t.Log("purge of empty cache removes nothing")
+	{
+		rec := s.serveRequest(app, purgeRequest(), adminToken.Signed)
+		require.Equal(http.StatusOK, rec.Code, "purge must succeed")
+		require.JSONEq(`{"removed": 0}`, rec.Body.String(), "nothing must be removed")
+	}
+}
+
+//nolint:funlen // function contains a lot of inlined tests
+func (s *handlersTestSuite) TestAdminHTTPHandlerSessions() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+	defer cancel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(err, "failed to generate jwt keys")
+
+	signingMethod := jwt.GetSigningMethod(jwtAlgoEd25519)
+	jwtIssuer := auth.NewJwtIssuer(jwtIssuerClaim, "", signingMethod, jwtTimeToLive, privateKey)
+	authorizeMw := middleware.Authorize(auth.NewJwtValidator(signingMethod, publicKey, jwtIssuerClaim, ""), auth.NewInMemoryTokenRevoker(jwtTimeToLive))
+
+	adminID := "3f6c1d2e-8a4b-4c5d-9e0f-1a2b3c4d5e6f"
+	adminToken, err := jwtIssuer.Sign(auth.Principal{Email: "admin@somemail.com", UserID: adminID}, time.Now())
+	require.NoError(err, "failed to sign admin jwt")
+
+	userToken, err := jwtIssuer.Sign(auth.Principal{Email: "user@somemail.com", UserID: "9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a"}, time.Now())
+	require.NoError(err, "failed to sign user jwt")
+
+	runtimeCfg, err := config.NewRuntimeHolder("", config.RuntimeCfg{CustomerCacheTimeToLive: customerCacheTimeToLive})
+	require.NoError(err, "failed to build runtime config")
+
+	flags, err := feature.NewFlags("")
+	require.NoError(err, "failed to build feature flags")
+
+	adminHTTPHandler := NewAdminHTTPHandler(runtimeCfg, flags, s.sessionSvc, service.NewCacheService())
+
+	// routes are mounted the same way as in application
+	app := echo.New()
+	app.Validator = s.app.Validator
+	app.GET("/api/admin/sessions", adminHTTPHandler.Sessions, authorizeMw, middleware.Admin([]string{adminID}))
+
+	txExecutor := transactor.NewPgxWithinTransactionExecutor(s.pgPool)
+	userRps := repository.NewPostgresUserRepository(txExecutor)
+	rfrTokenRps := repository.NewPostgresRefreshTokenRepository(txExecutor)
+
+	userKate := &model.User{ID: "6a7b8c9d-0e1f-4a2b-8c3d-4e5f6a7b8c9d", Email: "kate.sessions@somemail.com", PasswordHash: "hash"}
+	userPaul := &model.User{ID: "7b8c9d0e-1f2a-4b3c-9d4e-5f6a7b8c9d0e", Email: "paul.sessions@somemail.com", PasswordHash: "hash"}
+
+	now := time.Now().UTC()
+
+	// kate has 2 active and 1 expired session, paul has 1 expired session
+	refreshTokens := []*model.RefreshToken{
+		{ID: "8c9d0e1f-2a3b-4c4d-8e5f-6a7b8c9d0e1f", UserID: userKate.ID, Fingerprint: testFingerprint, ExpiresIn: 3600, CreatedAt: now.Add(-time.Minute)},
+		{ID: "9d0e1f2a-3b4c-4d5e-9f6a-7b8c9d0e1f2a", UserID: userKate.ID, Fingerprint: testFingerprint, ExpiresIn: 3600, CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "0e1f2a3b-4c5d-4e6f-8a7b-8c9d0e1f2a3b", UserID: userKate.ID, Fingerprint: testFingerprint, ExpiresIn: 60, CreatedAt: now.Add(-time.Hour)},
+		{ID: "1f2a3b4c-5d6e-4f7a-9b8c-9d0e1f2a3b4c", UserID: userPaul.ID, Fingerprint: testFingerprint, ExpiresIn: 60, CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	t.Log("reference users and sessions must be added")
+	{
+		_, err := s.pgPool.Exec(ctx, "DELETE FROM refresh_tokens")
+		require.NoError(err, "failed to clean up refresh tokens")
+
+		for _, u := range []*model.User{userKate, userPaul} {
+			err := userRps.Create(ctx, u)
+			require.NoError(err, "failed to create user %s", u.Email)
+		}
+
+		for _, tkn := range refreshTokens {
+			err := rfrTokenRps.Create(ctx, tkn)
+			require.NoError(err, "failed to create token %s", tkn.ID)
+		}
+	}
+
+	listSessions := func(query, token string) (*httptest.ResponseRecorder, sessionsPage) {
+		rec := s.serveRequest(app, httptest.NewRequest(http.MethodGet, "/api/admin/sessions"+query, http.NoBody), token)
+
+		var page sessionsPage
+		if rec.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(rec.Body.Bytes(), &page), "failed to decode sessions page")
+		}
+		return rec, page
+	}
+
+	t.Log("sessions are available only for admins")
+	{
+		rec, _ := listSessions("", "")
+		require.Equal(http.StatusUnauthorized, rec.Code, "sessions must require token")
+
+		rec, _ = listSessions("", userToken.Signed)
+		require.Equal(http.StatusForbidden, rec.Code, "sessions must be forbidden for regular user")
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+		total    int
+		active   int
+	}{
+		{name: "no filters", query: "", expected: 4, total: 4, active: 2},
+		{name: "by user", query: "?userId=" + userPaul.ID, expected: 1, total: 1, active: 0},
+		{name: "active only", query: "?activeOnly=true", expected: 2, total: 2, active: 2},
+		{name: "by user active only", query: fmt.Sprintf("?userId=%s&activeOnly=true", userKate.ID), expected: 2, total: 2, active: 2},
+		{name: "page", query: "?limit=2&offset=1", expected: 2, total: 4, active: 1},
+		{name: "by user active only page", query: fmt.Sprintf("?userId=%s&activeOnly=true&limit=1&offset=1", userKate.ID), expected: 1, total: 2, active: 1},
+		{name: "page beyond last session", query: "?limit=2&offset=10", expected: 0, total: 4, active: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("list sessions - %s", tc.name)
+		{
+			rec, page := listSessions(tc.query, adminToken.Signed)
+			require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+			require.Len(page.Sessions, tc.expected, "incorrect number of sessions returned")
+			require.Equal(tc.total, page.Total, "incorrect total returned")
+
+			active := 0
+			for _, sess := range page.Sessions {
+				if sess.Active {
+					active++
+				}
+			}
+			require.Equal(tc.active, active, "incorrect number of active sessions returned")
+		}
+	}
+
+	t.Log("refresh token is not exposed")
+	{
+		rec, page := listSessions("?userId="+userPaul.ID, adminToken.Signed)
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.NotContains(rec.Body.String(), refreshTokens[3].ID, "refresh token must not be returned")
+		require.Equal(userPaul.ID, page.Sessions[0].UserID, "session user must be returned")
+		require.WithinDuration(refreshTokens[3].ExpiresAt(), page.Sessions[0].ExpiresAt, time.Second, "incorrect expiration returned")
+	}
+
+	t.Log("invalid query is rejected")
+	{
+		for _, query := range []string{"?userId=kate", "?limit=0", "?limit=101", "?offset=-1"} {
+			c, _ := s.echoGetContext("/api/admin/sessions" + query)
+			err := adminHTTPHandler.Sessions(c)
+			require.Error(err, "query %s is invalid but no error raised", query)
+			require.IsType(&validation.PayloadError{}, err, "error must be payload error")
+		}
+
+		c, _ := s.echoGetContext("/api/admin/sessions?activeOnly=maybe")
+		err := adminHTTPHandler.Sessions(c)
+		require.Error(err, "activeOnly is not boolean but no error raised")
+		require.Equal(http.StatusBadRequest, s.httpErrorCode(err), "response status must be Bad Request")
+	}
+}
+
+func (s *handlersTestSuite) echoPostContext(target, payload string) (echo.Context, *httptest.ResponseRecorder) {
 	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(payload))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -711,6 +3230,58 @@ func (s *handlersTestSuite) echoPutContext(target, id, payload string) (echo.Con
 	return c, rec
 }
 
+func (s *handlersTestSuite) echoUploadContext(target, filename string, content []byte) (echo.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	return s.app.NewContext(s.uploadRequest(target, filename, content), rec), rec
+}
+
+func (s *handlersTestSuite) uploadRequest(target, filename string, content []byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("image", filename)
+	s.Require().NoError(err, "failed to create multipart form file")
+
+	_, err = part.Write(content)
+	s.Require().NoError(err, "failed to write multipart form file")
+	s.Require().NoError(writer.Close(), "failed to close multipart writer")
+
+	req := httptest.NewRequest(http.MethodPost, target, body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func (s *handlersTestSuite) serveRequest(app *echo.Echo, req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, fmt.Sprintf("Bearer %s", token))
+	}
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *handlersTestSuite) echoDownloadImageContext(target, name string) (echo.Context, *httptest.ResponseRecorder) {
+	c, rec := s.echoGetContext(target)
+	c.SetParamNames("name")
+	c.SetParamValues(name)
+	return c, rec
+}
+
+func (s *handlersTestSuite) echoDeleteImageContext(name string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/images/%s", name), strings.NewReader(""))
+	rec := httptest.NewRecorder()
+	c := s.app.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues(name)
+	return c, rec
+}
+
+// httpErrorCode returns status code error is responded with by http error handler
+func (s *handlersTestSuite) httpErrorCode(err error) int {
+	s.Require().Error(err, "error must be raised")
+	return httpStatus(err)
+}
+
 // start handlers test suite
 func TestHandlersTestSuite(t *testing.T) {
 	suite.Run(t, new(handlersTestSuite))
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+const (
+	healthStatusUp   = "up"
+	healthStatusDown = "down"
+)
+
+// HealthCheck reports if dependency is available, nil error means dependency is healthy
+type HealthCheck func(context.Context) error
+
+type healthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // status of each dependency, readiness only
+}
+
+// HealthHTTPHandler is http handler for liveness and readiness probes
+type HealthHTTPHandler struct {
+	checks map[string]HealthCheck
+}
+
+// NewHealthHTTPHandler builds new HealthHTTPHandler, checks are keyed by dependency name and used by readiness probe only
+func NewHealthHTTPHandler(checks map[string]HealthCheck) *HealthHTTPHandler {
+	return &HealthHTTPHandler{checks: checks}
+}
+
+// Live reports that process is up, dependencies are never checked, so pod is not restarted if they are down
+// @Summary     Liveness probe
+// @Description Reports that process is up, dependencies are not checked
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} healthStatus
+// @Router      /health/live [get]
+func (h *HealthHTTPHandler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, &healthStatus{Status: healthStatusUp})
+}
+
+// Ready reports if all dependencies are available, so traffic can be routed to instance
+// @Summary     Readiness probe
+// @Description Pings dependencies, responds 503 if any of them is unavailable
+// @Tags        health
+// @Produce     json
+// @Success     200 {object} healthStatus
+// @Failure     503 {object} healthStatus
+// @Router      /health/ready [get]
+func (h *HealthHTTPHandler) Ready(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	status, code := healthStatusUp, http.StatusOK
+	checks := make(map[string]string, len(h.checks))
+	for name, check := range h.checks {
+		if err := check(ctx); err != nil {
+			logging.FromContext(ctx).Warnf("readiness check of %s failed - %v", name, err)
+			checks[name] = healthStatusDown
+			status, code = healthStatusDown, http.StatusServiceUnavailable
+			continue
+		}
+		checks[name] = healthStatusUp
+	}
+
+	return c.JSON(code, &healthStatus{Status: status, Checks: checks})
+}
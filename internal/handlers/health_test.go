@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type healthTestSuite struct {
+	suite.Suite
+	app         *echo.Echo
+	redisHealth error
+	pings       int
+}
+
+func (s *healthTestSuite) SetupTest() {
+	s.redisHealth = nil
+	s.pings = 0
+
+	h := NewHealthHTTPHandler(map[string]HealthCheck{
+		"postgres": func(context.Context) error {
+			s.pings++
+			return nil
+		},
+		"redis": func(context.Context) error {
+			s.pings++
+			return s.redisHealth
+		},
+	})
+
+	s.app = echo.New()
+	s.app.GET("/health/live", h.Live)
+	s.app.GET("/health/ready", h.Ready)
+}
+
+func (s *healthTestSuite) TestHealth() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("liveness is up if dependencies are healthy")
+	{
+		rec, status := s.get("/health/live")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(healthStatusUp, status.Status, "process must be up")
+		require.Empty(status.Checks, "dependencies must not be reported")
+	}
+
+	t.Log("liveness is up if dependency is unhealthy")
+	{
+		s.redisHealth = errors.New("dial tcp 10.0.0.7:6379: connect: connection refused")
+
+		rec, status := s.get("/health/live")
+		require.Equal(http.StatusOK, rec.Code, "liveness must not fail because of dependency")
+		require.Equal(healthStatusUp, status.Status, "process must be up")
+		require.Zero(s.pings, "dependencies must not be pinged by liveness probe")
+	}
+
+	t.Log("readiness is down if dependency is unhealthy")
+	{
+		rec, status := s.get("/health/ready")
+		require.Equal(http.StatusServiceUnavailable, rec.Code, "response status must be Service Unavailable")
+		require.Equal(healthStatusDown, status.Status, "instance must not be ready")
+		require.Equal(map[string]string{"postgres": healthStatusUp, "redis": healthStatusDown}, status.Checks, "status of each dependency must be reported")
+	}
+
+	t.Log("readiness is up if dependencies are healthy")
+	{
+		s.redisHealth = nil
+
+		rec, status := s.get("/health/ready")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(healthStatusUp, status.Status, "instance must be ready")
+	}
+}
+
+func (s *healthTestSuite) get(target string) (*httptest.ResponseRecorder, *healthStatus) {
+	req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+
+	var status healthStatus
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &status), "failed to decode health status")
+	return rec, &status
+}
+
+// start health handler test suite
+func TestHealthTestSuite(t *testing.T) {
+	suite.Run(t, new(healthTestSuite))
+}
@@ -1,33 +1,118 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"reflect"
+	"strconv"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/config"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/feature"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/storage"
+	"github.com/umalmyha/customers/internal/validation"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
-const mimeBytesNumber = 512
+// MIMEApplicationListEnvelopeJSON is media type clients accept to get list responses wrapped into envelope
+const MIMEApplicationListEnvelopeJSON = "application/vnd.customers.envelope+json"
+
+// MIMEApplicationMsgpack is media type clients accept to get customer responses encoded with msgpack
+const MIMEApplicationMsgpack = "application/msgpack"
+
+const (
+	mimeBytesNumber          = 512
+	defaultImagesPageLimit   = 20
+	maxImagesPageLimit       = 100
+	defaultSessionsPageLimit = 20
+	immutableImageMaxAge     = 365 * 24 * time.Hour
+	headerIfNoneMatch        = "If-None-Match"
+)
+
+type imagesPage struct {
+	Images     []*storage.ImageInfo `json:"images"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
+
+type listMeta struct {
+	Total int `json:"total"`
+	Page  int `json:"page"`
+}
+
+type listEnvelope[T any] struct {
+	Data []T      `json:"data"`
+	Meta listMeta `json:"meta"`
+}
+
+type uploadedImage struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type signedURL struct {
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+type runtimeConfig struct {
+	CustomerCacheTimeToLive             string            `json:"customerCacheTimeToLive"`
+	CustomerCacheTimeToLiveByImportance map[string]string `json:"customerCacheTimeToLiveByImportance,omitempty"`
+}
+
+type purgedCache struct {
+	Removed int `json:"removed"`
+}
+
+type customersQuery struct {
+	ActiveOnly bool `query:"activeOnly"`
+}
+
+type sessionsQuery struct {
+	UserID     string `query:"userId" validate:"omitempty,uuid"`
+	ActiveOnly bool   `query:"activeOnly"`
+	Limit      int    `query:"limit" validate:"min=1,max=100"`
+	Offset     int    `query:"offset" validate:"min=0"`
+}
+
+type sessionInfo struct {
+	UserID      string    `json:"userId"`
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Active      bool      `json:"active"`
+}
+
+type sessionsPage struct {
+	Sessions []*sessionInfo `json:"sessions"`
+	Total    int            `json:"total"`
+}
 
 type session struct {
-	Token        string `json:"accessToken"`
-	ExpiresAt    int64  `json:"expiresAt"`
-	RefreshToken string `json:"refreshToken"`
+	Token                 string `json:"accessToken" redact:"true"`
+	ExpiresAt             int64  `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken" redact:"true"`
+	RefreshTokenExpiresAt int64  `json:"refreshTokenExpiresAt"`
 }
 
 type signup struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=4,max=24"`
+	Password string `json:"password" validate:"required,min=4,max=24" redact:"true"`
 }
 
 type logout struct {
-	RefreshToken string `json:"refreshToken" validate:"required,uuid"`
+	RefreshToken string `json:"refreshToken" validate:"required,uuid" redact:"true"`
 }
 
 type newUser struct {
@@ -37,13 +122,25 @@ type newUser struct {
 
 type login struct {
 	Email       string `json:"email" validate:"required,email"`
-	Password    string `json:"password" validate:"required"`
-	Fingerprint string `json:"fingerprint" validate:"required"`
+	Password    string `json:"password" validate:"required" redact:"true"`
+	Fingerprint string `json:"fingerprint" validate:"required,max=255"`
 }
 
 type refresh struct {
-	Fingerprint  string `json:"fingerprint" validate:"required"`
-	RefreshToken string `json:"refreshToken" validate:"required,uuid"`
+	Fingerprint  string `json:"fingerprint" validate:"required,max=255"`
+	RefreshToken string `json:"refreshToken" validate:"required,uuid" redact:"true"`
+}
+
+type introspect struct {
+	Token string `json:"token" validate:"required" redact:"true"`
+}
+
+// introspection follows OAuth token introspection response, claims are omitted for inactive tokens
+type introspection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
 }
 
 // AuthHTTPHandler is http handler for auth endpoint
@@ -66,8 +163,10 @@ func NewAuthHTTPHandler(authSvc service.AuthService) *AuthHTTPHandler {
 // @Produce     json
 // @Param       signup body	    signup true "New user data"
 // @Success     200    {object} newUser
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Failure     400    {object} errorEnvelope
+// @Failure     422    {object} errorEnvelope
+// @Failure     403    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/auth/signup [post]
 func (h *AuthHTTPHandler) Signup(c echo.Context) error {
 	var su signup
@@ -98,8 +197,9 @@ func (h *AuthHTTPHandler) Signup(c echo.Context) error {
 // @Produce     json
 // @Param       login  body	    login true "User credentials"
 // @Success     200    {object} session
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Failure     400    {object} errorEnvelope
+// @Failure     422    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/auth/login [post]
 func (h *AuthHTTPHandler) Login(c echo.Context) error {
 	var lgn login
@@ -117,9 +217,10 @@ func (h *AuthHTTPHandler) Login(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, &session{
-		Token:        jwt.Signed,
-		ExpiresAt:    jwt.ExpiresAt,
-		RefreshToken: rfrToken.ID,
+		Token:                 jwt.Signed,
+		ExpiresAt:             jwt.ExpiresAt,
+		RefreshToken:          rfrToken.ID,
+		RefreshTokenExpiresAt: rfrToken.ExpiresAt().Unix(),
 	})
 }
 
@@ -130,8 +231,9 @@ func (h *AuthHTTPHandler) Login(c echo.Context) error {
 // @Accept      json
 // @Param       logout body	    logout true "Refresh token id"
 // @Success     200    "Successful status code"
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Failure     400    {object} errorEnvelope
+// @Failure     422    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/auth/logout [post]
 func (h *AuthHTTPHandler) Logout(c echo.Context) error {
 	var lgt logout
@@ -151,16 +253,39 @@ func (h *AuthHTTPHandler) Logout(c echo.Context) error {
 
 // Refresh refreshes user session
 // @Summary     Refresh jwt
-// @Description Sign new jwt and refresh token
+// @Description Sign new jwt and refresh token, refresh token issued less than min rotation age ago is rejected
 // @Tags        auth
 // @Accept      json
 // @Produce     json
 // @Param       refresh body	 refresh true "Fingerprint and refresh token id"
 // @Success     200     {object} session
-// @Failure     400     {object} echo.HTTPError
-// @Failure     500     {object} echo.HTTPError
+// @Failure     400     {object} errorEnvelope
+// @Failure     422     {object} errorEnvelope
+// @Failure     500     {object} errorEnvelope
 // @Router      /api/auth/refresh [post]
 func (h *AuthHTTPHandler) Refresh(c echo.Context) error {
+	return h.refreshSession(c)
+}
+
+// Rotate rotates refresh token of single session before it expires
+// @Summary     Rotate refresh token
+// @Description Proactively replace refresh token of session without re-login. Provided refresh token is revoked, new jwt and refresh token are issued
+// @Description and expiry of both is returned. Refresh token issued less than min rotation age ago is rejected and revoked to prevent rotation storms, guard is disabled by default
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       rotate body     refresh true "Fingerprint and refresh token id"
+// @Success     200    {object} session
+// @Failure     400    {object} errorEnvelope
+// @Failure     422    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
+// @Router      /api/auth/rotate [post]
+func (h *AuthHTTPHandler) Rotate(c echo.Context) error {
+	return h.refreshSession(c)
+}
+
+// refreshSession is shared by Refresh and Rotate, rotation is the same as refresh and differs only in client intent
+func (h *AuthHTTPHandler) refreshSession(c echo.Context) error {
 	var r refresh
 	if err := c.Bind(&r); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -176,38 +301,197 @@ func (h *AuthHTTPHandler) Refresh(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, &session{
-		Token:        jwt.Signed,
-		ExpiresAt:    jwt.ExpiresAt,
-		RefreshToken: rfrToken.ID,
+		Token:                 jwt.Signed,
+		ExpiresAt:             jwt.ExpiresAt,
+		RefreshToken:          rfrToken.ID,
+		RefreshTokenExpiresAt: rfrToken.ExpiresAt().Unix(),
 	})
 }
 
-type identifier struct {
-	ID string `json:"id" validate:"required,uuid"`
+// Introspect reports state of jwt
+// @Summary     Introspect jwt
+// @Description Verifies jwt and returns its subject, issuer and expiration if it is active, nothing is returned about inactive tokens
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Security	ApiKeyAuth
+// @Param       introspect body     introspect true "Token to introspect"
+// @Success     200        {object} introspection
+// @Failure     400        {object} errorEnvelope
+// @Failure     422        {object} errorEnvelope
+// @Failure     401        {object} errorEnvelope
+// @Failure     500        {object} errorEnvelope
+// @Router      /api/auth/introspect [post]
+func (h *AuthHTTPHandler) Introspect(c echo.Context) error {
+	var i introspect
+	if err := c.Bind(&i); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(&i); err != nil {
+		return err
+	}
+
+	res, err := h.authSvc.Introspect(c.Request().Context(), i.Token)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &introspection{
+		Active:    res.Active,
+		Subject:   res.Subject,
+		Issuer:    res.Issuer,
+		ExpiresAt: res.ExpiresAt,
+	})
 }
 
+// newCustomer max lengths match customers table columns
 type newCustomer struct {
-	FirstName  string           `json:"firstName" validate:"required"`
-	LastName   string           `json:"lastName" validate:"required"`
-	MiddleName *string          `json:"middleName"`
-	Email      string           `json:"email" validate:"required,email"`
-	Importance model.Importance `json:"importance" validate:"required,oneof=1 2 3 4"`
+	FirstName  string           `json:"firstName" validate:"required,max=200"`
+	LastName   string           `json:"lastName" validate:"required,max=200"`
+	MiddleName *string          `json:"middleName" validate:"omitempty,max=200"`
+	Email      string           `json:"email" validate:"required,max=250,email"`
+	Importance model.Importance `json:"importance" validate:"required,importance"`
 	Inactive   bool             `json:"inactive"`
 }
 
+// RegisterCustomerValidation registers struct level validation of customer payloads, it applies to create and update
+func RegisterCustomerValidation(v *validator.Validate) {
+	v.RegisterStructValidation(validateNewCustomer, newCustomer{})
+}
+
+// customerExclusions are combinations of customer payload values which are not allowed together
+var customerExclusions = []validation.Exclusion{
+	{Field: "Importance", Value: importanceToHTTP(model.ImportanceCritical), Other: "Inactive", OtherValue: true},
+}
+
+// validateNewCustomer rejects names consisting of whitespaces only, they pass required, and excluded combinations of values
+func validateNewCustomer(sl validator.StructLevel) {
+	validation.ReportBlank(sl, "FirstName", "LastName")
+	validation.ReportExclusions(sl, customerExclusions...)
+}
+
+// importanceFromHTTP converts http api importance to model one, http api numbers importance from 1 while model from 0
+func importanceFromHTTP(i model.Importance) model.Importance {
+	return model.ImportanceFromExternal(i)
+}
+
+func importanceToHTTP(i model.Importance) model.Importance {
+	return i.External()
+}
+
+// optionalImportanceFromHTTP converts optional http api importance, nil is kept as is
+func optionalImportanceFromHTTP(i *model.Importance) *model.Importance {
+	if i == nil {
+		return nil
+	}
+
+	importance := importanceFromHTTP(*i)
+	return &importance
+}
+
+// changesToHTTP converts importance of customer changes to http api one, other changes are kept as is
+func changesToHTTP(changes []model.CustomerChange) []model.CustomerChange {
+	return model.ExternalChanges(changes)
+}
+
+// updateCustomer is payload of customer update, id is taken from path
 type updateCustomer struct {
-	ID string `param:"id" validate:"required,uuid"`
 	newCustomer
 }
 
-// CustomerHTTPHandler is http handler for customer endpoint
+// customerV1 is customer representation of v1 api
+type customerV1 struct {
+	ID         string           `json:"id"`
+	FirstName  string           `json:"firstName"`
+	LastName   string           `json:"lastName"`
+	MiddleName *string          `json:"middleName"`
+	Email      string           `json:"email"`
+	Importance model.Importance `json:"importance"`
+	Inactive   bool             `json:"inactive"`
+}
+
+// customerV2 is customer representation of v2 api, it is separate from v1, so versions can evolve independently
+type customerV2 struct {
+	ID         string           `json:"id"`
+	FirstName  string           `json:"firstName"`
+	LastName   string           `json:"lastName"`
+	MiddleName *string          `json:"middleName"`
+	Email      string           `json:"email"`
+	Importance model.Importance `json:"importance"`
+	Inactive   bool             `json:"inactive"`
+}
+
+// customerView maps customer to representation of api version, nil customer must be mapped to nil
+type customerView func(*model.Customer) any
+
+func customerV1View(c *model.Customer) any {
+	if c == nil {
+		return nil
+	}
+
+	return &customerV1{
+		ID:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: importanceToHTTP(c.Importance),
+		Inactive:   c.Inactive,
+	}
+}
+
+func customerV2View(c *model.Customer) any {
+	if c == nil {
+		return nil
+	}
+
+	return &customerV2{
+		ID:         c.ID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: importanceToHTTP(c.Importance),
+		Inactive:   c.Inactive,
+	}
+}
+
+// customerUpdate is customer with changes applied by update, diff is null if customer was created
+type customerUpdate struct {
+	Customer any                    `json:"customer"` // customer representation of api version
+	Diff     []model.CustomerChange `json:"diff"`
+}
+
+type customersFilter struct {
+	Importance *model.Importance `json:"importance" validate:"omitempty,importance"`
+	Inactive   *bool             `json:"inactive"`
+}
+
+type customersPatch struct {
+	Importance *model.Importance `json:"importance" validate:"omitempty,importance"`
+	Inactive   *bool             `json:"inactive"`
+}
+
+type bulkUpdate struct {
+	Filter customersFilter `json:"filter"`
+	Update customersPatch  `json:"update"`
+}
+
+type bulkUpdateResult struct {
+	Updated int `json:"updated"`
+}
+
+// CustomerHTTPHandler is http handler for customer endpoint of v1 api
 type CustomerHTTPHandler struct {
-	customerSvc service.CustomerService
+	customerSvc  service.CustomerService
+	listEnvelope bool
+	view         customerView
 }
 
-// NewCustomerHTTPHandler builds new CustomerHTTPHandler
-func NewCustomerHTTPHandler(customerSvc service.CustomerService) *CustomerHTTPHandler {
-	return &CustomerHTTPHandler{customerSvc: customerSvc}
+// NewCustomerHTTPHandler builds new CustomerHTTPHandler, lists are always wrapped into envelope if listEnvelope is set
+func NewCustomerHTTPHandler(customerSvc service.CustomerService, listEnvelope bool) *CustomerHTTPHandler {
+	return &CustomerHTTPHandler{customerSvc: customerSvc, listEnvelope: listEnvelope, view: customerV1View}
 }
 
 // Get gets user
@@ -215,16 +499,16 @@ func NewCustomerHTTPHandler(customerSvc service.CustomerService) *CustomerHTTPHa
 // @Description Returns single customer with provided id
 // @Tags        customers
 // @Security	ApiKeyAuth
-// @Produce     json
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json,application/msgpack
 // @Param       id     query 	string true "Customer guid" Format(uuid)
-// @Success     200    {object} model.Customer
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Success     200    {object} customerV1
+// @Failure     400    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/v1/customers/{id} [get]
-// @Router      /api/v2/customers/{id} [get]
 func (h *CustomerHTTPHandler) Get(c echo.Context) error {
-	id := c.Param("id")
-	if err := c.Validate(&identifier{ID: id}); err != nil {
+	id, err := pathUUID(c, "id")
+	if err != nil {
 		return err
 	}
 
@@ -233,7 +517,7 @@ func (h *CustomerHTTPHandler) Get(c echo.Context) error {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, customer)
+	return respond(c, http.StatusOK, h.view(customer))
 }
 
 // GetAll gets all users
@@ -241,34 +525,60 @@ func (h *CustomerHTTPHandler) Get(c echo.Context) error {
 // @Description Returns all customers
 // @Tags        customers
 // @Security	ApiKeyAuth
-// @Produce     json
-// @Success     200    {array}  model.Customer
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Param       Accept      header string false "application/vnd.customers.envelope+json wraps list into envelope with meta"
+// @Param       activeOnly  query  bool   false "Return only active customers"
+// @Produce     json,application/msgpack
+// @Success     200    {array}  customerV1
+// @Failure     400    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/v1/customers [get]
-// @Router      /api/v2/customers [get]
 func (h *CustomerHTTPHandler) GetAll(c echo.Context) error {
-	customers, err := h.customerSvc.FindAll(c.Request().Context())
+	var q customersQuery
+	if err := bindQuery(c, &q); err != nil {
+		return err
+	}
+
+	findAll := h.customerSvc.FindAll
+	if q.ActiveOnly {
+		findAll = h.customerSvc.FindAllActive
+	}
+
+	customers, err := findAll(c.Request().Context())
 	if err != nil {
 		return err
 	}
-	return c.JSON(http.StatusOK, customers)
+
+	views := h.views(customers)
+	if h.listEnvelope || acceptsListEnvelope(c.Request()) {
+		return respond(c, http.StatusOK, newListEnvelope(views))
+	}
+	return respond(c, http.StatusOK, views)
 }
 
 // Post creates new customer
 // @Summary     New Customer
-// @Description Creates new customer
+// @Description Creates new customer.
+// @Description If createIfNotExists is requested, customer with the same email is returned with 200 instead of error.
 // @Tags        customers
 // @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
 // @Accept		json
 // @Produce     json
-// @Param 		newCustomer body	 newCustomer true "Data for new customer"
-// @Success     200    		{object} model.Customer
-// @Failure     400    		{object} echo.HTTPError
-// @Failure     500    		{object} echo.HTTPError
+// @Param 		newCustomer       body	 newCustomer true  "Data for new customer"
+// @Param 		createIfNotExists query  bool        false "Return existing customer with the same email instead of error"
+// @Success     200    		{object} customerV1
+// @Success     201    		{object} customerV1
+// @Failure     400    		{object} errorEnvelope
+// @Failure     422    		{object} errorEnvelope
+// @Failure     500    		{object} errorEnvelope
 // @Router      /api/v1/customers [post]
-// @Router      /api/v2/customers [post]
 func (h *CustomerHTTPHandler) Post(c echo.Context) error {
+	var createIfNotExists bool
+	if err := echo.QueryParamsBinder(c).Bool("createIfNotExists", &createIfNotExists).BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
 	var nc newCustomer
 	if err := c.Bind(&nc); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -278,19 +588,33 @@ func (h *CustomerHTTPHandler) Post(c echo.Context) error {
 		return err
 	}
 
-	customer, err := h.customerSvc.Create(c.Request().Context(), &model.Customer{
+	customer := &model.Customer{
 		FirstName:  nc.FirstName,
 		LastName:   nc.LastName,
 		MiddleName: nc.MiddleName,
 		Email:      nc.Email,
-		Importance: nc.Importance,
+		Importance: importanceFromHTTP(nc.Importance),
 		Inactive:   nc.Inactive,
-	})
+	}
+
+	if createIfNotExists {
+		result, created, err := h.customerSvc.CreateIfNotExists(c.Request().Context(), customer)
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			return c.JSON(http.StatusOK, h.view(result))
+		}
+		return c.JSON(http.StatusCreated, h.view(result))
+	}
+
+	customer, err := h.customerSvc.Create(c.Request().Context(), customer)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusCreated, customer)
+	return c.JSON(http.StatusCreated, h.view(customer))
 }
 
 // Put updates/creates customer
@@ -298,16 +622,25 @@ func (h *CustomerHTTPHandler) Post(c echo.Context) error {
 // @Description Updates customer or creates new if not exist
 // @Tags        customers
 // @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
 // @Accept		json
 // @Produce     json
 // @Param       id     		   query 	string 		   true "Customer guid" Format(uuid)
+// @Param       includeDiff    query 	bool 		   false "Respond with customerUpdate containing customer and its changed fields"
+// @Param       If-None-Match  header 	string 		   false "Pass * to only create customer, existing customer isn't updated"
 // @Param 		updateCustomer body	    updateCustomer true "Customer data"
-// @Success     200    		   {object} model.Customer
-// @Failure     400    		   {object} echo.HTTPError
-// @Failure     500    		   {object} echo.HTTPError
+// @Success     200    		   {object} customerV1
+// @Failure     400    		   {object} errorEnvelope
+// @Failure     422    		   {object} errorEnvelope
+// @Failure     412    		   {object} errorEnvelope
+// @Failure     500    		   {object} errorEnvelope
 // @Router      /api/v1/customers/{id} [put]
-// @Router      /api/v2/customers/{id} [put]
 func (h *CustomerHTTPHandler) Put(c echo.Context) error {
+	id, err := pathUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
 	var uc updateCustomer
 	if err := c.Bind(&uc); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -317,20 +650,165 @@ func (h *CustomerHTTPHandler) Put(c echo.Context) error {
 		return err
 	}
 
-	customer, err := h.customerSvc.Upsert(c.Request().Context(), &model.Customer{
-		ID:         uc.ID,
+	var includeDiff bool
+	if err := echo.QueryParamsBinder(c).Bool("includeDiff", &includeDiff).BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// If-None-Match: * turns upsert into conditional create
+	if c.Request().Header.Get(headerIfNoneMatch) == "*" {
+		existing, err := h.customerSvc.FindByID(c.Request().Context(), id)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			return echo.NewHTTPError(http.StatusPreconditionFailed, fmt.Sprintf("customer %s already exists", id))
+		}
+	}
+
+	customer, diff, err := h.customerSvc.Upsert(c.Request().Context(), &model.Customer{
+		ID:         id,
+		FirstName:  uc.FirstName,
+		LastName:   uc.LastName,
+		MiddleName: uc.MiddleName,
+		Email:      uc.Email,
+		Importance: importanceFromHTTP(uc.Importance),
+		Inactive:   uc.Inactive,
+	})
+	if err != nil {
+		return err
+	}
+
+	if includeDiff {
+		return c.JSON(http.StatusOK, &customerUpdate{Customer: h.view(customer), Diff: changesToHTTP(diff)})
+	}
+	return c.JSON(http.StatusOK, h.view(customer))
+}
+
+// Patch applies JSON patch to customer
+// @Summary     Patch Customer
+// @Description Applies RFC 6902 JSON patch to existing customer, patched customer is validated as a whole and patch is applied entirely or not at all.
+// @Description Operations add, remove and replace are supported for paths /firstName, /lastName, /middleName, /email, /importance and /inactive.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		application/json-patch+json
+// @Produce     json
+// @Param       id    path 	string 			 true "Customer guid" Format(uuid)
+// @Param 		patch body	    []patchOperation true "JSON patch document"
+// @Success     200   {object} customerV1
+// @Failure     400   {object} errorEnvelope
+// @Failure     404   {object} errorEnvelope
+// @Failure     415   {object} errorEnvelope
+// @Failure     422   {object} errorEnvelope
+// @Failure     500   {object} errorEnvelope
+// @Router      /api/v1/customers/{id} [patch]
+func (h *CustomerHTTPHandler) Patch(c echo.Context) error {
+	id, err := pathUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType)); mediaType != MIMEApplicationJSONPatch {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("patch must be sent as %s", MIMEApplicationJSONPatch))
+	}
+
+	var ops []patchOperation
+	if err := json.NewDecoder(c.Request().Body).Decode(&ops); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if len(ops) == 0 {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "patch must contain at least one operation")
+	}
+
+	existing, err := h.customerSvc.FindByID(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return appErrors.NewEntryNotFoundErr("customer", id)
+	}
+
+	uc := updateCustomer{newCustomer{
+		FirstName:  existing.FirstName,
+		LastName:   existing.LastName,
+		MiddleName: existing.MiddleName,
+		Email:      existing.Email,
+		Importance: importanceToHTTP(existing.Importance),
+		Inactive:   existing.Inactive,
+	}}
+	if err := applyCustomerPatch(&uc, ops); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&uc); err != nil {
+		return err
+	}
+
+	// customer deleted after it was read is reported as not found by update, so patch never brings it back
+	customer, _, err := h.customerSvc.Update(c.Request().Context(), &model.Customer{
+		ID:         id,
 		FirstName:  uc.FirstName,
 		LastName:   uc.LastName,
 		MiddleName: uc.MiddleName,
 		Email:      uc.Email,
-		Importance: uc.Importance,
+		Importance: importanceFromHTTP(uc.Importance),
 		Inactive:   uc.Inactive,
 	})
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, &customer)
+	return c.JSON(http.StatusOK, h.view(customer))
+}
+
+// BulkUpdate updates all customers matching filter
+// @Summary     Bulk update customers
+// @Description Applies partial update to all customers matching filter, admin only
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		json
+// @Produce     json
+// @Param 		bulkUpdate body	    bulkUpdate true "Filter and fields to change"
+// @Success     200    	   {object} bulkUpdateResult
+// @Failure     400    	   {object} errorEnvelope
+// @Failure     422    	   {object} errorEnvelope
+// @Failure     403    	   {object} errorEnvelope
+// @Failure     500    	   {object} errorEnvelope
+// @Router      /api/v1/customers/bulk-update [post]
+// @Router      /api/v2/customers/bulk-update [post]
+func (h *CustomerHTTPHandler) BulkUpdate(c echo.Context) error {
+	var bu bulkUpdate
+	if err := c.Bind(&bu); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(&bu); err != nil {
+		return err
+	}
+
+	if bu.Filter.Importance == nil && bu.Filter.Inactive == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "filter must contain at least one condition")
+	}
+
+	if bu.Update.Importance == nil && bu.Update.Inactive == nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "update must change at least one field")
+	}
+
+	updated, err := h.customerSvc.BulkUpdate(
+		c.Request().Context(),
+		&model.CustomerFilter{Importance: optionalImportanceFromHTTP(bu.Filter.Importance), Inactive: bu.Filter.Inactive},
+		&model.CustomerPatch{Importance: optionalImportanceFromHTTP(bu.Update.Importance), Inactive: bu.Update.Inactive},
+	)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &bulkUpdateResult{Updated: updated})
 }
 
 // DeleteByID deletes customer
@@ -338,16 +816,17 @@ func (h *CustomerHTTPHandler) Put(c echo.Context) error {
 // @Description Deletes customer with provided id
 // @Tags        customers
 // @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
 // @Produce     json
 // @Param       id     query 	string true "Customer guid" Format(uuid)
 // @Success     204    "Successful status code"
-// @Failure     400    {object} echo.HTTPError
-// @Failure     500    {object} echo.HTTPError
+// @Failure     400    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
 // @Router      /api/v1/customers/{id} [delete]
 // @Router      /api/v2/customers/{id} [delete]
 func (h *CustomerHTTPHandler) DeleteByID(c echo.Context) error {
-	id := c.Param("id")
-	if err := c.Validate(&identifier{ID: id}); err != nil {
+	id, err := pathUUID(c, "id")
+	if err != nil {
 		return err
 	}
 
@@ -358,39 +837,173 @@ func (h *CustomerHTTPHandler) DeleteByID(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// views maps customers to representation of api version, nil list is kept nil
+func (h *CustomerHTTPHandler) views(customers []*model.Customer) []any {
+	if customers == nil {
+		return nil
+	}
+
+	views := make([]any, len(customers))
+	for i, c := range customers {
+		views[i] = h.view(c)
+	}
+	return views
+}
+
+// CustomerHTTPHandlerV2 is http handler for customer endpoint of v2 api, it differs from v1 by customer representation,
+// handlers responding customers are redeclared to be documented with v2 representation
+type CustomerHTTPHandlerV2 struct {
+	*CustomerHTTPHandler
+}
+
+// NewCustomerHTTPHandlerV2 builds new CustomerHTTPHandlerV2, lists are always wrapped into envelope if listEnvelope is set
+func NewCustomerHTTPHandlerV2(customerSvc service.CustomerService, listEnvelope bool) *CustomerHTTPHandlerV2 {
+	return &CustomerHTTPHandlerV2{
+		CustomerHTTPHandler: &CustomerHTTPHandler{customerSvc: customerSvc, listEnvelope: listEnvelope, view: customerV2View},
+	}
+}
+
+// Get gets user
+// @Summary     Get single customer by id
+// @Description Returns single customer with provided id
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json,application/msgpack
+// @Param       id     query 	string true "Customer guid" Format(uuid)
+// @Success     200    {object} customerV2
+// @Failure     400    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
+// @Router      /api/v2/customers/{id} [get]
+func (h *CustomerHTTPHandlerV2) Get(c echo.Context) error {
+	return h.CustomerHTTPHandler.Get(c)
+}
+
+// GetAll gets all users
+// @Summary     Get all customers
+// @Description Returns all customers
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Param       Accept      header string false "application/vnd.customers.envelope+json wraps list into envelope with meta"
+// @Param       activeOnly  query  bool   false "Return only active customers"
+// @Produce     json,application/msgpack
+// @Success     200    {array}  customerV2
+// @Failure     400    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
+// @Router      /api/v2/customers [get]
+func (h *CustomerHTTPHandlerV2) GetAll(c echo.Context) error {
+	return h.CustomerHTTPHandler.GetAll(c)
+}
+
+// Post creates new customer
+// @Summary     New Customer
+// @Description Creates new customer.
+// @Description If createIfNotExists is requested, customer with the same email is returned with 200 instead of error.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		json
+// @Produce     json
+// @Param 		newCustomer       body	 newCustomer true  "Data for new customer"
+// @Param 		createIfNotExists query  bool        false "Return existing customer with the same email instead of error"
+// @Success     200    		{object} customerV2
+// @Success     201    		{object} customerV2
+// @Failure     400    		{object} errorEnvelope
+// @Failure     422    		{object} errorEnvelope
+// @Failure     500    		{object} errorEnvelope
+// @Router      /api/v2/customers [post]
+func (h *CustomerHTTPHandlerV2) Post(c echo.Context) error {
+	return h.CustomerHTTPHandler.Post(c)
+}
+
+// Put updates/creates customer
+// @Summary     Update/Create Customer
+// @Description Updates customer or creates new if not exist
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		json
+// @Produce     json
+// @Param       id     		   query 	string 		   true "Customer guid" Format(uuid)
+// @Param       includeDiff    query 	bool 		   false "Respond with customerUpdate containing customer and its changed fields"
+// @Param       If-None-Match  header 	string 		   false "Pass * to only create customer, existing customer isn't updated"
+// @Param 		updateCustomer body	    updateCustomer true "Customer data"
+// @Success     200    		   {object} customerV2
+// @Failure     400    		   {object} errorEnvelope
+// @Failure     422    		   {object} errorEnvelope
+// @Failure     412    		   {object} errorEnvelope
+// @Failure     500    		   {object} errorEnvelope
+// @Router      /api/v2/customers/{id} [put]
+func (h *CustomerHTTPHandlerV2) Put(c echo.Context) error {
+	return h.CustomerHTTPHandler.Put(c)
+}
+
+// Patch applies JSON patch to customer
+// @Summary     Patch Customer
+// @Description Applies RFC 6902 JSON patch to existing customer, patched customer is validated as a whole and patch is applied entirely or not at all.
+// @Description Operations add, remove and replace are supported for paths /firstName, /lastName, /middleName, /email, /importance and /inactive.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		application/json-patch+json
+// @Produce     json
+// @Param       id    path 	string 			 true "Customer guid" Format(uuid)
+// @Param 		patch body	    []patchOperation true "JSON patch document"
+// @Success     200   {object} customerV2
+// @Failure     400   {object} errorEnvelope
+// @Failure     404   {object} errorEnvelope
+// @Failure     415   {object} errorEnvelope
+// @Failure     422   {object} errorEnvelope
+// @Failure     500   {object} errorEnvelope
+// @Router      /api/v2/customers/{id} [patch]
+func (h *CustomerHTTPHandlerV2) Patch(c echo.Context) error {
+	return h.CustomerHTTPHandler.Patch(c)
+}
+
 // ImageHTTPHandler is http handler for image endpoint
 type ImageHTTPHandler struct {
-	validImgMimeTypes map[string]struct{}
+	*imageUploader
+	urlSigner *auth.URLSigner
 }
 
-// NewImageHTTPHandler builds new ImageHTTPHandler
-func NewImageHTTPHandler() *ImageHTTPHandler {
+// NewImageHTTPHandler builds new ImageHTTPHandler, signed URLs are available only if signing key is configured
+func NewImageHTTPHandler(imageStorage storage.ImageStorage, imageMetaStore storage.ImageMetadataStore, cfg *config.ImagesCfg) *ImageHTTPHandler {
+	var urlSigner *auth.URLSigner
+	if cfg.SignedURLKey != "" {
+		urlSigner = auth.NewURLSigner([]byte(cfg.SignedURLKey), cfg.SignedURLClockSkew)
+	}
+
 	return &ImageHTTPHandler{
-		validImgMimeTypes: map[string]struct{}{
-			"image/gif":                {},
-			"image/jpeg":               {},
-			"image/pjpeg":              {},
-			"image/png":                {},
-			"image/svg+xml":            {},
-			"image/tiff":               {},
-			"image/vnd.microsoft.icon": {},
-			"image/vnd.wap.wbmp":       {},
-			"image/webp":               {},
-		},
+		imageUploader: newImageUploader(imageStorage, imageMetaStore, cfg),
+		urlSigner:     urlSigner,
 	}
 }
 
 // Upload uploads image
 // @Summary     Upload image
-// @Description Uploads image to the server
+// @Description Uploads image to the server, existing image is replaced only if overwrite is requested.
+// @Description Image with identical content is stored once and shared, image is available under its own name anyway.
+// @Description EXIF and other metadata is removed from jpeg images if stripping is enabled.
+// @Description File extension must be allowed and match detected MIME type, e.g. png content must be named .png.
 // @Tags        images
+// @Security	ApiKeyAuth
 // @Accept		mpfd
-// @Param 		image formData file true "Image"
-// @Success     200   "Successful status code"
-// @Failure     400   {object} echo.HTTPError
-// @Failure     500   {object} echo.HTTPError
+// @Produce     json
+// @Param 		image     formData file true  "Image"
+// @Param 		overwrite query    bool false "Replace existing image with the same name"
+// @Success     200   {object} uploadedImage
+// @Failure     400   {object} errorEnvelope
+// @Failure     401   {object} errorEnvelope
+// @Failure     409   {object} errorEnvelope
+// @Failure     500   {object} errorEnvelope
 // @Router      /images/upload [post]
-func (h *ImageHTTPHandler) Upload(c echo.Context) (err error) {
+func (h *ImageHTTPHandler) Upload(c echo.Context) error {
+	var overwrite bool
+	if err := echo.QueryParamsBinder(c).Bool("overwrite", &overwrite).BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
 	fileHdr, err := c.FormFile("image")
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -400,48 +1013,81 @@ func (h *ImageHTTPHandler) Upload(c echo.Context) (err error) {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to load file content - %v", err))
 	}
+	defer file.Close()
 
 	mimeBuff := make([]byte, mimeBytesNumber)
-	_, err = file.Read(mimeBuff)
-	if err != nil {
+	n, err := file.Read(mimeBuff)
+	if err != nil && !errors.Is(err, io.EOF) {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	mimeType := http.DetectContentType(mimeBuff)
+	mimeType := http.DetectContentType(mimeBuff[:n])
 	if !h.isMimeTypeAllowed(mimeType) {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("MIME type %s is not allowed", mimeType))
 	}
 
-	_, err = file.Seek(0, io.SeekStart)
+	if err := h.checkExtension(fileHdr.Filename, mimeType); err != nil {
+		return err
+	}
+
+	info, err := h.store(c.Request().Context(), fileHdr.Filename, file, fileHdr.Size, mimeType, overwrite)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	path := fmt.Sprintf("./images/%s", fileHdr.Filename)
-	dst, err := os.Create(filepath.Clean(path))
+	return c.JSON(http.StatusOK, newUploadedImage(info.Name))
+}
+
+// List lists uploaded images
+// @Summary     List images
+// @Description Returns page of uploaded images metadata ordered by name
+// @Tags        images
+// @Security	ApiKeyAuth
+// @Produce     json
+// @Param 		limit  query    int    false "Page size" minimum(1) maximum(100) default(20)
+// @Param 		cursor query    string false "Cursor returned with previous page"
+// @Success     200    {object} imagesPage
+// @Failure     400    {object} errorEnvelope
+// @Failure     401    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
+// @Router      /images [get]
+func (h *ImageHTTPHandler) List(c echo.Context) error {
+	limit := defaultImagesPageLimit
+	var cursor string
+
+	err := echo.QueryParamsBinder(c).
+		Int("limit", &limit).
+		String("cursor", &cursor).
+		BindError()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	if _, err := io.Copy(dst, file); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	if limit < 1 || limit > maxImagesPageLimit {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxImagesPageLimit))
 	}
 
-	if err := file.Close(); err != nil {
+	// request one extra entry to find out if next page exists
+	infos, err := h.imageMetaStore.List(c.Request().Context(), cursor, limit+1)
+	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
-	if err := dst.Close(); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	page := imagesPage{Images: infos}
+	if len(infos) > limit {
+		page.Images = infos[:limit]
+		page.NextCursor = infos[limit-1].Name
 	}
 
-	return c.NoContent(http.StatusOK)
+	return c.JSON(http.StatusOK, page)
 }
 
 // Download downloads image
 // @Summary     Download image
-// @Description Downloads image from the server
+// @Description Downloads image from the server, supports range and conditional requests. Authorization is not required if public downloads are enabled.
+// @Description ETag is content hash of the image, response is cached as immutable if requested version matches it.
 // @Tags        images
+// @Security	ApiKeyAuth
 // @Produce		image/gif
 // @Produce		image/jpeg
 // @Produce		image/pjpeg
@@ -451,20 +1097,425 @@ func (h *ImageHTTPHandler) Upload(c echo.Context) (err error) {
 // @Produce		image/vnd.microsoft.icon
 // @Produce		image/vnd.wap.wbmp
 // @Produce		image/webp
-// @Param 		name  query    string true "Image name"
-// @Success     200   {string} file
-// @Failure     400   {object} echo.HTTPError
-// @Failure     500   {object} echo.HTTPError
+// @Param 		name   path     string true  "Image name"
+// @Param 		inline query    bool   false "Display image inline instead of downloading it as attachment"
+// @Param 		v      query    string false "Content hash of expected image version"
+// @Success     200    {string} file
+// @Success     206    {string} file
+// @Success     304    "Not modified"
+// @Failure     400    {object} errorEnvelope
+// @Failure     401    {object} errorEnvelope
+// @Failure     404    {object} errorEnvelope
+// @Failure     416    "Requested range not satisfiable"
+// @Failure     500    {object} errorEnvelope
 // @Router      /images/{name}/download [get]
 func (h *ImageHTTPHandler) Download(c echo.Context) error {
 	name := c.Param("name")
-	path := fmt.Sprintf("./images/%s", name)
-	return c.Attachment(path, name)
+
+	var inline bool
+	var version string
+
+	err := echo.QueryParamsBinder(c).
+		Bool("inline", &inline).
+		String("v", &version).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	f, info, err := h.open(c.Request().Context(), name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	hdr := c.Response().Header()
+
+	// etag is derived from file stat unless content hash is tracked in metadata
+	etag := fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size())
+	immutable := false
+
+	// content type is sniffed while serving if metadata is not tracked for the image
+	if info != nil {
+		hdr.Set(echo.HeaderContentType, info.ContentType)
+		if info.Hash != "" {
+			etag = fmt.Sprintf("%q", info.Hash)
+			immutable = version == info.Hash // content of requested version never changes
+		}
+	}
+
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+
+	hdr.Set(echo.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, name))
+	hdr.Set("ETag", etag)
+	hdr.Set("Cache-Control", h.cacheControl(immutable))
+
+	// ServeContent sets Last-Modified and handles Range, If-Range, If-None-Match and If-Modified-Since headers
+	http.ServeContent(c.Response(), c.Request(), name, stat.ModTime(), f)
+	return nil
+}
+
+// SignedURL signs download URL of image
+// @Summary     Sign image download URL
+// @Description Returns time-limited URL image can be downloaded with without authorization, URL can be bound to client IP
+// @Tags        images
+// @Security	ApiKeyAuth
+// @Produce     json
+// @Param 		name   path     string true  "Image name"
+// @Param 		bindIp query    bool   false "Allow download only from IP URL is requested from"
+// @Success     200    {object} signedURL
+// @Failure     400    {object} errorEnvelope
+// @Failure     401    {object} errorEnvelope
+// @Failure     404    {object} errorEnvelope
+// @Failure     500    {object} errorEnvelope
+// @Router      /images/{name}/signed-url [post]
+func (h *ImageHTTPHandler) SignedURL(c echo.Context) error {
+	if h.urlSigner == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "signed URLs are disabled")
+	}
+
+	name := c.Param("name")
+
+	var bindIP bool
+	if err := echo.QueryParamsBinder(c).Bool("bindIp", &bindIP).BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	f, _, err := h.open(c.Request().Context(), name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var clientIP string
+	if bindIP {
+		clientIP = c.RealIP()
+	}
+
+	expiresAt := time.Now().Add(h.cfg.SignedURLTimeToLive).Truncate(time.Second)
+
+	q := make(url.Values)
+	q.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	q.Set("signature", h.urlSigner.Sign(name, expiresAt, clientIP))
+	if bindIP {
+		q.Set("bindIp", "true")
+	}
+
+	return c.JSON(http.StatusOK, &signedURL{
+		URL:       fmt.Sprintf("/images/%s/signed-download?%s", url.PathEscape(name), q.Encode()),
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
+
+// SignedDownload downloads image by signed URL
+// @Summary     Download image by signed URL
+// @Description Verifies signature and expiration of URL and downloads image without authorization
+// @Tags        images
+// @Produce		image/gif
+// @Produce		image/jpeg
+// @Produce		image/png
+// @Produce		image/webp
+// @Param 		name      path     string true  "Image name"
+// @Param 		expires   query    int    true  "Unix time URL expires at"
+// @Param 		signature query    string true  "URL signature"
+// @Param 		bindIp    query    bool   false "URL is bound to client IP"
+// @Param 		inline    query    bool   false "Display image inline instead of downloading it as attachment"
+// @Success     200       {string} file
+// @Success     206       {string} file
+// @Success     304       "Not modified"
+// @Failure     403       {object} errorEnvelope
+// @Failure     404       {object} errorEnvelope
+// @Failure     500       {object} errorEnvelope
+// @Router      /images/{name}/signed-download [get]
+func (h *ImageHTTPHandler) SignedDownload(c echo.Context) error {
+	if h.urlSigner == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "signed URLs are disabled")
+	}
+
+	var expires int64
+	var signature string
+	var bindIP bool
+
+	err := echo.QueryParamsBinder(c).
+		Int64("expires", &expires).
+		String("signature", &signature).
+		Bool("bindIp", &bindIP).
+		BindError()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "signed URL is malformed")
+	}
+
+	var clientIP string
+	if bindIP {
+		clientIP = c.RealIP()
+	}
+
+	if err := h.urlSigner.Verify(c.Param("name"), time.Unix(expires, 0), clientIP, signature, time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	return h.Download(c)
+}
+
+// Delete deletes image
+// @Summary     Delete image
+// @Description Deletes image, content shared by images with identical content is removed from the server with the last of them
+// @Tags        images
+// @Security	ApiKeyAuth
+// @Param 		name  query    string true "Image name"
+// @Success     204   "Successful status code"
+// @Failure     400   {object} errorEnvelope
+// @Failure     401   {object} errorEnvelope
+// @Failure     404   {object} errorEnvelope
+// @Failure     500   {object} errorEnvelope
+// @Router      /images/{name} [delete]
+func (h *ImageHTTPHandler) Delete(c echo.Context) error {
+	// image content is shared by all uploads of identical image, so it is removed with the last of them only
+	if err := h.release(c.Request().Context(), c.Param("name")); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func newUploadedImage(name string) *uploadedImage {
+	return &uploadedImage{Name: name, URL: fmt.Sprintf("/images/%s/download", url.PathEscape(name))}
+}
+
+// cacheControl builds Cache-Control header, images are cached by browser only unless downloads are public
+func (h *ImageHTTPHandler) cacheControl(immutable bool) string {
+	visibility := "private"
+	if h.cfg.PublicDownloads {
+		visibility = "public"
+	}
+
+	if immutable {
+		return fmt.Sprintf("%s, max-age=%d, immutable", visibility, int(immutableImageMaxAge.Seconds()))
+	}
+
+	if h.cfg.CacheMaxAge <= 0 { // cached image must be revalidated on every use
+		return fmt.Sprintf("%s, no-cache", visibility)
+	}
+	return fmt.Sprintf("%s, max-age=%d", visibility, int(h.cfg.CacheMaxAge.Seconds()))
+}
+
+// newListEnvelope wraps whole (not paginated) list into envelope, so it is always the first page
+func newListEnvelope[T any](items []T) *listEnvelope[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return &listEnvelope[T]{Data: items, Meta: listMeta{Total: len(items), Page: 1}}
+}
+
+func acceptsListEnvelope(r *http.Request) bool {
+	return acceptsMediaType(r, MIMEApplicationListEnvelopeJSON)
+}
+
+// respond encodes response with msgpack if client prefers it to json, json is used by default
+func respond(c echo.Context, code int, i interface{}) error {
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if !prefersMsgpack(c.Request()) {
+		return c.JSON(code, i)
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json") // same field names as in json responses
+	if err := enc.Encode(i); err != nil {
+		return fmt.Errorf("failed to encode msgpack response - %w", err)
+	}
+	return c.Blob(code, MIMEApplicationMsgpack, buf.Bytes())
+}
+
+// prefersMsgpack reports if msgpack is accepted with higher quality than json, list envelope is json flavor.
+// The one listed first wins on equal quality
+func prefersMsgpack(r *http.Request) bool {
+	offers := map[string]string{
+		echo.MIMEApplicationJSON:        echo.MIMEApplicationJSON,
+		MIMEApplicationListEnvelopeJSON: echo.MIMEApplicationJSON,
+		MIMEApplicationMsgpack:          MIMEApplicationMsgpack,
+	}
+	return preferredMediaType(r, offers) == MIMEApplicationMsgpack
+}
+
+// pathUUID returns path parameter validated to be uuid, violation is reported under parameter name
+func pathUUID(c echo.Context, name string) (string, error) {
+	value := c.Param(name)
+	if err := c.Validate(pathParam(name, value, "required,uuid")); err != nil {
+		return "", inURL(err)
+	}
+	return value, nil
 }
 
-func (h *ImageHTTPHandler) isMimeTypeAllowed(mime string) bool {
-	if _, ok := h.validImgMimeTypes[mime]; ok {
-		return true
+// bindQuery binds query parameters to struct q by query tags and validates it, so handlers declare typed query
+// instead of parsing parameters one by one. Malformed value and value failing rules are both rejected as bad request
+func bindQuery(c echo.Context, q any) error {
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, q); err != nil {
+		return err
+	}
+	return inURL(c.Validate(q))
+}
+
+// inURL marks payload error as violations of path or query parameters, they are rejected as bad request
+// unlike body failing validation
+func inURL(err error) error {
+	var pldErr *validation.PayloadError
+	if errors.As(err, &pldErr) {
+		pldErr.InURL()
+	}
+	return err
+}
+
+// pathParam builds struct with single field named after path parameter, so validator can check it with rules
+// as any payload field, struct type is the same for the same name and rules, so it is cached by validator
+func pathParam(name, value, rules string) any {
+	param := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf(value),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:%q validate:%q`, name, rules)),
+	}}))
+	param.Elem().Field(0).SetString(value)
+	return param.Interface()
+}
+
+// HeadHandler serves HEAD request with GET handler, status and headers are kept, body is discarded
+func HeadHandler(h echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Writer = &headResponseWriter{ResponseWriter: c.Response().Writer}
+		return h(c)
+	}
+}
+
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// AdminHTTPHandler is http handler for admin endpoints
+type AdminHTTPHandler struct {
+	runtimeCfg *config.RuntimeHolder
+	flags      *feature.Flags
+	sessionSvc service.SessionService
+	cacheSvc   service.CacheService
+}
+
+// NewAdminHTTPHandler builds new AdminHTTPHandler
+func NewAdminHTTPHandler(
+	runtimeCfg *config.RuntimeHolder,
+	flags *feature.Flags,
+	sessionSvc service.SessionService,
+	cacheSvc service.CacheService,
+) *AdminHTTPHandler {
+	return &AdminHTTPHandler{
+		runtimeCfg: runtimeCfg,
+		flags:      flags,
+		sessionSvc: sessionSvc,
+		cacheSvc:   cacheSvc,
+	}
+}
+
+// Reload re-reads config which is safe to change at runtime
+// @Summary     Reload runtime config
+// @Description Re-reads runtime config (cache TTL and its overrides per importance) and feature flags and applies them without restart
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Produce     json
+// @Success     200 {object} runtimeConfig
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/reload [post]
+func (h *AdminHTTPHandler) Reload(c echo.Context) error {
+	cfg, err := h.runtimeCfg.Reload()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.flags.Reload(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, &runtimeConfig{
+		CustomerCacheTimeToLive:             cfg.CustomerCacheTimeToLive.String(),
+		CustomerCacheTimeToLiveByImportance: cfg.CustomerCacheTimeToLiveByImportance.ByName(),
+	})
+}
+
+// PurgeCache removes all cached customers
+// @Summary     Purge customers cache
+// @Description Removes all customers from redis and in-memory caches, so they are read from datastore on the next request.
+// @Description Purge is broadcast over customers stream, so in-memory caches of other instances are purged shortly after.
+// @Description Returns number of removed redis entries and in-memory entries of instance serving request.
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Produce     json
+// @Success     200 {object} purgedCache
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/cache/purge [post]
+func (h *AdminHTTPHandler) PurgeCache(c echo.Context) error {
+	removed, err := h.cacheSvc.Purge(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, &purgedCache{Removed: removed})
+}
+
+// Sessions lists sessions of all users
+// @Summary     List sessions
+// @Description Returns page of sessions (refresh tokens) ordered from the newest, sessions can be filtered by user and expiration.
+// @Description Refresh token itself is never returned.
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Produce     json
+// @Param 		userId     query    string false "User id" format(uuid)
+// @Param 		activeOnly query    bool   false "Return only not expired sessions"
+// @Param 		limit      query    int    false "Page size" minimum(1) maximum(100) default(20)
+// @Param 		offset     query    int    false "Number of sessions to skip" minimum(0) default(0)
+// @Success     200        {object} sessionsPage
+// @Failure     400        {object} errorEnvelope
+// @Failure     401        {object} errorEnvelope
+// @Failure     403        {object} errorEnvelope
+// @Failure     500        {object} errorEnvelope
+// @Router      /api/admin/sessions [get]
+func (h *AdminHTTPHandler) Sessions(c echo.Context) error {
+	q := sessionsQuery{Limit: defaultSessionsPageLimit}
+	if err := bindQuery(c, &q); err != nil {
+		return err
 	}
-	return false
+
+	now := time.Now().UTC()
+	filter := &model.RefreshTokenFilter{UserID: q.UserID, Limit: q.Limit, Offset: q.Offset}
+	if q.ActiveOnly {
+		filter.ActiveAt = now
+	}
+
+	tokens, total, err := h.sessionSvc.FindAll(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	page := sessionsPage{Sessions: make([]*sessionInfo, len(tokens)), Total: total}
+	for i, tkn := range tokens {
+		page.Sessions[i] = &sessionInfo{
+			UserID:      tkn.UserID,
+			Fingerprint: tkn.Fingerprint,
+			CreatedAt:   tkn.CreatedAt,
+			ExpiresAt:   tkn.ExpiresAt(),
+			Active:      tkn.ExpiresAt().After(now),
+		}
+	}
+
+	return c.JSON(http.StatusOK, page)
 }
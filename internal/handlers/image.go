@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/storage"
+	"github.com/umalmyha/customers/pkg/exif"
+)
+
+// imageUploader stores uploaded images, it is shared by HTTP and gRPC image handlers
+type imageUploader struct {
+	imageStorage      storage.ImageStorage
+	imageMetaStore    storage.ImageMetadataStore
+	validImgMimeTypes map[string]struct{}
+	allowedExtensions map[string]struct{}
+	cfg               *config.ImagesCfg
+}
+
+// imageExtensions are file extensions matching detected MIME type of image content
+var imageExtensions = map[string][]string{
+	"image/gif":                {".gif"},
+	"image/jpeg":               {".jpg", ".jpeg", ".jpe"},
+	"image/pjpeg":              {".jpg", ".jpeg", ".jpe"},
+	"image/png":                {".png"},
+	"image/svg+xml":            {".svg"},
+	"image/tiff":               {".tif", ".tiff"},
+	"image/vnd.microsoft.icon": {".ico"},
+	"image/vnd.wap.wbmp":       {".wbmp"},
+	"image/webp":               {".webp"},
+}
+
+func newImageUploader(imageStorage storage.ImageStorage, imageMetaStore storage.ImageMetadataStore, cfg *config.ImagesCfg) *imageUploader {
+	allowedExtensions := make(map[string]struct{}, len(cfg.AllowedExtensions))
+	for _, ext := range cfg.AllowedExtensions {
+		allowedExtensions[strings.ToLower(ext)] = struct{}{}
+	}
+
+	return &imageUploader{
+		imageStorage:      imageStorage,
+		imageMetaStore:    imageMetaStore,
+		allowedExtensions: allowedExtensions,
+		cfg:               cfg,
+		validImgMimeTypes: map[string]struct{}{
+			"image/gif":                {},
+			"image/jpeg":               {},
+			"image/pjpeg":              {},
+			"image/png":                {},
+			"image/svg+xml":            {},
+			"image/tiff":               {},
+			"image/vnd.microsoft.icon": {},
+			"image/vnd.wap.wbmp":       {},
+			"image/webp":               {},
+		},
+	}
+}
+
+// store saves image content of already checked MIME type, image with identical content is stored once
+// and shared by all images uploaded with it, so each of them can be downloaded and deleted by its own name
+func (h *imageUploader) store(ctx context.Context, name string, file io.ReadSeeker, size int64, mimeType string, overwrite bool) (*storage.ImageInfo, error) {
+	content := file
+	var stripped bool
+	if h.cfg.StripMetadata && isJPEG(mimeType) {
+		var err error
+		content, size, stripped, err = stripJPEGMetadata(file)
+		if err != nil {
+			if errors.Is(err, exif.ErrInvalidJPEG) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	hash, err := contentHash(content)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	var reserved bool
+	existing, err := h.imageMetaStore.Get(ctx, name)
+	switch {
+	case err == nil:
+		if existing.Hash == hash {
+			return existing, nil // the same image is uploaded again
+		}
+
+		if !overwrite {
+			return nil, h.storageError(storage.ErrImageExists, name)
+		}
+
+		if existing, err = h.replace(ctx, existing); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, storage.ErrImageNameReserved):
+		reserved = true // name of deleted image is taken back only by upload of content stored under it
+	case errors.Is(err, storage.ErrImageNotFound):
+	default:
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	info := &storage.ImageInfo{
+		Name:             name,
+		Size:             size,
+		ContentType:      mimeType,
+		UploadedAt:       time.Now().UTC(),
+		Hash:             hash,
+		MetadataStripped: stripped,
+	}
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		info.Uploader = claims.Subject
+	}
+
+	// identical content is stored once, so upload just shares content of existing image
+	shared, err := h.imageMetaStore.Share(ctx, hash, info)
+	if err == nil {
+		if existing != nil { // overwritten content isn't referenced by the image anymore
+			if err := h.imageStorage.Delete(ctx, name); err != nil && !errors.Is(err, storage.ErrImageNotFound) {
+				return nil, h.storageError(err, name)
+			}
+		}
+		return shared, nil
+	}
+	if !errors.Is(err, storage.ErrImageNotFound) {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if reserved {
+		return nil, h.storageError(storage.ErrImageNameReserved, name)
+	}
+
+	if err := h.imageStorage.Save(ctx, name, content, overwrite); err != nil {
+		return nil, h.storageError(err, name)
+	}
+
+	if err := h.imageMetaStore.Put(ctx, info); err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	info.Blob = name
+	info.RefCount = 1
+	return info, nil
+}
+
+// replace prepares image to be overwritten with other content. Image content is overwritten in place,
+// so image sharing content of another one is released, while content shared by other images can't be overwritten.
+// Image which content is left to be overwritten is returned.
+func (h *imageUploader) replace(ctx context.Context, existing *storage.ImageInfo) (*storage.ImageInfo, error) {
+	if existing.ContentName() != existing.Name {
+		return nil, h.release(ctx, existing.Name)
+	}
+
+	if existing.RefCount > 1 {
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("image %s is shared by other images and can't be overwritten", existing.Name))
+	}
+	return existing, nil
+}
+
+// release deletes image, its content is removed along with the last image sharing it
+func (h *imageUploader) release(ctx context.Context, name string) error {
+	blob, refs, err := h.imageMetaStore.Release(ctx, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrImageNameReserved) {
+			return h.storageError(storage.ErrImageNotFound, name)
+		}
+		return h.storageError(err, name)
+	}
+
+	if refs == 0 {
+		if err := h.imageStorage.Delete(ctx, blob); err != nil && !errors.Is(err, storage.ErrImageNotFound) {
+			return h.storageError(err, name)
+		}
+	}
+	return nil
+}
+
+// open opens content of image, content of image without metadata is looked up by image name
+func (h *imageUploader) open(ctx context.Context, name string) (storage.ImageFile, *storage.ImageInfo, error) {
+	info, err := h.imageMetaStore.Get(ctx, name)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrImageNameReserved):
+		return nil, nil, h.storageError(storage.ErrImageNotFound, name) // image is deleted, content is kept for others
+	case errors.Is(err, storage.ErrImageNotFound):
+		info = nil // metadata is not tracked for the image
+	default:
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	blob := name
+	if info != nil {
+		blob = info.ContentName()
+	}
+
+	f, err := h.imageStorage.Open(ctx, blob)
+	if err != nil {
+		return nil, nil, h.storageError(err, name)
+	}
+	return f, info, nil
+}
+
+func (h *imageUploader) isMimeTypeAllowed(mimeType string) bool {
+	if _, ok := h.validImgMimeTypes[mimeType]; ok {
+		return true
+	}
+	return false
+}
+
+// checkExtension verifies that file extension is allowed and matches detected MIME type,
+// so content sniffing can't be fooled by e.g. script named as image, empty allowlist permits any matching extension
+func (h *imageUploader) checkExtension(name string, mimeType string) error {
+	ext := strings.ToLower(filepath.Ext(name))
+
+	if len(h.allowedExtensions) > 0 {
+		if _, ok := h.allowedExtensions[ext]; !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("file extension %q is not allowed", ext))
+		}
+	}
+
+	for _, expected := range imageExtensions[mimeType] {
+		if ext == expected {
+			return nil
+		}
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("file extension %q doesn't match MIME type %s", ext, mimeType))
+}
+
+func (h *imageUploader) storageError(err error, name string) error {
+	switch {
+	case errors.Is(err, storage.ErrImageNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("image %s not found", name))
+	case errors.Is(err, storage.ErrImageExists):
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("image %s already exists", name))
+	case errors.Is(err, storage.ErrImageNameReserved):
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("image name %s is reserved by images sharing its content", name))
+	case errors.Is(err, storage.ErrInvalidImageName):
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("image name %s is invalid", name))
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// contentHash calculates hex encoded SHA-256 of the whole content and rewinds it to the beginning
+func contentHash(content io.ReadSeeker) (string, error) {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// stripJPEGMetadata removes EXIF and other metadata from jpeg, so it is never stored
+func stripJPEGMetadata(file io.ReadSeeker) (io.ReadSeeker, int64, bool, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, false, err
+	}
+
+	original, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	content, stripped, err := exif.StripJPEG(original)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return bytes.NewReader(content), int64(len(content)), stripped, nil
+}
+
+func isJPEG(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/pjpeg"
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/validation"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// csvImportColumns are columns of customers import file named as fields of customer json, id is optional
+var csvImportColumns = []string{"id", "firstName", "lastName", "middleName", "email", "importance", "inactive"}
+
+var csvImportRequiredColumns = []string{"firstName", "lastName", "email", "importance"}
+
+// importedCustomer is customer read from import file, customer with the same email is updated if id is empty
+type importedCustomer struct {
+	ID string `json:"id" validate:"omitempty,uuid"`
+	newCustomer
+}
+
+// importReport summarizes import, error is set if import is stopped by it, rows read before are reported anyway
+type importReport struct {
+	Imported int            `json:"imported"`
+	Rejected []*rejectedRow `json:"rejected"`
+	Error    string         `json:"error,omitempty"`
+}
+
+type rejectedRow struct {
+	Line    int                    `json:"line"`
+	Message string                 `json:"message"`
+	Details []validation.Violation `json:"details"`
+}
+
+// limitedBody reads body up to limit, err is returned once body exceeds it
+type limitedBody struct {
+	r     io.Reader
+	read  int64
+	limit int64
+	err   error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n, b.err
+	}
+	return n, err
+}
+
+// CustomerImportHTTPHandler is http handler for customers import of v1 api
+type CustomerImportHTTPHandler struct {
+	importSvc service.CustomerImportService
+	cfg       *config.ImportCfg
+}
+
+// NewCustomerImportHTTPHandler builds new CustomerImportHTTPHandler
+func NewCustomerImportHTTPHandler(importSvc service.CustomerImportService, cfg *config.ImportCfg) *CustomerImportHTTPHandler {
+	return &CustomerImportHTTPHandler{importSvc: importSvc, cfg: cfg}
+}
+
+// ImportCSV imports customers from csv
+// @Summary     Import customers from CSV
+// @Description Upserts customers from CSV file with header row. Columns are named as customer fields, firstName, lastName, email
+// @Description and importance are required, id is optional. Customer is matched by id if it is set, otherwise by email.
+// @Description Rejected rows are reported with line they start at, they don't stop import unless it is strict.
+// @Description Strict import imports nothing if any row is invalid or violates business rules.
+// @Description Import is stopped once file exceeds max size or number of rows, rows read before are reported along with error.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept      text/csv
+// @Produce     json
+// @Param 		strict query    bool false "Import nothing if any row is invalid"
+// @Success     200    {object} importReport
+// @Failure     400    {object} importReport "malformed csv, errorEnvelope is returned for malformed header"
+// @Failure     401    {object} errorEnvelope
+// @Failure     413    {object} importReport "file exceeds max size or number of rows"
+// @Failure     422    {object} importReport "strict import rejected, nothing is imported"
+// @Failure     500    {object} importReport
+// @Router      /api/v1/customers/import.csv [post]
+func (h *CustomerImportHTTPHandler) ImportCSV(c echo.Context) error {
+	var strict bool
+	if err := echo.QueryParamsBinder(c).Bool("strict", &strict).BindError(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	tooLarge := echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("csv must not exceed %d bytes", h.cfg.MaxBodySize))
+	body := &limitedBody{r: io.LimitReader(c.Request().Body, h.cfg.MaxBodySize+1), limit: h.cfg.MaxBodySize, err: tooLarge}
+
+	r := csv.NewReader(body) // rows are read one by one, so file is never loaded into memory as a whole
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return echo.NewHTTPError(http.StatusBadRequest, "csv is empty, header row is expected")
+		}
+		return csvReadError(err)
+	}
+
+	columns, err := csvColumns(header)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	rows := 0
+	next := func() (*service.CustomerImportRow, error) {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, csvReadError(err)
+		}
+
+		if rows++; rows > h.cfg.MaxRows {
+			return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("csv must not exceed %d rows", h.cfg.MaxRows))
+		}
+
+		line, _ := r.FieldPos(0)
+		if err != nil {
+			return &service.CustomerImportRow{Line: line, Err: fmt.Errorf("row has %d fields, but header has %d", len(record), len(header))}, nil
+		}
+
+		customer, err := csvCustomer(c, columns, record)
+		return &service.CustomerImportRow{Line: line, Customer: customer, Err: err}, nil
+	}
+
+	report, err := h.importSvc.Import(c.Request().Context(), next, strict)
+	if err != nil {
+		return importStopped(c, report, err)
+	}
+
+	status := http.StatusOK
+	if strict && len(report.Rejected) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	return c.JSON(status, newImportReport(report))
+}
+
+// importStopped responds with report of rows read before import is stopped by error,
+// server error is logged and its message is replaced with status text, so internals are never exposed
+func importStopped(c echo.Context, r *service.CustomerImportReport, err error) error {
+	status := httpStatus(err)
+	report := newImportReport(r)
+	report.Error = err.Error()
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		report.Error = fmt.Sprint(unwrapHTTPError(httpErr).Message)
+	}
+
+	if status >= http.StatusInternalServerError {
+		logging.FromContext(c.Request().Context()).Errorf("customers import is stopped - %v", err)
+		report.Error = http.StatusText(status)
+	}
+	return c.JSON(status, report)
+}
+
+// csvReadError maps error of reading csv to response error, body exceeding max size is reported as is
+func csvReadError(err error) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("malformed csv - %v", err))
+}
+
+// csvColumns maps header of import file to columns, so column of record field is known by its index
+func csvColumns(header []string) ([]string, error) {
+	columns := make([]string, len(header))
+	present := make(map[string]bool)
+	for i, name := range header {
+		column, ok := csvImportColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown column %s, supported columns are %s", name, strings.Join(csvImportColumns, ", "))
+		}
+
+		if present[column] {
+			return nil, fmt.Errorf("column %s is duplicated", column)
+		}
+
+		columns[i] = column
+		present[column] = true
+	}
+
+	for _, column := range csvImportRequiredColumns {
+		if !present[column] {
+			return nil, fmt.Errorf("required column %s is missing", column)
+		}
+	}
+	return columns, nil
+}
+
+func csvImportColumn(name string) (string, bool) {
+	for _, column := range csvImportColumns {
+		if strings.EqualFold(column, name) {
+			return column, true
+		}
+	}
+	return "", false
+}
+
+// csvCustomer builds customer from record, customer is validated by the same rules as customer created via api
+func csvCustomer(c echo.Context, columns []string, record []string) (*model.Customer, error) {
+	var ic importedCustomer
+	for i, value := range record {
+		switch columns[i] {
+		case "id":
+			ic.ID = value
+		case "firstName":
+			ic.FirstName = value
+		case "lastName":
+			ic.LastName = value
+		case "middleName":
+			if value != "" {
+				middleName := value
+				ic.MiddleName = &middleName
+			}
+		case "email":
+			ic.Email = value
+		case "importance":
+			if value == "" {
+				continue // reported by required check
+			}
+			importance, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, csvFieldError(columns[i], "number", fmt.Sprintf("importance must be a number, got %s", value))
+			}
+			ic.Importance = model.Importance(importance)
+		case "inactive":
+			if value == "" {
+				continue
+			}
+			inactive, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, csvFieldError(columns[i], "boolean", fmt.Sprintf("inactive must be a boolean, got %s", value))
+			}
+			ic.Inactive = inactive
+		}
+	}
+
+	if err := c.Validate(&ic); err != nil {
+		return nil, err
+	}
+
+	return &model.Customer{
+		ID:         ic.ID,
+		FirstName:  ic.FirstName,
+		LastName:   ic.LastName,
+		MiddleName: ic.MiddleName,
+		Email:      ic.Email,
+		Importance: importanceFromHTTP(ic.Importance),
+		Inactive:   ic.Inactive,
+	}, nil
+}
+
+func csvFieldError(field string, code string, msg string) error {
+	pldErr := &validation.PayloadError{}
+	pldErr.Violation(validation.Violation{Field: field, Message: msg, Code: code})
+	return pldErr
+}
+
+func newImportReport(r *service.CustomerImportReport) *importReport {
+	report := &importReport{Imported: r.Imported, Rejected: make([]*rejectedRow, len(r.Rejected))}
+	for i, row := range r.Rejected {
+		rejected := &rejectedRow{Line: row.Line, Message: row.Err.Error(), Details: make([]validation.Violation, 0)}
+
+		var pldErr *validation.PayloadError
+		if errors.As(row.Err, &pldErr) {
+			rejected.Message = payloadErrorMessage
+			rejected.Details = pldErr.Violations()
+		}
+		report.Rejected[i] = rejected
+	}
+	return report
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/internal/validation"
+)
+
+// importCSV mixes valid rows, rows failing validation and row with wrong number of fields,
+// middle name of the last row spans two lines
+const importCSV = `firstName,lastName,middleName,email,importance,inactive
+John,Walls,,john.walls@somemail.com,1,false
+Jane,,,jane@somemail.com,4,
+Jack,Black,,not-an-email,5,true
+Hank,Scorpio,,hank@somemail.com,critical,false
+Bart,Simpson,,bart@somemail.com
+Lisa,Simpson,"Marie
+Ann",lisa@somemail.com,3,true
+`
+
+// failingCustomerRepository fails to create customer with email, so import is stopped by server error
+type failingCustomerRepository struct {
+	repository.CustomerRepository
+	email string
+}
+
+func (r *failingCustomerRepository) Create(ctx context.Context, c *model.Customer) error {
+	if c.Email == r.email {
+		return errors.New("postgres: connection refused")
+	}
+	return r.CustomerRepository.Create(ctx, c)
+}
+
+type importTestSuite struct {
+	suite.Suite
+	app         *echo.Echo
+	customerRps repository.CustomerRepository
+	importCfg   *config.ImportCfg
+}
+
+func (s *importTestSuite) SetupTest() {
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	RegisterCustomerValidation(v)
+	uni, err := validation.Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	echoValidator := validation.Echo(v, uni)
+	s.app = echo.New()
+	s.app.Validator = echoValidator
+	s.app.HTTPErrorHandler = NewHTTPErrorHandler(echoValidator, true)
+
+	s.customerRps = repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant)
+	s.importCfg = &config.ImportCfg{MaxRows: 100, MaxBodySize: 1 << 20}
+	customerRps := &failingCustomerRepository{CustomerRepository: s.customerRps, email: "failing@somemail.com"}
+	customerSvc := service.NewCustomerService(customerRps, cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+	handler := NewCustomerImportHTTPHandler(service.NewCustomerImportService(customerSvc, nil), s.importCfg)
+	s.app.POST("/api/v1/customers/import.csv", handler.ImportCSV, tenantClaims(), middleware.Tenant())
+}
+
+func (s *importTestSuite) TestImportCSV() {
+	t := s.T()
+	require := s.Require()
+
+	existing := &model.Customer{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: tenant.DefaultID, FirstName: "Johnny", LastName: "Walls", Email: "john.walls@somemail.com", Importance: model.ImportanceCritical}
+	require.NoError(s.customerRps.Create(context.Background(), existing), "failed to create customer")
+
+	t.Log("valid rows are imported, invalid rows are reported with their lines")
+	{
+		rec := s.importCSV("", importCSV)
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		report := s.report(rec)
+		require.Equal(2, report.Imported, "valid rows must be imported")
+		require.Equal([]int{3, 4, 5, 6}, s.lines(report), "invalid rows must be rejected")
+
+		require.Equal(payloadErrorMessage, report.Rejected[0].Message, "row failed validation must be reported as payload error")
+		require.Equal("lastName", report.Rejected[0].Details[0].Field, "violated field must be reported")
+
+		fields := make([]string, 0)
+		for _, violation := range report.Rejected[1].Details {
+			fields = append(fields, violation.Field)
+		}
+		require.ElementsMatch([]string{"email", "importance"}, fields, "all violations of row must be reported")
+
+		require.Equal("importance", report.Rejected[2].Details[0].Field, "unparsable field must be reported")
+		require.Equal("number", report.Rejected[2].Details[0].Code, "unparsable field must be reported")
+		require.Contains(report.Rejected[3].Message, "row has 4 fields", "row with wrong number of fields must be reported")
+	}
+
+	t.Log("customer with the same email is updated, new customer is created")
+	{
+		customers, err := s.customerRps.FindAll(context.Background(), tenant.DefaultID)
+		require.NoError(err, "failed to read customers")
+		require.Len(customers, 2, "only valid rows must be imported")
+
+		updated, err := s.customerRps.FindByID(context.Background(), tenant.DefaultID, existing.ID)
+		require.NoError(err, "failed to read customer")
+		require.Equal("John", updated.FirstName, "customer with the same email must be updated")
+		require.Equal(model.ImportanceLow, updated.Importance, "customer with the same email must be updated")
+
+		created, err := s.customerRps.FindByEmail(context.Background(), tenant.DefaultID, "lisa@somemail.com")
+		require.NoError(err, "failed to read customer")
+		require.Equal("Marie\nAnn", *created.MiddleName, "quoted multiline field must be read")
+		require.True(created.Inactive, "inactive must be imported")
+	}
+}
+
+func (s *importTestSuite) TestImportCSVStrict() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("nothing is imported in strict mode if any row is invalid")
+	{
+		rec := s.importCSV("strict=true", importCSV)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+
+		report := s.report(rec)
+		require.Zero(report.Imported, "nothing must be imported")
+		require.Equal([]int{3, 4, 5, 6}, s.lines(report), "invalid rows must be reported")
+
+		customers, err := s.customerRps.FindAll(context.Background(), tenant.DefaultID)
+		require.NoError(err, "failed to read customers")
+		require.Empty(customers, "nothing must be imported")
+	}
+
+	t.Log("all rows are imported in strict mode if all rows are valid")
+	{
+		rec := s.importCSV("strict=true", "email,firstName,lastName,importance\njohn@somemail.com,John,Walls,2\njane@somemail.com,Jane,Doe,3\n")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(2, s.report(rec).Imported, "all rows must be imported")
+	}
+}
+
+func (s *importTestSuite) TestImportCSVMalformed() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("file without valid header is rejected")
+	{
+		for _, body := range []string{"", "firstName,lastName,email\n", "firstName,lastName,email,importance,phone\n", "firstName,lastName,email,importance,email\n"} {
+			rec := s.importCSV("", body)
+			require.Equal(http.StatusBadRequest, rec.Code, "file %q must be rejected", body)
+		}
+	}
+
+	t.Log("malformed csv is rejected")
+	{
+		rec := s.importCSV("", "firstName,lastName,email,importance\nJohn,\"Walls,john@somemail.com,1\n")
+		require.Equal(http.StatusBadRequest, rec.Code, "malformed csv must be rejected")
+	}
+}
+
+func (s *importTestSuite) TestImportCSVStopped() {
+	t := s.T()
+	require := s.Require()
+
+	csv := "email,firstName,lastName,importance\njohn@somemail.com,John,Walls,2\njane@somemail.com,Jane,Doe,3\n"
+
+	t.Log("import is stopped once file exceeds max number of rows, rows read before are reported")
+	{
+		s.importCfg.MaxRows = 1
+		rec := s.importCSV("", csv)
+		require.Equal(http.StatusRequestEntityTooLarge, rec.Code, "response status must be Request Entity Too Large")
+
+		report := s.report(rec)
+		require.Equal(1, report.Imported, "row read before limit must be imported")
+		require.Contains(report.Error, "1 rows", "exceeded limit must be reported")
+		s.importCfg.MaxRows = 100
+	}
+
+	t.Log("import is stopped once file exceeds max size")
+	{
+		s.importCfg.MaxBodySize = int64(strings.Index(csv, "\n") + 1)
+		rec := s.importCSV("strict=true", csv)
+		require.Equal(http.StatusRequestEntityTooLarge, rec.Code, "response status must be Request Entity Too Large")
+
+		report := s.report(rec)
+		require.Zero(report.Imported, "nothing must be imported in strict mode")
+		require.Contains(report.Error, "bytes", "exceeded limit must be reported")
+		s.importCfg.MaxBodySize = 1 << 20
+	}
+
+	t.Log("report of rows imported before server error is returned")
+	{
+		rec := s.importCSV("", "email,firstName,lastName,importance\nhank@somemail.com,Hank,Scorpio,2\nfailing@somemail.com,Bart,Simpson,3\n")
+		require.Equal(http.StatusInternalServerError, rec.Code, "response status must be Internal Server Error")
+
+		report := s.report(rec)
+		require.Equal(1, report.Imported, "row imported before failure must be reported")
+		require.Equal(http.StatusText(http.StatusInternalServerError), report.Error, "server error must not be exposed")
+	}
+}
+
+func (s *importTestSuite) importCSV(query string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/customers/import.csv?"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *importTestSuite) report(rec *httptest.ResponseRecorder) *importReport {
+	var report importReport
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &report), "failed to decode report")
+	return &report
+}
+
+func (s *importTestSuite) lines(report *importReport) []int {
+	lines := make([]int, len(report.Rejected))
+	for i, row := range report.Rejected {
+		lines[i] = row.Line
+	}
+	return lines
+}
+
+// start import test suite
+func TestImportTestSuite(t *testing.T) {
+	suite.Run(t, new(importTestSuite))
+}
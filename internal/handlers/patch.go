@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// MIMEApplicationJSONPatch is media type of RFC 6902 JSON patch documents
+const MIMEApplicationJSONPatch = "application/json-patch+json"
+
+// JSON patch operations supported for customers, move, copy and test are not supported
+const (
+	patchOpAdd     = "add"
+	patchOpRemove  = "remove"
+	patchOpReplace = "replace"
+)
+
+// patchOperation is single operation of JSON patch document
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"` // any JSON value, missing for remove
+}
+
+// applyCustomerPatch applies operations to customer payload one by one, patch is applied as a whole or not at all
+// since payload is copy of loaded customer. Only top-level customer fields are patchable, removal of field resets it,
+// so removal of required field fails validation of patched payload, and only optional middleName can really be removed
+func applyCustomerPatch(uc *updateCustomer, ops []patchOperation) error {
+	for i, op := range ops {
+		target, ok := customerPatchTarget(uc, op.Path)
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("operation %d: path %q is not supported", i, op.Path))
+		}
+
+		switch op.Op {
+		case patchOpAdd, patchOpReplace:
+			if op.Value == nil {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("operation %d: %s requires value", i, op.Op))
+			}
+
+			// RFC 6902 requires replaced value to exist, the only member which may be absent is middleName
+			if op.Op == patchOpReplace && op.Path == "/middleName" && uc.MiddleName == nil {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("operation %d: path %q does not exist", i, op.Path))
+			}
+
+			if err := json.Unmarshal(op.Value, target); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("operation %d: invalid value for path %q - %v", i, op.Path, err))
+			}
+		case patchOpRemove:
+			if op.Path == "/middleName" && uc.MiddleName == nil {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("operation %d: path %q does not exist", i, op.Path))
+			}
+			resetPatchTarget(target)
+		default:
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("operation %d: operation %q is not supported", i, op.Op))
+		}
+	}
+	return nil
+}
+
+// customerPatchTarget returns pointer to payload field addressed by JSON pointer
+func customerPatchTarget(uc *updateCustomer, path string) (any, bool) {
+	switch path {
+	case "/firstName":
+		return &uc.FirstName, true
+	case "/lastName":
+		return &uc.LastName, true
+	case "/middleName":
+		return &uc.MiddleName, true
+	case "/email":
+		return &uc.Email, true
+	case "/importance":
+		return &uc.Importance, true
+	case "/inactive":
+		return &uc.Inactive, true
+	default:
+		return nil, false
+	}
+}
+
+// resetPatchTarget resets payload field to zero value, zero importance is not valid, so its removal is rejected after patching
+func resetPatchTarget(target any) {
+	switch t := target.(type) {
+	case *string:
+		*t = ""
+	case **string:
+		*t = nil
+	case *model.Importance:
+		*t = 0
+	case *bool:
+		*t = false
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/validation"
+)
+
+const (
+	patchTestTenant     = "acme"
+	patchTestCustomerID = "4f7c9a1e-5b6d-4c0e-8f3a-4b5c6d7e8f90"
+)
+
+type patchTestSuite struct {
+	suite.Suite
+	app         *echo.Echo
+	customerRps repository.CustomerRepository
+}
+
+func (s *patchTestSuite) SetupTest() {
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	RegisterCustomerValidation(v)
+	s.Require().NoError(validation.RegisterImportance(v), "failed to register importance validation")
+	uni, err := validation.Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	echoValidator := validation.Echo(v, uni)
+	s.app = echo.New()
+	s.app.Validator = echoValidator
+	s.app.HTTPErrorHandler = NewHTTPErrorHandler(echoValidator, true)
+
+	s.customerRps = repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant)
+	middleName := "Ann"
+	s.Require().NoError(s.customerRps.Create(context.Background(), &model.Customer{
+		ID:         patchTestCustomerID,
+		TenantID:   patchTestTenant,
+		FirstName:  "Jane",
+		LastName:   "Johnson",
+		MiddleName: &middleName,
+		Email:      "jane@somemail.com",
+		Importance: model.ImportanceLow,
+	}), "failed to create customer")
+
+	customerSvc := service.NewCustomerService(s.customerRps, cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+	s.app.PATCH("/api/v1/customers/:id", NewCustomerHTTPHandler(customerSvc, false).Patch, tenantClaims(), middleware.Tenant())
+}
+
+func (s *patchTestSuite) TestPatchReplace() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("fields are replaced and other fields are kept")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"replace","path":"/firstName","value":"Mary"},{"op":"replace","path":"/importance","value":3}]`)
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		var patched customerV1
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &patched), "failed to decode patched customer")
+		require.Equal("Mary", patched.FirstName, "first name must be replaced")
+		require.Equal(importanceToHTTP(model.ImportanceHigh), patched.Importance, "importance must be replaced as in http api")
+		require.Equal("Johnson", patched.LastName, "last name must be kept")
+		require.NotNil(patched.MiddleName, "middle name must be kept")
+
+		stored := s.stored()
+		require.Equal("Mary", stored.FirstName, "first name must be stored")
+		require.Equal(model.ImportanceHigh, stored.Importance, "importance must be stored")
+	}
+
+	t.Log("patch violating validation rules is rejected entirely")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"replace","path":"/lastName","value":"Stone"},{"op":"replace","path":"/email","value":"not-email"}]`)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+		require.Equal("Johnson", s.stored().LastName, "customer must not be changed")
+	}
+}
+
+func (s *patchTestSuite) TestPatchRemove() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("middle name is removed")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"remove","path":"/middleName"}]`)
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Nil(s.stored().MiddleName, "middle name must be removed")
+	}
+
+	t.Log("removal of absent middle name is rejected")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"remove","path":"/middleName"}]`)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+	}
+
+	t.Log("replace of absent middle name is rejected, add sets it")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"replace","path":"/middleName","value":"Lee"}]`)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+
+		rec = s.patch(MIMEApplicationJSONPatch, `[{"op":"add","path":"/middleName","value":"Lee"}]`)
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal("Lee", *s.stored().MiddleName, "middle name must be added")
+	}
+
+	t.Log("removal of required field fails validation")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"remove","path":"/firstName"}]`)
+		require.Equal(http.StatusUnprocessableEntity, rec.Code, "response status must be Unprocessable Entity")
+
+		var envelope errorEnvelope
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &envelope), "error response must be envelope")
+		require.Len(envelope.Details, 1, "single violation expected")
+		require.Equal("firstName", envelope.Details[0].Field, "violation must be reported for first name")
+		require.Equal("Jane", s.stored().FirstName, "customer must not be changed")
+	}
+}
+
+func (s *patchTestSuite) TestPatchInvalid() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("unsupported paths and operations are rejected")
+	{
+		for _, doc := range []string{
+			`[{"op":"replace","path":"/id","value":"0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c"}]`,
+			`[{"op":"replace","path":"/tenantId","value":"globex"}]`,
+			`[{"op":"replace","path":"/name/first","value":"Mary"}]`,
+			`[{"op":"move","from":"/firstName","path":"/lastName"}]`,
+			`[{"op":"replace","path":"/firstName"}]`,
+			`[]`,
+		} {
+			rec := s.patch(MIMEApplicationJSONPatch, doc)
+			require.Equal(http.StatusUnprocessableEntity, rec.Code, "patch %s must be rejected", doc)
+		}
+	}
+
+	t.Log("malformed patch is rejected as bad request")
+	{
+		for _, doc := range []string{`{"op":"remove"}`, `[{"op":"replace","path":"/importance","value":"high"}]`} {
+			rec := s.patch(MIMEApplicationJSONPatch, doc)
+			require.Equal(http.StatusBadRequest, rec.Code, "patch %s must be rejected", doc)
+		}
+	}
+
+	t.Log("patch of other media type is rejected")
+	{
+		rec := s.patch(echo.MIMEApplicationJSON, `[{"op":"replace","path":"/firstName","value":"Mary"}]`)
+		require.Equal(http.StatusUnsupportedMediaType, rec.Code, "response status must be Unsupported Media Type")
+	}
+
+	t.Log("patch of missing customer is rejected as not found")
+	{
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/customers/0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", strings.NewReader(`[{"op":"remove","path":"/middleName"}]`))
+		req.Header.Set(echo.HeaderContentType, MIMEApplicationJSONPatch)
+		req.Header.Set(middleware.TenantHeader, patchTestTenant)
+		rec := httptest.NewRecorder()
+		s.app.ServeHTTP(rec, req)
+		require.Equal(http.StatusNotFound, rec.Code, "response status must be Not Found")
+	}
+
+	require.Equal("Jane", s.stored().FirstName, "customer must not be changed")
+}
+
+func (s *patchTestSuite) TestPatchDeletedConcurrently() {
+	t := s.T()
+	require := s.Require()
+
+	customerSvc := service.NewCustomerService(&deletingCustomerRepository{CustomerRepository: s.customerRps}, cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+	s.app.PATCH("/api/v1/customers/:id", NewCustomerHTTPHandler(customerSvc, false).Patch, tenantClaims(), middleware.Tenant())
+
+	t.Log("customer deleted while patch is applied is reported as not found and isn't created again")
+	{
+		rec := s.patch(MIMEApplicationJSONPatch, `[{"op":"replace","path":"/firstName","value":"Mary"}]`)
+		require.Equal(http.StatusNotFound, rec.Code, "response status must be Not Found")
+
+		c, err := s.customerRps.FindByID(context.Background(), patchTestTenant, patchTestCustomerID)
+		require.NoError(err, "failed to read customer")
+		require.Nil(c, "deleted customer must not be created again")
+	}
+}
+
+// deletingCustomerRepository deletes customer right before update to emulate concurrent delete
+type deletingCustomerRepository struct {
+	repository.CustomerRepository
+}
+
+func (r *deletingCustomerRepository) Update(ctx context.Context, c *model.Customer) error {
+	if err := r.CustomerRepository.DeleteByID(ctx, c.TenantID, c.ID); err != nil {
+		return err
+	}
+	return r.CustomerRepository.Update(ctx, c)
+}
+
+func (s *patchTestSuite) patch(contentType string, doc string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/customers/"+patchTestCustomerID, strings.NewReader(doc))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	req.Header.Set(middleware.TenantHeader, patchTestTenant)
+
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *patchTestSuite) stored() *model.Customer {
+	c, err := s.customerRps.FindByID(context.Background(), patchTestTenant, patchTestCustomerID)
+	s.Require().NoError(err, "failed to read customer")
+	return c
+}
+
+// start patch test suite
+func TestPatchTestSuite(t *testing.T) {
+	suite.Run(t, new(patchTestSuite))
+}
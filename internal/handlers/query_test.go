@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/validation"
+)
+
+type pageQuery struct {
+	Importance *model.Importance `query:"importance" validate:"omitempty,importance"`
+	Limit      int               `query:"limit" validate:"min=1,max=100"`
+	Offset     int               `query:"offset" validate:"min=0"`
+}
+
+type queryTestSuite struct {
+	suite.Suite
+	app *echo.Echo
+}
+
+func (s *queryTestSuite) SetupTest() {
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	s.Require().NoError(validation.RegisterImportance(v), "failed to register importance validation")
+	uni, err := validation.Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	echoValidator := validation.Echo(v, uni)
+	s.app = echo.New()
+	s.app.Validator = echoValidator
+	s.app.HTTPErrorHandler = NewHTTPErrorHandler(echoValidator, true)
+}
+
+func (s *queryTestSuite) TestBindQuery() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("query parameters are bound, defaults are kept for missing parameters")
+	{
+		q := pageQuery{Limit: 20}
+		require.NoError(bindQuery(s.context("/?importance=2&offset=40"), &q), "valid query must be bound")
+		require.Equal(20, q.Limit, "default limit must be kept")
+		require.Equal(40, q.Offset, "incorrect offset")
+		require.NotNil(q.Importance, "importance must be bound")
+		require.Equal(importanceToHTTP(model.ImportanceMedium), *q.Importance, "importance must be bound as in http api")
+	}
+
+	t.Log("query violating rules is rejected with payload error reported under parameter name")
+	{
+		for query, field := range map[string]string{"/?limit=0": "limit", "/?offset=-1": "offset", "/?importance=5": "importance"} {
+			q := pageQuery{Limit: 20}
+			err := bindQuery(s.context(query), &q)
+			require.IsType(&validation.PayloadError{}, err, "query %s must be rejected with payload error", query)
+			violations := err.(*validation.PayloadError).Violations()
+			require.Len(violations, 1, "single violation expected for query %s", query)
+			require.Equal(field, violations[0].Field, "violation must be reported under query parameter name")
+		}
+	}
+
+	t.Log("malformed query is rejected as bad request")
+	{
+		for _, query := range []string{"/?limit=ten", "/?offset=1.5", "/?importance=high"} {
+			q := pageQuery{Limit: 20}
+			err := bindQuery(s.context(query), &q)
+			require.Error(err, "query %s is malformed but no error raised", query)
+			require.Equal(http.StatusBadRequest, httpStatus(err), "query %s must be rejected as bad request", query)
+		}
+	}
+}
+
+func (s *queryTestSuite) TestGetAllInvalidQuery() {
+	t := s.T()
+	require := s.Require()
+
+	s.app.GET("/api/v1/customers", NewCustomerHTTPHandler(nil, false).GetAll)
+
+	t.Log("customers list with malformed activeOnly is rejected as bad request")
+	{
+		rec := httptest.NewRecorder()
+		s.app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/customers?activeOnly=maybe", http.NoBody))
+		require.Equal(http.StatusBadRequest, rec.Code, "response status must be Bad Request")
+	}
+}
+
+func (s *queryTestSuite) context(target string) echo.Context {
+	return s.app.NewContext(httptest.NewRequest(http.MethodGet, target, http.NoBody), httptest.NewRecorder())
+}
+
+// start query test suite
+func TestQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(queryTestSuite))
+}
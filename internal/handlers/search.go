@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/service"
+)
+
+const defaultSearchPageLimit = 20
+
+type customersSearchQuery struct {
+	Query      string            `query:"q" validate:"required,max=256"`
+	Importance *model.Importance `query:"importance" validate:"omitempty,importance"`
+	Inactive   *bool             `query:"inactive"`
+	Limit      int               `query:"limit" validate:"min=1,max=100"`
+	Offset     int               `query:"offset" validate:"min=0"`
+}
+
+type customersPage struct {
+	Customers []any `json:"customers"`
+	Total     int   `json:"total"`
+}
+
+type reindexResult struct {
+	Indexed int `json:"indexed"`
+}
+
+// CustomerSearchHTTPHandler is http handler for customers full-text search of v1 api
+type CustomerSearchHTTPHandler struct {
+	searchSvc  service.CustomerSearchService
+	reindexSvc service.CustomerReindexService
+}
+
+// NewCustomerSearchHTTPHandler builds new CustomerSearchHTTPHandler, reindexSvc is set only if search index is configured
+func NewCustomerSearchHTTPHandler(searchSvc service.CustomerSearchService, reindexSvc service.CustomerReindexService) *CustomerSearchHTTPHandler {
+	return &CustomerSearchHTTPHandler{searchSvc: searchSvc, reindexSvc: reindexSvc}
+}
+
+// Search searches customers
+// @Summary     Search customers
+// @Description Returns page of customers whose name or email matches query, the most relevant customers go first.
+// @Description Customers are ranked and typos are tolerated only if search index is configured.
+// @Tags        customers
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header   string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Param 		q          query    string true  "Search query" maxlength(256)
+// @Param 		importance query    int    false "Only customers with importance" Enums(1, 2, 3, 4)
+// @Param 		inactive   query    bool   false "Only active or inactive customers"
+// @Param 		limit      query    int    false "Page size" minimum(1) maximum(100) default(20)
+// @Param 		offset     query    int    false "Number of customers to skip" minimum(0) default(0)
+// @Success     200        {object} customersPage
+// @Failure     400        {object} errorEnvelope
+// @Failure     401        {object} errorEnvelope
+// @Failure     500        {object} errorEnvelope
+// @Router      /api/v1/customers/search [get]
+func (h *CustomerSearchHTTPHandler) Search(c echo.Context) error {
+	q := customersSearchQuery{Limit: defaultSearchPageLimit}
+	if err := bindQuery(c, &q); err != nil {
+		return err
+	}
+
+	customers, total, err := h.searchSvc.Search(c.Request().Context(), &model.CustomerSearch{
+		Query:  q.Query,
+		Filter: model.CustomerFilter{Importance: optionalImportanceFromHTTP(q.Importance), Inactive: q.Inactive},
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	page := customersPage{Customers: make([]any, len(customers)), Total: total}
+	for i, customer := range customers {
+		page.Customers[i] = customerV1View(customer)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// Reindex rebuilds search index of tenant customers
+// @Summary     Reindex customers
+// @Description Indexes all customers of tenant from datastore and removes documents of customers missing in it, admin only.
+// @Description Index is kept in sync with customer changes, so reindex is needed only to backfill it or to recover it after outage.
+// @Description Returns number of indexed customers.
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Success     200 {object} reindexResult
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/search/reindex [post]
+func (h *CustomerSearchHTTPHandler) Reindex(c echo.Context) error {
+	indexed, err := h.reindexSvc.Reindex(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, &reindexResult{Indexed: indexed})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/validation"
+)
+
+// failingCustomerSearchRepository imitates unavailable search index
+type failingCustomerSearchRepository struct{}
+
+func (failingCustomerSearchRepository) Search(context.Context, string, *model.CustomerSearch) ([]*model.Customer, int, error) {
+	return nil, 0, errors.New("elasticsearch: failed to search customers - connection refused")
+}
+
+// staleCustomerSearchRepository imitates search index lagging behind datastore, it finds customers as they were indexed
+type staleCustomerSearchRepository struct {
+	customers []*model.Customer
+}
+
+func (r staleCustomerSearchRepository) Search(context.Context, string, *model.CustomerSearch) ([]*model.Customer, int, error) {
+	return r.customers, len(r.customers), nil
+}
+
+type searchTestSuite struct {
+	suite.Suite
+	app         *echo.Echo
+	customerRps repository.CustomerRepository
+}
+
+func (s *searchTestSuite) SetupTest() {
+	v := validator.New()
+	v.RegisterTagNameFunc(validation.JSONTagName)
+	RegisterCustomerValidation(v)
+	uni, err := validation.Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	echoValidator := validation.Echo(v, uni)
+	s.app = echo.New()
+	s.app.Validator = echoValidator
+	s.app.HTTPErrorHandler = NewHTTPErrorHandler(echoValidator, true)
+
+	s.customerRps = repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant)
+	middleName := "Ann"
+	for _, c := range []*model.Customer{
+		{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: "acme", FirstName: "John", LastName: "Walls", Email: "john.walls@somemail.com", Importance: model.ImportanceLow},
+		{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "acme", FirstName: "Jane", LastName: "Johnson", MiddleName: &middleName, Email: "jane@somemail.com", Importance: model.ImportanceCritical},
+		{ID: "2d5a7e9c-3f4b-4a8c-8d1e-2f3a4b5c6d7e", TenantID: "acme", FirstName: "Jack", LastName: "Black", Email: "jack.black@somemail.com", Importance: model.ImportanceCritical, Inactive: true},
+		{ID: "3e6b8f0d-4a5c-4b9d-9e2f-3a4b5c6d7e8f", TenantID: "globex", FirstName: "John", LastName: "Doe", Email: "john.doe@somemail.com", Importance: model.ImportanceCritical},
+	} {
+		s.Require().NoError(s.customerRps.Create(context.Background(), c), "failed to create customer")
+	}
+}
+
+func (s *searchTestSuite) TestSearch() {
+	t := s.T()
+	require := s.Require()
+
+	s.route(service.NewCustomerSearchService(repository.NewInMemoryCustomerSearchRepository(s.customerRps), nil))
+
+	t.Log("customers of caller tenant matching query are returned")
+	{
+		rec := s.search("acme", "q=joHN")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		page := s.page(rec)
+		require.Equal(2, page.Total, "incorrect number of matched customers")
+		require.Equal([]string{"1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c"}, s.ids(page), "incorrect customers")
+	}
+
+	t.Log("customers are matched by email and narrowed down by filter")
+	{
+		page := s.page(s.search("acme", "q=somemail.com&importance=4&inactive=false"))
+		require.Equal(1, page.Total, "incorrect number of matched customers")
+		require.Equal([]string{"1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d"}, s.ids(page), "incorrect customers")
+	}
+
+	t.Log("customers are paged, total counts all matched customers")
+	{
+		page := s.page(s.search("acme", "q=somemail&limit=2&offset=2"))
+		require.Equal(3, page.Total, "incorrect number of matched customers")
+		require.Equal([]string{"0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c"}, s.ids(page), "incorrect page")
+	}
+
+	t.Log("invalid search is rejected")
+	{
+		for _, query := range []string{
+			"", "q=john&limit=0", "q=john&limit=101", "q=john&offset=-1", "q=john&importance=5",
+			"q=john&limit=ten", "q=john&offset=1.5", "q=john&inactive=maybe",
+		} {
+			rec := s.search("acme", query)
+			require.Equal(http.StatusBadRequest, rec.Code, "search %s must be rejected", query)
+		}
+	}
+}
+
+func (s *searchTestSuite) TestSearchFallback() {
+	t := s.T()
+	require := s.Require()
+
+	s.route(service.NewCustomerSearchService(failingCustomerSearchRepository{}, repository.NewInMemoryCustomerSearchRepository(s.customerRps)))
+
+	t.Log("customers are searched in fallback if search index is unavailable")
+	{
+		rec := s.search("globex", "q=john")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		page := s.page(rec)
+		require.Equal(1, page.Total, "incorrect number of matched customers")
+		require.Equal([]string{"3e6b8f0d-4a5c-4b9d-9e2f-3a4b5c6d7e8f"}, s.ids(page), "incorrect customers")
+	}
+}
+
+func (s *searchTestSuite) TestSearchReadBack() {
+	t := s.T()
+	require := s.Require()
+
+	indexed := []*model.Customer{
+		{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "acme", FirstName: "Jane", LastName: "Doe", Email: "jane@somemail.com", Importance: model.ImportanceCritical},
+		{ID: "2d5a7e9c-3f4b-4a8c-8d1e-2f3a4b5c6d7e", TenantID: "acme", FirstName: "Jack", LastName: "Black", Email: "jack.black@somemail.com", Importance: model.ImportanceCritical},
+		{ID: "9f8e7d6c-5b4a-4392-8170-6f5e4d3c2b1a", TenantID: "acme", FirstName: "Hank", LastName: "Scorpio", Email: "hank@somemail.com", Importance: model.ImportanceCritical},
+	}
+	searchRps := repository.NewInMemoryCustomerSearchRepository(s.customerRps)
+	s.route(service.NewIndexedCustomerSearchService(staleCustomerSearchRepository{customers: indexed}, searchRps, s.customerRps))
+
+	t.Log("customers found in index are served as they are kept in datastore")
+	{
+		rec := s.search("acme", "q=somemail&inactive=false")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+
+		page := s.page(rec)
+		require.Equal(1, page.Total, "deleted customers and customers not matching filter anymore must not be counted")
+		require.Equal([]string{"1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d"}, s.ids(page), "deleted customers and customers not matching filter anymore must be dropped")
+		require.Equal("Johnson", page.Customers[0].(map[string]any)["lastName"], "customer must be read back from datastore")
+	}
+}
+
+func (s *searchTestSuite) route(searchSvc service.CustomerSearchService) {
+	s.app.GET("/api/v1/customers/search", NewCustomerSearchHTTPHandler(searchSvc, nil).Search, tenantClaims(), middleware.Tenant())
+}
+
+func (s *searchTestSuite) search(tenantID string, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/customers/search?"+query, http.NoBody)
+	req.Header.Set(middleware.TenantHeader, tenantID)
+
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *searchTestSuite) page(rec *httptest.ResponseRecorder) *customersPage {
+	var page customersPage
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &page), "failed to decode page")
+	return &page
+}
+
+func (s *searchTestSuite) ids(page *customersPage) []string {
+	ids := make([]string, len(page.Customers))
+	for i, c := range page.Customers {
+		ids[i] = c.(map[string]any)["id"].(string)
+	}
+	return ids
+}
+
+// start search test suite
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(searchTestSuite))
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/interceptors"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const watchTestTimeout = 5 * time.Second
+
+type watchTestSuite struct {
+	suite.Suite
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client proto.CustomerServiceClient
+}
+
+func (s *watchTestSuite) SetupTest() {
+	broker := event.NewBroker(8)
+	customerSvc := service.NewEventPublishingCustomerService(
+		service.NewCustomerService(repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant), cache.NewInMemoryCache(), config.CachePopulationFailureFail),
+		broker,
+	)
+
+	listener := bufconn.Listen(grpcConnBufSize)
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			tenantClaimsUnaryInterceptor(),
+			interceptors.TenantUnaryInterceptor(),
+			interceptors.ValidatorUnaryInterceptor(true),
+			interceptors.ErrorUnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			tenantClaimsStreamInterceptor(),
+			interceptors.TenantStreamInterceptor(),
+			interceptors.ErrorStreamInterceptor(),
+		),
+	)
+	proto.RegisterCustomerServiceServer(s.server, NewCustomerGrpcHandler(customerSvc, broker))
+
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err, "failed to create gRPC connection")
+
+	s.conn = conn
+	s.client = proto.NewCustomerServiceClient(conn)
+}
+
+func (s *watchTestSuite) TearDownTest() {
+	s.conn.Close()
+	s.server.Stop()
+}
+
+func (s *watchTestSuite) TestWatch() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchTestTimeout)
+	defer cancel()
+
+	stream := s.watch(ctx, "acme")
+
+	t.Log("created event is received after customer is created")
+	{
+		created, err := s.client.Create(metadata.AppendToOutgoingContext(ctx, "tenantId", "acme"), &proto.NewCustomerRequest{
+			FirstName:  "John",
+			LastName:   "Walls",
+			Email:      "john.walls@somemal.com",
+			Importance: proto.CustomerImportance_HIGH,
+		})
+		require.NoError(err, "customer must be created")
+
+		e, err := stream.Recv()
+		require.NoError(err, "event must be received")
+		require.Equal(proto.CustomerEventType_CREATED, e.Type, "incorrect event type")
+		require.Equal(created.Id, e.CustomerId, "incorrect customer id")
+		require.Equal("john.walls@somemal.com", e.Customer.Email, "customer must be part of event")
+		require.Equal(proto.CustomerImportance_HIGH, e.Customer.Importance, "customer must be part of event")
+		require.NotEmpty(e.Id, "event must have id")
+		require.NotNil(e.OccurredAt, "event must have occurrence time")
+	}
+
+	t.Log("events of other tenants are not received")
+	{
+		_, err := s.client.Create(metadata.AppendToOutgoingContext(ctx, "tenantId", "globex"), &proto.NewCustomerRequest{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     "jane.doe@globex.com",
+		})
+		require.NoError(err, "customer of other tenant must be created")
+
+		created, err := s.client.Create(metadata.AppendToOutgoingContext(ctx, "tenantId", "acme"), &proto.NewCustomerRequest{
+			FirstName: "Jack",
+			LastName:  "Ryan",
+			Email:     "jack.ryan@somemal.com",
+		})
+		require.NoError(err, "customer must be created")
+
+		e, err := stream.Recv()
+		require.NoError(err, "event must be received")
+		require.Equal(created.Id, e.CustomerId, "only event of caller tenant must be received")
+	}
+
+	t.Log("deleted event is received without customer")
+	{
+		created, err := s.client.Create(metadata.AppendToOutgoingContext(ctx, "tenantId", "acme"), &proto.NewCustomerRequest{
+			FirstName: "Mike",
+			LastName:  "Ross",
+			Email:     "mike.ross@somemal.com",
+		})
+		require.NoError(err, "customer must be created")
+
+		_, err = s.client.DeleteByID(metadata.AppendToOutgoingContext(ctx, "tenantId", "acme"), &proto.DeleteCustomerByIdRequest{Id: created.Id})
+		require.NoError(err, "customer must be deleted")
+
+		e, err := stream.Recv()
+		require.NoError(err, "event must be received")
+		require.Equal(proto.CustomerEventType_CREATED, e.Type, "created event must be received first")
+
+		e, err = stream.Recv()
+		require.NoError(err, "event must be received")
+		require.Equal(proto.CustomerEventType_DELETED, e.Type, "incorrect event type")
+		require.Equal(created.Id, e.CustomerId, "incorrect customer id")
+		require.Nil(e.Customer, "deleted customer must not be part of event")
+	}
+}
+
+func (s *watchTestSuite) TestWatchClientDisconnected() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchTestTimeout)
+	defer cancel()
+
+	watchCtx, disconnect := context.WithCancel(ctx)
+	stream := s.watch(watchCtx, "acme")
+
+	t.Log("stream is closed once client disconnects")
+	{
+		disconnect()
+
+		_, err := stream.Recv()
+		require.Equal(codes.Canceled, status.Code(err), "stream must be cancelled")
+	}
+
+	t.Log("customers are still served after watcher is gone")
+	{
+		_, err := s.client.Create(metadata.AppendToOutgoingContext(ctx, "tenantId", "acme"), &proto.NewCustomerRequest{
+			FirstName: "John",
+			LastName:  "Walls",
+			Email:     "john.walls@somemal.com",
+		})
+		require.NoError(err, "customer must be created")
+	}
+}
+
+// watch opens watch stream and waits until subscription is established
+func (s *watchTestSuite) watch(ctx context.Context, tenantID string) proto.CustomerService_WatchClient {
+	stream, err := s.client.Watch(metadata.AppendToOutgoingContext(ctx, "tenantId", tenantID), new(emptypb.Empty))
+	s.Require().NoError(err, "failed to open watch stream")
+
+	_, err = stream.Header()
+	s.Require().NoError(err, "subscription must be established")
+	return stream
+}
+
+// start watch test suite
+func TestWatchTestSuite(t *testing.T) {
+	suite.Run(t, new(watchTestSuite))
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/service"
+)
+
+type newWebhook struct {
+	URL        string                    `json:"url" validate:"required,url,max=2000"`
+	Secret     string                    `json:"secret" validate:"omitempty,min=16,max=256" redact:"true"` // generated if omitted
+	EventTypes []model.CustomerEventType `json:"eventTypes" validate:"required,min=1,unique,dive,oneof=customer.created customer.updated customer.deleted"`
+	Active     *bool                     `json:"active"` // webhook is active if omitted
+}
+
+type webhookInfo struct {
+	ID         string                    `json:"id"`
+	URL        string                    `json:"url"`
+	Secret     string                    `json:"secret,omitempty" redact:"true"` // returned on creation only
+	EventTypes []model.CustomerEventType `json:"eventTypes"`
+	Active     bool                      `json:"active"`
+	CreatedAt  time.Time                 `json:"createdAt"`
+}
+
+type webhookDelivery struct {
+	ID             string                      `json:"id"`
+	EventID        string                      `json:"eventId"`
+	EventType      model.CustomerEventType     `json:"eventType"`
+	Status         model.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                         `json:"attempts"`
+	LastStatusCode int                         `json:"lastStatusCode,omitempty"`
+	LastError      string                      `json:"lastError,omitempty"`
+	CreatedAt      time.Time                   `json:"createdAt"`
+	UpdatedAt      time.Time                   `json:"updatedAt"`
+}
+
+// WebhookHTTPHandler is http handler for webhook subscriptions admin endpoints
+type WebhookHTTPHandler struct {
+	webhookSvc service.WebhookService
+}
+
+// NewWebhookHTTPHandler builds new WebhookHTTPHandler
+func NewWebhookHTTPHandler(webhookSvc service.WebhookService) *WebhookHTTPHandler {
+	return &WebhookHTTPHandler{webhookSvc: webhookSvc}
+}
+
+// Post subscribes webhook to customer events
+// @Summary     New webhook
+// @Description Subscribes webhook to customer events of caller tenant. Each event is POSTed to webhook url as json
+// @Description signed with HMAC-SHA256 of body keyed with secret in X-Webhook-Signature header, e.g. sha256=5d41...
+// @Description Secret is generated if omitted and is returned only in this response.
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Accept		json
+// @Produce     json
+// @Param 		newWebhook body	    newWebhook true "Webhook url and event types"
+// @Success     201        {object} webhookInfo
+// @Failure     400        {object} errorEnvelope
+// @Failure     422        {object} errorEnvelope
+// @Failure     401        {object} errorEnvelope
+// @Failure     403        {object} errorEnvelope
+// @Failure     500        {object} errorEnvelope
+// @Router      /api/admin/webhooks [post]
+func (h *WebhookHTTPHandler) Post(c echo.Context) error {
+	var nw newWebhook
+	if err := c.Bind(&nw); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(&nw); err != nil {
+		return err
+	}
+
+	webhook := &model.Webhook{
+		URL:        nw.URL,
+		Secret:     nw.Secret,
+		EventTypes: nw.EventTypes,
+		Active:     nw.Active == nil || *nw.Active,
+	}
+
+	webhook, err := h.webhookSvc.Create(c.Request().Context(), webhook)
+	if err != nil {
+		return err
+	}
+
+	info := newWebhookInfo(webhook)
+	info.Secret = webhook.Secret
+	return c.JSON(http.StatusCreated, info)
+}
+
+// GetAll returns webhooks
+// @Summary     List webhooks
+// @Description Returns webhooks of caller tenant, secrets are never returned
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Success     200 {array}  webhookInfo
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/webhooks [get]
+func (h *WebhookHTTPHandler) GetAll(c echo.Context) error {
+	webhooks, err := h.webhookSvc.FindAll(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	infos := make([]*webhookInfo, len(webhooks))
+	for i, w := range webhooks {
+		infos[i] = newWebhookInfo(w)
+	}
+	return c.JSON(http.StatusOK, infos)
+}
+
+// DeleteByID unsubscribes webhook
+// @Summary     Delete webhook by id
+// @Description Unsubscribes webhook, its deliveries are deleted as well
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Param       id  path     string true "Webhook guid" Format(uuid)
+// @Success     204 "Successful status code"
+// @Failure     400 {object} errorEnvelope
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/webhooks/{id} [delete]
+func (h *WebhookHTTPHandler) DeleteByID(c echo.Context) error {
+	id, err := pathUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookSvc.DeleteByID(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Deliveries returns deliveries of webhook
+// @Summary     List webhook deliveries
+// @Description Returns deliveries of events to webhook from the newest with number of attempts and last failure
+// @Tags        admin
+// @Security	ApiKeyAuth
+// @Param       X-Tenant-ID header string false "Caller tenant, must match tenant of access token if provided"
+// @Produce     json
+// @Param       id  path     string true "Webhook guid" Format(uuid)
+// @Success     200 {array}  webhookDelivery
+// @Failure     400 {object} errorEnvelope
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     404 {object} errorEnvelope
+// @Failure     500 {object} errorEnvelope
+// @Router      /api/admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHTTPHandler) Deliveries(c echo.Context) error {
+	id, err := pathUUID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.webhookSvc.FindDeliveries(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	views := make([]*webhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		views[i] = &webhookDelivery{
+			ID:             d.ID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+		}
+	}
+	return c.JSON(http.StatusOK, views)
+}
+
+func newWebhookInfo(w *model.Webhook) *webhookInfo {
+	return &webhookInfo{
+		ID:         w.ID,
+		URL:        w.URL,
+		EventTypes: w.EventTypes,
+		Active:     w.Active,
+		CreatedAt:  w.CreatedAt,
+	}
+}
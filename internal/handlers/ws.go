@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/logging"
+	"golang.org/x/time/rate"
+)
+
+// websocket frame types, client sends auth and get frames, the rest are sent by server
+const (
+	wsFrameAuth          = "auth"
+	wsFrameAuthenticated = "authenticated"
+	wsFrameGet           = "get"
+	wsFrameCustomer      = "customer"
+	wsFrameEvent         = "event"
+	wsFrameError         = "error"
+)
+
+const wsSendBufferSize = 16
+
+// wsTenantHeader is optional header with caller tenant, it must match tenant of access token
+const wsTenantHeader = "X-Tenant-ID"
+
+// wsRequest is frame sent by client, id is echoed in response, so client can match responses with requests
+type wsRequest struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Token      string `json:"token,omitempty" redact:"true"` // auth frame only
+	CustomerID string `json:"customerId,omitempty"`          // get frame only
+}
+
+// wsResponse is frame sent by server, either response to client request or pushed customer event
+type wsResponse struct {
+	Type     string               `json:"type"`
+	ID       string               `json:"id,omitempty"`
+	EventID  string               `json:"eventId,omitempty"` // stream id of pushed event
+	Event    *model.CustomerEvent `json:"event,omitempty"`
+	Customer any                  `json:"customer,omitempty"`
+	Code     int                  `json:"code,omitempty"` // http status code of failed request
+	Message  string               `json:"message,omitempty"`
+}
+
+// CustomerWebSocketHandler serves websocket pushing customer events of caller tenant and answering customer requests
+// over the same connection. Events are fanned out by broker, so connections don't read stream themselves.
+// Connections are closed with going away code once handler is closed and with policy violation once token expires or is revoked
+type CustomerWebSocketHandler struct {
+	customerSvc service.CustomerService
+	broker      *event.Broker
+	validator   *auth.JwtValidator
+	revoker     auth.TokenRevoker
+	cfg         *config.WebSocketCfg
+	upgrader    websocket.Upgrader
+	retryAfter  string // seconds of Retry-After header sent with 503
+	slots       chan struct{}
+	closing     chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewCustomerWebSocketHandler builds new CustomerWebSocketHandler
+func NewCustomerWebSocketHandler(
+	customerSvc service.CustomerService,
+	broker *event.Broker,
+	validator *auth.JwtValidator,
+	revoker auth.TokenRevoker,
+	cfg *config.WebSocketCfg,
+) *CustomerWebSocketHandler {
+	return &CustomerWebSocketHandler{
+		customerSvc: customerSvc,
+		broker:      broker,
+		validator:   validator,
+		revoker:     revoker,
+		cfg:         cfg,
+		retryAfter:  retryAfterSeconds(cfg.RetryAfter),
+		slots:       make(chan struct{}, cfg.MaxConnections),
+		closing:     make(chan struct{}),
+	}
+}
+
+// Close closes all connections and rejects new ones, it is meant to be called on server shutdown
+// since hijacked connections are not tracked by http server
+func (h *CustomerWebSocketHandler) Close() {
+	h.closeOnce.Do(func() {
+		close(h.closing)
+	})
+}
+
+// Stream upgrades connection to websocket
+// @Summary     Customers websocket
+// @Description Upgrades connection to websocket. JWT is passed in access_token query parameter or in the first frame {"type":"auth","token":"..."}.
+// @Description Server confirms authentication with {"type":"authenticated"} and then pushes change events of caller tenant customers
+// @Description as {"type":"event","eventId":"...","event":{...}}. Customer is requested with {"type":"get","id":"1","customerId":"..."}
+// @Description and returned as {"type":"customer","id":"1","customer":{...}}, failed requests get {"type":"error","id":"1","code":404,"message":"..."}.
+// @Description Connection is closed with policy violation once token expires or is revoked and with try again later once client falls behind events.
+// @Tags        customers
+// @Param       X-Tenant-ID  header string false "Caller tenant, must match tenant of access token if provided"
+// @Param       access_token query  string false "JWT, otherwise it is expected in the first frame"
+// @Success     101
+// @Failure     401 {object} errorEnvelope
+// @Failure     403 {object} errorEnvelope
+// @Failure     503 {object} errorEnvelope
+// @Router      /api/v1/customers/ws [get]
+func (h *CustomerWebSocketHandler) Stream(c echo.Context) error {
+	// closing is checked first, since slots are freed by connections closed on shutdown
+	select {
+	case <-h.closing:
+		c.Response().Header().Set("Retry-After", h.retryAfter)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "server is shutting down")
+	default:
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+	default:
+		c.Response().Header().Set("Retry-After", h.retryAfter)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "too many websocket connections, retry later")
+	}
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+
+	// token passed in url is verified before upgrade, so client gets plain http error
+	requestedTenant := c.Request().Header.Get(wsTenantHeader)
+	var claims *auth.JwtClaims
+	if token := c.QueryParam("access_token"); token != "" {
+		cl, err := h.authenticate(ctx, token)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+
+		if _, err := tenant.Resolve(cl.TenantID, requestedTenant); err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		claims = &cl
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil { // upgrader has already responded with error
+		logging.FromContext(ctx).Warnf("failed to upgrade connection to websocket - %v", err)
+		return nil
+	}
+	defer conn.Close()
+
+	if claims == nil {
+		cl, err := h.authenticateFirstFrame(ctx, conn)
+		if err != nil {
+			_ = conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+			_ = conn.WriteJSON(wsError("", http.StatusUnauthorized, err.Error()))
+			h.closeConn(conn, websocket.ClosePolicyViolation, "authentication failed")
+			return nil
+		}
+
+		if _, err := tenant.Resolve(cl.TenantID, requestedTenant); err != nil {
+			_ = conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+			_ = conn.WriteJSON(wsError("", http.StatusForbidden, err.Error()))
+			h.closeConn(conn, websocket.ClosePolicyViolation, "tenant mismatch")
+			return nil
+		}
+		claims = &cl
+	}
+
+	tenantID, _ := tenant.Resolve(claims.TenantID, "")
+	ctx = tenant.ContextWithID(ctx, tenantID)
+	ctx = auth.ContextWithClaims(ctx, *claims)
+	ctx = logging.ContextWithFields(ctx, logrus.Fields{logging.FieldPrincipal: claims.Subject})
+
+	// subscribed before authentication is confirmed, so events published after confirmation are pushed
+	sub := h.broker.Subscribe(tenantID)
+	defer h.broker.Unsubscribe(sub)
+
+	out := make(chan wsResponse, wsSendBufferSize)
+	out <- wsResponse{Type: wsFrameAuthenticated}
+
+	go h.write(ctx, cancel, conn, out)
+	go h.pushEvents(ctx, conn, sub, out)
+	go h.watchToken(ctx, conn, *claims)
+
+	h.serveRequests(ctx, conn, out)
+	return nil
+}
+
+// authenticateFirstFrame expects auth frame within auth timeout
+func (h *CustomerWebSocketHandler) authenticateFirstFrame(ctx context.Context, conn *websocket.Conn) (auth.JwtClaims, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(h.cfg.AuthTimeout)); err != nil {
+		return auth.JwtClaims{}, err
+	}
+
+	var req wsRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		return auth.JwtClaims{}, fmt.Errorf("auth frame is expected - %w", err)
+	}
+
+	if req.Type != wsFrameAuth || req.Token == "" {
+		return auth.JwtClaims{}, errors.New("auth frame with token is expected")
+	}
+	return h.authenticate(ctx, req.Token)
+}
+
+func (h *CustomerWebSocketHandler) authenticate(ctx context.Context, token string) (auth.JwtClaims, error) {
+	claims, err := h.validator.Verify(token)
+	if err != nil {
+		return auth.JwtClaims{}, fmt.Errorf("token verification failed - %w", err)
+	}
+
+	revoked, err := h.revoker.IsRevoked(ctx, claims)
+	if err != nil {
+		return auth.JwtClaims{}, fmt.Errorf("failed to check token revocation - %w", err)
+	}
+
+	if revoked {
+		return auth.JwtClaims{}, errors.New("token has been revoked")
+	}
+	return claims, nil
+}
+
+// serveRequests handles client frames until connection is closed, requests over rate limit are rejected
+func (h *CustomerWebSocketHandler) serveRequests(ctx context.Context, conn *websocket.Conn, out chan<- wsResponse) {
+	limiter := rate.NewLimiter(rate.Limit(h.cfg.RequestsPerSecond), h.cfg.RequestsBurst)
+
+	readTimeout := 2 * h.cfg.PingInterval
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && ctx.Err() == nil {
+				logging.FromContext(ctx).Debugf("websocket connection is closed - %v", err)
+			}
+			return
+		}
+
+		res := h.serveRequest(ctx, limiter, &req)
+
+		select {
+		case out <- res:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *CustomerWebSocketHandler) serveRequest(ctx context.Context, limiter *rate.Limiter, req *wsRequest) wsResponse {
+	if !limiter.Allow() {
+		return wsError(req.ID, http.StatusTooManyRequests, "rate limit exceeded")
+	}
+
+	switch req.Type {
+	case wsFrameGet:
+		if _, err := uuid.Parse(req.CustomerID); err != nil {
+			return wsError(req.ID, http.StatusBadRequest, "customerId must be a valid UUID")
+		}
+
+		c, err := h.customerSvc.FindByID(ctx, req.CustomerID)
+		if err != nil {
+			status := httpStatus(err)
+			if status >= http.StatusInternalServerError {
+				logging.FromContext(ctx).Errorf("failed to get customer %s over websocket - %v", req.CustomerID, err)
+				return wsError(req.ID, status, http.StatusText(status))
+			}
+			return wsError(req.ID, status, err.Error())
+		}
+
+		if c == nil {
+			return wsError(req.ID, http.StatusNotFound, fmt.Sprintf("customer %s not found", req.CustomerID))
+		}
+		return wsResponse{Type: wsFrameCustomer, ID: req.ID, Customer: customerV1View(c)}
+	case wsFrameAuth:
+		return wsError(req.ID, http.StatusBadRequest, "connection is already authenticated")
+	default:
+		return wsError(req.ID, http.StatusBadRequest, fmt.Sprintf("unknown frame type %s", req.Type))
+	}
+}
+
+// pushEvents sends events of caller tenant published after connection is established,
+// connection is closed once client falls behind and subscription is dropped
+func (h *CustomerWebSocketHandler) pushEvents(ctx context.Context, conn *websocket.Conn, sub *event.Subscription, out chan<- wsResponse) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Dropped():
+			h.closeConn(conn, websocket.CloseTryAgainLater, "customer events are not received fast enough")
+			return
+		case d := <-sub.Events():
+			select {
+			case out <- wsResponse{Type: wsFrameEvent, EventID: d.StreamID, Event: d.Event.External()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// watchToken closes connection once access token expires or is revoked, revocation is checked periodically.
+// Connection is kept if revocation can't be checked, so outage of revocation store doesn't disconnect all clients
+func (h *CustomerWebSocketHandler) watchToken(ctx context.Context, conn *websocket.Conn, claims auth.JwtClaims) {
+	var expired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	ticker := time.NewTicker(h.cfg.RevocationCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			h.closeConn(conn, websocket.ClosePolicyViolation, "token has expired")
+			return
+		case <-ticker.C:
+			revoked, err := h.revoker.IsRevoked(ctx, claims)
+			if err != nil {
+				logging.FromContext(ctx).Warnf("failed to check token revocation of websocket connection - %v", err)
+				continue
+			}
+
+			if revoked {
+				h.closeConn(conn, websocket.ClosePolicyViolation, "token has been revoked")
+				return
+			}
+		}
+	}
+}
+
+// write is the only writer of frames to connection, it pings client and closes connection on shutdown
+func (h *CustomerWebSocketHandler) write(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, out <-chan wsResponse) {
+	defer cancel()
+
+	ticker := time.NewTicker(h.cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.closing:
+			h.closeConn(conn, websocket.CloseGoingAway, "server is shutting down")
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.cfg.WriteTimeout)); err != nil {
+				conn.Close()
+				return
+			}
+		case res := <-out:
+			_ = conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteTimeout))
+			if err := conn.WriteJSON(res); err != nil {
+				logging.FromContext(ctx).Debugf("failed to write websocket frame - %v", err)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// closeConn sends close frame and closes connection, so reader is unblocked. It is safe to call concurrently
+// with writer, since control frames and close may be written concurrently with other frames
+func (h *CustomerWebSocketHandler) closeConn(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(h.cfg.WriteTimeout))
+	conn.Close()
+}
+
+func wsError(id string, code int, message string) wsResponse {
+	return wsResponse{Type: wsFrameError, ID: id, Code: code, Message: message}
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/middleware"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+const (
+	wsTestTimeout  = 5 * time.Second
+	wsTestIssuer   = "customers-api"
+	wsTestAudience = "customers"
+	wsTestSubject  = "john.walls@somemail.com"
+	wsTestTenant   = "acme"
+)
+
+type wsTestSuite struct {
+	suite.Suite
+	broker   *event.Broker
+	issuer   *auth.JwtIssuer
+	revoker  auth.TokenRevoker
+	handler  *CustomerWebSocketHandler
+	server   *httptest.Server
+	customer *model.Customer
+}
+
+func (s *wsTestSuite) SetupTest() {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	s.Require().NoError(err, "failed to generate jwt keys")
+
+	method := jwt.GetSigningMethod("EdDSA")
+	s.issuer = auth.NewJwtIssuer(wsTestIssuer, wsTestAudience, method, time.Minute, privateKey)
+	s.revoker = auth.NewInMemoryTokenRevoker(time.Minute)
+
+	s.broker = event.NewBroker(wsSendBufferSize)
+
+	customerSvc := service.NewCustomerService(repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant), cache.NewInMemoryCache(), config.CachePopulationFailureFail)
+	s.customer, err = customerSvc.Create(tenant.ContextWithID(context.Background(), "acme"), &model.Customer{
+		FirstName:  "John",
+		LastName:   "Walls",
+		Email:      "john.walls@somemail.com",
+		Importance: model.ImportanceHigh,
+	})
+	s.Require().NoError(err, "failed to create customer")
+
+	s.handler = NewCustomerWebSocketHandler(
+		customerSvc,
+		s.broker,
+		auth.NewJwtValidator(method, publicKey, wsTestIssuer, wsTestAudience),
+		s.revoker,
+		&config.WebSocketCfg{
+			MaxConnections:    1,
+			RequestsPerSecond: 1,
+			RequestsBurst:     3,
+			AuthTimeout:       time.Second,
+			PingInterval:      time.Second,
+			RevocationCheck:   20 * time.Millisecond,
+			WriteTimeout:      time.Second,
+			RetryAfter:        2 * time.Second,
+		},
+	)
+
+	app := echo.New()
+	app.GET("/api/v1/customers/ws", s.handler.Stream)
+	s.server = httptest.NewServer(app)
+}
+
+func (s *wsTestSuite) TearDownTest() {
+	s.handler.Close()
+	s.server.Close()
+}
+
+func (s *wsTestSuite) TestRequests() {
+	t := s.T()
+	require := s.Require()
+
+	conn, res := s.dial("acme", s.sign())
+	defer conn.Close()
+
+	t.Log("connection is authenticated with token passed in url")
+	{
+		require.Equal(http.StatusSwitchingProtocols, res.StatusCode, "connection must be upgraded")
+		require.Equal(wsFrameAuthenticated, s.read(conn).Type, "authentication must be confirmed")
+	}
+
+	t.Log("customer of caller tenant is returned")
+	{
+		s.write(conn, wsRequest{Type: wsFrameGet, ID: "1", CustomerID: s.customer.ID})
+
+		res := s.read(conn)
+		require.Equal(wsFrameCustomer, res.Type, "customer must be returned")
+		require.Equal("1", res.ID, "request id must be echoed")
+
+		customer, ok := res.Customer.(map[string]any)
+		require.True(ok, "customer must be object")
+		require.Equal(s.customer.ID, customer["id"], "incorrect customer")
+		require.Equal("John", customer["firstName"], "incorrect customer")
+	}
+
+	t.Log("missing customer is reported as not found")
+	{
+		s.write(conn, wsRequest{Type: wsFrameGet, ID: "2", CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692"})
+
+		res := s.read(conn)
+		require.Equal(wsFrameError, res.Type, "error must be returned")
+		require.Equal("2", res.ID, "request id must be echoed")
+		require.Equal(http.StatusNotFound, res.Code, "customer must not be found")
+	}
+
+	t.Log("invalid customer id is rejected")
+	{
+		s.write(conn, wsRequest{Type: wsFrameGet, ID: "3", CustomerID: "john"})
+
+		res := s.read(conn)
+		require.Equal(wsFrameError, res.Type, "error must be returned")
+		require.Equal(http.StatusBadRequest, res.Code, "customer id must be rejected")
+	}
+
+	t.Log("requests over rate limit are rejected")
+	{
+		s.write(conn, wsRequest{Type: wsFrameGet, ID: "4", CustomerID: s.customer.ID})
+
+		res := s.read(conn)
+		require.Equal(wsFrameError, res.Type, "error must be returned")
+		require.Equal("4", res.ID, "request id must be echoed")
+		require.Equal(http.StatusTooManyRequests, res.Code, "request must be rate limited")
+	}
+}
+
+func (s *wsTestSuite) TestEvents() {
+	t := s.T()
+	require := s.Require()
+
+	conn, _ := s.dial("acme", "")
+	defer conn.Close()
+
+	t.Log("connection is authenticated with auth frame")
+	{
+		s.write(conn, wsRequest{Type: wsFrameAuth, Token: s.sign()})
+		require.Equal(wsFrameAuthenticated, s.read(conn).Type, "authentication must be confirmed")
+	}
+
+	t.Log("events of caller tenant are pushed")
+	{
+		ctx := context.Background()
+		require.NoError(s.broker.PublishStream(ctx, "1700000000000-0", &model.CustomerEvent{ID: "c1d2e3f4-a5b6-4c7d-8e9f-0a1b2c3d4e5f", Type: model.CustomerCreated, TenantID: "globex"}), "failed to publish event")
+		require.NoError(s.broker.PublishStream(ctx, "1700000000001-0", &model.CustomerEvent{
+			ID:         "8d7c6b5a-4f3e-4d2c-9b1a-0f9e8d7c6b5a",
+			Type:       model.CustomerUpdated,
+			TenantID:   "acme",
+			CustomerID: s.customer.ID,
+			Customer:   s.customer,
+			Changes:    []model.CustomerChange{{Field: "importance", Old: model.ImportanceLow, New: model.ImportanceHigh}},
+		}), "failed to publish event")
+
+		res := s.read(conn)
+		require.Equal(wsFrameEvent, res.Type, "event must be pushed")
+		require.Equal("1700000000001-0", res.EventID, "events of other tenants must be skipped")
+		require.Equal(model.CustomerUpdated, res.Event.Type, "incorrect event")
+		require.Equal(s.customer.ID, res.Event.CustomerID, "incorrect event")
+		require.Equal(model.ImportanceHigh.External(), res.Event.Customer.Importance, "importance must be numbered as in http api")
+		require.Equal(float64(model.ImportanceHigh.External()), res.Event.Changes[0].New, "changed importance must be numbered as in http api")
+	}
+
+	t.Log("connection is closed with going away once handler is closed")
+	{
+		s.handler.Close()
+
+		_, _, err := conn.ReadMessage()
+		require.True(websocket.IsCloseError(err, websocket.CloseGoingAway), "going away close frame must be sent")
+
+		require.Eventually(func() bool {
+			return s.broker.Subscribers() == 0
+		}, wsTestTimeout, 10*time.Millisecond, "subscription must be cancelled after connection is closed")
+	}
+}
+
+func (s *wsTestSuite) TestTokenLifetime() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("connection is closed once token expires")
+	{
+		token, err := s.issuer.Sign(auth.Principal{Email: wsTestSubject, TenantID: wsTestTenant}, time.Now().Add(-time.Minute+2*time.Second)) // expiry is kept in seconds
+		require.NoError(err, "failed to sign jwt")
+
+		conn, _ := s.dial("acme", token.Signed)
+		defer conn.Close()
+		require.Equal(wsFrameAuthenticated, s.read(conn).Type, "authentication must be confirmed")
+
+		_, _, err = conn.ReadMessage()
+		require.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "policy violation close frame must be sent")
+
+		require.Eventually(func() bool {
+			return s.broker.Subscribers() == 0
+		}, wsTestTimeout, 10*time.Millisecond, "connection must be released")
+	}
+
+	t.Log("connection is closed once token is revoked")
+	{
+		conn, _ := s.dial("acme", s.sign())
+		defer conn.Close()
+		require.Equal(wsFrameAuthenticated, s.read(conn).Type, "authentication must be confirmed")
+
+		require.NoError(s.revoker.RevokeSubject(context.Background(), wsTestSubject, time.Now()), "failed to revoke token")
+
+		_, _, err := conn.ReadMessage()
+		require.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "policy violation close frame must be sent")
+	}
+}
+
+func (s *wsTestSuite) TestAuthentication() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("invalid token in url is rejected before upgrade")
+	{
+		_, res, err := s.dialer().Dial(s.url("invalid"), nil)
+		require.ErrorIs(err, websocket.ErrBadHandshake, "connection must not be upgraded")
+		require.Equal(http.StatusUnauthorized, res.StatusCode, "response status must be Unauthorized")
+		res.Body.Close()
+	}
+
+	t.Log("revoked token is rejected")
+	{
+		token := s.sign()
+		require.NoError(s.revoker.RevokeSubject(context.Background(), wsTestSubject, time.Now()), "failed to revoke token")
+
+		_, res, err := s.dialer().Dial(s.url(token), nil)
+		require.ErrorIs(err, websocket.ErrBadHandshake, "connection must not be upgraded")
+		require.Equal(http.StatusUnauthorized, res.StatusCode, "response status must be Unauthorized")
+		res.Body.Close()
+	}
+
+	t.Log("connection is closed if first frame is not auth one")
+	{
+		conn, _ := s.dial("acme", "")
+		defer conn.Close()
+
+		s.write(conn, wsRequest{Type: wsFrameGet, ID: "1", CustomerID: s.customer.ID})
+
+		res := s.read(conn)
+		require.Equal(wsFrameError, res.Type, "error must be returned")
+		require.Equal(http.StatusUnauthorized, res.Code, "response code must be Unauthorized")
+
+		_, _, err := conn.ReadMessage()
+		require.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "policy violation close frame must be sent")
+	}
+}
+
+func (s *wsTestSuite) TestTenant() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("header not matching tenant of token in url is rejected before upgrade")
+	{
+		header := http.Header{}
+		header.Set(middleware.TenantHeader, "globex")
+
+		_, res, err := s.dialer().Dial(s.url(s.sign()), header)
+		require.ErrorIs(err, websocket.ErrBadHandshake, "connection must not be upgraded")
+		require.Equal(http.StatusForbidden, res.StatusCode, "response status must be Forbidden")
+		res.Body.Close()
+	}
+
+	t.Log("header not matching tenant of token in auth frame closes connection")
+	{
+		conn, _ := s.dial("globex", "")
+		defer conn.Close()
+
+		s.write(conn, wsRequest{Type: wsFrameAuth, Token: s.sign()})
+
+		res := s.read(conn)
+		require.Equal(wsFrameError, res.Type, "error must be returned")
+		require.Equal(http.StatusForbidden, res.Code, "response code must be Forbidden")
+
+		_, _, err := conn.ReadMessage()
+		require.True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), "policy violation close frame must be sent")
+	}
+}
+
+func (s *wsTestSuite) TestMaxConnections() {
+	t := s.T()
+	require := s.Require()
+
+	conn, _ := s.dial("acme", s.sign())
+	defer conn.Close()
+	require.Equal(wsFrameAuthenticated, s.read(conn).Type, "authentication must be confirmed")
+
+	t.Log("connections over limit are rejected")
+	{
+		_, res, err := s.dialer().Dial(s.url(s.sign()), nil)
+		require.ErrorIs(err, websocket.ErrBadHandshake, "connection must not be upgraded")
+		require.Equal(http.StatusServiceUnavailable, res.StatusCode, "response status must be Service Unavailable")
+		require.Equal("2", res.Header.Get("Retry-After"), "retry after must be advertised")
+		res.Body.Close()
+	}
+
+	t.Log("connections are rejected once handler is closed")
+	{
+		s.handler.Close()
+
+		_, res, err := s.dialer().Dial(s.url(s.sign()), nil)
+		require.ErrorIs(err, websocket.ErrBadHandshake, "connection must not be upgraded")
+		require.Equal(http.StatusServiceUnavailable, res.StatusCode, "response status must be Service Unavailable")
+		require.Equal("2", res.Header.Get("Retry-After"), "retry after must be advertised")
+		res.Body.Close()
+	}
+}
+
+func (s *wsTestSuite) sign() string {
+	token, err := s.issuer.Sign(auth.Principal{Email: wsTestSubject, TenantID: wsTestTenant}, time.Now())
+	s.Require().NoError(err, "failed to sign jwt")
+	return token.Signed
+}
+
+func (s *wsTestSuite) url(token string) string {
+	u := "ws" + strings.TrimPrefix(s.server.URL, "http") + "/api/v1/customers/ws"
+	if token != "" {
+		u += "?access_token=" + token
+	}
+	return u
+}
+
+func (s *wsTestSuite) dialer() *websocket.Dialer {
+	return &websocket.Dialer{HandshakeTimeout: wsTestTimeout}
+}
+
+func (s *wsTestSuite) dial(tenantID string, token string) (*websocket.Conn, *http.Response) {
+	header := http.Header{}
+	header.Set(middleware.TenantHeader, tenantID)
+
+	conn, res, err := s.dialer().Dial(s.url(token), header)
+	s.Require().NoError(err, "failed to open websocket")
+	return conn, res
+}
+
+func (s *wsTestSuite) write(conn *websocket.Conn, req wsRequest) {
+	s.Require().NoError(conn.WriteJSON(req), "failed to write frame")
+}
+
+func (s *wsTestSuite) read(conn *websocket.Conn) wsResponse {
+	s.Require().NoError(conn.SetReadDeadline(time.Now().Add(wsTestTimeout)), "failed to set read deadline")
+
+	var res wsResponse
+	s.Require().NoError(conn.ReadJSON(&res), "failed to read frame")
+	return res
+}
+
+// start websocket test suite
+func TestWsTestSuite(t *testing.T) {
+	suite.Run(t, new(wsTestSuite))
+}
@@ -3,34 +3,78 @@ package interceptors
 import (
 	"context"
 
+	"github.com/sirupsen/logrus"
 	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/pkg/logging"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-// AuthUnaryInterceptor verifies that jwt is provided in metadata and valid
-func AuthUnaryInterceptor(validator *auth.JwtValidator, applicables ...UnaryInterceptorApplicable) grpc.UnaryServerInterceptor {
+// AuthUnaryInterceptor verifies that jwt is provided in metadata, valid and not revoked
+func AuthUnaryInterceptor(validator *auth.JwtValidator, revoker auth.TokenRevoker, applicables ...UnaryInterceptorApplicable) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
 		if !isUnaryInterceptorApplicable(info, applicables...) {
 			return h(ctx, req)
 		}
 
-		headers, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, status.Error(codes.Unauthenticated, "no auth info provided")
+		claims, err := authenticate(ctx, validator, revoker)
+		if err != nil {
+			return nil, err
 		}
 
-		tokenHdr := headers.Get("accessToken")
-		if len(tokenHdr) == 0 {
-			return nil, status.Error(codes.Unauthenticated, "accessToken header is missing")
+		return h(contextWithClaims(ctx, claims), req)
+	}
+}
+
+// AuthStreamInterceptor verifies that jwt is provided in metadata, valid and not revoked for streaming calls
+func AuthStreamInterceptor(validator *auth.JwtValidator, revoker auth.TokenRevoker, applicables ...StreamInterceptorApplicable) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		if !isStreamInterceptorApplicable(info, applicables...) {
+			return h(srv, ss)
 		}
 
-		if _, err := validator.Verify(tokenHdr[0]); err != nil {
-			return nil, status.Errorf(codes.Unauthenticated, "invalid access token provided - %v", err)
+		ctx := ss.Context()
+		claims, err := authenticate(ctx, validator, revoker)
+		if err != nil {
+			return err
 		}
 
-		return h(ctx, req)
+		return h(srv, &contextServerStream{ServerStream: ss, ctx: contextWithClaims(ctx, claims)})
 	}
 }
+
+// contextWithClaims puts claims to context and adds their subject to request scoped log fields
+func contextWithClaims(ctx context.Context, claims auth.JwtClaims) context.Context {
+	ctx = auth.ContextWithClaims(ctx, claims)
+	return logging.ContextWithFields(ctx, logrus.Fields{logging.FieldPrincipal: claims.Subject})
+}
+
+func authenticate(ctx context.Context, validator *auth.JwtValidator, revoker auth.TokenRevoker) (auth.JwtClaims, error) {
+	headers, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return auth.JwtClaims{}, status.Error(codes.Unauthenticated, "no auth info provided")
+	}
+
+	tokenHdr := headers.Get("accessToken")
+	if len(tokenHdr) == 0 {
+		return auth.JwtClaims{}, status.Error(codes.Unauthenticated, "accessToken header is missing")
+	}
+
+	claims, err := validator.Verify(tokenHdr[0])
+	if err != nil {
+		return auth.JwtClaims{}, status.Errorf(codes.Unauthenticated, "invalid access token provided - %v", err)
+	}
+
+	revoked, err := revoker.IsRevoked(ctx, claims)
+	if err != nil {
+		return auth.JwtClaims{}, status.Errorf(codes.Internal, "failed to check token revocation - %v", err)
+	}
+
+	if revoked {
+		return auth.JwtClaims{}, status.Error(codes.Unauthenticated, "access token has been revoked")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,26 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+
+	"github.com/umalmyha/customers/internal/audit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// ClientIPUnaryInterceptor puts client ip of peer to context
+func ClientIPUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return h(ctx, req)
+		}
+
+		ip := p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		return h(audit.ContextWithClientIP(ctx, ip), req)
+	}
+}
@@ -7,11 +7,20 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/pkg/logging"
+	"github.com/umalmyha/customers/pkg/redact"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+const errorDomain = "customers"
+
 func httpToGrpcCode(s int) codes.Code {
 	switch s {
 	case http.StatusBadRequest:
@@ -20,6 +29,14 @@ func httpToGrpcCode(s int) codes.Code {
 		return codes.Unauthenticated
 	case http.StatusForbidden:
 		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
 	default:
 		return codes.Internal
 	}
@@ -36,22 +53,92 @@ func ErrorUnaryInterceptor(applicables ...UnaryInterceptorApplicable) grpc.Unary
 		if err == nil {
 			return res, nil
 		}
-		logrus.Errorf("error occurred on grpc request processing - %v", err)
+		requestID := requestid.IDFromContext(ctx)
+		logging.FromContext(ctx).WithFields(logrus.Fields{
+			"payload":              redact.Value(req),
+			logging.FieldRequestID: requestID,
+		}).Errorf("error occurred on grpc request %s processing - %s", info.FullMethod, redact.Text(err.Error()))
 
-		if _, ok := status.FromError(err); ok { // it is already grpc status error
-			return nil, err
-		}
+		return nil, withRequestInfo(grpcError(err), requestID)
+	}
+}
 
-		code := codes.Internal
+// ErrorStreamInterceptor converts error retrieved from stream handler to gRPC error with corresponding code
+func ErrorStreamInterceptor(applicables ...StreamInterceptorApplicable) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		if !isStreamInterceptorApplicable(info, applicables...) {
+			return h(srv, ss)
+		}
 
-		var echoErr *echo.HTTPError
-		if errors.As(err, &echoErr) {
-			code = httpToGrpcCode(echoErr.Code)
+		err := h(srv, ss)
+		if err == nil {
+			return nil
 		}
+		requestID := requestid.IDFromContext(ss.Context())
+		logging.FromContext(ss.Context()).WithField(logging.FieldRequestID, requestID).Errorf("error occurred on grpc stream %s processing - %s", info.FullMethod, redact.Text(err.Error()))
+
+		return withRequestInfo(grpcError(err), requestID)
+	}
+}
+
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok { // it is already grpc status error
+		return err
+	}
 
-		if code == codes.Internal {
-			return nil, status.Error(code, "Internal server error")
+	var businessErr *appErrors.BusinessErr
+	var notFoundErr *appErrors.EntryNotFoundErr
+	var unavailableErr *appErrors.UnavailableErr
+	var echoErr *echo.HTTPError
+	switch {
+	case errors.Is(err, appErrors.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, "Unauthorized") // reason of rejection is never exposed
+	case errors.As(err, &notFoundErr):
+		st := status.New(codes.NotFound, notFoundErr.Error())
+		return withDetails(st, &errdetails.ResourceInfo{ResourceType: notFoundErr.Entry, ResourceName: notFoundErr.ID})
+	case errors.As(err, &businessErr):
+		code, reason := businessErrCode(businessErr)
+		st := status.New(code, businessErr.Error())
+		return withDetails(st, &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	case errors.As(err, &unavailableErr):
+		st := status.New(codes.Unavailable, unavailableErr.Error())
+		return withDetails(st, &errdetails.RetryInfo{RetryDelay: durationpb.New(unavailableErr.RetryAfter)})
+	case errors.As(err, &echoErr): // handlers still raise echo errors for transport level checks
+		if code := httpToGrpcCode(echoErr.Code); code != codes.Internal {
+			return status.Error(code, err.Error())
 		}
-		return nil, status.Error(code, err.Error())
 	}
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+// businessErrCode returns gRPC code and machine readable reason of business error
+func businessErrCode(err *appErrors.BusinessErr) (codes.Code, string) {
+	switch {
+	case errors.Is(err, appErrors.ErrDuplicateEmail):
+		return codes.AlreadyExists, "DUPLICATE_EMAIL"
+	case errors.Is(err, appErrors.ErrConcurrentModification):
+		return codes.Aborted, "CONCURRENT_MODIFICATION"
+	case errors.Is(err, appErrors.ErrInvalidArgument):
+		return codes.InvalidArgument, "INVALID_ARGUMENT"
+	case errors.Is(err, appErrors.ErrForbidden):
+		return codes.PermissionDenied, "FORBIDDEN"
+	default:
+		return codes.FailedPrecondition, "FAILED_PRECONDITION"
+	}
+}
+
+// withRequestInfo attaches request id to status details, so client can quote it to support even for internal errors
+func withRequestInfo(err error, requestID string) error {
+	if requestID == "" {
+		return err
+	}
+	return withDetails(status.Convert(err), &errdetails.RequestInfo{RequestId: requestID})
+}
+
+// withDetails attaches details to status, status is returned as is if details can't be marshaled
+func withDetails(st *status.Status, details ...protoiface.MessageV1) error {
+	if detailed, err := st.WithDetails(details...); err == nil {
+		return detailed.Err()
+	}
+	return st.Err()
 }
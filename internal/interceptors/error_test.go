@@ -0,0 +1,227 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/requestid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type errorInterceptorTestSuite struct {
+	suite.Suite
+	interceptor grpc.UnaryServerInterceptor
+	info        *grpc.UnaryServerInfo
+}
+
+func (s *errorInterceptorTestSuite) SetupTest() {
+	s.interceptor = ErrorUnaryInterceptor()
+	s.info = &grpc.UnaryServerInfo{FullMethod: "/customers.CustomerService/Create"}
+}
+
+func (s *errorInterceptorTestSuite) TestErrorUnaryInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	expectations := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{
+			name:    "duplicate email",
+			err:     appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, "customer with email john@email.com already exist"),
+			code:    codes.AlreadyExists,
+			message: "customer with email john@email.com already exist",
+		},
+		{
+			name:    "concurrent modification",
+			err:     appErrors.NewBusinessErr(appErrors.ErrConcurrentModification, "customer with email john@email.com was modified concurrently"),
+			code:    codes.Aborted,
+			message: "customer with email john@email.com was modified concurrently",
+		},
+		{
+			name:    "invalid argument",
+			err:     appErrors.NewBusinessErr(appErrors.ErrInvalidArgument, "fingerprint must be a valid uuid"),
+			code:    codes.InvalidArgument,
+			message: "fingerprint must be a valid uuid",
+		},
+		{
+			name:    "forbidden",
+			err:     appErrors.NewBusinessErr(appErrors.ErrForbidden, "signup is disabled, contact administrator to get an account"),
+			code:    codes.PermissionDenied,
+			message: "signup is disabled, contact administrator to get an account",
+		},
+		{
+			name:    "business rule violation",
+			err:     appErrors.NewBusinessErr(nil, "refresh token already expired"),
+			code:    codes.FailedPrecondition,
+			message: "refresh token already expired",
+		},
+		{
+			name:    "entry not found",
+			err:     appErrors.NewEntryNotFoundErr("user", "42"),
+			code:    codes.NotFound,
+			message: "user 42 not found",
+		},
+		{
+			name:    "unauthorized",
+			err:     appErrors.ErrUnauthorized,
+			code:    codes.Unauthenticated,
+			message: "Unauthorized",
+		},
+		{
+			name:    "wrapped error chain",
+			err:     fmt.Errorf("transaction failed - %w", fmt.Errorf("signup failed - %w", appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, "user with email john@email.com already exist"))),
+			code:    codes.AlreadyExists,
+			message: "user with email john@email.com already exist",
+		},
+		{
+			name:    "echo error",
+			err:     echo.NewHTTPError(http.StatusConflict, "image logo.png already exists"),
+			code:    codes.AlreadyExists,
+			message: "code=409, message=image logo.png already exists",
+		},
+		{
+			name:    "internal echo error",
+			err:     echo.NewHTTPError(http.StatusInternalServerError, "disk is full"),
+			code:    codes.Internal,
+			message: "Internal server error",
+		},
+		{
+			name:    "unknown error",
+			err:     errors.New("postgres: connection to 10.0.0.5 refused"),
+			code:    codes.Internal,
+			message: "Internal server error",
+		},
+		{
+			name:    "grpc status error",
+			err:     status.Error(codes.PermissionDenied, "admin role required"),
+			code:    codes.PermissionDenied,
+			message: "admin role required",
+		},
+	}
+
+	for _, e := range expectations {
+		t.Logf("%s is converted to %s", e.name, e.code)
+		{
+			st := s.intercept(e.err)
+			require.Equal(e.code, st.Code(), "incorrect code for %s", e.name)
+			require.Equal(e.message, st.Message(), "incorrect message for %s", e.name)
+		}
+	}
+
+	t.Log("business error details carry reason")
+	{
+		st := s.intercept(fmt.Errorf("signup failed - %w", appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, "user with email john@email.com already exist")))
+		require.Len(st.Details(), 1, "single detail must be attached")
+
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(ok, "detail must be error info")
+		require.Equal("DUPLICATE_EMAIL", info.Reason, "incorrect reason")
+		require.Equal(errorDomain, info.Domain, "incorrect domain")
+	}
+
+	t.Log("not found error details carry resource")
+	{
+		st := s.intercept(appErrors.NewEntryNotFoundErr("user", "42"))
+		require.Len(st.Details(), 1, "single detail must be attached")
+
+		info, ok := st.Details()[0].(*errdetails.ResourceInfo)
+		require.True(ok, "detail must be resource info")
+		require.Equal("user", info.ResourceType, "incorrect resource type")
+		require.Equal("42", info.ResourceName, "incorrect resource name")
+	}
+
+	t.Log("internal error has no details")
+	{
+		st := s.intercept(errors.New("postgres: connection to 10.0.0.5 refused"))
+		require.Empty(st.Details(), "internal error details must not be exposed")
+	}
+
+	t.Log("unavailable error is mapped to unavailable code with retry delay")
+	{
+		st := s.intercept(appErrors.NewUnavailableErr("postgres is temporarily unavailable", 2*time.Second, errors.New("circuit breaker is open")))
+		require.Equal(codes.Unavailable, st.Code(), "unavailable code must be returned")
+		require.Equal("postgres is temporarily unavailable", st.Message(), "incorrect message")
+		require.Len(st.Details(), 1, "retry info must be attached")
+
+		info, ok := st.Details()[0].(*errdetails.RetryInfo)
+		require.True(ok, "detail must be retry info")
+		require.Equal(2*time.Second, info.RetryDelay.AsDuration(), "incorrect retry delay")
+	}
+
+	t.Log("request id is attached to details and logged")
+	{
+		logHook := logrusTest.NewGlobal()
+		defer logHook.Reset()
+
+		ctx := requestid.ContextWithID(context.Background(), "0b7d6c1e-quoted-by-user")
+		_, err := s.interceptor(ctx, nil, s.info, func(context.Context, any) (any, error) {
+			return nil, errors.New("postgres: connection to 10.0.0.5 refused")
+		})
+
+		st, ok := status.FromError(err)
+		require.True(ok, "error must be grpc status error")
+		require.Equal(codes.Internal, st.Code(), "internal error must be raised")
+		require.Len(st.Details(), 1, "only request info must be attached to internal error")
+
+		info, ok := st.Details()[0].(*errdetails.RequestInfo)
+		require.True(ok, "detail must be request info")
+		require.Equal("0b7d6c1e-quoted-by-user", info.RequestId, "incorrect request id")
+
+		entry := logHook.LastEntry()
+		require.NotNil(entry, "error must be logged")
+		require.Equal("0b7d6c1e-quoted-by-user", entry.Data["requestId"], "request id must be logged")
+	}
+
+	t.Log("request id is appended to existing details")
+	{
+		ctx := requestid.ContextWithID(context.Background(), "0b7d6c1e-quoted-by-user")
+		_, err := s.interceptor(ctx, nil, s.info, func(context.Context, any) (any, error) {
+			return nil, appErrors.NewEntryNotFoundErr("user", "42")
+		})
+
+		st := status.Convert(err)
+		require.Len(st.Details(), 2, "resource and request info must be attached")
+		require.IsType(&errdetails.ResourceInfo{}, st.Details()[0], "resource info must be kept")
+		require.IsType(&errdetails.RequestInfo{}, st.Details()[1], "request info must be appended")
+	}
+
+	t.Log("successful response is passed through")
+	{
+		res, err := s.interceptor(context.Background(), nil, s.info, func(context.Context, any) (any, error) {
+			return "ok", nil
+		})
+		require.NoError(err, "no error must be raised")
+		require.Equal("ok", res, "response must be passed through")
+	}
+}
+
+func (s *errorInterceptorTestSuite) intercept(handlerErr error) *status.Status {
+	_, err := s.interceptor(context.Background(), nil, s.info, func(context.Context, any) (any, error) {
+		return nil, handlerErr
+	})
+	s.Require().Error(err, "error must be raised")
+
+	st, ok := status.FromError(err)
+	s.Require().True(ok, "error must be grpc status error")
+	return st
+}
+
+// start error interceptor test suite
+func TestErrorInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(errorInterceptorTestSuite))
+}
@@ -0,0 +1,37 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+// NewInFlightGauge builds and registers gauge of gRPC requests being processed, streams are counted while they are open
+func NewInFlightGauge(reg prometheus.Registerer) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "grpc_server_in_flight_requests",
+		Help: "Number of gRPC requests and streams being processed",
+	})
+	reg.MustRegister(g)
+
+	return g
+}
+
+// InFlightUnaryInterceptor tracks number of requests being processed in gauge, gauge is decremented even if handler panics
+func InFlightUnaryInterceptor(gauge prometheus.Gauge) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		gauge.Inc()
+		defer gauge.Dec()
+		return h(ctx, req)
+	}
+}
+
+// InFlightStreamInterceptor tracks number of open streams in gauge, gauge is decremented even if handler panics
+func InFlightStreamInterceptor(gauge prometheus.Gauge) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		gauge.Inc()
+		defer gauge.Dec()
+		return h(srv, ss)
+	}
+}
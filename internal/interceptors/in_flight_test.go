@@ -0,0 +1,114 @@
+package interceptors
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type inFlightInterceptorTestSuite struct {
+	suite.Suite
+	gauge prometheus.Gauge
+	info  *grpc.UnaryServerInfo
+}
+
+func (s *inFlightInterceptorTestSuite) SetupTest() {
+	s.gauge = NewInFlightGauge(prometheus.NewRegistry())
+	s.info = &grpc.UnaryServerInfo{FullMethod: "/customers.CustomerService/Create"}
+}
+
+func (s *inFlightInterceptorTestSuite) TestInFlightUnaryInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	interceptor := InFlightUnaryInterceptor(s.gauge)
+
+	t.Log("requests are counted while they are processed")
+	{
+		const requests = 3
+
+		var started, done sync.WaitGroup
+		started.Add(requests)
+		release := make(chan struct{})
+
+		for i := 0; i < requests; i++ {
+			done.Add(1)
+			go func() {
+				defer done.Done()
+				_, _ = interceptor(context.Background(), nil, s.info, func(ctx context.Context, req any) (any, error) {
+					started.Done()
+					<-release
+					return nil, nil
+				})
+			}()
+		}
+
+		started.Wait()
+		require.Equal(float64(requests), testutil.ToFloat64(s.gauge), "all requests must be in flight")
+
+		close(release)
+		done.Wait()
+		require.Zero(testutil.ToFloat64(s.gauge), "gauge must return to zero once requests are completed")
+	}
+
+	t.Log("failed request is not counted once it is completed")
+	{
+		_, err := interceptor(context.Background(), nil, s.info, func(ctx context.Context, req any) (any, error) {
+			return nil, status.Error(codes.NotFound, "customer not found")
+		})
+		require.Error(err, "error must be returned")
+		require.Zero(testutil.ToFloat64(s.gauge), "gauge must return to zero once request is failed")
+	}
+
+	t.Log("panicked request is not counted once it is recovered")
+	{
+		recovery := RecoveryUnaryInterceptor()
+		_, err := interceptor(context.Background(), nil, s.info, func(ctx context.Context, req any) (any, error) {
+			return recovery(ctx, req, s.info, func(context.Context, any) (any, error) {
+				panic("nil map assignment")
+			})
+		})
+		require.Equal(codes.Internal, status.Code(err), "panic must be turned into internal error")
+		require.Zero(testutil.ToFloat64(s.gauge), "gauge must return to zero once request is panicked")
+	}
+}
+
+func (s *inFlightInterceptorTestSuite) TestInFlightStreamInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	interceptor := InFlightStreamInterceptor(s.gauge)
+	info := &grpc.StreamServerInfo{FullMethod: "/customers.CustomerService/Watch"}
+
+	t.Log("stream is counted while it is open")
+	{
+		err := interceptor(nil, nil, info, func(any, grpc.ServerStream) error {
+			require.Equal(1.0, testutil.ToFloat64(s.gauge), "stream must be in flight")
+			return nil
+		})
+		require.NoError(err, "no error must be raised")
+		require.Zero(testutil.ToFloat64(s.gauge), "gauge must return to zero once stream is closed")
+	}
+
+	t.Log("panicked stream is not counted even if panic isn't recovered")
+	{
+		require.Panics(func() {
+			_ = interceptor(nil, nil, info, func(any, grpc.ServerStream) error {
+				panic("nil map assignment")
+			})
+		}, "panic must be propagated")
+		require.Zero(testutil.ToFloat64(s.gauge), "gauge must return to zero once stream is panicked")
+	}
+}
+
+// start in-flight interceptor test suite
+func TestInFlightInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(inFlightInterceptorTestSuite))
+}
@@ -1,6 +1,7 @@
 package interceptors
 
 import (
+	"context"
 	"strings"
 
 	"google.golang.org/grpc"
@@ -25,6 +26,19 @@ func isUnaryInterceptorApplicable(info *grpc.UnaryServerInfo, fns ...UnaryInterc
 	return true
 }
 
+func isStreamInterceptorApplicable(info *grpc.StreamServerInfo, fns ...StreamInterceptorApplicable) bool {
+	if len(fns) == 0 {
+		return true
+	}
+
+	for _, fn := range fns {
+		if !fn(info) {
+			return false
+		}
+	}
+	return true
+}
+
 // UnaryApplicableForService adds verification that interceptor is executed only for specific service
 func UnaryApplicableForService(svc string) UnaryInterceptorApplicable {
 	return func(info *grpc.UnaryServerInfo) bool {
@@ -32,3 +46,32 @@ func UnaryApplicableForService(svc string) UnaryInterceptorApplicable {
 		return strings.Contains(info.FullMethod, svc)
 	}
 }
+
+// StreamApplicableForService adds verification that stream interceptor is executed only for specific service
+func StreamApplicableForService(svc string) StreamInterceptorApplicable {
+	return func(info *grpc.StreamServerInfo) bool {
+		return strings.Contains(info.FullMethod, svc)
+	}
+}
+
+// StreamApplicableForAnyService adds verification that stream interceptor is executed only for one of services
+func StreamApplicableForAnyService(svcs ...string) StreamInterceptorApplicable {
+	return func(info *grpc.StreamServerInfo) bool {
+		for _, svc := range svcs {
+			if strings.Contains(info.FullMethod, svc) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// contextServerStream is server stream with context replaced by interceptor
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
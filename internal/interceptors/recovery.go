@@ -0,0 +1,40 @@
+package interceptors
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/umalmyha/customers/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryInterceptor turns panic of handler into internal error, so single request doesn't crash the server
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, info.FullMethod, r)
+			}
+		}()
+		return h(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor turns panic of stream handler into internal error
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return h(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, fullMethod string, r any) error {
+	logging.FromContext(ctx).Errorf("panic recovered while processing grpc request %s - %v\n%s", fullMethod, r, debug.Stack())
+	return status.Error(codes.Internal, "Internal server error")
+}
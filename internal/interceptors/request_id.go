@@ -0,0 +1,57 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDUnaryInterceptor puts request id and request scoped log fields to context and responds id in header metadata,
+// id is taken from metadata if client passed it, otherwise new one is generated
+func RequestIDUnaryInterceptor(header string) grpc.UnaryServerInterceptor {
+	key := strings.ToLower(header)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		id := incomingRequestID(ctx, key)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(key, id)); err != nil {
+			logrus.Warnf("failed to respond request id of grpc request %s - %v", info.FullMethod, err)
+		}
+		return h(contextWithRequestID(ctx, id, info.FullMethod), req)
+	}
+}
+
+// RequestIDStreamInterceptor puts request id to stream context and responds it in header metadata
+func RequestIDStreamInterceptor(header string) grpc.StreamServerInterceptor {
+	key := strings.ToLower(header)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		id := incomingRequestID(ss.Context(), key)
+		if err := ss.SetHeader(metadata.Pairs(key, id)); err != nil {
+			logrus.Warnf("failed to respond request id of grpc stream %s - %v", info.FullMethod, err)
+		}
+		return h(srv, &contextServerStream{ServerStream: ss, ctx: contextWithRequestID(ss.Context(), id, info.FullMethod)})
+	}
+}
+
+// contextWithRequestID puts request id to context along with request scoped log fields
+func contextWithRequestID(ctx context.Context, id string, fullMethod string) context.Context {
+	ctx = requestid.ContextWithID(ctx, id)
+	return logging.ContextWithFields(ctx, logrus.Fields{
+		logging.FieldRequestID: id,
+		logging.FieldTransport: logging.TransportGRPC,
+		logging.FieldMethod:    fullMethod,
+	})
+}
+
+func incomingRequestID(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return uuid.NewString()
+}
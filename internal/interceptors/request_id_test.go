@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type requestIDInterceptorTestSuite struct {
+	suite.Suite
+	interceptor grpc.UnaryServerInterceptor
+	info        *grpc.UnaryServerInfo
+}
+
+func (s *requestIDInterceptorTestSuite) SetupTest() {
+	s.interceptor = RequestIDUnaryInterceptor("X-Correlation-Id")
+	s.info = &grpc.UnaryServerInfo{FullMethod: "/customers.CustomerService/Create"}
+}
+
+func (s *requestIDInterceptorTestSuite) TestRequestIDUnaryInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("request id is taken from metadata")
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "6f1c2b7e-support-quoted"))
+		require.Equal("6f1c2b7e-support-quoted", s.intercept(ctx), "request id from metadata must be put to context")
+	}
+
+	t.Log("request id is generated if metadata is missing")
+	{
+		first := s.intercept(context.Background())
+		second := s.intercept(context.Background())
+		require.NotEmpty(first, "request id must be generated")
+		require.NotEqual(first, second, "request id must be unique")
+	}
+
+	t.Log("request scoped fields are put to context")
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "6f1c2b7e-support-quoted"))
+
+		var fields logrus.Fields
+		_, err := s.interceptor(ctx, nil, s.info, func(ctx context.Context, _ any) (any, error) {
+			fields = logging.FieldsFromContext(ctx)
+			return nil, nil
+		})
+		require.NoError(err, "no error must be raised")
+		require.Equal(logrus.Fields{
+			logging.FieldRequestID: "6f1c2b7e-support-quoted",
+			logging.FieldTransport: logging.TransportGRPC,
+			logging.FieldMethod:    "/customers.CustomerService/Create",
+		}, fields, "request scoped fields must be put to context")
+	}
+}
+
+func (s *requestIDInterceptorTestSuite) intercept(ctx context.Context) string {
+	var requestID string
+	_, err := s.interceptor(ctx, nil, s.info, func(ctx context.Context, _ any) (any, error) {
+		requestID = requestid.IDFromContext(ctx)
+		return nil, nil
+	})
+	s.Require().NoError(err, "no error must be raised")
+	return requestID
+}
+
+// start request id interceptor test suite
+func TestRequestIDInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(requestIDInterceptorTestSuite))
+}
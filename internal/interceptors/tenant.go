@@ -0,0 +1,65 @@
+package interceptors
+
+import (
+	"context"
+
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TenantUnaryInterceptor puts caller tenant from verified jwt claims to context, so it must follow auth interceptor.
+// tenantId metadata is optional, call is denied if it doesn't match tenant of jwt
+func TenantUnaryInterceptor(applicables ...UnaryInterceptorApplicable) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		if !isUnaryInterceptorApplicable(info, applicables...) {
+			return h(ctx, req)
+		}
+
+		ctx, err := contextWithTenant(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return h(ctx, req)
+	}
+}
+
+// TenantStreamInterceptor puts caller tenant from verified jwt claims to context of streaming calls
+func TenantStreamInterceptor(applicables ...StreamInterceptorApplicable) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+		if !isStreamInterceptorApplicable(info, applicables...) {
+			return h(srv, ss)
+		}
+
+		ctx, err := contextWithTenant(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return h(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func contextWithTenant(ctx context.Context) (context.Context, error) {
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "tenant can't be resolved for unauthenticated call")
+	}
+
+	var requested string
+	if headers, ok := metadata.FromIncomingContext(ctx); ok {
+		if tenantHdr := headers.Get("tenantId"); len(tenantHdr) > 0 {
+			requested = tenantHdr[0]
+		}
+	}
+
+	id, err := tenant.Resolve(claims.TenantID, requested)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "tenantId doesn't match tenant of access token")
+	}
+	return tenant.ContextWithID(ctx, id), nil
+}
@@ -0,0 +1,83 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tenantInterceptorTestSuite struct {
+	suite.Suite
+	interceptor grpc.UnaryServerInterceptor
+	info        *grpc.UnaryServerInfo
+}
+
+func (s *tenantInterceptorTestSuite) SetupTest() {
+	s.interceptor = TenantUnaryInterceptor()
+	s.info = &grpc.UnaryServerInfo{FullMethod: "/customers.CustomerService/Create"}
+}
+
+func (s *tenantInterceptorTestSuite) TestTenantUnaryInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("tenant is taken from jwt claims")
+	{
+		ctx := auth.ContextWithClaims(context.Background(), auth.JwtClaims{TenantID: "acme"})
+		tenantID, err := s.intercept(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Equal("acme", tenantID, "tenant from claims must be put to context")
+	}
+
+	t.Log("metadata matching tenant of jwt claims is accepted")
+	{
+		ctx := auth.ContextWithClaims(context.Background(), auth.JwtClaims{TenantID: "acme"})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("tenantId", "acme"))
+		tenantID, err := s.intercept(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Equal("acme", tenantID, "tenant from claims must be put to context")
+	}
+
+	t.Log("default tenant is used if claims have no tenant")
+	{
+		tenantID, err := s.intercept(auth.ContextWithClaims(context.Background(), auth.JwtClaims{}))
+		require.NoError(err, "no error must be raised")
+		require.Equal(tenant.DefaultID, tenantID, "default tenant must be used")
+	}
+
+	t.Log("metadata not matching tenant of jwt claims is denied")
+	{
+		ctx := auth.ContextWithClaims(context.Background(), auth.JwtClaims{TenantID: "acme"})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("tenantId", "globex"))
+		_, err := s.intercept(ctx)
+		require.Equal(codes.PermissionDenied, status.Code(err), "call must be denied")
+	}
+
+	t.Log("unauthenticated call is rejected")
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("tenantId", "acme"))
+		_, err := s.intercept(ctx)
+		require.Equal(codes.Unauthenticated, status.Code(err), "call must be rejected")
+	}
+}
+
+func (s *tenantInterceptorTestSuite) intercept(ctx context.Context) (string, error) {
+	var tenantID string
+	_, err := s.interceptor(ctx, nil, s.info, func(ctx context.Context, _ any) (any, error) {
+		tenantID = tenant.IDFromContext(ctx)
+		return nil, nil
+	})
+	return tenantID, err
+}
+
+// start tenant interceptor test suite
+func TestTenantInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(tenantInterceptorTestSuite))
+}
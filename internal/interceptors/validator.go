@@ -2,7 +2,10 @@ package interceptors
 
 import (
 	"context"
+	"unicode"
+	"unicode/utf8"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -13,7 +16,21 @@ type validator interface {
 	ValidateAll() error
 }
 
-// ValidatorUnaryInterceptor runs validation on payload if it implements validator interface
+// fieldValidationError is error of single field generated by protoc-gen-validate,
+// cause is set for embedded messages which failed validation
+type fieldValidationError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// multiValidationError is error generated by protoc-gen-validate if all rules are checked
+type multiValidationError interface {
+	AllErrors() []error
+}
+
+// ValidatorUnaryInterceptor runs validation on payload if it implements validator interface,
+// violated fields are attached to InvalidArgument status as BadRequest details
 func ValidatorUnaryInterceptor(all bool, applicables ...UnaryInterceptorApplicable) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
 		if !isUnaryInterceptorApplicable(info, applicables...) {
@@ -30,10 +47,53 @@ func ValidatorUnaryInterceptor(all bool, applicables ...UnaryInterceptorApplicab
 			}
 
 			if err != nil {
-				return nil, status.Error(codes.InvalidArgument, err.Error())
+				return nil, invalidArgumentError(err)
 			}
 		}
 
 		return h(ctx, req)
 	}
 }
+
+func invalidArgumentError(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+
+	violations := fieldViolations(err, "")
+	if len(violations) == 0 {
+		return st.Err()
+	}
+	return withDetails(st, &errdetails.BadRequest{FieldViolations: violations})
+}
+
+// fieldViolations flattens validation error to violations of leaf fields, field path is built
+// from json names of fields, e.g. customers[0].email, so it matches field of HTTP payload violations
+func fieldViolations(err error, parent string) []*errdetails.BadRequest_FieldViolation {
+	switch e := err.(type) {
+	case multiValidationError:
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.AllErrors()))
+		for _, err := range e.AllErrors() {
+			violations = append(violations, fieldViolations(err, parent)...)
+		}
+		return violations
+	case fieldValidationError:
+		field := jsonFieldName(e.Field())
+		if parent != "" {
+			field = parent + "." + field
+		}
+
+		if cause := e.Cause(); cause != nil {
+			if nested := fieldViolations(cause, field); len(nested) > 0 {
+				return nested
+			}
+		}
+		return []*errdetails.BadRequest_FieldViolation{{Field: field, Description: e.Reason()}}
+	default:
+		return nil
+	}
+}
+
+// jsonFieldName converts go field name used by protoc-gen-validate to json name of proto field, e.g. FirstName to firstName
+func jsonFieldName(field string) string {
+	r, size := utf8.DecodeRuneInString(field)
+	return string(unicode.ToLower(r)) + field[size:]
+}
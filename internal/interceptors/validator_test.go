@@ -0,0 +1,118 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nestedValidationError mimics protoc-gen-validate error of embedded message
+type nestedValidationError struct {
+	field string
+	cause error
+}
+
+func (e nestedValidationError) Field() string  { return e.field }
+func (e nestedValidationError) Reason() string { return "embedded message failed validation" }
+func (e nestedValidationError) Cause() error   { return e.cause }
+func (e nestedValidationError) Error() string  { return e.field + ": " + e.Reason() }
+
+type validatorInterceptorTestSuite struct {
+	suite.Suite
+	info *grpc.UnaryServerInfo
+}
+
+func (s *validatorInterceptorTestSuite) SetupTest() {
+	s.info = &grpc.UnaryServerInfo{FullMethod: "/customers.CustomerService/Create"}
+}
+
+func (s *validatorInterceptorTestSuite) TestValidatorUnaryInterceptor() {
+	t := s.T()
+	require := s.Require()
+
+	invalid := &proto.NewCustomerRequest{
+		FirstName: "John",
+		LastName:  "",
+		Email:     "not-an-email",
+	}
+
+	t.Log("all violated fields are attached as bad request details")
+	{
+		st := s.intercept(ValidatorUnaryInterceptor(true), invalid)
+		require.Equal(codes.InvalidArgument, st.Code(), "invalid argument code must be returned")
+
+		violations := s.violations(st)
+		require.Len(violations, 2, "each violated field must be reported")
+		require.Equal("lastName", violations[0].Field, "field must be named as json field")
+		require.Equal("value length must be at least 1 bytes", violations[0].Description, "rule reason must be reported")
+		require.Equal("email", violations[1].Field, "field must be named as json field")
+	}
+
+	t.Log("only first violated field is attached if validation stops on first error")
+	{
+		st := s.intercept(ValidatorUnaryInterceptor(false), invalid)
+		require.Equal(codes.InvalidArgument, st.Code(), "invalid argument code must be returned")
+
+		violations := s.violations(st)
+		require.Len(violations, 1, "first violated field must be reported")
+		require.Equal("lastName", violations[0].Field, "field must be named as json field")
+	}
+
+	t.Log("fields of embedded message are reported with path")
+	{
+		err := nestedValidationError{field: "Customers[1]", cause: (&proto.NewCustomerRequest{FirstName: "John", LastName: "Walls", Email: "john"}).ValidateAll()}
+
+		violations := fieldViolations(err, "")
+		require.Len(violations, 1, "leaf field of embedded message must be reported")
+		require.Equal("customers[1].email", violations[0].Field, "path to embedded field must be reported")
+	}
+
+	t.Log("error of unknown shape is returned without details")
+	{
+		st := status.Convert(invalidArgumentError(errors.New("payload is invalid")))
+		require.Equal(codes.InvalidArgument, st.Code(), "invalid argument code must be returned")
+		require.Empty(st.Details(), "no details must be attached")
+	}
+
+	t.Log("valid payload is passed to handler")
+	{
+		valid := &proto.NewCustomerRequest{FirstName: "John", LastName: "Walls", Email: "john.walls@somemail.com"}
+		res, err := ValidatorUnaryInterceptor(true)(context.Background(), valid, s.info, func(context.Context, any) (any, error) {
+			return "ok", nil
+		})
+		require.NoError(err, "no error must be raised")
+		require.Equal("ok", res, "response must be passed through")
+	}
+}
+
+func (s *validatorInterceptorTestSuite) intercept(interceptor grpc.UnaryServerInterceptor, req any) *status.Status {
+	_, err := interceptor(context.Background(), req, s.info, func(context.Context, any) (any, error) {
+		s.FailNow("handler must not be called for invalid payload")
+		return nil, nil
+	})
+	s.Require().Error(err, "error must be raised")
+
+	st, ok := status.FromError(err)
+	s.Require().True(ok, "error must be grpc status error")
+	return st
+}
+
+func (s *validatorInterceptorTestSuite) violations(st *status.Status) []*errdetails.BadRequest_FieldViolation {
+	s.Require().Len(st.Details(), 1, "single detail must be attached")
+
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	s.Require().True(ok, "detail must be bad request")
+	return badRequest.FieldViolations
+}
+
+// start validator interceptor test suite
+func TestValidatorInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(validatorInterceptorTestSuite))
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/auth"
+)
+
+// Admin is middleware function allowing access only to users with provided ids, must be applied after Authorize.
+// User id is taken from uid claim, since subject of jwt is user email
+func Admin(userIDs []string) echo.MiddlewareFunc {
+	admins := make(map[string]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := auth.ClaimsFromContext(c.Request().Context())
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "user is not authenticated")
+			}
+
+			if _, ok := admins[claims.UserID]; !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "admin access is required")
+			}
+
+			return next(c)
+		}
+	}
+}
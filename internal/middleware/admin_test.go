@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+)
+
+const adminUserID = "0b8d5b5e-7d5f-4e0c-9f43-2f3c8a1b6d20"
+
+type adminTestSuite struct {
+	suite.Suite
+	app *echo.Echo
+}
+
+func (s *adminTestSuite) SetupTest() {
+	s.app = echo.New()
+	s.app.POST("/admin", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, Admin([]string{adminUserID}))
+}
+
+func (s *adminTestSuite) TestAdmin() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("admin is allowed")
+	{
+		rec := s.post(adminUserID)
+		require.Equal(http.StatusNoContent, rec.Code, "admin must be allowed")
+	}
+
+	t.Log("regular user is forbidden")
+	{
+		rec := s.post("6a0f1c8e-3b2d-4f5a-9c7e-1d2b3a4c5e6f")
+		require.Equal(http.StatusForbidden, rec.Code, "response status must be Forbidden")
+	}
+
+	t.Log("unauthenticated request is rejected")
+	{
+		rec := s.post("")
+		require.Equal(http.StatusUnauthorized, rec.Code, "response status must be Unauthorized")
+	}
+}
+
+func (s *adminTestSuite) post(userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin", http.NoBody)
+	if userID != "" {
+		claims := auth.JwtClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "john@somemail.com"}, UserID: userID}
+		req = req.WithContext(auth.ContextWithClaims(req.Context(), claims))
+	}
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start admin middleware test suite
+func TestAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(adminTestSuite))
+}
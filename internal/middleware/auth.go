@@ -6,13 +6,16 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/pkg/logging"
 )
 
 const splitAuthHeaderPartsCount = 2
 
-// Authorize is middleware function for validating Authorization JWT header
-func Authorize(validator *auth.JwtValidator) echo.MiddlewareFunc {
+// Authorize is middleware function for validating Authorization JWT header, revoked tokens are rejected,
+// token subject is added to request scoped log fields as principal
+func Authorize(validator *auth.JwtValidator, revoker auth.TokenRevoker) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHdr := c.Request().Header.Get("Authorization")
@@ -21,10 +24,26 @@ func Authorize(validator *auth.JwtValidator) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid Authorization header format")
 			}
 
-			if _, err := validator.Verify(hdrSplit[1]); err != nil {
+			claims, err := validator.Verify(hdrSplit[1])
+			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("token verification failed - %v", err))
 			}
 
+			req := c.Request()
+
+			revoked, err := revoker.IsRevoked(req.Context(), claims)
+			if err != nil {
+				return fmt.Errorf("failed to check token revocation - %w", err)
+			}
+
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token has been revoked")
+			}
+
+			ctx := auth.ContextWithClaims(req.Context(), claims)
+			ctx = logging.ContextWithFields(ctx, logrus.Fields{logging.FieldPrincipal: claims.Subject})
+			c.SetRequest(req.WithContext(ctx))
+
 			return next(c)
 		}
 	}
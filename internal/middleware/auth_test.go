@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+const (
+	authTestIssuer     = "customers-api"
+	authTestTimeToLive = time.Minute
+	authTestSubject    = "john@somemail.com"
+)
+
+type authorizeTestSuite struct {
+	suite.Suite
+	app       *echo.Echo
+	issuer    *auth.JwtIssuer
+	validator *auth.JwtValidator
+	revoker   auth.TokenRevoker
+}
+
+func (s *authorizeTestSuite) SetupTest() {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	s.Require().NoError(err, "failed to generate keys")
+
+	method := jwt.GetSigningMethod("EdDSA")
+	s.issuer = auth.NewJwtIssuer(authTestIssuer, "", method, authTestTimeToLive, privateKey)
+	s.validator = auth.NewJwtValidator(method, publicKey, authTestIssuer, "")
+	s.revoker = auth.NewInMemoryTokenRevoker(authTestTimeToLive)
+
+	s.app = echo.New()
+	s.app.GET("/me", func(c echo.Context) error {
+		return c.String(http.StatusOK, fmt.Sprint(logging.FieldsFromContext(c.Request().Context())[logging.FieldPrincipal]))
+	}, Authorize(s.validator, s.revoker))
+}
+
+func (s *authorizeTestSuite) TestAuthorizeRevoked() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	issuedAt := time.Now().Add(-2 * time.Second)
+
+	revokedJwt, err := s.issuer.Sign(auth.Principal{Email: authTestSubject}, issuedAt)
+	require.NoError(err, "failed to sign token")
+
+	validJwt, err := s.issuer.Sign(auth.Principal{Email: authTestSubject}, issuedAt)
+	require.NoError(err, "failed to sign token")
+
+	t.Log("valid token is accepted")
+	{
+		rec := s.get(validJwt.Signed)
+		require.Equal(http.StatusOK, rec.Code, "valid token must be accepted")
+		require.Equal(authTestSubject, rec.Body.String(), "token subject must be added to log fields as principal")
+	}
+
+	t.Log("revoked token is rejected")
+	{
+		claims, err := s.validator.Verify(revokedJwt.Signed)
+		require.NoError(err, "failed to verify token")
+		require.NoError(s.revoker.Revoke(ctx, claims), "failed to revoke token")
+
+		rec := s.get(revokedJwt.Signed)
+		require.Equal(http.StatusUnauthorized, rec.Code, "revoked token must be rejected")
+
+		rec = s.get(validJwt.Signed)
+		require.Equal(http.StatusOK, rec.Code, "other tokens must be still accepted")
+	}
+
+	t.Log("tokens issued before subject revocation are rejected")
+	{
+		require.NoError(s.revoker.RevokeSubject(ctx, authTestSubject, issuedAt.Add(time.Second)), "failed to revoke subject tokens")
+
+		rec := s.get(validJwt.Signed)
+		require.Equal(http.StatusUnauthorized, rec.Code, "token issued before revocation must be rejected")
+
+		newJwt, err := s.issuer.Sign(auth.Principal{Email: authTestSubject}, time.Now())
+		require.NoError(err, "failed to sign token")
+
+		rec = s.get(newJwt.Signed)
+		require.Equal(http.StatusOK, rec.Code, "token issued after revocation must be accepted")
+	}
+}
+
+func (s *authorizeTestSuite) get(token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start authorize test suite
+func TestAuthorizeTestSuite(t *testing.T) {
+	suite.Run(t, new(authorizeTestSuite))
+}
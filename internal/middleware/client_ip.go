@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/audit"
+)
+
+// ClientIP is middleware function putting client ip to request context. IP is resolved by IPExtractor of echo,
+// so forwarding headers are honoured for trusted proxies only, connection address is used if echo has no extractor
+func ClientIP() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			ip := c.RealIP()
+			if c.Echo().IPExtractor == nil { // echo trusts forwarding headers of any request without extractor
+				ip = echo.ExtractIPDirect()(req)
+			}
+
+			c.SetRequest(req.WithContext(audit.ContextWithClientIP(req.Context(), ip)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/audit"
+)
+
+type clientIPTestSuite struct {
+	suite.Suite
+}
+
+func (s *clientIPTestSuite) TestClientIP() {
+	t := s.T()
+	require := s.Require()
+
+	direct, err := IPExtractor(nil)
+	require.NoError(err, "failed to build ip extractor")
+
+	behindProxy, err := IPExtractor([]string{"10.0.0.0/8"})
+	require.NoError(err, "failed to build ip extractor")
+
+	t.Log("spoofed forwarding headers are not recorded")
+	{
+		rec := s.get(direct, "203.0.113.9:4321")
+		require.Equal("203.0.113.9", rec.Body.String(), "connection address must be recorded")
+	}
+
+	t.Log("spoofed forwarding headers are not recorded if echo has no extractor")
+	{
+		rec := s.get(nil, "203.0.113.9:4321")
+		require.Equal("203.0.113.9", rec.Body.String(), "connection address must be recorded")
+	}
+
+	t.Log("spoofed forwarding headers are not recorded for requests bypassing trusted proxy")
+	{
+		rec := s.get(behindProxy, "203.0.113.9:4321")
+		require.Equal("203.0.113.9", rec.Body.String(), "connection address must be recorded")
+	}
+
+	t.Log("client address forwarded by trusted proxy is recorded")
+	{
+		rec := s.get(behindProxy, "10.1.2.3:4321")
+		require.Equal("198.51.100.7", rec.Body.String(), "forwarded address must be recorded")
+	}
+}
+
+func (s *clientIPTestSuite) get(extractor echo.IPExtractor, remoteAddr string) *httptest.ResponseRecorder {
+	app := echo.New()
+	app.IPExtractor = extractor
+	app.Use(ClientIP())
+	app.GET("/client-ip", func(c echo.Context) error {
+		return c.String(http.StatusOK, audit.ClientIPFromContext(c.Request().Context()))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/client-ip", http.NoBody)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.7")
+	req.Header.Set(echo.HeaderXRealIP, "198.51.100.7")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start client ip middleware test suite
+func TestClientIPTestSuite(t *testing.T) {
+	suite.Run(t, new(clientIPTestSuite))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MaxConcurrency is middleware function shedding requests with 503 once maxInFlight requests are being processed,
+// requests to skipped route paths (e.g. probes) are never shed
+func MaxConcurrency(maxInFlight int, retryAfter time.Duration, skipPaths ...string) echo.MiddlewareFunc {
+	slots := make(chan struct{}, maxInFlight)
+
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = struct{}{}
+	}
+
+	retryAfterSeconds := strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds()))))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := skip[c.Path()]; ok {
+				return next(c)
+			}
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				return next(c)
+			default:
+				c.Response().Header().Set("Retry-After", retryAfterSeconds)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "too many requests in flight, retry later")
+			}
+		}
+	}
+}
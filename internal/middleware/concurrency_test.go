@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	maxInFlight    = 5
+	shedRetryAfter = 1500 * time.Millisecond
+)
+
+type maxConcurrencyTestSuite struct {
+	suite.Suite
+	app     *echo.Echo
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *maxConcurrencyTestSuite) SetupTest() {
+	s.started = make(chan struct{}, maxInFlight)
+	s.release = make(chan struct{})
+
+	s.app = echo.New()
+	s.app.Use(MaxConcurrency(maxInFlight, shedRetryAfter, "/health"))
+	s.app.GET("/slow", func(c echo.Context) error {
+		s.started <- struct{}{}
+		<-s.release
+		return c.NoContent(http.StatusNoContent)
+	})
+	s.app.GET("/health", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+func (s *maxConcurrencyTestSuite) TestMaxConcurrency() {
+	t := s.T()
+	require := s.Require()
+
+	const excess = 20
+
+	var wg sync.WaitGroup
+	codes := make(chan int, maxInFlight)
+
+	t.Logf("fill all %d slots with slow requests", maxInFlight)
+	{
+		for i := 0; i < maxInFlight; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- s.get("/slow").Code
+			}()
+		}
+
+		for i := 0; i < maxInFlight; i++ {
+			<-s.started
+		}
+	}
+
+	t.Logf("excess %d requests are shed", excess)
+	{
+		var shedWg sync.WaitGroup
+		shed := make(chan *httptest.ResponseRecorder, excess)
+		for i := 0; i < excess; i++ {
+			shedWg.Add(1)
+			go func() {
+				defer shedWg.Done()
+				shed <- s.get("/slow")
+			}()
+		}
+		shedWg.Wait()
+		close(shed)
+
+		for rec := range shed {
+			require.Equal(http.StatusServiceUnavailable, rec.Code, "excess request must be shed")
+
+			retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			require.NoError(err, "Retry-After must be number of seconds")
+			require.Equal(2, retryAfter, "Retry-After must be rounded up to whole seconds")
+		}
+	}
+
+	t.Log("skipped path is served while slots are busy")
+	{
+		rec := s.get("/health")
+		require.Equal(http.StatusNoContent, rec.Code, "health check must not be shed")
+	}
+
+	t.Log("in-flight requests complete and slots are freed")
+	{
+		close(s.release)
+		wg.Wait()
+		close(codes)
+
+		for code := range codes {
+			require.Equal(http.StatusNoContent, code, "in-flight request must be completed")
+		}
+
+		rec := s.get("/slow")
+		require.Equal(http.StatusNoContent, rec.Code, "request must be served once slots are freed")
+	}
+}
+
+func (s *maxConcurrencyTestSuite) get(target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start max concurrency middleware test suite
+func TestMaxConcurrencyTestSuite(t *testing.T) {
+	suite.Run(t, new(maxConcurrencyTestSuite))
+}
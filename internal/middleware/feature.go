@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/feature"
+)
+
+// Feature is middleware function hiding route if provided feature is disabled, so route appears nonexistent
+func Feature(flags *feature.Flags, name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !flags.Enabled(name) {
+				return echo.ErrNotFound
+			}
+			return next(c)
+		}
+	}
+}
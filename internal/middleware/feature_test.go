@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/feature"
+)
+
+const (
+	enabledFeature  = "enabled-feature"
+	disabledFeature = "disabled-feature"
+)
+
+type featureTestSuite struct {
+	suite.Suite
+	app       *echo.Echo
+	flagsFile string
+	flags     *feature.Flags
+}
+
+func (s *featureTestSuite) SetupTest() {
+	s.flagsFile = filepath.Join(s.T().TempDir(), "features.json")
+	s.writeFlags(`{"enabled-feature": true, "disabled-feature": false}`)
+
+	flags, err := feature.NewFlags(s.flagsFile)
+	s.Require().NoError(err, "failed to load feature flags")
+	s.flags = flags
+
+	s.app = echo.New()
+	s.app.GET("/enabled", s.ok, Feature(s.flags, enabledFeature))
+	s.app.GET("/disabled", s.ok, Feature(s.flags, disabledFeature))
+	s.app.GET("/unknown", s.ok, Feature(s.flags, "unknown-feature"))
+}
+
+func (s *featureTestSuite) TestFeatureRoutes() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("route with enabled feature is served")
+	{
+		require.Equal(http.StatusOK, s.get("/enabled"), "response status must be OK")
+	}
+
+	t.Log("route with disabled feature appears nonexistent")
+	{
+		require.Equal(http.StatusNotFound, s.get("/disabled"), "response status must be Not Found")
+	}
+
+	t.Log("route with feature missing in config is served")
+	{
+		require.Equal(http.StatusOK, s.get("/unknown"), "response status must be OK")
+	}
+
+	t.Log("flags are switched after reload")
+	{
+		s.writeFlags(`{"enabled-feature": false, "disabled-feature": true}`)
+		require.NoError(s.flags.Reload(), "no error must be raised on reload")
+		require.Equal(http.StatusNotFound, s.get("/enabled"), "response status must be Not Found")
+		require.Equal(http.StatusOK, s.get("/disabled"), "response status must be OK")
+	}
+
+	t.Log("flags are kept if reload failed")
+	{
+		s.writeFlags(`{"enabled-feature":`)
+		require.Error(s.flags.Reload(), "invalid flags file must raise error")
+		require.Equal(http.StatusOK, s.get("/disabled"), "response status must be OK")
+	}
+}
+
+func (s *featureTestSuite) ok(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *featureTestSuite) get(target string) int {
+	req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (s *featureTestSuite) writeFlags(content string) {
+	s.Require().NoError(os.WriteFile(s.flagsFile, []byte(content), 0o600), "failed to write feature flags file")
+}
+
+// start feature middleware test suite
+func TestFeatureTestSuite(t *testing.T) {
+	suite.Run(t, new(featureTestSuite))
+}
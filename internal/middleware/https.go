@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequireHTTPS is middleware function ensuring requests arrived over HTTPS, scheme is taken from X-Forwarded-Proto
+// when TLS is terminated upstream. Plain HTTP GET and HEAD requests are redirected to HTTPS, other methods are rejected
+// with 403, so request body is never sent in clear again. Requests to skipped route paths (e.g. probes) are always passed
+func RequireHTTPS(skipPaths ...string) echo.MiddlewareFunc {
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := skip[c.Path()]; ok || c.Scheme() == "https" {
+				return next(c)
+			}
+
+			req := c.Request()
+			switch req.Method {
+			case http.MethodGet, http.MethodHead:
+				return c.Redirect(http.StatusMovedPermanently, "https://"+req.Host+req.RequestURI)
+			default:
+				return echo.NewHTTPError(http.StatusForbidden, "HTTPS is required")
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type httpsTestSuite struct {
+	suite.Suite
+	app *echo.Echo
+}
+
+func (s *httpsTestSuite) SetupTest() {
+	ok := func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}
+
+	s.app = echo.New()
+	s.app.Use(RequireHTTPS("/metrics"))
+	s.app.GET("/customers", ok)
+	s.app.POST("/customers", ok)
+	s.app.GET("/metrics", ok)
+}
+
+func (s *httpsTestSuite) TestRequireHTTPS() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("request forwarded over https is allowed")
+	{
+		rec := s.request(http.MethodPost, "/customers", "https")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal("ok", rec.Body.String(), "request must reach handler")
+	}
+
+	t.Log("plain http request with body is rejected")
+	{
+		rec := s.request(http.MethodPost, "/customers", "http")
+		require.Equal(http.StatusForbidden, rec.Code, "response status must be Forbidden")
+	}
+
+	t.Log("request without forwarded proto is rejected")
+	{
+		rec := s.request(http.MethodPost, "/customers", "")
+		require.Equal(http.StatusForbidden, rec.Code, "response status must be Forbidden")
+	}
+
+	t.Log("plain http get request is redirected to https")
+	{
+		rec := s.request(http.MethodGet, "/customers?page=2", "http")
+		require.Equal(http.StatusMovedPermanently, rec.Code, "response status must be Moved Permanently")
+		require.Equal("https://example.com/customers?page=2", rec.Header().Get(echo.HeaderLocation), "request must be redirected to same url over https")
+	}
+
+	t.Log("skipped path is allowed over plain http")
+	{
+		rec := s.request(http.MethodGet, "/metrics", "http")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+	}
+}
+
+func (s *httpsTestSuite) request(method string, target string, proto string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, http.NoBody)
+	if proto != "" {
+		req.Header.Set(echo.HeaderXForwardedProto, proto)
+	}
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start https middleware test suite
+func TestHTTPSTestSuite(t *testing.T) {
+	suite.Run(t, new(httpsTestSuite))
+}
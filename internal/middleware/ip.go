@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IPExtractor builds extractor of client IP which must be set to echo, otherwise echo trusts X-Forwarded-For and X-Real-IP
+// of any request. Connection address is used if there are no trusted proxies, otherwise X-Forwarded-For is taken into account
+// only for requests coming from trusted proxies and client IP is the closest address which is not a trusted proxy
+func IPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	opts := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %s - %w", proxy, err)
+		}
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...), nil
+}
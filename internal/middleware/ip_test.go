@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type ipExtractorTestSuite struct {
+	suite.Suite
+}
+
+func (s *ipExtractorTestSuite) TestIPExtractor() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("connection address is used without trusted proxies")
+	{
+		extract, err := IPExtractor(nil)
+		require.NoError(err, "extractor must be built")
+		require.Equal("203.0.113.9", extract(s.request("203.0.113.9:4321", "198.51.100.7")), "X-Forwarded-For must be ignored")
+	}
+
+	t.Log("X-Forwarded-For of trusted proxy is used")
+	{
+		extract, err := IPExtractor([]string{"10.0.0.0/8"})
+		require.NoError(err, "extractor must be built")
+		require.Equal("198.51.100.7", extract(s.request("10.1.2.3:4321", "198.51.100.7")), "client IP must be taken from X-Forwarded-For")
+	}
+
+	t.Log("X-Forwarded-For of untrusted address is ignored")
+	{
+		extract, err := IPExtractor([]string{"10.0.0.0/8"})
+		require.NoError(err, "extractor must be built")
+		require.Equal("203.0.113.9", extract(s.request("203.0.113.9:4321", "198.51.100.7")), "X-Forwarded-For must be ignored")
+	}
+
+	t.Log("address prepended by client to X-Forwarded-For is ignored")
+	{
+		extract, err := IPExtractor([]string{"10.0.0.0/8"})
+		require.NoError(err, "extractor must be built")
+		require.Equal("203.0.113.9", extract(s.request("10.1.2.3:4321", "198.51.100.7, 203.0.113.9")), "closest untrusted address must be used")
+	}
+
+	t.Log("private networks are not trusted unless configured")
+	{
+		extract, err := IPExtractor([]string{"10.0.0.0/8"})
+		require.NoError(err, "extractor must be built")
+		require.Equal("192.168.1.1", extract(s.request("192.168.1.1:4321", "198.51.100.7")), "X-Forwarded-For must be ignored")
+	}
+
+	t.Log("invalid trusted proxy is rejected")
+	{
+		_, err := IPExtractor([]string{"10.0.0.1"})
+		require.Error(err, "proxy must be CIDR")
+	}
+}
+
+func (s *ipExtractorTestSuite) request(remoteAddr string, forwardedFor string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+	return req
+}
+
+// start ip extractor test suite
+func TestIPExtractorTestSuite(t *testing.T) {
+	suite.Run(t, new(ipExtractorTestSuite))
+}
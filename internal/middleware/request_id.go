@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	echoMw "github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// RequestID is middleware function assigning id to request, id is taken from header if client passed it,
+// id is responded in the same header and put to request context along with request scoped log fields,
+// so errors can be correlated with logs
+func RequestID(header string) echo.MiddlewareFunc {
+	return echoMw.RequestIDWithConfig(echoMw.RequestIDConfig{
+		TargetHeader: header,
+		RequestIDHandler: func(c echo.Context, id string) {
+			req := c.Request()
+			ctx := requestid.ContextWithID(req.Context(), id)
+			ctx = logging.ContextWithFields(ctx, logrus.Fields{
+				logging.FieldRequestID: id,
+				logging.FieldTransport: logging.TransportHTTP,
+				logging.FieldMethod:    req.Method,
+				logging.FieldRoute:     c.Path(),
+			})
+			c.SetRequest(req.WithContext(ctx))
+		},
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/requestid"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+const requestIDTestHeader = "X-Correlation-Id"
+
+type requestIDTestSuite struct {
+	suite.Suite
+	app *echo.Echo
+}
+
+func (s *requestIDTestSuite) SetupTest() {
+	s.app = echo.New()
+	s.app.Use(RequestID(requestIDTestHeader))
+	s.app.GET("/request-id", func(c echo.Context) error {
+		return c.String(http.StatusOK, requestid.IDFromContext(c.Request().Context()))
+	})
+	s.app.GET("/customers/:id", func(c echo.Context) error {
+		logging.FromContext(c.Request().Context()).Warn("customer is not cached")
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+func (s *requestIDTestSuite) TestRequestID() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("request id is taken from configured header")
+	{
+		rec := s.get("6f1c2b7e-support-quoted")
+		require.Equal("6f1c2b7e-support-quoted", rec.Body.String(), "request id from header must be put to context")
+		require.Equal("6f1c2b7e-support-quoted", rec.Header().Get(requestIDTestHeader), "request id must be responded")
+	}
+
+	t.Log("request id is generated if header is missing")
+	{
+		rec := s.get("")
+		require.NotEmpty(rec.Body.String(), "request id must be generated")
+		require.Equal(rec.Body.String(), rec.Header().Get(requestIDTestHeader), "generated request id must be responded")
+		require.Empty(rec.Header().Get(echo.HeaderXRequestID), "only configured header must be used")
+	}
+
+	t.Log("request scoped fields are logged by handler")
+	{
+		logHook := logrusTest.NewGlobal()
+		defer logHook.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/customers/42", http.NoBody)
+		req.Header.Set(requestIDTestHeader, "6f1c2b7e-support-quoted")
+		rec := httptest.NewRecorder()
+		s.app.ServeHTTP(rec, req)
+		require.Equal(http.StatusNoContent, rec.Code, "response status must be No Content")
+
+		entry := logHook.LastEntry()
+		require.NotNil(entry, "handler log entry must be written")
+		require.Equal("6f1c2b7e-support-quoted", entry.Data[logging.FieldRequestID], "request id must be logged")
+		require.Equal(logging.TransportHTTP, entry.Data[logging.FieldTransport], "transport must be logged")
+		require.Equal(http.MethodGet, entry.Data[logging.FieldMethod], "method must be logged")
+		require.Equal("/customers/:id", entry.Data[logging.FieldRoute], "route must be logged")
+	}
+}
+
+func (s *requestIDTestSuite) get(requestID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/request-id", http.NoBody)
+	if requestID != "" {
+		req.Header.Set(requestIDTestHeader, requestID)
+	}
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start request id middleware test suite
+func TestRequestIDTestSuite(t *testing.T) {
+	suite.Run(t, new(requestIDTestSuite))
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+// TenantHeader is header carrying caller tenant id
+const TenantHeader = "X-Tenant-ID"
+
+// Tenant is middleware function putting caller tenant from verified jwt claims to request context, so it must follow Authorize.
+// X-Tenant-ID header is optional, request is forbidden if it doesn't match tenant of jwt
+func Tenant() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			claims, ok := auth.ClaimsFromContext(req.Context())
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "tenant can't be resolved for unauthenticated request")
+			}
+
+			id, err := tenant.Resolve(claims.TenantID, req.Header.Get(TenantHeader))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusForbidden, TenantHeader+" header doesn't match tenant of access token")
+			}
+
+			c.SetRequest(req.WithContext(tenant.ContextWithID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
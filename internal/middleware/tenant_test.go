@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/auth"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+type tenantTestSuite struct {
+	suite.Suite
+	app *echo.Echo
+}
+
+func (s *tenantTestSuite) SetupTest() {
+	s.app = echo.New()
+	s.app.GET("/tenant", func(c echo.Context) error {
+		return c.String(http.StatusOK, tenant.IDFromContext(c.Request().Context()))
+	}, Tenant())
+}
+
+func (s *tenantTestSuite) TestTenant() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("tenant is taken from jwt claims")
+	{
+		rec := s.get(&auth.JwtClaims{TenantID: "acme"}, "")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal("acme", rec.Body.String(), "tenant from claims must be put to context")
+	}
+
+	t.Log("header matching tenant of jwt claims is accepted")
+	{
+		rec := s.get(&auth.JwtClaims{TenantID: "acme"}, "acme")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal("acme", rec.Body.String(), "tenant from claims must be put to context")
+	}
+
+	t.Log("default tenant is used if claims have no tenant")
+	{
+		rec := s.get(&auth.JwtClaims{}, "")
+		require.Equal(http.StatusOK, rec.Code, "response status must be OK")
+		require.Equal(tenant.DefaultID, rec.Body.String(), "default tenant must be used")
+	}
+
+	t.Log("header not matching tenant of jwt claims is forbidden")
+	{
+		rec := s.get(&auth.JwtClaims{TenantID: "acme"}, "globex")
+		require.Equal(http.StatusForbidden, rec.Code, "response status must be Forbidden")
+	}
+
+	t.Log("header can't switch tenant of jwt without tenant")
+	{
+		rec := s.get(&auth.JwtClaims{}, "globex")
+		require.Equal(http.StatusForbidden, rec.Code, "response status must be Forbidden")
+	}
+
+	t.Log("unauthenticated request is rejected")
+	{
+		rec := s.get(nil, "acme")
+		require.Equal(http.StatusUnauthorized, rec.Code, "response status must be Unauthorized")
+	}
+}
+
+func (s *tenantTestSuite) get(claims *auth.JwtClaims, tenantID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/tenant", http.NoBody)
+	if claims != nil {
+		req = req.WithContext(auth.ContextWithClaims(req.Context(), *claims))
+	}
+	if tenantID != "" {
+		req.Header.Set(TenantHeader, tenantID)
+	}
+	rec := httptest.NewRecorder()
+	s.app.ServeHTTP(rec, req)
+	return rec
+}
+
+// start tenant middleware test suite
+func TestTenantTestSuite(t *testing.T) {
+	suite.Run(t, new(tenantTestSuite))
+}
@@ -17,6 +17,7 @@ const (
 // Customer is customer model entity
 type Customer struct {
 	ID         string     `json:"id" bson:"_id,omitempty"`
+	TenantID   string     `json:"-" bson:"tenantId"`
 	FirstName  string     `json:"firstName" bson:"firstName"`
 	LastName   string     `json:"lastName" bson:"lastName"`
 	MiddleName *string    `json:"middleName" bson:"middleName"`
@@ -24,3 +25,117 @@ type Customer struct {
 	Importance Importance `json:"importance" bson:"importance"`
 	Inactive   bool       `json:"inactive" bson:"inactive"`
 }
+
+// CustomerChange describes change of single customer field
+type CustomerChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Diff returns changed fields of customer compared to its previous state, fields are named as in json
+func (c *Customer) Diff(prev *Customer) []CustomerChange {
+	changes := make([]CustomerChange, 0)
+
+	add := func(field string, before, after any) {
+		changes = append(changes, CustomerChange{Field: field, Old: before, New: after})
+	}
+
+	if c.FirstName != prev.FirstName {
+		add("firstName", prev.FirstName, c.FirstName)
+	}
+
+	if c.LastName != prev.LastName {
+		add("lastName", prev.LastName, c.LastName)
+	}
+
+	if !equalOptional(c.MiddleName, prev.MiddleName) {
+		add("middleName", prev.MiddleName, c.MiddleName)
+	}
+
+	if c.Email != prev.Email {
+		add("email", prev.Email, c.Email)
+	}
+
+	if c.Importance != prev.Importance {
+		add("importance", prev.Importance, c.Importance)
+	}
+
+	if c.Inactive != prev.Inactive {
+		add("inactive", prev.Inactive, c.Inactive)
+	}
+
+	return changes
+}
+
+// CustomerFilter narrows down customers of tenant, nil values don't filter
+type CustomerFilter struct {
+	Importance *Importance
+	Inactive   *bool
+}
+
+// CustomerSearch is full-text search of tenant customers by name and email, matched customers are narrowed down by filter
+type CustomerSearch struct {
+	Query  string
+	Filter CustomerFilter
+	Limit  int
+	Offset int
+}
+
+// CustomerPatch contains customer fields to change, nil values are left untouched
+type CustomerPatch struct {
+	Importance *Importance
+	Inactive   *bool
+}
+
+func equalOptional(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// External converts importance to the one exposed outside of service, external api numbers importance from 1 while model from 0
+func (i Importance) External() Importance {
+	return i + 1
+}
+
+// ImportanceFromExternal converts importance exposed outside of service to model one
+func ImportanceFromExternal(i Importance) Importance {
+	return i - 1
+}
+
+// ExternalChanges converts importance of customer changes to the external one, other changes are kept as is.
+// Changes decoded from json or bson hold importance as plain number, so it is converted as well
+func ExternalChanges(changes []CustomerChange) []CustomerChange {
+	if changes == nil {
+		return nil
+	}
+
+	converted := make([]CustomerChange, len(changes))
+	for i, change := range changes {
+		if change.Field == "importance" {
+			change.Old = externalImportanceValue(change.Old)
+			change.New = externalImportanceValue(change.New)
+		}
+		converted[i] = change
+	}
+	return converted
+}
+
+func externalImportanceValue(v any) any {
+	switch i := v.(type) {
+	case Importance:
+		return i.External()
+	case int:
+		return Importance(i).External()
+	case int32:
+		return Importance(i).External()
+	case int64:
+		return Importance(i).External()
+	case float64:
+		return Importance(i).External()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type customerTestSuite struct {
+	suite.Suite
+	customer *Customer
+}
+
+func (s *customerTestSuite) SetupTest() {
+	middleName := "Ben"
+	s.customer = &Customer{
+		ID:         "6c1e2f0a-8b7d-4c3e-9a5f-1d2e3f4a5b6c",
+		TenantID:   "acme",
+		FirstName:  "John",
+		LastName:   "Walls",
+		MiddleName: &middleName,
+		Email:      "john.walls@somemail.com",
+		Importance: ImportanceLow,
+		Inactive:   false,
+	}
+}
+
+func (s *customerTestSuite) TestDiff() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("unchanged customer has empty diff")
+	{
+		same := *s.customer
+		sameMiddleName := *s.customer.MiddleName
+		same.MiddleName = &sameMiddleName
+
+		require.Empty(same.Diff(s.customer), "diff must be empty if nothing changed")
+	}
+
+	t.Log("only changed fields appear in diff")
+	{
+		updated := *s.customer
+		updated.MiddleName = nil
+		updated.Email = "new.john@somemail.com"
+		updated.Importance = ImportanceCritical
+
+		require.Equal([]CustomerChange{
+			{Field: "middleName", Old: s.customer.MiddleName, New: (*string)(nil)},
+			{Field: "email", Old: s.customer.Email, New: updated.Email},
+			{Field: "importance", Old: ImportanceLow, New: ImportanceCritical},
+		}, updated.Diff(s.customer), "diff must contain only changed fields")
+	}
+}
+
+func (s *customerTestSuite) TestExternal() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("importance is numbered from 1 outside of service")
+	{
+		require.Equal(Importance(1), ImportanceLow.External(), "low importance must be 1")
+		require.Equal(ImportanceCritical, ImportanceFromExternal(ImportanceCritical.External()), "conversion must be reversible")
+	}
+
+	t.Log("importance of event customer and changes is converted, event itself is left untouched")
+	{
+		e := &CustomerEvent{
+			Type:     CustomerUpdated,
+			Customer: s.customer,
+			Changes: []CustomerChange{
+				{Field: "email", Old: "old.john@somemail.com", New: s.customer.Email},
+				{Field: "importance", Old: ImportanceHigh, New: ImportanceLow},
+			},
+		}
+
+		external := e.External()
+		require.Equal(ImportanceLow.External(), external.Customer.Importance, "customer importance must be converted")
+		require.Equal(e.Changes[0], external.Changes[0], "other changes must be kept as is")
+		require.Equal(CustomerChange{Field: "importance", Old: ImportanceHigh.External(), New: ImportanceLow.External()}, external.Changes[1], "changed importance must be converted")
+		require.Equal(ImportanceLow, s.customer.Importance, "original customer must not be changed")
+		require.Equal(ImportanceHigh, e.Changes[1].Old, "original changes must not be changed")
+	}
+
+	t.Log("importance decoded as plain number is converted")
+	{
+		changes := ExternalChanges([]CustomerChange{{Field: "importance", Old: float64(0), New: int32(2)}})
+		require.Equal(CustomerChange{Field: "importance", Old: Importance(1), New: Importance(3)}, changes[0], "decoded importance must be converted")
+	}
+}
+
+// start customer test suite
+func TestCustomerTestSuite(t *testing.T) {
+	suite.Run(t, new(customerTestSuite))
+}
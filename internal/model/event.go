@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// CustomerEventType is type of customer change event
+type CustomerEventType string
+
+const (
+	// CustomerCreated is raised once customer is created
+	CustomerCreated CustomerEventType = "customer.created"
+	// CustomerUpdated is raised once any customer field is changed
+	CustomerUpdated CustomerEventType = "customer.updated"
+	// CustomerDeleted is raised once customer is deleted
+	CustomerDeleted CustomerEventType = "customer.deleted"
+)
+
+// CustomerEventTypes lists all customer event types
+var CustomerEventTypes = []CustomerEventType{CustomerCreated, CustomerUpdated, CustomerDeleted}
+
+// CustomerEvent describes change of single customer, customer is omitted for deleted event
+type CustomerEvent struct {
+	ID         string            `json:"id"`
+	Type       CustomerEventType `json:"type"`
+	TenantID   string            `json:"tenantId"`
+	CustomerID string            `json:"customerId"`
+	Customer   *Customer         `json:"customer,omitempty"`
+	Changes    []CustomerChange  `json:"changes,omitempty"` // updated event only
+	OccurredAt time.Time         `json:"occurredAt"`
+}
+
+// External returns copy of event with importance converted to the one exposed outside of service.
+// Events leaving service (streams, webhooks, brokers) must be converted to match http api
+func (e *CustomerEvent) External() *CustomerEvent {
+	external := *e
+	if e.Customer != nil {
+		c := *e.Customer
+		c.Importance = c.Importance.External()
+		external.Customer = &c
+	}
+	external.Changes = ExternalChanges(e.Changes)
+	return &external
+}
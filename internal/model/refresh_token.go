@@ -2,6 +2,9 @@ package model
 
 import "time"
 
+// RefreshTokenFingerprintMaxLength is max length of fingerprint refresh token is bound to
+const RefreshTokenFingerprintMaxLength = 255
+
 // RefreshToken is refresh token model entity
 type RefreshToken struct {
 	ID          string
@@ -10,3 +13,16 @@ type RefreshToken struct {
 	ExpiresIn   int
 	CreatedAt   time.Time
 }
+
+// RefreshTokenFilter narrows down refresh tokens across all users, zero values don't filter
+type RefreshTokenFilter struct {
+	UserID   string
+	ActiveAt time.Time // only tokens which are not expired at this moment
+	Limit    int       // ignored by count
+	Offset   int       // ignored by count
+}
+
+// ExpiresAt returns moment in UTC when refresh token expires
+func (t *RefreshToken) ExpiresAt() time.Time {
+	return t.CreatedAt.UTC().Add(time.Duration(t.ExpiresIn) * time.Second)
+}
@@ -3,6 +3,7 @@ package model
 // User is user model entity
 type User struct {
 	ID           string
+	TenantID     string
 	Email        string
-	PasswordHash string
+	PasswordHash string `redact:"true"`
 }
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// WebhookDeliveryStatus is state of event delivery to webhook
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending means delivery is scheduled to be attempted
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered means receiver responded with 2xx status
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead means all delivery attempts failed, delivery is not retried anymore
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// Webhook is subscription of external system to customer events of tenant
+type Webhook struct {
+	ID         string
+	TenantID   string
+	URL        string
+	Secret     string // key of HMAC signature of payload
+	EventTypes []CustomerEventType
+	Active     bool
+	CreatedAt  time.Time
+}
+
+// Subscribed reports if webhook is active and subscribed to event type
+func (w *Webhook) Subscribed(t CustomerEventType) bool {
+	if !w.Active {
+		return false
+	}
+
+	for _, et := range w.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is delivery of single event to webhook, it is updated after each attempt
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	EventID        string
+	EventType      CustomerEventType
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	LastStatusCode int // zero if receiver didn't respond
+	LastError      string
+	Payload        []byte    // serialized event, the same payload is posted on each attempt
+	NextAttemptAt  time.Time // pending delivery is attempted once it is due
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DueWebhookDelivery is pending delivery which is due to be attempted along with its webhook
+type DueWebhookDelivery struct {
+	Webhook  *Webhook
+	Delivery *WebhookDelivery
+}
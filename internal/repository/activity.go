@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/umalmyha/customers/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const mongoNamespaceExistsCode = 48
+
+// CustomerActivityRepository represents behavior of repository of recent customer events
+type CustomerActivityRepository interface {
+	Append(context.Context, *model.CustomerEvent) error
+	FindRecent(ctx context.Context, tenantID string, customerID string, since time.Time, limit int) ([]*model.CustomerEvent, error)
+}
+
+// mongoCustomerActivity is document of activity collection, event id is document id, so redelivered event is stored once
+type mongoCustomerActivity struct {
+	ID         string                  `bson:"_id"`
+	Type       model.CustomerEventType `bson:"type"`
+	TenantID   string                  `bson:"tenantId"`
+	CustomerID string                  `bson:"customerId"`
+	Customer   *model.Customer         `bson:"customer,omitempty"`
+	Changes    []model.CustomerChange  `bson:"changes,omitempty"`
+	OccurredAt time.Time               `bson:"occurredAt"`
+}
+
+type mongoCustomerActivityRepository struct {
+	client *mongo.Client
+}
+
+// NewMongoCustomerActivityRepository builds repository keeping customer events in capped collection,
+// so the oldest events are overwritten once collection is full
+func NewMongoCustomerActivityRepository(client *mongo.Client) CustomerActivityRepository {
+	return &mongoCustomerActivityRepository{client: client}
+}
+
+func (r *mongoCustomerActivityRepository) Append(ctx context.Context, e *model.CustomerEvent) error {
+	_, err := r.collection().InsertOne(ctx, &mongoCustomerActivity{
+		ID:         e.ID,
+		Type:       e.Type,
+		TenantID:   e.TenantID,
+		CustomerID: e.CustomerID,
+		Customer:   e.Customer,
+		Changes:    e.Changes,
+		OccurredAt: e.OccurredAt,
+	})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("mongo: failed to append customer event %s to activity - %w", e.ID, err)
+	}
+	return nil
+}
+
+func (r *mongoCustomerActivityRepository) FindRecent(ctx context.Context, tenantID string, customerID string, since time.Time, limit int) ([]*model.CustomerEvent, error) {
+	filter := bson.M{"tenantId": tenantID, "customerId": customerID, "occurredAt": bson.M{"$gte": since}}
+	opts := options.Find().SetSort(bson.D{{Key: "occurredAt", Value: -1}}).SetLimit(int64(limit))
+
+	cur, err := r.collection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to read activity of customer %s - %w", customerID, err)
+	}
+
+	docs := make([]*mongoCustomerActivity, 0)
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("mongo: failed to scan activity of customer %s - %w", customerID, err)
+	}
+
+	events := make([]*model.CustomerEvent, len(docs))
+	for i, doc := range docs {
+		events[i] = &model.CustomerEvent{
+			ID:         doc.ID,
+			Type:       doc.Type,
+			TenantID:   doc.TenantID,
+			CustomerID: doc.CustomerID,
+			Customer:   doc.Customer,
+			Changes:    doc.Changes,
+			OccurredAt: doc.OccurredAt.UTC(),
+		}
+	}
+	return events, nil
+}
+
+func (r *mongoCustomerActivityRepository) collection() *mongo.Collection {
+	return r.client.Database("customers").Collection("customer_activity")
+}
+
+// MigrateMongoCustomerActivity creates capped collection of customer events with size in bytes, existing collection is left untouched
+func MigrateMongoCustomerActivity(ctx context.Context, client *mongo.Client, size int64) error {
+	db := client.Database("customers")
+
+	err := db.CreateCollection(ctx, "customer_activity", options.CreateCollection().SetCapped(true).SetSizeInBytes(size))
+	var cmdErr mongo.CommandError
+	if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == mongoNamespaceExistsCode) {
+		return fmt.Errorf("mongo: failed to create customer activity collection - %w", err)
+	}
+
+	_, err = db.Collection("customer_activity").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "customerId", Value: 1}, {Key: "occurredAt", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to create customer activity index - %w", err)
+	}
+	return nil
+}
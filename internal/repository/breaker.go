@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/umalmyha/customers/internal/config"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/breaker"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type circuitBreakerCustomerRepository struct {
+	next    CustomerRepository
+	breaker *breaker.Breaker
+}
+
+// NewCircuitBreakerCustomerRepository wraps repository with circuit breaker, so calls fail fast with 503
+// for cooldown window after consecutive failures instead of waiting for unavailable database
+func NewCircuitBreakerCustomerRepository(
+	name string,
+	next CustomerRepository,
+	reg prometheus.Registerer,
+	cfg *config.RepositoryBreakerCfg,
+) CustomerRepository {
+	stateGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "customers_repository_breaker_state",
+		Help:        "State of customers repository circuit breaker, 0 - closed, 1 - half-open, 2 - open",
+		ConstLabels: prometheus.Labels{"repository": name},
+	})
+	reg.MustRegister(stateGauge)
+
+	b := breaker.New(breaker.Settings{
+		Name:             name,
+		Kind:             "repository",
+		FailureThreshold: cfg.FailureThreshold,
+		Cooldown:         cfg.Cooldown,
+		IsSuccessful:     isDatabaseReachable,
+		StateGauge:       stateGauge,
+	})
+
+	return &circuitBreakerCustomerRepository{next: next, breaker: b}
+}
+
+func (r *circuitBreakerCustomerRepository) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	return execute(r, func() (*model.Customer, error) {
+		return r.next.FindByID(ctx, tenantID, id)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) FindByEmail(ctx context.Context, tenantID string, email string) (*model.Customer, error) {
+	return execute(r, func() (*model.Customer, error) {
+		return r.next.FindByEmail(ctx, tenantID, email)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) FindAll(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	return execute(r, func() ([]*model.Customer, error) {
+		return r.next.FindAll(ctx, tenantID)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) FindAllActive(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	return execute(r, func() ([]*model.Customer, error) {
+		return r.next.FindAllActive(ctx, tenantID)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) FindMostImportant(ctx context.Context, tenantID string, limit int) ([]*model.Customer, error) {
+	return execute(r, func() ([]*model.Customer, error) {
+		return r.next.FindMostImportant(ctx, tenantID, limit)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) Create(ctx context.Context, c *model.Customer) error {
+	_, err := execute(r, func() (struct{}, error) {
+		return struct{}{}, r.next.Create(ctx, c)
+	})
+	return err
+}
+
+func (r *circuitBreakerCustomerRepository) Update(ctx context.Context, c *model.Customer) error {
+	_, err := execute(r, func() (struct{}, error) {
+		return struct{}{}, r.next.Update(ctx, c)
+	})
+	return err
+}
+
+func (r *circuitBreakerCustomerRepository) BulkUpdate(
+	ctx context.Context,
+	tenantID string,
+	filter *model.CustomerFilter,
+	patch *model.CustomerPatch,
+) ([]string, error) {
+	return execute(r, func() ([]string, error) {
+		return r.next.BulkUpdate(ctx, tenantID, filter, patch)
+	})
+}
+
+func (r *circuitBreakerCustomerRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	_, err := execute(r, func() (struct{}, error) {
+		return struct{}{}, r.next.DeleteByID(ctx, tenantID, id)
+	})
+	return err
+}
+
+// execute calls fn through breaker, rejected call fails with unavailable error telling to retry once breaker lets probe through.
+// Half-open breaker rejects calls while probe is in flight, so they are asked to retry right away
+func execute[T any](r *circuitBreakerCustomerRepository, fn func() (T, error)) (T, error) {
+	res, err := breaker.Execute(r.breaker, fn)
+	if breaker.IsRejection(err) {
+		msg := fmt.Sprintf("%s is temporarily unavailable", r.breaker.Name())
+		return res, appErrors.NewUnavailableErr(msg, r.breaker.RetryAfter(time.Now()), err)
+	}
+	return res, err
+}
+
+// isDatabaseReachable reports if call outcome says database is healthy, errors returned by database server itself
+// (e.g. unique violation), updates of missing customers and cancelled requests are not counted as failures
+func isDatabaseReachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || IsMissingCustomer(err) {
+		return true
+	}
+
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return true
+	}
+
+	var mongoErr mongo.ServerError
+	return errors.As(err, &mongoErr)
+}
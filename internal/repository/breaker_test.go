@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository/mocks"
+)
+
+const (
+	breakerFailureThreshold = 3
+	breakerCooldown         = 50 * time.Millisecond
+)
+
+// sqlStateError mimics error returned by postgres server
+type sqlStateError struct {
+	code string
+}
+
+func (e *sqlStateError) Error() string {
+	return "server error " + e.code
+}
+
+func (e *sqlStateError) SQLState() string {
+	return e.code
+}
+
+type circuitBreakerTestSuite struct {
+	suite.Suite
+	customerRps *mocks.CustomerRepository
+	registry    *prometheus.Registry
+	breakerRps  CustomerRepository
+	customer    *model.Customer
+}
+
+func (s *circuitBreakerTestSuite) SetupTest() {
+	s.customerRps = mocks.NewCustomerRepository(s.T())
+	s.registry = prometheus.NewRegistry()
+	s.breakerRps = NewCircuitBreakerCustomerRepository("postgres", s.customerRps, s.registry, &config.RepositoryBreakerCfg{
+		FailureThreshold: breakerFailureThreshold,
+		Cooldown:         breakerCooldown,
+	})
+	s.customer = &model.Customer{ID: "5e0c7a9d-3b1f-4d2e-8a6c-9f4b2d1e0c7a", TenantID: "acme", FirstName: "John", LastName: "Smith"}
+}
+
+func (s *circuitBreakerTestSuite) breakerState() float64 {
+	families, err := s.registry.Gather()
+	s.Require().NoError(err, "failed to gather metrics")
+	s.Require().Len(families, 1, "breaker state metric must be registered")
+	return families[0].GetMetric()[0].GetGauge().GetValue()
+}
+
+func (s *circuitBreakerTestSuite) TestOpenBreakerFailsFastWithUnavailable() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+	dbErr := errors.New("failed to connect to host")
+
+	t.Log("failures are returned until threshold is reached")
+	{
+		s.customerRps.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(nil, dbErr).
+			Times(breakerFailureThreshold)
+
+		for i := 0; i < breakerFailureThreshold; i++ {
+			_, err := s.breakerRps.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+			require.ErrorIs(err, dbErr, "database error must be returned while breaker is closed")
+		}
+		require.Equal(float64(2), s.breakerState(), "breaker must be open")
+	}
+
+	t.Log("open breaker fails fast with 503 without touching database")
+	{
+		_, err := s.breakerRps.FindAll(ctx, s.customer.TenantID)
+		var unavailableErr *appErrors.UnavailableErr
+		require.ErrorAs(err, &unavailableErr, "open breaker must return unavailable error")
+		require.Positive(unavailableErr.RetryAfter, "remaining open time must be returned")
+		require.LessOrEqual(unavailableErr.RetryAfter, breakerCooldown, "remaining open time must not exceed cooldown")
+		require.ErrorIs(err, gobreaker.ErrOpenState, "breaker error must be kept as cause")
+
+		err = s.breakerRps.Create(ctx, s.customer)
+		require.ErrorAs(err, &unavailableErr, "open breaker must reject writes")
+	}
+
+	t.Log("customer is returned once breaker is closed")
+	{
+		time.Sleep(breakerCooldown + 10*time.Millisecond)
+
+		s.customerRps.EXPECT().FindByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(s.customer, nil).
+			Once()
+
+		c, err := s.breakerRps.FindByID(ctx, s.customer.TenantID, s.customer.ID)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.customer, c, "customer must be returned")
+	}
+}
+
+func (s *circuitBreakerTestSuite) TestServerErrorsDoNotTripBreaker() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+	uniqueViolation := &sqlStateError{code: "23505"}
+
+	t.Log("errors reported by database server are not counted as failures")
+	{
+		s.customerRps.EXPECT().Create(mock.Anything, s.customer).
+			Return(uniqueViolation).
+			Times(breakerFailureThreshold + 1)
+
+		for i := 0; i <= breakerFailureThreshold; i++ {
+			err := s.breakerRps.Create(ctx, s.customer)
+			require.ErrorIs(err, uniqueViolation, "database error must be returned")
+		}
+		require.Zero(s.breakerState(), "breaker must stay closed")
+	}
+
+	t.Log("cancelled requests are not counted as failures")
+	{
+		s.customerRps.EXPECT().DeleteByID(mock.Anything, s.customer.TenantID, s.customer.ID).
+			Return(context.Canceled).
+			Times(breakerFailureThreshold + 1)
+
+		for i := 0; i <= breakerFailureThreshold; i++ {
+			err := s.breakerRps.DeleteByID(ctx, s.customer.TenantID, s.customer.ID)
+			require.ErrorIs(err, context.Canceled, "cancellation must be returned")
+		}
+		require.Zero(s.breakerState(), "breaker must stay closed")
+	}
+}
+
+// start circuit breaker test suite
+func TestCircuitBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(circuitBreakerTestSuite))
+}
@@ -4,38 +4,72 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/umalmyha/customers/internal/config"
 	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// CustomerRepository represents behavior for customer repository
+const (
+	pgUniqueViolationCode  = "23505"
+	mongoIndexNotFoundCode = 27
+	mongoGlobalEmailIndex  = "email_1_global"
+	pgGlobalEmailIndex     = "customers_email_uidx"
+)
+
+// pgFindAllActiveCustomersQuery reads active customers of tenant, inactive is compared to literal,
+// so query matches predicate of partial index customers_tenant_active_idx
+const pgFindAllActiveCustomersQuery = `SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers
+          WHERE tenant_id = $1 AND inactive = FALSE ORDER BY id`
+
+var (
+	errDuplicateCustomer = errors.New("customer with the same id or email already exists")
+	errMissingCustomer   = errors.New("customer doesn't exist")
+)
+
+// CustomerRepository represents behavior for customer repository,
+// FindByEmail looks for customer within email uniqueness scope, so tenant is ignored if email is unique globally
 type CustomerRepository interface {
-	FindByID(context.Context, string) (*model.Customer, error)
-	FindAll(context.Context) ([]*model.Customer, error)
+	FindByID(context.Context, string, string) (*model.Customer, error)
+	FindByEmail(context.Context, string, string) (*model.Customer, error)
+	FindAll(context.Context, string) ([]*model.Customer, error)
+	FindAllActive(context.Context, string) ([]*model.Customer, error)
+	FindMostImportant(context.Context, string, int) ([]*model.Customer, error)
 	Create(context.Context, *model.Customer) error
 	Update(context.Context, *model.Customer) error
-	DeleteByID(context.Context, string) error
+	BulkUpdate(context.Context, string, *model.CustomerFilter, *model.CustomerPatch) ([]string, error)
+	DeleteByID(context.Context, string, string) error
 }
 
 type postgresCustomerRepository struct {
-	pool *pgxpool.Pool
+	transactor.PgxWithinTransactionExecutor
+	uniqueness config.EmailUniqueness
+	bestEffort bool
 }
 
-// NewPostgresCustomerRepository builds postgresCustomerRepository
-func NewPostgresCustomerRepository(p *pgxpool.Pool) CustomerRepository {
-	return &postgresCustomerRepository{pool: p}
+// NewPostgresCustomerRepository builds postgresCustomerRepository, customers which fail to be read
+// are skipped by FindAll and FindAllActive if best effort reads are enabled. Queries join transaction of context
+func NewPostgresCustomerRepository(e transactor.PgxWithinTransactionExecutor, uniqueness config.EmailUniqueness, cfg *config.RepositoryCfg) CustomerRepository {
+	return &postgresCustomerRepository{PgxWithinTransactionExecutor: e, uniqueness: uniqueness, bestEffort: cfg.BestEffortReads}
 }
 
-func (r *postgresCustomerRepository) FindByID(ctx context.Context, id string) (*model.Customer, error) {
+func (r *postgresCustomerRepository) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
 	var c model.Customer
-	q := "SELECT id, first_name, last_name, middle_name, email, importance, inactive FROM customers WHERE id = $1"
+	q := `SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers
+          WHERE tenant_id = $1 AND id = $2`
 
-	row := r.pool.QueryRow(ctx, q, id)
-	err := row.Scan(&c.ID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Email, &c.Importance, &c.Inactive)
+	row := r.Executor(ctx).QueryRow(ctx, q, tenantID, id)
+	err := row.Scan(&c.ID, &c.TenantID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Email, &c.Importance, &c.Inactive)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -45,32 +79,61 @@ func (r *postgresCustomerRepository) FindByID(ctx context.Context, id string) (*
 	return &c, nil
 }
 
-func (r *postgresCustomerRepository) FindAll(ctx context.Context) ([]*model.Customer, error) {
-	customers := make([]*model.Customer, 0)
-	q := "SELECT id, first_name, last_name, middle_name, email, importance, inactive FROM customers"
+func (r *postgresCustomerRepository) FindByEmail(ctx context.Context, tenantID string, email string) (*model.Customer, error) {
+	var c model.Customer
+	q := `SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers
+          WHERE email = $1`
+	args := []any{email}
+	if r.uniqueness == config.EmailUniquenessTenant {
+		q += " AND tenant_id = $2"
+		args = append(args, tenantID)
+	}
+
+	row := r.Executor(ctx).QueryRow(ctx, q, args...)
+	err := row.Scan(&c.ID, &c.TenantID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Email, &c.Importance, &c.Inactive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("postgres: failed to scan customer while reading by email %s - %w", email, err)
+	}
+	return &c, nil
+}
 
-	rows, err := r.pool.Query(ctx, q)
+func (r *postgresCustomerRepository) FindAll(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	q := "SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers WHERE tenant_id = $1 ORDER BY id"
+
+	customers, err := r.query(ctx, q, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("postgres: failed to read all customers - %w", err)
 	}
-	defer rows.Close()
+	return customers, nil
+}
 
-	for rows.Next() {
-		var c model.Customer
-		if err := rows.Scan(&c.ID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Email, &c.Importance, &c.Inactive); err != nil {
-			return nil, fmt.Errorf("postgres: failed to scan customer while reading all customers - %w", err)
-		}
-		customers = append(customers, &c)
+func (r *postgresCustomerRepository) FindAllActive(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	customers, err := r.query(ctx, pgFindAllActiveCustomersQuery, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read active customers - %w", err)
 	}
+	return customers, nil
+}
+
+func (r *postgresCustomerRepository) FindMostImportant(ctx context.Context, tenantID string, limit int) ([]*model.Customer, error) {
+	q := `SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers
+          WHERE tenant_id = $1 ORDER BY importance DESC, id LIMIT $2`
 
+	customers, err := r.query(ctx, q, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read the most important customers - %w", err)
+	}
 	return customers, nil
 }
 
 func (r *postgresCustomerRepository) Create(ctx context.Context, c *model.Customer) error {
-	q := `INSERT INTO customers(id, first_name, last_name, middle_name, email, importance, inactive)
-					  VALUES($1, $2, $3, $4, $5, $6, $7)`
+	q := `INSERT INTO customers(id, tenant_id, first_name, last_name, middle_name, email, importance, inactive)
+					  VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	_, err := r.pool.Exec(ctx, q, c.ID, c.FirstName, c.LastName, c.MiddleName, c.Email, c.Importance, c.Inactive)
+	_, err := r.Executor(ctx).Exec(ctx, q, c.ID, c.TenantID, c.FirstName, c.LastName, c.MiddleName, c.Email, c.Importance, c.Inactive)
 	if err != nil {
 		return fmt.Errorf("postgres: failed to insert customer %s while reading by id - %w", c.ID, err)
 	}
@@ -79,35 +142,119 @@ func (r *postgresCustomerRepository) Create(ctx context.Context, c *model.Custom
 
 func (r *postgresCustomerRepository) Update(ctx context.Context, c *model.Customer) error {
 	q := `UPDATE customers SET first_name = $1, last_name = $2, middle_name = $3, email = $4, importance = $5, inactive = $6
-          WHERE id = $7`
-	_, err := r.pool.Exec(ctx, q, c.FirstName, c.LastName, c.MiddleName, c.Email, c.Importance, c.Inactive, c.ID)
+          WHERE tenant_id = $7 AND id = $8`
+	tag, err := r.Executor(ctx).Exec(ctx, q, c.FirstName, c.LastName, c.MiddleName, c.Email, c.Importance, c.Inactive, c.TenantID, c.ID)
 	if err != nil {
 		return fmt.Errorf("postgres: failed to update customer %s - %w", c.ID, err)
 	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("postgres: failed to update customer %s - %w", c.ID, errMissingCustomer)
+	}
 	return nil
 }
 
-func (r *postgresCustomerRepository) DeleteByID(ctx context.Context, id string) error {
-	q := "DELETE FROM customers WHERE id = $1"
-	_, err := r.pool.Exec(ctx, q, id)
+func (r *postgresCustomerRepository) BulkUpdate(
+	ctx context.Context,
+	tenantID string,
+	filter *model.CustomerFilter,
+	patch *model.CustomerPatch,
+) ([]string, error) {
+	set, args := customersSet(patch)
+	where, args := customersWhere(tenantID, filter, args)
+	q := "UPDATE customers SET " + set + where + " RETURNING id"
+
+	rows, err := r.Executor(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to bulk update customers - %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan customer id while bulk updating customers - %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres: failed to bulk update customers - %w", err)
+	}
+	return ids, nil
+}
+
+func (r *postgresCustomerRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	q := "DELETE FROM customers WHERE tenant_id = $1 AND id = $2"
+	_, err := r.Executor(ctx).Exec(ctx, q, tenantID, id)
 	if err != nil {
 		return fmt.Errorf("postgres: failed to delete customer %s - %w", id, err)
 	}
 	return nil
 }
 
+func (r *postgresCustomerRepository) query(ctx context.Context, q string, args ...any) ([]*model.Customer, error) {
+	rows, err := r.Executor(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := make([]*model.Customer, 0)
+	skipped := 0
+	for rows.Next() {
+		// nullable columns are scanned as pointers, since pgx scan error closes rows and the rest rows can't be read
+		var row struct {
+			model.Customer
+			importance *int
+			inactive   *bool
+		}
+		if err := rows.Scan(&row.ID, &row.TenantID, &row.FirstName, &row.LastName, &row.MiddleName, &row.Email, &row.importance, &row.inactive); err != nil {
+			return nil, fmt.Errorf("failed to scan customer - %w", err)
+		}
+
+		if row.importance == nil || row.inactive == nil {
+			err := fmt.Errorf("failed to scan customer %s - importance or inactive is null", row.ID)
+			if !r.bestEffort {
+				return nil, err
+			}
+
+			skipped++
+			logging.FromContext(ctx).Warnf("postgres: customer is skipped - %v", err)
+			continue
+		}
+
+		row.Importance = model.Importance(*row.importance)
+		row.Inactive = *row.inactive
+		customers = append(customers, &row.Customer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if skipped > 0 {
+		logging.FromContext(ctx).Warnf("postgres: %d of %d customers are skipped since they failed to be read", skipped, skipped+len(customers))
+	}
+	return customers, nil
+}
+
 type mongoCustomerRepository struct {
-	client *mongo.Client
+	transactor.MongoWithinTransactionExecutor
+	uniqueness config.EmailUniqueness
 }
 
-// NewMongoCustomerRepository builds new mongoCustomerRepository
-func NewMongoCustomerRepository(client *mongo.Client) CustomerRepository {
-	return &mongoCustomerRepository{client: client}
+// NewMongoCustomerRepository builds new mongoCustomerRepository, operations join transaction started by mongo transactor
+func NewMongoCustomerRepository(e transactor.MongoWithinTransactionExecutor, uniqueness config.EmailUniqueness) CustomerRepository {
+	return &mongoCustomerRepository{MongoWithinTransactionExecutor: e, uniqueness: uniqueness}
 }
 
-func (r *mongoCustomerRepository) FindByID(ctx context.Context, id string) (*model.Customer, error) {
+func (r *mongoCustomerRepository) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	ctx = r.Session(ctx)
+
 	var c model.Customer
-	err := r.client.Database("customers").Collection("customers").FindOne(ctx, bson.M{"_id": id}).Decode(&c)
+	err := r.collection().FindOne(ctx, bson.M{"_id": id, "tenantId": tenantID}).Decode(&c)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
@@ -117,8 +264,30 @@ func (r *mongoCustomerRepository) FindByID(ctx context.Context, id string) (*mod
 	return &c, nil
 }
 
-func (r *mongoCustomerRepository) FindAll(ctx context.Context) ([]*model.Customer, error) {
-	cur, err := r.client.Database("customers").Collection("customers").Find(ctx, bson.M{})
+func (r *mongoCustomerRepository) FindByEmail(ctx context.Context, tenantID string, email string) (*model.Customer, error) {
+	ctx = r.Session(ctx)
+
+	filter := bson.M{"email": email}
+	if r.uniqueness == config.EmailUniquenessTenant {
+		filter["tenantId"] = tenantID
+	}
+
+	var c model.Customer
+	err := r.collection().FindOne(ctx, filter).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("mongo: failed to read customer by email %s - %w", email, err)
+	}
+	return &c, nil
+}
+
+func (r *mongoCustomerRepository) FindAll(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	ctx = r.Session(ctx)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}) // stable order, so pages don't overlap
+	cur, err := r.collection().Find(ctx, bson.M{"tenantId": tenantID}, opts)
 	if err != nil {
 		return nil, fmt.Errorf("mongo: failed to read all customers - %w", err)
 	}
@@ -130,8 +299,42 @@ func (r *mongoCustomerRepository) FindAll(ctx context.Context) ([]*model.Custome
 	return customers, nil
 }
 
+func (r *mongoCustomerRepository) FindAllActive(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	ctx = r.Session(ctx)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := r.collection().Find(ctx, bson.M{"tenantId": tenantID, "inactive": false}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to read active customers - %w", err)
+	}
+
+	customers := make([]*model.Customer, 0)
+	if err := cur.All(ctx, &customers); err != nil {
+		return nil, fmt.Errorf("mongo: failed to scan customers while reading active - %w", err)
+	}
+	return customers, nil
+}
+
+func (r *mongoCustomerRepository) FindMostImportant(ctx context.Context, tenantID string, limit int) ([]*model.Customer, error) {
+	ctx = r.Session(ctx)
+
+	opts := options.Find().SetSort(bson.D{{Key: "importance", Value: -1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cur, err := r.collection().Find(ctx, bson.M{"tenantId": tenantID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to read the most important customers - %w", err)
+	}
+
+	customers := make([]*model.Customer, 0)
+	if err := cur.All(ctx, &customers); err != nil {
+		return nil, fmt.Errorf("mongo: failed to scan customers while reading the most important - %w", err)
+	}
+	return customers, nil
+}
+
 func (r *mongoCustomerRepository) Create(ctx context.Context, c *model.Customer) error {
-	_, err := r.client.Database("customers").Collection("customers").InsertOne(ctx, c)
+	ctx = r.Session(ctx)
+
+	_, err := r.collection().InsertOne(ctx, c)
 	if err != nil {
 		return fmt.Errorf("mongo: failed to create customer %s - %w", c.ID, err)
 	}
@@ -139,7 +342,9 @@ func (r *mongoCustomerRepository) Create(ctx context.Context, c *model.Customer)
 }
 
 func (r *mongoCustomerRepository) Update(ctx context.Context, c *model.Customer) error {
-	_, err := r.client.Database("customers").Collection("customers").UpdateByID(ctx, c.ID, bson.D{
+	ctx = r.Session(ctx)
+
+	res, err := r.collection().UpdateOne(ctx, bson.M{"_id": c.ID, "tenantId": c.TenantID}, bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "firstName", Value: c.FirstName},
 			{Key: "lastName", Value: c.LastName},
@@ -152,13 +357,375 @@ func (r *mongoCustomerRepository) Update(ctx context.Context, c *model.Customer)
 	if err != nil {
 		return fmt.Errorf("mongo: failed to update customer %s - %w", c.ID, err)
 	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("mongo: failed to update customer %s - %w", c.ID, errMissingCustomer)
+	}
 	return nil
 }
 
-func (r *mongoCustomerRepository) DeleteByID(ctx context.Context, id string) error {
-	_, err := r.client.Database("customers").Collection("customers").DeleteOne(ctx, bson.M{"_id": id})
+func (r *mongoCustomerRepository) BulkUpdate(
+	ctx context.Context,
+	tenantID string,
+	filter *model.CustomerFilter,
+	patch *model.CustomerPatch,
+) ([]string, error) {
+	ctx = r.Session(ctx)
+
+	coll := r.collection()
+	query := customersQuery(tenantID, filter)
+
+	cur, err := coll.Find(ctx, query, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to read customers to bulk update - %w", err)
+	}
+
+	var matched []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &matched); err != nil {
+		return nil, fmt.Errorf("mongo: failed to scan customers to bulk update - %w", err)
+	}
+
+	ids := make([]string, len(matched))
+	for i := range matched {
+		ids[i] = matched[i].ID
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	query["_id"] = bson.M{"$in": ids}
+	if _, err := coll.UpdateMany(ctx, query, bson.M{"$set": customersUpdate(patch)}); err != nil {
+		return nil, fmt.Errorf("mongo: failed to bulk update customers - %w", err)
+	}
+	return ids, nil
+}
+
+func (r *mongoCustomerRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	ctx = r.Session(ctx)
+
+	_, err := r.collection().DeleteOne(ctx, bson.M{"_id": id, "tenantId": tenantID})
 	if err != nil {
 		return fmt.Errorf("mongo: failed to delete customer %s - %w", id, err)
 	}
 	return nil
 }
+
+func (r *mongoCustomerRepository) collection() *mongo.Collection {
+	return r.Database("customers").Collection("customers")
+}
+
+type inMemoryCustomerKey struct {
+	tenantID string
+	id       string
+}
+
+type inMemoryCustomerRepository struct {
+	customers  map[inMemoryCustomerKey]model.Customer
+	uniqueness config.EmailUniqueness
+	mu         sync.RWMutex
+}
+
+// NewInMemoryCustomerRepository builds new in-memory customer repository, email is unique within the same scope as in databases
+func NewInMemoryCustomerRepository(uniqueness config.EmailUniqueness) CustomerRepository {
+	return &inMemoryCustomerRepository{
+		customers:  make(map[inMemoryCustomerKey]model.Customer),
+		uniqueness: uniqueness,
+	}
+}
+
+func (r *inMemoryCustomerRepository) FindByID(_ context.Context, tenantID string, id string) (*model.Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.customers[inMemoryCustomerKey{tenantID: tenantID, id: id}]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *inMemoryCustomerRepository) FindByEmail(_ context.Context, tenantID string, email string) (*model.Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findByEmail(tenantID, email), nil
+}
+
+func (r *inMemoryCustomerRepository) FindAll(_ context.Context, tenantID string) ([]*model.Customer, error) {
+	return r.findAll(tenantID, func(*model.Customer) bool { return true }), nil
+}
+
+func (r *inMemoryCustomerRepository) FindAllActive(_ context.Context, tenantID string) ([]*model.Customer, error) {
+	return r.findAll(tenantID, func(c *model.Customer) bool { return !c.Inactive }), nil
+}
+
+func (r *inMemoryCustomerRepository) FindMostImportant(_ context.Context, tenantID string, limit int) ([]*model.Customer, error) {
+	customers := r.findAll(tenantID, func(*model.Customer) bool { return true })
+	sort.SliceStable(customers, func(i, j int) bool { // customers are already ordered by id
+		return customers[i].Importance > customers[j].Importance
+	})
+
+	if len(customers) > limit {
+		customers = customers[:limit]
+	}
+	return customers, nil
+}
+
+func (r *inMemoryCustomerRepository) Create(_ context.Context, c *model.Customer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := inMemoryCustomerKey{tenantID: c.TenantID, id: c.ID}
+	if _, ok := r.customers[key]; ok || r.findByEmail(c.TenantID, c.Email) != nil {
+		return fmt.Errorf("memory: failed to create customer %s - %w", c.ID, errDuplicateCustomer)
+	}
+
+	r.customers[key] = *c
+	return nil
+}
+
+func (r *inMemoryCustomerRepository) Update(_ context.Context, c *model.Customer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := inMemoryCustomerKey{tenantID: c.TenantID, id: c.ID}
+	if _, ok := r.customers[key]; !ok {
+		return fmt.Errorf("memory: failed to update customer %s - %w", c.ID, errMissingCustomer)
+	}
+
+	if existing := r.findByEmail(c.TenantID, c.Email); existing != nil && (existing.TenantID != c.TenantID || existing.ID != c.ID) {
+		return fmt.Errorf("memory: failed to update customer %s - %w", c.ID, errDuplicateCustomer)
+	}
+
+	r.customers[key] = *c
+	return nil
+}
+
+func (r *inMemoryCustomerRepository) BulkUpdate(
+	_ context.Context,
+	tenantID string,
+	filter *model.CustomerFilter,
+	patch *model.CustomerPatch,
+) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0)
+	for key, c := range r.customers {
+		if key.tenantID != tenantID ||
+			(filter.Importance != nil && c.Importance != *filter.Importance) ||
+			(filter.Inactive != nil && c.Inactive != *filter.Inactive) {
+			continue
+		}
+
+		if patch.Importance != nil {
+			c.Importance = *patch.Importance
+		}
+
+		if patch.Inactive != nil {
+			c.Inactive = *patch.Inactive
+		}
+
+		r.customers[key] = c
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+func (r *inMemoryCustomerRepository) DeleteByID(_ context.Context, tenantID string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.customers, inMemoryCustomerKey{tenantID: tenantID, id: id})
+	return nil
+}
+
+func (r *inMemoryCustomerRepository) findAll(tenantID string, match func(*model.Customer) bool) []*model.Customer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customers := make([]*model.Customer, 0)
+	for key, c := range r.customers {
+		if key.tenantID == tenantID && match(&c) {
+			c := c
+			customers = append(customers, &c)
+		}
+	}
+
+	// map iteration order is random, so customers are ordered by id the same way as in databases
+	sort.Slice(customers, func(i, j int) bool {
+		return customers[i].ID < customers[j].ID
+	})
+	return customers
+}
+
+func (r *inMemoryCustomerRepository) findByEmail(tenantID string, email string) *model.Customer {
+	for key, c := range r.customers {
+		if (r.uniqueness == config.EmailUniquenessGlobal || key.tenantID == tenantID) && c.Email == email {
+			return &c
+		}
+	}
+	return nil
+}
+
+// MigrateMongoCustomers assigns default tenant to customers created before tenants were introduced,
+// creates unique email per tenant index, partial index of active customers and index of customers by importance.
+// Unique email index across tenants exists only if email is unique globally
+func MigrateMongoCustomers(ctx context.Context, client *mongo.Client, uniqueness config.EmailUniqueness) error {
+	coll := client.Database("customers").Collection("customers")
+
+	_, err := coll.UpdateMany(ctx, bson.M{"tenantId": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"tenantId": tenant.DefaultID}})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to assign default tenant to customers - %w", err)
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to create customers tenant email index - %w", err)
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().
+			SetName("tenantId_1__id_1_active").
+			SetPartialFilterExpression(bson.M{"inactive": false}),
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to create active customers index - %w", err)
+	}
+
+	_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "importance", Value: -1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to create customers importance index - %w", err)
+	}
+
+	if uniqueness == config.EmailUniquenessGlobal {
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName(mongoGlobalEmailIndex).SetUnique(true),
+		})
+		if err != nil {
+			return fmt.Errorf("mongo: failed to create customers global email index - %w", err)
+		}
+		return nil
+	}
+
+	_, err = coll.Indexes().DropOne(ctx, mongoGlobalEmailIndex)
+	var cmdErr mongo.CommandError
+	if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == mongoIndexNotFoundCode) {
+		return fmt.Errorf("mongo: failed to drop customers global email index - %w", err)
+	}
+	return nil
+}
+
+// VerifyPostgresCustomers checks that schema matches configured email uniqueness, i.e. unique email index across tenants
+// exists only if email is unique globally. Indexes are managed by schema migrations only, so mismatch is reported
+// instead of being fixed. Index is maintained by repeatable migration, which flyway re-applies once
+// customersEmailUniqueness placeholder changes
+func VerifyPostgresCustomers(ctx context.Context, pool *pgxpool.Pool, uniqueness config.EmailUniqueness) error {
+	q := "SELECT EXISTS(SELECT 1 FROM pg_indexes WHERE tablename = 'customers' AND indexname = $1)"
+
+	var exists bool
+	if err := pool.QueryRow(ctx, q, pgGlobalEmailIndex).Scan(&exists); err != nil {
+		return fmt.Errorf("postgres: failed to check customers email index - %w", err)
+	}
+
+	if uniqueness == config.EmailUniquenessGlobal && !exists {
+		return fmt.Errorf("postgres: customers email uniqueness is %s, but index %s doesn't exist - %s, "+
+			"emails duplicated across tenants must be resolved first", uniqueness, pgGlobalEmailIndex, pgEmailUniquenessHint(uniqueness))
+	}
+
+	if uniqueness != config.EmailUniquenessGlobal && exists {
+		return fmt.Errorf("postgres: customers email uniqueness is %s, but index %s exists - %s", uniqueness, pgGlobalEmailIndex, pgEmailUniquenessHint(uniqueness))
+	}
+	return nil
+}
+
+func pgEmailUniquenessHint(uniqueness config.EmailUniqueness) string {
+	return fmt.Sprintf("run flyway migrate with -placeholders.customersEmailUniqueness=%s to re-apply R__customers_email_uniqueness.sql", uniqueness)
+}
+
+// IsMissingCustomer reports whether customer wasn't updated because it doesn't exist, e.g. it was deleted concurrently
+func IsMissingCustomer(err error) bool {
+	return errors.Is(err, errMissingCustomer)
+}
+
+// IsDuplicateCustomer reports whether customer wasn't created because the same customer, e.g. with the same email, already exists
+func IsDuplicateCustomer(err error) bool {
+	if errors.Is(err, errDuplicateCustomer) {
+		return true
+	}
+
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState() == pgUniqueViolationCode
+	}
+	return mongo.IsDuplicateKeyError(err)
+}
+
+func customersSet(p *model.CustomerPatch) (string, []any) {
+	assignments := make([]string, 0)
+	args := make([]any, 0)
+
+	if p.Importance != nil {
+		args = append(args, *p.Importance)
+		assignments = append(assignments, fmt.Sprintf("importance = $%d", len(args)))
+	}
+
+	if p.Inactive != nil {
+		args = append(args, *p.Inactive)
+		assignments = append(assignments, fmt.Sprintf("inactive = $%d", len(args)))
+	}
+
+	return strings.Join(assignments, ", "), args
+}
+
+func customersWhere(tenantID string, f *model.CustomerFilter, args []any) (string, []any) {
+	args = append(args, tenantID)
+	conditions := []string{fmt.Sprintf("tenant_id = $%d", len(args))}
+
+	if f.Importance != nil {
+		args = append(args, *f.Importance)
+		conditions = append(conditions, fmt.Sprintf("importance = $%d", len(args)))
+	}
+
+	if f.Inactive != nil { // literal, so generic plan of prepared statement can use partial index of active customers
+		conditions = append(conditions, fmt.Sprintf("inactive = %t", *f.Inactive))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func customersQuery(tenantID string, f *model.CustomerFilter) bson.M {
+	query := bson.M{"tenantId": tenantID}
+
+	if f.Importance != nil {
+		query["importance"] = *f.Importance
+	}
+
+	if f.Inactive != nil {
+		query["inactive"] = *f.Inactive
+	}
+	return query
+}
+
+func customersUpdate(p *model.CustomerPatch) bson.M {
+	update := bson.M{}
+
+	if p.Importance != nil {
+		update["importance"] = *p.Importance
+	}
+
+	if p.Inactive != nil {
+		update["inactive"] = *p.Inactive
+	}
+	return update
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	esErrorBodyMaxBytes       = 4 << 10
+	esIndexAlreadyExistsError = "resource_already_exists_exception"
+)
+
+// esCustomersMapping is mapping of customers index, tenant and filter fields are not analyzed
+const esCustomersMapping = `{
+	"mappings": {
+		"properties": {
+			"id":         {"type": "keyword"},
+			"tenantId":   {"type": "keyword"},
+			"firstName":  {"type": "text"},
+			"lastName":   {"type": "text"},
+			"middleName": {"type": "text"},
+			"email":      {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+			"importance": {"type": "integer"},
+			"inactive":   {"type": "boolean"},
+			"indexedAt":  {"type": "date"}
+		}
+	}
+}`
+
+// esSearchFields are fields query is matched against, names weigh more than email
+var esSearchFields = []string{"firstName^2", "lastName^2", "middleName", "email"}
+
+// esCustomer is document of customers index, unlike customer json it carries tenant and time it was indexed at
+type esCustomer struct {
+	ID         string           `json:"id"`
+	TenantID   string           `json:"tenantId"`
+	FirstName  string           `json:"firstName"`
+	LastName   string           `json:"lastName"`
+	MiddleName *string          `json:"middleName"`
+	Email      string           `json:"email"`
+	Importance model.Importance `json:"importance"`
+	Inactive   bool             `json:"inactive"`
+	IndexedAt  time.Time        `json:"indexedAt"`
+}
+
+type esSearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source esCustomer `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type esErrorResponse struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// CustomerIndexRepository represents behavior of customers search index kept in sync with customer changes,
+// BulkUpdate patches documents of tenant customers matching filter and DeleteIndexedBefore deletes documents
+// of tenant which weren't indexed since given time
+type CustomerIndexRepository interface {
+	Index(context.Context, *model.Customer) error
+	DeleteByID(context.Context, string, string) error
+	BulkUpdate(context.Context, string, *model.CustomerFilter, *model.CustomerPatch) error
+	DeleteIndexedBefore(context.Context, string, time.Time) error
+}
+
+// ElasticsearchCustomerRepository keeps customers in elasticsearch index and searches them ranked by relevance,
+// it talks to elasticsearch REST API, so OpenSearch is supported as well
+type ElasticsearchCustomerRepository struct {
+	client *http.Client
+	cfg    *config.ElasticsearchCfg
+}
+
+// NewElasticsearchCustomerRepository builds new ElasticsearchCustomerRepository
+func NewElasticsearchCustomerRepository(client *http.Client, cfg *config.ElasticsearchCfg) *ElasticsearchCustomerRepository {
+	return &ElasticsearchCustomerRepository{client: client, cfg: cfg}
+}
+
+// CreateIndex creates customers index with mapping, existing index is left untouched
+func (r *ElasticsearchCustomerRepository) CreateIndex(ctx context.Context) error {
+	res, err := r.do(ctx, http.MethodPut, r.cfg.Index, strings.NewReader(esCustomersMapping))
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to create index %s - %w", r.cfg.Index, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest {
+		var e esErrorResponse
+		if err := json.NewDecoder(io.LimitReader(res.Body, esErrorBodyMaxBytes)).Decode(&e); err == nil && e.Error.Type == esIndexAlreadyExistsError {
+			return nil
+		}
+		return fmt.Errorf("elasticsearch: failed to create index %s - %s", r.cfg.Index, e.Error.Reason)
+	}
+
+	if err := esResponseError(res); err != nil {
+		return fmt.Errorf("elasticsearch: failed to create index %s - %w", r.cfg.Index, err)
+	}
+	return nil
+}
+
+// Index creates or replaces customer document
+func (r *ElasticsearchCustomerRepository) Index(ctx context.Context, c *model.Customer) error {
+	doc, err := json.Marshal(&esCustomer{
+		ID:         c.ID,
+		TenantID:   c.TenantID,
+		FirstName:  c.FirstName,
+		LastName:   c.LastName,
+		MiddleName: c.MiddleName,
+		Email:      c.Email,
+		Importance: c.Importance,
+		Inactive:   c.Inactive,
+		IndexedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to encode customer %s - %w", c.ID, err)
+	}
+
+	res, err := r.do(ctx, http.MethodPut, r.docPath(c.TenantID, c.ID), bytes.NewReader(doc))
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to index customer %s - %w", c.ID, err)
+	}
+	defer res.Body.Close()
+
+	if err := esResponseError(res); err != nil {
+		return fmt.Errorf("elasticsearch: failed to index customer %s - %w", c.ID, err)
+	}
+	return nil
+}
+
+// DeleteByID deletes customer document, missing document is not an error
+func (r *ElasticsearchCustomerRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	res, err := r.do(ctx, http.MethodDelete, r.docPath(tenantID, id), nil)
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to delete customer %s - %w", id, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	if err := esResponseError(res); err != nil {
+		return fmt.Errorf("elasticsearch: failed to delete customer %s - %w", id, err)
+	}
+	return nil
+}
+
+// BulkUpdate applies patch to documents of tenant customers matching filter,
+// documents changed by concurrent indexing are skipped, since they are indexed from datastore anyway
+func (r *ElasticsearchCustomerRepository) BulkUpdate(ctx context.Context, tenantID string, filter *model.CustomerFilter, patch *model.CustomerPatch) error {
+	params := make(map[string]any)
+	if patch.Importance != nil {
+		params["importance"] = *patch.Importance
+	}
+	if patch.Inactive != nil {
+		params["inactive"] = *patch.Inactive
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{"bool": map[string]any{"filter": esFilter(tenantID, filter)}},
+		"script": map[string]any{
+			"source": "for (entry in params.entrySet()) { ctx._source[entry.getKey()] = entry.getValue() }",
+			"params": params,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to encode bulk update - %w", err)
+	}
+
+	res, err := r.do(ctx, http.MethodPost, r.cfg.Index+"/_update_by_query?conflicts=proceed", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to bulk update customers - %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := esResponseError(res); err != nil {
+		return fmt.Errorf("elasticsearch: failed to bulk update customers - %w", err)
+	}
+	return nil
+}
+
+// DeleteIndexedBefore deletes documents of tenant customers indexed before given time
+func (r *ElasticsearchCustomerRepository) DeleteIndexedBefore(ctx context.Context, tenantID string, before time.Time) error {
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{
+			"bool": map[string]any{
+				"filter": []any{
+					esTerm("tenantId", tenantID),
+					map[string]any{"range": map[string]any{"indexedAt": map[string]any{"lt": before.UTC()}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to encode delete query - %w", err)
+	}
+
+	res, err := r.do(ctx, http.MethodPost, r.cfg.Index+"/_delete_by_query?conflicts=proceed", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("elasticsearch: failed to delete customers of tenant %s - %w", tenantID, err)
+	}
+	defer res.Body.Close()
+
+	if err := esResponseError(res); err != nil {
+		return fmt.Errorf("elasticsearch: failed to delete customers of tenant %s - %w", tenantID, err)
+	}
+	return nil
+}
+
+// Search returns page of tenant customers matching query ordered by relevance, typos are tolerated
+func (r *ElasticsearchCustomerRepository) Search(ctx context.Context, tenantID string, s *model.CustomerSearch) ([]*model.Customer, int, error) {
+	body, err := json.Marshal(map[string]any{
+		"from":             s.Offset,
+		"size":             s.Limit,
+		"track_total_hits": true,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{
+					"multi_match": map[string]any{
+						"query":     s.Query,
+						"fields":    esSearchFields,
+						"fuzziness": "AUTO",
+					},
+				},
+				"filter": esFilter(tenantID, &s.Filter),
+			},
+		},
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("elasticsearch: failed to encode search query - %w", err)
+	}
+
+	res, err := r.do(ctx, http.MethodPost, r.cfg.Index+"/_search", bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("elasticsearch: failed to search customers - %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := esResponseError(res); err != nil {
+		return nil, 0, fmt.Errorf("elasticsearch: failed to search customers - %w", err)
+	}
+
+	var sr esSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, 0, fmt.Errorf("elasticsearch: failed to decode found customers - %w", err)
+	}
+
+	customers := make([]*model.Customer, len(sr.Hits.Hits))
+	for i, hit := range sr.Hits.Hits {
+		doc := hit.Source
+		customers[i] = &model.Customer{
+			ID:         doc.ID,
+			TenantID:   doc.TenantID,
+			FirstName:  doc.FirstName,
+			LastName:   doc.LastName,
+			MiddleName: doc.MiddleName,
+			Email:      doc.Email,
+			Importance: doc.Importance,
+			Inactive:   doc.Inactive,
+		}
+	}
+	return customers, sr.Hits.Total.Value, nil
+}
+
+func (r *ElasticsearchCustomerRepository) do(ctx context.Context, method string, path string, body io.Reader) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.cfg.URL, "/")+"/"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if r.cfg.Username != "" {
+		req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// body must be read before request context is cancelled
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+	return res, nil
+}
+
+// docPath returns path of customer document, id is prefixed with tenant since customer ids are unique per tenant only
+func (r *ElasticsearchCustomerRepository) docPath(tenantID string, id string) string {
+	return r.cfg.Index + "/_doc/" + url.PathEscape(tenantID+":"+id)
+}
+
+// esFilter narrows down documents to tenant customers matching filter
+func esFilter(tenantID string, f *model.CustomerFilter) []any {
+	filter := []any{esTerm("tenantId", tenantID)}
+	if f.Importance != nil {
+		filter = append(filter, esTerm("importance", *f.Importance))
+	}
+	if f.Inactive != nil {
+		filter = append(filter, esTerm("inactive", *f.Inactive))
+	}
+	return filter
+}
+
+func esTerm(field string, value any) map[string]any {
+	return map[string]any{"term": map[string]any{field: value}}
+}
+
+func esResponseError(res *http.Response) error {
+	if res.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	var e esErrorResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, esErrorBodyMaxBytes)).Decode(&e); err != nil || e.Error.Reason == "" {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return fmt.Errorf("unexpected status %d, %s - %s", res.StatusCode, e.Error.Type, e.Error.Reason)
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+const (
+	esContainerName = "es-rps-test-customers"
+	esPort          = "9200"
+	esTestIndex     = "customers-test"
+)
+
+type elasticsearchTestSuite struct {
+	suite.Suite
+	dockerPool    *dockertest.Pool
+	elasticsearch *dockertest.Resource
+	client        *http.Client
+	cfg           *config.ElasticsearchCfg
+}
+
+func (s *elasticsearchTestSuite) SetupSuite() {
+	t := s.T()
+	assert := s.Require()
+
+	// create docker pool
+	dockerPool, err := dockertest.NewPool("")
+	assert.NoError(err, "failed to create pool")
+
+	t.Log("sending ping to docker...")
+	err = dockerPool.Client.Ping()
+	assert.NoError(err, "failed to connect to docker")
+
+	s.dockerPool = dockerPool
+	dockerPool.MaxWait = 2 * time.Minute // elasticsearch takes a while to start
+
+	// start elasticsearch
+	t.Log("starting elasticsearch...")
+	elasticsearch, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       esContainerName,
+		Repository: "docker.elastic.co/elasticsearch/elasticsearch",
+		Tag:        "8.4.1",
+		Env: []string{
+			"discovery.type=single-node",
+			"xpack.security.enabled=false",
+			"ES_JAVA_OPTS=-Xms512m -Xmx512m",
+		},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9200/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", esPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start elasticsearch")
+
+	s.elasticsearch = elasticsearch
+	s.client = &http.Client{}
+	s.cfg = &config.ElasticsearchCfg{
+		URL:     fmt.Sprintf("http://localhost:%s", esPort),
+		Index:   esTestIndex,
+		Timeout: connectionTimeout,
+	}
+
+	// wait for elasticsearch
+	t.Log("connecting to elasticsearch...")
+	err = dockerPool.Retry(func() error {
+		res, err := s.client.Get(s.cfg.URL + "/_cluster/health?wait_for_status=yellow&timeout=1s")
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("elasticsearch is not ready, status %d", res.StatusCode)
+		}
+		return nil
+	})
+	assert.NoError(err, "failed to establish connection to elasticsearch")
+}
+
+func (s *elasticsearchTestSuite) TearDownSuite() {
+	if s.elasticsearch != nil {
+		if err := s.dockerPool.Purge(s.elasticsearch); err != nil {
+			s.T().Logf("failed to purge elasticsearch container - %v", err)
+		}
+	}
+}
+
+func (s *elasticsearchTestSuite) TestElasticsearchCustomerRps() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	esRps := NewElasticsearchCustomerRepository(s.client, s.cfg)
+
+	critical := model.ImportanceCritical
+	inactive := true
+	customers := []*model.Customer{
+		{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: "acme", FirstName: "John", LastName: "Walls", Email: "john.walls@somemail.com", Importance: model.ImportanceLow},
+		{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "acme", FirstName: "Johnny", LastName: "Black", Email: "jblack@somemail.com", Importance: model.ImportanceCritical},
+		{ID: "2d5a7e9c-3f4b-4a8c-8d1e-2f3a4b5c6d7e", TenantID: "acme", FirstName: "Jack", LastName: "John", Email: "jack@somemail.com", Importance: model.ImportanceCritical, Inactive: true},
+		{ID: "3e6b8f0d-4a5c-4b9d-9e2f-3a4b5c6d7e8f", TenantID: "globex", FirstName: "John", LastName: "Doe", Email: "john.doe@somemail.com", Importance: model.ImportanceCritical},
+	}
+
+	t.Log("create index")
+	{
+		require.NoError(esRps.CreateIndex(ctx), "failed to create index")
+		require.NoError(esRps.CreateIndex(ctx), "existing index must be left untouched")
+	}
+
+	t.Log("index customers")
+	{
+		for _, c := range customers {
+			require.NoError(esRps.Index(ctx, c), "failed to index customer")
+		}
+		s.refresh(ctx)
+	}
+
+	t.Log("search customers of tenant")
+	{
+		found, total, err := esRps.Search(ctx, "acme", &model.CustomerSearch{Query: "john", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Equal(2, total, "customers of tenant matching query must be found")
+		require.Len(found, 2, "customers of tenant matching query must be found")
+		for _, c := range found {
+			require.Equal("acme", c.TenantID, "customers of other tenants must not be found")
+		}
+	}
+
+	t.Log("search tolerates typos")
+	{
+		found, _, err := esRps.Search(ctx, "globex", &model.CustomerSearch{Query: "jonh", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Len(found, 1, "customer must be found despite typo")
+		require.Equal(customers[3], found[0], "incorrect customer")
+	}
+
+	t.Log("search with filter")
+	{
+		found, total, err := esRps.Search(ctx, "acme", &model.CustomerSearch{
+			Query:  "john",
+			Filter: model.CustomerFilter{Importance: &critical, Inactive: &inactive},
+			Limit:  10,
+		})
+		require.NoError(err, "failed to search customers")
+		require.Equal(1, total, "only customers matching filter must be found")
+		require.Equal(customers[2], found[0], "incorrect customer")
+	}
+
+	t.Log("search page")
+	{
+		found, total, err := esRps.Search(ctx, "acme", &model.CustomerSearch{Query: "somemail", Limit: 2, Offset: 2})
+		require.NoError(err, "failed to search customers")
+		require.Equal(3, total, "total must count all matched customers")
+		require.Len(found, 1, "rest of matched customers must be returned")
+	}
+
+	t.Log("bulk update customers of tenant matching filter")
+	{
+		low := model.ImportanceLow
+		require.NoError(esRps.BulkUpdate(ctx, "acme", &model.CustomerFilter{Importance: &critical}, &model.CustomerPatch{Importance: &low}), "failed to bulk update customers")
+		s.refresh(ctx)
+
+		found, total, err := esRps.Search(ctx, "acme", &model.CustomerSearch{Query: "somemail", Filter: model.CustomerFilter{Importance: &low}, Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Equal(3, total, "customers matching filter must be updated")
+		require.Len(found, 3, "customers matching filter must be updated")
+
+		found, _, err = esRps.Search(ctx, "globex", &model.CustomerSearch{Query: "john", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Equal(model.ImportanceCritical, found[0].Importance, "customers of other tenants must be left untouched")
+	}
+
+	t.Log("delete customers of tenant indexed before reindex")
+	{
+		reindexedAt := time.Now()
+		require.NoError(esRps.Index(ctx, customers[0]), "failed to index customer")
+		require.NoError(esRps.DeleteIndexedBefore(ctx, "acme", reindexedAt), "failed to delete customers")
+		s.refresh(ctx)
+
+		found, total, err := esRps.Search(ctx, "acme", &model.CustomerSearch{Query: "somemail", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Equal(1, total, "customers indexed before must be deleted")
+		require.Equal(customers[0], found[0], "customer indexed after must be kept")
+
+		_, total, err = esRps.Search(ctx, "globex", &model.CustomerSearch{Query: "john", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Equal(1, total, "customers of other tenants must be left untouched")
+	}
+
+	t.Log("delete customer")
+	{
+		require.NoError(esRps.DeleteByID(ctx, "globex", customers[3].ID), "failed to delete customer")
+		require.NoError(esRps.DeleteByID(ctx, "globex", customers[3].ID), "missing customer must be ignored")
+		s.refresh(ctx)
+
+		found, total, err := esRps.Search(ctx, "globex", &model.CustomerSearch{Query: "john", Limit: 10})
+		require.NoError(err, "failed to search customers")
+		require.Zero(total, "deleted customer must not be found")
+		require.Empty(found, "deleted customer must not be found")
+	}
+}
+
+// refresh makes indexed documents searchable immediately
+func (s *elasticsearchTestSuite) refresh(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL+"/"+esTestIndex+"/_refresh", http.NoBody)
+	s.Require().NoError(err, "failed to build refresh request")
+
+	res, err := s.client.Do(req)
+	s.Require().NoError(err, "failed to refresh index")
+	res.Body.Close()
+}
+
+// start elasticsearch test suite
+func TestElasticsearchTestSuite(t *testing.T) {
+	suite.Run(t, new(elasticsearchTestSuite))
+}
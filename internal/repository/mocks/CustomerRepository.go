@@ -22,6 +22,55 @@ func (_m *CustomerRepository) EXPECT() *CustomerRepository_Expecter {
 	return &CustomerRepository_Expecter{mock: &_m.Mock}
 }
 
+// BulkUpdate provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *CustomerRepository) BulkUpdate(_a0 context.Context, _a1 string, _a2 *model.CustomerFilter, _a3 *model.CustomerPatch) ([]string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.CustomerFilter, *model.CustomerPatch) []string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *model.CustomerFilter, *model.CustomerPatch) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CustomerRepository_BulkUpdate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BulkUpdate'
+type CustomerRepository_BulkUpdate_Call struct {
+	*mock.Call
+}
+
+// BulkUpdate is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 string
+//  - _a2 *model.CustomerFilter
+//  - _a3 *model.CustomerPatch
+func (_e *CustomerRepository_Expecter) BulkUpdate(_a0 interface{}, _a1 interface{}, _a2 interface{}, _a3 interface{}) *CustomerRepository_BulkUpdate_Call {
+	return &CustomerRepository_BulkUpdate_Call{Call: _e.mock.On("BulkUpdate", _a0, _a1, _a2, _a3)}
+}
+
+func (_c *CustomerRepository_BulkUpdate_Call) Run(run func(_a0 context.Context, _a1 string, _a2 *model.CustomerFilter, _a3 *model.CustomerPatch)) *CustomerRepository_BulkUpdate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*model.CustomerFilter), args[3].(*model.CustomerPatch))
+	})
+	return _c
+}
+
+func (_c *CustomerRepository_BulkUpdate_Call) Return(_a0 []string, _a1 error) *CustomerRepository_BulkUpdate_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // Create provides a mock function with given fields: _a0, _a1
 func (_m *CustomerRepository) Create(_a0 context.Context, _a1 *model.Customer) error {
 	ret := _m.Called(_a0, _a1)
@@ -60,13 +109,13 @@ func (_c *CustomerRepository_Create_Call) Return(_a0 error) *CustomerRepository_
 	return _c
 }
 
-// DeleteByID provides a mock function with given fields: _a0, _a1
-func (_m *CustomerRepository) DeleteByID(_a0 context.Context, _a1 string) error {
-	ret := _m.Called(_a0, _a1)
+// DeleteByID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerRepository) DeleteByID(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -82,13 +131,14 @@ type CustomerRepository_DeleteByID_Call struct {
 // DeleteByID is a helper method to define mock.On call
 //  - _a0 context.Context
 //  - _a1 string
-func (_e *CustomerRepository_Expecter) DeleteByID(_a0 interface{}, _a1 interface{}) *CustomerRepository_DeleteByID_Call {
-	return &CustomerRepository_DeleteByID_Call{Call: _e.mock.On("DeleteByID", _a0, _a1)}
+//  - _a2 string
+func (_e *CustomerRepository_Expecter) DeleteByID(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerRepository_DeleteByID_Call {
+	return &CustomerRepository_DeleteByID_Call{Call: _e.mock.On("DeleteByID", _a0, _a1, _a2)}
 }
 
-func (_c *CustomerRepository_DeleteByID_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerRepository_DeleteByID_Call {
+func (_c *CustomerRepository_DeleteByID_Call) Run(run func(_a0 context.Context, _a1 string, _a2 string)) *CustomerRepository_DeleteByID_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
@@ -98,13 +148,13 @@ func (_c *CustomerRepository_DeleteByID_Call) Return(_a0 error) *CustomerReposit
 	return _c
 }
 
-// FindAll provides a mock function with given fields: _a0
-func (_m *CustomerRepository) FindAll(_a0 context.Context) ([]*model.Customer, error) {
-	ret := _m.Called(_a0)
+// FindAll provides a mock function with given fields: _a0, _a1
+func (_m *CustomerRepository) FindAll(_a0 context.Context, _a1 string) ([]*model.Customer, error) {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 []*model.Customer
-	if rf, ok := ret.Get(0).(func(context.Context) []*model.Customer); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Customer); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.Customer)
@@ -112,8 +162,8 @@ func (_m *CustomerRepository) FindAll(_a0 context.Context) ([]*model.Customer, e
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -128,13 +178,14 @@ type CustomerRepository_FindAll_Call struct {
 
 // FindAll is a helper method to define mock.On call
 //  - _a0 context.Context
-func (_e *CustomerRepository_Expecter) FindAll(_a0 interface{}) *CustomerRepository_FindAll_Call {
-	return &CustomerRepository_FindAll_Call{Call: _e.mock.On("FindAll", _a0)}
+//  - _a1 string
+func (_e *CustomerRepository_Expecter) FindAll(_a0 interface{}, _a1 interface{}) *CustomerRepository_FindAll_Call {
+	return &CustomerRepository_FindAll_Call{Call: _e.mock.On("FindAll", _a0, _a1)}
 }
 
-func (_c *CustomerRepository_FindAll_Call) Run(run func(_a0 context.Context)) *CustomerRepository_FindAll_Call {
+func (_c *CustomerRepository_FindAll_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerRepository_FindAll_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context))
+		run(args[0].(context.Context), args[1].(string))
 	})
 	return _c
 }
@@ -144,16 +195,16 @@ func (_c *CustomerRepository_FindAll_Call) Return(_a0 []*model.Customer, _a1 err
 	return _c
 }
 
-// FindByID provides a mock function with given fields: _a0, _a1
-func (_m *CustomerRepository) FindByID(_a0 context.Context, _a1 string) (*model.Customer, error) {
+// FindAllActive provides a mock function with given fields: _a0, _a1
+func (_m *CustomerRepository) FindAllActive(_a0 context.Context, _a1 string) ([]*model.Customer, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 *model.Customer
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.Customer); ok {
+	var r0 []*model.Customer
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.Customer); ok {
 		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.Customer)
+			r0 = ret.Get(0).([]*model.Customer)
 		}
 	}
 
@@ -167,6 +218,101 @@ func (_m *CustomerRepository) FindByID(_a0 context.Context, _a1 string) (*model.
 	return r0, r1
 }
 
+// CustomerRepository_FindAllActive_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindAllActive'
+type CustomerRepository_FindAllActive_Call struct {
+	*mock.Call
+}
+
+// FindAllActive is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 string
+func (_e *CustomerRepository_Expecter) FindAllActive(_a0 interface{}, _a1 interface{}) *CustomerRepository_FindAllActive_Call {
+	return &CustomerRepository_FindAllActive_Call{Call: _e.mock.On("FindAllActive", _a0, _a1)}
+}
+
+func (_c *CustomerRepository_FindAllActive_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerRepository_FindAllActive_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *CustomerRepository_FindAllActive_Call) Return(_a0 []*model.Customer, _a1 error) *CustomerRepository_FindAllActive_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// FindByEmail provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerRepository) FindByEmail(_a0 context.Context, _a1 string, _a2 string) (*model.Customer, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *model.Customer
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Customer); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Customer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CustomerRepository_FindByEmail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByEmail'
+type CustomerRepository_FindByEmail_Call struct {
+	*mock.Call
+}
+
+// FindByEmail is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 string
+//  - _a2 string
+func (_e *CustomerRepository_Expecter) FindByEmail(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerRepository_FindByEmail_Call {
+	return &CustomerRepository_FindByEmail_Call{Call: _e.mock.On("FindByEmail", _a0, _a1, _a2)}
+}
+
+func (_c *CustomerRepository_FindByEmail_Call) Run(run func(_a0 context.Context, _a1 string, _a2 string)) *CustomerRepository_FindByEmail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *CustomerRepository_FindByEmail_Call) Return(_a0 *model.Customer, _a1 error) *CustomerRepository_FindByEmail_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+// FindByID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerRepository) FindByID(_a0 context.Context, _a1 string, _a2 string) (*model.Customer, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *model.Customer
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Customer); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Customer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CustomerRepository_FindByID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindByID'
 type CustomerRepository_FindByID_Call struct {
 	*mock.Call
@@ -175,13 +321,14 @@ type CustomerRepository_FindByID_Call struct {
 // FindByID is a helper method to define mock.On call
 //  - _a0 context.Context
 //  - _a1 string
-func (_e *CustomerRepository_Expecter) FindByID(_a0 interface{}, _a1 interface{}) *CustomerRepository_FindByID_Call {
-	return &CustomerRepository_FindByID_Call{Call: _e.mock.On("FindByID", _a0, _a1)}
+//  - _a2 string
+func (_e *CustomerRepository_Expecter) FindByID(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerRepository_FindByID_Call {
+	return &CustomerRepository_FindByID_Call{Call: _e.mock.On("FindByID", _a0, _a1, _a2)}
 }
 
-func (_c *CustomerRepository_FindByID_Call) Run(run func(_a0 context.Context, _a1 string)) *CustomerRepository_FindByID_Call {
+func (_c *CustomerRepository_FindByID_Call) Run(run func(_a0 context.Context, _a1 string, _a2 string)) *CustomerRepository_FindByID_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
@@ -191,6 +338,54 @@ func (_c *CustomerRepository_FindByID_Call) Return(_a0 *model.Customer, _a1 erro
 	return _c
 }
 
+// FindMostImportant provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CustomerRepository) FindMostImportant(_a0 context.Context, _a1 string, _a2 int) ([]*model.Customer, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*model.Customer
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*model.Customer); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Customer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CustomerRepository_FindMostImportant_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindMostImportant'
+type CustomerRepository_FindMostImportant_Call struct {
+	*mock.Call
+}
+
+// FindMostImportant is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 string
+//  - _a2 int
+func (_e *CustomerRepository_Expecter) FindMostImportant(_a0 interface{}, _a1 interface{}, _a2 interface{}) *CustomerRepository_FindMostImportant_Call {
+	return &CustomerRepository_FindMostImportant_Call{Call: _e.mock.On("FindMostImportant", _a0, _a1, _a2)}
+}
+
+func (_c *CustomerRepository_FindMostImportant_Call) Run(run func(_a0 context.Context, _a1 string, _a2 int)) *CustomerRepository_FindMostImportant_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *CustomerRepository_FindMostImportant_Call) Return(_a0 []*model.Customer, _a1 error) *CustomerRepository_FindMostImportant_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // Update provides a mock function with given fields: _a0, _a1
 func (_m *CustomerRepository) Update(_a0 context.Context, _a1 *model.Customer) error {
 	ret := _m.Called(_a0, _a1)
@@ -22,6 +22,51 @@ func (_m *RefreshTokenRepository) EXPECT() *RefreshTokenRepository_Expecter {
 	return &RefreshTokenRepository_Expecter{mock: &_m.Mock}
 }
 
+// Count provides a mock function with given fields: _a0, _a1
+func (_m *RefreshTokenRepository) Count(_a0 context.Context, _a1 *model.RefreshTokenFilter) (int, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, *model.RefreshTokenFilter) int); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.RefreshTokenFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshTokenRepository_Count_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Count'
+type RefreshTokenRepository_Count_Call struct {
+	*mock.Call
+}
+
+// Count is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 *model.RefreshTokenFilter
+func (_e *RefreshTokenRepository_Expecter) Count(_a0 interface{}, _a1 interface{}) *RefreshTokenRepository_Count_Call {
+	return &RefreshTokenRepository_Count_Call{Call: _e.mock.On("Count", _a0, _a1)}
+}
+
+func (_c *RefreshTokenRepository_Count_Call) Run(run func(_a0 context.Context, _a1 *model.RefreshTokenFilter)) *RefreshTokenRepository_Count_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.RefreshTokenFilter))
+	})
+	return _c
+}
+
+func (_c *RefreshTokenRepository_Count_Call) Return(_a0 int, _a1 error) *RefreshTokenRepository_Count_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // Create provides a mock function with given fields: _a0, _a1
 func (_m *RefreshTokenRepository) Create(_a0 context.Context, _a1 *model.RefreshToken) error {
 	ret := _m.Called(_a0, _a1)
@@ -136,6 +181,92 @@ func (_c *RefreshTokenRepository_DeleteByUserID_Call) Return(_a0 error) *Refresh
 	return _c
 }
 
+// DeleteOldestByUserID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *RefreshTokenRepository) DeleteOldestByUserID(_a0 context.Context, _a1 string, _a2 int) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshTokenRepository_DeleteOldestByUserID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteOldestByUserID'
+type RefreshTokenRepository_DeleteOldestByUserID_Call struct {
+	*mock.Call
+}
+
+// DeleteOldestByUserID is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 string
+//  - _a2 int
+func (_e *RefreshTokenRepository_Expecter) DeleteOldestByUserID(_a0 interface{}, _a1 interface{}, _a2 interface{}) *RefreshTokenRepository_DeleteOldestByUserID_Call {
+	return &RefreshTokenRepository_DeleteOldestByUserID_Call{Call: _e.mock.On("DeleteOldestByUserID", _a0, _a1, _a2)}
+}
+
+func (_c *RefreshTokenRepository_DeleteOldestByUserID_Call) Run(run func(_a0 context.Context, _a1 string, _a2 int)) *RefreshTokenRepository_DeleteOldestByUserID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *RefreshTokenRepository_DeleteOldestByUserID_Call) Return(_a0 error) *RefreshTokenRepository_DeleteOldestByUserID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+// FindAll provides a mock function with given fields: _a0, _a1
+func (_m *RefreshTokenRepository) FindAll(_a0 context.Context, _a1 *model.RefreshTokenFilter) ([]*model.RefreshToken, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*model.RefreshToken
+	if rf, ok := ret.Get(0).(func(context.Context, *model.RefreshTokenFilter) []*model.RefreshToken); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.RefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *model.RefreshTokenFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshTokenRepository_FindAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindAll'
+type RefreshTokenRepository_FindAll_Call struct {
+	*mock.Call
+}
+
+// FindAll is a helper method to define mock.On call
+//  - _a0 context.Context
+//  - _a1 *model.RefreshTokenFilter
+func (_e *RefreshTokenRepository_Expecter) FindAll(_a0 interface{}, _a1 interface{}) *RefreshTokenRepository_FindAll_Call {
+	return &RefreshTokenRepository_FindAll_Call{Call: _e.mock.On("FindAll", _a0, _a1)}
+}
+
+func (_c *RefreshTokenRepository_FindAll_Call) Run(run func(_a0 context.Context, _a1 *model.RefreshTokenFilter)) *RefreshTokenRepository_FindAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*model.RefreshTokenFilter))
+	})
+	return _c
+}
+
+func (_c *RefreshTokenRepository_FindAll_Call) Return(_a0 []*model.RefreshToken, _a1 error) *RefreshTokenRepository_FindAll_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
 // FindByID provides a mock function with given fields: _a0, _a1
 func (_m *RefreshTokenRepository) FindByID(_a0 context.Context, _a1 string) (*model.RefreshToken, error) {
 	ret := _m.Called(_a0, _a1)
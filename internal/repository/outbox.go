@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// outboxRelayLockKey is key of postgres advisory lock held by instance relaying outbox
+const outboxRelayLockKey = 7_468_202_451
+
+// EventOutboxRepository represents behavior of outbox keeping customer events which failed to be published,
+// events are read back in order they were saved. TryLock makes caller the only one relaying outbox until returned unlock
+// is called, false is returned if outbox is already relayed by someone else
+type EventOutboxRepository interface {
+	Save(context.Context, *model.CustomerEvent) error
+	FindPending(context.Context, int) ([]*model.CustomerEvent, error)
+	DeleteByID(context.Context, string) error
+	TryLock(context.Context) (func(), bool, error)
+}
+
+type postgresEventOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresEventOutboxRepository builds postgresEventOutboxRepository
+func NewPostgresEventOutboxRepository(p *pgxpool.Pool) EventOutboxRepository {
+	return &postgresEventOutboxRepository{pool: p}
+}
+
+func (r *postgresEventOutboxRepository) Save(ctx context.Context, e *model.CustomerEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to serialize customer event %s - %w", e.ID, err)
+	}
+
+	q := `INSERT INTO customer_events_outbox(event_id, payload, created_at) VALUES($1, $2, $3)
+          ON CONFLICT (event_id) DO NOTHING`
+	if _, err := r.pool.Exec(ctx, q, e.ID, payload, time.Now().UTC()); err != nil {
+		return fmt.Errorf("postgres: failed to save customer event %s to outbox - %w", e.ID, err)
+	}
+	return nil
+}
+
+func (r *postgresEventOutboxRepository) FindPending(ctx context.Context, limit int) ([]*model.CustomerEvent, error) {
+	q := "SELECT payload FROM customer_events_outbox ORDER BY seq LIMIT $1"
+
+	rows, err := r.pool.Query(ctx, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read outbox customer events - %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*model.CustomerEvent, 0)
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan outbox customer event - %w", err)
+		}
+
+		var e model.CustomerEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to deserialize outbox customer event - %w", err)
+		}
+		events = append(events, &e)
+	}
+
+	return events, nil
+}
+
+func (r *postgresEventOutboxRepository) DeleteByID(ctx context.Context, id string) error {
+	q := "DELETE FROM customer_events_outbox WHERE event_id = $1"
+	if _, err := r.pool.Exec(ctx, q, id); err != nil {
+		return fmt.Errorf("postgres: failed to delete customer event %s from outbox - %w", id, err)
+	}
+	return nil
+}
+
+// TryLock takes session advisory lock, so it is held on dedicated connection until unlock is called
+func (r *postgresEventOutboxRepository) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("postgres: failed to acquire connection to lock outbox - %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", outboxRelayLockKey).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("postgres: failed to lock outbox - %w", err)
+	}
+
+	if !locked {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// lock is released with session anyway, so connection is closed if lock can't be released explicitly
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", outboxRelayLockKey); err != nil {
+			_ = conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+	return unlock, true, nil
+}
+
+type inMemoryEventOutboxRepository struct {
+	events []model.CustomerEvent
+	mu     sync.RWMutex
+	relay  sync.Mutex
+}
+
+// NewInMemoryEventOutboxRepository builds new in-memory event outbox repository
+func NewInMemoryEventOutboxRepository() EventOutboxRepository {
+	return &inMemoryEventOutboxRepository{events: make([]model.CustomerEvent, 0)}
+}
+
+func (r *inMemoryEventOutboxRepository) Save(_ context.Context, e *model.CustomerEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, saved := range r.events {
+		if saved.ID == e.ID {
+			return nil
+		}
+	}
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r *inMemoryEventOutboxRepository) FindPending(_ context.Context, limit int) ([]*model.CustomerEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*model.CustomerEvent, 0)
+	for i := 0; i < len(r.events) && i < limit; i++ {
+		e := r.events[i]
+		events = append(events, &e)
+	}
+	return events, nil
+}
+
+func (r *inMemoryEventOutboxRepository) DeleteByID(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, e := range r.events {
+		if e.ID == id {
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryEventOutboxRepository) TryLock(_ context.Context) (func(), bool, error) {
+	if !r.relay.TryLock() {
+		return nil, false, nil
+	}
+	return r.relay.Unlock, true, nil
+}
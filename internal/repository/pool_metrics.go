@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/umalmyha/customers/internal/config"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolStats is snapshot of connection pool state
+type PoolStats struct {
+	Acquired int64
+	Idle     int64
+	Total    int64
+}
+
+// PoolStatsSource provides current stats of connection pool
+type PoolStatsSource interface {
+	Stats() PoolStats
+}
+
+// PoolStatsSourceFunc is adapter allowing to use ordinary function as PoolStatsSource
+type PoolStatsSourceFunc func() PoolStats
+
+// Stats calls f()
+func (f PoolStatsSourceFunc) Stats() PoolStats {
+	return f()
+}
+
+// PgxPoolStats builds stats source reading stats of pgx pool
+func PgxPoolStats(pool *pgxpool.Pool) PoolStatsSource {
+	return PoolStatsSourceFunc(func() PoolStats {
+		stat := pool.Stat()
+		return PoolStats{
+			Acquired: int64(stat.AcquiredConns()),
+			Idle:     int64(stat.IdleConns()),
+			Total:    int64(stat.TotalConns()),
+		}
+	})
+}
+
+// MongoPoolMonitor keeps track of connections of mongo client pools based on pool events,
+// since mongo driver doesn't expose pool stats. Connections of all servers are summed up
+type MongoPoolMonitor struct {
+	total    int64
+	acquired int64
+}
+
+// NewMongoPoolMonitor builds new MongoPoolMonitor, its Monitor must be set to client options before connect
+func NewMongoPoolMonitor() *MongoPoolMonitor {
+	return &MongoPoolMonitor{}
+}
+
+// Monitor returns pool monitor for mongo client options
+func (m *MongoPoolMonitor) Monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: m.handle}
+}
+
+func (m *MongoPoolMonitor) handle(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		atomic.AddInt64(&m.total, 1)
+	case event.ConnectionClosed:
+		atomic.AddInt64(&m.total, -1)
+	case event.GetSucceeded:
+		atomic.AddInt64(&m.acquired, 1)
+	case event.ConnectionReturned:
+		atomic.AddInt64(&m.acquired, -1)
+	}
+}
+
+// Stats returns current stats of mongo client pools
+func (m *MongoPoolMonitor) Stats() PoolStats {
+	total := atomic.LoadInt64(&m.total)
+	acquired := atomic.LoadInt64(&m.acquired)
+
+	idle := total - acquired
+	if idle < 0 {
+		idle = 0
+	}
+	return PoolStats{Acquired: acquired, Idle: idle, Total: total}
+}
+
+// PoolMetricsCollector periodically reads stats of connection pools and exposes them as gauges labeled by pool name
+type PoolMetricsCollector struct {
+	sources  map[string]PoolStatsSource
+	interval time.Duration
+	acquired *prometheus.GaugeVec
+	idle     *prometheus.GaugeVec
+	total    *prometheus.GaugeVec
+}
+
+// NewPoolMetricsCollector builds new PoolMetricsCollector for pools by name and registers its gauges
+func NewPoolMetricsCollector(sources map[string]PoolStatsSource, reg prometheus.Registerer, cfg *config.PoolMetricsCfg) *PoolMetricsCollector {
+	c := &PoolMetricsCollector{
+		sources:  sources,
+		interval: cfg.Interval,
+		acquired: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_pool_acquired_connections",
+			Help: "Number of connections currently acquired from pool",
+		}, []string{"pool"}),
+		idle: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_pool_idle_connections",
+			Help: "Number of idle connections in pool",
+		}, []string{"pool"}),
+		total: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_pool_total_connections",
+			Help: "Total number of connections in pool",
+		}, []string{"pool"}),
+	}
+	reg.MustRegister(c.acquired, c.idle, c.total)
+
+	return c
+}
+
+// Run updates gauges every interval until context is cancelled
+func (c *PoolMetricsCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.Update()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Update()
+		}
+	}
+}
+
+// Update reads stats of all pools and updates gauges
+func (c *PoolMetricsCollector) Update() {
+	for name, src := range c.sources {
+		stats := src.Stats()
+		c.acquired.WithLabelValues(name).Set(float64(stats.Acquired))
+		c.idle.WithLabelValues(name).Set(float64(stats.Idle))
+		c.total.WithLabelValues(name).Set(float64(stats.Total))
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// fakePoolStats is stats source which stats can be changed by test
+type fakePoolStats struct {
+	mu    sync.Mutex
+	stats PoolStats
+}
+
+func (f *fakePoolStats) Stats() PoolStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+func (f *fakePoolStats) set(stats PoolStats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats = stats
+}
+
+type poolMetricsTestSuite struct {
+	suite.Suite
+	reg       *prometheus.Registry
+	pgStats   *fakePoolStats
+	collector *PoolMetricsCollector
+}
+
+func (s *poolMetricsTestSuite) SetupTest() {
+	s.reg = prometheus.NewRegistry()
+	s.pgStats = &fakePoolStats{stats: PoolStats{Acquired: 2, Idle: 3, Total: 5}}
+	s.collector = NewPoolMetricsCollector(
+		map[string]PoolStatsSource{"postgres": s.pgStats},
+		s.reg,
+		&config.PoolMetricsCfg{Interval: 10 * time.Millisecond},
+	)
+}
+
+func (s *poolMetricsTestSuite) TestUpdate() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("gauges are registered and set from stats source")
+	{
+		s.collector.Update()
+
+		n, err := testutil.GatherAndCount(s.reg, "db_pool_acquired_connections", "db_pool_idle_connections", "db_pool_total_connections")
+		require.NoError(err, "failed to gather metrics")
+		require.Equal(3, n, "gauge per stat must be registered")
+
+		require.Equal(2.0, testutil.ToFloat64(s.collector.acquired.WithLabelValues("postgres")), "incorrect acquired connections")
+		require.Equal(3.0, testutil.ToFloat64(s.collector.idle.WithLabelValues("postgres")), "incorrect idle connections")
+		require.Equal(5.0, testutil.ToFloat64(s.collector.total.WithLabelValues("postgres")), "incorrect total connections")
+	}
+}
+
+func (s *poolMetricsTestSuite) TestRun() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.collector.Run(ctx)
+
+	t.Log("gauges are updated periodically")
+	{
+		require.Eventually(func() bool {
+			return testutil.ToFloat64(s.collector.total.WithLabelValues("postgres")) == 5
+		}, time.Second, 5*time.Millisecond, "gauges must be set on start")
+
+		s.pgStats.set(PoolStats{Acquired: 4, Idle: 6, Total: 10})
+		require.Eventually(func() bool {
+			return testutil.ToFloat64(s.collector.acquired.WithLabelValues("postgres")) == 4 &&
+				testutil.ToFloat64(s.collector.idle.WithLabelValues("postgres")) == 6 &&
+				testutil.ToFloat64(s.collector.total.WithLabelValues("postgres")) == 10
+		}, time.Second, 5*time.Millisecond, "gauges must be updated")
+	}
+}
+
+func (s *poolMetricsTestSuite) TestMongoPoolMonitor() {
+	t := s.T()
+	require := s.Require()
+
+	m := NewMongoPoolMonitor()
+	monitor := m.Monitor()
+
+	t.Log("connections are counted based on pool events")
+	{
+		for _, typ := range []string{
+			event.ConnectionCreated,
+			event.ConnectionCreated,
+			event.ConnectionCreated,
+			event.GetSucceeded,
+			event.GetSucceeded,
+			event.ConnectionReturned,
+			event.ConnectionClosed,
+			event.GetStarted,
+		} {
+			monitor.Event(&event.PoolEvent{Type: typ})
+		}
+
+		require.Equal(PoolStats{Acquired: 1, Idle: 1, Total: 2}, m.Stats(), "incorrect mongo pool stats")
+	}
+}
+
+// start pool metrics test suite
+func TestPoolMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(poolMetricsTestSuite))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/umalmyha/customers/internal/model"
@@ -15,8 +16,11 @@ type RefreshTokenRepository interface {
 	Create(context.Context, *model.RefreshToken) error
 	FindTokensByUserID(context.Context, string) ([]*model.RefreshToken, error)
 	DeleteByUserID(context.Context, string) error
+	DeleteOldestByUserID(context.Context, string, int) error
 	DeleteByID(context.Context, string) error
 	FindByID(context.Context, string) (*model.RefreshToken, error)
+	FindAll(context.Context, *model.RefreshTokenFilter) ([]*model.RefreshToken, error)
+	Count(context.Context, *model.RefreshTokenFilter) (int, error)
 }
 
 type postgresRefreshTokenRepository struct {
@@ -30,7 +34,7 @@ func NewPostgresRefreshTokenRepository(e transactor.PgxWithinTransactionExecutor
 
 func (r *postgresRefreshTokenRepository) Create(ctx context.Context, tkn *model.RefreshToken) error {
 	q := "INSERT INTO refresh_tokens(id, user_id, fingerprint, expires_in, created_at) VALUES($1, $2, $3, $4, $5)"
-	if _, err := r.Executor(ctx).Exec(ctx, q, tkn.ID, tkn.UserID, tkn.Fingerprint, tkn.ExpiresIn, tkn.CreatedAt); err != nil {
+	if _, err := r.Executor(ctx).Exec(ctx, q, tkn.ID, tkn.UserID, tkn.Fingerprint, tkn.ExpiresIn, tkn.CreatedAt.UTC()); err != nil {
 		return fmt.Errorf("postgres: failed to create refresh token %s - %w", tkn.ID, err)
 	}
 	return nil
@@ -51,6 +55,7 @@ func (r *postgresRefreshTokenRepository) FindTokensByUserID(ctx context.Context,
 		if err := rows.Scan(&tkn.ID, &tkn.UserID, &tkn.Fingerprint, &tkn.ExpiresIn, &tkn.CreatedAt); err != nil {
 			return nil, fmt.Errorf("postgres: failed to scan refresh token while reading for user id %s - %w", userID, err)
 		}
+		tkn.CreatedAt = tkn.CreatedAt.UTC()
 		tokens = append(tokens, &tkn)
 	}
 
@@ -65,6 +70,16 @@ func (r *postgresRefreshTokenRepository) DeleteByUserID(ctx context.Context, use
 	return nil
 }
 
+func (r *postgresRefreshTokenRepository) DeleteOldestByUserID(ctx context.Context, userID string, keep int) error {
+	q := `DELETE FROM refresh_tokens WHERE id IN (
+            SELECT id FROM refresh_tokens WHERE user_id = $1 ORDER BY created_at DESC OFFSET $2
+          )`
+	if _, err := r.Executor(ctx).Exec(ctx, q, userID, keep); err != nil {
+		return fmt.Errorf("postgres: failed to delete oldest tokens for user id %s - %w", userID, err)
+	}
+	return nil
+}
+
 func (r *postgresRefreshTokenRepository) DeleteByID(ctx context.Context, id string) error {
 	q := "DELETE FROM refresh_tokens WHERE id = $1"
 	if _, err := r.Executor(ctx).Exec(ctx, q, id); err != nil {
@@ -79,6 +94,50 @@ func (r *postgresRefreshTokenRepository) FindByID(ctx context.Context, id string
 	return r.scanRow(row)
 }
 
+func (r *postgresRefreshTokenRepository) FindAll(ctx context.Context, filter *model.RefreshTokenFilter) ([]*model.RefreshToken, error) {
+	where, args := refreshTokensWhere(filter)
+	q := "SELECT id, user_id, fingerprint, expires_in, created_at FROM refresh_tokens" + where + " ORDER BY created_at DESC, id"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		q += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		q += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.Executor(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read refresh tokens - %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*model.RefreshToken, 0)
+	for rows.Next() {
+		var tkn model.RefreshToken
+		if err := rows.Scan(&tkn.ID, &tkn.UserID, &tkn.Fingerprint, &tkn.ExpiresIn, &tkn.CreatedAt); err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan refresh token - %w", err)
+		}
+		tkn.CreatedAt = tkn.CreatedAt.UTC()
+		tokens = append(tokens, &tkn)
+	}
+
+	return tokens, nil
+}
+
+func (r *postgresRefreshTokenRepository) Count(ctx context.Context, filter *model.RefreshTokenFilter) (int, error) {
+	where, args := refreshTokensWhere(filter)
+	q := "SELECT COUNT(*) FROM refresh_tokens" + where
+
+	var count int
+	if err := r.Executor(ctx).QueryRow(ctx, q, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("postgres: failed to count refresh tokens - %w", err)
+	}
+	return count, nil
+}
+
 func (r *postgresRefreshTokenRepository) scanRow(row pgx.Row) (*model.RefreshToken, error) {
 	var tkn model.RefreshToken
 	if err := row.Scan(&tkn.ID, &tkn.UserID, &tkn.Fingerprint, &tkn.ExpiresIn, &tkn.CreatedAt); err != nil {
@@ -87,5 +146,27 @@ func (r *postgresRefreshTokenRepository) scanRow(row pgx.Row) (*model.RefreshTok
 		}
 		return nil, fmt.Errorf("postgres: failed to scan token - %w", err)
 	}
+	tkn.CreatedAt = tkn.CreatedAt.UTC() // driver returns timestamps in local time zone
 	return &tkn, nil
 }
+
+// refreshTokensWhere builds WHERE clause for filter, returned args are referenced by clause placeholders
+func refreshTokensWhere(f *model.RefreshTokenFilter) (string, []any) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+
+	if f.UserID != "" {
+		args = append(args, f.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	if !f.ActiveAt.IsZero() {
+		args = append(args, f.ActiveAt.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at + expires_in * INTERVAL '1 second' > $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
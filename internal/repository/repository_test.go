@@ -4,13 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/pkg/db/transactor"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,18 +44,26 @@ const (
 	mongoTestPassword  = "rps-test"
 )
 
+const (
+	mongoReplicaSetContainerName = "mongo-rs-rps-test-customers"
+	mongoReplicaSetPort          = "27018"
+	mongoReplicaSetName          = "rs0"
+)
+
 type repositoryDockerResources struct {
-	postgres *dockertest.Resource
-	mongodb  *dockertest.Resource
-	network  *docker.Network
+	postgres        *dockertest.Resource
+	mongodb         *dockertest.Resource
+	mongoReplicaSet *dockertest.Resource
+	network         *docker.Network
 }
 
 type repositoryTestSuite struct {
 	suite.Suite
-	dockerPool  *dockertest.Pool
-	resources   repositoryDockerResources
-	pgPool      *pgxpool.Pool
-	mongoClient *mongo.Client
+	dockerPool    *dockertest.Pool
+	resources     repositoryDockerResources
+	pgPool        *pgxpool.Pool
+	mongoClient   *mongo.Client
+	mongoRsClient *mongo.Client // replica set member, since transactions aren't supported by standalone server
 }
 
 func (s *repositoryTestSuite) SetupSuite() {
@@ -101,6 +113,7 @@ func (s *repositoryTestSuite) SetupSuite() {
 		fmt.Sprintf("-user=%s", pgTestUser),
 		fmt.Sprintf("-password=%s", pgTestPassword),
 		"-connectRetries=10",
+		"-placeholders.customersEmailUniqueness=tenant",
 		"migrate",
 	}
 
@@ -181,6 +194,62 @@ func (s *repositoryTestSuite) SetupSuite() {
 		return s.mongoClient.Ping(ctx, readpref.Primary())
 	})
 	assert.NoError(err, "failed to establish connection to mongodb")
+
+	// start single member mongo replica set
+	t.Log("starting mongodb replica set...")
+	mongoReplicaSet, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Name:       mongoReplicaSetContainerName,
+		Repository: "mongo",
+		Tag:        "latest",
+		NetworkID:  network.ID,
+		Cmd:        []string{"--replSet", mongoReplicaSetName, "--bind_ip_all"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"27017/tcp": {{HostIP: "localhost", HostPort: fmt.Sprintf("%s/tcp", mongoReplicaSetPort)}},
+		},
+	})
+	assert.NoError(err, "failed to start mongodb replica set")
+
+	s.resources.mongoReplicaSet = mongoReplicaSet // assign mongodb replica set
+
+	// connect to replica set member directly and initiate replica set
+	t.Log("initiating mongodb replica set...")
+	mongoRsUri := fmt.Sprintf("mongodb://localhost:%s/?directConnection=true", mongoReplicaSetPort)
+	err = dockerPool.Retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+		defer cancel()
+
+		var err error
+		s.mongoRsClient, err = mongo.Connect(ctx, options.Client().ApplyURI(mongoRsUri))
+		if err != nil {
+			return err
+		}
+		return s.mongoRsClient.Ping(ctx, readpref.Primary())
+	})
+	assert.NoError(err, "failed to establish connection to mongodb replica set")
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	err = s.mongoRsClient.Database("admin").RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: bson.M{
+		"_id":     mongoReplicaSetName,
+		"members": bson.A{bson.M{"_id": 0, "host": "localhost:27017"}},
+	}}}).Err()
+	assert.NoError(err, "failed to initiate mongodb replica set")
+
+	err = dockerPool.Retry(func() error {
+		var res struct {
+			IsWritablePrimary bool `bson:"ismaster"`
+		}
+		if err := s.mongoRsClient.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+			return err
+		}
+
+		if !res.IsWritablePrimary {
+			return errors.New("replica set member isn't elected as primary yet")
+		}
+		return nil
+	})
+	assert.NoError(err, "failed to await mongodb replica set primary")
 }
 
 func (s *repositoryTestSuite) TearDownSuite() {
@@ -200,6 +269,15 @@ func (s *repositoryTestSuite) TearDownSuite() {
 		cancel()
 	}
 
+	if s.mongoRsClient != nil {
+		t.Log("closing connection to mongodb replica set")
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		if err := s.mongoRsClient.Disconnect(ctx); err != nil {
+			t.Logf("failed to gracefully close connection to mongodb replica set - %v", err)
+		}
+		cancel()
+	}
+
 	resources := s.resources
 
 	if resources.postgres != nil {
@@ -214,6 +292,12 @@ func (s *repositoryTestSuite) TearDownSuite() {
 		}
 	}
 
+	if resources.mongoReplicaSet != nil {
+		if err := s.dockerPool.Purge(resources.mongoReplicaSet); err != nil {
+			t.Logf("failed to purge mongodb replica set container - %v", err)
+		}
+	}
+
 	if resources.network != nil {
 		if err := s.dockerPool.Client.RemoveNetwork(resources.network.ID); err != nil {
 			t.Logf("failed to delete network - %v", err)
@@ -303,7 +387,7 @@ func (s *repositoryTestSuite) TestRefreshTokenRps() {
 			UserID:      userJohn.ID,
 			Fingerprint: fingerprint,
 			ExpiresIn:   expiresIn,
-			CreatedAt:   createdAt,
+			CreatedAt:   createdAt.Add(time.Minute),
 		},
 		{
 			ID:          "112a54c0-e744-4712-8acf-59e6b1a386e5",
@@ -342,6 +426,17 @@ func (s *repositoryTestSuite) TestRefreshTokenRps() {
 		require.Equal(expected, actual, "%d tokens where created for user %s, got %d", expected, userJohn.Email, actual)
 	}
 
+	t.Logf("delete oldest tokens for user %s keeping the newest one", userJohn.Email)
+	{
+		err := rfrTokenRps.DeleteOldestByUserID(ctx, userJohn.ID, 1)
+		require.NoError(err, "failed to delete oldest tokens")
+
+		johnDBTokens, err := rfrTokenRps.FindTokensByUserID(ctx, userJohn.ID)
+		require.NoError(err, "failed to read tokens")
+		require.Len(johnDBTokens, 1, "only the newest token must be kept for user %s", userJohn.Email)
+		require.Equal(refreshTokens[1].ID, johnDBTokens[0].ID, "the newest token must be kept for user %s", userJohn.Email)
+	}
+
 	t.Logf("delete tokens for user %s", userJohn.Email)
 	{
 		err := rfrTokenRps.DeleteByUserID(ctx, userJohn.ID)
@@ -364,6 +459,29 @@ func (s *repositoryTestSuite) TestRefreshTokenRps() {
 		require.NotNil(henryDBToken, "token was created for user %s, but not found in postgres", userHenry.Email)
 	}
 
+	t.Logf("token created in non-UTC time zone is read in UTC for user %s", userHenry.Email)
+	{
+		minskToken := &model.RefreshToken{
+			ID:          "7d3e9f1a-2b4c-4d5e-8f6a-9b0c1d2e3f4a",
+			UserID:      userHenry.ID,
+			Fingerprint: fingerprint,
+			ExpiresIn:   expiresIn,
+			CreatedAt:   time.Date(2022, time.August, 1, 10, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60)),
+		}
+
+		err := rfrTokenRps.Create(ctx, minskToken)
+		require.NoError(err, "failed to create token %s", minskToken.ID)
+
+		dbToken, err := rfrTokenRps.FindByID(ctx, minskToken.ID)
+		require.NoError(err, "failed to read token")
+		require.Equal(time.UTC, dbToken.CreatedAt.Location(), "token must be read in UTC")
+		require.Equal(time.Date(2022, time.August, 1, 7, 0, 0, 0, time.UTC), dbToken.CreatedAt, "token must be stored at provided moment")
+		require.Equal(minskToken.ExpiresAt(), dbToken.ExpiresAt(), "token expiration must not depend on time zone")
+
+		err = rfrTokenRps.DeleteByID(ctx, minskToken.ID)
+		require.NoError(err, "failed to delete token")
+	}
+
 	t.Logf("delete user %s token", userHenry.Email)
 	{
 		err := rfrTokenRps.DeleteByID(ctx, henryToken.ID)
@@ -378,14 +496,559 @@ func (s *repositoryTestSuite) TestRefreshTokenRps() {
 	}
 }
 
+func (s *repositoryTestSuite) TestRefreshTokenRpsFilter() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	fingerprint := "5a1e7c3d-9b2f-4e8a-b6d4-0c3f1a2e9d7b"
+
+	userRps := NewPostgresUserRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool))
+	rfrTokenRps := NewPostgresRefreshTokenRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool))
+
+	userAnna := &model.User{
+		ID:           "c2d3e4f5-a6b7-4c8d-9e0f-1a2b3c4d5e6f",
+		Email:        "anna@somemail.com",
+		PasswordHash: "5f4dcc3b5aa765d61d8327deb882cf99",
+	}
+
+	userMark := &model.User{
+		ID:           "d3e4f5a6-b7c8-4d9e-8f0a-2b3c4d5e6f7a",
+		Email:        "mark@somemail.com",
+		PasswordHash: "e99a18c428cb38d5f260853678922e03",
+	}
+
+	// anna has 2 active and 1 expired token, mark has 1 active and 1 expired token
+	refreshTokens := []*model.RefreshToken{
+		{ID: "0a1b2c3d-4e5f-4a6b-8c7d-8e9f0a1b2c3d", UserID: userAnna.ID, Fingerprint: fingerprint, ExpiresIn: 3600, CreatedAt: now.Add(-time.Minute)},
+		{ID: "1b2c3d4e-5f6a-4b7c-9d8e-9f0a1b2c3d4e", UserID: userAnna.ID, Fingerprint: fingerprint, ExpiresIn: 3600, CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "2c3d4e5f-6a7b-4c8d-ae9f-0a1b2c3d4e5f", UserID: userAnna.ID, Fingerprint: fingerprint, ExpiresIn: 60, CreatedAt: now.Add(-time.Hour)},
+		{ID: "3d4e5f6a-7b8c-4d9e-bf0a-1b2c3d4e5f6a", UserID: userMark.ID, Fingerprint: fingerprint, ExpiresIn: 3600, CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "4e5f6a7b-8c9d-4eaf-8a1b-2c3d4e5f6a7b", UserID: userMark.ID, Fingerprint: fingerprint, ExpiresIn: 60, CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	t.Log("reference users and tokens must be added")
+	{
+		_, err := s.pgPool.Exec(ctx, "DELETE FROM refresh_tokens")
+		require.NoError(err, "failed to clean up refresh tokens")
+
+		for _, u := range []*model.User{userAnna, userMark} {
+			err := userRps.Create(ctx, u)
+			require.NoError(err, "failed to create user %s", u.Email)
+		}
+
+		for _, tkn := range refreshTokens {
+			err := rfrTokenRps.Create(ctx, tkn)
+			require.NoError(err, "failed to create token %s", tkn.ID)
+		}
+	}
+
+	tokenIDs := func(tokens []*model.RefreshToken) []string {
+		ids := make([]string, len(tokens))
+		for i, tkn := range tokens {
+			ids[i] = tkn.ID
+		}
+		return ids
+	}
+
+	testCases := []struct {
+		name     string
+		filter   *model.RefreshTokenFilter
+		expected []*model.RefreshToken
+		total    int
+	}{
+		{
+			name:     "no filters",
+			filter:   &model.RefreshTokenFilter{},
+			expected: []*model.RefreshToken{refreshTokens[0], refreshTokens[1], refreshTokens[3], refreshTokens[2], refreshTokens[4]},
+			total:    5,
+		},
+		{
+			name:     "by user",
+			filter:   &model.RefreshTokenFilter{UserID: userMark.ID},
+			expected: []*model.RefreshToken{refreshTokens[3], refreshTokens[4]},
+			total:    2,
+		},
+		{
+			name:     "active only",
+			filter:   &model.RefreshTokenFilter{ActiveAt: now},
+			expected: []*model.RefreshToken{refreshTokens[0], refreshTokens[1], refreshTokens[3]},
+			total:    3,
+		},
+		{
+			name:     "by user active only",
+			filter:   &model.RefreshTokenFilter{UserID: userAnna.ID, ActiveAt: now},
+			expected: []*model.RefreshToken{refreshTokens[0], refreshTokens[1]},
+			total:    2,
+		},
+		{
+			name:     "page",
+			filter:   &model.RefreshTokenFilter{Limit: 2, Offset: 1},
+			expected: []*model.RefreshToken{refreshTokens[1], refreshTokens[3]},
+			total:    5,
+		},
+		{
+			name:     "by user active only page",
+			filter:   &model.RefreshTokenFilter{UserID: userAnna.ID, ActiveAt: now, Limit: 1, Offset: 1},
+			expected: []*model.RefreshToken{refreshTokens[1]},
+			total:    2,
+		},
+		{
+			name:     "page beyond last token",
+			filter:   &model.RefreshTokenFilter{Limit: 2, Offset: 10},
+			expected: []*model.RefreshToken{},
+			total:    5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Logf("find tokens - %s", tc.name)
+		{
+			tokens, err := rfrTokenRps.FindAll(ctx, tc.filter)
+			require.NoError(err, "failed to read tokens")
+			require.Equal(tokenIDs(tc.expected), tokenIDs(tokens), "incorrect tokens returned")
+
+			total, err := rfrTokenRps.Count(ctx, tc.filter)
+			require.NoError(err, "failed to count tokens")
+			require.Equal(tc.total, total, "incorrect total returned")
+		}
+	}
+}
+
 func (s *repositoryTestSuite) TestPostgresCustomerRps() {
 	s.T().Log("running tests for postgres")
-	s.testCustomerRps(NewPostgresCustomerRepository(s.pgPool))
+	s.testCustomerRps(NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessTenant, &config.RepositoryCfg{}))
+}
+
+func (s *repositoryTestSuite) TestPostgresCustomerRpsGlobalEmail() {
+	s.T().Log("running tests for postgres with globally unique email")
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	// the same index R__customers_email_uniqueness migration creates if customersEmailUniqueness is global
+	_, err := s.pgPool.Exec(ctx, "CREATE UNIQUE INDEX "+pgGlobalEmailIndex+" ON customers(email)")
+	s.Require().NoError(err, "failed to create global email index")
+	defer func() {
+		_, err := s.pgPool.Exec(ctx, "DROP INDEX "+pgGlobalEmailIndex)
+		s.Require().NoError(err, "failed to drop global email index")
+	}()
+
+	s.testCustomerRpsGlobalEmail(NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessGlobal, &config.RepositoryCfg{}))
+}
+
+func (s *repositoryTestSuite) TestVerifyPostgresCustomers() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	t.Log("schema migrated for tenant uniqueness matches tenant config")
+	{
+		require.NoError(VerifyPostgresCustomers(ctx, s.pgPool, config.EmailUniquenessTenant), "schema must match config")
+	}
+
+	t.Log("schema migrated for tenant uniqueness doesn't match global config")
+	{
+		err := VerifyPostgresCustomers(ctx, s.pgPool, config.EmailUniquenessGlobal)
+		require.ErrorContains(err, pgGlobalEmailIndex, "missing index must be reported")
+	}
+
+	_, err := s.pgPool.Exec(ctx, "CREATE UNIQUE INDEX "+pgGlobalEmailIndex+" ON customers(email)")
+	require.NoError(err, "failed to create global email index")
+	defer func() {
+		_, err := s.pgPool.Exec(ctx, "DROP INDEX "+pgGlobalEmailIndex)
+		require.NoError(err, "failed to drop global email index")
+	}()
+
+	t.Log("schema migrated for global uniqueness matches global config")
+	{
+		require.NoError(VerifyPostgresCustomers(ctx, s.pgPool, config.EmailUniquenessGlobal), "schema must match config")
+	}
+
+	t.Log("schema migrated for global uniqueness doesn't match tenant config")
+	{
+		err := VerifyPostgresCustomers(ctx, s.pgPool, config.EmailUniquenessTenant)
+		require.ErrorContains(err, pgGlobalEmailIndex, "unexpected index must be reported")
+	}
+}
+
+func (s *repositoryTestSuite) TestPostgresEventOutboxRps() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	outboxRps := NewPostgresEventOutboxRepository(s.pgPool)
+	anotherOutboxRps := NewPostgresEventOutboxRepository(s.pgPool) // outbox shared by another instance
+
+	ids := []string{"3a4b5c6d-7e8f-4a9b-8c0d-1e2f3a4b5c6d", "4b5c6d7e-8f9a-4b0c-9d1e-2f3a4b5c6d7e"}
+
+	t.Log("events are read back in order they were saved")
+	{
+		for _, id := range ids {
+			require.NoError(outboxRps.Save(ctx, &model.CustomerEvent{ID: id, Type: model.CustomerUpdated, TenantID: "acme"}), "failed to save event")
+		}
+
+		events, err := outboxRps.FindPending(ctx, 10)
+		require.NoError(err, "failed to read events")
+		require.Len(events, 2, "saved events must be read")
+		require.Equal(ids[0], events[0].ID, "events must be read in order")
+		require.Equal(ids[1], events[1].ID, "events must be read in order")
+	}
+
+	t.Log("outbox is relayed by single instance at a time")
+	{
+		unlock, locked, err := outboxRps.TryLock(ctx)
+		require.NoError(err, "failed to lock outbox")
+		require.True(locked, "outbox must be locked")
+
+		_, locked, err = anotherOutboxRps.TryLock(ctx)
+		require.NoError(err, "failed to lock outbox")
+		require.False(locked, "outbox locked by another instance must not be locked")
+
+		unlock()
+
+		unlock, locked, err = anotherOutboxRps.TryLock(ctx)
+		require.NoError(err, "failed to lock outbox")
+		require.True(locked, "released outbox must be locked")
+		unlock()
+	}
+
+	for _, id := range ids {
+		require.NoError(outboxRps.DeleteByID(ctx, id), "failed to delete event")
+	}
+}
+
+func (s *repositoryTestSuite) TestPostgresCustomerRpsBestEffortReads() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	tenantID := "initech"
+	customer := &model.Customer{
+		TenantID:   tenantID,
+		ID:         uuid.NewString(),
+		FirstName:  "Peter",
+		LastName:   "Gibbons",
+		Email:      "petergibbons@somemal.com",
+		Importance: model.ImportanceMedium,
+	}
+	malformedID := uuid.NewString()
+
+	strictRps := NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessTenant, &config.RepositoryCfg{})
+	bestEffortRps := NewPostgresCustomerRepository(transactor.NewPgxWithinTransactionExecutor(s.pgPool), config.EmailUniquenessTenant, &config.RepositoryCfg{BestEffortReads: true})
+
+	t.Log("create customer and row which can't be read as customer")
+	{
+		require.NoError(strictRps.Create(ctx, customer), "failed to create customer")
+
+		_, err := s.pgPool.Exec(
+			ctx,
+			"INSERT INTO customers(id, tenant_id, first_name, last_name, email, importance) VALUES($1, $2, 'Bill', 'Lumbergh', 'billlumbergh@somemal.com', NULL)",
+			malformedID,
+			tenantID,
+		)
+		require.NoError(err, "failed to insert malformed customer")
+	}
+	defer func() {
+		_, err := s.pgPool.Exec(ctx, "DELETE FROM customers WHERE tenant_id = $1", tenantID)
+		require.NoError(err, "failed to delete customers")
+	}()
+
+	t.Log("strict read fails on malformed row")
+	{
+		_, err := strictRps.FindAll(ctx, tenantID)
+		require.ErrorContains(err, malformedID, "malformed row must fail strict read")
+	}
+
+	t.Log("best effort read skips malformed row")
+	{
+		customers, err := bestEffortRps.FindAll(ctx, tenantID)
+		require.NoError(err, "malformed row must be skipped")
+		require.Equal([]*model.Customer{customer}, customers, "only readable customers must be returned")
+
+		customers, err = bestEffortRps.FindAllActive(ctx, tenantID)
+		require.NoError(err, "malformed row must be skipped")
+		require.Equal([]*model.Customer{customer}, customers, "only readable active customers must be returned")
+	}
+}
+
+func (s *repositoryTestSuite) TestPostgresActiveCustomersIndex() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	conn, err := s.pgPool.Acquire(ctx)
+	require.NoError(err, "failed to acquire connection")
+	defer conn.Release()
+
+	// table is tiny, so sequential scan is forbidden to see whether index can be used at all
+	_, err = conn.Exec(ctx, "SET enable_seqscan = off")
+	require.NoError(err, "failed to disable sequential scan")
+	defer func() {
+		_, err := conn.Exec(context.Background(), "RESET enable_seqscan")
+		require.NoError(err, "failed to reset sequential scan")
+	}()
+
+	explain := func(q string, args ...any) string {
+		rows, err := conn.Query(ctx, "EXPLAIN "+q, args...)
+		require.NoError(err, "failed to explain query")
+		defer rows.Close()
+
+		plan := make([]string, 0)
+		for rows.Next() {
+			var line string
+			require.NoError(rows.Scan(&line), "failed to scan plan")
+			plan = append(plan, line)
+		}
+		require.NoError(rows.Err(), "failed to read plan")
+		return strings.Join(plan, "\n")
+	}
+
+	t.Log("active customers query uses partial index")
+	{
+		plan := explain(pgFindAllActiveCustomersQuery, "acme")
+		require.Contains(plan, "customers_tenant_active_idx", "partial index must be used, plan:\n%s", plan)
+	}
+
+	t.Log("customers filtered by activity use partial index")
+	{
+		inactive := false
+		where, args := customersWhere("acme", &model.CustomerFilter{Inactive: &inactive}, nil)
+		plan := explain("SELECT id FROM customers"+where, args...)
+		require.Contains(plan, "customers_tenant_active_idx", "partial index must be used, plan:\n%s", plan)
+	}
 }
 
 func (s *repositoryTestSuite) TestMongoCustomerRps() {
 	s.T().Log("running tests for mongo")
-	s.testCustomerRps(NewMongoCustomerRepository(s.mongoClient))
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	err := MigrateMongoCustomers(ctx, s.mongoClient, config.EmailUniquenessTenant)
+	s.Require().NoError(err, "failed to migrate mongo customers")
+
+	s.testCustomerRps(NewMongoCustomerRepository(transactor.NewMongoWithinTransactionExecutor(s.mongoClient), config.EmailUniquenessTenant))
+}
+
+func (s *repositoryTestSuite) TestMongoCustomerRpsGlobalEmail() {
+	s.T().Log("running tests for mongo with globally unique email")
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	err := MigrateMongoCustomers(ctx, s.mongoClient, config.EmailUniquenessGlobal)
+	s.Require().NoError(err, "failed to migrate mongo customers")
+	defer func() {
+		err := MigrateMongoCustomers(ctx, s.mongoClient, config.EmailUniquenessTenant)
+		s.Require().NoError(err, "failed to revert mongo customers migration")
+	}()
+
+	s.testCustomerRpsGlobalEmail(NewMongoCustomerRepository(transactor.NewMongoWithinTransactionExecutor(s.mongoClient), config.EmailUniquenessGlobal))
+}
+
+func (s *repositoryTestSuite) TestMongoTransactor() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	txtor, err := transactor.NewMongoTransactor(ctx, s.mongoRsClient)
+	require.NoError(err, "failed to build mongo transactor")
+	require.True(txtor.Transactional(), "replica set must support transactions")
+
+	customerRps := NewMongoCustomerRepository(transactor.NewMongoWithinTransactionExecutor(s.mongoRsClient), config.EmailUniquenessTenant)
+	errRollback := errors.New("rollback")
+
+	newCustomer := func() *model.Customer {
+		return &model.Customer{
+			TenantID:   "acme",
+			ID:         uuid.NewString(),
+			FirstName:  "Mary",
+			LastName:   "Stone",
+			Email:      "marystone-" + uuid.NewString() + "@somemal.com",
+			Importance: model.ImportanceLow,
+		}
+	}
+
+	t.Log("changes are committed once function succeeds")
+	{
+		first, second := newCustomer(), newCustomer()
+		err := txtor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := customerRps.Create(ctx, first); err != nil {
+				return err
+			}
+
+			// nested call joins transaction
+			return txtor.WithinTransaction(ctx, func(ctx context.Context) error {
+				return customerRps.Create(ctx, second)
+			})
+		})
+		require.NoError(err, "transaction must be committed")
+
+		for _, c := range []*model.Customer{first, second} {
+			dbCustomer, err := customerRps.FindByID(ctx, c.TenantID, c.ID)
+			require.NoError(err, "failed to read customer")
+			require.Equal(c, dbCustomer, "customer must be created")
+		}
+	}
+
+	t.Log("changes are rolled back once function fails")
+	{
+		c := newCustomer()
+		err := txtor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := customerRps.Create(ctx, c); err != nil {
+				return err
+			}
+
+			dbCustomer, err := customerRps.FindByID(ctx, c.TenantID, c.ID)
+			require.NoError(err, "failed to read customer")
+			require.Equal(c, dbCustomer, "customer must be visible within transaction")
+			return errRollback
+		})
+		require.ErrorIs(err, errRollback, "error of function must be returned")
+
+		dbCustomer, err := customerRps.FindByID(ctx, c.TenantID, c.ID)
+		require.NoError(err, "failed to read customer")
+		require.Nil(dbCustomer, "customer must not be created")
+	}
+
+	t.Log("changes are applied without transaction by standalone server")
+	{
+		standaloneTxtor, err := transactor.NewMongoTransactor(ctx, s.mongoClient)
+		require.NoError(err, "failed to build mongo transactor")
+		require.False(standaloneTxtor.Transactional(), "standalone server must not support transactions")
+
+		standaloneRps := NewMongoCustomerRepository(transactor.NewMongoWithinTransactionExecutor(s.mongoClient), config.EmailUniquenessTenant)
+		c := newCustomer()
+		err = standaloneTxtor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := standaloneRps.Create(ctx, c); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		require.ErrorIs(err, errRollback, "error of function must be returned")
+
+		dbCustomer, err := standaloneRps.FindByID(ctx, c.TenantID, c.ID)
+		require.NoError(err, "failed to read customer")
+		require.Equal(c, dbCustomer, "customer must be created since there is no transaction")
+	}
+}
+
+func (s *repositoryTestSuite) TestTransactorAfterCommitHooks() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	mongoTxtor, err := transactor.NewMongoTransactor(ctx, s.mongoRsClient)
+	require.NoError(err, "failed to build mongo transactor")
+
+	errRollback := errors.New("rollback")
+	txtors := map[string]transactor.Transactor{
+		"pgx":   transactor.NewPgxTransactor(s.pgPool),
+		"mongo": mongoTxtor,
+	}
+
+	for name, txtor := range txtors {
+		t.Logf("%s: hooks are run after commit", name)
+		{
+			var calls []string
+			err := txtor.WithinTransaction(ctx, func(ctx context.Context) error {
+				require.True(transactor.RegisterAfterCommit(ctx, func(context.Context) { calls = append(calls, "first") }), "hook must be registered")
+				require.True(transactor.RegisterAfterCommit(ctx, func(context.Context) { panic("broken hook") }), "hook must be registered")
+				require.True(transactor.RegisterAfterCommit(ctx, func(context.Context) { calls = append(calls, "second") }), "hook must be registered")
+				require.Empty(calls, "hooks must not be run before commit")
+				return nil
+			})
+			require.NoError(err, "transaction must be committed")
+			require.Equal([]string{"first", "second"}, calls, "hooks must be run in order despite panic of another hook")
+		}
+
+		t.Logf("%s: hooks are dropped on rollback", name)
+		{
+			called := false
+			err := txtor.WithinTransaction(ctx, func(ctx context.Context) error {
+				transactor.RegisterAfterCommit(ctx, func(context.Context) { called = true })
+				return errRollback
+			})
+			require.ErrorIs(err, errRollback, "error of function must be returned")
+			require.False(called, "hook must not be run on rollback")
+		}
+	}
+}
+
+func (s *repositoryTestSuite) TestMongoCustomerActivityRps() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	err := MigrateMongoCustomerActivity(ctx, s.mongoClient, 1<<20)
+	require.NoError(err, "failed to migrate mongo customer activity")
+
+	err = MigrateMongoCustomerActivity(ctx, s.mongoClient, 1<<20)
+	require.NoError(err, "repeated migration must be no-op")
+
+	activityRps := NewMongoCustomerActivityRepository(s.mongoClient)
+	customerID := uuid.NewString()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	events := []*model.CustomerEvent{
+		{ID: uuid.NewString(), Type: model.CustomerCreated, TenantID: "acme", CustomerID: customerID, OccurredAt: now.Add(-2 * time.Hour)},
+		{ID: uuid.NewString(), Type: model.CustomerUpdated, TenantID: "acme", CustomerID: customerID, OccurredAt: now.Add(-2 * time.Minute)},
+		{ID: uuid.NewString(), Type: model.CustomerUpdated, TenantID: "acme", CustomerID: customerID, OccurredAt: now.Add(-time.Minute)},
+		{ID: uuid.NewString(), Type: model.CustomerUpdated, TenantID: "globex", CustomerID: customerID, OccurredAt: now},
+	}
+
+	t.Log("append events, redelivered event is stored once")
+	{
+		for _, e := range events {
+			require.NoError(activityRps.Append(ctx, e), "failed to append event")
+		}
+		require.NoError(activityRps.Append(ctx, events[2]), "redelivered event must be ignored")
+	}
+
+	t.Log("recent events of tenant are returned newest first")
+	{
+		recent, err := activityRps.FindRecent(ctx, "acme", customerID, now.Add(-time.Hour), 50)
+		require.NoError(err, "failed to read recent activity")
+		require.Len(recent, 2, "events outside of window and of other tenant must be skipped")
+		require.Equal(events[2], recent[0], "latest event must be first")
+		require.Equal(events[1], recent[1], "incorrect event")
+	}
+
+	t.Log("number of events is limited")
+	{
+		recent, err := activityRps.FindRecent(ctx, "acme", customerID, now.Add(-3*time.Hour), 1)
+		require.NoError(err, "failed to read recent activity")
+		require.Len(recent, 1, "events must be limited")
+		require.Equal(events[2].ID, recent[0].ID, "latest event must be returned")
+	}
+}
+
+func (s *repositoryTestSuite) TestInMemoryCustomerRps() {
+	s.T().Log("running tests for in-memory repository")
+	s.testCustomerRps(NewInMemoryCustomerRepository(config.EmailUniquenessTenant))
+}
+
+func (s *repositoryTestSuite) TestInMemoryCustomerRpsGlobalEmail() {
+	s.T().Log("running tests for in-memory repository with globally unique email")
+	s.testCustomerRpsGlobalEmail(NewInMemoryCustomerRepository(config.EmailUniquenessGlobal))
 }
 
 func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
@@ -396,9 +1059,12 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 	defer cancel()
 
 	middleName := "Ben"
+	tenantAcme := "acme"
+	tenantGlobex := "globex"
 
 	customers := []*model.Customer{
 		{
+			TenantID:   tenantAcme,
 			ID:         "53b9062b-0f45-4671-8c01-52fce0d8c750",
 			FirstName:  "John",
 			LastName:   "Norman",
@@ -408,6 +1074,7 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 			Inactive:   false,
 		},
 		{
+			TenantID:   tenantAcme,
 			ID:         "48fa2e4f-7937-4257-ac61-a42ef9f45f69",
 			FirstName:  "Albert",
 			LastName:   "Peers",
@@ -417,6 +1084,7 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 			Inactive:   false,
 		},
 		{
+			TenantID:   tenantAcme,
 			ID:         "3b9974de-ed71-4a5d-9121-42213e526234",
 			FirstName:  "Andrew",
 			LastName:   "Wallet",
@@ -426,6 +1094,7 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 			Inactive:   true,
 		},
 		{
+			TenantID:   tenantAcme,
 			ID:         "f917ab49-55f3-4b92-8abd-1f1124630cd9",
 			FirstName:  "Oliver",
 			LastName:   "Jefferson",
@@ -439,6 +1108,7 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 	customerJohn := customers[0]
 
 	customerJohnUpd := &model.Customer{
+		TenantID:   tenantAcme,
 		ID:         customerJohn.ID,
 		FirstName:  customerJohn.FirstName,
 		LastName:   customerJohn.LastName,
@@ -448,6 +1118,17 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 		Inactive:   true,
 	}
 
+	customerJohnGlobex := &model.Customer{
+		TenantID:   tenantGlobex,
+		ID:         "0d7f0a4e-6f0b-4c5e-a0f5-2d0c8a1e9b33",
+		FirstName:  "John",
+		LastName:   "Norman",
+		MiddleName: nil,
+		Email:      customerJohn.Email,
+		Importance: model.ImportanceLow,
+		Inactive:   false,
+	}
+
 	t.Logf("create %d customers", len(customers))
 	{
 		for _, c := range customers {
@@ -456,58 +1137,209 @@ func (s *repositoryTestSuite) testCustomerRps(customerRps CustomerRepository) {
 		}
 	}
 
+	t.Log("create customer with the same email in the same tenant")
+	{
+		duplicate := *customerJohnGlobex
+		duplicate.TenantID = tenantAcme
+		err := customerRps.Create(ctx, &duplicate)
+		require.Error(err, "email must be unique within tenant")
+		require.True(IsDuplicateCustomer(err), "error must be recognized as duplicate customer")
+	}
+
+	t.Log("create customer with the same email in another tenant")
+	{
+		err := customerRps.Create(ctx, customerJohnGlobex)
+		require.NoError(err, "email must be unique only within tenant")
+	}
+
 	t.Logf("verify %d customers in database", len(customers))
 	{
-		dbCustomers, err := customerRps.FindAll(ctx)
+		dbCustomers, err := customerRps.FindAll(ctx, tenantAcme)
 		require.NoError(err, "failed to read customers")
 		expected := len(customers)
 		actual := len(dbCustomers)
 		require.Equal(expected, actual, "%d customers were created, but got %d", expected, actual)
 	}
 
+	t.Log("verify customers are ordered by id across repeated reads")
+	{
+		ordered := []*model.Customer{customers[2], customers[1], customers[0], customers[3]}
+		for i := 0; i < 3; i++ {
+			dbCustomers, err := customerRps.FindAll(ctx, tenantAcme)
+			require.NoError(err, "failed to read customers")
+			require.Equal(ordered, dbCustomers, "customers must be ordered by id on every read")
+		}
+	}
+
+	t.Log("verify only active customers are read by active customers query")
+	{
+		dbCustomers, err := customerRps.FindAllActive(ctx, tenantAcme)
+		require.NoError(err, "failed to read active customers")
+		require.Equal([]*model.Customer{customers[1], customers[0]}, dbCustomers, "only active customers ordered by id must be returned")
+
+		dbCustomers, err = customerRps.FindAllActive(ctx, tenantGlobex)
+		require.NoError(err, "failed to read active customers")
+		require.Equal([]*model.Customer{customerJohnGlobex}, dbCustomers, "only active customers of the tenant must be returned")
+	}
+
+	t.Log("verify tenants see only own customers")
+	{
+		dbCustomers, err := customerRps.FindAll(ctx, tenantGlobex)
+		require.NoError(err, "failed to read customers")
+		require.Equal([]*model.Customer{customerJohnGlobex}, dbCustomers, "only customer of the tenant must be returned")
+
+		dbCustomer, err := customerRps.FindByID(ctx, tenantGlobex, customerJohn.ID)
+		require.NoError(err, "failed to read customer")
+		require.Nil(dbCustomer, "customer of another tenant must not be found")
+	}
+
 	t.Logf("find customer by id %s", customerJohn.ID)
 	{
-		dbCustomer, err := customerRps.FindByID(ctx, customerJohn.ID)
+		dbCustomer, err := customerRps.FindByID(ctx, tenantAcme, customerJohn.ID)
 		require.NoError(err, "failed to read customer")
 		require.NotNil(dbCustomer, "customer was created, but not found in database")
 		require.Equal(customerJohn, dbCustomer, "customer created in database is not the same it was passed")
 	}
 
+	t.Logf("find customer by email %s", customerJohn.Email)
+	{
+		dbCustomer, err := customerRps.FindByEmail(ctx, tenantAcme, customerJohn.Email)
+		require.NoError(err, "failed to read customer")
+		require.Equal(customerJohn, dbCustomer, "customer of the tenant must be found by email")
+
+		dbCustomer, err = customerRps.FindByEmail(ctx, tenantGlobex, customerJohn.Email)
+		require.NoError(err, "failed to read customer")
+		require.Equal(customerJohnGlobex, dbCustomer, "customer of another tenant must be found by the same email")
+	}
+
 	t.Logf("update customer %s", customerJohn.ID)
 	{
 		err := customerRps.Update(ctx, customerJohnUpd)
 		require.NoError(err, "failed to update customer")
 	}
 
+	t.Log("update of missing customer is reported")
+	{
+		missing := *customerJohnUpd
+		missing.TenantID = "initech"
+		err := customerRps.Update(ctx, &missing)
+		require.True(IsMissingCustomer(err), "missing customer must be reported")
+	}
+
 	t.Logf("find customer by id %s and verify it is updated", customerJohn.ID)
 	{
-		dbCustomer, err := customerRps.FindByID(ctx, customerJohn.ID)
+		dbCustomer, err := customerRps.FindByID(ctx, tenantAcme, customerJohn.ID)
 		require.NoError(err, "failed to read customer")
 		require.NotNil(dbCustomer, "customer was created and deleted, but not found in database")
 		require.Equal(customerJohnUpd, dbCustomer, "customer is in database, but wasn't updated correctly")
 	}
 
+	t.Logf("delete customer by id %s on behalf of another tenant", customerJohn.ID)
+	{
+		err := customerRps.DeleteByID(ctx, tenantGlobex, customerJohnUpd.ID)
+		require.NoError(err, "failed to delete customer")
+
+		dbCustomer, err := customerRps.FindByID(ctx, tenantAcme, customerJohnUpd.ID)
+		require.NoError(err, "failed to read customer by id")
+		require.NotNil(dbCustomer, "customer must not be deleted by another tenant")
+	}
+
 	t.Logf("delete customer by id %s", customerJohn.ID)
 	{
-		err := customerRps.DeleteByID(ctx, customerJohnUpd.ID)
+		err := customerRps.DeleteByID(ctx, tenantAcme, customerJohnUpd.ID)
 		require.NoError(err, "failed to delete customer")
 	}
 
 	t.Logf("verify customer %s is deleted", customerJohn.ID)
 	{
-		dbCustomer, err := customerRps.FindByID(ctx, customerJohnUpd.ID)
+		dbCustomer, err := customerRps.FindByID(ctx, tenantAcme, customerJohnUpd.ID)
 		require.NoError(err, "failed to read customer by id")
 		require.Nil(dbCustomer, "customer was deleted, but still present in database")
 	}
 
 	t.Logf("verify %d entries left", len(customers)-1)
 	{
-		dbCustomers, err := customerRps.FindAll(ctx)
+		dbCustomers, err := customerRps.FindAll(ctx, tenantAcme)
 		require.NoError(err, "failed to read customers")
 		expected := len(customers) - 1
 		actual := len(dbCustomers)
 		require.Equal(expected, actual, "there must be %d customers in database, but got %d", expected, actual)
 	}
+
+	t.Log("bulk update active customers")
+	{
+		inactive := false
+		importance := model.ImportanceCritical
+
+		ids, err := customerRps.BulkUpdate(
+			ctx,
+			tenantAcme,
+			&model.CustomerFilter{Inactive: &inactive},
+			&model.CustomerPatch{Importance: &importance},
+		)
+		require.NoError(err, "failed to bulk update customers")
+		require.Equal([]string{customers[1].ID}, ids, "only active customers of the tenant must be updated")
+
+		dbCustomer, err := customerRps.FindByID(ctx, tenantAcme, customers[1].ID)
+		require.NoError(err, "failed to read customer")
+		require.Equal(importance, dbCustomer.Importance, "importance must be changed by bulk update")
+		require.False(dbCustomer.Inactive, "fields out of update must be left untouched")
+
+		dbCustomer, err = customerRps.FindByID(ctx, tenantAcme, customers[2].ID)
+		require.NoError(err, "failed to read customer")
+		require.Equal(customers[2], dbCustomer, "customer not matching filter must be left untouched")
+
+		dbCustomer, err = customerRps.FindByID(ctx, tenantGlobex, customerJohnGlobex.ID)
+		require.NoError(err, "failed to read customer")
+		require.Equal(customerJohnGlobex, dbCustomer, "customers of another tenant must be left untouched")
+	}
+}
+
+func (s *repositoryTestSuite) testCustomerRpsGlobalEmail(customerRps CustomerRepository) {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testCtxTimeout)
+	defer cancel()
+
+	customerAcme := &model.Customer{
+		TenantID:   "acme",
+		ID:         uuid.NewString(),
+		FirstName:  "Mary",
+		LastName:   "Stone",
+		Email:      "marystone-" + uuid.NewString() + "@somemal.com",
+		Importance: model.ImportanceLow,
+	}
+
+	customerGlobex := *customerAcme
+	customerGlobex.TenantID = "globex"
+	customerGlobex.ID = uuid.NewString()
+
+	t.Log("create customer")
+	{
+		err := customerRps.Create(ctx, customerAcme)
+		require.NoError(err, "failed to create customer")
+	}
+
+	t.Log("create customer with the same email in another tenant")
+	{
+		err := customerRps.Create(ctx, &customerGlobex)
+		require.Error(err, "email must be unique across tenants")
+		require.True(IsDuplicateCustomer(err), "error must be recognized as duplicate customer")
+	}
+
+	t.Log("find customer by email on behalf of another tenant")
+	{
+		dbCustomer, err := customerRps.FindByEmail(ctx, customerGlobex.TenantID, customerAcme.Email)
+		require.NoError(err, "failed to read customer")
+		require.Equal(customerAcme, dbCustomer, "customer owning email must be found regardless of tenant")
+	}
+
+	t.Log("delete customer")
+	{
+		err := customerRps.DeleteByID(ctx, customerAcme.TenantID, customerAcme.ID)
+		require.NoError(err, "failed to delete customer")
+	}
 }
 
 // start repository test suite
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ilikeEscaper escapes wildcards of ILIKE pattern, so query is matched literally
+var ilikeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// CustomerSearchRepository represents behavior of customers full-text search, page of matched customers
+// and total number of matched customers are returned
+type CustomerSearchRepository interface {
+	Search(context.Context, string, *model.CustomerSearch) ([]*model.Customer, int, error)
+}
+
+// NewPostgresCustomerSearchRepository builds search matching query as substring of customer name and email,
+// customers are ordered by name since matches are not ranked
+func NewPostgresCustomerSearchRepository(p *pgxpool.Pool) CustomerSearchRepository {
+	return &postgresCustomerRepository{PgxWithinTransactionExecutor: transactor.NewPgxWithinTransactionExecutor(p)}
+}
+
+func (r *postgresCustomerRepository) Search(ctx context.Context, tenantID string, s *model.CustomerSearch) ([]*model.Customer, int, error) {
+	where, args := customersWhere(tenantID, &s.Filter, nil)
+	args = append(args, "%"+ilikeEscaper.Replace(s.Query)+"%")
+	where += fmt.Sprintf(
+		" AND (first_name ILIKE $%[1]d OR last_name ILIKE $%[1]d OR middle_name ILIKE $%[1]d OR email ILIKE $%[1]d OR first_name || ' ' || last_name ILIKE $%[1]d)",
+		len(args),
+	)
+
+	var total int
+	if err := r.Executor(ctx).QueryRow(ctx, "SELECT COUNT(*) FROM customers"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("postgres: failed to count found customers - %w", err)
+	}
+
+	args = append(args, s.Limit, s.Offset)
+	q := "SELECT id, tenant_id, first_name, last_name, middle_name, email, importance, inactive FROM customers" + where +
+		fmt.Sprintf(" ORDER BY last_name, first_name, id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.Executor(ctx).Query(ctx, q, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("postgres: failed to search customers - %w", err)
+	}
+	defer rows.Close()
+
+	customers := make([]*model.Customer, 0)
+	for rows.Next() {
+		var c model.Customer
+		if err := rows.Scan(&c.ID, &c.TenantID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Email, &c.Importance, &c.Inactive); err != nil {
+			return nil, 0, fmt.Errorf("postgres: failed to scan customer while searching customers - %w", err)
+		}
+		customers = append(customers, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("postgres: failed to search customers - %w", err)
+	}
+	return customers, total, nil
+}
+
+// NewMongoCustomerSearchRepository builds search matching query as case-insensitive substring of customer name and email,
+// customers are ordered by name since matches are not ranked
+func NewMongoCustomerSearchRepository(client *mongo.Client) CustomerSearchRepository {
+	return &mongoCustomerRepository{MongoWithinTransactionExecutor: transactor.NewMongoWithinTransactionExecutor(client)}
+}
+
+func (r *mongoCustomerRepository) Search(ctx context.Context, tenantID string, s *model.CustomerSearch) ([]*model.Customer, int, error) {
+	ctx = r.Session(ctx)
+	coll := r.collection()
+
+	pattern := bson.M{"$regex": regexp.QuoteMeta(s.Query), "$options": "i"}
+	query := customersQuery(tenantID, &s.Filter)
+	query["$or"] = bson.A{
+		bson.M{"firstName": pattern},
+		bson.M{"lastName": pattern},
+		bson.M{"middleName": pattern},
+		bson.M{"email": pattern},
+	}
+
+	total, err := coll.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("mongo: failed to count found customers - %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "lastName", Value: 1}, {Key: "firstName", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(s.Offset)).
+		SetLimit(int64(s.Limit))
+
+	cur, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("mongo: failed to search customers - %w", err)
+	}
+
+	customers := make([]*model.Customer, 0)
+	if err := cur.All(ctx, &customers); err != nil {
+		return nil, 0, fmt.Errorf("mongo: failed to scan customers while searching - %w", err)
+	}
+	return customers, int(total), nil
+}
+
+type inMemoryCustomerSearchRepository struct {
+	customerRps CustomerRepository
+}
+
+// NewInMemoryCustomerSearchRepository builds search scanning all tenant customers of repository,
+// it is meant for tests and small in-memory datasets only
+func NewInMemoryCustomerSearchRepository(customerRps CustomerRepository) CustomerSearchRepository {
+	return &inMemoryCustomerSearchRepository{customerRps: customerRps}
+}
+
+func (r *inMemoryCustomerSearchRepository) Search(ctx context.Context, tenantID string, s *model.CustomerSearch) ([]*model.Customer, int, error) {
+	all, err := r.customerRps.FindAll(ctx, tenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := strings.ToLower(s.Query)
+	customers := make([]*model.Customer, 0)
+	for _, c := range all {
+		if (s.Filter.Importance != nil && c.Importance != *s.Filter.Importance) ||
+			(s.Filter.Inactive != nil && c.Inactive != *s.Filter.Inactive) ||
+			!customerMatches(c, query) {
+			continue
+		}
+		customers = append(customers, c)
+	}
+
+	sort.SliceStable(customers, func(i, j int) bool {
+		a, b := customers[i], customers[j]
+		if a.LastName != b.LastName {
+			return a.LastName < b.LastName
+		}
+		return a.FirstName < b.FirstName
+	})
+
+	total := len(customers)
+	if s.Offset >= total {
+		return make([]*model.Customer, 0), total, nil
+	}
+
+	customers = customers[s.Offset:]
+	if len(customers) > s.Limit {
+		customers = customers[:s.Limit]
+	}
+	return customers, total, nil
+}
+
+// customerMatches reports whether lower-cased query is substring of customer name or email
+func customerMatches(c *model.Customer, query string) bool {
+	fields := []string{c.FirstName, c.LastName, c.Email, c.FirstName + " " + c.LastName}
+	if c.MiddleName != nil {
+		fields = append(fields, *c.MiddleName)
+	}
+
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), query) {
+			return true
+		}
+	}
+	return false
+}
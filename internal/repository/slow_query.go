@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// SlowQueryLogger logs warning for queries which take longer than configured threshold
+type SlowQueryLogger struct {
+	logger    logrus.FieldLogger
+	threshold time.Duration
+}
+
+// NewSlowQueryLogger builds new SlowQueryLogger, zero threshold disables logging
+func NewSlowQueryLogger(logger logrus.FieldLogger, threshold time.Duration) *SlowQueryLogger {
+	return &SlowQueryLogger{logger: logger, threshold: threshold}
+}
+
+// Track starts timing of query, returned function must be called once query is finished,
+// request scoped fields of context are added to warning
+func (l *SlowQueryLogger) Track(ctx context.Context, query string) func() {
+	start := time.Now()
+	return func() {
+		if l.threshold <= 0 {
+			return
+		}
+
+		if elapsed := time.Since(start); elapsed > l.threshold {
+			l.logger.WithFields(logging.FieldsFromContext(ctx)).WithFields(logrus.Fields{
+				"query":   query,
+				"elapsed": elapsed,
+			}).Warnf("slow query %s took %s, threshold is %s", query, elapsed, l.threshold)
+		}
+	}
+}
+
+type slowQueryCustomerRepository struct {
+	next    CustomerRepository
+	slowLog *SlowQueryLogger
+}
+
+// NewSlowQueryCustomerRepository wraps CustomerRepository, so slow queries are logged
+func NewSlowQueryCustomerRepository(next CustomerRepository, slowLog *SlowQueryLogger) CustomerRepository {
+	return &slowQueryCustomerRepository{next: next, slowLog: slowLog}
+}
+
+func (r *slowQueryCustomerRepository) FindByID(ctx context.Context, tenantID string, id string) (*model.Customer, error) {
+	defer r.slowLog.Track(ctx, "customers.FindByID")()
+	return r.next.FindByID(ctx, tenantID, id)
+}
+
+func (r *slowQueryCustomerRepository) FindByEmail(ctx context.Context, tenantID string, email string) (*model.Customer, error) {
+	defer r.slowLog.Track(ctx, "customers.FindByEmail")()
+	return r.next.FindByEmail(ctx, tenantID, email)
+}
+
+func (r *slowQueryCustomerRepository) FindAll(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	defer r.slowLog.Track(ctx, "customers.FindAll")()
+	return r.next.FindAll(ctx, tenantID)
+}
+
+func (r *slowQueryCustomerRepository) FindAllActive(ctx context.Context, tenantID string) ([]*model.Customer, error) {
+	defer r.slowLog.Track(ctx, "customers.FindAllActive")()
+	return r.next.FindAllActive(ctx, tenantID)
+}
+
+func (r *slowQueryCustomerRepository) FindMostImportant(ctx context.Context, tenantID string, limit int) ([]*model.Customer, error) {
+	defer r.slowLog.Track(ctx, "customers.FindMostImportant")()
+	return r.next.FindMostImportant(ctx, tenantID, limit)
+}
+
+func (r *slowQueryCustomerRepository) Create(ctx context.Context, c *model.Customer) error {
+	defer r.slowLog.Track(ctx, "customers.Create")()
+	return r.next.Create(ctx, c)
+}
+
+func (r *slowQueryCustomerRepository) Update(ctx context.Context, c *model.Customer) error {
+	defer r.slowLog.Track(ctx, "customers.Update")()
+	return r.next.Update(ctx, c)
+}
+
+func (r *slowQueryCustomerRepository) BulkUpdate(
+	ctx context.Context,
+	tenantID string,
+	filter *model.CustomerFilter,
+	patch *model.CustomerPatch,
+) ([]string, error) {
+	defer r.slowLog.Track(ctx, "customers.BulkUpdate")()
+	return r.next.BulkUpdate(ctx, tenantID, filter, patch)
+}
+
+func (r *slowQueryCustomerRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	defer r.slowLog.Track(ctx, "customers.DeleteByID")()
+	return r.next.DeleteByID(ctx, tenantID, id)
+}
+
+type slowQueryUserRepository struct {
+	next    UserRepository
+	slowLog *SlowQueryLogger
+}
+
+// NewSlowQueryUserRepository wraps UserRepository, so slow queries are logged
+func NewSlowQueryUserRepository(next UserRepository, slowLog *SlowQueryLogger) UserRepository {
+	return &slowQueryUserRepository{next: next, slowLog: slowLog}
+}
+
+func (r *slowQueryUserRepository) Create(ctx context.Context, u *model.User) error {
+	defer r.slowLog.Track(ctx, "users.Create")()
+	return r.next.Create(ctx, u)
+}
+
+func (r *slowQueryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	defer r.slowLog.Track(ctx, "users.FindByEmail")()
+	return r.next.FindByEmail(ctx, email)
+}
+
+func (r *slowQueryUserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
+	defer r.slowLog.Track(ctx, "users.FindByID")()
+	return r.next.FindByID(ctx, id)
+}
+
+type slowQueryRefreshTokenRepository struct {
+	next    RefreshTokenRepository
+	slowLog *SlowQueryLogger
+}
+
+// NewSlowQueryRefreshTokenRepository wraps RefreshTokenRepository, so slow queries are logged
+func NewSlowQueryRefreshTokenRepository(next RefreshTokenRepository, slowLog *SlowQueryLogger) RefreshTokenRepository {
+	return &slowQueryRefreshTokenRepository{next: next, slowLog: slowLog}
+}
+
+func (r *slowQueryRefreshTokenRepository) Create(ctx context.Context, tkn *model.RefreshToken) error {
+	defer r.slowLog.Track(ctx, "refreshTokens.Create")()
+	return r.next.Create(ctx, tkn)
+}
+
+func (r *slowQueryRefreshTokenRepository) FindTokensByUserID(ctx context.Context, userID string) ([]*model.RefreshToken, error) {
+	defer r.slowLog.Track(ctx, "refreshTokens.FindTokensByUserID")()
+	return r.next.FindTokensByUserID(ctx, userID)
+}
+
+func (r *slowQueryRefreshTokenRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	defer r.slowLog.Track(ctx, "refreshTokens.DeleteByUserID")()
+	return r.next.DeleteByUserID(ctx, userID)
+}
+
+func (r *slowQueryRefreshTokenRepository) DeleteOldestByUserID(ctx context.Context, userID string, keep int) error {
+	defer r.slowLog.Track(ctx, "refreshTokens.DeleteOldestByUserID")()
+	return r.next.DeleteOldestByUserID(ctx, userID, keep)
+}
+
+func (r *slowQueryRefreshTokenRepository) DeleteByID(ctx context.Context, id string) error {
+	defer r.slowLog.Track(ctx, "refreshTokens.DeleteByID")()
+	return r.next.DeleteByID(ctx, id)
+}
+
+func (r *slowQueryRefreshTokenRepository) FindByID(ctx context.Context, id string) (*model.RefreshToken, error) {
+	defer r.slowLog.Track(ctx, "refreshTokens.FindByID")()
+	return r.next.FindByID(ctx, id)
+}
+
+func (r *slowQueryRefreshTokenRepository) FindAll(ctx context.Context, filter *model.RefreshTokenFilter) ([]*model.RefreshToken, error) {
+	defer r.slowLog.Track(ctx, "refreshTokens.FindAll")()
+	return r.next.FindAll(ctx, filter)
+}
+
+func (r *slowQueryRefreshTokenRepository) Count(ctx context.Context, filter *model.RefreshTokenFilter) (int, error) {
+	defer r.slowLog.Track(ctx, "refreshTokens.Count")()
+	return r.next.Count(ctx, filter)
+}
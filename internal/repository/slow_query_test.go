@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository/mocks"
+)
+
+const (
+	slowQueryThreshold = 20 * time.Millisecond
+	slowQueryDelay     = 50 * time.Millisecond
+)
+
+type slowQueryTestSuite struct {
+	suite.Suite
+	hook        *logrusTest.Hook
+	customerRps *mocks.CustomerRepository
+	slowRps     CustomerRepository
+}
+
+func (s *slowQueryTestSuite) SetupTest() {
+	logger, hook := logrusTest.NewNullLogger()
+	s.hook = hook
+	s.customerRps = mocks.NewCustomerRepository(s.T())
+	s.slowRps = NewSlowQueryCustomerRepository(s.customerRps, NewSlowQueryLogger(logger, slowQueryThreshold))
+}
+
+func (s *slowQueryTestSuite) TestSlowQueryIsLogged() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("query exceeding threshold is logged as warning")
+	{
+		s.customerRps.EXPECT().FindAll(mock.Anything, "acme").
+			After(slowQueryDelay).
+			Return([]*model.Customer{}, nil).
+			Once()
+
+		_, err := s.slowRps.FindAll(context.Background(), "acme")
+		require.NoError(err, "wrapped repository must not fail")
+
+		entry := s.hook.LastEntry()
+		require.NotNil(entry, "warning must be logged")
+		require.Equal(logrus.WarnLevel, entry.Level, "slow query must be logged as warning")
+		require.Equal("customers.FindAll", entry.Data["query"], "query name must be logged")
+		require.GreaterOrEqual(entry.Data["elapsed"], slowQueryDelay, "elapsed time must be logged")
+	}
+}
+
+func (s *slowQueryTestSuite) TestFastQueryIsNotLogged() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("query within threshold is not logged")
+	{
+		s.customerRps.EXPECT().FindByID(mock.Anything, "acme", "id").
+			Return(nil, nil).
+			Once()
+
+		_, err := s.slowRps.FindByID(context.Background(), "acme", "id")
+		require.NoError(err, "wrapped repository must not fail")
+		require.Empty(s.hook.AllEntries(), "nothing must be logged for fast query")
+	}
+}
+
+func (s *slowQueryTestSuite) TestZeroThresholdDisablesLogging() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("zero threshold disables slow query logging")
+	{
+		logger, hook := logrusTest.NewNullLogger()
+		rps := NewSlowQueryCustomerRepository(s.customerRps, NewSlowQueryLogger(logger, 0))
+
+		s.customerRps.EXPECT().DeleteByID(mock.Anything, "acme", "id").
+			After(slowQueryDelay).
+			Return(nil).
+			Once()
+
+		err := rps.DeleteByID(context.Background(), "acme", "id")
+		require.NoError(err, "wrapped repository must not fail")
+		require.Empty(hook.AllEntries(), "nothing must be logged when threshold is zero")
+	}
+}
+
+// start slow query test suite
+func TestSlowQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(slowQueryTestSuite))
+}
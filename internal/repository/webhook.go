@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// WebhookRepository represents behavior of webhook subscriptions and their deliveries repository
+type WebhookRepository interface {
+	Create(context.Context, *model.Webhook) error
+	FindAll(context.Context, string) ([]*model.Webhook, error)
+	FindByID(context.Context, string, string) (*model.Webhook, error)
+	FindSubscribed(context.Context, string, model.CustomerEventType) ([]*model.Webhook, error)
+	DeleteByID(context.Context, string, string) error
+	CreateDeliveries(context.Context, []*model.WebhookDelivery) error
+	SaveDelivery(context.Context, *model.WebhookDelivery) error
+	FindDeliveries(context.Context, string) ([]*model.WebhookDelivery, error)
+	ClaimDueDeliveries(context.Context, time.Time, time.Time, int) ([]*model.DueWebhookDelivery, error)
+}
+
+type postgresWebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresWebhookRepository builds postgresWebhookRepository
+func NewPostgresWebhookRepository(p *pgxpool.Pool) WebhookRepository {
+	return &postgresWebhookRepository{pool: p}
+}
+
+func (r *postgresWebhookRepository) Create(ctx context.Context, w *model.Webhook) error {
+	q := `INSERT INTO webhooks(id, tenant_id, url, secret, event_types, active, created_at)
+          VALUES($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.pool.Exec(ctx, q, w.ID, w.TenantID, w.URL, w.Secret, eventTypeNames(w.EventTypes), w.Active, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("postgres: failed to create webhook %s - %w", w.ID, err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) FindAll(ctx context.Context, tenantID string) ([]*model.Webhook, error) {
+	q := `SELECT id, tenant_id, url, secret, event_types, active, created_at FROM webhooks
+          WHERE tenant_id = $1 ORDER BY created_at`
+	return r.query(ctx, q, tenantID)
+}
+
+func (r *postgresWebhookRepository) FindByID(ctx context.Context, tenantID string, id string) (*model.Webhook, error) {
+	q := `SELECT id, tenant_id, url, secret, event_types, active, created_at FROM webhooks
+          WHERE tenant_id = $1 AND id = $2`
+
+	webhooks, err := r.query(ctx, q, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) == 0 {
+		return nil, nil
+	}
+	return webhooks[0], nil
+}
+
+func (r *postgresWebhookRepository) FindSubscribed(ctx context.Context, tenantID string, t model.CustomerEventType) ([]*model.Webhook, error) {
+	q := `SELECT id, tenant_id, url, secret, event_types, active, created_at FROM webhooks
+          WHERE tenant_id = $1 AND active AND $2 = ANY(event_types)`
+	return r.query(ctx, q, tenantID, string(t))
+}
+
+func (r *postgresWebhookRepository) DeleteByID(ctx context.Context, tenantID string, id string) error {
+	q := "DELETE FROM webhooks WHERE tenant_id = $1 AND id = $2"
+	if _, err := r.pool.Exec(ctx, q, tenantID, id); err != nil {
+		return fmt.Errorf("postgres: failed to delete webhook %s - %w", id, err)
+	}
+	return nil
+}
+
+// CreateDeliveries records deliveries unless delivery of the same event to the same webhook is recorded already,
+// so event consumed more than once is delivered once
+func (r *postgresWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	q := `INSERT INTO webhook_deliveries(id, webhook_id, event_id, event_type, status, attempts, last_status_code, last_error, payload, next_attempt_at, created_at, updated_at)
+          VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+          ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+	for _, d := range deliveries {
+		_, err := r.pool.Exec(ctx, q, d.ID, d.WebhookID, d.EventID, string(d.EventType), string(d.Status), d.Attempts,
+			d.LastStatusCode, d.LastError, d.Payload, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("postgres: failed to create webhook delivery %s - %w", d.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) SaveDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	q := `UPDATE webhook_deliveries SET status = $2, attempts = $3, last_status_code = $4, last_error = $5,
+          next_attempt_at = $6, updated_at = $7 WHERE id = $1`
+
+	_, err := r.pool.Exec(ctx, q, d.ID, string(d.Status), d.Attempts, d.LastStatusCode, d.LastError, d.NextAttemptAt, d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("postgres: failed to save webhook delivery %s - %w", d.ID, err)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) FindDeliveries(ctx context.Context, webhookID string) ([]*model.WebhookDelivery, error) {
+	q := `SELECT id, webhook_id, event_id, event_type, status, attempts, last_status_code, last_error, next_attempt_at, created_at, updated_at
+          FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.pool.Query(ctx, q, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read deliveries of webhook %s - %w", webhookID, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		var eventType, status string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &eventType, &status, &d.Attempts, &d.LastStatusCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan delivery of webhook %s - %w", webhookID, err)
+		}
+		d.EventType, d.Status = model.CustomerEventType(eventType), model.WebhookDeliveryStatus(status)
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries returns pending deliveries due at now and postpones their next attempt until lease is over,
+// so they are not claimed by other instances while being attempted. Claimed delivery which is not saved
+// until lease is over, e.g. instance is stopped, is claimed again
+func (r *postgresWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*model.DueWebhookDelivery, error) {
+	q := `UPDATE webhook_deliveries d SET next_attempt_at = $2 FROM webhooks w
+          WHERE w.id = d.webhook_id AND d.id IN (
+              SELECT id FROM webhook_deliveries WHERE status = $3 AND next_attempt_at <= $1
+              ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED
+          )
+          RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.status, d.attempts, d.last_status_code, d.last_error,
+          d.payload, d.next_attempt_at, d.created_at, d.updated_at, w.tenant_id, w.url, w.secret, w.event_types, w.active, w.created_at`
+
+	rows, err := r.pool.Query(ctx, q, now, leaseUntil, string(model.WebhookDeliveryPending), limit)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to claim due webhook deliveries - %w", err)
+	}
+	defer rows.Close()
+
+	due := make([]*model.DueWebhookDelivery, 0)
+	for rows.Next() {
+		var d model.WebhookDelivery
+		var w model.Webhook
+		var eventType, status string
+		var eventTypes []string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &eventType, &status, &d.Attempts, &d.LastStatusCode, &d.LastError,
+			&d.Payload, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt, &w.TenantID, &w.URL, &w.Secret, &eventTypes, &w.Active, &w.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan due webhook delivery - %w", err)
+		}
+
+		d.EventType, d.Status = model.CustomerEventType(eventType), model.WebhookDeliveryStatus(status)
+		w.ID = d.WebhookID
+		w.EventTypes = make([]model.CustomerEventType, len(eventTypes))
+		for i, et := range eventTypes {
+			w.EventTypes[i] = model.CustomerEventType(et)
+		}
+		due = append(due, &model.DueWebhookDelivery{Webhook: &w, Delivery: &d})
+	}
+
+	return due, rows.Err()
+}
+
+func (r *postgresWebhookRepository) query(ctx context.Context, q string, args ...any) ([]*model.Webhook, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to read webhooks - %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*model.Webhook, 0)
+	for rows.Next() {
+		var w model.Webhook
+		var eventTypes []string
+		if err := rows.Scan(&w.ID, &w.TenantID, &w.URL, &w.Secret, &eventTypes, &w.Active, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("postgres: failed to scan webhook - %w", err)
+		}
+
+		w.EventTypes = make([]model.CustomerEventType, len(eventTypes))
+		for i, et := range eventTypes {
+			w.EventTypes[i] = model.CustomerEventType(et)
+		}
+		webhooks = append(webhooks, &w)
+	}
+
+	return webhooks, nil
+}
+
+// eventTypeNames converts event types to strings, so they are encoded as postgres text array
+func eventTypeNames(types []model.CustomerEventType) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return names
+}
+
+type inMemoryWebhookRepository struct {
+	webhooks   map[string]model.Webhook
+	deliveries map[string]model.WebhookDelivery
+	mu         sync.RWMutex
+}
+
+// NewInMemoryWebhookRepository builds new in-memory webhook repository
+func NewInMemoryWebhookRepository() WebhookRepository {
+	return &inMemoryWebhookRepository{
+		webhooks:   make(map[string]model.Webhook),
+		deliveries: make(map[string]model.WebhookDelivery),
+	}
+}
+
+func (r *inMemoryWebhookRepository) Create(_ context.Context, w *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks[w.ID] = *w
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) FindAll(_ context.Context, tenantID string) ([]*model.Webhook, error) {
+	return r.find(func(w *model.Webhook) bool { return w.TenantID == tenantID }), nil
+}
+
+func (r *inMemoryWebhookRepository) FindByID(_ context.Context, tenantID string, id string) (*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.webhooks[id]
+	if !ok || w.TenantID != tenantID {
+		return nil, nil
+	}
+	return &w, nil
+}
+
+func (r *inMemoryWebhookRepository) FindSubscribed(_ context.Context, tenantID string, t model.CustomerEventType) ([]*model.Webhook, error) {
+	return r.find(func(w *model.Webhook) bool { return w.TenantID == tenantID && w.Subscribed(t) }), nil
+}
+
+func (r *inMemoryWebhookRepository) DeleteByID(_ context.Context, tenantID string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w, ok := r.webhooks[id]; !ok || w.TenantID != tenantID {
+		return nil
+	}
+
+	delete(r.webhooks, id)
+	for deliveryID, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) CreateDeliveries(_ context.Context, deliveries []*model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range deliveries {
+		if !r.deliveryExists(d.WebhookID, d.EventID) {
+			r.deliveries[d.ID] = *d
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) SaveDelivery(_ context.Context, d *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.deliveries[d.ID]
+	if !ok {
+		return nil
+	}
+
+	d.Payload = stored.Payload
+	r.deliveries[d.ID] = *d
+	return nil
+}
+
+func (r *inMemoryWebhookRepository) FindDeliveries(_ context.Context, webhookID string) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID {
+			d := d
+			deliveries = append(deliveries, &d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries, nil
+}
+
+func (r *inMemoryWebhookRepository) ClaimDueDeliveries(_ context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*model.DueWebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := make([]model.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.Status == model.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			pending = append(pending, d)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].NextAttemptAt.Before(pending[j].NextAttemptAt)
+	})
+
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	due := make([]*model.DueWebhookDelivery, len(pending))
+	for i, d := range pending {
+		d := d
+		d.NextAttemptAt = leaseUntil
+		r.deliveries[d.ID] = d
+
+		w := r.webhooks[d.WebhookID]
+		due[i] = &model.DueWebhookDelivery{Webhook: &w, Delivery: &d}
+	}
+	return due, nil
+}
+
+func (r *inMemoryWebhookRepository) deliveryExists(webhookID string, eventID string) bool {
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID && d.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *inMemoryWebhookRepository) find(match func(*model.Webhook) bool) []*model.Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]*model.Webhook, 0)
+	for _, w := range r.webhooks {
+		w := w
+		if match(&w) {
+			webhooks = append(webhooks, &w)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks
+}
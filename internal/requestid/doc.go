@@ -0,0 +1,2 @@
+// Package requestid contains helpers for passing request id through request context, so errors can be correlated with logs
+package requestid
@@ -0,0 +1,16 @@
+package requestid
+
+import "context"
+
+type requestIDCtxKey struct{}
+
+// ContextWithID returns copy of parent context carrying request id
+func ContextWithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// IDFromContext extracts request id from context, empty string is returned if request id is not set
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
@@ -0,0 +1,2 @@
+// Package search keeps customers search index in sync with customer changes
+package search
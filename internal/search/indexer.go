@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// Indexer keeps search index in sync with customers datastore
+type Indexer struct {
+	indexRps    repository.CustomerIndexRepository
+	customerRps repository.CustomerRepository
+}
+
+// NewIndexer builds new Indexer, customers are indexed as they are kept in customerRps, which must be the datastore
+// customers are searched in while index is unavailable
+func NewIndexer(indexRps repository.CustomerIndexRepository, customerRps repository.CustomerRepository) *Indexer {
+	return &Indexer{indexRps: indexRps, customerRps: customerRps}
+}
+
+// Index syncs document of customer event is raised for with datastore. Customer is read back instead of taken
+// from event, so redelivered or reordered events never overwrite document with outdated customer.
+// Error is returned if document isn't synced, so event is redelivered
+func (i *Indexer) Index(ctx context.Context, e *model.CustomerEvent) error {
+	switch e.Type {
+	case model.CustomerCreated, model.CustomerUpdated, model.CustomerDeleted:
+		return i.sync(ctx, e.TenantID, e.CustomerID)
+	default:
+		return nil
+	}
+}
+
+// Reindex indexes all customers of tenant and deletes documents of customers missing in datastore,
+// e.g. ones deleted while index was unavailable. Number of indexed customers is returned
+func (i *Indexer) Reindex(ctx context.Context, tenantID string) (int, error) {
+	startedAt := time.Now()
+
+	customers, err := i.customerRps.FindAll(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read customers of tenant %s - %w", tenantID, err)
+	}
+
+	for n, c := range customers {
+		if err := i.indexRps.Index(ctx, c); err != nil {
+			return n, err
+		}
+	}
+
+	if err := i.indexRps.DeleteIndexedBefore(ctx, tenantID, startedAt); err != nil {
+		return len(customers), err
+	}
+
+	logging.FromContext(ctx).Infof("%d customers of tenant %s are reindexed", len(customers), tenantID)
+	return len(customers), nil
+}
+
+func (i *Indexer) sync(ctx context.Context, tenantID string, id string) error {
+	c, err := i.customerRps.FindByID(ctx, tenantID, id)
+	if err != nil {
+		return fmt.Errorf("failed to read customer %s to index - %w", id, err)
+	}
+
+	if c == nil {
+		return i.indexRps.DeleteByID(ctx, tenantID, id)
+	}
+	return i.indexRps.Index(ctx, c)
+}
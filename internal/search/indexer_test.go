@@ -0,0 +1,141 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+type indexedCustomer struct {
+	customer  model.Customer
+	indexedAt time.Time
+}
+
+// inMemoryCustomerIndex imitates search index, writes fail while it is unavailable
+type inMemoryCustomerIndex struct {
+	docs        map[string]indexedCustomer
+	unavailable bool
+}
+
+func (i *inMemoryCustomerIndex) Index(_ context.Context, c *model.Customer) error {
+	if i.unavailable {
+		return errors.New("elasticsearch: failed to index customer - connection refused")
+	}
+	i.docs[c.TenantID+":"+c.ID] = indexedCustomer{customer: *c, indexedAt: time.Now()}
+	return nil
+}
+
+func (i *inMemoryCustomerIndex) DeleteByID(_ context.Context, tenantID string, id string) error {
+	if i.unavailable {
+		return errors.New("elasticsearch: failed to delete customer - connection refused")
+	}
+	delete(i.docs, tenantID+":"+id)
+	return nil
+}
+
+func (i *inMemoryCustomerIndex) BulkUpdate(context.Context, string, *model.CustomerFilter, *model.CustomerPatch) error {
+	return nil
+}
+
+func (i *inMemoryCustomerIndex) DeleteIndexedBefore(_ context.Context, tenantID string, before time.Time) error {
+	for key, doc := range i.docs {
+		if doc.customer.TenantID == tenantID && doc.indexedAt.Before(before) {
+			delete(i.docs, key)
+		}
+	}
+	return nil
+}
+
+type indexerTestSuite struct {
+	suite.Suite
+	index       *inMemoryCustomerIndex
+	customerRps repository.CustomerRepository
+	indexer     *Indexer
+	customer    *model.Customer
+}
+
+func (s *indexerTestSuite) SetupTest() {
+	s.index = &inMemoryCustomerIndex{docs: make(map[string]indexedCustomer)}
+	s.customerRps = repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant)
+	s.indexer = NewIndexer(s.index, s.customerRps)
+	s.customer = &model.Customer{
+		ID:         "ecc770d9-4576-4f72-affa-8b1454246692",
+		TenantID:   "acme",
+		FirstName:  "John",
+		LastName:   "Walls",
+		Email:      "john.walls@somemail.com",
+		Importance: model.ImportanceLow,
+	}
+	s.Require().NoError(s.customerRps.Create(context.Background(), s.customer), "failed to create customer")
+}
+
+func (s *indexerTestSuite) TestIndex() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	stale := *s.customer
+	stale.FirstName = "Johnny"
+
+	t.Log("customer is indexed as it is kept in datastore")
+	{
+		require.NoError(s.indexer.Index(ctx, s.event(model.CustomerUpdated, &stale)), "no error must be raised")
+		require.Equal(*s.customer, s.index.docs["acme:"+s.customer.ID].customer, "customer must be read back from datastore")
+	}
+
+	t.Log("document of customer missing in datastore is deleted whatever event is")
+	{
+		require.NoError(s.customerRps.DeleteByID(ctx, "acme", s.customer.ID), "failed to delete customer")
+		require.NoError(s.indexer.Index(ctx, s.event(model.CustomerUpdated, &stale)), "no error must be raised")
+		require.Empty(s.index.docs, "redelivered event must not bring deleted customer back")
+	}
+
+	t.Log("index failure is returned, so event is redelivered")
+	{
+		require.NoError(s.customerRps.Create(ctx, s.customer), "failed to create customer")
+		s.index.unavailable = true
+		require.Error(s.indexer.Index(ctx, s.event(model.CustomerCreated, s.customer)), "index failure must be returned")
+	}
+}
+
+func (s *indexerTestSuite) TestReindex() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	deleted := model.Customer{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: "acme", FirstName: "Jane", LastName: "Doe", Email: "jane@somemail.com"}
+	other := model.Customer{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "globex", FirstName: "Jack", LastName: "Black", Email: "jack@somemail.com"}
+	s.index.docs["acme:"+deleted.ID] = indexedCustomer{customer: deleted, indexedAt: time.Now().Add(-time.Minute)}
+	s.index.docs["globex:"+other.ID] = indexedCustomer{customer: other, indexedAt: time.Now().Add(-time.Minute)}
+
+	t.Log("customers of tenant are indexed and documents of missing ones are deleted")
+	{
+		indexed, err := s.indexer.Reindex(ctx, "acme")
+		require.NoError(err, "no error must be raised")
+		require.Equal(1, indexed, "incorrect number of indexed customers")
+		require.Contains(s.index.docs, "acme:"+s.customer.ID, "customer must be indexed")
+		require.NotContains(s.index.docs, "acme:"+deleted.ID, "document of missing customer must be deleted")
+		require.Contains(s.index.docs, "globex:"+other.ID, "documents of other tenants must be left untouched")
+	}
+}
+
+func (s *indexerTestSuite) event(t model.CustomerEventType, c *model.Customer) *model.CustomerEvent {
+	return &model.CustomerEvent{
+		ID:         "7b0c3a5e-1d2f-4e6a-8b9c-0d1e2f3a4b5c",
+		Type:       t,
+		TenantID:   c.TenantID,
+		CustomerID: c.ID,
+		Customer:   c,
+	}
+}
+
+// start indexer test suite
+func TestIndexerTestSuite(t *testing.T) {
+	suite.Run(t, new(indexerTestSuite))
+}
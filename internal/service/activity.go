@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+// CustomerActivityService represents behavior of service reading recent changes of customers
+type CustomerActivityService interface {
+	FindRecent(context.Context, string, int) ([]*model.CustomerEvent, error)
+}
+
+type customerActivityService struct {
+	activityRps repository.CustomerActivityRepository
+	cfg         *config.ActivityCfg
+}
+
+// NewCustomerActivityService builds new customerActivityService
+func NewCustomerActivityService(activityRps repository.CustomerActivityRepository, cfg *config.ActivityCfg) CustomerActivityService {
+	return &customerActivityService{activityRps: activityRps, cfg: cfg}
+}
+
+// FindRecent returns the newest events of caller tenant customer occurred within configured window
+func (s *customerActivityService) FindRecent(ctx context.Context, customerID string, limit int) ([]*model.CustomerEvent, error) {
+	since := time.Now().UTC().Add(-s.cfg.Window)
+	return s.activityRps.FindRecent(ctx, tenant.IDFromContext(ctx), customerID, since, limit)
+}
@@ -3,17 +3,19 @@ package service
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/audit"
 	"github.com/umalmyha/customers/internal/auth"
 	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/email"
+	appErrors "github.com/umalmyha/customers/internal/errors"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/tenant"
 	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
 )
 
 // AuthService represents auth service behavior
@@ -21,62 +23,110 @@ type AuthService interface {
 	Signup(context.Context, string, string) (*model.User, error)
 	Login(context.Context, string, string, string, time.Time) (*auth.Jwt, *model.RefreshToken, error)
 	Logout(context.Context, string) error
+	// Refresh rotates refresh token, returned token carries its own expiry. Token younger than configured
+	// min rotation age is rejected and stays valid, so clients rotating proactively can't cause rotation storms
 	Refresh(context.Context, string, string, time.Time) (*auth.Jwt, *model.RefreshToken, error)
+	Introspect(context.Context, string) (*auth.Introspection, error)
 }
 
 type authService struct {
-	txtor       transactor.Transactor
-	userRps     repository.UserRepository
-	rfrTknRps   repository.RefreshTokenRepository
-	jwtIssuer   *auth.JwtIssuer
-	rfrTokenCfg *config.RefreshTokenCfg
+	txtor        transactor.Transactor
+	userRps      repository.UserRepository
+	rfrTknRps    repository.RefreshTokenRepository
+	jwtIssuer    *auth.JwtIssuer
+	jwtValidator *auth.JwtValidator
+	tokenRevoker auth.TokenRevoker
+	rfrTokenCfg  *config.RefreshTokenCfg
+	signupCfg    *config.SignupCfg
+	auditLog     *audit.Logger
+	emailSender  email.Sender
 }
 
 // NewAuthService builds new authService
 func NewAuthService(
 	jwtIssuer *auth.JwtIssuer,
+	jwtValidator *auth.JwtValidator,
+	tokenRevoker auth.TokenRevoker,
 	rfrTokenCfg *config.RefreshTokenCfg,
+	signupCfg *config.SignupCfg,
 	txtor transactor.Transactor,
 	userRps repository.UserRepository,
 	rfrTknRps repository.RefreshTokenRepository,
+	auditLog *audit.Logger,
+	emailSender email.Sender,
 ) AuthService {
 	return &authService{
-		jwtIssuer:   jwtIssuer,
-		rfrTokenCfg: rfrTokenCfg,
-		txtor:       txtor,
-		userRps:     userRps,
-		rfrTknRps:   rfrTknRps,
+		jwtIssuer:    jwtIssuer,
+		jwtValidator: jwtValidator,
+		tokenRevoker: tokenRevoker,
+		rfrTokenCfg:  rfrTokenCfg,
+		signupCfg:    signupCfg,
+		txtor:        txtor,
+		userRps:      userRps,
+		rfrTknRps:    rfrTknRps,
+		auditLog:     auditLog,
+		emailSender:  emailSender,
 	}
 }
 
-func (s *authService) Signup(ctx context.Context, email, password string) (*model.User, error) {
+func (s *authService) Signup(ctx context.Context, email, password string) (u *model.User, e error) {
+	event := audit.Event{Type: audit.EventSignup, Email: email}
+	defer func() { s.auditLog.Log(ctx, event, e) }()
+
+	if !s.signupCfg.Enabled {
+		return nil, appErrors.NewBusinessErr(appErrors.ErrForbidden, "signup is disabled, contact administrator to get an account")
+	}
+
 	existingUser, err := s.userRps.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
 	if existingUser != nil {
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("user with email %s already exist", email))
+		return nil, appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, fmt.Sprintf("user with email %s already exist", email))
 	}
 
 	hash, err := auth.GeneratePasswordHash(password)
 	if err != nil {
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to generate password hash - %v", err))
+		return nil, appErrors.NewBusinessErr(appErrors.ErrInvalidArgument, fmt.Sprintf("failed to generate password hash - %v", err))
 	}
 
-	u := &model.User{
+	u = &model.User{
 		ID:           uuid.NewString(),
+		TenantID:     tenant.DefaultID,
 		Email:        email,
 		PasswordHash: hash,
 	}
+	event.UserID = u.ID
 
 	if err := s.userRps.Create(ctx, u); err != nil {
 		return nil, err
 	}
+
+	s.sendWelcomeEmail(ctx, u)
 	return u, nil
 }
 
+// sendWelcomeEmail queues welcome email, user is signed up even if email can't be sent
+func (s *authService) sendWelcomeEmail(ctx context.Context, u *model.User) {
+	msg, err := email.Render(email.TemplateWelcome, []string{u.Email}, email.WelcomeData{Email: u.Email})
+	if err == nil {
+		err = s.emailSender.Send(ctx, msg)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx).Warnf("failed to send welcome email to user %s - %v", u.ID, err)
+	}
+}
+
 func (s *authService) Login(ctx context.Context, email, password, fingerprint string, now time.Time) (jwtToken *auth.Jwt, rfrToken *model.RefreshToken, e error) {
+	event := audit.Event{Type: audit.EventLogin, Email: email, Fingerprint: fingerprint}
+	defer func() { s.auditLog.Log(ctx, event, e) }()
+
+	if err := s.verifyFingerprint(fingerprint); err != nil {
+		return nil, nil, err
+	}
+
 	e = s.txtor.WithinTransaction(ctx, func(ctx context.Context) error {
 		user, err := s.userRps.FindByEmail(ctx, email)
 		if err != nil {
@@ -84,15 +134,18 @@ func (s *authService) Login(ctx context.Context, email, password, fingerprint st
 		}
 
 		if user == nil {
-			return echo.ErrUnauthorized
+			event.Reason = "unknown email"
+			return appErrors.ErrUnauthorized
 		}
+		event.UserID = user.ID
 
 		err = auth.VerifyPassword(user.PasswordHash, password)
 		if err != nil {
-			return echo.ErrUnauthorized
+			event.Reason = "invalid password"
+			return appErrors.ErrUnauthorized
 		}
 
-		jwtToken, err = s.jwtIssuer.Sign(email, now)
+		jwtToken, err = s.jwtIssuer.Sign(auth.Principal{Email: user.Email, UserID: user.ID, TenantID: user.TenantID}, now)
 		if err != nil {
 			return err
 		}
@@ -103,8 +156,7 @@ func (s *authService) Login(ctx context.Context, email, password, fingerprint st
 		}
 
 		if len(userTokens) >= s.rfrTokenCfg.MaxCount {
-			logrus.Infof("max refresh tokens count %d is exceeded for user %s - removing all tokens before generation of new one", s.rfrTokenCfg.MaxCount, user.Email)
-			if err := s.rfrTknRps.DeleteByUserID(ctx, user.ID); err != nil {
+			if err := s.removeExceededTokens(ctx, user); err != nil {
 				return err
 			}
 		}
@@ -120,15 +172,23 @@ func (s *authService) Login(ctx context.Context, email, password, fingerprint st
 	return jwtToken, rfrToken, e
 }
 
-func (s *authService) Refresh(ctx context.Context, rfrTokenID, fingerprint string, now time.Time) (*auth.Jwt, *model.RefreshToken, error) {
+func (s *authService) Refresh(ctx context.Context, rfrTokenID, fingerprint string, now time.Time) (_ *auth.Jwt, _ *model.RefreshToken, e error) {
+	event := audit.Event{Type: audit.EventRefresh, Fingerprint: fingerprint}
+	defer func() { s.auditLog.Log(ctx, event, e) }()
+
+	if err := s.verifyFingerprint(fingerprint); err != nil {
+		return nil, nil, err
+	}
+
 	rfrToken, err := s.rfrTknRps.FindByID(ctx, rfrTokenID)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	if rfrToken == nil {
-		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid refresh token provided")
+		return nil, nil, appErrors.NewBusinessErr(nil, "invalid refresh token provided")
 	}
+	event.UserID = rfrToken.UserID
 
 	err = s.rfrTknRps.DeleteByID(ctx, rfrToken.ID)
 	if err != nil {
@@ -136,11 +196,19 @@ func (s *authService) Refresh(ctx context.Context, rfrTokenID, fingerprint strin
 	}
 
 	if rfrToken.Fingerprint != fingerprint {
-		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid fingerprint provided")
+		if s.rfrTokenCfg.FingerprintBinding != config.FingerprintBindingLenient {
+			return nil, nil, appErrors.NewBusinessErr(nil, "invalid fingerprint provided")
+		}
+		logging.FromContext(ctx).Warnf("refresh token of user %s is refreshed from another fingerprint, since fingerprint binding is lenient", rfrToken.UserID)
+	}
+
+	// checked after token is removed, so replayed token is revoked even if it is too young
+	if now.Sub(rfrToken.CreatedAt) < s.rfrTokenCfg.MinRotationAge {
+		return nil, nil, appErrors.NewBusinessErr(nil, "refresh token is too young to be rotated, log in again")
 	}
 
-	if rfrToken.CreatedAt.Add(time.Duration(rfrToken.ExpiresIn) * time.Second).Before(now) {
-		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "refresh token already expired")
+	if rfrToken.ExpiresAt().Before(now.UTC()) {
+		return nil, nil, appErrors.NewBusinessErr(nil, "refresh token already expired")
 	}
 
 	user, err := s.userRps.FindByID(ctx, rfrToken.UserID)
@@ -148,7 +216,12 @@ func (s *authService) Refresh(ctx context.Context, rfrTokenID, fingerprint strin
 		return nil, nil, err
 	}
 
-	jwtToken, err := s.jwtIssuer.Sign(user.Email, now)
+	if user == nil { // user is removed while session is still alive
+		return nil, nil, appErrors.NewEntryNotFoundErr("user", rfrToken.UserID)
+	}
+	event.Email = user.Email
+
+	jwtToken, err := s.jwtIssuer.Sign(auth.Principal{Email: user.Email, UserID: user.ID, TenantID: user.TenantID}, now)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -161,19 +234,84 @@ func (s *authService) Refresh(ctx context.Context, rfrTokenID, fingerprint strin
 	return jwtToken, newRfrToken, nil
 }
 
-func (s *authService) Logout(ctx context.Context, rfrTokenID string) error {
+func (s *authService) Logout(ctx context.Context, rfrTokenID string) (e error) {
+	event := audit.Event{Type: audit.EventLogout}
+	defer func() { s.auditLog.Log(ctx, event, e) }()
+
+	// token is looked up only to know whose session is finished, token itself is secret and never audited
+	rfrToken, err := s.rfrTknRps.FindByID(ctx, rfrTokenID)
+	if err != nil {
+		return err
+	}
+
+	if rfrToken != nil {
+		event.UserID = rfrToken.UserID
+		event.Fingerprint = rfrToken.Fingerprint
+	}
+
 	if err := s.rfrTknRps.DeleteByID(ctx, rfrTokenID); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Introspect reports whether jwt is active, nothing is disclosed about inactive tokens as in OAuth token introspection
+func (s *authService) Introspect(ctx context.Context, rawToken string) (*auth.Introspection, error) {
+	claims, err := s.jwtValidator.Verify(rawToken)
+	if err != nil {
+		return &auth.Introspection{Active: false}, nil
+	}
+
+	revoked, err := s.tokenRevoker.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation - %w", err)
+	}
+
+	if revoked {
+		return &auth.Introspection{Active: false}, nil
+	}
+
+	introspection := &auth.Introspection{
+		Active:  true,
+		Subject: claims.Subject,
+		Issuer:  claims.Issuer,
+	}
+
+	if claims.ExpiresAt != nil {
+		introspection.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	return introspection, nil
+}
+
+func (s *authService) verifyFingerprint(fingerprint string) error {
+	if len(fingerprint) > model.RefreshTokenFingerprintMaxLength {
+		return appErrors.NewBusinessErr(appErrors.ErrInvalidArgument, fmt.Sprintf("fingerprint must not exceed %d characters", model.RefreshTokenFingerprintMaxLength))
+	}
+
+	if s.rfrTokenCfg.FingerprintFormat == config.FingerprintFormatUUID {
+		if _, err := uuid.Parse(fingerprint); err != nil {
+			return appErrors.NewBusinessErr(appErrors.ErrInvalidArgument, "fingerprint must be a valid uuid")
+		}
+	}
+	return nil
+}
+
+func (s *authService) removeExceededTokens(ctx context.Context, user *model.User) error {
+	if s.rfrTokenCfg.ExceedStrategy == config.RefreshTokenExceedEvictOldest {
+		logging.FromContext(ctx).Infof("max refresh tokens count %d is exceeded for user %s - removing oldest tokens before generation of new one", s.rfrTokenCfg.MaxCount, user.Email)
+		return s.rfrTknRps.DeleteOldestByUserID(ctx, user.ID, s.rfrTokenCfg.MaxCount-1)
+	}
+
+	logging.FromContext(ctx).Infof("max refresh tokens count %d is exceeded for user %s - removing all tokens before generation of new one", s.rfrTokenCfg.MaxCount, user.Email)
+	return s.rfrTknRps.DeleteByUserID(ctx, user.ID)
+}
+
 func (s *authService) refreshToken(userID, fingerprint string, createdAt time.Time) *model.RefreshToken {
 	return &model.RefreshToken{
 		ID:          uuid.NewString(),
 		UserID:      userID,
 		Fingerprint: fingerprint,
 		ExpiresIn:   int(s.rfrTokenCfg.TimeToLive.Seconds()),
-		CreatedAt:   createdAt,
+		CreatedAt:   createdAt.UTC(), // caller may pass time in any zone, tokens are kept in UTC
 	}
 }
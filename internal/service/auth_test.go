@@ -3,15 +3,25 @@ package service
 import (
 	"context"
 	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/audit"
 	"github.com/umalmyha/customers/internal/auth"
 	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/email"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/middleware"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/repository/mocks"
 )
@@ -20,7 +30,6 @@ const (
 	jwtAlgoEd25519 = "EdDSA"
 	jwtIssuerClaim = "test-issuer"
 	jwtTimeToLive  = 3 * time.Minute
-	jwtPrivateKey  = "MC4CAQAwBQYDK2VwBCIEIBvYJuek9MjwZuvYT+6W7S9RRgr0SmxRqejl2v6y9jjo"
 )
 
 const (
@@ -28,15 +37,27 @@ const (
 	refreshTokenTimeToLive = 720 * time.Hour
 )
 
+// fakeEmailSender keeps messages instead of sending them
+type fakeEmailSender struct {
+	sent []*email.Message
+}
+
+func (f *fakeEmailSender) Send(_ context.Context, m *email.Message) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
 type authTestData struct {
 	ctx         context.Context
 	now         time.Time
 	password    string
 	fingerprint string
 	issuer      *auth.JwtIssuer
+	validator   *auth.JwtValidator
 	user        *model.User
 	rfrToken    *model.RefreshToken
 	rfrTokenCfg *config.RefreshTokenCfg
+	signupCfg   *config.SignupCfg
 }
 
 type authServiceTestSuite struct {
@@ -45,6 +66,10 @@ type authServiceTestSuite struct {
 	transactorMock  *mocks.Transactor
 	userRpsMock     *mocks.UserRepository
 	rfrTokenRpsMock *mocks.RefreshTokenRepository
+	tokenRevoker    auth.TokenRevoker
+	auditLog        *audit.Logger
+	auditHook       *logrusTest.Hook
+	emailSender     *fakeEmailSender
 	testData        *authTestData
 }
 
@@ -62,15 +87,21 @@ func (s *authServiceTestSuite) SetupSuite() {
 	fingerprint := "87c37298-2f3d-40a1-9438-f45d2d819206"
 	password := "secret_password"
 
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	s.Require().NoError(err, "failed to generate jwt keys")
+
 	jwtIssuer := auth.NewJwtIssuer(
 		jwtIssuerClaim,
+		"",
 		jwt.GetSigningMethod(jwtAlgoEd25519),
 		jwtTimeToLive,
-		ed25519.PrivateKey(jwtPrivateKey),
+		privateKey,
 	)
+	jwtValidator := auth.NewJwtValidator(jwt.GetSigningMethod(jwtAlgoEd25519), publicKey, jwtIssuerClaim, "")
 
 	user := &model.User{
 		ID:           "bdf2f837-75f6-462a-b9ec-5dfb2e8f8792",
+		TenantID:     "acme",
 		Email:        "test@email.com",
 		PasswordHash: "$2y$10$iKrALz6vQTs8KcAOElIdHeO0ZKWZkyfFnxPsJYU.Dys/2Rz177p32",
 	}
@@ -83,7 +114,11 @@ func (s *authServiceTestSuite) SetupSuite() {
 		CreatedAt:   now,
 	}
 
-	rfrTokenCfg := &config.RefreshTokenCfg{MaxCount: refreshTokenMaxCount, TimeToLive: refreshTokenTimeToLive}
+	rfrTokenCfg := &config.RefreshTokenCfg{
+		MaxCount:       refreshTokenMaxCount,
+		TimeToLive:     refreshTokenTimeToLive,
+		ExceedStrategy: config.RefreshTokenExceedDeleteAll,
+	}
 
 	s.testData = &authTestData{
 		ctx:         context.Background(),
@@ -91,9 +126,11 @@ func (s *authServiceTestSuite) SetupSuite() {
 		password:    password,
 		fingerprint: fingerprint,
 		issuer:      jwtIssuer,
+		validator:   jwtValidator,
 		user:        user,
 		rfrToken:    rfrToken,
 		rfrTokenCfg: rfrTokenCfg,
+		signupCfg:   &config.SignupCfg{Enabled: true},
 	}
 }
 
@@ -101,7 +138,12 @@ func (s *authServiceTestSuite) SetupTest() {
 	t := s.T()
 	s.userRpsMock = mocks.NewUserRepository(t)
 	s.rfrTokenRpsMock = mocks.NewRefreshTokenRepository(t)
-	s.authSvc = NewAuthService(s.testData.issuer, s.testData.rfrTokenCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock)
+	auditLogger, auditHook := logrusTest.NewNullLogger()
+	s.auditLog = audit.NewLogger(auditLogger)
+	s.auditHook = auditHook
+	s.tokenRevoker = auth.NewInMemoryTokenRevoker(jwtTimeToLive)
+	s.emailSender = &fakeEmailSender{}
+	s.authSvc = NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, s.testData.rfrTokenCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
 	s.userRpsMock.TestData()
 }
 
@@ -117,7 +159,12 @@ func (s *authServiceTestSuite) TestSignupEmailReserved() {
 	{
 		_, err := s.authSvc.Signup(ctx, email, password)
 		s.Assert().Error(err, "user with email %s already exist but no error raised", email)
-		s.Assert().IsType(&echo.HTTPError{}, err, "error must be echo error")
+		s.Assert().ErrorIs(err, appErrors.ErrDuplicateEmail, "it must be duplicate email error")
+
+		entry := s.requireAuditEntry(audit.EventSignup, audit.OutcomeFailure)
+		s.Assert().Equal(email, entry.Data["email"], "email must be audited")
+		s.Assert().Equal(fmt.Sprintf("user with email %s already exist", email), entry.Data["reason"], "failure reason must be audited")
+		s.Assert().Empty(s.emailSender.sent, "welcome email must not be sent")
 	}
 }
 
@@ -126,13 +173,51 @@ func (s *authServiceTestSuite) TestSuccessfulSignup() {
 	email := s.testData.user.Email
 	password := s.testData.password
 
-	s.userRpsMock.On("FindByEmail", ctx, email).Return(nil, nil).Once()
-	s.userRpsMock.On("Create", ctx, mock.AnythingOfType("*model.User")).Return(nil).Once()
+	clientIP := "203.0.113.7"
+	ipCtx := audit.ContextWithClientIP(ctx, clientIP)
+
+	s.userRpsMock.On("FindByEmail", ipCtx, email).Return(nil, nil).Once()
+	s.userRpsMock.On("Create", ipCtx, mock.AnythingOfType("*model.User")).Return(nil).Once()
 
 	s.T().Logf("signup user %s and it must be signed up successfully", email)
 	{
-		_, err := s.authSvc.Signup(context.Background(), email, password)
+		user, err := s.authSvc.Signup(ipCtx, email, password)
 		s.Assert().NoError(err, "user with email %s must be signed up successfully", email)
+
+		entry := s.requireAuditEntry(audit.EventSignup, audit.OutcomeSuccess)
+		s.Assert().Equal(logrus.InfoLevel, entry.Level, "successful event must be audited with info level")
+		s.Assert().Equal(user.ID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(email, entry.Data["email"], "email must be audited")
+		s.Assert().Equal(clientIP, entry.Data["ip"], "client ip must be audited")
+		s.Assert().NotContains(entry.Data, "reason", "successful event must not have failure reason")
+	}
+
+	s.T().Log("welcome email must be sent to signed up user")
+	{
+		s.Require().Len(s.emailSender.sent, 1, "single welcome email must be sent")
+		msg := s.emailSender.sent[0]
+		s.Assert().Equal([]string{email}, msg.To, "welcome email must be sent to user")
+		s.Assert().True(msg.HTML, "welcome email must be html")
+		s.Assert().Contains(msg.Body, email, "welcome email must mention account")
+	}
+}
+
+func (s *authServiceTestSuite) TestSignupDisabled() {
+	ctx := s.testData.ctx
+	email := s.testData.user.Email
+	password := s.testData.password
+
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, s.testData.rfrTokenCfg, &config.SignupCfg{Enabled: false}, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.T().Logf("signup user %s, but signup is disabled", email)
+	{
+		_, err := authSvc.Signup(ctx, email, password)
+		s.Assert().ErrorIs(err, appErrors.ErrForbidden, "it must be forbidden error")
+		s.userRpsMock.AssertNotCalled(s.T(), "Create", mock.Anything, mock.Anything)
+
+		entry := s.requireAuditEntry(audit.EventSignup, audit.OutcomeFailure)
+		s.Assert().Equal(email, entry.Data["email"], "email must be audited")
+		s.Assert().Equal("signup is disabled, contact administrator to get an account", entry.Data["reason"], "failure reason must be audited")
 	}
 }
 
@@ -149,7 +234,45 @@ func (s *authServiceTestSuite) TestLoginBadUsername() {
 	{
 		_, _, err := s.authSvc.Login(ctx, email, password, fingerprint, now)
 		s.Assert().Error(err, "user with email %s is not registered, but no error raised", email)
-		s.Assert().ErrorIs(err, echo.ErrUnauthorized, "it must be unauthorized error")
+		s.Assert().ErrorIs(err, appErrors.ErrUnauthorized, "it must be unauthorized error")
+
+		entry := s.requireAuditEntry(audit.EventLogin, audit.OutcomeFailure)
+		s.Assert().Equal("unknown email", entry.Data["reason"], "failure reason must be audited")
+		s.Assert().NotContains(entry.Data, "userId", "unknown user can't be audited")
+	}
+}
+
+func (s *authServiceTestSuite) TestLoginTokenPassesAdminCheck() {
+	ctx := s.testData.ctx
+	user := s.testData.user
+	fingerprint := s.testData.fingerprint
+	password := s.testData.password
+
+	s.userRpsMock.On("FindByEmail", ctx, user.Email).Return(user, nil).Once()
+	s.rfrTokenRpsMock.On("FindTokensByUserID", ctx, user.ID).Return(nil, nil).Once()
+	s.rfrTokenRpsMock.On("Create", ctx, mock.AnythingOfType("*model.RefreshToken")).Return(nil).Once()
+
+	app := echo.New()
+	app.GET("/admin", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, middleware.Authorize(s.testData.validator, s.tokenRevoker), middleware.Admin([]string{user.ID}))
+
+	s.T().Log("token issued on login identifies user by id, so admin is allowed")
+	{
+		jwToken, _, err := s.authSvc.Login(ctx, user.Email, password, fingerprint, time.Now().UTC())
+		s.Require().NoError(err, "login must succeed")
+
+		claims, err := s.testData.validator.Verify(jwToken.Signed)
+		s.Require().NoError(err, "token must be valid")
+		s.Assert().Equal(user.Email, claims.Subject, "email must be subject of token")
+		s.Assert().Equal(user.ID, claims.UserID, "user id must be carried by token")
+		s.Assert().Equal(user.TenantID, claims.TenantID, "user tenant must be carried by token")
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+jwToken.Signed)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		s.Assert().Equal(http.StatusNoContent, rec.Code, "admin logged in via auth service must be allowed")
 	}
 }
 
@@ -167,7 +290,17 @@ func (s *authServiceTestSuite) TestLoginBadPassword() {
 	{
 		_, _, err := s.authSvc.Login(ctx, email, invalidPassword, fingerprint, now)
 		s.Assert().Error(err, "wrong password is provided but no error raised")
-		s.Assert().ErrorIs(err, echo.ErrUnauthorized, "it must be unauthorized error")
+		s.Assert().ErrorIs(err, appErrors.ErrUnauthorized, "it must be unauthorized error")
+
+		entry := s.requireAuditEntry(audit.EventLogin, audit.OutcomeFailure)
+		s.Assert().Equal(logrus.WarnLevel, entry.Level, "failed event must be audited with warning level")
+		s.Assert().Equal(user.ID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(email, entry.Data["email"], "email must be audited")
+		s.Assert().Equal(fingerprint, entry.Data["fingerprint"], "fingerprint must be audited")
+		s.Assert().Equal("invalid password", entry.Data["reason"], "failure reason must be audited")
+		for _, v := range entry.Data {
+			s.Assert().NotEqual(invalidPassword, v, "password must never be audited")
+		}
 	}
 }
 
@@ -208,6 +341,58 @@ func (s *authServiceTestSuite) TestLoginSuccessAndPreviousTokensRemoved() {
 		s.Assert().Equal(now.Add(jwtTimeToLive).Unix(), jwToken.ExpiresAt, "incorrect time to live was set for jwt")
 		s.Assert().Equal(int(refreshTokenTimeToLive.Seconds()), rfrToken.ExpiresIn, "expires in is set incorrectly")
 		s.rfrTokenRpsMock.AssertCalled(s.T(), "DeleteByUserID", ctx, user.ID)
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "DeleteOldestByUserID", ctx, user.ID, refreshTokenMaxCount-1)
+
+		entry := s.requireAuditEntry(audit.EventLogin, audit.OutcomeSuccess)
+		s.Assert().Equal(user.ID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(fingerprint, entry.Data["fingerprint"], "fingerprint must be audited")
+		for _, v := range entry.Data {
+			s.Assert().NotEqual(jwToken.Signed, v, "access token must never be audited")
+			s.Assert().NotEqual(rfrToken.ID, v, "refresh token must never be audited")
+		}
+	}
+}
+
+func (s *authServiceTestSuite) TestLoginSuccessAndOldestTokensEvicted() {
+	ctx := s.testData.ctx
+	user := s.testData.user
+	email := s.testData.user.Email
+	password := s.testData.password
+	fingerprint := s.testData.fingerprint
+	now := s.testData.now
+
+	evictCfg := *s.testData.rfrTokenCfg
+	evictCfg.ExceedStrategy = config.RefreshTokenExceedEvictOldest
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &evictCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	dbTokens := []*model.RefreshToken{
+		{
+			ID:          "af1adce5-51a4-4d2e-a6ba-da0e7009a1bf",
+			UserID:      user.ID,
+			Fingerprint: "86d36dcb-512b-402d-bec4-ae8922677cd7",
+			ExpiresIn:   1000,
+			CreatedAt:   now.Add(-time.Hour),
+		},
+		{
+			ID:          "2b3a0c5e-92a4-4b8e-a0f5-f5b7e1bd3c7e",
+			UserID:      user.ID,
+			Fingerprint: "88a6a8ac-1104-41ae-b13c-c33deb5af5c2",
+			ExpiresIn:   2000,
+			CreatedAt:   now,
+		},
+	}
+
+	s.userRpsMock.On("FindByEmail", ctx, email).Return(user, nil).Once()
+	s.rfrTokenRpsMock.On("FindTokensByUserID", ctx, user.ID).Return(dbTokens, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteOldestByUserID", ctx, user.ID, refreshTokenMaxCount-1).Return(nil).Once()
+	s.rfrTokenRpsMock.On("Create", ctx, mock.AnythingOfType("*model.RefreshToken")).Return(nil).Once()
+
+	s.T().Logf("login user %s successfully, only the oldest tokens will be removed", email)
+	{
+		_, _, err := authSvc.Login(ctx, email, password, fingerprint, now)
+		s.Assert().NoError(err, "user login is correct but error was raised")
+		s.rfrTokenRpsMock.AssertCalled(s.T(), "DeleteOldestByUserID", ctx, user.ID, refreshTokenMaxCount-1)
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "DeleteByUserID", ctx, user.ID)
 	}
 }
 
@@ -223,7 +408,7 @@ func (s *authServiceTestSuite) TestRefreshInvalidToken() {
 	{
 		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, fingerprint, now)
 		s.Assert().Error(err, "invalid refresh token id was provided but no error raised")
-		s.Assert().IsType(&echo.HTTPError{}, err, "error must be echo error")
+		s.Assert().IsType(&appErrors.BusinessErr{}, err, "error must be business error")
 	}
 }
 
@@ -240,7 +425,113 @@ func (s *authServiceTestSuite) TestRefreshInvalidFingerprint() {
 	{
 		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, invalidFingerprint, now)
 		s.Assert().Error(err, "invalid refresh token fingerprint was provided but no error raised")
-		s.Assert().IsType(&echo.HTTPError{}, err, "error must be echo error")
+		s.Assert().IsType(&appErrors.BusinessErr{}, err, "error must be business error")
+
+		entry := s.requireAuditEntry(audit.EventRefresh, audit.OutcomeFailure)
+		s.Assert().Equal(rfrToken.UserID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(invalidFingerprint, entry.Data["fingerprint"], "provided fingerprint must be audited")
+		s.Assert().Equal("invalid fingerprint provided", entry.Data["reason"], "failure reason must be audited")
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshInvalidFingerprintStrictBinding() {
+	ctx := s.testData.ctx
+	rfrToken := s.testData.rfrToken
+	now := s.testData.now
+	invalidFingerprint := "461b07b5-3373-495d-b26b-d689a0c8a557"
+
+	strictCfg := *s.testData.rfrTokenCfg
+	strictCfg.FingerprintBinding = config.FingerprintBindingStrict
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &strictCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+
+	s.T().Log("refresh with mismatched fingerprint when binding is strict")
+	{
+		_, _, err := authSvc.Refresh(ctx, rfrToken.ID, invalidFingerprint, now)
+		s.Assert().Error(err, "mismatched fingerprint was provided but no error raised")
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.RefreshToken"))
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshInvalidFingerprintLenientBinding() {
+	ctx := s.testData.ctx
+	user := s.testData.user
+	rfrToken := s.testData.rfrToken
+	now := s.testData.now
+	newFingerprint := "461b07b5-3373-495d-b26b-d689a0c8a557"
+
+	logHook := logrusTest.NewGlobal()
+	defer logHook.Reset()
+
+	lenientCfg := *s.testData.rfrTokenCfg
+	lenientCfg.FingerprintBinding = config.FingerprintBindingLenient
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &lenientCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+	s.userRpsMock.On("FindByID", ctx, rfrToken.UserID).Return(user, nil).Once()
+	s.rfrTokenRpsMock.On("Create", ctx, mock.AnythingOfType("*model.RefreshToken")).Return(nil).Once()
+
+	s.T().Log("refresh with mismatched fingerprint when binding is lenient")
+	{
+		_, newRfrToken, err := authSvc.Refresh(ctx, rfrToken.ID, newFingerprint, now)
+		s.Require().NoError(err, "mismatched fingerprint must be allowed")
+		s.Assert().Equal(newFingerprint, newRfrToken.Fingerprint, "new token must be bound to the new fingerprint")
+
+		s.Require().NotNil(logHook.LastEntry(), "fingerprint mismatch must be logged")
+		s.Assert().Equal(logrus.WarnLevel, logHook.LastEntry().Level, "fingerprint mismatch must be logged as warning")
+		s.requireAuditEntry(audit.EventRefresh, audit.OutcomeSuccess)
+	}
+}
+
+func (s *authServiceTestSuite) TestLoginOverlongFingerprint() {
+	ctx := s.testData.ctx
+	email := s.testData.user.Email
+	password := s.testData.password
+	now := s.testData.now
+	overlongFingerprint := strings.Repeat("f", model.RefreshTokenFingerprintMaxLength+1)
+
+	s.T().Log("login with overlong fingerprint")
+	{
+		_, _, err := s.authSvc.Login(ctx, email, password, overlongFingerprint, now)
+		s.Assert().Error(err, "overlong fingerprint was provided but no error raised")
+		s.Assert().ErrorIs(err, appErrors.ErrInvalidArgument, "overlong fingerprint must be rejected as invalid argument")
+		s.userRpsMock.AssertNotCalled(s.T(), "FindByEmail", ctx, email)
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshOverlongFingerprint() {
+	ctx := s.testData.ctx
+	rfrToken := s.testData.rfrToken
+	now := s.testData.now
+	overlongFingerprint := strings.Repeat("f", model.RefreshTokenFingerprintMaxLength+1)
+
+	s.T().Log("refresh with overlong fingerprint")
+	{
+		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, overlongFingerprint, now)
+		s.Assert().Error(err, "overlong fingerprint was provided but no error raised")
+		s.Assert().ErrorIs(err, appErrors.ErrInvalidArgument, "overlong fingerprint must be rejected as invalid argument")
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "FindByID", ctx, rfrToken.ID)
+	}
+}
+
+func (s *authServiceTestSuite) TestLoginFingerprintMustBeUUID() {
+	ctx := s.testData.ctx
+	email := s.testData.user.Email
+	password := s.testData.password
+	now := s.testData.now
+
+	uuidCfg := *s.testData.rfrTokenCfg
+	uuidCfg.FingerprintFormat = config.FingerprintFormatUUID
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &uuidCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.T().Log("login with non-uuid fingerprint when uuid format is required")
+	{
+		_, _, err := authSvc.Login(ctx, email, password, "browser-fingerprint", now)
+		s.Assert().Error(err, "non-uuid fingerprint was provided but no error raised")
+		s.Assert().ErrorIs(err, appErrors.ErrInvalidArgument, "non-uuid fingerprint must be rejected as invalid argument")
 	}
 }
 
@@ -258,7 +549,97 @@ func (s *authServiceTestSuite) TestRefreshExpiredToken() {
 	{
 		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, fingerprint, futureNow)
 		s.Assert().Error(err, "refresh for expired refresh token was provided but no error raised")
-		s.Assert().IsType(&echo.HTTPError{}, err, "error must be echo error")
+		s.Assert().IsType(&appErrors.BusinessErr{}, err, "error must be business error")
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshTooSoon() {
+	ctx := s.testData.ctx
+	rfrToken := s.testData.rfrToken
+	fingerprint := s.testData.fingerprint
+	now := s.testData.now
+
+	minAgeCfg := *s.testData.rfrTokenCfg
+	minAgeCfg.MinRotationAge = time.Minute
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &minAgeCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+
+	s.T().Log("rotation of token issued within min rotation age is rejected and token is revoked")
+	{
+		_, _, err := authSvc.Refresh(ctx, rfrToken.ID, fingerprint, now.Add(30*time.Second))
+		s.Assert().Error(err, "too soon rotation was requested but no error raised")
+		s.Assert().IsType(&appErrors.BusinessErr{}, err, "error must be business error")
+		s.rfrTokenRpsMock.AssertCalled(s.T(), "DeleteByID", ctx, rfrToken.ID)
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.RefreshToken"))
+		s.requireAuditEntry(audit.EventRefresh, audit.OutcomeFailure)
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshTooSoonFromAnotherFingerprint() {
+	ctx := s.testData.ctx
+	rfrToken := s.testData.rfrToken
+	now := s.testData.now
+
+	minAgeCfg := *s.testData.rfrTokenCfg
+	minAgeCfg.MinRotationAge = time.Minute
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &minAgeCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+
+	s.T().Log("replayed young token from another fingerprint is revoked and rejected as fingerprint mismatch")
+	{
+		_, _, err := authSvc.Refresh(ctx, rfrToken.ID, "0f6b2c1e-8d4a-4e3b-9a7c-5d2e1f0a9b8c", now.Add(30*time.Second))
+		s.Require().Error(err, "young token with foreign fingerprint was provided but no error raised")
+		s.Assert().Contains(err.Error(), "invalid fingerprint", "fingerprint mismatch must be reported")
+		s.rfrTokenRpsMock.AssertCalled(s.T(), "DeleteByID", ctx, rfrToken.ID)
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.RefreshToken"))
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshAfterMinRotationAge() {
+	ctx := s.testData.ctx
+	user := s.testData.user
+	rfrToken := s.testData.rfrToken
+	fingerprint := s.testData.fingerprint
+	rotatedAt := s.testData.now.Add(time.Minute)
+
+	minAgeCfg := *s.testData.rfrTokenCfg
+	minAgeCfg.MinRotationAge = time.Minute
+	authSvc := NewAuthService(s.testData.issuer, s.testData.validator, s.tokenRevoker, &minAgeCfg, s.testData.signupCfg, s.transactorMock, s.userRpsMock, s.rfrTokenRpsMock, s.auditLog, s.emailSender)
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+	s.userRpsMock.On("FindByID", ctx, rfrToken.UserID).Return(user, nil).Once()
+	s.rfrTokenRpsMock.On("Create", ctx, mock.AnythingOfType("*model.RefreshToken")).Return(nil).Once()
+
+	s.T().Log("token is rotated once min rotation age is reached and new expiry is exposed")
+	{
+		_, newRfrToken, err := authSvc.Refresh(ctx, rfrToken.ID, fingerprint, rotatedAt)
+		s.Require().NoError(err, "rotation after min rotation age must be allowed")
+		s.Assert().Equal(rotatedAt.Add(refreshTokenTimeToLive).Unix(), newRfrToken.ExpiresAt().Unix(), "incorrect expiry of rotated token")
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshRemovedUser() {
+	ctx := s.testData.ctx
+	rfrToken := s.testData.rfrToken
+	fingerprint := s.testData.fingerprint
+	now := s.testData.now
+
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+	s.userRpsMock.On("FindByID", ctx, rfrToken.UserID).Return(nil, nil).Once()
+
+	s.T().Log("refresh session of removed user")
+	{
+		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, fingerprint, now)
+		var notFoundErr *appErrors.EntryNotFoundErr
+		s.Require().ErrorAs(err, &notFoundErr, "error must be entry not found error")
+		s.Assert().Equal(rfrToken.UserID, notFoundErr.ID, "id of missing user must be reported")
+		s.rfrTokenRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.RefreshToken"))
 	}
 }
 
@@ -280,6 +661,56 @@ func (s *authServiceTestSuite) TestRefreshSuccessful() {
 		s.Assert().NoError(err, "refresh request is correctly sent but no error raised")
 		s.Assert().Equal(now.Add(jwtTimeToLive).Unix(), jwToken.ExpiresAt, "incorrect time to live was set for jwt")
 		s.Assert().Equal(int(refreshTokenTimeToLive.Seconds()), newRfrToken.ExpiresIn, "expires in is set incorrectly")
+
+		entry := s.requireAuditEntry(audit.EventRefresh, audit.OutcomeSuccess)
+		s.Assert().Equal(user.ID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(user.Email, entry.Data["email"], "email must be audited")
+	}
+}
+
+func (s *authServiceTestSuite) TestRefreshNonUTCTime() {
+	ctx := s.testData.ctx
+	user := s.testData.user
+	fingerprint := s.testData.fingerprint
+	minsk := time.FixedZone("UTC+3", 3*60*60)
+	newYork := time.FixedZone("UTC-5", -5*60*60)
+	createdAt := time.Date(2022, time.August, 1, 10, 0, 0, 0, minsk)
+
+	rfrToken := &model.RefreshToken{
+		ID:          "5e0f6a2b-8c4d-4e1f-9a7b-3c2d1e0f9a8b",
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		ExpiresIn:   60,
+		CreatedAt:   createdAt,
+	}
+
+	s.T().Log("refresh token created in other time zone is valid until its expiration")
+	{
+		now := createdAt.Add(59 * time.Second).In(newYork)
+
+		s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+		s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+		s.userRpsMock.On("FindByID", ctx, rfrToken.UserID).Return(user, nil).Once()
+		s.rfrTokenRpsMock.On("Create", ctx, mock.AnythingOfType("*model.RefreshToken")).Return(nil).Once()
+
+		_, newRfrToken, err := s.authSvc.Refresh(ctx, rfrToken.ID, fingerprint, now)
+		s.Require().NoError(err, "refresh token is not expired yet but error raised")
+		s.Assert().Equal(time.UTC, newRfrToken.CreatedAt.Location(), "refresh token must be created in UTC")
+		s.Assert().True(now.Equal(newRfrToken.CreatedAt), "refresh token must be created at provided moment")
+		s.Assert().Equal(time.UTC, rfrToken.ExpiresAt().Location(), "expiration must be calculated in UTC")
+		s.Assert().Equal(time.Date(2022, time.August, 1, 7, 1, 0, 0, time.UTC), rfrToken.ExpiresAt(), "incorrect expiration")
+	}
+
+	s.T().Log("refresh token created in other time zone is expired after its expiration")
+	{
+		now := createdAt.Add(61 * time.Second).In(newYork)
+
+		s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
+		s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
+
+		_, _, err := s.authSvc.Refresh(ctx, rfrToken.ID, fingerprint, now)
+		s.Assert().Error(err, "refresh token is expired but no error raised")
+		s.Assert().IsType(&appErrors.BusinessErr{}, err, "error must be business error")
 	}
 }
 
@@ -287,13 +718,88 @@ func (s *authServiceTestSuite) TestLogout() {
 	ctx := s.testData.ctx
 	rfrToken := s.testData.rfrToken
 
+	s.rfrTokenRpsMock.On("FindByID", ctx, rfrToken.ID).Return(rfrToken, nil).Once()
 	s.rfrTokenRpsMock.On("DeleteByID", ctx, rfrToken.ID).Return(nil).Once()
 
 	s.T().Log("refresh with already expired token")
 	{
 		err := s.authSvc.Logout(ctx, rfrToken.ID)
 		s.Assert().NoError(err, "logout request is correct but error was raised")
+
+		entry := s.requireAuditEntry(audit.EventLogout, audit.OutcomeSuccess)
+		s.Assert().Equal(rfrToken.UserID, entry.Data["userId"], "user id must be audited")
+		s.Assert().Equal(rfrToken.Fingerprint, entry.Data["fingerprint"], "fingerprint must be audited")
+		for _, v := range entry.Data {
+			s.Assert().NotEqual(rfrToken.ID, v, "refresh token must never be audited")
+		}
+	}
+}
+
+func (s *authServiceTestSuite) TestIntrospectValidToken() {
+	ctx := s.testData.ctx
+	email := s.testData.user.Email
+	now := time.Now()
+
+	jwToken, err := s.testData.issuer.Sign(auth.Principal{Email: email}, now)
+	s.Require().NoError(err, "failed to sign jwt")
+
+	s.T().Log("introspect valid token")
+	{
+		res, err := s.authSvc.Introspect(ctx, jwToken.Signed)
+		s.Require().NoError(err, "token is valid but error was raised")
+		s.Assert().True(res.Active, "valid token must be active")
+		s.Assert().Equal(email, res.Subject, "subject must be returned")
+		s.Assert().Equal(jwtIssuerClaim, res.Issuer, "issuer must be returned")
+		s.Assert().Equal(jwToken.ExpiresAt, res.ExpiresAt, "expiration must be returned")
+	}
+
+	s.T().Log("introspect revoked token")
+	{
+		claims, err := s.testData.validator.Verify(jwToken.Signed)
+		s.Require().NoError(err, "failed to verify jwt")
+		s.Require().NoError(s.tokenRevoker.Revoke(ctx, claims), "failed to revoke jwt")
+
+		res, err := s.authSvc.Introspect(ctx, jwToken.Signed)
+		s.Require().NoError(err, "token is revoked but error was raised")
+		s.Assert().Equal(&auth.Introspection{Active: false}, res, "revoked token must be inactive")
+	}
+}
+
+func (s *authServiceTestSuite) TestIntrospectExpiredToken() {
+	ctx := s.testData.ctx
+	email := s.testData.user.Email
+
+	jwToken, err := s.testData.issuer.Sign(auth.Principal{Email: email}, time.Now().Add(-2*jwtTimeToLive))
+	s.Require().NoError(err, "failed to sign jwt")
+
+	s.T().Log("introspect expired token")
+	{
+		res, err := s.authSvc.Introspect(ctx, jwToken.Signed)
+		s.Require().NoError(err, "token is expired but error was raised")
+		s.Assert().Equal(&auth.Introspection{Active: false}, res, "expired token must be inactive and claims must not be disclosed")
+	}
+}
+
+func (s *authServiceTestSuite) TestIntrospectMalformedToken() {
+	ctx := s.testData.ctx
+
+	s.T().Log("introspect malformed token")
+	{
+		res, err := s.authSvc.Introspect(ctx, "not.a.jwt")
+		s.Require().NoError(err, "token is malformed but error was raised")
+		s.Assert().Equal(&auth.Introspection{Active: false}, res, "malformed token must be inactive")
+	}
+}
+
+func (s *authServiceTestSuite) requireAuditEntry(event audit.EventType, outcome string) *logrus.Entry {
+	entry := s.auditHook.LastEntry()
+	s.Require().NotNil(entry, "auth event must be audited")
+	s.Assert().Equal(event, entry.Data["event"], "incorrect audited event")
+	s.Assert().Equal(outcome, entry.Data["outcome"], "incorrect audited outcome")
+	for _, v := range entry.Data {
+		s.Assert().NotEqual(s.testData.password, v, "password must never be audited")
 	}
+	return entry
 }
 
 // start auth service test suite
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// CacheService represents behavior of cache maintenance service
+type CacheService interface {
+	Purge(context.Context) (int, error)
+}
+
+type cacheService struct {
+	purgers []cache.CustomerCachePurger
+}
+
+// NewCacheService builds new cacheService purging all provided caches
+func NewCacheService(purgers ...cache.CustomerCachePurger) CacheService {
+	return &cacheService{purgers: purgers}
+}
+
+// Purge removes all cached customers and returns number of removed entries, purging stops on the first failed cache
+func (s *cacheService) Purge(ctx context.Context) (int, error) {
+	removed := 0
+	for _, p := range s.purgers {
+		n, err := p.Purge(ctx)
+		removed += n
+		if err != nil {
+			logging.FromContext(ctx).Errorf("customer cache purge failed, %d entries are removed - %v", removed, err)
+			return removed, err
+		}
+	}
+
+	logging.FromContext(ctx).Infof("customer cache is purged, %d entries are removed", removed)
+	return removed, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+// failingCachePurger imitates unreachable cache
+type failingCachePurger struct{}
+
+func (failingCachePurger) Purge(context.Context) (int, error) {
+	return 0, errors.New("redis: connection refused")
+}
+
+type cacheServiceTestSuite struct {
+	suite.Suite
+	primary  cache.PurgeableCustomerCache
+	fallback cache.PurgeableCustomerCache
+}
+
+func (s *cacheServiceTestSuite) SetupTest() {
+	s.primary = cache.NewInMemoryCache()
+	s.fallback = cache.NewInMemoryCache()
+
+	customers := []*model.Customer{
+		{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: "acme", FirstName: "John", LastName: "Walls", Email: "john.walls@somemail.com", Importance: model.ImportanceLow},
+		{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "globex", FirstName: "Jane", LastName: "Doe", Email: "jane.doe@somemail.com", Importance: model.ImportanceCritical},
+	}
+	for _, c := range customers {
+		s.Require().NoError(s.primary.Create(context.Background(), c), "failed to cache customer")
+	}
+	s.Require().NoError(s.fallback.Create(context.Background(), customers[0]), "failed to cache customer")
+}
+
+func (s *cacheServiceTestSuite) TestPurge() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("all caches are purged and removed entries are summed up")
+	{
+		removed, err := NewCacheService(s.primary, s.fallback).Purge(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Equal(3, removed, "all cached customers must be removed")
+
+		found, err := s.primary.FindByID(ctx, "acme", "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c")
+		require.NoError(err, "no error must be raised")
+		require.Nil(found, "customer must be removed from cache")
+	}
+}
+
+func (s *cacheServiceTestSuite) TestPurgeFailed() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("purge stops on failed cache and entries removed so far are reported")
+	{
+		removed, err := NewCacheService(s.primary, failingCachePurger{}, s.fallback).Purge(ctx)
+		require.Error(err, "failure must be raised")
+		require.Equal(2, removed, "entries removed before failure must be counted")
+
+		found, err := s.fallback.FindByID(ctx, "acme", "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c")
+		require.NoError(err, "no error must be raised")
+		require.NotNil(found, "cache after failed one must be left untouched")
+	}
+}
+
+// start cache service test suite
+func TestCacheServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(cacheServiceTestSuite))
+}
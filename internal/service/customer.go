@@ -2,57 +2,170 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"github.com/umalmyha/customers/internal/cache"
+	"github.com/umalmyha/customers/internal/config"
+	appErrors "github.com/umalmyha/customers/internal/errors"
 	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
 )
 
 // CustomerService represents behavior of customer service
 type CustomerService interface {
 	FindAll(context.Context) ([]*model.Customer, error)
+	FindAllActive(context.Context) ([]*model.Customer, error)
 	FindByID(context.Context, string) (*model.Customer, error)
 	Create(context.Context, *model.Customer) (*model.Customer, error)
+	CreateIfNotExists(context.Context, *model.Customer) (*model.Customer, bool, error)
 	DeleteByID(context.Context, string) error
-	Upsert(context.Context, *model.Customer) (*model.Customer, error)
+	Upsert(context.Context, *model.Customer) (*model.Customer, []model.CustomerChange, error)
+	Update(context.Context, *model.Customer) (*model.Customer, []model.CustomerChange, error)
+	BulkUpdate(context.Context, *model.CustomerFilter, *model.CustomerPatch) (int, error)
 }
 
 type customerService struct {
-	customerRps repository.CustomerRepository
-	cacheRps    cache.CustomerCacheRepository
+	customerRps       repository.CustomerRepository
+	cacheRps          cache.CustomerCacheRepository
+	populationFailure config.CachePopulationFailure
 }
 
-// NewCustomerService builds new customerService
+// NewCustomerService builds new customerService, populationFailure defines whether failure to cache customer fails FindByID
 func NewCustomerService(
 	customerRps repository.CustomerRepository,
 	cacheRps cache.CustomerCacheRepository,
+	populationFailure config.CachePopulationFailure,
 ) CustomerService {
-	return &customerService{customerRps: customerRps, cacheRps: cacheRps}
+	return &customerService{customerRps: customerRps, cacheRps: cacheRps, populationFailure: populationFailure}
+}
+
+// CustomerBackend is datastore customer service is built on top of,
+// transactor is optional and must be the one repository operations join transactions of
+type CustomerBackend struct {
+	Repository             repository.CustomerRepository
+	Cache                  cache.CustomerCacheRepository
+	CachePopulationFailure config.CachePopulationFailure
+	Transactor             transactor.Transactor
+}
+
+// CustomerServiceFactory builds customer services for configured backends, service is shared by api versions with the same backend
+type CustomerServiceFactory struct {
+	backends map[config.CustomersBackend]CustomerBackend
+	services map[config.CustomersBackend]CustomerService
+}
+
+// NewCustomerServiceFactory builds new CustomerServiceFactory
+func NewCustomerServiceFactory(backends map[config.CustomersBackend]CustomerBackend) *CustomerServiceFactory {
+	return &CustomerServiceFactory{
+		backends: backends,
+		services: make(map[config.CustomersBackend]CustomerService),
+	}
+}
+
+// Build returns customer service on top of backend, error is raised if backend is unknown
+func (f *CustomerServiceFactory) Build(backend config.CustomersBackend) (CustomerService, error) {
+	if svc, ok := f.services[backend]; ok {
+		return svc, nil
+	}
+
+	b, ok := f.backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown customers backend %s", backend)
+	}
+
+	svc := NewCustomerService(b.Repository, b.Cache, b.CachePopulationFailure)
+	if b.Transactor != nil {
+		svc = NewTransactionalCustomerService(svc, b.Transactor)
+	}
+	f.services[backend] = svc
+	return svc, nil
 }
 
 func (s *customerService) Create(ctx context.Context, c *model.Customer) (*model.Customer, error) {
 	c.ID = uuid.NewString()
+	sanitize(c)
+	c.TenantID = tenant.IDFromContext(ctx)
+
+	if err := s.verifyEmailUnique(ctx, c); err != nil {
+		return nil, err
+	}
+
 	if err := s.customerRps.Create(ctx, c); err != nil {
+		if repository.IsDuplicateCustomer(err) { // created concurrently after email check
+			return nil, s.emailTakenError(c)
+		}
 		return nil, err
 	}
 	return c, nil
 }
 
+// CreateIfNotExists creates customer only if there is no customer with the same email, otherwise existing customer is returned
+func (s *customerService) CreateIfNotExists(ctx context.Context, c *model.Customer) (*model.Customer, bool, error) {
+	c.ID = uuid.NewString()
+	sanitize(c)
+	c.TenantID = tenant.IDFromContext(ctx)
+
+	existing, err := s.customerRps.FindByEmail(ctx, c.TenantID, c.Email)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing != nil {
+		return s.existingOfTenant(c, existing)
+	}
+
+	err = s.customerRps.Create(ctx, c)
+	if err == nil {
+		return c, true, nil
+	}
+
+	if !repository.IsDuplicateCustomer(err) {
+		return nil, false, err
+	}
+
+	// unique email index is the guard against concurrent creation, so the winner is returned
+	existing, err = s.customerRps.FindByEmail(ctx, c.TenantID, c.Email)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing == nil { // created and deleted in between
+		return nil, false, appErrors.NewBusinessErr(appErrors.ErrConcurrentModification, fmt.Sprintf("customer with email %s was modified concurrently", c.Email))
+	}
+	return s.existingOfTenant(c, existing)
+}
+
+// existingOfTenant returns existing customer with the same email, customer of another tenant is never exposed,
+// it can be found only if email is unique globally, so email is considered taken
+func (s *customerService) existingOfTenant(c *model.Customer, existing *model.Customer) (*model.Customer, bool, error) {
+	if existing.TenantID != c.TenantID {
+		return nil, false, s.emailTakenError(c)
+	}
+	return existing, false, nil
+}
+
 func (s *customerService) DeleteByID(ctx context.Context, id string) error {
-	if err := s.cacheRps.DeleteByID(ctx, id); err != nil {
+	tenantID := tenant.IDFromContext(ctx)
+
+	if err := s.evictFromCache(ctx, tenantID, id); err != nil {
 		return err
 	}
 
-	if err := s.customerRps.DeleteByID(ctx, id); err != nil {
+	if err := s.customerRps.DeleteByID(ctx, tenantID, id); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (s *customerService) FindByID(ctx context.Context, id string) (*model.Customer, error) {
-	c, err := s.cacheRps.FindByID(ctx, id)
+	tenantID := tenant.IDFromContext(ctx)
+
+	c, err := s.cacheRps.FindByID(ctx, tenantID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +174,7 @@ func (s *customerService) FindByID(ctx context.Context, id string) (*model.Custo
 		return c, nil
 	}
 
-	c, err = s.customerRps.FindByID(ctx, id)
+	c, err = s.customerRps.FindByID(ctx, tenantID, id)
 	if err != nil {
 		return nil, err
 	}
@@ -71,41 +184,159 @@ func (s *customerService) FindByID(ctx context.Context, id string) (*model.Custo
 	}
 
 	if err := s.cacheRps.Create(ctx, c); err != nil {
-		return nil, err
+		if s.populationFailure != config.CachePopulationFailureIgnore {
+			return nil, err
+		}
+		logging.FromContext(ctx).Warnf("failed to cache customer %s, it is returned from datastore - %v", c.ID, err)
 	}
 
 	return c, nil
 }
 
 func (s *customerService) FindAll(ctx context.Context) ([]*model.Customer, error) {
-	customers, err := s.customerRps.FindAll(ctx)
+	customers, err := s.customerRps.FindAll(ctx, tenant.IDFromContext(ctx))
 	if err != nil {
-		logrus.Errorf("failed to read all customers - %v", err)
+		logging.FromContext(ctx).Errorf("failed to read all customers - %v", err)
 		return nil, err
 	}
 	return customers, nil
 }
 
-func (s *customerService) Upsert(ctx context.Context, c *model.Customer) (*model.Customer, error) {
-	existingCustomer, err := s.customerRps.FindByID(ctx, c.ID)
+func (s *customerService) FindAllActive(ctx context.Context) ([]*model.Customer, error) {
+	customers, err := s.customerRps.FindAllActive(ctx, tenant.IDFromContext(ctx))
 	if err != nil {
+		logging.FromContext(ctx).Errorf("failed to read active customers - %v", err)
 		return nil, err
 	}
+	return customers, nil
+}
+
+func (s *customerService) Upsert(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	sanitize(c)
+	c.TenantID = tenant.IDFromContext(ctx)
+
+	existingCustomer, err := s.customerRps.FindByID(ctx, c.TenantID, c.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.verifyEmailUnique(ctx, c); err != nil {
+		return nil, nil, err
+	}
 
 	if existingCustomer == nil {
 		if err := s.customerRps.Create(ctx, c); err != nil {
-			return nil, err
+			// customer with the same id or email was created concurrently, or id is taken by customer of another tenant
+			if repository.IsDuplicateCustomer(err) {
+				return nil, nil, appErrors.NewBusinessErr(appErrors.ErrConcurrentModification, fmt.Sprintf("customer %s can't be created, it conflicts with existing customer", c.ID))
+			}
+			return nil, nil, err
 		}
-		return c, nil
+		return c, nil, nil
 	}
 
-	if err := s.cacheRps.DeleteByID(ctx, c.ID); err != nil {
-		return nil, err
+	if err := s.evictFromCache(ctx, c.TenantID, c.ID); err != nil {
+		return nil, nil, err
 	}
 
 	if err := s.customerRps.Update(ctx, c); err != nil {
-		return nil, err
+		if repository.IsMissingCustomer(err) { // deleted concurrently after it was read
+			return nil, nil, appErrors.NewBusinessErr(appErrors.ErrConcurrentModification, fmt.Sprintf("customer %s was deleted concurrently", c.ID))
+		}
+		return nil, nil, err
 	}
 
-	return c, nil
+	return c, c.Diff(existingCustomer), nil
+}
+
+// Update changes existing customer only, unlike Upsert missing customer is never created, so customer deleted
+// concurrently is reported as not found instead of being brought back
+func (s *customerService) Update(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	sanitize(c)
+	c.TenantID = tenant.IDFromContext(ctx)
+
+	existingCustomer, err := s.customerRps.FindByID(ctx, c.TenantID, c.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if existingCustomer == nil {
+		return nil, nil, appErrors.NewEntryNotFoundErr("customer", c.ID)
+	}
+
+	if err := s.verifyEmailUnique(ctx, c); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.evictFromCache(ctx, c.TenantID, c.ID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.customerRps.Update(ctx, c); err != nil {
+		switch {
+		case repository.IsMissingCustomer(err):
+			return nil, nil, appErrors.NewEntryNotFoundErr("customer", c.ID)
+		case repository.IsDuplicateCustomer(err): // email taken concurrently after check
+			return nil, nil, s.emailTakenError(c)
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return c, c.Diff(existingCustomer), nil
+}
+
+func (s *customerService) BulkUpdate(ctx context.Context, filter *model.CustomerFilter, patch *model.CustomerPatch) (int, error) {
+	tenantID := tenant.IDFromContext(ctx)
+
+	ids, err := s.customerRps.BulkUpdate(ctx, tenantID, filter, patch)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.evictFromCache(ctx, tenantID, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// evictFromCache removes customer from cache, eviction is postponed until commit if called within transaction,
+// so rolled back change doesn't leave cache without customer which still exists. Failed postponed eviction is only logged
+func (s *customerService) evictFromCache(ctx context.Context, tenantID string, id string) error {
+	registered := transactor.RegisterAfterCommit(ctx, func(ctx context.Context) {
+		if err := s.cacheRps.DeleteByID(ctx, tenantID, id); err != nil {
+			logging.FromContext(ctx).Errorf("failed to evict customer %s from cache after commit - %v", id, err)
+		}
+	})
+	if registered {
+		return nil
+	}
+	return s.cacheRps.DeleteByID(ctx, tenantID, id)
+}
+
+func (s *customerService) verifyEmailUnique(ctx context.Context, c *model.Customer) error {
+	existingCustomer, err := s.customerRps.FindByEmail(ctx, c.TenantID, c.Email)
+	if err != nil {
+		return err
+	}
+
+	// customer of another tenant is found only if email is unique globally
+	if existingCustomer != nil && (existingCustomer.TenantID != c.TenantID || existingCustomer.ID != c.ID) {
+		return s.emailTakenError(c)
+	}
+	return nil
+}
+
+// sanitize stores blank middle name as null, so it isn't distinct from missing one
+func sanitize(c *model.Customer) {
+	if c.MiddleName != nil && strings.TrimSpace(*c.MiddleName) == "" {
+		c.MiddleName = nil
+	}
+}
+
+func (s *customerService) emailTakenError(c *model.Customer) error {
+	return appErrors.NewBusinessErr(appErrors.ErrDuplicateEmail, fmt.Sprintf("customer with email %s already exist", c.Email))
 }
@@ -3,17 +3,27 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	cacheMocks "github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/config"
+	appErrors "github.com/umalmyha/customers/internal/errors"
 	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
 	rpsMocks "github.com/umalmyha/customers/internal/repository/mocks"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/logging"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type customerTestData struct {
 	ctx      context.Context
+	tenantID string
 	customer *model.Customer
 }
 
@@ -26,10 +36,13 @@ type customerServiceTestSuite struct {
 }
 
 func (s *customerServiceTestSuite) SetupSuite() {
+	tenantID := "acme"
 	s.testData = &customerTestData{
-		ctx: context.Background(),
+		ctx:      tenant.ContextWithID(context.Background(), tenantID),
+		tenantID: tenantID,
 		customer: &model.Customer{
 			ID:         "ecc770d9-4576-4f72-affa-8b1454246692",
+			TenantID:   tenantID,
 			FirstName:  "John",
 			LastName:   "Walls",
 			MiddleName: nil,
@@ -44,20 +57,20 @@ func (s *customerServiceTestSuite) SetupTest() {
 	t := s.T()
 	s.customerRpsMock = rpsMocks.NewCustomerRepository(t)
 	s.customerCacheMock = cacheMocks.NewCustomerCacheRepository(t)
-	s.customerSvc = NewCustomerService(s.customerRpsMock, s.customerCacheMock)
+	s.customerSvc = NewCustomerService(s.customerRpsMock, s.customerCacheMock, config.CachePopulationFailureFail)
 }
 
 func (s *customerServiceTestSuite) TestFindByIDFromCache() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerCacheMock.On("FindByID", ctx, customer.ID).Return(customer, nil).Once()
+	s.customerCacheMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
 
 	s.T().Log("customer must be found in cache")
 	{
 		_, err := s.customerSvc.FindByID(ctx, customer.ID)
 		s.Assert().NoError(err, "no error must be raised")
-		s.customerRpsMock.AssertNotCalled(s.T(), "FindByID", ctx, customer.ID)
+		s.customerRpsMock.AssertNotCalled(s.T(), "FindByID", ctx, s.testData.tenantID, customer.ID)
 	}
 }
 
@@ -65,8 +78,8 @@ func (s *customerServiceTestSuite) TestFindByIDNotFound() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerCacheMock.On("FindByID", ctx, customer.ID).Return(nil, nil).Once()
-	s.customerRpsMock.On("FindByID", ctx, customer.ID).Return(nil, nil).Once()
+	s.customerCacheMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
 
 	s.T().Log("customer is missing in cache and in primary datasource")
 	{
@@ -81,8 +94,8 @@ func (s *customerServiceTestSuite) TestFindByIDCached() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerCacheMock.On("FindByID", ctx, customer.ID).Return(nil, nil).Once()
-	s.customerRpsMock.On("FindByID", ctx, customer.ID).Return(customer, nil).Once()
+	s.customerCacheMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
 	s.customerCacheMock.On("Create", ctx, customer).Return(nil).Once()
 
 	s.T().Log("customer is not in cache, found in primary datasource and cached")
@@ -94,11 +107,44 @@ func (s *customerServiceTestSuite) TestFindByIDCached() {
 	}
 }
 
+func (s *customerServiceTestSuite) TestFindByIDCacheFailed() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	s.customerCacheMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerCacheMock.On("Create", ctx, customer).Return(errors.New("cache err")).Once()
+
+	s.T().Log("customer is found in primary datasource, but caching failed - error must be raised up")
+	{
+		c, err := s.customerSvc.FindByID(ctx, customer.ID)
+		s.Assert().Error(err, "cache raised error - error must be raised up")
+		s.Assert().Nil(c, "no customer must be returned")
+	}
+}
+
+func (s *customerServiceTestSuite) TestFindByIDCacheFailedIgnored() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+	s.customerSvc = NewCustomerService(s.customerRpsMock, s.customerCacheMock, config.CachePopulationFailureIgnore)
+
+	s.customerCacheMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerCacheMock.On("Create", ctx, customer).Return(errors.New("cache err")).Once()
+
+	s.T().Log("customer is found in primary datasource, caching failure is ignored")
+	{
+		c, err := s.customerSvc.FindByID(ctx, customer.ID)
+		s.Assert().NoError(err, "cache failure must be ignored")
+		s.Assert().Equal(customer, c, "customer from primary datasource must be returned")
+	}
+}
+
 func (s *customerServiceTestSuite) TestDeleteByIDCacheFailed() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerCacheMock.On("DeleteByID", ctx, customer.ID).Return(errors.New("cache err")).Once()
+	s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(errors.New("cache err")).Once()
 
 	s.T().Log("delete customer from cache failed")
 	{
@@ -111,14 +157,14 @@ func (s *customerServiceTestSuite) TestDeleteByIDSuccessfully() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerCacheMock.On("DeleteByID", ctx, customer.ID).Return(nil).Once()
-	s.customerRpsMock.On("DeleteByID", ctx, customer.ID).Return(nil).Once()
+	s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(nil).Once()
+	s.customerRpsMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(nil).Once()
 
 	s.T().Log("deleted successfully")
 	{
 		err := s.customerSvc.DeleteByID(ctx, customer.ID)
 		s.Assert().NoError(err, "no error must be raised")
-		s.customerRpsMock.AssertCalled(s.T(), "DeleteByID", ctx, customer.ID)
+		s.customerRpsMock.AssertCalled(s.T(), "DeleteByID", ctx, s.testData.tenantID, customer.ID)
 	}
 }
 
@@ -126,13 +172,35 @@ func (s *customerServiceTestSuite) TestUpsertNewCustomer() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerRpsMock.On("FindByID", ctx, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
 	s.customerRpsMock.On("Create", ctx, mock.AnythingOfType("*model.Customer")).Return(nil).Once()
 
 	s.T().Log("user is not present, so must be created")
 	{
-		_, err := s.customerSvc.Upsert(ctx, customer)
+		_, diff, err := s.customerSvc.Upsert(ctx, customer)
 		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Nil(diff, "created customer must have no diff")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Update", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestUpsertNewCustomerConflict() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	duplicateErr := fmt.Errorf("mongo: failed to insert customer - %w", mongo.WriteException{
+		WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}},
+	})
+
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
+	s.customerRpsMock.On("Create", ctx, mock.AnythingOfType("*model.Customer")).Return(duplicateErr).Once()
+
+	s.T().Log("customer is created concurrently or id is taken by another tenant - conflict must be raised")
+	{
+		_, _, err := s.customerSvc.Upsert(ctx, customer)
+		s.Assert().ErrorIs(err, appErrors.ErrConcurrentModification, "concurrent modification error must be raised")
 		s.customerRpsMock.AssertNotCalled(s.T(), "Update", ctx, mock.AnythingOfType("*model.Customer"))
 	}
 }
@@ -141,15 +209,81 @@ func (s *customerServiceTestSuite) TestUpsertUpdateCustomer() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
-	s.customerRpsMock.On("FindByID", ctx, customer.ID).Return(customer, nil).Once()
-	s.customerCacheMock.On("DeleteByID", ctx, customer.ID).Return(nil).Once()
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(customer, nil).Once()
+	s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(nil).Once()
 	s.customerRpsMock.On("Update", ctx, mock.AnythingOfType("*model.Customer")).Return(nil).Once()
 
 	s.T().Log("user is present, so must be updated")
 	{
-		_, err := s.customerSvc.Upsert(ctx, customer)
+		updated := *customer
+		updated.Inactive = !customer.Inactive
+
+		_, diff, err := s.customerSvc.Upsert(ctx, &updated)
 		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal([]model.CustomerChange{{Field: "inactive", Old: customer.Inactive, New: updated.Inactive}}, diff, "diff must contain changed field")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestUpdateNotFound() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(nil, nil).Once()
+
+	s.T().Log("customer is not present, so it must not be created")
+	{
+		_, _, err := s.customerSvc.Update(ctx, customer)
+		var notFoundErr *appErrors.EntryNotFoundErr
+		s.Assert().ErrorAs(err, &notFoundErr, "not found error must be raised")
 		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+		s.customerRpsMock.AssertNotCalled(s.T(), "Update", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestUpdateDeletedConcurrently() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	// update of customer absent in empty repository fails the same way as update of deleted one
+	missingErr := repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant).Update(ctx, customer)
+	s.Require().True(repository.IsMissingCustomer(missingErr), "missing customer error must be raised by repository")
+
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(customer, nil).Once()
+	s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(nil).Once()
+	s.customerRpsMock.On("Update", ctx, mock.AnythingOfType("*model.Customer")).Return(missingErr).Once()
+
+	s.T().Log("customer is deleted after it was read, so not found error must be raised")
+	{
+		updated := *customer
+		updated.Inactive = !customer.Inactive
+
+		_, _, err := s.customerSvc.Update(ctx, &updated)
+		var notFoundErr *appErrors.EntryNotFoundErr
+		s.Assert().ErrorAs(err, &notFoundErr, "not found error must be raised")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestUpdateCustomer() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(customer, nil).Once()
+	s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, customer.ID).Return(nil).Once()
+	s.customerRpsMock.On("Update", ctx, mock.AnythingOfType("*model.Customer")).Return(nil).Once()
+
+	s.T().Log("customer is present, so must be updated")
+	{
+		updated := *customer
+		updated.Inactive = !customer.Inactive
+
+		_, diff, err := s.customerSvc.Update(ctx, &updated)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal([]model.CustomerChange{{Field: "inactive", Old: customer.Inactive, New: updated.Inactive}}, diff, "diff must contain changed field")
 	}
 }
 
@@ -157,12 +291,197 @@ func (s *customerServiceTestSuite) TestCreateSuccessfully() {
 	ctx := s.testData.ctx
 	customer := s.testData.customer
 
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
 	s.customerRpsMock.On("Create", ctx, customer).Return(nil).Once()
 
 	s.T().Log("user must be created successfully")
+	{
+		c, err := s.customerSvc.Create(ctx, customer)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal(s.testData.tenantID, c.TenantID, "customer must be assigned to caller tenant")
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateEmailTakenWithinTenant() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	existing := *customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("customer with the same email exists within tenant")
+	{
+		_, err := s.customerSvc.Create(ctx, customer)
+		s.Assert().ErrorIs(err, appErrors.ErrDuplicateEmail, "email is already taken within tenant - duplicate email error must be raised")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateEmailTakenInAnotherTenant() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	existing := *customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+	existing.TenantID = "globex"
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("customer with the same email exists in another tenant and email is unique globally")
 	{
 		_, err := s.customerSvc.Create(ctx, customer)
+		s.Assert().ErrorIs(err, appErrors.ErrDuplicateEmail, "email is already taken globally - duplicate email error must be raised")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateBlankMiddleNameStoredAsNull() {
+	ctx := s.testData.ctx
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, s.testData.customer.Email).Return(nil, nil).Twice()
+	s.customerRpsMock.On("Create", ctx, mock.AnythingOfType("*model.Customer")).Return(nil).Twice()
+
+	for _, middleName := range []string{"", "   "} {
+		s.T().Logf("middle name %q must be stored as null", middleName)
+		{
+			customer := *s.testData.customer
+			customer.MiddleName = &middleName
+
+			c, err := s.customerSvc.Create(ctx, &customer)
+			s.Assert().NoError(err, "no error must be raised")
+			s.Assert().Nil(c.MiddleName, "blank middle name must be normalized to null")
+		}
+	}
+
+	s.T().Log("non-blank middle name must be kept as is")
+	{
+		middleName := "Jr"
+		customer := *s.testData.customer
+		customer.MiddleName = &middleName
+
+		s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
+		s.customerRpsMock.On("Create", ctx, &customer).Return(nil).Once()
+
+		c, err := s.customerSvc.Create(ctx, &customer)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal(&middleName, c.MiddleName, "middle name must be kept")
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateIfNotExistsCreated() {
+	ctx := s.testData.ctx
+	customer := *s.testData.customer
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
+	s.customerRpsMock.On("Create", ctx, &customer).Return(nil).Once()
+
+	s.T().Log("customer with the email doesn't exist, so it is created")
+	{
+		c, created, err := s.customerSvc.CreateIfNotExists(ctx, &customer)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().True(created, "customer must be created")
+		s.Assert().Equal(&customer, c, "created customer must be returned")
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateIfNotExistsExisting() {
+	ctx := s.testData.ctx
+	customer := *s.testData.customer
+
+	existing := customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("customer with the email exists, so it is returned")
+	{
+		c, created, err := s.customerSvc.CreateIfNotExists(ctx, &customer)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().False(created, "customer must not be created")
+		s.Assert().Equal(existing.ID, c.ID, "existing customer must be returned")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateIfNotExistsExistingInAnotherTenant() {
+	ctx := s.testData.ctx
+	customer := *s.testData.customer
+
+	existing := customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+	existing.TenantID = "globex"
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("customer with the email exists in another tenant, so it isn't exposed")
+	{
+		c, created, err := s.customerSvc.CreateIfNotExists(ctx, &customer)
+		s.Assert().ErrorIs(err, appErrors.ErrDuplicateEmail, "email is already taken globally - duplicate email error must be raised")
+		s.Assert().False(created, "customer must not be created")
+		s.Assert().Nil(c, "customer of another tenant must not be returned")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Create", ctx, mock.AnythingOfType("*model.Customer"))
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateIfNotExistsConcurrentlyCreated() {
+	ctx := s.testData.ctx
+	customer := *s.testData.customer
+
+	existing := customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+
+	duplicateErr := fmt.Errorf("mongo: failed to insert customer - %w", mongo.WriteException{
+		WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}},
+	})
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Once()
+	s.customerRpsMock.On("Create", ctx, &customer).Return(duplicateErr).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("customer with the email is created concurrently, so winner is returned")
+	{
+		c, created, err := s.customerSvc.CreateIfNotExists(ctx, &customer)
 		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().False(created, "customer must not be created")
+		s.Assert().Equal(existing.ID, c.ID, "concurrently created customer must be returned")
+	}
+}
+
+func (s *customerServiceTestSuite) TestCreateIfNotExistsConcurrentlyDeleted() {
+	ctx := s.testData.ctx
+	customer := *s.testData.customer
+
+	duplicateErr := fmt.Errorf("mongo: failed to insert customer - %w", mongo.WriteException{
+		WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}},
+	})
+
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(nil, nil).Twice()
+	s.customerRpsMock.On("Create", ctx, &customer).Return(duplicateErr).Once()
+
+	s.T().Log("customer with the email is created and deleted concurrently")
+	{
+		_, _, err := s.customerSvc.CreateIfNotExists(ctx, &customer)
+		s.Assert().ErrorIs(err, appErrors.ErrConcurrentModification, "concurrent modification error must be raised")
+	}
+}
+
+func (s *customerServiceTestSuite) TestUpsertEmailTakenWithinTenant() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	existing := *customer
+	existing.ID = "2f1e8a3c-5b7d-4e9f-a1c3-d5e7f9b1c3e5"
+
+	s.customerRpsMock.On("FindByID", ctx, s.testData.tenantID, customer.ID).Return(customer, nil).Once()
+	s.customerRpsMock.On("FindByEmail", ctx, s.testData.tenantID, customer.Email).Return(&existing, nil).Once()
+
+	s.T().Log("another customer with the same email exists within tenant")
+	{
+		_, _, err := s.customerSvc.Upsert(ctx, customer)
+		s.Assert().ErrorIs(err, appErrors.ErrDuplicateEmail, "email is already taken within tenant - duplicate email error must be raised")
+		s.customerRpsMock.AssertNotCalled(s.T(), "Update", ctx, mock.AnythingOfType("*model.Customer"))
 	}
 }
 
@@ -174,7 +493,7 @@ func (s *customerServiceTestSuite) TestFindAllSuccessfully() {
 		customer,
 	}
 
-	s.customerRpsMock.On("FindAll", ctx).Return(customers, nil).Once()
+	s.customerRpsMock.On("FindAll", ctx, s.testData.tenantID).Return(customers, nil).Once()
 
 	s.T().Log("users must be found from data source")
 	{
@@ -183,6 +502,185 @@ func (s *customerServiceTestSuite) TestFindAllSuccessfully() {
 	}
 }
 
+func (s *customerServiceTestSuite) TestFindAllActiveSuccessfully() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	s.customerRpsMock.On("FindAllActive", ctx, s.testData.tenantID).Return([]*model.Customer{customer}, nil).Once()
+
+	s.T().Log("active customers must be read with dedicated query")
+	{
+		customers, err := s.customerSvc.FindAllActive(ctx)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal([]*model.Customer{customer}, customers, "active customers must be returned")
+		s.customerRpsMock.AssertNotCalled(s.T(), "FindAll", ctx, s.testData.tenantID)
+	}
+}
+
+func (s *customerServiceTestSuite) TestFindAllFailedLogsRequestFields() {
+	ctx := logging.ContextWithFields(s.testData.ctx, logrus.Fields{
+		logging.FieldRequestID: "3a9e5c1b-quoted-by-user",
+		logging.FieldTransport: logging.TransportHTTP,
+		logging.FieldRoute:     "/api/v1/customers",
+		logging.FieldPrincipal: "b1f3c4d2-8e6a-4f0c-9d1e-2a7b5c3e9f10",
+	})
+
+	s.customerRpsMock.On("FindAll", ctx, s.testData.tenantID).Return(nil, errors.New("db err")).Once()
+
+	s.T().Log("failure is logged with request scoped fields")
+	{
+		logHook := logrusTest.NewGlobal()
+		defer logHook.Reset()
+
+		_, err := s.customerSvc.FindAll(ctx)
+		s.Require().Error(err, "repository raised error - error must be raised up")
+
+		entry := logHook.LastEntry()
+		s.Require().NotNil(entry, "failure must be logged")
+		s.Require().Equal("3a9e5c1b-quoted-by-user", entry.Data[logging.FieldRequestID], "request id must be logged")
+		s.Require().Equal(logging.TransportHTTP, entry.Data[logging.FieldTransport], "transport must be logged")
+		s.Require().Equal("/api/v1/customers", entry.Data[logging.FieldRoute], "route must be logged")
+		s.Require().Equal("b1f3c4d2-8e6a-4f0c-9d1e-2a7b5c3e9f10", entry.Data[logging.FieldPrincipal], "principal must be logged")
+	}
+}
+
+func (s *customerServiceTestSuite) TestTenantsAreIsolated() {
+	customer := s.testData.customer
+
+	acmeCtx := s.testData.ctx
+	globexCtx := tenant.ContextWithID(context.Background(), "globex")
+
+	s.customerRpsMock.On("FindAll", acmeCtx, s.testData.tenantID).Return([]*model.Customer{customer}, nil).Once()
+	s.customerRpsMock.On("FindAll", globexCtx, "globex").Return([]*model.Customer{}, nil).Once()
+	s.customerCacheMock.On("FindByID", globexCtx, "globex", customer.ID).Return(nil, nil).Once()
+	s.customerRpsMock.On("FindByID", globexCtx, "globex", customer.ID).Return(nil, nil).Once()
+
+	s.T().Log("each tenant must see only own customers")
+	{
+		acmeCustomers, err := s.customerSvc.FindAll(acmeCtx)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Len(acmeCustomers, 1, "acme customers must be returned")
+
+		globexCustomers, err := s.customerSvc.FindAll(globexCtx)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Empty(globexCustomers, "acme customers must not be visible for globex")
+	}
+
+	s.T().Log("customer of another tenant must not be found by id")
+	{
+		c, err := s.customerSvc.FindByID(globexCtx, customer.ID)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Nil(c, "customer of another tenant must not be found")
+	}
+}
+
+func (s *customerServiceTestSuite) TestBulkUpdateInvalidatesCache() {
+	ctx := s.testData.ctx
+	customer := s.testData.customer
+
+	importance := model.ImportanceCritical
+	inactive := true
+	filter := &model.CustomerFilter{Importance: &importance}
+	patch := &model.CustomerPatch{Inactive: &inactive}
+	ids := []string{customer.ID, "0d6f9c71-2b3a-4f5e-8c7d-9e0f1a2b3c4d"}
+
+	s.customerRpsMock.On("BulkUpdate", ctx, s.testData.tenantID, filter, patch).Return(ids, nil).Once()
+	for _, id := range ids {
+		s.customerCacheMock.On("DeleteByID", ctx, s.testData.tenantID, id).Return(nil).Once()
+	}
+
+	s.T().Log("updated customers are counted and evicted from cache")
+	{
+		count, err := s.customerSvc.BulkUpdate(ctx, filter, patch)
+		s.Assert().NoError(err, "no error must be raised")
+		s.Assert().Equal(len(ids), count, "count of updated customers must be returned")
+		s.customerCacheMock.AssertNumberOfCalls(s.T(), "DeleteByID", len(ids))
+	}
+}
+
+func (s *customerServiceTestSuite) TestBulkUpdateFailed() {
+	ctx := s.testData.ctx
+
+	inactive := true
+	filter := &model.CustomerFilter{}
+	patch := &model.CustomerPatch{Inactive: &inactive}
+
+	s.customerRpsMock.On("BulkUpdate", ctx, s.testData.tenantID, filter, patch).Return(nil, errors.New("db err")).Once()
+
+	s.T().Log("repository failure is raised up and cache is untouched")
+	{
+		_, err := s.customerSvc.BulkUpdate(ctx, filter, patch)
+		s.Assert().Error(err, "repository raised error - error must be raised up")
+		s.customerCacheMock.AssertNotCalled(s.T(), "DeleteByID", mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func (s *customerServiceTestSuite) TestCustomerServiceFactory() {
+	t := s.T()
+	require := s.Require()
+
+	pgRps, pgCache := rpsMocks.NewCustomerRepository(t), cacheMocks.NewCustomerCacheRepository(t)
+	mongoRps, mongoCache := rpsMocks.NewCustomerRepository(t), cacheMocks.NewCustomerCacheRepository(t)
+	factory := NewCustomerServiceFactory(map[config.CustomersBackend]CustomerBackend{
+		config.CustomersBackendPostgres: {Repository: pgRps, Cache: pgCache},
+		config.CustomersBackendMongo:    {Repository: mongoRps, Cache: mongoCache},
+	})
+
+	t.Log("service is built on top of configured backend")
+	{
+		for backend, expected := range map[config.CustomersBackend]CustomerBackend{
+			config.CustomersBackendPostgres: {Repository: pgRps, Cache: pgCache},
+			config.CustomersBackendMongo:    {Repository: mongoRps, Cache: mongoCache},
+		} {
+			svc, err := factory.Build(backend)
+			require.NoError(err, "service for %s backend must be built", backend)
+			require.Same(expected.Repository, svc.(*customerService).customerRps, "service must use %s repository", backend)
+			require.Same(expected.Cache, svc.(*customerService).cacheRps, "service must use %s cache", backend)
+		}
+	}
+
+	t.Log("versions with the same backend share service")
+	{
+		v1Svc, err := factory.Build(config.CustomersBackendMongo)
+		require.NoError(err, "service for mongo backend must be built")
+		v2Svc, err := factory.Build(config.CustomersBackendMongo)
+		require.NoError(err, "service for mongo backend must be built")
+		require.Same(v1Svc, v2Svc, "the same service must be returned for the same backend")
+	}
+
+	t.Log("unknown backend is rejected")
+	{
+		_, err := factory.Build("cassandra")
+		require.Error(err, "unknown backend must be rejected")
+	}
+
+	t.Log("service of backend with transactor changes customers within transaction")
+	{
+		ctx := s.testData.ctx
+		txtor := rpsMocks.NewTransactor(t)
+		txFactory := NewCustomerServiceFactory(map[config.CustomersBackend]CustomerBackend{
+			config.CustomersBackendMongo: {Repository: mongoRps, Cache: mongoCache, Transactor: txtor},
+		})
+
+		svc, err := txFactory.Build(config.CustomersBackendMongo)
+		require.NoError(err, "service for mongo backend must be built")
+
+		inactive := true
+		txtor.On("WithinTransaction", ctx, mock.AnythingOfType("func(context.Context) error")).
+			Return(func(ctx context.Context, txFunc func(ctx context.Context) error) error {
+				return txFunc(ctx)
+			}).Once()
+		mongoRps.On("BulkUpdate", ctx, s.testData.tenantID, &model.CustomerFilter{}, &model.CustomerPatch{Inactive: &inactive}).
+			Return([]string{s.testData.customer.ID}, nil).Once()
+		mongoCache.On("DeleteByID", ctx, s.testData.tenantID, s.testData.customer.ID).Return(nil).Once()
+
+		updated, err := svc.BulkUpdate(ctx, &model.CustomerFilter{}, &model.CustomerPatch{Inactive: &inactive})
+		require.NoError(err, "no error must be raised")
+		require.Equal(1, updated, "customer must be updated")
+		txtor.AssertExpectations(t)
+	}
+}
+
 // start customer service test suite
 func TestCustomerServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(customerServiceTestSuite))
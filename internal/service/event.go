@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+type eventPublishingCustomerService struct {
+	CustomerService
+	publisher event.Publisher
+}
+
+// NewEventPublishingCustomerService wraps CustomerService, so change event is published once customer is created,
+// updated or deleted. Change is already stored when event is published, so publishing failure is only logged.
+// Event of change made within transaction is published once it is committed and dropped on rollback.
+// Customers changed by BulkUpdate are not published, since their state after update is not read back
+func NewEventPublishingCustomerService(next CustomerService, publisher event.Publisher) CustomerService {
+	return &eventPublishingCustomerService{CustomerService: next, publisher: publisher}
+}
+
+func (s *eventPublishingCustomerService) Create(ctx context.Context, c *model.Customer) (*model.Customer, error) {
+	created, err := s.CustomerService.Create(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, model.CustomerCreated, created.ID, created, nil)
+	return created, nil
+}
+
+func (s *eventPublishingCustomerService) CreateIfNotExists(ctx context.Context, c *model.Customer) (*model.Customer, bool, error) {
+	customer, created, err := s.CustomerService.CreateIfNotExists(ctx, c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		s.publish(ctx, model.CustomerCreated, customer.ID, customer, nil)
+	}
+	return customer, created, nil
+}
+
+func (s *eventPublishingCustomerService) Upsert(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	customer, changes, err := s.CustomerService.Upsert(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case changes == nil: // customer didn't exist before
+		s.publish(ctx, model.CustomerCreated, customer.ID, customer, nil)
+	case len(changes) > 0:
+		s.publish(ctx, model.CustomerUpdated, customer.ID, customer, changes)
+	}
+	return customer, changes, nil
+}
+
+func (s *eventPublishingCustomerService) Update(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	customer, changes, err := s.CustomerService.Update(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(changes) > 0 {
+		s.publish(ctx, model.CustomerUpdated, customer.ID, customer, changes)
+	}
+	return customer, changes, nil
+}
+
+func (s *eventPublishingCustomerService) DeleteByID(ctx context.Context, id string) error {
+	if err := s.CustomerService.DeleteByID(ctx, id); err != nil {
+		return err
+	}
+
+	s.publish(ctx, model.CustomerDeleted, id, nil, nil)
+	return nil
+}
+
+func (s *eventPublishingCustomerService) publish(
+	ctx context.Context,
+	t model.CustomerEventType,
+	customerID string,
+	c *model.Customer,
+	changes []model.CustomerChange,
+) {
+	e := &model.CustomerEvent{
+		ID:         uuid.NewString(),
+		Type:       t,
+		TenantID:   tenant.IDFromContext(ctx),
+		CustomerID: customerID,
+		Customer:   c,
+		Changes:    changes,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	publish := func(ctx context.Context) {
+		if err := s.publisher.Publish(ctx, e); err != nil {
+			logging.FromContext(ctx).Errorf("failed to publish %s event of customer %s - %v", t, customerID, err)
+		}
+	}
+
+	if !transactor.RegisterAfterCommit(ctx, publish) {
+		publish(ctx)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	cacheMocks "github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	rpsMocks "github.com/umalmyha/customers/internal/repository/mocks"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+type recordingPublisher struct {
+	events []*model.CustomerEvent
+	err    error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e *model.CustomerEvent) error {
+	p.events = append(p.events, e)
+	return p.err
+}
+
+type eventPublishingServiceTestSuite struct {
+	suite.Suite
+	customerSvc       CustomerService
+	customerRpsMock   *rpsMocks.CustomerRepository
+	customerCacheMock *cacheMocks.CustomerCacheRepository
+	publisher         *recordingPublisher
+	ctx               context.Context
+	customer          *model.Customer
+}
+
+func (s *eventPublishingServiceTestSuite) SetupTest() {
+	t := s.T()
+	s.ctx = tenant.ContextWithID(context.Background(), "acme")
+	s.customer = &model.Customer{
+		ID:         "ecc770d9-4576-4f72-affa-8b1454246692",
+		TenantID:   "acme",
+		FirstName:  "John",
+		LastName:   "Walls",
+		Email:      "john.walls@somemal.com",
+		Importance: model.ImportanceCritical,
+	}
+	s.customerRpsMock = rpsMocks.NewCustomerRepository(t)
+	s.customerCacheMock = cacheMocks.NewCustomerCacheRepository(t)
+	s.publisher = &recordingPublisher{}
+	s.customerSvc = NewEventPublishingCustomerService(NewCustomerService(s.customerRpsMock, s.customerCacheMock, config.CachePopulationFailureFail), s.publisher)
+}
+
+func (s *eventPublishingServiceTestSuite) TestCreatePublished() {
+	t := s.T()
+	require := s.Require()
+
+	s.customerRpsMock.On("FindByEmail", s.ctx, "acme", s.customer.Email).Return(nil, nil).Once()
+	s.customerRpsMock.On("Create", s.ctx, s.customer).Return(nil).Once()
+
+	t.Log("created event is published with customer")
+	{
+		_, err := s.customerSvc.Create(s.ctx, s.customer)
+		require.NoError(err, "no error must be raised")
+		require.Len(s.publisher.events, 1, "single event must be published")
+
+		e := s.publisher.events[0]
+		require.Equal(model.CustomerCreated, e.Type, "incorrect event type")
+		require.Equal("acme", e.TenantID, "event must be published for caller tenant")
+		require.Equal(s.customer.ID, e.CustomerID, "incorrect customer id")
+		require.Equal(s.customer, e.Customer, "customer must be part of event")
+		require.NotEmpty(e.ID, "event must have id")
+	}
+}
+
+func (s *eventPublishingServiceTestSuite) TestUpsertPublished() {
+	t := s.T()
+	require := s.Require()
+
+	s.customerRpsMock.On("FindByID", s.ctx, "acme", s.customer.ID).Return(s.customer, nil).Twice()
+	s.customerRpsMock.On("FindByEmail", s.ctx, "acme", s.customer.Email).Return(s.customer, nil).Twice()
+	s.customerCacheMock.On("DeleteByID", s.ctx, "acme", s.customer.ID).Return(nil).Twice()
+	s.customerRpsMock.On("Update", s.ctx, mock.AnythingOfType("*model.Customer")).Return(nil).Twice()
+
+	t.Log("updated event is published with changes")
+	{
+		updated := *s.customer
+		updated.Inactive = true
+
+		_, _, err := s.customerSvc.Upsert(s.ctx, &updated)
+		require.NoError(err, "no error must be raised")
+		require.Len(s.publisher.events, 1, "single event must be published")
+		require.Equal(model.CustomerUpdated, s.publisher.events[0].Type, "incorrect event type")
+		require.Equal([]model.CustomerChange{{Field: "inactive", Old: false, New: true}}, s.publisher.events[0].Changes, "changes must be part of event")
+	}
+
+	t.Log("no event is published if nothing changed")
+	{
+		unchanged := *s.customer
+		_, _, err := s.customerSvc.Upsert(s.ctx, &unchanged)
+		require.NoError(err, "no error must be raised")
+		require.Len(s.publisher.events, 1, "no event must be published")
+	}
+}
+
+func (s *eventPublishingServiceTestSuite) TestDeleteByIDPublishFailed() {
+	t := s.T()
+	require := s.Require()
+
+	logHook := logrusTest.NewGlobal()
+	defer logHook.Reset()
+
+	s.publisher.err = errors.New("redis: connection refused")
+	s.customerCacheMock.On("DeleteByID", s.ctx, "acme", s.customer.ID).Return(nil).Once()
+	s.customerRpsMock.On("DeleteByID", s.ctx, "acme", s.customer.ID).Return(nil).Once()
+
+	t.Log("publishing failure is logged, but not returned")
+	{
+		require.NoError(s.customerSvc.DeleteByID(s.ctx, s.customer.ID), "no error must be raised")
+		require.Len(s.publisher.events, 1, "event must be published")
+		require.Equal(model.CustomerDeleted, s.publisher.events[0].Type, "incorrect event type")
+		require.Nil(s.publisher.events[0].Customer, "deleted customer must not be part of event")
+		require.NotNil(logHook.LastEntry(), "publishing failure must be logged")
+	}
+}
+
+func (s *eventPublishingServiceTestSuite) TestFailedChangeNotPublished() {
+	t := s.T()
+	require := s.Require()
+
+	s.customerCacheMock.On("DeleteByID", s.ctx, "acme", s.customer.ID).Return(errors.New("redis: connection refused")).Once()
+
+	t.Log("no event is published if change failed")
+	{
+		require.Error(s.customerSvc.DeleteByID(s.ctx, s.customer.ID), "error must be raised")
+		require.Empty(s.publisher.events, "no event must be published")
+	}
+}
+
+// start event publishing customer service test suite
+func TestEventPublishingServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(eventPublishingServiceTestSuite))
+}
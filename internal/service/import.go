@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// errStrictImportRejected rolls back transaction of strict import once row is rejected
+var errStrictImportRejected = errors.New("strict customers import is rejected")
+
+// CustomerImportRow is customer read from import file, row which can't be imported carries error instead of customer
+type CustomerImportRow struct {
+	Line     int // line of import file row starts at
+	Customer *model.Customer
+	Err      error
+}
+
+// CustomerImportReport summarizes import, rejected rows are listed in the order they were rejected
+type CustomerImportReport struct {
+	Imported int
+	Rejected []*CustomerImportRow
+}
+
+// CustomerImportService represents behavior of customers import, report of rows read so far is returned along with error
+type CustomerImportService interface {
+	Import(context.Context, func() (*CustomerImportRow, error), bool) (*CustomerImportReport, error)
+}
+
+type customerImportService struct {
+	customerSvc CustomerService
+	txtor       transactor.Transactor
+}
+
+// NewCustomerImportService builds new customerImportService, customers are upserted via customerSvc,
+// so imported customers are cached and published the same way as customers changed via api.
+// Transactor is optional and must be the one customer repository operations join transactions of
+func NewCustomerImportService(customerSvc CustomerService, txtor transactor.Transactor) CustomerImportService {
+	return &customerImportService{customerSvc: customerSvc, txtor: txtor}
+}
+
+// Import upserts customers of rows returned by next until it returns io.EOF. Customer is matched by id if it is set,
+// otherwise by email. Invalid rows and rows violating business rules are rejected and don't stop the import.
+// In strict mode nothing is imported if any row is invalid, so valid rows are kept in memory until all rows are read.
+// Then they are imported within single transaction, which is rolled back once any row violates business rules
+func (s *customerImportService) Import(ctx context.Context, next func() (*CustomerImportRow, error), strict bool) (*CustomerImportReport, error) {
+	report := &CustomerImportReport{Rejected: make([]*CustomerImportRow, 0)}
+	valid := make([]*CustomerImportRow, 0)
+
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		switch {
+		case row.Err != nil:
+			report.Rejected = append(report.Rejected, row)
+		case strict:
+			valid = append(valid, row)
+		default:
+			if err := s.importRow(ctx, row, report); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if strict {
+		return report, s.importStrict(ctx, valid, report)
+	}
+
+	logging.FromContext(ctx).Infof("%d customers are imported, %d rows are rejected", report.Imported, len(report.Rejected))
+	return report, nil
+}
+
+// importStrict imports valid rows unless any row is rejected, rows are imported within transaction if transactor is set,
+// so they are all rolled back once any of them is rejected or fails. Import is stopped on the first rejected row
+func (s *customerImportService) importStrict(ctx context.Context, valid []*CustomerImportRow, report *CustomerImportReport) error {
+	if len(report.Rejected) > 0 {
+		logging.FromContext(ctx).Infof("strict customers import is rejected, %d rows are invalid", len(report.Rejected))
+		return nil
+	}
+
+	importAll := func(ctx context.Context) error {
+		for _, row := range valid {
+			if err := s.importRow(ctx, row, report); err != nil {
+				return err
+			}
+
+			if len(report.Rejected) > 0 {
+				return errStrictImportRejected
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if s.txtor != nil {
+		if err = s.txtor.WithinTransaction(ctx, importAll); err != nil {
+			report.Imported = 0 // rolled back
+		}
+	} else {
+		err = importAll(ctx)
+	}
+
+	if errors.Is(err, errStrictImportRejected) {
+		logging.FromContext(ctx).Infof("strict customers import is rejected, line %d violates business rules", report.Rejected[0].Line)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Infof("%d customers are imported in strict mode", report.Imported)
+	return nil
+}
+
+func (s *customerImportService) importRow(ctx context.Context, row *CustomerImportRow, report *CustomerImportReport) error {
+	err := s.upsert(ctx, row.Customer)
+
+	var businessErr *appErrors.BusinessErr
+	switch {
+	case err == nil:
+		report.Imported++
+	case errors.As(err, &businessErr):
+		row.Err = err
+		report.Rejected = append(report.Rejected, row)
+	default:
+		return fmt.Errorf("failed to import customer of line %d - %w", row.Line, err)
+	}
+	return nil
+}
+
+func (s *customerImportService) upsert(ctx context.Context, c *model.Customer) error {
+	if c.ID != "" {
+		_, _, err := s.customerSvc.Upsert(ctx, c)
+		return err
+	}
+
+	candidate := *c
+	existing, created, err := s.customerSvc.CreateIfNotExists(ctx, &candidate)
+	if err != nil || created {
+		return err
+	}
+
+	c.ID = existing.ID
+	_, _, err = s.customerSvc.Upsert(ctx, c)
+	return err
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/search"
+	"github.com/umalmyha/customers/internal/tenant"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// CustomerSearchService represents behavior of customers full-text search
+type CustomerSearchService interface {
+	Search(context.Context, *model.CustomerSearch) ([]*model.Customer, int, error)
+}
+
+type customerSearchService struct {
+	searchRps   repository.CustomerSearchRepository
+	fallbackRps repository.CustomerSearchRepository
+	customerRps repository.CustomerRepository
+}
+
+// NewCustomerSearchService builds new customerSearchService, customers are searched with fallbackRps
+// if searchRps fails, fallbackRps is optional
+func NewCustomerSearchService(searchRps repository.CustomerSearchRepository, fallbackRps repository.CustomerSearchRepository) CustomerSearchService {
+	return &customerSearchService{searchRps: searchRps, fallbackRps: fallbackRps}
+}
+
+// NewIndexedCustomerSearchService builds customerSearchService searching customers in index kept in sync with datastore
+// asynchronously. Found customers are read back from customerRps, so customers deleted or changed since they were
+// indexed are served the same way as by fallbackRps searching datastore while index is unavailable
+func NewIndexedCustomerSearchService(
+	indexRps repository.CustomerSearchRepository,
+	fallbackRps repository.CustomerSearchRepository,
+	customerRps repository.CustomerRepository,
+) CustomerSearchService {
+	return &customerSearchService{searchRps: indexRps, fallbackRps: fallbackRps, customerRps: customerRps}
+}
+
+// Search returns page of caller tenant customers matching search and total number of matched customers
+func (s *customerSearchService) Search(ctx context.Context, search *model.CustomerSearch) ([]*model.Customer, int, error) {
+	tenantID := tenant.IDFromContext(ctx)
+
+	customers, total, err := s.searchRps.Search(ctx, tenantID, search)
+	if err == nil {
+		return s.readBack(ctx, tenantID, customers, total, &search.Filter)
+	}
+
+	if s.fallbackRps == nil {
+		return nil, 0, err
+	}
+
+	logging.FromContext(ctx).Warnf("failed to search customers, falling back to datastore search - %v", err)
+	return s.fallbackRps.Search(ctx, tenantID, search)
+}
+
+// readBack replaces found customers with their current state, customers which are deleted or don't match filter
+// anymore are dropped and not counted. Customers are returned as is if datastore isn't set
+func (s *customerSearchService) readBack(
+	ctx context.Context,
+	tenantID string,
+	found []*model.Customer,
+	total int,
+	filter *model.CustomerFilter,
+) ([]*model.Customer, int, error) {
+	if s.customerRps == nil {
+		return found, total, nil
+	}
+
+	customers := make([]*model.Customer, 0, len(found))
+	for _, f := range found {
+		c, err := s.customerRps.FindByID(ctx, tenantID, f.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if c == nil || !matchesFilter(c, filter) {
+			total--
+			continue
+		}
+		customers = append(customers, c)
+	}
+	return customers, total, nil
+}
+
+func matchesFilter(c *model.Customer, filter *model.CustomerFilter) bool {
+	return (filter.Importance == nil || *filter.Importance == c.Importance) && (filter.Inactive == nil || *filter.Inactive == c.Inactive)
+}
+
+// CustomerReindexService represents behavior of rebuilding search index of caller tenant customers
+type CustomerReindexService interface {
+	Reindex(context.Context) (int, error)
+}
+
+type customerReindexService struct {
+	indexer *search.Indexer
+}
+
+// NewCustomerReindexService builds new customerReindexService
+func NewCustomerReindexService(indexer *search.Indexer) CustomerReindexService {
+	return &customerReindexService{indexer: indexer}
+}
+
+// Reindex indexes all customers of caller tenant and drops documents of missing ones, number of indexed customers is returned
+func (s *customerReindexService) Reindex(ctx context.Context) (int, error) {
+	return s.indexer.Reindex(ctx, tenant.IDFromContext(ctx))
+}
+
+type searchIndexingCustomerService struct {
+	CustomerService
+	indexRps repository.CustomerIndexRepository
+}
+
+// NewSearchIndexingCustomerService wraps CustomerService, so customers changed by BulkUpdate are patched in search index,
+// since no events are published for them. Change is already stored when index is patched, so failure is only logged,
+// customers are searched with outdated fields until tenant is reindexed
+func NewSearchIndexingCustomerService(next CustomerService, indexRps repository.CustomerIndexRepository) CustomerService {
+	return &searchIndexingCustomerService{CustomerService: next, indexRps: indexRps}
+}
+
+func (s *searchIndexingCustomerService) BulkUpdate(ctx context.Context, filter *model.CustomerFilter, patch *model.CustomerPatch) (int, error) {
+	updated, err := s.CustomerService.BulkUpdate(ctx, filter, patch)
+	if err != nil || updated == 0 {
+		return updated, err
+	}
+
+	if err := s.indexRps.BulkUpdate(ctx, tenant.IDFromContext(ctx), filter, patch); err != nil {
+		logging.FromContext(ctx).Errorf("failed to patch %d bulk updated customers in search index - %v", updated, err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+// SessionService represents behavior of session service
+type SessionService interface {
+	FindAll(context.Context, *model.RefreshTokenFilter) ([]*model.RefreshToken, int, error)
+}
+
+type sessionService struct {
+	rfrTknRps repository.RefreshTokenRepository
+}
+
+// NewSessionService builds new sessionService
+func NewSessionService(rfrTknRps repository.RefreshTokenRepository) SessionService {
+	return &sessionService{rfrTknRps: rfrTknRps}
+}
+
+// FindAll returns page of sessions (refresh tokens) matching filter and total number of matching sessions
+func (s *sessionService) FindAll(ctx context.Context, filter *model.RefreshTokenFilter) ([]*model.RefreshToken, int, error) {
+	tokens, err := s.rfrTknRps.FindAll(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// whole result fits into the first page, so there is no need to count
+	if filter.Offset == 0 && (filter.Limit == 0 || len(tokens) < filter.Limit) {
+		return tokens, len(tokens), nil
+	}
+
+	total, err := s.rfrTknRps.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tokens, total, nil
+}
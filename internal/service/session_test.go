@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository/mocks"
+)
+
+type sessionServiceTestSuite struct {
+	suite.Suite
+	sessionSvc      SessionService
+	rfrTokenRpsMock *mocks.RefreshTokenRepository
+	tokens          []*model.RefreshToken
+}
+
+func (s *sessionServiceTestSuite) SetupSuite() {
+	now := time.Now().UTC()
+	s.tokens = []*model.RefreshToken{
+		{ID: "2b3f0c4e-6d1a-4f8e-9b7c-5a4d3e2f1c0b", UserID: "7e1d2c3b-4a5f-4e6d-8c9b-0a1f2e3d4c5b", ExpiresIn: 60, CreatedAt: now},
+		{ID: "9c8b7a6f-5e4d-4c3b-8a2f-1e0d9c8b7a6f", UserID: "7e1d2c3b-4a5f-4e6d-8c9b-0a1f2e3d4c5b", ExpiresIn: 60, CreatedAt: now},
+	}
+}
+
+func (s *sessionServiceTestSuite) SetupTest() {
+	s.rfrTokenRpsMock = mocks.NewRefreshTokenRepository(s.T())
+	s.sessionSvc = NewSessionService(s.rfrTokenRpsMock)
+}
+
+func (s *sessionServiceTestSuite) TestFindAll() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	t.Log("total is not counted if all sessions fit into the first page")
+	{
+		filter := &model.RefreshTokenFilter{Limit: 10}
+		s.rfrTokenRpsMock.EXPECT().FindAll(ctx, filter).Return(s.tokens, nil).Once()
+
+		tokens, total, err := s.sessionSvc.FindAll(ctx, filter)
+		require.NoError(err, "no error must be raised")
+		require.Equal(s.tokens, tokens, "sessions must be returned")
+		require.Equal(len(s.tokens), total, "total must be equal to number of sessions")
+	}
+
+	t.Log("total is counted for full page")
+	{
+		filter := &model.RefreshTokenFilter{Limit: 2}
+		s.rfrTokenRpsMock.EXPECT().FindAll(ctx, filter).Return(s.tokens, nil).Once()
+		s.rfrTokenRpsMock.EXPECT().Count(ctx, filter).Return(5, nil).Once()
+
+		tokens, total, err := s.sessionSvc.FindAll(ctx, filter)
+		require.NoError(err, "no error must be raised")
+		require.Len(tokens, 2, "page of sessions must be returned")
+		require.Equal(5, total, "total must be counted")
+	}
+
+	t.Log("total is counted for pages after the first one")
+	{
+		filter := &model.RefreshTokenFilter{Limit: 2, Offset: 4}
+		s.rfrTokenRpsMock.EXPECT().FindAll(ctx, filter).Return([]*model.RefreshToken{}, nil).Once()
+		s.rfrTokenRpsMock.EXPECT().Count(ctx, filter).Return(3, nil).Once()
+
+		tokens, total, err := s.sessionSvc.FindAll(ctx, filter)
+		require.NoError(err, "no error must be raised")
+		require.Empty(tokens, "page beyond last session must be empty")
+		require.Equal(3, total, "total must be counted even for empty page")
+	}
+
+	t.Log("repository error is propagated")
+	{
+		filter := &model.RefreshTokenFilter{Limit: 2}
+		rpsErr := errors.New("connection lost")
+		s.rfrTokenRpsMock.EXPECT().FindAll(ctx, filter).Return(nil, rpsErr).Once()
+
+		_, _, err := s.sessionSvc.FindAll(ctx, filter)
+		require.ErrorIs(err, rpsErr, "repository error must be returned")
+	}
+}
+
+// start session service test suite
+func TestSessionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(sessionServiceTestSuite))
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/pkg/db/transactor"
+)
+
+type transactionalCustomerService struct {
+	CustomerService
+	txtor transactor.Transactor
+}
+
+// NewTransactionalCustomerService wraps CustomerService, so operations reading customers before change
+// are run within transaction and can't be interleaved with concurrent changes. CreateIfNotExists is run as is,
+// since it reads winner of concurrent creation after duplicate error, which aborts transaction
+func NewTransactionalCustomerService(next CustomerService, txtor transactor.Transactor) CustomerService {
+	return &transactionalCustomerService{CustomerService: next, txtor: txtor}
+}
+
+func (s *transactionalCustomerService) Upsert(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	var customer *model.Customer
+	var changes []model.CustomerChange
+
+	err := s.txtor.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		customer, changes, err = s.CustomerService.Upsert(ctx, c)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return customer, changes, nil
+}
+
+func (s *transactionalCustomerService) Update(ctx context.Context, c *model.Customer) (*model.Customer, []model.CustomerChange, error) {
+	var customer *model.Customer
+	var changes []model.CustomerChange
+
+	err := s.txtor.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		customer, changes, err = s.CustomerService.Update(ctx, c)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return customer, changes, nil
+}
+
+func (s *transactionalCustomerService) BulkUpdate(ctx context.Context, filter *model.CustomerFilter, patch *model.CustomerPatch) (int, error) {
+	var updated int
+
+	err := s.txtor.WithinTransaction(ctx, func(ctx context.Context) (err error) {
+		updated, err = s.CustomerService.BulkUpdate(ctx, filter, patch)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
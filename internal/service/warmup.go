@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/pkg/logging"
+)
+
+// WarmUpCustomerCache loads the most important customers of configured tenants into backend cache,
+// so the first requests after deploy are not served from datastore. Number of cached customers is returned
+func WarmUpCustomerCache(ctx context.Context, b CustomerBackend, cfg *config.CacheWarmUpCfg) (int, error) {
+	cached := 0
+	for _, tenantID := range cfg.Tenants {
+		customers, err := b.Repository.FindMostImportant(ctx, tenantID, cfg.Limit)
+		if err != nil {
+			return cached, fmt.Errorf("failed to read customers of tenant %s - %w", tenantID, err)
+		}
+
+		for _, c := range customers {
+			if err := b.Cache.Create(ctx, c); err != nil {
+				return cached, fmt.Errorf("failed to cache customer %s of tenant %s - %w", c.ID, tenantID, err)
+			}
+			cached++
+		}
+
+		logging.FromContext(ctx).Debugf("%d customers of tenant %s are cached", len(customers), tenantID)
+	}
+	return cached, nil
+}
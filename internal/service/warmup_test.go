@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/cache"
+	cacheMocks "github.com/umalmyha/customers/internal/cache/mocks"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+type warmUpTestSuite struct {
+	suite.Suite
+	customerRps repository.CustomerRepository
+	cacheRps    cache.CustomerCacheRepository
+	customers   []*model.Customer
+}
+
+func (s *warmUpTestSuite) SetupTest() {
+	s.customerRps = repository.NewInMemoryCustomerRepository(config.EmailUniquenessTenant)
+	s.cacheRps = cache.NewInMemoryCache()
+	s.customers = []*model.Customer{
+		{ID: "0b3e5c7a-1d2f-4e6a-8b9c-0d1e2f3a4b5c", TenantID: "acme", FirstName: "John", LastName: "Walls", Email: "john.walls@somemail.com", Importance: model.ImportanceLow},
+		{ID: "1c4f6d8b-2e3a-4f7b-9c0d-1e2f3a4b5c6d", TenantID: "acme", FirstName: "Jane", LastName: "Doe", Email: "jane.doe@somemail.com", Importance: model.ImportanceCritical},
+		{ID: "2d5a7e9c-3f4b-4a8c-8d1e-2f3a4b5c6d7e", TenantID: "acme", FirstName: "Jack", LastName: "Black", Email: "jack.black@somemail.com", Importance: model.ImportanceHigh},
+		{ID: "3e6b8f0d-4a5c-4b9d-9e2f-3a4b5c6d7e8f", TenantID: "globex", FirstName: "Hank", LastName: "Scorpio", Email: "hank.scorpio@somemail.com", Importance: model.ImportanceCritical},
+	}
+
+	for _, c := range s.customers {
+		s.Require().NoError(s.customerRps.Create(context.Background(), c), "failed to create customer")
+	}
+}
+
+func (s *warmUpTestSuite) TestWarmUp() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	backend := CustomerBackend{Repository: s.customerRps, Cache: s.cacheRps}
+	cached, err := WarmUpCustomerCache(ctx, backend, &config.CacheWarmUpCfg{Enabled: true, Tenants: []string{"acme"}, Limit: 2})
+
+	t.Log("the most important customers of configured tenants are cached up to limit")
+	{
+		require.NoError(err, "no error must be raised")
+		require.Equal(2, cached, "customers must be cached up to limit")
+
+		for _, c := range s.customers[1:3] {
+			found, err := s.cacheRps.FindByID(ctx, c.TenantID, c.ID)
+			require.NoError(err, "no error must be raised")
+			require.Equal(c, found, "important customer must be cached")
+		}
+	}
+
+	t.Log("less important customers and customers of other tenants are not cached")
+	{
+		for _, c := range []*model.Customer{s.customers[0], s.customers[3]} {
+			found, err := s.cacheRps.FindByID(ctx, c.TenantID, c.ID)
+			require.NoError(err, "no error must be raised")
+			require.Nil(found, "customer must not be cached")
+		}
+	}
+}
+
+func (s *warmUpTestSuite) TestWarmUpCacheFailed() {
+	t := s.T()
+	require := s.Require()
+	ctx := context.Background()
+
+	cacheMock := cacheMocks.NewCustomerCacheRepository(t)
+	cacheMock.EXPECT().Create(mock.Anything, s.customers[3]).Return(errors.New("cache err")).Once()
+
+	t.Log("warm-up is stopped once cache fails")
+	{
+		backend := CustomerBackend{Repository: s.customerRps, Cache: cacheMock}
+		cached, err := WarmUpCustomerCache(ctx, backend, &config.CacheWarmUpCfg{Enabled: true, Tenants: []string{"globex"}, Limit: 10})
+		require.Error(err, "cache error must be raised up")
+		require.Zero(cached, "no customers must be cached")
+	}
+}
+
+// start warm-up test suite
+func TestWarmUpTestSuite(t *testing.T) {
+	suite.Run(t, new(warmUpTestSuite))
+}
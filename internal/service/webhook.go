@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/umalmyha/customers/internal/errors"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/tenant"
+)
+
+const webhookSecretBytes = 32
+
+// WebhookService represents behavior of webhook subscriptions service
+type WebhookService interface {
+	Create(context.Context, *model.Webhook) (*model.Webhook, error)
+	FindAll(context.Context) ([]*model.Webhook, error)
+	DeleteByID(context.Context, string) error
+	FindDeliveries(context.Context, string) ([]*model.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	webhookRps repository.WebhookRepository
+}
+
+// NewWebhookService builds new webhookService
+func NewWebhookService(webhookRps repository.WebhookRepository) WebhookService {
+	return &webhookService{webhookRps: webhookRps}
+}
+
+// Create subscribes webhook to customer events of caller tenant, secret is generated if it is not provided
+func (s *webhookService) Create(ctx context.Context, w *model.Webhook) (*model.Webhook, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, appErrors.NewBusinessErr(appErrors.ErrInvalidArgument, fmt.Sprintf("webhook url %s must be absolute http or https url", w.URL))
+	}
+
+	if w.Secret == "" {
+		secret := make([]byte, webhookSecretBytes)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret - %w", err)
+		}
+		w.Secret = hex.EncodeToString(secret)
+	}
+
+	w.ID = uuid.NewString()
+	w.TenantID = tenant.IDFromContext(ctx)
+	w.CreatedAt = time.Now().UTC()
+
+	if err := s.webhookRps.Create(ctx, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *webhookService) FindAll(ctx context.Context) ([]*model.Webhook, error) {
+	return s.webhookRps.FindAll(ctx, tenant.IDFromContext(ctx))
+}
+
+func (s *webhookService) DeleteByID(ctx context.Context, id string) error {
+	return s.webhookRps.DeleteByID(ctx, tenant.IDFromContext(ctx), id)
+}
+
+// FindDeliveries returns deliveries of webhook from the newest, error is raised if webhook doesn't belong to caller tenant
+func (s *webhookService) FindDeliveries(ctx context.Context, webhookID string) ([]*model.WebhookDelivery, error) {
+	w, err := s.webhookRps.FindByID(ctx, tenant.IDFromContext(ctx), webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if w == nil {
+		return nil, appErrors.NewEntryNotFoundErr("webhook", webhookID)
+	}
+	return s.webhookRps.FindDeliveries(ctx, webhookID)
+}
@@ -0,0 +1,2 @@
+// Package storage contains storages for binary objects like images
+package storage
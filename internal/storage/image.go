@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const imageDirPerm = 0o750
+
+// ErrImageNotFound is raised when requested image is missing in storage
+var ErrImageNotFound = errors.New("image not found")
+
+// ErrImageExists is raised when image with the same name is already stored and overwrite is not requested
+var ErrImageExists = errors.New("image already exists")
+
+// ErrInvalidImageName is raised when image name can't be used as object name
+var ErrInvalidImageName = errors.New("invalid image name")
+
+// ImageFile represents opened stored image
+type ImageFile interface {
+	io.ReadSeekCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// ImageStorage represents behavior of image storage backend
+type ImageStorage interface {
+	Save(ctx context.Context, name string, r io.Reader, overwrite bool) error
+	Open(ctx context.Context, name string) (ImageFile, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type filesystemImageStorage struct {
+	root string
+}
+
+// NewFilesystemImageStorage builds new filesystem image storage with provided root directory
+func NewFilesystemImageStorage(root string) ImageStorage {
+	return &filesystemImageStorage{root: root}
+}
+
+func (s *filesystemImageStorage) Save(_ context.Context, name string, r io.Reader, overwrite bool) (err error) {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	if !overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return ErrImageExists
+		}
+	}
+
+	if err := os.MkdirAll(s.root, imageDirPerm); err != nil {
+		return fmt.Errorf("filesystem: failed to create images directory - %w", err)
+	}
+
+	// write to temporary file first and rename it afterwards, so readers never see partially written image
+	tmp, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("filesystem: failed to create temporary file for image %s - %w", name, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("filesystem: failed to write image %s - %w", name, err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("filesystem: failed to close temporary file for image %s - %w", name, err)
+	}
+
+	if !overwrite {
+		if _, statErr := os.Stat(path); statErr == nil {
+			err = ErrImageExists
+			return err
+		}
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("filesystem: failed to store image %s - %w", name, err)
+	}
+	return nil
+}
+
+func (s *filesystemImageStorage) Open(_ context.Context, name string) (ImageFile, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("filesystem: failed to open image %s - %w", name, err)
+	}
+	return f, nil
+}
+
+func (s *filesystemImageStorage) Delete(_ context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrImageNotFound
+		}
+		return fmt.Errorf("filesystem: failed to delete image %s - %w", name, err)
+	}
+	return nil
+}
+
+func (s *filesystemImageStorage) path(name string) (string, error) {
+	base := filepath.Base(name)
+	// names starting with dot are reserved for service files like temporary uploads and index
+	if base != name || strings.HasPrefix(base, ".") || base == string(filepath.Separator) {
+		return "", ErrInvalidImageName
+	}
+	return filepath.Join(s.root, base), nil
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	imageIndexFile     = ".index.json"
+	defaultContentType = "application/octet-stream"
+)
+
+// ErrImageNameReserved is raised when image was deleted, but its content is still shared by other images under its name
+var ErrImageNameReserved = errors.New("image name is reserved by images sharing its content")
+
+// ImageInfo represents metadata of stored image, images with identical content share content stored once.
+// RefCount is number of images sharing content of the image, it is maintained by the store.
+type ImageInfo struct {
+	Name             string    `json:"name"`
+	Size             int64     `json:"size"`
+	ContentType      string    `json:"contentType"`
+	UploadedAt       time.Time `json:"uploadedAt"`
+	Uploader         string    `json:"uploader,omitempty"`
+	Hash             string    `json:"hash,omitempty"`
+	Blob             string    `json:"blob,omitempty"`
+	RefCount         int       `json:"refCount,omitempty"`
+	MetadataStripped bool      `json:"metadataStripped,omitempty"`
+}
+
+// ContentName returns name image content is stored under, it is the name of image which content was uploaded first
+func (i *ImageInfo) ContentName() string {
+	if i.Blob == "" {
+		return i.Name // stored before content was shared
+	}
+	return i.Blob
+}
+
+// ImageMetadataStore represents behavior of image metadata store
+type ImageMetadataStore interface {
+	Get(ctx context.Context, name string) (*ImageInfo, error)
+	Put(ctx context.Context, info *ImageInfo) error
+	Delete(ctx context.Context, name string) error
+	List(ctx context.Context, after string, limit int) ([]*ImageInfo, error)
+	Share(ctx context.Context, hash string, info *ImageInfo) (*ImageInfo, error)
+	Release(ctx context.Context, name string) (string, int, error)
+}
+
+type filesystemImageMetadataStore struct {
+	mu     sync.RWMutex
+	root   string
+	names  []string
+	infos  map[string]*ImageInfo
+	hashes map[string]string // content name by content hash
+	refs   map[string]int    // number of images by content name
+}
+
+// NewFilesystemImageMetadataStore builds new image metadata store which keeps sorted index in memory
+// and persists it to the index file inside root directory. Index is rebuilt from directory content if file is missing.
+func NewFilesystemImageMetadataStore(root string) (ImageMetadataStore, error) {
+	s := &filesystemImageMetadataStore{
+		root:   root,
+		infos:  make(map[string]*ImageInfo),
+		hashes: make(map[string]string),
+		refs:   make(map[string]int),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *filesystemImageMetadataStore) Get(_ context.Context, name string) (*ImageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, ok := s.infos[name]
+	if !ok {
+		return nil, s.missing(name)
+	}
+	return s.found(info), nil
+}
+
+// Put stores image which content is stored under its own name unless other content name is provided
+func (s *filesystemImageMetadataStore) Put(_ context.Context, info *ImageInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *info
+	if stored.Blob == "" {
+		stored.Blob = stored.Name
+	}
+	return s.put(&stored)
+}
+
+func (s *filesystemImageMetadataStore) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.infos[name]; !ok {
+		return nil
+	}
+
+	s.remove(name)
+	return s.persist()
+}
+
+// Share stores image sharing content of already stored image with provided content hash
+func (s *filesystemImageMetadataStore) Share(_ context.Context, hash string, info *ImageInfo) (*ImageInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blob, ok := s.hashes[hash]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+
+	stored := *info
+	stored.Hash = hash
+	stored.Blob = blob
+	if err := s.put(&stored); err != nil {
+		return nil, err
+	}
+	return s.found(&stored), nil
+}
+
+// Release removes image metadata. Name image content is stored under and number of images still sharing the content
+// are returned, so caller knows when image content must be removed.
+func (s *filesystemImageMetadataStore) Release(_ context.Context, name string) (string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, ok := s.infos[name]
+	if !ok {
+		return "", 0, s.missing(name)
+	}
+
+	blob := info.ContentName()
+	s.remove(name)
+	if err := s.persist(); err != nil {
+		return "", 0, err
+	}
+	return blob, s.refs[blob], nil
+}
+
+func (s *filesystemImageMetadataStore) List(_ context.Context, after string, limit int) ([]*ImageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.names), func(i int) bool {
+		return s.names[i] > after
+	})
+
+	end := start + limit
+	if end > len(s.names) {
+		end = len(s.names)
+	}
+
+	infos := make([]*ImageInfo, 0, end-start)
+	for _, name := range s.names[start:end] {
+		infos = append(infos, s.found(s.infos[name]))
+	}
+	return infos, nil
+}
+
+func (s *filesystemImageMetadataStore) load() error {
+	content, err := os.ReadFile(s.indexPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s.rebuild()
+		}
+		return fmt.Errorf("filesystem: failed to read images index - %w", err)
+	}
+
+	var infos []*ImageInfo
+	if err := json.Unmarshal(content, &infos); err != nil {
+		return fmt.Errorf("filesystem: failed to decode images index - %w", err)
+	}
+
+	for _, info := range infos {
+		info.RefCount = 0 // references are counted from images sharing content
+		s.infos[info.Name] = info
+		s.names = append(s.names, info.Name)
+		s.reference(info)
+	}
+	sort.Strings(s.names)
+
+	return nil
+}
+
+func (s *filesystemImageMetadataStore) rebuild() error {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("filesystem: failed to read images directory - %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		fi, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("filesystem: failed to read image %s info - %w", entry.Name(), err)
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(fi.Name()))
+		if contentType == "" {
+			contentType = defaultContentType
+		}
+
+		info := &ImageInfo{
+			Name:        fi.Name(),
+			Blob:        fi.Name(),
+			Size:        fi.Size(),
+			ContentType: contentType,
+			UploadedAt:  fi.ModTime().UTC(),
+		}
+		s.infos[fi.Name()] = info
+		s.names = append(s.names, fi.Name()) // ReadDir returns entries sorted by name
+		s.reference(info)
+	}
+
+	return s.persist()
+}
+
+func (s *filesystemImageMetadataStore) persist() error {
+	infos := make([]*ImageInfo, 0, len(s.names))
+	for _, name := range s.names {
+		infos = append(infos, s.infos[name])
+	}
+
+	content, err := json.Marshal(infos)
+	if err != nil {
+		return fmt.Errorf("filesystem: failed to encode images index - %w", err)
+	}
+
+	if err := os.MkdirAll(s.root, imageDirPerm); err != nil {
+		return fmt.Errorf("filesystem: failed to create images directory - %w", err)
+	}
+
+	// write to temporary file first and rename it afterwards, so index is never left partially written
+	tmp, err := os.CreateTemp(s.root, ".index-*")
+	if err != nil {
+		return fmt.Errorf("filesystem: failed to create temporary images index - %w", err)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("filesystem: failed to write images index - %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("filesystem: failed to close temporary images index - %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.indexPath()); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("filesystem: failed to store images index - %w", err)
+	}
+	return nil
+}
+
+func (s *filesystemImageMetadataStore) put(info *ImageInfo) error {
+	if existing, ok := s.infos[info.Name]; ok {
+		s.unreference(existing)
+	} else {
+		idx := sort.SearchStrings(s.names, info.Name)
+		s.names = append(s.names, "")
+		copy(s.names[idx+1:], s.names[idx:])
+		s.names[idx] = info.Name
+	}
+
+	info.RefCount = 0
+	s.infos[info.Name] = info
+	s.reference(info)
+
+	return s.persist()
+}
+
+func (s *filesystemImageMetadataStore) remove(name string) {
+	idx := sort.SearchStrings(s.names, name)
+	s.names = append(s.names[:idx], s.names[idx+1:]...)
+	s.unreference(s.infos[name])
+	delete(s.infos, name)
+}
+
+// reference counts image as one more sharing its content, content is found by hash while it is shared
+func (s *filesystemImageMetadataStore) reference(info *ImageInfo) {
+	blob := info.ContentName()
+	s.refs[blob]++
+	if info.Hash != "" {
+		s.hashes[info.Hash] = blob
+	}
+}
+
+func (s *filesystemImageMetadataStore) unreference(info *ImageInfo) {
+	blob := info.ContentName()
+	s.refs[blob]--
+	if s.refs[blob] > 0 {
+		return
+	}
+
+	delete(s.refs, blob)
+	if name, ok := s.hashes[info.Hash]; ok && name == blob {
+		delete(s.hashes, info.Hash)
+	}
+}
+
+// found returns copy of image metadata with number of images sharing its content
+func (s *filesystemImageMetadataStore) found(info *ImageInfo) *ImageInfo {
+	found := *info
+	found.RefCount = s.refs[info.ContentName()]
+	return &found
+}
+
+// missing reports missing image, name of deleted image is reserved while its content is shared by other images
+func (s *filesystemImageMetadataStore) missing(name string) error {
+	if s.refs[name] > 0 {
+		return ErrImageNameReserved
+	}
+	return ErrImageNotFound
+}
+
+func (s *filesystemImageMetadataStore) indexPath() string {
+	return filepath.Join(s.root, imageIndexFile)
+}
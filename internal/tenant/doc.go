@@ -0,0 +1,2 @@
+// Package tenant contains helpers for passing caller tenant through request context
+package tenant
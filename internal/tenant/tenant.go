@@ -0,0 +1,43 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultID is tenant id used when caller didn't specify any tenant
+const DefaultID = "default"
+
+// MaxIDLength is max length of tenant id
+const MaxIDLength = 64
+
+// ErrMismatch is raised when caller requests tenant other than one granted by access token
+var ErrMismatch = errors.New("requested tenant doesn't match tenant of access token")
+
+type tenantCtxKey struct{}
+
+// ContextWithID returns copy of parent context carrying caller tenant id
+func ContextWithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, id)
+}
+
+// IDFromContext extracts caller tenant id from context, DefaultID is returned if tenant is not set
+func IDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantCtxKey{}).(string); ok && id != "" {
+		return id
+	}
+	return DefaultID
+}
+
+// Resolve returns tenant granted to caller by signed access token, tokens without tenant are granted DefaultID.
+// Tenant requested by caller, e.g. in header, is optional, but it must be the granted one if provided
+func Resolve(granted string, requested string) (string, error) {
+	if granted == "" {
+		granted = DefaultID
+	}
+
+	if requested != "" && requested != granted {
+		return "", ErrMismatch
+	}
+	return granted, nil
+}
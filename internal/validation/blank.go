@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// NotBlankTag is reported for string fields consisting of whitespaces only
+const NotBlankTag = "notblank"
+
+// notBlankMessages are message templates per locale
+var notBlankMessages = map[string]string{
+	"en": "{0} must not be blank",
+	"es": "{0} no debe estar en blanco",
+	"de": "{0} darf nicht leer sein",
+}
+
+// JSONTagName returns field name from json tag, query tag is used for query parameters structs without json tag
+// and struct field name is used if both tags are missing or skipped
+func JSONTagName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "query"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// ReportBlank reports NotBlankTag violation for each listed string field of current struct consisting
+// of whitespaces only, violation is reported under json field name, empty strings are left for required
+func ReportBlank(sl validator.StructLevel, structFieldNames ...string) {
+	current := sl.Current()
+	for _, name := range structFieldNames {
+		field, ok := current.Type().FieldByName(name)
+		if !ok {
+			continue
+		}
+
+		value := current.FieldByIndex(field.Index)
+		if value.Kind() != reflect.String || value.String() == "" || strings.TrimSpace(value.String()) != "" {
+			continue
+		}
+
+		sl.ReportError(value.Interface(), JSONTagName(field), field.Name, NotBlankTag, "")
+	}
+}
+
+func registerNotBlankTranslations(v *validator.Validate, translators ...ut.Translator) error {
+	for _, trans := range translators {
+		msg, ok := notBlankMessages[trans.Locale()]
+		if !ok {
+			msg = notBlankMessages["en"]
+		}
+
+		register := func(t ut.Translator) error {
+			return t.Add(NotBlankTag, msg, true)
+		}
+
+		if err := v.RegisterTranslation(NotBlankTag, trans, register, translateField); err != nil {
+			return fmt.Errorf("failed to register %s translation - %w", NotBlankTag, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/suite"
+)
+
+type fullName struct {
+	FirstName string `json:"firstName" validate:"required"`
+	LastName  string `json:"lastName" validate:"required"`
+}
+
+type blankTestSuite struct {
+	suite.Suite
+	validator *EchoValidator
+}
+
+func (s *blankTestSuite) SetupSuite() {
+	v := validator.New()
+	v.RegisterTagNameFunc(JSONTagName)
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		ReportBlank(sl, "FirstName", "LastName")
+	}, fullName{})
+
+	uni, err := Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+	s.validator = Echo(v, uni)
+}
+
+func (s *blankTestSuite) TestReportBlank() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("whitespace-only names are reported under json field names")
+	{
+		err := s.validator.Validate(&fullName{FirstName: "  ", LastName: "\t"})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		violations := err.(*PayloadError).violations
+		require.Len(violations, 2, "both names must be reported")
+		require.Equal("firstName", violations[0].Field, "json field name expected")
+		require.Equal(NotBlankTag, violations[0].Code, "notblank code expected")
+		require.Equal("firstName must not be blank", violations[0].Message, "incorrect message")
+		require.Equal("lastName", violations[1].Field, "json field name expected")
+	}
+
+	t.Log("empty name is reported by required only")
+	{
+		err := s.validator.Validate(&fullName{FirstName: "", LastName: "Walls"})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		violations := err.(*PayloadError).violations
+		require.Len(violations, 1, "single violation expected")
+		require.Equal("required", violations[0].Code, "required code expected")
+	}
+
+	t.Log("names with surrounding whitespaces are valid")
+	{
+		require.NoError(s.validator.Validate(&fullName{FirstName: " John ", LastName: "Walls"}), "names must be valid")
+	}
+
+	t.Log("blank message is translated")
+	{
+		err := s.validator.Validate(&fullName{FirstName: " ", LastName: "Walls"})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		translated := err.(*PayloadError).Translate(s.validator.Translator("de"))
+		require.Equal("firstName darf nicht leer sein", translated.violations[0].Message, "message must be in german")
+	}
+}
+
+// start blank test suite
+func TestBlankTestSuite(t *testing.T) {
+	suite.Run(t, new(blankTestSuite))
+}
@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// ExclusiveTag is reported for field which value is not allowed along with value of other field
+const ExclusiveTag = "exclusive"
+
+// exclusiveMessages are message templates per locale, placeholders are field, its value, other field and its value
+var exclusiveMessages = map[string]string{
+	"en": "{0} must not be {1} when {2} is {3}",
+	"es": "{0} no debe ser {1} cuando {2} es {3}",
+	"de": "{0} darf nicht {1} sein, wenn {2} {3} ist",
+}
+
+// Exclusion forbids Field to have Value while Other field has OtherValue, fields are referenced by struct field names
+type Exclusion struct {
+	Field      string
+	Value      any
+	Other      string
+	OtherValue any
+}
+
+// Check reports violation with english message under json field name if struct s holds excluded combination of values
+func (e Exclusion) Check(s any) (Violation, bool) {
+	current := reflect.Indirect(reflect.ValueOf(s))
+	field, other, ok := e.fields(current)
+	if !ok {
+		return Violation{}, false
+	}
+
+	return Violation{
+		Field:   JSONTagName(field),
+		Message: fmt.Sprintf("%s must not be %v when %s is %v", JSONTagName(field), e.Value, JSONTagName(other), e.OtherValue),
+		Code:    ExclusiveTag,
+		Param:   exclusiveParam(other, e.OtherValue),
+	}, true
+}
+
+// fields returns both struct fields if current struct holds excluded combination of values
+func (e Exclusion) fields(current reflect.Value) (reflect.StructField, reflect.StructField, bool) {
+	if current.Kind() != reflect.Struct {
+		return reflect.StructField{}, reflect.StructField{}, false
+	}
+
+	field, ok := current.Type().FieldByName(e.Field)
+	if !ok {
+		return reflect.StructField{}, reflect.StructField{}, false
+	}
+
+	other, ok := current.Type().FieldByName(e.Other)
+	if !ok {
+		return reflect.StructField{}, reflect.StructField{}, false
+	}
+
+	excluded := reflect.DeepEqual(current.FieldByIndex(field.Index).Interface(), e.Value) &&
+		reflect.DeepEqual(current.FieldByIndex(other.Index).Interface(), e.OtherValue)
+	return field, other, excluded
+}
+
+// ReportExclusions reports ExclusiveTag violation on Field of each exclusion held by current struct,
+// violation param is other field json name and its value, e.g. inactive=true
+func ReportExclusions(sl validator.StructLevel, exclusions ...Exclusion) {
+	current := sl.Current()
+	for _, e := range exclusions {
+		field, other, ok := e.fields(current)
+		if !ok {
+			continue
+		}
+
+		value := current.FieldByIndex(field.Index)
+		sl.ReportError(value.Interface(), JSONTagName(field), field.Name, ExclusiveTag, exclusiveParam(other, e.OtherValue))
+	}
+}
+
+func exclusiveParam(other reflect.StructField, value any) string {
+	return fmt.Sprintf("%s=%v", JSONTagName(other), value)
+}
+
+func registerExclusiveTranslations(v *validator.Validate, translators ...ut.Translator) error {
+	for _, trans := range translators {
+		msg, ok := exclusiveMessages[trans.Locale()]
+		if !ok {
+			msg = exclusiveMessages["en"]
+		}
+
+		register := func(t ut.Translator) error {
+			return t.Add(ExclusiveTag, msg, true)
+		}
+
+		if err := v.RegisterTranslation(ExclusiveTag, trans, register, translateExclusive); err != nil {
+			return fmt.Errorf("failed to register %s translation - %w", ExclusiveTag, err)
+		}
+	}
+	return nil
+}
+
+func translateExclusive(t ut.Translator, fe validator.FieldError) string {
+	other, otherValue, _ := strings.Cut(fe.Param(), "=")
+	msg, err := t.T(fe.Tag(), fe.Field(), fmt.Sprint(fe.Value()), other, otherValue)
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/suite"
+)
+
+// importance values are numbered as in http api
+const (
+	importanceLow      = 1
+	importanceHigh     = 3
+	importanceCritical = 4
+)
+
+type customerStatus struct {
+	Importance int  `json:"importance" validate:"required,importance"`
+	Inactive   bool `json:"inactive"`
+}
+
+var customerStatusExclusion = Exclusion{Field: "Importance", Value: importanceCritical, Other: "Inactive", OtherValue: true}
+
+type exclusiveTestSuite struct {
+	suite.Suite
+	validator *EchoValidator
+}
+
+func (s *exclusiveTestSuite) SetupSuite() {
+	v := validator.New()
+	v.RegisterTagNameFunc(JSONTagName)
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		ReportExclusions(sl, customerStatusExclusion)
+	}, customerStatus{})
+
+	uni, err := Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+	s.validator = Echo(v, uni)
+}
+
+func (s *exclusiveTestSuite) TestReportExclusions() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("excluded combination is reported on field")
+	{
+		err := s.validator.Validate(&customerStatus{Importance: importanceCritical, Inactive: true})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		violations := err.(*PayloadError).violations
+		require.Len(violations, 1, "single violation expected")
+		require.Equal("importance", violations[0].Field, "json field name expected")
+		require.Equal(ExclusiveTag, violations[0].Code, "exclusive code expected")
+		require.Equal("inactive=true", violations[0].Param, "other field and its value expected")
+		require.Equal("importance must not be 4 when inactive is true", violations[0].Message, "incorrect message")
+	}
+
+	t.Log("other combinations are valid")
+	{
+		valid := []customerStatus{
+			{Importance: importanceCritical, Inactive: false},
+			{Importance: importanceHigh, Inactive: true},
+			{Importance: importanceLow, Inactive: false},
+		}
+		for _, c := range valid {
+			require.NoError(s.validator.Validate(&c), "importance %d with inactive %t must be valid", c.Importance, c.Inactive)
+		}
+	}
+
+	t.Log("exclusive message is translated")
+	{
+		err := s.validator.Validate(&customerStatus{Importance: importanceCritical, Inactive: true})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		translated := err.(*PayloadError).Translate(s.validator.Translator("de"))
+		require.Equal("importance darf nicht 4 sein, wenn inactive true ist", translated.violations[0].Message, "message must be in german")
+	}
+}
+
+func (s *exclusiveTestSuite) TestCheck() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("excluded combination is reported as violation")
+	{
+		v, ok := customerStatusExclusion.Check(&customerStatus{Importance: importanceCritical, Inactive: true})
+		require.True(ok, "violation must be reported")
+		require.Equal(Violation{
+			Field:   "importance",
+			Message: "importance must not be 4 when inactive is true",
+			Code:    ExclusiveTag,
+			Param:   "inactive=true",
+		}, v, "incorrect violation")
+	}
+
+	t.Log("other combination is not reported")
+	{
+		_, ok := customerStatusExclusion.Check(customerStatus{Importance: importanceHigh, Inactive: true})
+		require.False(ok, "violation must not be reported")
+	}
+
+	t.Log("struct without fields of exclusion is not reported")
+	{
+		_, ok := customerStatusExclusion.Check(&fullName{FirstName: "John", LastName: "Walls"})
+		require.False(ok, "violation must not be reported")
+	}
+}
+
+// start exclusive test suite
+func TestExclusiveTestSuite(t *testing.T) {
+	suite.Run(t, new(exclusiveTestSuite))
+}
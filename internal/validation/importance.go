@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+const importanceTag = "importance"
+
+const (
+	importanceMin = 1
+	importanceMax = 4
+)
+
+var importanceNames = []string{"low", "medium", "high", "critical"}
+
+// importanceMessages are message templates per locale, placeholders are range bounds and names
+var importanceMessages = map[string]string{
+	"en": "{0} must be one of %d-%d or %s",
+	"es": "{0} debe ser uno de %d-%d o %s",
+	"de": "{0} muss einer der Werte %d-%d oder %s sein",
+}
+
+// RegisterImportance registers importance validation tag accepting numeric range or importance names
+// along with its translations, unknown locales get english message
+func RegisterImportance(v *validator.Validate, translators ...ut.Translator) error {
+	if err := v.RegisterValidation(importanceTag, isImportance); err != nil {
+		return fmt.Errorf("failed to register %s validation - %w", importanceTag, err)
+	}
+
+	for _, trans := range translators {
+		tmpl, ok := importanceMessages[trans.Locale()]
+		if !ok {
+			tmpl = importanceMessages["en"]
+		}
+		msg := fmt.Sprintf(tmpl, importanceMin, importanceMax, strings.Join(importanceNames, ", "))
+
+		register := func(t ut.Translator) error {
+			return t.Add(importanceTag, msg, true)
+		}
+
+		if err := v.RegisterTranslation(importanceTag, trans, register, translateField); err != nil {
+			return fmt.Errorf("failed to register %s translation - %w", importanceTag, err)
+		}
+	}
+	return nil
+}
+
+func isImportance(fl validator.FieldLevel) bool {
+	field := fl.Field()
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return isImportanceNumber(field.Int())
+	case reflect.String:
+		value := strings.TrimSpace(field.String())
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return isImportanceNumber(n)
+		}
+
+		for _, name := range importanceNames {
+			if strings.EqualFold(value, name) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func isImportanceNumber(n int64) bool {
+	return n >= importanceMin && n <= importanceMax
+}
@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/model"
+)
+
+type importanceNumber struct {
+	Importance model.Importance `validate:"importance"`
+}
+
+type importanceName struct {
+	Importance string `validate:"importance"`
+}
+
+type optionalImportanceName struct {
+	Importance string `validate:"omitempty,importance"`
+}
+
+type importanceTestSuite struct {
+	suite.Suite
+	validator *EchoValidator
+}
+
+func (s *importanceTestSuite) SetupTest() {
+	v := validator.New()
+	uni, err := Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	s.validator = Echo(v, uni)
+}
+
+func (s *importanceTestSuite) TestValid() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("numeric range is accepted")
+	{
+		for i := model.Importance(1); i <= 4; i++ {
+			require.NoError(s.validator.Validate(&importanceNumber{Importance: i}), "importance %d must be valid", i)
+		}
+		require.NoError(s.validator.Validate(&importanceName{Importance: "3"}), "numeric string must be valid")
+	}
+
+	t.Log("importance names are accepted case-insensitive")
+	{
+		for _, name := range []string{"low", "Medium", "HIGH", "critical"} {
+			require.NoError(s.validator.Validate(&importanceName{Importance: name}), "importance %s must be valid", name)
+		}
+	}
+}
+
+func (s *importanceTestSuite) TestInvalid() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("numbers out of range are rejected")
+	{
+		for _, i := range []model.Importance{-1, 5} {
+			err := s.validator.Validate(&importanceNumber{Importance: i})
+			require.IsType(&PayloadError{}, err, "importance %d must be invalid", i)
+		}
+	}
+
+	t.Log("unknown name is rejected with allowed values listed")
+	{
+		err := s.validator.Validate(&importanceName{Importance: "urgent"})
+		require.IsType(&PayloadError{}, err, "unknown name must be invalid")
+		require.Equal("Importance must be one of 1-4 or low, medium, high, critical\n", err.Error(), "message must list allowed values")
+	}
+}
+
+func (s *importanceTestSuite) TestEmpty() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("empty values are rejected")
+	{
+		require.IsType(&PayloadError{}, s.validator.Validate(&importanceNumber{}), "zero importance must be invalid")
+		require.IsType(&PayloadError{}, s.validator.Validate(&importanceName{}), "empty importance name must be invalid")
+	}
+
+	t.Log("empty value is skipped when optional")
+	{
+		require.NoError(s.validator.Validate(&optionalImportanceName{}), "empty optional importance must be valid")
+	}
+}
+
+func (s *importanceTestSuite) TestCustomMessage() {
+	t := s.T()
+	require := s.Require()
+
+	v := validator.New()
+	uni, err := Translations(v)
+	require.NoError(err, "failed to register validation translations")
+
+	echoValidator := Echo(v, uni, Message{
+		Field: "Importance",
+		Tag:   importanceTag,
+		Messages: map[string]string{
+			"en": "{0} must be low, medium, high or critical",
+			"es": "{0} debe ser low, medium, high o critical",
+		},
+	})
+
+	t.Log("custom message is returned for invalid importance")
+	{
+		err := echoValidator.Validate(&importanceName{Importance: "urgent"})
+		require.IsType(&PayloadError{}, err, "unknown name must be invalid")
+		require.Equal("Importance must be low, medium, high or critical\n", err.Error(), "custom message must be returned")
+	}
+
+	t.Log("custom message is translated, fallback message is used for locale without message")
+	{
+		var pldErr *PayloadError
+		require.ErrorAs(echoValidator.Validate(&importanceNumber{Importance: 5}), &pldErr, "importance must be invalid")
+
+		es := pldErr.Translate(echoValidator.Translator("es")).Violations()
+		require.Equal("Importance debe ser low, medium, high o critical", es[0].Message, "custom message must be translated")
+
+		de := pldErr.Translate(echoValidator.Translator("de")).Violations()
+		require.Equal("Importance must be low, medium, high or critical", de[0].Message, "fallback custom message must be used")
+		require.Equal(importanceTag, de[0].Code, "code must be validation tag")
+	}
+}
+
+// start importance validation test suite
+func TestImportanceTestSuite(t *testing.T) {
+	suite.Run(t, new(importanceTestSuite))
+}
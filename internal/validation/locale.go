@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTrans "github.com/go-playground/validator/v10/translations/en"
+	esTrans "github.com/go-playground/validator/v10/translations/es"
+)
+
+// Translations builds universal translator for supported locales (en, es, de), en is fallback,
+// and registers validation messages for all of them
+func Translations(v *validator.Validate) (*ut.UniversalTranslator, error) {
+	enLocale := en.New()
+	uni := ut.New(enLocale, enLocale, es.New(), de.New())
+
+	enT, _ := uni.GetTranslator("en")
+	if err := enTrans.RegisterDefaultTranslations(v, enT); err != nil {
+		return nil, fmt.Errorf("failed to register en translations - %w", err)
+	}
+
+	esT, _ := uni.GetTranslator("es")
+	if err := esTrans.RegisterDefaultTranslations(v, esT); err != nil {
+		return nil, fmt.Errorf("failed to register es translations - %w", err)
+	}
+
+	deT, _ := uni.GetTranslator("de")
+	if err := registerDeTranslations(v, deT); err != nil {
+		return nil, fmt.Errorf("failed to register de translations - %w", err)
+	}
+
+	if err := RegisterImportance(v, enT, esT, deT); err != nil {
+		return nil, err
+	}
+
+	if err := registerNotBlankTranslations(v, enT, esT, deT); err != nil {
+		return nil, err
+	}
+
+	if err := registerExclusiveTranslations(v, enT, esT, deT); err != nil {
+		return nil, err
+	}
+	return uni, nil
+}
+
+// acceptedLocales returns locales listed in Accept-Language header ordered by preference,
+// region specific locale is followed by its base language, e.g. de-AT, de
+func acceptedLocales(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	accepted := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil || parsed <= 0 {
+				continue
+			}
+			quality = parsed
+		}
+
+		accepted = append(accepted, weighted{locale: strings.ReplaceAll(tag, "-", "_"), quality: quality})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+
+	locales := make([]string, 0, len(accepted))
+	seen := make(map[string]struct{})
+	add := func(locale string) {
+		if _, ok := seen[locale]; !ok {
+			seen[locale] = struct{}{}
+			locales = append(locales, locale)
+		}
+	}
+
+	for _, a := range accepted {
+		add(a.locale)
+		if base, _, ok := strings.Cut(a.locale, "_"); ok {
+			add(base)
+		}
+	}
+	return locales
+}
+
+// deMessages are german messages for validation tags used in api payloads, together with min and max below
+// they cover every tag of payloads. Catalog is partial on purpose: other tags are reported in english
+var deMessages = map[string]string{
+	"required": "{0} ist ein Pflichtfeld",
+	"email":    "{0} muss eine gültige E-Mail-Adresse sein",
+	"uuid":     "{0} muss eine gültige UUID sein",
+	"url":      "{0} muss eine gültige URL sein",
+	"unique":   "{0} darf keine doppelten Werte enthalten",
+	"oneof":    "{0} muss einer der Werte [{1}] sein",
+}
+
+func registerDeTranslations(v *validator.Validate, trans ut.Translator) error {
+	for tag, msg := range deMessages {
+		msg := msg
+		register := func(t ut.Translator) error {
+			return t.Add(tag, msg, true)
+		}
+
+		if err := v.RegisterTranslation(tag, trans, register, translateField); err != nil {
+			return err
+		}
+	}
+
+	lengths := map[string][2]string{
+		"min": {"{0} muss mindestens {1} Zeichen lang sein", "{0} muss {1} oder größer sein"},
+		"max": {"{0} darf höchstens {1} Zeichen lang sein", "{0} muss {1} oder kleiner sein"},
+	}
+
+	for tag, msgs := range lengths {
+		tag, msgs := tag, msgs
+		register := func(t ut.Translator) error {
+			if err := t.Add(tag+"-string", msgs[0], true); err != nil {
+				return err
+			}
+			return t.Add(tag+"-number", msgs[1], true)
+		}
+
+		translate := func(t ut.Translator, fe validator.FieldError) string {
+			key := tag + "-number"
+			if fe.Kind() == reflect.String {
+				key = tag + "-string"
+			}
+
+			msg, err := t.T(key, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return msg
+		}
+
+		if err := v.RegisterTranslation(tag, trans, register, translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func translateField(t ut.Translator, fe validator.FieldError) string {
+	msg, err := t.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
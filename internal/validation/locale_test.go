@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/suite"
+)
+
+type localeTestSuite struct {
+	suite.Suite
+}
+
+func (s *localeTestSuite) TestAcceptedLocales() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("locales are ordered by quality and followed by base language")
+	{
+		locales := acceptedLocales("en;q=0.8, de-AT, es;q=0.9")
+		require.Equal([]string{"de_AT", "de", "es", "en"}, locales, "locales are ordered incorrectly")
+	}
+
+	t.Log("wildcard, zero quality and duplicates are skipped")
+	{
+		locales := acceptedLocales("fr-CH, fr;q=0.9, es;q=0, *;q=0.5")
+		require.Equal([]string{"fr_CH", "fr"}, locales, "locales are filtered incorrectly")
+	}
+
+	t.Log("empty header has no locales")
+	{
+		require.Empty(acceptedLocales(""), "no locales expected")
+	}
+}
+
+func (s *localeTestSuite) TestImportanceTranslations() {
+	t := s.T()
+	require := s.Require()
+
+	v := validator.New()
+	uni, err := Translations(v)
+	require.NoError(err, "failed to register validation translations")
+	echoValidator := Echo(v, uni)
+
+	err = echoValidator.Validate(&importanceName{Importance: "urgent"})
+	require.IsType(&PayloadError{}, err, "error must be payload error")
+	pldErr := err.(*PayloadError)
+
+	t.Log("importance message is translated")
+	{
+		translated := pldErr.Translate(echoValidator.Translator("de"))
+		require.Equal("Importance muss einer der Werte 1-4 oder low, medium, high, critical sein", translated.violations[0].Message, "message must be in german")
+
+		translated = pldErr.Translate(echoValidator.Translator("es"))
+		require.Equal("Importance debe ser uno de 1-4 o low, medium, high, critical", translated.violations[0].Message, "message must be in spanish")
+	}
+}
+
+type deCatalogPayload struct {
+	Name   string   `json:"name" validate:"required"`
+	Email  string   `json:"email" validate:"email"`
+	ID     string   `json:"id" validate:"uuid"`
+	Short  string   `json:"short" validate:"min=3"`
+	Long   string   `json:"long" validate:"max=2"`
+	URL    string   `json:"url" validate:"url"`
+	Tags   []string `json:"tags" validate:"unique"`
+	Kind   string   `json:"kind" validate:"oneof=a b"`
+	Letter string   `json:"letter" validate:"alpha"`
+}
+
+func (s *localeTestSuite) TestDeTranslations() {
+	t := s.T()
+	require := s.Require()
+
+	v := validator.New()
+	v.RegisterTagNameFunc(JSONTagName)
+	uni, err := Translations(v)
+	require.NoError(err, "failed to register validation translations")
+	echoValidator := Echo(v, uni)
+
+	err = echoValidator.Validate(&deCatalogPayload{
+		Email:  "not-an-email",
+		ID:     "1111",
+		Short:  "ab",
+		Long:   "abc",
+		URL:    "not a url",
+		Tags:   []string{"a", "a"},
+		Kind:   "c",
+		Letter: "1",
+	})
+	require.IsType(&PayloadError{}, err, "error must be payload error")
+	translated := err.(*PayloadError).Translate(echoValidator.Translator("de"))
+
+	messages := make(map[string]string)
+	for _, v := range translated.violations {
+		messages[v.Field] = v.Message
+	}
+
+	t.Log("tags of api payloads are translated to german")
+	{
+		require.Equal("name ist ein Pflichtfeld", messages["name"], "incorrect required message")
+		require.Equal("email muss eine gültige E-Mail-Adresse sein", messages["email"], "incorrect email message")
+		require.Equal("id muss eine gültige UUID sein", messages["id"], "incorrect uuid message")
+		require.Equal("short muss mindestens 3 Zeichen lang sein", messages["short"], "incorrect min message")
+		require.Equal("long darf höchstens 2 Zeichen lang sein", messages["long"], "incorrect max message")
+		require.Equal("url muss eine gültige URL sein", messages["url"], "incorrect url message")
+		require.Equal("tags darf keine doppelten Werte enthalten", messages["tags"], "incorrect unique message")
+		require.Equal("kind muss einer der Werte [a b] sein", messages["kind"], "incorrect oneof message")
+	}
+
+	t.Log("tag missing in german catalog falls back to english")
+	{
+		require.Equal("letter can only contain alphabetic characters", messages["letter"], "english message expected")
+	}
+}
+
+// start locale test suite
+func TestLocaleTestSuite(t *testing.T) {
+	suite.Run(t, new(localeTestSuite))
+}
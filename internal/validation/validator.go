@@ -5,20 +5,36 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
-type violation struct {
+// Violation describes single failed check of payload field
+type Violation struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
+	Code    string `json:"code"`            // failed validation tag, stable for clients unlike message
+	Param   string `json:"param,omitempty"` // tag parameter, e.g. min length
+}
+
+// Message is custom message of violation replacing translation of validation tag, messages are keyed by locale
+// and message of fallback locale is used for other locales. {0} is replaced with field name and {1} with tag parameter
+type Message struct {
+	Field    string // field name as reported in violations, any field if empty
+	Tag      string
+	Messages map[string]string
 }
 
 // PayloadError represents struct with failed checks
 type PayloadError struct {
-	violations []violation
+	violations  []Violation
+	fieldErrors validator.ValidationErrors
+	fallback    ut.Translator
+	messages    *customMessages
+	inURL       bool
 }
 
 // Error returns error string
@@ -34,30 +50,59 @@ func (e *PayloadError) Error() string {
 }
 
 // Violation adds new violation
-func (e *PayloadError) Violation(v violation) {
+func (e *PayloadError) Violation(v Violation) {
 	e.violations = append(e.violations, v)
 }
 
+// Violations returns failed checks of payload
+func (e *PayloadError) Violations() []Violation {
+	return e.violations
+}
+
+// InURL marks violations as found in path or query parameters rather than in request body
+func (e *PayloadError) InURL() *PayloadError {
+	e.inURL = true
+	return e
+}
+
+// IsInURL reports if violations are found in path or query parameters
+func (e *PayloadError) IsInURL() bool {
+	return e.inURL
+}
+
+// Translate returns copy of error with violation messages translated with provided translator,
+// messages of tags which translator doesn't know are left in fallback locale
+func (e *PayloadError) Translate(trans ut.Translator) *PayloadError {
+	if e.fieldErrors == nil {
+		return e
+	}
+	translated := newPayloadError(e.fieldErrors, trans, e.fallback, e.messages)
+	translated.inURL = e.inURL
+	return translated
+}
+
 // MarshalJSON defines json marshaling
 func (e *PayloadError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Errors []violation `json:"errors"`
+		Errors []Violation `json:"errors"`
 	}{
 		Errors: e.violations,
 	})
 }
 
-// EchoValidator represents echo error handler
+// EchoValidator represents echo error handler, messages are in fallback locale unless error is translated
 type EchoValidator struct {
 	validator  *validator.Validate
-	translator ut.Translator
+	translator *ut.UniversalTranslator
+	messages   *customMessages
 }
 
-// Echo builds validator for echo
-func Echo(v *validator.Validate, trans ut.Translator) *EchoValidator {
+// Echo builds validator for echo, custom messages take precedence over translations of validation tags
+func Echo(v *validator.Validate, uni *ut.UniversalTranslator, messages ...Message) *EchoValidator {
 	return &EchoValidator{
 		validator:  v,
-		translator: trans,
+		translator: uni,
+		messages:   newCustomMessages(uni.GetFallback().Locale(), messages),
 	}
 }
 
@@ -70,19 +115,78 @@ func (v *EchoValidator) Validate(i any) error {
 
 	var ve validator.ValidationErrors
 	if errors.As(err, &ve) {
-		return v.payloadError(ve)
+		fallback := v.translator.GetFallback()
+		return newPayloadError(ve, fallback, fallback, v.messages)
 	}
 
 	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 }
 
-func (v *EchoValidator) payloadError(ve validator.ValidationErrors) error {
-	pldErr := &PayloadError{violations: make([]violation, 0)}
+// Translator picks translator for locales accepted by client in Accept-Language header, fallback is used if none is supported
+func (v *EchoValidator) Translator(acceptLanguage string) ut.Translator {
+	trans, _ := v.translator.FindTranslator(acceptedLocales(acceptLanguage)...)
+	return trans
+}
+
+func newPayloadError(ve validator.ValidationErrors, trans, fallback ut.Translator, messages *customMessages) *PayloadError {
+	pldErr := &PayloadError{violations: make([]Violation, 0), fieldErrors: ve, fallback: fallback, messages: messages}
 	for _, e := range ve {
-		pldErr.Violation(violation{
+		msg, ok := messages.message(e, trans.Locale())
+		if !ok {
+			msg = e.Translate(trans)
+		}
+
+		// untranslated tag is reported as raw error, so message of fallback locale is used instead
+		if !ok && msg == e.Error() && fallback != nil {
+			msg = e.Translate(fallback)
+		}
+
+		pldErr.Violation(Violation{
 			Field:   e.Field(),
-			Message: e.Translate(v.translator),
+			Message: msg,
+			Code:    e.Tag(),
+			Param:   e.Param(),
 		})
 	}
 	return pldErr
 }
+
+// customMessages are custom messages keyed by field and tag, messages of any field are keyed by tag only
+type customMessages struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+func newCustomMessages(fallback string, messages []Message) *customMessages {
+	m := &customMessages{fallback: fallback, messages: make(map[string]map[string]string, len(messages))}
+	for _, msg := range messages {
+		m.messages[customMessageKey(msg.Field, msg.Tag)] = msg.Messages
+	}
+	return m
+}
+
+// message returns custom message of violation in locale, message for field takes precedence over message for any field
+func (m *customMessages) message(fe validator.FieldError, locale string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+
+	msgs, ok := m.messages[customMessageKey(fe.Field(), fe.Tag())]
+	if !ok {
+		if msgs, ok = m.messages[customMessageKey("", fe.Tag())]; !ok {
+			return "", false
+		}
+	}
+
+	msg, ok := msgs[locale]
+	if !ok {
+		if msg, ok = msgs[m.fallback]; !ok {
+			return "", false
+		}
+	}
+	return strings.NewReplacer("{0}", fe.Field(), "{1}", fe.Param()).Replace(msg), true
+}
+
+func customMessageKey(field, tag string) string {
+	return field + "|" + tag
+}
@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/suite"
+)
+
+type credentials struct {
+	Email    string `validate:"required,email"`
+	Password string `validate:"min=8"`
+}
+
+type echoValidatorTestSuite struct {
+	suite.Suite
+	validator *EchoValidator
+}
+
+func (s *echoValidatorTestSuite) SetupTest() {
+	v := validator.New()
+	uni, err := Translations(v)
+	s.Require().NoError(err, "failed to register validation translations")
+
+	s.validator = Echo(v, uni)
+}
+
+func (s *echoValidatorTestSuite) TestViolationCodes() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("violations carry failed tag and its parameter")
+	{
+		err := s.validator.Validate(&credentials{Password: "short"})
+		require.IsType(&PayloadError{}, err, "error must be payload error")
+
+		encoded, err := json.Marshal(err)
+		require.NoError(err, "failed to encode payload error")
+		require.JSONEq(`{"errors": [
+			{"field": "Email", "message": "Email is a required field", "code": "required"},
+			{"field": "Password", "message": "Password must be at least 8 characters in length", "code": "min", "param": "8"}
+		]}`, string(encoded), "violations are encoded incorrectly")
+	}
+}
+
+func (s *echoValidatorTestSuite) TestTranslate() {
+	t := s.T()
+	require := s.Require()
+
+	err := s.validator.Validate(&credentials{Password: "short"})
+	require.IsType(&PayloadError{}, err, "error must be payload error")
+	pldErr := err.(*PayloadError)
+
+	messages := func(acceptLanguage string) []string {
+		translated := pldErr.Translate(s.validator.Translator(acceptLanguage))
+		msgs := make([]string, 0, len(translated.violations))
+		for _, v := range translated.violations {
+			msgs = append(msgs, v.Message)
+		}
+		return msgs
+	}
+
+	t.Log("spanish messages are used if requested")
+	{
+		require.Equal([]string{
+			"Email es un campo requerido",
+			"Password debe tener al menos 8 caracteres de longitud",
+		}, messages("es"), "messages must be in spanish")
+	}
+
+	t.Log("german messages are used for regional locale")
+	{
+		require.Equal([]string{
+			"Email ist ein Pflichtfeld",
+			"Password muss mindestens 8 Zeichen lang sein",
+		}, messages("de-DE,de;q=0.9"), "messages must be in german")
+	}
+
+	t.Log("english is used for unsupported or missing locale")
+	{
+		expected := []string{
+			"Email is a required field",
+			"Password must be at least 8 characters in length",
+		}
+		require.Equal(expected, messages("fr"), "messages must fall back to english")
+		require.Equal(expected, messages(""), "messages must fall back to english")
+	}
+
+	t.Log("codes stay the same for any locale")
+	{
+		translated := pldErr.Translate(s.validator.Translator("es"))
+		require.Equal(pldErr.violations[0].Code, translated.violations[0].Code, "code must not be translated")
+		require.Equal(pldErr.violations[1].Param, translated.violations[1].Param, "param must not be translated")
+	}
+}
+
+// start echo validator test suite
+func TestEchoValidatorTestSuite(t *testing.T) {
+	suite.Run(t, new(echoValidatorTestSuite))
+}
@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/pkg/retry"
+)
+
+// Headers of webhook request
+const (
+	SignatureHeader = "X-Webhook-Signature" // HMAC-SHA256 of body keyed with webhook secret, e.g. sha256=5d41...
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	signaturePrefix      = "sha256="
+	responseBodyMaxBytes = 64 << 10
+	claimDeliveriesLimit = 100
+)
+
+// Dispatcher records deliveries of customer events to subscribed webhooks and attempts them on schedule
+type Dispatcher struct {
+	webhookRps repository.WebhookRepository
+	client     *http.Client
+	cfg        *config.WebhooksCfg
+	backoff    retry.Backoff
+	recorded   chan struct{} // signals that new deliveries are recorded, so they are attempted without waiting for poll
+}
+
+// NewDispatcher builds new Dispatcher
+func NewDispatcher(webhookRps repository.WebhookRepository, client *http.Client, cfg *config.WebhooksCfg) *Dispatcher {
+	return &Dispatcher{
+		webhookRps: webhookRps,
+		client:     client,
+		cfg:        cfg,
+		backoff:    retry.Backoff{Interval: cfg.RetryInterval, MaxInterval: cfg.RetryMaxInterval},
+		recorded:   make(chan struct{}, 1),
+	}
+}
+
+// Sign returns signature of payload sent in SignatureHeader, so receiver can verify payload is sent by us
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch records pending delivery of event to all active webhooks of event tenant subscribed to event type.
+// Error is returned if deliveries are not recorded, so event is consumed again. Recorded deliveries are attempted by Run
+func (d *Dispatcher) Dispatch(ctx context.Context, e *model.CustomerEvent) error {
+	webhooks, err := d.webhookRps.FindSubscribed(ctx, e.TenantID, e.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(e.External())
+	if err != nil {
+		return fmt.Errorf("failed to serialize customer event %s - %w", e.ID, err)
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]*model.WebhookDelivery, len(webhooks))
+	for i, w := range webhooks {
+		deliveries[i] = &model.WebhookDelivery{
+			ID:            uuid.NewString(),
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Status:        model.WebhookDeliveryPending,
+			Payload:       payload,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+
+	if err := d.webhookRps.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	select {
+	case d.recorded <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run attempts due deliveries until context is cancelled, deliveries are looked up each poll interval and once new
+// deliveries are recorded. Due deliveries are claimed, so each one is attempted by single instance at a time.
+// Delivery is attempted at least once, receiver can tell repeated delivery by DeliveryHeader
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		claimed, err := d.attemptDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			logrus.Errorf("failed to attempt due webhook deliveries - %v", err)
+		}
+
+		// more deliveries may be due once the whole batch is claimed
+		if claimed == claimDeliveriesLimit {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-d.recorded:
+		}
+	}
+}
+
+// attemptDue claims due deliveries and attempts them concurrently, so slow receiver doesn't delay others.
+// Deliveries are claimed for twice as long as single attempt may take, they are claimed again once lease is over
+// unless attempt is saved, e.g. instance is stopped in the middle of attempt
+func (d *Dispatcher) attemptDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	due, err := d.webhookRps.ClaimDueDeliveries(ctx, now, now.Add(2*d.cfg.Timeout), claimDeliveriesLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, dd := range due {
+		wg.Add(1)
+		go func(dd *model.DueWebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, dd.Webhook, dd.Delivery)
+		}(dd)
+	}
+	wg.Wait()
+
+	return len(due), nil
+}
+
+// attempt posts delivery payload to webhook, failed delivery is scheduled for the next attempt with backoff
+// until attempts are exhausted. Delivery is left pending as is if context is cancelled, so it is attempted again
+func (d *Dispatcher) attempt(ctx context.Context, w *model.Webhook, delivery *model.WebhookDelivery) {
+	statusCode, err := d.post(ctx, w, delivery.ID, delivery.EventType, delivery.Payload)
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.Status = model.WebhookDeliveryDelivered
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		logrus.Warnf("delivery %s of %s event %s to webhook %s is dead - %v", delivery.ID, delivery.EventType, delivery.EventID, w.ID, err)
+		delivery.Status = model.WebhookDeliveryDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff.Delay(delivery.Attempts))
+	}
+
+	if err := d.webhookRps.SaveDelivery(ctx, delivery); err != nil {
+		logrus.Errorf("failed to save webhook delivery %s - %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, w *model.Webhook, deliveryID string, t model.CustomerEventType, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request - %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(w.Secret, payload))
+	req.Header.Set(EventHeader, string(t))
+	req.Header.Set(DeliveryHeader, deliveryID)
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// body is drained, so connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, responseBodyMaxBytes))
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return res.StatusCode, fmt.Errorf("receiver responded with status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/model"
+	"github.com/umalmyha/customers/internal/repository"
+)
+
+const (
+	webhookTestTenant = "acme"
+	webhookTestSecret = "8b1d2c0e6f4a4e1f9a7c3b5d2e0f1a6c"
+)
+
+type receivedRequest struct {
+	body      []byte
+	signature string
+	event     string
+	delivery  string
+}
+
+type dispatcherTestSuite struct {
+	suite.Suite
+	receiver   *httptest.Server
+	webhookRps repository.WebhookRepository
+	dispatcher *Dispatcher
+	mu         sync.Mutex
+	received   []receivedRequest
+	failures   int // number of next requests receiver fails with 500
+}
+
+func (s *dispatcherTestSuite) SetupTest() {
+	s.received = nil
+	s.failures = 0
+	s.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.received = append(s.received, receivedRequest{
+			body:      body,
+			signature: r.Header.Get(SignatureHeader),
+			event:     r.Header.Get(EventHeader),
+			delivery:  r.Header.Get(DeliveryHeader),
+		})
+
+		if s.failures > 0 {
+			s.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	s.webhookRps = repository.NewInMemoryWebhookRepository()
+	s.dispatcher = NewDispatcher(s.webhookRps, s.receiver.Client(), &config.WebhooksCfg{
+		MaxAttempts:      3,
+		RetryInterval:    time.Millisecond,
+		RetryMaxInterval: 5 * time.Millisecond,
+		Timeout:          time.Second,
+		PollInterval:     time.Hour,
+	})
+}
+
+func (s *dispatcherTestSuite) TearDownTest() {
+	s.receiver.Close()
+}
+
+func (s *dispatcherTestSuite) TestDispatchSigned() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	w := s.webhook("f3b1c0de-7a6b-4c1d-9e8f-0a1b2c3d4e5f", true, model.CustomerCreated)
+	e := s.event(model.CustomerCreated)
+
+	t.Log("event is posted with signature of body")
+	{
+		require.NoError(s.dispatcher.Dispatch(ctx, e), "no error must be raised")
+		s.deliver(ctx, w.ID)
+		require.Len(s.received, 1, "event must be delivered once")
+
+		req := s.received[0]
+		require.Equal(Sign(webhookTestSecret, req.body), req.signature, "signature must be HMAC of body keyed with secret")
+		require.Regexp("^sha256=[0-9a-f]{64}$", req.signature, "signature must be hex encoded sha256")
+		require.Equal(string(model.CustomerCreated), req.event, "event type must be sent in header")
+
+		var received model.CustomerEvent
+		require.NoError(json.Unmarshal(req.body, &received), "body must be json encoded event")
+		require.Equal(e.ID, received.ID, "event must be sent as body")
+		require.Equal(e.Customer.Email, received.Customer.Email, "customer must be sent as part of event")
+		require.Equal(e.Customer.Importance.External(), received.Customer.Importance, "importance must be numbered as in http api")
+	}
+
+	t.Log("delivery is persisted as delivered")
+	{
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+		require.Len(deliveries, 1, "single delivery must be persisted")
+		require.Equal(model.WebhookDeliveryDelivered, deliveries[0].Status, "delivery must be delivered")
+		require.Equal(1, deliveries[0].Attempts, "single attempt must be made")
+		require.Equal(http.StatusNoContent, deliveries[0].LastStatusCode, "receiver status must be persisted")
+		require.Equal(deliveries[0].ID, s.received[0].delivery, "delivery id must be sent in header")
+	}
+
+	t.Log("signature doesn't match if payload is tampered")
+	{
+		require.NotEqual(Sign(webhookTestSecret, append(s.received[0].body, ' ')), s.received[0].signature, "signature must depend on body")
+		require.NotEqual(Sign("another-secret-of-other-webhook", s.received[0].body), s.received[0].signature, "signature must depend on secret")
+	}
+}
+
+func (s *dispatcherTestSuite) TestDispatchRetried() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	w := s.webhook("0e2d4c6b-8a9f-4b1c-8d3e-5f7a9b1c3d5e", true, model.CustomerUpdated)
+
+	t.Log("failed delivery is retried until receiver accepts it")
+	{
+		s.failures = 2
+		require.NoError(s.dispatcher.Dispatch(ctx, s.event(model.CustomerUpdated)), "no error must be raised")
+		s.deliver(ctx, w.ID)
+		require.Len(s.received, 3, "delivery must be retried after each failure")
+		require.Equal(s.received[0].body, s.received[2].body, "the same payload must be retried")
+		require.Equal(s.received[0].delivery, s.received[2].delivery, "the same delivery must be retried")
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+		require.Equal(model.WebhookDeliveryDelivered, deliveries[0].Status, "delivery must be delivered")
+		require.Equal(3, deliveries[0].Attempts, "all attempts must be counted")
+	}
+
+	t.Log("delivery is dead once attempts are exhausted")
+	{
+		s.received, s.failures = nil, 10
+		require.NoError(s.dispatcher.Dispatch(ctx, s.event(model.CustomerUpdated)), "no error must be raised")
+		s.deliver(ctx, w.ID)
+		require.Len(s.received, 3, "max attempts must be made")
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+		require.Len(deliveries, 2, "each event must have own delivery")
+
+		dead := deliveries[0]
+		require.Equal(model.WebhookDeliveryDead, dead.Status, "delivery must be dead")
+		require.Equal(3, dead.Attempts, "all attempts must be counted")
+		require.Equal(http.StatusInternalServerError, dead.LastStatusCode, "last receiver status must be persisted")
+		require.Contains(dead.LastError, "500", "last failure must be persisted")
+	}
+}
+
+func (s *dispatcherTestSuite) TestDispatchSubscribedOnly() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	s.webhook("3c5e7a9b-1d2f-4a6c-8e0b-2d4f6a8c0e1b", true, model.CustomerCreated)
+	s.webhook("7a9c1e3b-5d7f-4b2d-9f1a-3c5e7b9d1f2a", false, model.CustomerDeleted)
+
+	t.Log("event is not delivered to webhooks which are not subscribed or inactive")
+	{
+		require.NoError(s.dispatcher.Dispatch(ctx, s.event(model.CustomerDeleted)), "no error must be raised")
+		claimed, err := s.dispatcher.attemptDue(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Zero(claimed, "no delivery must be recorded")
+		require.Empty(s.received, "event must not be delivered")
+	}
+
+	t.Log("event of other tenant is not delivered")
+	{
+		e := s.event(model.CustomerCreated)
+		e.TenantID = "globex"
+		require.NoError(s.dispatcher.Dispatch(ctx, e), "no error must be raised")
+		claimed, err := s.dispatcher.attemptDue(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Zero(claimed, "no delivery must be recorded")
+		require.Empty(s.received, "event must not be delivered")
+	}
+}
+
+func (s *dispatcherTestSuite) TestDispatchScheduled() {
+	t := s.T()
+	require := s.Require()
+
+	ctx := context.Background()
+	w := s.webhook("5b7d9f1a-3c5e-4a7b-9d1f-3a5c7e9b1d3f", true, model.CustomerCreated)
+	e := s.event(model.CustomerCreated)
+
+	t.Log("delivery is recorded as pending, but not attempted by dispatch")
+	{
+		require.NoError(s.dispatcher.Dispatch(ctx, e), "no error must be raised")
+		require.NoError(s.dispatcher.Dispatch(ctx, e), "no error must be raised on repeated event")
+		require.Empty(s.received, "event must not be posted by dispatch")
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+		require.Len(deliveries, 1, "repeated event must be recorded once")
+		require.Equal(model.WebhookDeliveryPending, deliveries[0].Status, "delivery must be pending")
+		require.Zero(deliveries[0].Attempts, "no attempts must be made")
+	}
+
+	t.Log("delivery is left pending as is once attempt is interrupted")
+	{
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		claimed, err := s.dispatcher.attemptDue(cancelled)
+		require.NoError(err, "no error must be raised")
+		require.Equal(1, claimed, "due delivery must be claimed")
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+		require.Equal(model.WebhookDeliveryPending, deliveries[0].Status, "interrupted delivery must be left pending")
+		require.Zero(deliveries[0].Attempts, "interrupted attempt must not be counted")
+	}
+
+	t.Log("claimed delivery isn't attempted again until lease is over")
+	{
+		claimed, err := s.dispatcher.attemptDue(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Zero(claimed, "claimed delivery must not be due")
+	}
+
+	t.Log("failed delivery is scheduled for the next attempt")
+	{
+		s.failures = 1
+		e := s.event(model.CustomerCreated)
+		require.NoError(s.dispatcher.Dispatch(ctx, e), "no error must be raised")
+
+		claimed, err := s.dispatcher.attemptDue(ctx)
+		require.NoError(err, "no error must be raised")
+		require.Equal(1, claimed, "due delivery must be claimed")
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, w.ID)
+		require.NoError(err, "failed to read deliveries")
+
+		failed := deliveries[0]
+		require.Equal(e.ID, failed.EventID, "incorrect delivery")
+		require.Equal(model.WebhookDeliveryPending, failed.Status, "failed delivery must be left pending")
+		require.Equal(1, failed.Attempts, "failed attempt must be counted")
+		require.True(failed.NextAttemptAt.After(failed.UpdatedAt), "next attempt must be scheduled with backoff")
+	}
+}
+
+func (s *dispatcherTestSuite) TestRun() {
+	t := s.T()
+	require := s.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.webhook("9d1f3a5c-7e9b-4d1f-8a3c-5e7a9c1e3b5d", true, model.CustomerDeleted)
+	go s.dispatcher.Run(ctx)
+
+	t.Log("recorded delivery is attempted without waiting for poll interval")
+	{
+		require.NoError(s.dispatcher.Dispatch(ctx, s.event(model.CustomerDeleted)), "no error must be raised")
+		require.Eventually(func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.received) == 1
+		}, time.Second, 5*time.Millisecond, "delivery must be attempted")
+	}
+}
+
+// deliver attempts due deliveries until all deliveries of webhook are either delivered or dead
+func (s *dispatcherTestSuite) deliver(ctx context.Context, webhookID string) {
+	s.Require().Eventually(func() bool {
+		if _, err := s.dispatcher.attemptDue(ctx); err != nil {
+			return false
+		}
+
+		deliveries, err := s.webhookRps.FindDeliveries(ctx, webhookID)
+		if err != nil {
+			return false
+		}
+
+		for _, d := range deliveries {
+			if d.Status == model.WebhookDeliveryPending {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond, "deliveries must be attempted")
+}
+
+func (s *dispatcherTestSuite) webhook(id string, active bool, types ...model.CustomerEventType) *model.Webhook {
+	w := &model.Webhook{
+		ID:         id,
+		TenantID:   webhookTestTenant,
+		URL:        s.receiver.URL + "/hooks/customers",
+		Secret:     webhookTestSecret,
+		EventTypes: types,
+		Active:     active,
+		CreatedAt:  time.Now().UTC(),
+	}
+	s.Require().NoError(s.webhookRps.Create(context.Background(), w), "failed to create webhook")
+	return w
+}
+
+func (s *dispatcherTestSuite) event(t model.CustomerEventType) *model.CustomerEvent {
+	return &model.CustomerEvent{
+		ID:         time.Now().Format(time.RFC3339Nano),
+		Type:       t,
+		TenantID:   webhookTestTenant,
+		CustomerID: "ecc770d9-4576-4f72-affa-8b1454246692",
+		Customer: &model.Customer{
+			ID:         "ecc770d9-4576-4f72-affa-8b1454246692",
+			FirstName:  "John",
+			LastName:   "Walls",
+			Email:      "john.walls@somemal.com",
+			Importance: model.ImportanceHigh,
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// start webhook dispatcher test suite
+func TestDispatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(dispatcherTestSuite))
+}
@@ -0,0 +1,2 @@
+// Package webhook delivers customer events to webhooks external systems are subscribed with
+package webhook
@@ -2,40 +2,54 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"reflect"
-	"strings"
+	"path/filepath"
+	"syscall"
 	"time"
 
-	"github.com/go-playground/locales/en"
-	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	enTrans "github.com/go-playground/validator/v10/translations/en"
 	"github.com/go-redis/redis/v9"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/labstack/echo/v4"
 	echoMw "github.com/labstack/echo/v4/middleware"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
 	"github.com/sirupsen/logrus"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	_ "github.com/umalmyha/customers/docs"
+	"github.com/umalmyha/customers/internal/audit"
 	"github.com/umalmyha/customers/internal/auth"
 	"github.com/umalmyha/customers/internal/cache"
 	"github.com/umalmyha/customers/internal/config"
+	"github.com/umalmyha/customers/internal/email"
+	"github.com/umalmyha/customers/internal/event"
+	"github.com/umalmyha/customers/internal/feature"
 	"github.com/umalmyha/customers/internal/handlers"
 	"github.com/umalmyha/customers/internal/interceptors"
 	"github.com/umalmyha/customers/internal/middleware"
-	"github.com/umalmyha/customers/internal/model"
 	"github.com/umalmyha/customers/internal/repository"
+	"github.com/umalmyha/customers/internal/search"
 	"github.com/umalmyha/customers/internal/service"
+	"github.com/umalmyha/customers/internal/storage"
 	"github.com/umalmyha/customers/internal/validation"
+	"github.com/umalmyha/customers/internal/webhook"
 	"github.com/umalmyha/customers/pkg/db/transactor"
+	"github.com/umalmyha/customers/pkg/logfields"
+	"github.com/umalmyha/customers/pkg/redact"
+	"github.com/umalmyha/customers/pkg/retry"
 	"github.com/umalmyha/customers/proto"
-	"github.com/vmihailenco/msgpack/v5"
+	mongoevent "go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -46,9 +60,14 @@ const httpPort = 3000
 const grpcPort = 3010
 const shutdownTimeout = 10 * time.Second
 const serverStartupTimeout = 10 * time.Second
-const readStreamMessagesMaxCount = 10
-const readStreamBlockTime = 0
-const cacheWriteTimeout = 5 * time.Second
+const imagesRoot = "images"
+const auditLogFilePerm = 0o600
+const webhooksConsumerGroup = "webhooks"
+const searchIndexerConsumerGroup = "search-indexer"
+const activityConsumerGroup = "customer-activity"
+const customerWatchBufferSize = 64
+const natsCacheStreamMaxMsgs = 1000
+const kafkaBatchTimeout = 10 * time.Millisecond
 
 // @title Customers API
 // @version 1.0
@@ -74,17 +93,26 @@ func main() {
 	if err != nil {
 		logrus.Fatal(err)
 	}
+	redact.AddSensitiveNames(cfg.LogCfg.RedactKeys...)
+	logrus.AddHook(logfields.NewHook(logFields(cfg.LogCfg)))
 
-	ctx, cancel := context.WithTimeout(context.Background(), serverStartupTimeout)
-	defer cancel()
+	ctx := context.Background()
 
-	pgPool, err := postgresql(ctx, cfg.PostgresConnString)
+	var pgPool *pgxpool.Pool
+	err = connectWithRetry(ctx, cfg.StartupCfg, "postgres", func(ctx context.Context) (err error) {
+		pgPool, err = postgresql(ctx, cfg.PostgresConnString, cfg.CustomersCfg.EmailUniqueness)
+		return err
+	})
 	if err != nil {
 		logrus.Fatal(err)
 	}
 	defer pgPool.Close()
 
-	redisClient, err := redisClient(ctx, cfg.RedisCfg)
+	var redisClient *redis.Client
+	err = connectWithRetry(ctx, cfg.StartupCfg, "redis", func(ctx context.Context) (err error) {
+		redisClient, err = newRedisClient(ctx, cfg.RedisCfg)
+		return err
+	})
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -94,7 +122,12 @@ func main() {
 		}
 	}()
 
-	mongoClient, err := mongodb(ctx, cfg.MongoConnString)
+	var mongoClient *mongo.Client
+	mongoPoolMonitor := repository.NewMongoPoolMonitor()
+	err = connectWithRetry(ctx, cfg.StartupCfg, "mongo", func(ctx context.Context) (err error) {
+		mongoClient, err = mongodb(ctx, cfg.MongoCfg, cfg.ActivityCfg, cfg.CustomersCfg.EmailUniqueness, mongoPoolMonitor.Monitor())
+		return err
+	})
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -104,16 +137,31 @@ func main() {
 		}
 	}()
 
-	start(pgPool, mongoClient, redisClient, &cfg.JwtCfg, &cfg.RefreshTokenCfg)
+	// NATS is connected only if it is event backend, nil JetStream context is passed otherwise
+	var jetStream nats.JetStreamContext
+	if cfg.EventBackend == config.EventBackendNats {
+		var natsConn *nats.Conn
+		err = connectWithRetry(ctx, cfg.StartupCfg, "nats", func(ctx context.Context) (err error) {
+			natsConn, jetStream, err = newJetStream(cfg.NatsCfg)
+			return err
+		})
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer natsConn.Close()
+	}
+
+	start(pgPool, mongoClient, mongoPoolMonitor, redisClient, jetStream, &cfg)
 }
 
 //nolint:funlen // function contains a lot of endpoints definitions
 func start(
 	pgPool *pgxpool.Pool,
 	mongoClient *mongo.Client,
+	mongoPoolMonitor *repository.MongoPoolMonitor,
 	redisClient *redis.Client,
-	jwtCfg *config.JwtCfg,
-	rfrTokenCfg *config.RefreshTokenCfg,
+	jetStream nats.JetStreamContext,
+	cfg *config.Config,
 ) {
 	e := echo.New()
 
@@ -123,69 +171,279 @@ func start(
 	}
 	e.Validator = echoValidator
 
-	e.HTTPErrorHandler = func(err error, c echo.Context) {
-		logrus.Errorf("error occurred during request processing - %v", err)
-
-		var pldErr *validation.PayloadError
-		if errors.As(err, &pldErr) {
-			err = c.JSON(http.StatusBadRequest, pldErr)
-			if err == nil {
-				return
-			}
-		}
+	e.HTTPErrorHandler = handlers.NewHTTPErrorHandler(echoValidator, cfg.HTTPCfg.ProductionMode)
 
-		e.DefaultHTTPErrorHandler(err, c)
+	e.IPExtractor, err = middleware.IPExtractor(cfg.HTTPCfg.TrustedProxies)
+	if err != nil {
+		logrus.Fatal(err)
 	}
+	echo.MethodNotAllowedHandler = handlers.MethodNotAllowedHandler
 
 	// Transactors
 	pgxTransactor := transactor.NewPgxTransactor(pgPool)
 	pgxTxExecutor := transactor.NewPgxWithinTransactionExecutor(pgPool)
+	mongoTransactor, err := transactor.NewMongoTransactor(context.Background(), mongoClient)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if !mongoTransactor.Transactional() {
+		logrus.Warn("mongo server is standalone, mongo customers are changed without transactions")
+	}
+	mongoTxExecutor := transactor.NewMongoWithinTransactionExecutor(mongoClient)
 
 	// Extra functionality
-	jwtIssuer := auth.NewJwtIssuer(jwtCfg.Issuer, jwtCfg.SigningMethod, jwtCfg.TimeToLive, jwtCfg.PrivateKey)
-	jwtValidator := auth.NewJwtValidator(jwtCfg.SigningMethod, jwtCfg.PublicKey)
+	jwtCfg := &cfg.JwtCfg
+	jwtIssuer := auth.NewJwtIssuer(jwtCfg.Issuer, jwtCfg.Audience, jwtCfg.SigningMethod, jwtCfg.TimeToLive, jwtCfg.PrivateKey)
+	jwtValidator := auth.NewJwtValidator(jwtCfg.SigningMethod, jwtCfg.PublicKey, jwtCfg.Issuer, jwtCfg.Audience)
+	tokenRevoker := cache.NewRedisTokenRevoker(redisClient, jwtCfg.TimeToLive)
+
+	featureFlags, err := feature.NewFlags(cfg.FeatureFlagsFile)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	runtimeCfg, err := config.NewRuntimeHolder(cfg.RuntimeCfgFile, cfg.RuntimeCfg)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	auditLog, err := auditLogger(cfg.AuditLogFile, logfields.NewHook(logFields(cfg.LogCfg)))
+	if err != nil {
+		logrus.Fatal(err)
+	}
 
 	// Middleware
-	authorizeMw := middleware.Authorize(jwtValidator)
+	authorizeMw := middleware.Authorize(jwtValidator, tokenRevoker)
+	tenantMw := middleware.Tenant()
+	adminMw := middleware.Admin(cfg.AdminCfg.UserIDs)
+	e.Use(middleware.RequestID(cfg.RequestIDHeader))
+	e.Use(echoMw.Recover())
+	e.Use(middleware.ClientIP())
+	if cfg.HTTPCfg.RequireHTTPS {
+		e.Use(middleware.RequireHTTPS("/metrics", "/health/live", "/health/ready"))
+	}
+	if cfg.HTTPCfg.MaxConcurrentRequests > 0 {
+		e.Use(middleware.MaxConcurrency(cfg.HTTPCfg.MaxConcurrentRequests, cfg.HTTPCfg.ShedRetryAfter, "/metrics", "/health/live", "/health/ready", "/api/v1/customers/events", "/api/v1/customers/ws"))
+	}
 
 	// caches
-	redisCustomerCache := cache.NewRedisCustomerCache(redisClient)
+	redisCustomerCache := cache.NewRedisCustomerCache(redisClient, runtimeCfg)
+	if cfg.RedisCfg.Serialization == config.CacheSerializationProto {
+		redisCustomerCache = cache.NewRedisProtoCustomerCache(redisClient, runtimeCfg)
+	}
 	inMemoryCustomerCache := cache.NewInMemoryCache()
 	redisStreamCustomerCache := cache.NewRedisStreamCustomerCache(redisClient, inMemoryCustomerCache)
+	if jetStream != nil { // in-memory cache is replicated without redis
+		redisStreamCustomerCache = cache.NewJetStreamCustomerCache(jetStream, cfg.NatsCfg.CacheSubject, inMemoryCustomerCache)
+	}
+	if cfg.CacheBreakerCfg.FailureThreshold > 0 {
+		redisCustomerCache = cache.NewCircuitBreakerCustomerCache("redis", redisCustomerCache, prometheus.DefaultRegisterer, &cfg.CacheBreakerCfg)
+		redisStreamCustomerCache = cache.NewCircuitBreakerCustomerCache("redis-stream", redisStreamCustomerCache, prometheus.DefaultRegisterer, &cfg.CacheBreakerCfg)
+	}
+	localCustomerCachePurgers := []cache.CustomerCachePurger{inMemoryCustomerCache}
+	if cfg.RedisCfg.Fallback {
+		fallbackCustomerCache := cache.NewExpiringInMemoryCache(runtimeCfg)
+		redisCustomerCache = cache.NewFallbackCustomerCache(redisCustomerCache, fallbackCustomerCache)
+		localCustomerCachePurgers = append(localCustomerCachePurgers, fallbackCustomerCache)
+	}
+	// local caches are purged at once and broadcast purge reaches local caches of other instances
+	customerCachePurgers := append([]cache.CustomerCachePurger{cache.NewRedisCustomerCachePurger(redisClient)}, localCustomerCachePurgers...)
+	redisCustomerCache = cache.NewMetricsCustomerCache(string(config.CustomersBackendPostgres), redisCustomerCache, prometheus.DefaultRegisterer)
+	redisStreamCustomerCache = cache.NewMetricsCustomerCache(string(config.CustomersBackendMongo), redisStreamCustomerCache, prometheus.DefaultRegisterer)
+	var customerStreamReader cache.CustomerStreamListener
+	if jetStream != nil {
+		customerCachePurgers = append(customerCachePurgers, cache.NewJetStreamCustomerCachePurger(jetStream, cfg.NatsCfg.CacheSubject))
+		customerStreamReader = cache.NewJetStreamCustomerStreamReader(
+			jetStream,
+			cfg.NatsCfg.CacheSubject,
+			inMemoryCustomerCache,
+			prometheus.DefaultRegisterer,
+			&cfg.CustomersStreamCfg,
+			localCustomerCachePurgers...,
+		)
+	} else {
+		customerCachePurgers = append(customerCachePurgers, cache.NewRedisStreamCustomerCachePurger(redisClient))
+		customerStreamReader = cache.NewRedisCustomerStreamReader(
+			redisClient,
+			inMemoryCustomerCache,
+			prometheus.DefaultRegisterer,
+			&cfg.CustomersStreamCfg,
+			localCustomerCachePurgers...,
+		)
+	}
 
 	// Repositories
-	userRps := repository.NewPostgresUserRepository(pgxTxExecutor)
-	rfrTokenRps := repository.NewPostgresRefreshTokenRepository(pgxTxExecutor)
-	pgCustomerRps := repository.NewPostgresCustomerRepository(pgPool)
-	mongoCustomerRps := repository.NewMongoCustomerRepository(mongoClient)
+	slowQueryLog := repository.NewSlowQueryLogger(logrus.StandardLogger(), cfg.RepositoryCfg.SlowQueryThreshold)
+	userRps := repository.NewSlowQueryUserRepository(repository.NewPostgresUserRepository(pgxTxExecutor), slowQueryLog)
+	rfrTokenRps := repository.NewSlowQueryRefreshTokenRepository(repository.NewPostgresRefreshTokenRepository(pgxTxExecutor), slowQueryLog)
+	pgCustomerRps := repository.NewSlowQueryCustomerRepository(repository.NewPostgresCustomerRepository(pgxTxExecutor, cfg.CustomersCfg.EmailUniqueness, &cfg.RepositoryCfg), slowQueryLog)
+	mongoCustomerRps := repository.NewSlowQueryCustomerRepository(repository.NewMongoCustomerRepository(mongoTxExecutor, cfg.CustomersCfg.EmailUniqueness), slowQueryLog)
+	webhookRps := repository.NewPostgresWebhookRepository(pgPool)
+	activityRps := repository.NewMongoCustomerActivityRepository(mongoClient)
+	eventOutboxRps := repository.NewPostgresEventOutboxRepository(pgPool)
+	if cfg.RepositoryBreakerCfg.FailureThreshold > 0 {
+		pgCustomerRps = repository.NewCircuitBreakerCustomerRepository("postgres", pgCustomerRps, prometheus.DefaultRegisterer, &cfg.RepositoryBreakerCfg)
+		mongoCustomerRps = repository.NewCircuitBreakerCustomerRepository("mongo", mongoCustomerRps, prometheus.DefaultRegisterer, &cfg.RepositoryBreakerCfg)
+	}
+	customerSearchRps := repository.NewPostgresCustomerSearchRepository(pgPool)
+	if cfg.CustomersCfg.V1Backend == config.CustomersBackendMongo {
+		customerSearchRps = repository.NewMongoCustomerSearchRepository(mongoClient)
+	}
+	var esCustomerRps *repository.ElasticsearchCustomerRepository
+	if cfg.ElasticsearchCfg.URL != "" {
+		esCustomerRps = repository.NewElasticsearchCustomerRepository(&http.Client{}, &cfg.ElasticsearchCfg)
+		if err := connectWithRetry(context.Background(), cfg.StartupCfg, "elasticsearch", esCustomerRps.CreateIndex); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	// Storages
+	imageStorage := storage.NewFilesystemImageStorage(imagesRoot)
+	imageMetaStore, err := storage.NewFilesystemImageMetadataStore(imagesRoot)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// emails are sent in background, so requests never wait for SMTP server
+	emailSender, err := newEmailSender(&cfg.SMTPCfg)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	emailQueue := email.NewQueue(emailSender, &cfg.SMTPCfg, prometheus.DefaultRegisterer)
 
 	// Services
-	authSvc := service.NewAuthService(jwtIssuer, rfrTokenCfg, pgxTransactor, userRps, rfrTokenRps)
-	customerSvcV1 := service.NewCustomerService(pgCustomerRps, redisCustomerCache)
-	customerSvcV2 := service.NewCustomerService(mongoCustomerRps, redisStreamCustomerCache)
+	authSvc := service.NewAuthService(
+		jwtIssuer,
+		jwtValidator,
+		tokenRevoker,
+		&cfg.RefreshTokenCfg,
+		&cfg.SignupCfg,
+		pgxTransactor,
+		userRps,
+		rfrTokenRps,
+		audit.NewLogger(auditLog),
+		emailQueue,
+	)
+	customerBackends := map[config.CustomersBackend]service.CustomerBackend{
+		config.CustomersBackendPostgres: {
+			Repository:             pgCustomerRps,
+			Cache:                  redisCustomerCache,
+			CachePopulationFailure: cfg.RedisCfg.PopulationFailure,
+		},
+		config.CustomersBackendMongo: {
+			Repository:             mongoCustomerRps,
+			Cache:                  redisStreamCustomerCache,
+			CachePopulationFailure: cfg.RedisCfg.PopulationFailure,
+			Transactor:             mongoTransactor,
+		},
+	}
+	customerSvcFactory := service.NewCustomerServiceFactory(customerBackends)
+	customerSvcV1, err := customerSvcFactory.Build(cfg.CustomersCfg.V1Backend)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	customerSvcV2, err := customerSvcFactory.Build(cfg.CustomersCfg.V2Backend)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// events are relayed to event backend for consumers outside of service in addition to redis stream consumed internally
+	customerEventPublisher := event.NewRedisStreamPublisher(redisClient)
+	var outboxPublisher *event.OutboxPublisher
+	var outboxRelayInterval time.Duration
+	switch cfg.EventBackend {
+	case config.EventBackendKafka:
+		kafkaWriter, err := newKafkaWriter(cfg.KafkaCfg)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer kafkaWriter.Close()
+
+		outboxPublisher = event.NewOutboxPublisher(event.NewKafkaPublisher(kafkaWriter), eventOutboxRps)
+		outboxRelayInterval = cfg.KafkaCfg.OutboxRelayInterval
+	case config.EventBackendNats:
+		outboxPublisher = event.NewOutboxPublisher(event.NewJetStreamPublisher(jetStream, cfg.NatsCfg.Subject, cfg.NatsCfg.PublishTimeout), eventOutboxRps)
+		outboxRelayInterval = cfg.NatsCfg.OutboxRelayInterval
+	}
+	if outboxPublisher != nil {
+		customerEventPublisher = event.NewMultiPublisher(customerEventPublisher, outboxPublisher)
+	}
+	customerSvcV1 = service.NewEventPublishingCustomerService(customerSvcV1, customerEventPublisher)
+	customerSvcV2 = service.NewEventPublishingCustomerService(customerSvcV2, customerEventPublisher)
+	sessionSvc := service.NewSessionService(rfrTokenRps)
+	cacheSvc := service.NewCacheService(customerCachePurgers...)
+	customerSearchSvc := service.NewCustomerSearchService(customerSearchRps, nil)
+	var customerReindexSvc service.CustomerReindexService
+	var searchIndexer *search.Indexer
+	if esCustomerRps != nil { // datastore search is used while elasticsearch is unavailable
+		v1CustomerRps := customerBackends[cfg.CustomersCfg.V1Backend].Repository
+		searchIndexer = search.NewIndexer(esCustomerRps, v1CustomerRps)
+		customerSearchSvc = service.NewIndexedCustomerSearchService(esCustomerRps, customerSearchRps, v1CustomerRps)
+		customerReindexSvc = service.NewCustomerReindexService(searchIndexer)
+		customerSvcV1 = service.NewSearchIndexingCustomerService(customerSvcV1, esCustomerRps)
+		if cfg.CustomersCfg.V2Backend == cfg.CustomersCfg.V1Backend { // index mirrors datastore of v1 customers
+			customerSvcV2 = service.NewSearchIndexingCustomerService(customerSvcV2, esCustomerRps)
+		}
+	}
+	// strict import is run within transaction of datastore v1 customers are kept in
+	importTransactors := map[config.CustomersBackend]transactor.Transactor{
+		config.CustomersBackendPostgres: pgxTransactor,
+		config.CustomersBackendMongo:    mongoTransactor,
+	}
+	customerImportSvc := service.NewCustomerImportService(customerSvcV1, importTransactors[cfg.CustomersCfg.V1Backend])
+	webhookSvc := service.NewWebhookService(webhookRps)
+	activitySvc := service.NewCustomerActivityService(activityRps, &cfg.ActivityCfg)
 
 	// HTTP Handlers
 	authHTTPHandler := handlers.NewAuthHTTPHandler(authSvc)
-	customerHTTPHandlerV1 := handlers.NewCustomerHTTPHandler(customerSvcV1)
-	customerHTTPHandlerV2 := handlers.NewCustomerHTTPHandler(customerSvcV2)
-	imageHandler := handlers.NewImageHTTPHandler()
+	customerHTTPHandlerV1 := handlers.NewCustomerHTTPHandler(customerSvcV1, cfg.HTTPCfg.ListEnvelope)
+	customerHTTPHandlerV2 := handlers.NewCustomerHTTPHandlerV2(customerSvcV2, cfg.HTTPCfg.ListEnvelope)
+	customerSearchHandler := handlers.NewCustomerSearchHTTPHandler(customerSearchSvc, customerReindexSvc)
+	customerImportHandler := handlers.NewCustomerImportHTTPHandler(customerImportSvc, &cfg.ImportCfg)
+	imageHandler := handlers.NewImageHTTPHandler(imageStorage, imageMetaStore, &cfg.ImagesCfg)
+	adminHandler := handlers.NewAdminHTTPHandler(runtimeCfg, featureFlags, sessionSvc, cacheSvc)
+	webhookHandler := handlers.NewWebhookHTTPHandler(webhookSvc)
+	activityHandler := handlers.NewCustomerActivityHTTPHandler(activitySvc)
+	customerEventBroker := event.NewBroker(customerWatchBufferSize)
+	customerEventReader := event.NewRedisStreamReader(redisClient)
+	customerEventsHandler := handlers.NewCustomerEventsHTTPHandler(customerEventBroker, customerEventReader, cfg.HTTPCfg.EventsKeepAlive)
+	customerWsHandler := handlers.NewCustomerWebSocketHandler(customerSvcV1, customerEventBroker, jwtValidator, tokenRevoker, &cfg.WebSocketCfg)
+	e.Server.RegisterOnShutdown(customerWsHandler.Close) // hijacked connections are not closed by server shutdown
 
 	// gRPC Handlers
 	authGrpcHandler := handlers.NewAuthGrpcHandler(authSvc)
-	customerGrpcHandler := handlers.NewCustomerGrpcHandler(customerSvcV1)
+	customerGrpcHandler := handlers.NewCustomerGrpcHandler(customerSvcV1, customerEventBroker)
+	imageGrpcHandler := handlers.NewImageGrpcHandler(imageStorage, imageMetaStore, &cfg.ImagesCfg)
 
 	// interceptors
-	authInterceptor := interceptors.AuthUnaryInterceptor(jwtValidator, interceptors.UnaryApplicableForService("CustomerService"))
+	authInterceptor := interceptors.AuthUnaryInterceptor(jwtValidator, tokenRevoker, interceptors.UnaryApplicableForService("CustomerService"))
 	validatorInterceptor := interceptors.ValidatorUnaryInterceptor(true)
+	tenantInterceptor := interceptors.TenantUnaryInterceptor(interceptors.UnaryApplicableForService("CustomerService"))
 	errorInterceptor := interceptors.ErrorUnaryInterceptor()
+	clientIPInterceptor := interceptors.ClientIPUnaryInterceptor()
+	requestIDInterceptor := interceptors.RequestIDUnaryInterceptor(cfg.RequestIDHeader)
+	requestIDStreamInterceptor := interceptors.RequestIDStreamInterceptor(cfg.RequestIDHeader)
+	authStreamInterceptor := interceptors.AuthStreamInterceptor(jwtValidator, tokenRevoker, interceptors.StreamApplicableForAnyService("ImageService", "CustomerService"))
+	tenantStreamInterceptor := interceptors.TenantStreamInterceptor(interceptors.StreamApplicableForService("CustomerService"))
+	errorStreamInterceptor := interceptors.ErrorStreamInterceptor()
+	inFlightGauge := interceptors.NewInFlightGauge(prometheus.DefaultRegisterer)
+	inFlightInterceptor := interceptors.InFlightUnaryInterceptor(inFlightGauge)
+	inFlightStreamInterceptor := interceptors.InFlightStreamInterceptor(inFlightGauge)
+	recoveryInterceptor := interceptors.RecoveryUnaryInterceptor()
+	recoveryStreamInterceptor := interceptors.RecoveryStreamInterceptor()
 
 	images := e.Group("/images")
-	images.POST("/upload", imageHandler.Upload)
-	images.GET("/:name/download", imageHandler.Download)
-	images.Use(echoMw.StaticWithConfig(echoMw.StaticConfig{
-		Root:   "images",
-		Browse: true,
-	}))
+	images.GET("", imageHandler.List, middleware.Feature(featureFlags, feature.ImagesList), authorizeMw)
+	images.POST("/upload", imageHandler.Upload, authorizeMw)
+	images.DELETE("/:name", imageHandler.Delete, middleware.Feature(featureFlags, feature.ImagesDelete), authorizeMw)
+	if cfg.ImagesCfg.PublicDownloads { // e.g. avatars embedded in emails
+		images.GET("/:name/download", imageHandler.Download)
+	} else {
+		images.GET("/:name/download", imageHandler.Download, authorizeMw)
+	}
+	if cfg.ImagesCfg.SignedURLKey != "" { // e.g. private images embedded into pages
+		images.POST("/:name/signed-url", imageHandler.SignedURL, authorizeMw)
+		images.GET("/:name/signed-download", imageHandler.SignedDownload)
+	}
 
 	// API routes
 	api := e.Group("/api")
@@ -196,24 +454,64 @@ func start(
 	apiAuth.POST("/login", authHTTPHandler.Login)
 	apiAuth.POST("/logout", authHTTPHandler.Logout)
 	apiAuth.POST("/refresh", authHTTPHandler.Refresh)
+	apiAuth.POST("/rotate", authHTTPHandler.Rotate)
+	apiAuth.POST("/introspect", authHTTPHandler.Introspect, authorizeMw)
+
+	// admin
+	apiAdmin := api.Group("/admin")
+	apiAdmin.POST("/reload", adminHandler.Reload, authorizeMw, adminMw)
+	apiAdmin.GET("/sessions", adminHandler.Sessions, authorizeMw, adminMw)
+	apiAdmin.POST("/cache/purge", adminHandler.PurgeCache, authorizeMw, adminMw)
+	if customerReindexSvc != nil {
+		apiAdmin.POST("/search/reindex", customerSearchHandler.Reindex, authorizeMw, adminMw, tenantMw)
+	}
+	apiAdmin.POST("/webhooks", webhookHandler.Post, authorizeMw, adminMw, tenantMw)
+	apiAdmin.GET("/webhooks", webhookHandler.GetAll, authorizeMw, adminMw, tenantMw)
+	apiAdmin.DELETE("/webhooks/:id", webhookHandler.DeleteByID, authorizeMw, adminMw, tenantMw)
+	apiAdmin.GET("/webhooks/:id/deliveries", webhookHandler.Deliveries, authorizeMw, adminMw, tenantMw)
+	apiAdmin.GET("/customers/:id/recent-activity", activityHandler.RecentActivity, authorizeMw, adminMw, tenantMw)
+
+	// customers, middlewares are set per route as group middlewares make router respond 404 instead of 405 for disallowed methods
+	customerMw := []echo.MiddlewareFunc{authorizeMw, tenantMw}
 
 	// customers v1
-	apiCustomersV1 := api.Group("/v1/customers", authorizeMw)
-	apiCustomersV1.GET("", customerHTTPHandlerV1.GetAll)
-	apiCustomersV1.GET("/:id", customerHTTPHandlerV1.Get)
-	apiCustomersV1.POST("", customerHTTPHandlerV1.Post)
-	apiCustomersV1.PUT("/:id", customerHTTPHandlerV1.Put)
-	apiCustomersV1.DELETE("/:id", customerHTTPHandlerV1.DeleteByID)
+	apiCustomersV1 := api.Group("/v1/customers")
+	apiCustomersV1.GET("", customerHTTPHandlerV1.GetAll, customerMw...)
+	apiCustomersV1.GET("/events", customerEventsHandler.Stream, customerMw...)
+	apiCustomersV1.GET("/search", customerSearchHandler.Search, customerMw...)
+	apiCustomersV1.POST("/import.csv", customerImportHandler.ImportCSV, customerMw...)
+	apiCustomersV1.GET("/ws", customerWsHandler.Stream) // authenticated by handler, token may be passed in the first frame
+	apiCustomersV1.HEAD("", handlers.HeadHandler(customerHTTPHandlerV1.GetAll), customerMw...)
+	apiCustomersV1.GET("/:id", customerHTTPHandlerV1.Get, customerMw...)
+	apiCustomersV1.HEAD("/:id", handlers.HeadHandler(customerHTTPHandlerV1.Get), customerMw...)
+	apiCustomersV1.POST("", customerHTTPHandlerV1.Post, customerMw...)
+	apiCustomersV1.PUT("/:id", customerHTTPHandlerV1.Put, customerMw...)
+	apiCustomersV1.PATCH("/:id", customerHTTPHandlerV1.Patch, customerMw...)
+	apiCustomersV1.DELETE("/:id", customerHTTPHandlerV1.DeleteByID, customerMw...)
+	apiCustomersV1.POST("/bulk-update", customerHTTPHandlerV1.BulkUpdate, append(customerMw, adminMw)...)
 
 	// customers v2
-	apiCustomersV2 := api.Group("/v2/customers", authorizeMw)
-	apiCustomersV2.GET("", customerHTTPHandlerV2.GetAll)
-	apiCustomersV2.GET("/:id", customerHTTPHandlerV2.Get)
-	apiCustomersV2.POST("", customerHTTPHandlerV2.Post)
-	apiCustomersV2.PUT("/:id", customerHTTPHandlerV2.Put)
-	apiCustomersV2.DELETE("/:id", customerHTTPHandlerV2.DeleteByID)
+	apiCustomersV2 := api.Group("/v2/customers")
+	apiCustomersV2.GET("", customerHTTPHandlerV2.GetAll, customerMw...)
+	apiCustomersV2.HEAD("", handlers.HeadHandler(customerHTTPHandlerV2.GetAll), customerMw...)
+	apiCustomersV2.GET("/:id", customerHTTPHandlerV2.Get, customerMw...)
+	apiCustomersV2.HEAD("/:id", handlers.HeadHandler(customerHTTPHandlerV2.Get), customerMw...)
+	apiCustomersV2.POST("", customerHTTPHandlerV2.Post, customerMw...)
+	apiCustomersV2.PUT("/:id", customerHTTPHandlerV2.Put, customerMw...)
+	apiCustomersV2.PATCH("/:id", customerHTTPHandlerV2.Patch, customerMw...)
+	apiCustomersV2.DELETE("/:id", customerHTTPHandlerV2.DeleteByID, customerMw...)
+	apiCustomersV2.POST("/bulk-update", customerHTTPHandlerV2.BulkUpdate, append(customerMw, adminMw)...)
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	healthHandler := handlers.NewHealthHTTPHandler(map[string]handlers.HealthCheck{
+		"postgres": pgPool.Ping,
+		"redis":    func(ctx context.Context) error { return redisClient.Ping(ctx).Err() },
+		"mongo":    func(ctx context.Context) error { return mongoClient.Ping(ctx, nil) },
+	})
+	e.GET("/health/live", healthHandler.Live)
+	e.GET("/health/ready", healthHandler.Ready)
 
 	shutdownCh := make(chan os.Signal, 1)
 	errorCh := make(chan error, 1)
@@ -236,14 +534,28 @@ func start(
 
 	grpcSvc := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
+			inFlightInterceptor,
+			requestIDInterceptor,
+			recoveryInterceptor,
+			clientIPInterceptor,
 			authInterceptor,
+			tenantInterceptor,
 			validatorInterceptor,
 			errorInterceptor,
 		),
+		grpc.ChainStreamInterceptor(
+			inFlightStreamInterceptor,
+			requestIDStreamInterceptor,
+			recoveryStreamInterceptor,
+			authStreamInterceptor,
+			tenantStreamInterceptor,
+			errorStreamInterceptor,
+		),
 	)
 
 	proto.RegisterAuthServiceServer(grpcSvc, authGrpcHandler)
 	proto.RegisterCustomerServiceServer(grpcSvc, customerGrpcHandler)
+	proto.RegisterImageServiceServer(grpcSvc, imageGrpcHandler)
 
 	go func() {
 		logrus.Infof("Starting gRPC server at port :%d", grpcPort)
@@ -256,7 +568,64 @@ func start(
 	// start redis steam listen loop
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go readCustomersStream(ctx, redisClient, inMemoryCustomerCache)
+	go customerStreamReader.Listen(ctx)
+	go customerStreamReader.MonitorLag(ctx)
+	go emailQueue.Run(ctx)
+
+	poolMetrics := repository.NewPoolMetricsCollector(map[string]repository.PoolStatsSource{
+		"postgres": repository.PgxPoolStats(pgPool),
+		"mongo":    mongoPoolMonitor,
+	}, prometheus.DefaultRegisterer, &cfg.PoolMetricsCfg)
+	go poolMetrics.Run(ctx)
+
+	if cfg.CacheWarmUpCfg.Enabled {
+		go warmUpCustomerCaches(ctx, customerBackends, cfg)
+	}
+
+	// record deliveries of customer events to webhooks, each event is recorded by single instance,
+	// while every instance attempts due deliveries claimed by it
+	webhookDispatcher := webhook.NewDispatcher(webhookRps, &http.Client{}, &cfg.WebhooksCfg)
+	webhookConsumer := event.NewRedisStreamConsumer(redisClient, webhooksConsumerGroup, instanceID(cfg.LogCfg))
+	go webhookDispatcher.Run(ctx)
+	go func() {
+		if err := webhookConsumer.Consume(ctx, webhookDispatcher.Dispatch); err != nil {
+			logrus.Errorf("failed to consume customer events for webhooks - %v", err)
+		}
+	}()
+
+	// mirror customer events to recent activity, each event is appended by single instance
+	activityConsumer := event.NewRedisStreamConsumer(redisClient, activityConsumerGroup, instanceID(cfg.LogCfg))
+	go func() {
+		if err := activityConsumer.Consume(ctx, activityRps.Append); err != nil {
+			logrus.Errorf("failed to consume customer events for recent activity - %v", err)
+		}
+	}()
+
+	// keep search index in sync with customers, each event is indexed by single instance
+	if searchIndexer != nil {
+		searchIndexerConsumer := event.NewRedisStreamConsumer(redisClient, searchIndexerConsumerGroup, instanceID(cfg.LogCfg))
+		go func() {
+			if err := searchIndexerConsumer.Consume(ctx, searchIndexer.Index); err != nil {
+				logrus.Errorf("failed to consume customer events for search index - %v", err)
+			}
+		}()
+	}
+
+	if outboxPublisher != nil {
+		go outboxPublisher.Relay(ctx, outboxRelayInterval)
+	}
+
+	// every instance reads all customer events once to fan them out to its gRPC watchers, event streams and websockets
+	go func() {
+		if err := customerEventReader.Read(ctx, customerEventBroker.PublishStream); err != nil {
+			logrus.Errorf("failed to read customer events for watchers - %v", err)
+		}
+	}()
+
+	// reload runtime config and feature flags on SIGHUP
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	go reloadConfig(ctx, reloadCh, runtimeCfg, featureFlags)
 
 	select {
 	case <-shutdownCh:
@@ -278,146 +647,325 @@ func start(
 	}
 }
 
-func mongodb(ctx context.Context, uri string) (*mongo.Client, error) {
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+func reloadConfig(ctx context.Context, reloadCh <-chan os.Signal, runtimeCfg *config.RuntimeHolder, flags *feature.Flags) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reloadCh:
+			if _, err := runtimeCfg.Reload(); err != nil {
+				logrus.Errorf("failed to reload runtime config - %v", err)
+			} else {
+				logrus.Info("runtime config has been reloaded")
+			}
+
+			if err := flags.Reload(); err != nil {
+				logrus.Errorf("failed to reload feature flags - %v", err)
+				continue
+			}
+			logrus.Info("feature flags have been reloaded")
+		}
+	}
+}
+
+// warmUpCustomerCaches fills caches of customer backends used by api versions, failure is logged only since cache is filled on reads anyway
+func warmUpCustomerCaches(ctx context.Context, backends map[config.CustomersBackend]service.CustomerBackend, cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(ctx, serverStartupTimeout)
+	defer cancel()
+
+	usedBackends := []config.CustomersBackend{cfg.CustomersCfg.V1Backend}
+	if cfg.CustomersCfg.V2Backend != cfg.CustomersCfg.V1Backend {
+		usedBackends = append(usedBackends, cfg.CustomersCfg.V2Backend)
+	}
+
+	for _, backend := range usedBackends {
+		cached, err := service.WarmUpCustomerCache(ctx, backends[backend], &cfg.CacheWarmUpCfg)
+		if err != nil {
+			logrus.Warnf("failed to warm up %s customers cache, %d customers are cached - %v", backend, cached, err)
+			continue
+		}
+		logrus.Infof("%s customers cache is warmed up with %d customers", backend, cached)
+	}
+}
+
+// auditLogger builds separate logger for auth events, so they can be shipped independently of application logs
+func auditLogger(path string, fieldsHook logrus.Hook) (*logrus.Logger, error) {
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(fieldsHook)
+	if path == "" {
+		return logger, nil
+	}
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, auditLogFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file - %w", err)
+	}
+	logger.SetOutput(f)
+
+	return logger, nil
+}
+
+// connectWithRetry calls connect until dependency is ready or attempts are exhausted, each attempt is limited by startup timeout
+func connectWithRetry(ctx context.Context, cfg config.StartupCfg, dependency string, connect func(context.Context) error) error {
+	backoff := retry.Backoff{Attempts: cfg.ConnectAttempts, Interval: cfg.ConnectInterval, MaxInterval: cfg.ConnectMaxInterval}
+
+	attempt := 0
+	err := retry.Do(ctx, backoff, func(ctx context.Context) error {
+		attempt++
+		logrus.Infof("connecting to %s, attempt %d of %d", dependency, attempt, cfg.ConnectAttempts)
+
+		ctx, cancel := context.WithTimeout(ctx, serverStartupTimeout)
+		defer cancel()
+		return connect(ctx)
+	}, func(attempt int, err error) {
+		logrus.Warnf("failed to connect to %s on attempt %d of %d - %v", dependency, attempt, cfg.ConnectAttempts, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s - %w", dependency, err)
+	}
+	return nil
+}
+
+func mongodb(
+	ctx context.Context,
+	cfg config.MongoCfg,
+	activityCfg config.ActivityCfg,
+	emailUniqueness config.EmailUniqueness,
+	poolMonitor *mongoevent.PoolMonitor,
+) (*mongo.Client, error) {
+	opts := options.Client().ApplyURI(cfg.ConnString).SetPoolMonitor(poolMonitor)
+	if cfg.TLS.Enabled {
+		tlsCfg, err := newTLSConfig(cfg.TLS, "")
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	}
+
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, err
+	}
+
+	if err := repository.MigrateMongoCustomers(ctx, client, emailUniqueness); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, err
+	}
+
+	if err := repository.MigrateMongoCustomerActivity(ctx, client, activityCfg.CollectionSize); err != nil {
+		_ = client.Disconnect(ctx)
 		return nil, err
 	}
 	return client, nil
 }
 
-func postgresql(ctx context.Context, uri string) (*pgxpool.Pool, error) {
+func postgresql(ctx context.Context, uri string, emailUniqueness config.EmailUniqueness) (*pgxpool.Pool, error) {
 	pool, err := pgxpool.Connect(ctx, uri)
 	if err != nil {
 		return nil, fmt.Errorf("failed to establish connection to db - %w", err)
 	}
 
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("didn't get response from database after sending ping request - %w", err)
 	}
+
+	if err := repository.VerifyPostgresCustomers(ctx, pool, emailUniqueness); err != nil {
+		pool.Close()
+		return nil, err
+	}
 	return pool, nil
 }
 
-func redisClient(ctx context.Context, cfg config.RedisCfg) (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
+func newRedisClient(ctx context.Context, cfg config.RedisCfg) (*redis.Client, error) {
+	opts := &redis.Options{
 		Addr:       cfg.Addr,
+		Username:   cfg.Username,
 		Password:   cfg.Password,
 		DB:         cfg.DB,
 		MaxRetries: cfg.MaxRetries,
 		PoolSize:   cfg.PoolSize,
-	})
+	}
+
+	if cfg.TLS.Enabled {
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis address %s - %w", cfg.Addr, err)
+		}
+
+		opts.TLSConfig, err = newTLSConfig(cfg.TLS, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := redis.NewClient(opts)
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
 		return nil, fmt.Errorf("didn't get response from redis after sending ping request - %w", err)
 	}
 	return client, nil
 }
 
-func setupLogger() {
-	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetOutput(os.Stdout)
-	logrus.SetReportCaller(true)
+// newKafkaWriter builds writer to customer events topic, messages with the same key are written to the same partition.
+// Outbox relays events one by one, so batch is flushed shortly instead of waiting for default second per event
+func newKafkaWriter(cfg config.KafkaCfg) (*kafka.Writer, error) {
+	transport := &kafka.Transport{}
+
+	if cfg.TLS.Enabled {
+		tlsCfg, err := newTLSConfig(cfg.TLS, "") // server name is taken from broker address
+		if err != nil {
+			return nil, err
+		}
+		transport.TLS = tlsCfg
+	}
+
+	switch cfg.SASLMechanism {
+	case config.KafkaSASLPlain:
+		transport.SASL = plain.Mechanism{Username: cfg.SASLUsername, Password: cfg.SASLPassword}
+	case config.KafkaSASLScramSHA256, config.KafkaSASLScramSHA512:
+		algo := scram.SHA256
+		if cfg.SASLMechanism == config.KafkaSASLScramSHA512 {
+			algo = scram.SHA512
+		}
+
+		mechanism, err := scram.Mechanism(algo, cfg.SASLUsername, cfg.SASLPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build kafka scram mechanism - %w", err)
+		}
+		transport.SASL = mechanism
+	}
+
+	return &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: kafkaBatchTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		Transport:    transport,
+	}, nil
 }
 
-func echoValidator() (echo.Validator, error) {
-	v := validator.New()
+// newJetStream connects to NATS and creates customer events and cache streams unless they exist. Events stream drops
+// events with the same id within duplicate window, cache stream keeps only recent operations as they are applied once read
+func newJetStream(cfg config.NatsCfg) (*nats.Conn, nats.JetStreamContext, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name("customers"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to nats - %w", err)
+	}
 
-	// store json tag fields, so can be handled on UI properly in struct PayloadErr -> field Field
-	v.RegisterTagNameFunc(func(field reflect.StructField) string {
-		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
-		if jsonName == "" || jsonName == "-" {
-			return field.Name
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to build jetstream context - %w", err)
+	}
+
+	streams := []*nats.StreamConfig{
+		{Name: cfg.Stream, Subjects: []string{cfg.Subject}, Storage: nats.FileStorage, Duplicates: cfg.DuplicateWindow},
+		{Name: cfg.CacheStream, Subjects: []string{cfg.CacheSubject}, Storage: nats.MemoryStorage, MaxMsgs: natsCacheStreamMaxMsgs},
+	}
+	for _, stream := range streams {
+		if _, err := js.StreamInfo(stream.Name); err == nil {
+			continue
 		}
-		return jsonName
-	})
 
-	enLocale := en.New()
-	unvTranslator := ut.New(enLocale, enLocale)
-	trans, ok := unvTranslator.GetTranslator("en")
-	if !ok {
-		return nil, errors.New("failed to find translator for en locale")
+		if _, err := js.AddStream(stream); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to create jetstream stream %s - %w", stream.Name, err)
+		}
 	}
 
-	// register default translations
-	if err := enTrans.RegisterDefaultTranslations(v, trans); err != nil {
-		return nil, fmt.Errorf("failed to register en translations - %w", err)
+	return conn, js, nil
+}
+
+// newEmailSender builds email sender, connection to SMTP server is established over TLS if it is enabled
+func newEmailSender(cfg *config.SMTPCfg) (email.Sender, error) {
+	if !cfg.TLS.Enabled {
+		return email.NewSender(cfg, nil), nil
 	}
 
-	return validation.Echo(v, trans), nil
+	tlsCfg, err := newTLSConfig(cfg.TLS, cfg.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build smtp tls config - %w", err)
+	}
+	return email.NewSender(cfg, tlsCfg), nil
 }
 
-func readCustomersStream(ctx context.Context, client *redis.Client, customerCache cache.CustomerCacheRepository) {
-	key := "$"
-	logrus.Info("starting to read customers redis stream")
+// newTLSConfig builds client TLS config trusting CA from file if provided, otherwise system root CAs are trusted
+func newTLSConfig(cfg config.TLSCfg, serverName string) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
 
-XRead:
-	for {
-		select {
-		case <-ctx.Done():
-			break XRead
-		default:
-			logrus.Infof("waiting for new messages starting from %s", key)
-			streams, err := client.XRead(ctx, &redis.XReadArgs{
-				Streams: []string{"customers-stream", key},
-				Count:   readStreamMessagesMaxCount,
-				Block:   readStreamBlockTime,
-			}).Result()
-			if err != nil {
-				logrus.Errorf("error occurred on reading message from stream - %v", err)
-				continue
-			}
+	if cfg.CAFile == "" {
+		return tlsCfg, nil
+	}
 
-			logrus.Info("messages were received")
+	caPEM, err := os.ReadFile(filepath.Clean(cfg.CAFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file - %w", err)
+	}
 
-			for _, stream := range streams {
-				for _, m := range stream.Messages {
-					logrus.Info("number of message received = ", len(stream.Messages))
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no valid certificates found in CA file %s", cfg.CAFile)
+	}
+	tlsCfg.RootCAs = roots
 
-					key = m.ID
-					if err := processStreamMessage(ctx, customerCache, m); err != nil {
-						logrus.Errorf("error occurred on message %s processing - %v", key, err)
-					}
-				}
-			}
-		}
+	return tlsCfg, nil
+}
+
+// logFields returns fields attached to every log entry, hostname identifies instance if instance id isn't configured
+func logFields(cfg config.LogCfg) logrus.Fields {
+	return logrus.Fields{
+		"service":     cfg.ServiceName,
+		"environment": cfg.Environment,
+		"instance":    instanceID(cfg),
 	}
 }
 
-func processStreamMessage(ctx context.Context, customerCache cache.CustomerCacheRepository, m redis.XMessage) error {
-	op, ok := m.Values["op"].(string)
-	if !ok || op == "" {
-		return errors.New("message has incorrect format - op field is missing, skipped")
+// instanceID returns configured instance id or hostname if it is not configured
+func instanceID(cfg config.LogCfg) string {
+	if cfg.InstanceID != "" {
+		return cfg.InstanceID
 	}
 
-	value, ok := m.Values["value"].(string)
-	if !ok {
-		return errors.New("message has incorrect format - value field is missing, skipped")
+	hostname, err := os.Hostname()
+	if err != nil {
+		logrus.Warnf("failed to resolve hostname for instance id - %v", err)
 	}
+	return hostname
+}
 
-	logrus.Infof("%s operation is requested", op)
+func setupLogger() {
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetOutput(os.Stdout)
+	logrus.SetReportCaller(true)
+}
 
-	writeCtx, cancel := context.WithTimeout(ctx, cacheWriteTimeout)
-	defer cancel()
+func echoValidator() (*validation.EchoValidator, error) {
+	v := validator.New()
 
-	switch op {
-	case "create":
-		var c model.Customer
-		if err := msgpack.Unmarshal([]byte(value), &c); err != nil {
-			return fmt.Errorf("failed to deserialize customer - %w", err)
-		}
+	// store json tag fields, so can be handled on UI properly in struct PayloadErr -> field Field
+	v.RegisterTagNameFunc(validation.JSONTagName)
 
-		if err := customerCache.Create(writeCtx, &c); err != nil {
-			return fmt.Errorf("failed to create customer entry in cache - %w", err)
-		}
-	case "delete":
-		if err := customerCache.DeleteByID(writeCtx, value); err != nil {
-			return fmt.Errorf("failed to delete customer entry from cache - %w", err)
-		}
+	// reject blank names and excluded combinations of values on customer payloads
+	handlers.RegisterCustomerValidation(v)
+
+	// register translations for supported locales, message locale is negotiated via Accept-Language
+	uni, err := validation.Translations(v)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return validation.Echo(v, uni), nil
 }
@@ -0,0 +1,12 @@
+-- customers email is unique across tenants only if CUSTOMERS_EMAIL_UNIQUENESS is global, its value is passed to flyway
+-- as customersEmailUniqueness placeholder. Migration is repeatable and flyway checksums it with placeholders replaced,
+-- so it is re-applied once the value changes
+DO $$
+BEGIN
+    IF '${customersEmailUniqueness}' = 'global' THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS CUSTOMERS_EMAIL_UIDX ON CUSTOMERS(EMAIL);
+    ELSE
+        DROP INDEX IF EXISTS CUSTOMERS_EMAIL_UIDX;
+    END IF;
+END
+$$;
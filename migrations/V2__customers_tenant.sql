@@ -0,0 +1,3 @@
+ALTER TABLE CUSTOMERS ADD COLUMN IF NOT EXISTS TENANT_ID VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE UNIQUE INDEX IF NOT EXISTS CUSTOMERS_TENANT_EMAIL_UIDX ON CUSTOMERS(TENANT_ID, EMAIL);
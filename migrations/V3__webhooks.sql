@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS WEBHOOKS(
+    ID UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    TENANT_ID VARCHAR(64) NOT NULL,
+    URL VARCHAR(2000) NOT NULL,
+    SECRET VARCHAR(256) NOT NULL,
+    EVENT_TYPES TEXT[] NOT NULL,
+    ACTIVE BOOLEAN NOT NULL DEFAULT TRUE,
+    CREATED_AT TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS WEBHOOKS_TENANT_IDX ON WEBHOOKS(TENANT_ID);
+
+CREATE TABLE IF NOT EXISTS WEBHOOK_DELIVERIES(
+    ID UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    WEBHOOK_ID UUID NOT NULL REFERENCES WEBHOOKS(ID) ON DELETE CASCADE,
+    EVENT_ID UUID NOT NULL,
+    EVENT_TYPE VARCHAR(64) NOT NULL,
+    STATUS VARCHAR(16) NOT NULL,
+    ATTEMPTS INT NOT NULL DEFAULT 0,
+    LAST_STATUS_CODE INT NOT NULL DEFAULT 0,
+    LAST_ERROR TEXT NOT NULL DEFAULT '',
+    CREATED_AT TIMESTAMP WITH TIME ZONE NOT NULL,
+    UPDATED_AT TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS WEBHOOK_DELIVERIES_WEBHOOK_IDX ON WEBHOOK_DELIVERIES(WEBHOOK_ID, CREATED_AT DESC);
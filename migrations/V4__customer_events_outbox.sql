@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS CUSTOMER_EVENTS_OUTBOX(
+    SEQ BIGSERIAL PRIMARY KEY,
+    EVENT_ID UUID NOT NULL UNIQUE,
+    PAYLOAD JSONB NOT NULL,
+    CREATED_AT TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS CUSTOMERS_TENANT_ACTIVE_IDX ON CUSTOMERS(TENANT_ID, ID) WHERE INACTIVE = FALSE;
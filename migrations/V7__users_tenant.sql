@@ -0,0 +1 @@
+ALTER TABLE USERS ADD COLUMN IF NOT EXISTS TENANT_ID VARCHAR(64) NOT NULL DEFAULT 'default';
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS CUSTOMERS_TENANT_IMPORTANCE_IDX ON CUSTOMERS(TENANT_ID, IMPORTANCE DESC, ID);
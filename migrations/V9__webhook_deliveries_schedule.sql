@@ -0,0 +1,9 @@
+-- deliveries which were in flight before redelivery was scheduled have no payload to post, so they are given up
+UPDATE WEBHOOK_DELIVERIES SET STATUS = 'dead', LAST_ERROR = 'delivery was interrupted' WHERE STATUS = 'pending';
+
+-- payload is kept as bytes, so the same body is signed and posted on each attempt
+ALTER TABLE WEBHOOK_DELIVERIES ADD COLUMN IF NOT EXISTS PAYLOAD BYTEA NOT NULL DEFAULT ''::BYTEA;
+ALTER TABLE WEBHOOK_DELIVERIES ADD COLUMN IF NOT EXISTS NEXT_ATTEMPT_AT TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE UNIQUE INDEX IF NOT EXISTS WEBHOOK_DELIVERIES_EVENT_IDX ON WEBHOOK_DELIVERIES(WEBHOOK_ID, EVENT_ID);
+CREATE INDEX IF NOT EXISTS WEBHOOK_DELIVERIES_DUE_IDX ON WEBHOOK_DELIVERIES(NEXT_ATTEMPT_AT) WHERE STATUS = 'pending';
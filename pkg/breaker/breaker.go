@@ -0,0 +1,98 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/sony/gobreaker"
+)
+
+// Settings configure Breaker. Breaker opens after FailureThreshold consecutive failures and lets probe through
+// once Cooldown is over, IsSuccessful decides which errors are not counted as failures
+type Settings struct {
+	Name             string // dependency name, e.g. redis or postgres
+	Kind             string // dependency kind used in logs, e.g. cache or repository
+	FailureThreshold uint32
+	Cooldown         time.Duration
+	IsSuccessful     func(error) bool
+	StateGauge       prometheus.Gauge // state is reported as 0 - closed, 1 - half-open, 2 - open
+}
+
+// Breaker is circuit breaker reporting its state to gauge and remembering when it was opened,
+// so rejected callers can be told when to retry
+type Breaker struct {
+	cb       *gobreaker.CircuitBreaker
+	cooldown time.Duration
+	mu       sync.Mutex
+	openedAt time.Time
+}
+
+// New builds new Breaker
+func New(s Settings) *Breaker {
+	b := &Breaker{cooldown: s.Cooldown}
+	b.cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    s.Name,
+		Timeout: s.Cooldown,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= s.FailureThreshold
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logrus.Warnf("%s %s circuit breaker changed state from %s to %s", name, s.Kind, from, to)
+			s.StateGauge.Set(float64(to))
+			if to == gobreaker.StateOpen {
+				b.opened(time.Now())
+			}
+		},
+		IsSuccessful: s.IsSuccessful,
+	})
+	return b
+}
+
+// Name returns name of dependency protected by breaker
+func (b *Breaker) Name() string {
+	return b.cb.Name()
+}
+
+// Open reports whether calls are short-circuited by open breaker
+func (b *Breaker) Open() bool {
+	return b.cb.State() == gobreaker.StateOpen
+}
+
+// RetryAfter returns time left until open breaker lets probe through, it is zero once cooldown is over
+func (b *Breaker) RetryAfter(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if left := b.openedAt.Add(b.cooldown).Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+func (b *Breaker) opened(at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = at
+}
+
+// Execute calls fn through breaker, rejected call fails with error recognized by IsRejection
+func Execute[T any](b *Breaker, fn func() (T, error)) (T, error) {
+	var zero T
+
+	res, err := b.cb.Execute(func() (any, error) {
+		return fn()
+	})
+	if err != nil {
+		return zero, err
+	}
+	return res.(T), nil
+}
+
+// IsRejection reports if call was rejected by breaker without reaching dependency. Half-open breaker
+// rejects calls while probe is in flight as well
+func IsRejection(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
@@ -0,0 +1,127 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testFailureThreshold = 3
+	testCooldown         = 50 * time.Millisecond
+)
+
+var errIgnored = errors.New("ignored")
+
+type breakerTestSuite struct {
+	suite.Suite
+	gauge   prometheus.Gauge
+	breaker *Breaker
+}
+
+func (s *breakerTestSuite) SetupTest() {
+	s.gauge = prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_breaker_state"})
+	s.breaker = New(Settings{
+		Name:             "test",
+		Kind:             "dependency",
+		FailureThreshold: testFailureThreshold,
+		Cooldown:         testCooldown,
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, errIgnored)
+		},
+		StateGauge: s.gauge,
+	})
+}
+
+func (s *breakerTestSuite) fail(err error) error {
+	_, execErr := Execute(s.breaker, func() (struct{}, error) {
+		return struct{}{}, err
+	})
+	return execErr
+}
+
+func (s *breakerTestSuite) trip() {
+	for i := 0; i < testFailureThreshold; i++ {
+		s.Require().Error(s.fail(errors.New("connection refused")), "failure must be returned while breaker is closed")
+	}
+}
+
+func (s *breakerTestSuite) TestBreakerOpensAndCloses() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("breaker opens once failure threshold is reached")
+	{
+		s.trip()
+		require.True(s.breaker.Open(), "breaker must be open")
+		require.Equal(float64(2), testutil.ToFloat64(s.gauge), "open state must be reported")
+	}
+
+	t.Log("open breaker rejects calls and tells when to retry")
+	{
+		called := false
+		_, err := Execute(s.breaker, func() (int, error) {
+			called = true
+			return 1, nil
+		})
+		require.True(IsRejection(err), "call must be rejected")
+		require.False(called, "rejected call must not reach dependency")
+
+		retryAfter := s.breaker.RetryAfter(time.Now())
+		require.Positive(retryAfter, "remaining open time must be returned")
+		require.LessOrEqual(retryAfter, testCooldown, "remaining open time must not exceed cooldown")
+	}
+
+	t.Log("successful probe after cooldown closes breaker")
+	{
+		time.Sleep(testCooldown + 10*time.Millisecond)
+		require.Zero(s.breaker.RetryAfter(time.Now()), "no wait is expected once cooldown is over")
+
+		res, err := Execute(s.breaker, func() (int, error) {
+			return 1, nil
+		})
+		require.NoError(err, "probe must reach dependency")
+		require.Equal(1, res, "probe result must be returned")
+		require.False(s.breaker.Open(), "breaker must be closed")
+		require.Zero(testutil.ToFloat64(s.gauge), "closed state must be reported")
+	}
+}
+
+func (s *breakerTestSuite) TestBreakerReopensOnFailedProbe() {
+	t := s.T()
+	require := s.Require()
+
+	s.trip()
+
+	t.Log("failed probe after cooldown opens breaker again")
+	{
+		time.Sleep(testCooldown + 10*time.Millisecond)
+
+		err := s.fail(errors.New("connection refused"))
+		require.False(IsRejection(err), "probe must reach dependency")
+		require.True(s.breaker.Open(), "breaker must be open again")
+		require.True(IsRejection(s.fail(nil)), "calls must be rejected again")
+	}
+}
+
+func (s *breakerTestSuite) TestSuccessfulErrorsDoNotTripBreaker() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("errors reported as successful are returned but not counted as failures")
+	{
+		for i := 0; i <= testFailureThreshold; i++ {
+			require.ErrorIs(s.fail(errIgnored), errIgnored, "error must be returned")
+		}
+		require.False(s.breaker.Open(), "breaker must stay closed")
+	}
+}
+
+// start breaker test suite
+func TestBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(breakerTestSuite))
+}
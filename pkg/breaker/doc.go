@@ -0,0 +1,2 @@
+// Package breaker contains circuit breaker shared by wrappers of unreliable dependencies like caches and databases
+package breaker
@@ -0,0 +1,115 @@
+package transactor
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoShardRouterMsg is reported by mongos in response to isMaster command
+const mongoShardRouterMsg = "isdbgrid"
+
+type mongoSessionKey struct{}
+
+func withMongoSession(ctx context.Context, sess mongo.Session) context.Context {
+	return context.WithValue(ctx, mongoSessionKey{}, sess)
+}
+
+// SessionFromContext returns session of transaction started by MongoTransactor, nil is returned outside of transaction
+func SessionFromContext(ctx context.Context) mongo.Session {
+	if sess, ok := ctx.Value(mongoSessionKey{}).(mongo.Session); ok {
+		return sess
+	}
+	return nil
+}
+
+// MongoTransactor represents mongo transactor behavior
+type MongoTransactor interface {
+	Transactor
+	// Transactional reports whether server supports transactions, functions are run without transaction otherwise
+	Transactional() bool
+}
+
+type mongoTransactor struct {
+	client        *mongo.Client
+	transactional bool
+}
+
+// NewMongoTransactor builds new MongoTransactor. Transactions are supported by replica set and sharded cluster only,
+// so server topology is detected once and functions are run without transaction on standalone server
+func NewMongoTransactor(ctx context.Context, client *mongo.Client) (MongoTransactor, error) {
+	var res struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+		return nil, fmt.Errorf("mongo: failed to detect server topology - %w", err)
+	}
+
+	return &mongoTransactor{
+		client:        client,
+		transactional: res.SetName != "" || res.Msg == mongoShardRouterMsg,
+	}, nil
+}
+
+func (t *mongoTransactor) Transactional() bool {
+	return t.transactional
+}
+
+// WithinTransaction runs txFunc within transaction, txFunc may be called several times since transaction
+// is retried on transient errors. Nested call joins already started transaction along with its after commit hooks.
+// RegisterAfterCommit reports no transaction on standalone server, so callers act immediately
+func (t *mongoTransactor) WithinTransaction(ctx context.Context, txFunc func(context.Context) error) error {
+	if !t.transactional || SessionFromContext(ctx) != nil {
+		return txFunc(ctx)
+	}
+
+	sess, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	var hooks *afterCommitHooks
+	_, err = sess.WithTransaction(ctx, func(mongo.SessionContext) (any, error) {
+		var hooksCtx context.Context
+		hooksCtx, hooks = withAfterCommitHooks(ctx) // hooks of retried attempts are dropped
+		return nil, txFunc(withMongoSession(hooksCtx, sess))
+	})
+	if err != nil {
+		return err
+	}
+
+	hooks.run(ctx)
+	return nil
+}
+
+// MongoWithinTransactionExecutor represents session aware executor for mongo
+type MongoWithinTransactionExecutor interface {
+	// Session binds session of transaction started by MongoTransactor, so operations run with returned context join it
+	Session(context.Context) context.Context
+	Database(string) *mongo.Database
+}
+
+type mongoWithinTransactionExecutor struct {
+	client *mongo.Client
+}
+
+// NewMongoWithinTransactionExecutor builds new MongoWithinTransactionExecutor
+func NewMongoWithinTransactionExecutor(client *mongo.Client) MongoWithinTransactionExecutor {
+	return &mongoWithinTransactionExecutor{client: client}
+}
+
+func (e *mongoWithinTransactionExecutor) Session(ctx context.Context) context.Context {
+	if sess := SessionFromContext(ctx); sess != nil {
+		return mongo.NewSessionContext(ctx, sess)
+	}
+	return ctx
+}
+
+func (e *mongoWithinTransactionExecutor) Database(name string) *mongo.Database {
+	return e.client.Database(name)
+}
@@ -40,6 +40,7 @@ func (t *pgxTransactor) WithinTransaction(ctx context.Context, txFunc func(conte
 	return t.WithinTransactionWithOptions(ctx, txFunc, pgx.TxOptions{})
 }
 
+// WithinTransactionWithOptions runs txFunc within transaction, hooks registered via RegisterAfterCommit are run after commit
 func (t *pgxTransactor) WithinTransactionWithOptions(ctx context.Context, txFunc func(context.Context) error, opts pgx.TxOptions) (err error) {
 	conn, err := t.pool.Acquire(ctx)
 	if err != nil {
@@ -51,6 +52,8 @@ func (t *pgxTransactor) WithinTransactionWithOptions(ctx context.Context, txFunc
 	if err != nil {
 		return err
 	}
+
+	hooksCtx, hooks := withAfterCommitHooks(ctx)
 	defer func() {
 		var txErr error
 		if err != nil {
@@ -62,9 +65,13 @@ func (t *pgxTransactor) WithinTransactionWithOptions(ctx context.Context, txFunc
 		if txErr != nil {
 			err = txErr
 		}
+
+		if err == nil {
+			hooks.run(ctx)
+		}
 	}()
 
-	err = txFunc(withPgTx(ctx, tx))
+	err = txFunc(withPgTx(hooksCtx, tx))
 	return err
 }
 
@@ -2,9 +2,58 @@ package transactor
 
 import (
 	"context"
+	"sync"
+
+	"github.com/umalmyha/customers/pkg/logging"
 )
 
 // Transactor represents behavior for transactors
 type Transactor interface {
 	WithinTransaction(context.Context, func(context.Context) error) error
 }
+
+type afterCommitKey struct{}
+
+// afterCommitHooks collects callbacks registered during single transaction
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func(context.Context)
+}
+
+func withAfterCommitHooks(ctx context.Context) (context.Context, *afterCommitHooks) {
+	h := &afterCommitHooks{}
+	return context.WithValue(ctx, afterCommitKey{}, h), h
+}
+
+// RegisterAfterCommit registers fn to be run after transaction started by transactor is committed, fn is dropped on rollback.
+// False is returned and fn is not registered outside of transaction, so caller is expected to act immediately
+func RegisterAfterCommit(ctx context.Context, fn func(context.Context)) bool {
+	h, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	if !ok {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, fn)
+	return true
+}
+
+// run calls hooks in registration order, panic of hook is logged and doesn't prevent other hooks from running
+func (h *afterCommitHooks) run(ctx context.Context) {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logging.FromContext(ctx).Errorf("after commit hook panicked - %v", r)
+				}
+			}()
+			fn(ctx)
+		}()
+	}
+}
@@ -0,0 +1,46 @@
+package transactor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type transactorTestSuite struct {
+	suite.Suite
+}
+
+func (s *transactorTestSuite) TestRegisterAfterCommit() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("hook is not registered outside of transaction")
+	{
+		called := false
+		registered := RegisterAfterCommit(context.Background(), func(context.Context) { called = true })
+		require.False(registered, "hook must not be registered outside of transaction")
+		require.False(called, "hook must be left to caller outside of transaction")
+	}
+
+	t.Log("hooks are run once in registration order and panic is isolated")
+	{
+		ctx, hooks := withAfterCommitHooks(context.Background())
+
+		var calls []int
+		RegisterAfterCommit(ctx, func(context.Context) { calls = append(calls, 1) })
+		RegisterAfterCommit(ctx, func(context.Context) { panic("broken hook") })
+		RegisterAfterCommit(ctx, func(context.Context) { calls = append(calls, 2) })
+
+		require.NotPanics(func() { hooks.run(context.Background()) }, "panic of hook must be recovered")
+		require.Equal([]int{1, 2}, calls, "hooks must be run in registration order")
+
+		hooks.run(context.Background())
+		require.Equal([]int{1, 2}, calls, "hooks must be run once")
+	}
+}
+
+// start transactor test suite
+func TestTransactorTestSuite(t *testing.T) {
+	suite.Run(t, new(transactorTestSuite))
+}
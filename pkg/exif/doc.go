@@ -0,0 +1,2 @@
+// Package exif removes privacy sensitive metadata like GPS coordinates and device identifiers from images
+package exif
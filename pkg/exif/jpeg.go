@@ -0,0 +1,197 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// ReencodeQuality is jpeg quality used when image has to be re-encoded to apply orientation
+const ReencodeQuality = 90
+
+// ErrInvalidJPEG is raised when jpeg structure can't be parsed
+var ErrInvalidJPEG = errors.New("invalid jpeg")
+
+const (
+	markerPrefix = 0xFF
+	markerSOI    = 0xD8
+	markerEOI    = 0xD9
+	markerSOS    = 0xDA
+	markerRST0   = 0xD0
+	markerRST7   = 0xD7
+	markerTEM    = 0x01
+	markerAPP1   = 0xE1 // EXIF and XMP
+	markerAPP13  = 0xED // IPTC
+	markerCOM    = 0xFE
+)
+
+const (
+	orientationTag     = 0x0112
+	orientationDefault = 1
+	tiffHeaderLength   = 8
+	ifdEntryLength     = 12
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// StripJPEG removes EXIF, XMP, IPTC and comment segments from jpeg and reports whether anything was removed.
+// If EXIF orientation is not the default one, image is re-encoded with orientation applied to pixels,
+// so it is displayed the same way without metadata.
+func StripJPEG(content []byte) ([]byte, bool, error) {
+	if len(content) < 2 || content[0] != markerPrefix || content[1] != markerSOI {
+		return nil, false, ErrInvalidJPEG
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(content)))
+	out.Write(content[:2])
+
+	stripped := false
+	orientation := orientationDefault
+
+	pos := 2
+	for {
+		if pos+2 > len(content) || content[pos] != markerPrefix {
+			return nil, false, fmt.Errorf("%w - marker expected at offset %d", ErrInvalidJPEG, pos)
+		}
+
+		marker := content[pos+1]
+		switch {
+		case marker == markerPrefix: // fill byte
+			pos++
+			continue
+		case marker == markerSOI || marker == markerEOI || marker == markerTEM || (marker >= markerRST0 && marker <= markerRST7):
+			out.Write(content[pos : pos+2])
+			pos += 2
+			if marker == markerEOI {
+				return finish(out.Bytes(), stripped, orientation)
+			}
+			continue
+		}
+
+		if pos+4 > len(content) {
+			return nil, false, fmt.Errorf("%w - truncated segment at offset %d", ErrInvalidJPEG, pos)
+		}
+
+		end := pos + 2 + int(binary.BigEndian.Uint16(content[pos+2:pos+4]))
+		if end > len(content) || end < pos+4 {
+			return nil, false, fmt.Errorf("%w - invalid segment length at offset %d", ErrInvalidJPEG, pos)
+		}
+
+		if marker == markerSOS { // entropy coded data follows, the rest is copied as is
+			out.Write(content[pos:])
+			return finish(out.Bytes(), stripped, orientation)
+		}
+
+		payload := content[pos+4 : end]
+		switch marker {
+		case markerAPP1, markerAPP13, markerCOM:
+			if marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader) {
+				orientation = exifOrientation(payload[len(exifHeader):])
+			}
+			stripped = true
+		default:
+			out.Write(content[pos:end])
+		}
+		pos = end
+	}
+}
+
+func finish(content []byte, stripped bool, orientation int) ([]byte, bool, error) {
+	if orientation == orientationDefault {
+		return content, stripped, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w - %v", ErrInvalidJPEG, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, orient(img, orientation), &jpeg.Options{Quality: ReencodeQuality}); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// exifOrientation reads orientation from IFD0 of EXIF tiff structure, default orientation is returned if it is missing
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < tiffHeaderLength {
+		return orientationDefault
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationDefault
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) || ifd < tiffHeaderLength {
+		return orientationDefault
+	}
+
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*ifdEntryLength
+		if entry+ifdEntryLength > len(tiff) {
+			break
+		}
+
+		if order.Uint16(tiff[entry:entry+2]) == orientationTag {
+			if o := int(order.Uint16(tiff[entry+8 : entry+10])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return orientationDefault
+}
+
+// orient transforms image according to EXIF orientation, so it looks correctly without orientation tag
+func orient(src image.Image, orientation int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dstW, dstH := w, h
+	if orientation >= 5 { // orientations 5-8 swap width and height
+		dstW, dstH = h, w
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 counterclockwise
+				sx, sy = w-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			dst.SetRGBA(x, y, rgba.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
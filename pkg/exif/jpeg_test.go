@@ -0,0 +1,82 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type jpegTestSuite struct {
+	suite.Suite
+}
+
+func (s *jpegTestSuite) fixture(name string) []byte {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	s.Require().NoError(err, "failed to read fixture")
+	return content
+}
+
+func (s *jpegTestSuite) TestStripJPEG() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("exif segment is removed and pixels are left untouched")
+	{
+		content := s.fixture("exif.jpg")
+		require.True(bytes.Contains(content, exifHeader), "fixture must contain exif")
+
+		stripped, ok, err := StripJPEG(content)
+		require.NoError(err, "jpeg must be stripped")
+		require.True(ok, "stripping must be reported")
+		require.False(bytes.Contains(stripped, exifHeader), "exif must be removed")
+		require.False(bytes.Contains(stripped, []byte("SecretCam")), "camera make must be removed")
+		require.Equal(s.fixture("plain.jpg"), stripped, "image data must be preserved as is")
+	}
+
+	t.Log("jpeg without metadata is returned unchanged")
+	{
+		content := s.fixture("plain.jpg")
+
+		stripped, ok, err := StripJPEG(content)
+		require.NoError(err, "jpeg must be processed")
+		require.False(ok, "nothing is stripped")
+		require.Equal(content, stripped, "content must be unchanged")
+	}
+
+	t.Log("orientation is applied to pixels before exif is removed")
+	{
+		stripped, ok, err := StripJPEG(s.fixture("exif_rotated.jpg"))
+		require.NoError(err, "jpeg must be stripped")
+		require.True(ok, "stripping must be reported")
+		require.False(bytes.Contains(stripped, exifHeader), "exif must be removed")
+
+		img, err := jpeg.Decode(bytes.NewReader(stripped))
+		require.NoError(err, "stripped jpeg must be decodable")
+		require.Equal(8, img.Bounds().Dx(), "width and height must be swapped")
+		require.Equal(16, img.Bounds().Dy(), "width and height must be swapped")
+
+		top, _, _, _ := img.At(4, 2).RGBA()
+		bottom, _, _, _ := img.At(4, 13).RGBA()
+		require.Greater(top, bottom, "left red half must be rotated to the top")
+	}
+
+	t.Log("invalid jpeg is rejected")
+	{
+		_, _, err := StripJPEG([]byte("definitely not a jpeg"))
+		require.True(errors.Is(err, ErrInvalidJPEG), "invalid jpeg error expected")
+
+		content := s.fixture("exif.jpg")
+		_, _, err = StripJPEG(content[:10])
+		require.True(errors.Is(err, ErrInvalidJPEG), "truncated jpeg must be rejected")
+	}
+}
+
+// start jpeg test suite
+func TestJPEGTestSuite(t *testing.T) {
+	suite.Run(t, new(jpegTestSuite))
+}
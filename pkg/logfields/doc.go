@@ -0,0 +1,2 @@
+// Package logfields attaches static context fields, e.g. service name, to every logrus entry
+package logfields
@@ -0,0 +1,36 @@
+package logfields
+
+import "github.com/sirupsen/logrus"
+
+// Hook adds base fields to every entry of logger it is registered for, so even package level
+// logrus calls carry them, fields set explicitly via WithFields take precedence over base ones
+type Hook struct {
+	fields logrus.Fields
+}
+
+// NewHook builds Hook with provided base fields, fields with empty string value are skipped
+func NewHook(fields logrus.Fields) *Hook {
+	nonEmpty := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		if s, ok := v.(string); ok && s == "" {
+			continue
+		}
+		nonEmpty[k] = v
+	}
+	return &Hook{fields: nonEmpty}
+}
+
+// Levels returns all levels, so base fields are present in every entry
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds base fields missing in entry
+func (h *Hook) Fire(e *logrus.Entry) error {
+	for k, v := range h.fields {
+		if _, ok := e.Data[k]; !ok {
+			e.Data[k] = v
+		}
+	}
+	return nil
+}
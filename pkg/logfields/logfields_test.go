@@ -0,0 +1,67 @@
+package logfields
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type logFieldsTestSuite struct {
+	suite.Suite
+	logger  *logrus.Logger
+	entries *logrusTest.Hook
+}
+
+func (s *logFieldsTestSuite) SetupTest() {
+	s.logger = logrus.New()
+	s.logger.AddHook(NewHook(logrus.Fields{
+		"service":     "customers",
+		"environment": "staging",
+		"instance":    "customers-7d9f",
+		"region":      "",
+	}))
+	s.entries = logrusTest.NewLocal(s.logger)
+}
+
+func (s *logFieldsTestSuite) TestHook() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("base fields are added to plain entry")
+	{
+		s.logger.Info("server started")
+
+		entry := s.entries.LastEntry()
+		require.Equal("customers", entry.Data["service"], "service name must be logged")
+		require.Equal("staging", entry.Data["environment"], "environment must be logged")
+		require.Equal("customers-7d9f", entry.Data["instance"], "instance id must be logged")
+	}
+
+	t.Log("empty base fields are skipped")
+	{
+		s.logger.Warn("slow query")
+		require.NotContains(s.entries.LastEntry().Data, "region", "empty field must not be logged")
+	}
+
+	t.Log("base fields are merged with derived entry fields")
+	{
+		s.logger.WithField("payload", "{}").Error("request failed")
+
+		entry := s.entries.LastEntry()
+		require.Equal("{}", entry.Data["payload"], "entry field must be kept")
+		require.Equal("customers", entry.Data["service"], "service name must be logged")
+	}
+
+	t.Log("explicit entry field takes precedence over base one")
+	{
+		s.logger.WithField("service", "audit").Info("login succeeded")
+		require.Equal("audit", s.entries.LastEntry().Data["service"], "explicit field must not be overwritten")
+	}
+}
+
+// start log fields test suite
+func TestLogFieldsTestSuite(t *testing.T) {
+	suite.Run(t, new(logFieldsTestSuite))
+}
@@ -0,0 +1,3 @@
+// Package logging carries request scoped log fields, e.g. request id, through context, so log lines
+// written deep inside services and repositories can be correlated with the request they belong to
+package logging
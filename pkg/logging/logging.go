@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field names of request scoped log fields
+const (
+	FieldRequestID = "requestId"
+	FieldTransport = "transport"
+	FieldRoute     = "route"
+	FieldMethod    = "method"
+	FieldPrincipal = "principal"
+)
+
+// Transports request is received over
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+type fieldsCtxKey struct{}
+
+// ContextWithFields returns copy of parent context carrying provided fields in addition to fields of parent,
+// fields of parent are overwritten on conflict
+func ContextWithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	parent := FieldsFromContext(ctx)
+
+	merged := make(logrus.Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, fieldsCtxKey{}, merged)
+}
+
+// FieldsFromContext extracts request scoped fields from context, nil is returned if fields are not set
+func FieldsFromContext(ctx context.Context) logrus.Fields {
+	fields, _ := ctx.Value(fieldsCtxKey{}).(logrus.Fields)
+	return fields
+}
+
+// FromContext returns standard logger pre-populated with request scoped fields of context,
+// standard logger itself is returned if context carries no fields, so callers never need setup
+func FromContext(ctx context.Context) logrus.FieldLogger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return logrus.StandardLogger()
+	}
+	return logrus.WithFields(fields)
+}
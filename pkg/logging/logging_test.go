@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type loggingTestSuite struct {
+	suite.Suite
+	entries *logrusTest.Hook
+}
+
+func (s *loggingTestSuite) SetupTest() {
+	s.entries = logrusTest.NewGlobal()
+}
+
+func (s *loggingTestSuite) TearDownTest() {
+	s.entries.Reset()
+}
+
+func (s *loggingTestSuite) TestFromContext() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("standard logger is used if context has no fields")
+	{
+		require.Same(logrus.StandardLogger(), FromContext(context.Background()), "standard logger must be returned")
+
+		FromContext(context.Background()).Info("stream reader started")
+		require.Empty(s.entries.LastEntry().Data, "no fields must be logged")
+	}
+
+	t.Log("context fields are logged")
+	{
+		ctx := ContextWithFields(context.Background(), logrus.Fields{
+			FieldRequestID: "2d4b1f0a-quoted-by-user",
+			FieldTransport: TransportHTTP,
+		})
+		FromContext(ctx).Error("failed to read all customers")
+
+		entry := s.entries.LastEntry()
+		require.Equal("2d4b1f0a-quoted-by-user", entry.Data[FieldRequestID], "request id must be logged")
+		require.Equal(TransportHTTP, entry.Data[FieldTransport], "transport must be logged")
+	}
+
+	t.Log("fields are merged with fields of parent context")
+	{
+		parent := ContextWithFields(context.Background(), logrus.Fields{FieldRequestID: "2d4b1f0a", FieldRoute: "/api/v1/customers"})
+		ctx := ContextWithFields(parent, logrus.Fields{FieldPrincipal: "8c1e7f3d", FieldRoute: "/api/v1/customers/:id"})
+
+		require.Equal(logrus.Fields{
+			FieldRequestID: "2d4b1f0a",
+			FieldPrincipal: "8c1e7f3d",
+			FieldRoute:     "/api/v1/customers/:id",
+		}, FieldsFromContext(ctx), "fields must be merged, child fields take precedence")
+		require.Equal(logrus.Fields{FieldRequestID: "2d4b1f0a", FieldRoute: "/api/v1/customers"}, FieldsFromContext(parent), "parent fields must not be changed")
+	}
+}
+
+// start logging test suite
+func TestLoggingTestSuite(t *testing.T) {
+	suite.Run(t, new(loggingTestSuite))
+}
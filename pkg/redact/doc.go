@@ -0,0 +1,2 @@
+// Package redact contains helpers masking sensitive data before it is logged
+package redact
@@ -0,0 +1,158 @@
+package redact
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces values of sensitive fields
+const Mask = "***"
+
+const tagName = "redact"
+
+// sensitiveNames are field names masked regardless of tags, compared in lower case
+var sensitiveNames = map[string]struct{}{
+	"password":     {},
+	"passwordhash": {},
+	"refreshtoken": {},
+	"accesstoken":  {},
+	"token":        {},
+	"email":        {},
+}
+
+var sensitiveNamesMu sync.RWMutex
+
+// emailPattern matches email addresses in free text, local part is captured to be masked
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+(@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// AddSensitiveNames extends list of field names masked regardless of tags, built-in names can't be removed
+func AddSensitiveNames(names ...string) {
+	sensitiveNamesMu.Lock()
+	defer sensitiveNamesMu.Unlock()
+
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			sensitiveNames[strings.ToLower(n)] = struct{}{}
+		}
+	}
+}
+
+// Text masks local part of email addresses found in free text, e.g. error message
+func Text(s string) string {
+	return emailPattern.ReplaceAllString(s, Mask+"$1")
+}
+
+// Value returns representation of v suitable for logging with sensitive fields masked.
+// Field is treated as sensitive if it is tagged with `redact:"true"` or its name is in the sensitive names list.
+// Structs are represented as maps keyed by json field names.
+func Value(v any) any {
+	return value(reflect.ValueOf(v))
+}
+
+// JSON masks values of sensitive keys in provided json document
+func JSON(data []byte) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(Value(doc))
+}
+
+func value(rv reflect.Value) any {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return value(rv.Elem())
+	case reflect.Struct:
+		fields := make(map[string]any)
+		structFields(rv, fields)
+		return fields
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return rv.Interface()
+		}
+
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if isSensitiveName(key) {
+				m[key] = Mask
+				continue
+			}
+			m[key] = value(iter.Value())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+
+		if rv.Type().Elem().Kind() == reflect.Uint8 { // keep byte slices as is
+			return rv.Interface()
+		}
+
+		s := make([]any, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			s[i] = value(rv.Index(i))
+		}
+		return s
+	default:
+		return rv.Interface()
+	}
+}
+
+func structFields(rv reflect.Value, fields map[string]any) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name, skip := jsonName(sf)
+		if skip {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct { // embedded structs are flattened like in json
+			structFields(fv, fields)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		if sf.Tag.Get(tagName) == "true" || isSensitiveName(sf.Name) || isSensitiveName(name) {
+			fields[name] = Mask
+			continue
+		}
+		fields[name] = value(fv)
+	}
+}
+
+func jsonName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func isSensitiveName(name string) bool {
+	sensitiveNamesMu.RLock()
+	defer sensitiveNamesMu.RUnlock()
+
+	_, ok := sensitiveNames[strings.ToLower(name)]
+	return ok
+}
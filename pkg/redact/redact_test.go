@@ -0,0 +1,151 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type tokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    int64  `json:"expiresAt"`
+}
+
+type account struct {
+	credentials
+	Fingerprint string   `json:"fingerprint" redact:"true"`
+	Tokens      *tokens  `json:"tokens"`
+	Roles       []string `json:"roles"`
+	Internal    string   `json:"-"`
+	Nickname    string
+	secret      string
+}
+
+type redactTestSuite struct {
+	suite.Suite
+}
+
+func (s *redactTestSuite) TestValue() {
+	t := s.T()
+	require := s.Require()
+
+	acc := &account{
+		credentials: credentials{Email: "john@somemal.com", Password: "secret"},
+		Fingerprint: "device",
+		Tokens:      &tokens{AccessToken: "jwt", RefreshToken: "uuid", ExpiresAt: 100},
+		Roles:       []string{"admin"},
+		Internal:    "internal",
+		Nickname:    "john",
+		secret:      "secret",
+	}
+
+	t.Log("sensitive fields are masked while others remain")
+	{
+		redacted, ok := Value(acc).(map[string]any)
+		require.True(ok, "struct must be represented as map")
+		require.Equal(Mask, redacted["email"], "email must be masked")
+		require.Equal(Mask, redacted["password"], "password must be masked")
+		require.Equal(Mask, redacted["fingerprint"], "tagged field must be masked")
+		require.Equal([]any{"admin"}, redacted["roles"], "roles must remain")
+		require.Equal("john", redacted["Nickname"], "untagged field must be keyed by its name")
+		require.NotContains(redacted, "Internal", "field excluded from json must be skipped")
+		require.NotContains(redacted, "secret", "unexported field must be skipped")
+
+		tkns, ok := redacted["tokens"].(map[string]any)
+		require.True(ok, "nested struct must be represented as map")
+		require.Equal(Mask, tkns["accessToken"], "access token must be masked")
+		require.Equal(Mask, tkns["refreshToken"], "refresh token must be masked")
+		require.Equal(int64(100), tkns["expiresAt"], "expires at must remain")
+	}
+
+	t.Log("original value is not modified")
+	{
+		require.Equal("secret", acc.Password, "original password must stay untouched")
+		require.Equal("jwt", acc.Tokens.AccessToken, "original access token must stay untouched")
+	}
+
+	t.Log("maps keys are checked against sensitive names")
+	{
+		redacted := Value(map[string]any{"PasswordHash": "hash", "id": 1})
+		require.Equal(map[string]any{"PasswordHash": Mask, "id": 1}, redacted, "password hash must be masked")
+	}
+
+	t.Log("nil value is represented as nil")
+	{
+		require.Nil(Value(nil), "nil must be returned")
+		require.Nil(Value((*account)(nil)), "nil must be returned for nil pointer")
+	}
+}
+
+func (s *redactTestSuite) TestJSON() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("sensitive keys are masked in json document")
+	{
+		raw := `{"email":"john@somemal.com","password":"secret","session":[{"refreshToken":"uuid","expiresAt":100}]}`
+		redacted, err := JSON([]byte(raw))
+		require.NoError(err, "no error must be raised")
+
+		var doc map[string]any
+		require.NoError(json.Unmarshal(redacted, &doc), "redacted document must be valid json")
+		require.Equal(Mask, doc["email"], "email must be masked")
+		require.Equal(Mask, doc["password"], "password must be masked")
+
+		session := doc["session"].([]any)[0].(map[string]any)
+		require.Equal(Mask, session["refreshToken"], "refresh token must be masked")
+		require.Equal(float64(100), session["expiresAt"], "expires at must remain")
+	}
+
+	t.Log("invalid json is rejected")
+	{
+		_, err := JSON([]byte(`{"password":`))
+		require.Error(err, "invalid json must raise error")
+	}
+}
+
+func (s *redactTestSuite) TestText() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("email addresses are masked in free text")
+	{
+		msg := "customer with email john.smith+work@somemal.com already exist, contact admin@customers.io"
+		require.Equal(
+			"customer with email ***@somemal.com already exist, contact ***@customers.io",
+			Text(msg),
+			"local part of emails must be masked",
+		)
+	}
+
+	t.Log("text without emails remains")
+	{
+		msg := "postgres: failed to read all customers - connection refused"
+		require.Equal(msg, Text(msg), "text must remain")
+	}
+}
+
+func (s *redactTestSuite) TestAddSensitiveNames() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("configured names are masked in addition to built-in")
+	{
+		AddSensitiveNames(" Fingerprint ", "")
+
+		redacted := Value(map[string]any{"fingerprint": "device", "password": "secret", "id": 1})
+		require.Equal(map[string]any{"fingerprint": Mask, "password": Mask, "id": 1}, redacted, "configured name must be masked")
+	}
+}
+
+// start redact test suite
+func TestRedactTestSuite(t *testing.T) {
+	suite.Run(t, new(redactTestSuite))
+}
@@ -0,0 +1,2 @@
+// Package retry contains bounded retry with exponential backoff used while connecting to dependencies
+package retry
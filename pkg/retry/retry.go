@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backoff defines how many attempts are made and how long to wait between them,
+// interval is doubled after each failed attempt up to max interval
+type Backoff struct {
+	Attempts    int
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// Delay returns interval to wait after failed attempt with number starting from 1
+func (b Backoff) Delay(attempt int) time.Duration {
+	interval := b.Interval
+	for i := 1; i < attempt; i++ {
+		interval *= 2
+		if b.MaxInterval > 0 && interval > b.MaxInterval {
+			return b.MaxInterval
+		}
+	}
+	return interval
+}
+
+// Do calls fn until it succeeds, attempts are exhausted or context is done,
+// onFailure is called after each failed attempt with attempt number starting from 1
+func Do(ctx context.Context, b Backoff, fn func(context.Context) error, onFailure func(int, error)) error {
+	attempts := b.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	interval := b.Interval
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		if onFailure != nil {
+			onFailure(attempt, err)
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("gave up after %d attempts - %w", attempt, err)
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if b.MaxInterval > 0 && interval > b.MaxInterval {
+			interval = b.MaxInterval
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts - %w", attempts, err)
+}
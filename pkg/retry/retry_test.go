@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var errNotReady = errors.New("connection refused")
+
+// fakeDialer fails until it is called failures+1 times
+type fakeDialer struct {
+	failures int
+	calls    int
+}
+
+func (d *fakeDialer) dial(context.Context) error {
+	d.calls++
+	if d.calls <= d.failures {
+		return errNotReady
+	}
+	return nil
+}
+
+type retryTestSuite struct {
+	suite.Suite
+}
+
+func (s *retryTestSuite) TestDo() {
+	t := s.T()
+	require := s.Require()
+
+	backoff := Backoff{Attempts: 3, Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+
+	t.Log("dependency becomes ready after failed attempts")
+	{
+		dialer := &fakeDialer{failures: 2}
+		failed := make([]int, 0)
+
+		err := Do(context.Background(), backoff, dialer.dial, func(attempt int, err error) {
+			require.ErrorIs(err, errNotReady, "attempt error must be passed")
+			failed = append(failed, attempt)
+		})
+		require.NoError(err, "dial must succeed on last attempt")
+		require.Equal(3, dialer.calls, "dialer must be called 3 times")
+		require.Equal([]int{1, 2}, failed, "each failed attempt must be reported")
+	}
+
+	t.Log("attempts are exhausted")
+	{
+		dialer := &fakeDialer{failures: 5}
+
+		err := Do(context.Background(), backoff, dialer.dial, nil)
+		require.ErrorIs(err, errNotReady, "last error must be wrapped")
+		require.Equal(3, dialer.calls, "dialer must be called exactly 3 times")
+	}
+
+	t.Log("at least one attempt is made")
+	{
+		dialer := &fakeDialer{}
+
+		err := Do(context.Background(), Backoff{}, dialer.dial, nil)
+		require.NoError(err, "dial must succeed")
+		require.Equal(1, dialer.calls, "dialer must be called once")
+	}
+
+	t.Log("waiting is interrupted by context")
+	{
+		dialer := &fakeDialer{failures: 5}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := Do(ctx, Backoff{Attempts: 5, Interval: time.Hour}, dialer.dial, func(int, error) { cancel() })
+		require.ErrorIs(err, errNotReady, "last error must be wrapped")
+		require.Equal(1, dialer.calls, "no attempts must be made after context is done")
+	}
+}
+
+func (s *retryTestSuite) TestDelay() {
+	t := s.T()
+	require := s.Require()
+
+	t.Log("delay is doubled after each attempt up to max interval")
+	{
+		backoff := Backoff{Interval: time.Second, MaxInterval: 5 * time.Second}
+		require.Equal(time.Second, backoff.Delay(1), "first delay must be interval")
+		require.Equal(4*time.Second, backoff.Delay(3), "delay must be doubled")
+		require.Equal(5*time.Second, backoff.Delay(10), "delay must be capped")
+	}
+}
+
+// start retry test suite
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(retryTestSuite))
+}
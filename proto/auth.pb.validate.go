@@ -364,10 +364,10 @@ func (m *LoginRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
-	if len(m.GetFingerprint()) < 1 {
+	if l := len(m.GetFingerprint()); l < 1 || l > 255 {
 		err := LoginRequestValidationError{
 			field:  "Fingerprint",
-			reason: "value length must be at least 1 bytes",
+			reason: "value length must be between 1 and 255 bytes, inclusive",
 		}
 		if !all {
 			return err
@@ -524,10 +524,10 @@ func (m *RefreshRequest) validate(all bool) error {
 
 	var errors []error
 
-	if len(m.GetFingerprint()) < 1 {
+	if l := len(m.GetFingerprint()); l < 1 || l > 255 {
 		err := RefreshRequestValidationError{
 			field:  "Fingerprint",
-			reason: "value length must be at least 1 bytes",
+			reason: "value length must be between 1 and 255 bytes, inclusive",
 		}
 		if !all {
 			return err
@@ -304,6 +304,17 @@ func (m *NewCustomerRequest) validate(all bool) error {
 
 	var errors []error
 
+	if utf8.RuneCountInString(m.GetFirstName()) > 200 {
+		err := NewCustomerRequestValidationError{
+			field:  "FirstName",
+			reason: "value length must be at most 200 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if len(m.GetFirstName()) < 1 {
 		err := NewCustomerRequestValidationError{
 			field:  "FirstName",
@@ -315,6 +326,17 @@ func (m *NewCustomerRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
+	if utf8.RuneCountInString(m.GetLastName()) > 200 {
+		err := NewCustomerRequestValidationError{
+			field:  "LastName",
+			reason: "value length must be at most 200 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if len(m.GetLastName()) < 1 {
 		err := NewCustomerRequestValidationError{
 			field:  "LastName",
@@ -326,6 +348,17 @@ func (m *NewCustomerRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
+	if utf8.RuneCountInString(m.GetEmail()) > 250 {
+		err := NewCustomerRequestValidationError{
+			field:  "Email",
+			reason: "value length must be at most 250 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if err := m._validateEmail(m.GetEmail()); err != nil {
 		err = NewCustomerRequestValidationError{
 			field:  "Email",
@@ -352,7 +385,18 @@ func (m *NewCustomerRequest) validate(all bool) error {
 	// no validation rules for Inactive
 
 	if m.MiddleName != nil {
-		// no validation rules for MiddleName
+
+		if utf8.RuneCountInString(m.GetMiddleName()) > 200 {
+			err := NewCustomerRequestValidationError{
+				field:  "MiddleName",
+				reason: "value length must be at most 200 runes",
+			}
+			if !all {
+				return err
+			}
+			errors = append(errors, err)
+		}
+
 	}
 
 	if len(errors) > 0 {
@@ -526,6 +570,17 @@ func (m *UpdateCustomerRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
+	if utf8.RuneCountInString(m.GetFirstName()) > 200 {
+		err := UpdateCustomerRequestValidationError{
+			field:  "FirstName",
+			reason: "value length must be at most 200 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if len(m.GetFirstName()) < 1 {
 		err := UpdateCustomerRequestValidationError{
 			field:  "FirstName",
@@ -537,6 +592,17 @@ func (m *UpdateCustomerRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
+	if utf8.RuneCountInString(m.GetLastName()) > 200 {
+		err := UpdateCustomerRequestValidationError{
+			field:  "LastName",
+			reason: "value length must be at most 200 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if len(m.GetLastName()) < 1 {
 		err := UpdateCustomerRequestValidationError{
 			field:  "LastName",
@@ -548,6 +614,17 @@ func (m *UpdateCustomerRequest) validate(all bool) error {
 		errors = append(errors, err)
 	}
 
+	if utf8.RuneCountInString(m.GetEmail()) > 250 {
+		err := UpdateCustomerRequestValidationError{
+			field:  "Email",
+			reason: "value length must be at most 250 runes",
+		}
+		if !all {
+			return err
+		}
+		errors = append(errors, err)
+	}
+
 	if err := m._validateEmail(m.GetEmail()); err != nil {
 		err = UpdateCustomerRequestValidationError{
 			field:  "Email",
@@ -574,7 +651,18 @@ func (m *UpdateCustomerRequest) validate(all bool) error {
 	// no validation rules for Inactive
 
 	if m.MiddleName != nil {
-		// no validation rules for MiddleName
+
+		if utf8.RuneCountInString(m.GetMiddleName()) > 200 {
+			err := UpdateCustomerRequestValidationError{
+				field:  "MiddleName",
+				reason: "value length must be at most 200 runes",
+			}
+			if !all {
+				return err
+			}
+			errors = append(errors, err)
+		}
+
 	}
 
 	if len(errors) > 0 {
@@ -973,3 +1061,169 @@ var _ interface {
 	Cause() error
 	ErrorName() string
 } = CustomerListResponseValidationError{}
+
+// Validate checks the field values on CustomerEvent with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the first error encountered is returned, or nil if there are no violations.
+func (m *CustomerEvent) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on CustomerEvent with the rules
+// defined in the proto definition for this message. If any rules are
+// violated, the result is a list of violation errors wrapped in
+// CustomerEventMultiError, or nil if none found.
+func (m *CustomerEvent) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *CustomerEvent) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	// no validation rules for Id
+
+	// no validation rules for Type
+
+	// no validation rules for CustomerId
+
+	if all {
+		switch v := interface{}(m.GetCustomer()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, CustomerEventValidationError{
+					field:  "Customer",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, CustomerEventValidationError{
+					field:  "Customer",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetCustomer()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return CustomerEventValidationError{
+				field:  "Customer",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	if all {
+		switch v := interface{}(m.GetOccurredAt()).(type) {
+		case interface{ ValidateAll() error }:
+			if err := v.ValidateAll(); err != nil {
+				errors = append(errors, CustomerEventValidationError{
+					field:  "OccurredAt",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				errors = append(errors, CustomerEventValidationError{
+					field:  "OccurredAt",
+					reason: "embedded message failed validation",
+					cause:  err,
+				})
+			}
+		}
+	} else if v, ok := interface{}(m.GetOccurredAt()).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return CustomerEventValidationError{
+				field:  "OccurredAt",
+				reason: "embedded message failed validation",
+				cause:  err,
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return CustomerEventMultiError(errors)
+	}
+
+	return nil
+}
+
+// CustomerEventMultiError is an error wrapping multiple validation
+// errors returned by CustomerEvent.ValidateAll() if the designated
+// constraints aren't met.
+type CustomerEventMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m CustomerEventMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m CustomerEventMultiError) AllErrors() []error { return m }
+
+// CustomerEventValidationError is the validation error returned by
+// CustomerEvent.Validate if the designated constraints aren't met.
+type CustomerEventValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e CustomerEventValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e CustomerEventValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e CustomerEventValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e CustomerEventValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e CustomerEventValidationError) ErrorName() string {
+	return "CustomerEventValidationError"
+}
+
+// Error satisfies the builtin error interface
+func (e CustomerEventValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sCustomerEvent.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = CustomerEventValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = CustomerEventValidationError{}
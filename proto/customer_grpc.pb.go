@@ -28,6 +28,7 @@ type CustomerServiceClient interface {
 	Create(ctx context.Context, in *NewCustomerRequest, opts ...grpc.CallOption) (*CustomerResponse, error)
 	Upsert(ctx context.Context, in *UpdateCustomerRequest, opts ...grpc.CallOption) (*CustomerResponse, error)
 	DeleteByID(ctx context.Context, in *DeleteCustomerByIdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Watch(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (CustomerService_WatchClient, error)
 }
 
 type customerServiceClient struct {
@@ -83,6 +84,38 @@ func (c *customerServiceClient) DeleteByID(ctx context.Context, in *DeleteCustom
 	return out, nil
 }
 
+func (c *customerServiceClient) Watch(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (CustomerService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CustomerService_ServiceDesc.Streams[0], "/customer.CustomerService/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &customerServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CustomerService_WatchClient interface {
+	Recv() (*CustomerEvent, error)
+	grpc.ClientStream
+}
+
+type customerServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *customerServiceWatchClient) Recv() (*CustomerEvent, error) {
+	m := new(CustomerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CustomerServiceServer is the server API for CustomerService service.
 // All implementations must embed UnimplementedCustomerServiceServer
 // for forward compatibility
@@ -92,6 +125,7 @@ type CustomerServiceServer interface {
 	Create(context.Context, *NewCustomerRequest) (*CustomerResponse, error)
 	Upsert(context.Context, *UpdateCustomerRequest) (*CustomerResponse, error)
 	DeleteByID(context.Context, *DeleteCustomerByIdRequest) (*emptypb.Empty, error)
+	Watch(*emptypb.Empty, CustomerService_WatchServer) error
 	mustEmbedUnimplementedCustomerServiceServer()
 }
 
@@ -114,6 +148,9 @@ func (UnimplementedCustomerServiceServer) Upsert(context.Context, *UpdateCustome
 func (UnimplementedCustomerServiceServer) DeleteByID(context.Context, *DeleteCustomerByIdRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteByID not implemented")
 }
+func (UnimplementedCustomerServiceServer) Watch(*emptypb.Empty, CustomerService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedCustomerServiceServer) mustEmbedUnimplementedCustomerServiceServer() {}
 
 // UnsafeCustomerServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -217,6 +254,27 @@ func _CustomerService_DeleteByID_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CustomerService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CustomerServiceServer).Watch(m, &customerServiceWatchServer{stream})
+}
+
+type CustomerService_WatchServer interface {
+	Send(*CustomerEvent) error
+	grpc.ServerStream
+}
+
+type customerServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *customerServiceWatchServer) Send(m *CustomerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // CustomerService_ServiceDesc is the grpc.ServiceDesc for CustomerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -245,6 +303,12 @@ var CustomerService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _CustomerService_DeleteByID_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _CustomerService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "customer.proto",
 }
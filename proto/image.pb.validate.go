@@ -0,0 +1,243 @@
+// Code generated by protoc-gen-validate. DO NOT EDIT.
+// source: image.proto
+
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ensure the imports are used
+var (
+	_ = bytes.MinRead
+	_ = errors.New("")
+	_ = fmt.Print
+	_ = utf8.UTFMax
+	_ = (*regexp.Regexp)(nil)
+	_ = (*strings.Reader)(nil)
+	_ = net.IPv4len
+	_ = time.Duration(0)
+	_ = (*url.URL)(nil)
+	_ = (*mail.Address)(nil)
+	_ = anypb.Any{}
+	_ = sort.Sort
+)
+
+// Validate checks the field values on ImageChunk with the rules defined in the
+// proto definition for this message. If any rules are violated, the first error
+// encountered is returned, or nil if there are no violations.
+func (m *ImageChunk) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ImageChunk with the rules defined in
+// the proto definition for this message. If any rules are violated, the result
+// is a list of violation errors wrapped in ImageChunkMultiError, or nil if none
+// found.
+func (m *ImageChunk) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ImageChunk) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	// no validation rules for Name
+
+	// no validation rules for Overwrite
+
+	// no validation rules for Data
+
+	if len(errors) > 0 {
+		return ImageChunkMultiError(errors)
+	}
+
+	return nil
+}
+
+// ImageChunkMultiError is an error wrapping multiple validation errors returned
+// by ImageChunk.ValidateAll() if the designated constraints aren't met.
+type ImageChunkMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ImageChunkMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ImageChunkMultiError) AllErrors() []error { return m }
+
+// ImageChunkValidationError is the validation error returned by
+// ImageChunk.Validate if the designated constraints aren't met.
+type ImageChunkValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ImageChunkValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ImageChunkValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ImageChunkValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ImageChunkValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ImageChunkValidationError) ErrorName() string { return "ImageChunkValidationError" }
+
+// Error satisfies the builtin error interface
+func (e ImageChunkValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sImageChunk.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ImageChunkValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ImageChunkValidationError{}
+
+// Validate checks the field values on ImageRef with the rules defined in the
+// proto definition for this message. If any rules are violated, the first error
+// encountered is returned, or nil if there are no violations.
+func (m *ImageRef) Validate() error {
+	return m.validate(false)
+}
+
+// ValidateAll checks the field values on ImageRef with the rules defined in the
+// proto definition for this message. If any rules are violated, the result is a
+// list of violation errors wrapped in ImageRefMultiError, or nil if none found.
+func (m *ImageRef) ValidateAll() error {
+	return m.validate(true)
+}
+
+func (m *ImageRef) validate(all bool) error {
+	if m == nil {
+		return nil
+	}
+
+	var errors []error
+
+	// no validation rules for Name
+
+	// no validation rules for Url
+
+	if len(errors) > 0 {
+		return ImageRefMultiError(errors)
+	}
+
+	return nil
+}
+
+// ImageRefMultiError is an error wrapping multiple validation errors returned
+// by ImageRef.ValidateAll() if the designated constraints aren't met.
+type ImageRefMultiError []error
+
+// Error returns a concatenation of all the error messages it wraps.
+func (m ImageRefMultiError) Error() string {
+	var msgs []string
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// AllErrors returns a list of validation violation errors.
+func (m ImageRefMultiError) AllErrors() []error { return m }
+
+// ImageRefValidationError is the validation error returned by ImageRef.Validate
+// if the designated constraints aren't met.
+type ImageRefValidationError struct {
+	field  string
+	reason string
+	cause  error
+	key    bool
+}
+
+// Field function returns field value.
+func (e ImageRefValidationError) Field() string { return e.field }
+
+// Reason function returns reason value.
+func (e ImageRefValidationError) Reason() string { return e.reason }
+
+// Cause function returns cause value.
+func (e ImageRefValidationError) Cause() error { return e.cause }
+
+// Key function returns key value.
+func (e ImageRefValidationError) Key() bool { return e.key }
+
+// ErrorName returns error name.
+func (e ImageRefValidationError) ErrorName() string { return "ImageRefValidationError" }
+
+// Error satisfies the builtin error interface
+func (e ImageRefValidationError) Error() string {
+	cause := ""
+	if e.cause != nil {
+		cause = fmt.Sprintf(" | caused by: %v", e.cause)
+	}
+
+	key := ""
+	if e.key {
+		key = "key for "
+	}
+
+	return fmt.Sprintf(
+		"invalid %sImageRef.%s: %s%s",
+		key,
+		e.field,
+		e.reason,
+		cause)
+}
+
+var _ error = ImageRefValidationError{}
+
+var _ interface {
+	Field() string
+	Reason() string
+	Key() bool
+	Cause() error
+	ErrorName() string
+} = ImageRefValidationError{}